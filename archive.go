@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"scnpm/pkg/input"
+	"scnpm/pkg/scnpm"
+)
+
+// maxArchiveEntrySize caps how much of a single archive member is read into memory
+const maxArchiveEntrySize = 64 << 20
+
+// lockfileNames are the archive members that get scanned
+var lockfileNames = map[string]bool{
+	"package-lock.json":   true,
+	"npm-shrinkwrap.json": true,
+	"yarn.lock":           true,
+	"pnpm-lock.yaml":      true,
+}
+
+// readArchive reads the lockfiles inside a zip or tar(.gz) archive into memory, named after their
+// path in it. It also returns warnings about the members too large to read.
+func readArchive(archivePath string) ([]scnpm.Lockfile, []string, error) {
+	members, skipped, err := input.ReadArchive(archivePath, func(name string) bool {
+		return lockfileNames[name]
+	}, maxArchiveEntrySize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var warnings []string
+	for _, name := range skipped {
+		warnings = append(warnings, fmt.Sprintf("skipped archive member '%s': larger than %d MB", name, maxArchiveEntrySize>>20))
+	}
+	if len(members) == 0 {
+		return nil, warnings, fmt.Errorf("no lockfiles found in archive")
+	}
+
+	var lockfiles []scnpm.Lockfile
+	for _, member := range members {
+		lockfiles = append(lockfiles, scnpm.Lockfile{Name: member.Name, Reader: bytes.NewReader(member.Data)})
+	}
+	return lockfiles, warnings, nil
+}
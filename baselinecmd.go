@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"scnpm/pkg/baseline"
+	"scnpm/pkg/types"
+
+	"github.com/spf13/cobra"
+)
+
+// baselineOut is the baseline file baseline write records the scan's findings in, instead of
+// reporting them
+var baselineOut string
+
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Record known findings so that scans fail only on new ones",
+}
+
+var baselineWriteCmd = &cobra.Command{
+	Use:   "write BASELINE-FILE [badpak.json | package@version ...]",
+	Short: "Scan and record the current findings in a baseline file",
+	Long: `Scan as scnpm would with the same flags and arguments, and record a fingerprint of every
+bad-package finding that isn't suppressed in BASELINE-FILE. A fingerprint is the package, its
+version, its install path and the lockfile, so moving to another line or reordering the lockfile
+doesn't make a finding new.
+
+Scans given --baseline BASELINE-FILE still report the recorded findings, marked BASELINE, but
+leave them out of the risk count and the exit status of --quiet. Entries that no longer match a
+finding are listed as warnings; run baseline write again to prune them.
+
+  scnpm baseline write baseline.json badpak.json
+  scnpm --baseline baseline.json --quiet badpak.json`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		baselineOut = args[0]
+		runScan(cmd, args[1:])
+	},
+}
+
+func init() {
+	baselineCmd.AddCommand(baselineWriteCmd)
+	rootCmd.AddCommand(baselineCmd)
+}
+
+// writeBaseline records the findings of a scan of lockfile in baselineOut
+func writeBaseline(results []types.ScanResult, lockfile string) {
+	fingerprints := baseline.Record(results, lockfile)
+	if err := baseline.Write(baselineOut, fingerprints); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing baseline: %v\n", err)
+		os.Exit(exitError)
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Recorded %d findings in %s\n", len(fingerprints), baselineOut)
+	}
+}
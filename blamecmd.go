@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"scnpm/pkg/output"
+	"scnpm/pkg/scanner"
+	"scnpm/pkg/scnpm"
+	"scnpm/pkg/types"
+
+	"github.com/spf13/cobra"
+)
+
+// blameOutput is the format of the answer: table or json
+var blameOutput string
+
+var blameCmd = &cobra.Command{
+	Use:   "blame PACKAGE[@VERSION]",
+	Short: "Find the commit that added a package to the lockfile",
+	Long: `Walk the git history of the lockfile, oldest first, and print the first commit whose lockfile
+installs the package, with the last commit of the lockfile before it, to answer when a compromised
+package landed and through which change. A version or range narrows the match. Run it inside the
+git repository that tracks the lockfile; git has to be installed.
+
+Renames such as npm-shrinkwrap.json to package-lock.json are followed as git detects them, and
+every lockfile format scnpm reads is matched, whichever one a commit has. The exit status is 1
+when no commit's lockfile installs the package.
+
+  scnpm blame event-stream@3.3.6 -f package-lock.json
+  scnpm blame debug --output json`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePackageNames,
+	Run:               runBlame,
+}
+
+func init() {
+	blameCmd.Flags().StringVarP(&packageLockPath, "file", "f", "package-lock.json", "Path to package-lock.json, yarn.lock or pnpm-lock.yaml")
+	blameCmd.Flags().StringVarP(&blameOutput, "output", "o", "table", "Output format (table, json)")
+	completeLockfileFlags(blameCmd, []string{"table", "json"})
+	rootCmd.AddCommand(blameCmd)
+}
+
+func runBlame(cmd *cobra.Command, args []string) {
+	if blameOutput != "table" && blameOutput != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", blameOutput)
+		os.Exit(exitError)
+	}
+	query, err := scnpm.ParseQuery(args[0])
+	if err != nil || scanner.IsPattern(query.Name) {
+		fmt.Fprintf(os.Stderr, "Error: '%s' isn't a package name, expected package or package@version\n", args[0])
+		os.Exit(exitError)
+	}
+	if packageLockPath == stdinPath {
+		fmt.Fprintln(os.Stderr, "Error: blame reads the lockfile's git history, so it needs a path instead of stdin")
+		os.Exit(exitError)
+	}
+
+	blame, warnings, err := blameLockfile(packageLockPath, query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	printWarnings(warnings)
+	blame.Package = args[0]
+
+	if blameOutput == "json" {
+		err = output.OutputBlameJSON(os.Stdout, blame)
+	} else if blame.Introduced != nil {
+		err = output.OutputBlame(os.Stdout, blame)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(exitError)
+	}
+	if blame.Introduced == nil {
+		if blameOutput != "json" {
+			fmt.Fprintf(os.Stderr, "No commit of '%s' installs '%s'\n", packageLockPath, args[0])
+		}
+		os.Exit(exitNotInstalled)
+	}
+}
+
+// blameLockfile walks the git history of the lockfile at path, oldest commit first, for the
+// first one whose lockfile installs a package matching query. Commits whose lockfile fails to
+// parse are skipped with a warning.
+func blameLockfile(path string, query types.PackageQuery) (output.Blame, []string, error) {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return output.Blame{}, nil, err
+	}
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return output.Blame{}, nil, fmt.Errorf("'%s' isn't in a git repository: %v", path, err)
+	}
+	root = strings.TrimSpace(root)
+	prefix, err := git(dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return output.Blame{}, nil, err
+	}
+	blame := output.Blame{Lockfile: strings.TrimSpace(prefix) + filepath.Base(path)}
+
+	commits, err := lockfileCommits(root, blame.Lockfile)
+	if err != nil {
+		return output.Blame{}, nil, err
+	}
+	if len(commits) == 0 {
+		return output.Blame{}, nil, fmt.Errorf("'%s' has no git history; commit it first", path)
+	}
+
+	var warnings []string
+	var previous *output.BlameCommit
+	for i := len(commits) - 1; i >= 0; i-- {
+		commit := commits[i]
+		if !commit.deleted {
+			installed, err := commitInstalls(root, commit.BlameCommit, query)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("skipping %s of commit %.12s: %v", commit.Lockfile, commit.Hash, err))
+				continue
+			}
+			if installed {
+				blame.Introduced, blame.LastAbsent = &commit.BlameCommit, previous
+				break
+			}
+		}
+		previous = &commit.BlameCommit
+	}
+	return blame, warnings, nil
+}
+
+// lockfileCommit is a commit that changed the lockfile
+type lockfileCommit struct {
+	output.BlameCommit
+	deleted bool
+}
+
+// lockfileCommits returns the commits that changed the lockfile at path, relative to the
+// repository root, newest first. Renames are followed, so older commits may name another path.
+func lockfileCommits(root, path string) ([]lockfileCommit, error) {
+	log, err := git(root, "log", "--follow", "--name-status", "--format=%x1e%H%x1f%an%x1f%aI%x1f%s", "--", path)
+	if err != nil {
+		return nil, err
+	}
+	var commits []lockfileCommit
+	for _, record := range strings.Split(log, "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		fields := strings.Split(lines[0], "\x1f")
+		if len(fields) != 4 {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected date in git log: %v", err)
+		}
+		commit := lockfileCommit{BlameCommit: output.BlameCommit{Hash: fields[0], Author: fields[1], Date: date, Subject: fields[3]}}
+		for _, line := range lines[1:] {
+			// "M\tpath", "D\tpath", or "R100\told\tnew" for renames
+			status := strings.Split(line, "\t")
+			if len(status) < 2 {
+				continue
+			}
+			commit.Lockfile = status[len(status)-1]
+			commit.deleted = strings.HasPrefix(status[0], "D")
+		}
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// commitInstalls reports whether the lockfile of a commit installs a package matching query,
+// in whichever lockfile format the commit has
+func commitInstalls(root string, commit output.BlameCommit, query types.PackageQuery) (bool, error) {
+	data, err := git(root, "show", commit.Hash+":"+commit.Lockfile)
+	if err != nil {
+		return false, err
+	}
+	// Parsing every revision of a large lockfile is slow, and most don't mention the package
+	if !strings.Contains(data, query.Name) {
+		return false, nil
+	}
+	packageLock, err := parseLockfile(commit.Lockfile, []byte(data))
+	if err != nil {
+		return false, err
+	}
+	for _, instance := range scanner.Inventory(packageLock) {
+		if (instance.Name == query.Name || instance.Alias == query.Name) && scanner.MatchesVersion(instance.Version, query.Version) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// git runs a git command in dir and returns its standard output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return "", fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return string(out), nil
+}
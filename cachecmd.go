@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"scnpm/pkg/cache"
+
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect or clear the response cache used by online sources",
+}
+
+var cacheInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show the cache location, size and entry counts",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		c := defaultCache()
+		stats, err := c.Info()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading cache '%s': %v\n", c.Dir, err)
+			os.Exit(exitError)
+		}
+
+		fmt.Printf("Location: %s\n", c.Dir)
+		fmt.Printf("Entries:  %d\n", stats.Entries)
+		fmt.Printf("Size:     %s\n", formatBytes(stats.Size))
+		for _, namespace := range stats.NamespaceNames() {
+			fmt.Printf("  %-20s %d\n", namespace, stats.Namespaces[namespace])
+		}
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every cached response",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		c := defaultCache()
+		stats, _ := c.Info()
+		if err := c.Clear(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error clearing cache '%s': %v\n", c.Dir, err)
+			os.Exit(exitError)
+		}
+		fmt.Printf("Removed %d entries (%s) from %s\n", stats.Entries, formatBytes(stats.Size), c.Dir)
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheInfoCmd, cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+func defaultCache() *cache.Cache {
+	c, err := cache.Default(cache.DefaultTTL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error locating cache directory: %v\n", err)
+		os.Exit(exitError)
+	}
+	return c
+}
+
+// formatBytes renders a size like "1.5 MB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
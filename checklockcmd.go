@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"scnpm/pkg/input"
+	"scnpm/pkg/lockfile"
+	"scnpm/pkg/output"
+	"scnpm/pkg/scanner"
+	"scnpm/pkg/types"
+	"scnpm/pkg/workspace"
+
+	"github.com/spf13/cobra"
+)
+
+// checkLockFormats are the formats scnpm check-lock reports in
+var checkLockFormats = []string{"table", "json", "sarif", "html", "github", "csv"}
+
+// checkLockOutput is the format of the consistency report
+var checkLockOutput string
+
+var checkLockCmd = &cobra.Command{
+	Use:   "check-lock",
+	Short: "Check that the lockfile matches package.json",
+	Long: `Compare the dependencies package.json declares with what the lockfile installs, as a stale
+lockfile installs something other than what was reviewed:
+
+  lock-missing  a declared dependency the lockfile doesn't install
+  lock-stale    a locked version outside the range package.json declares
+  lock-orphan   an installed package nothing declares or depends on, such as a dependency
+                removed from package.json but left in the lockfile
+
+package.json is read from the lockfile's directory. Workspaces it declares are checked against
+their section of the lockfile, or against their own package-lock.json. Ranges that aren't semver,
+such as dist-tags, git URLs and file: paths, aren't checked. The exit status is 1 when anything
+is reported.
+
+  scnpm check-lock -f package-lock.json
+  scnpm check-lock --output sarif --output-file lock.sarif`,
+	Args: cobra.NoArgs,
+	Run:  runCheckLock,
+}
+
+func init() {
+	checkLockCmd.Flags().StringVarP(&packageLockPath, "file", "f", "package-lock.json", "Path to package-lock.json, yarn.lock or pnpm-lock.yaml (use - for stdin)")
+	checkLockCmd.Flags().StringVarP(&checkLockOutput, "output", "o", "table", "Output format ("+strings.Join(checkLockFormats, ", ")+")")
+	checkLockCmd.Flags().StringVar(&outputFile, "output-file", "", "Write the report to this file instead of stdout")
+	completeLockfileFlags(checkLockCmd, checkLockFormats)
+	rootCmd.AddCommand(checkLockCmd)
+}
+
+func runCheckLock(cmd *cobra.Command, args []string) {
+	if !containsString(checkLockFormats, checkLockOutput) {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", checkLockOutput)
+		os.Exit(exitError)
+	}
+	packageLock, err := readPackageLock(packageLockPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading '%s': %v\n", packageLockPath, err)
+		os.Exit(exitError)
+	}
+	results, workspaces, err := checkLock(workspaceRoot(packageLockPath), packageLock)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	var report io.Writer = os.Stdout
+	var reportFile *os.File
+	if outputFile != "" {
+		reportFile, err = os.Create(outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+			os.Exit(exitError)
+		}
+		report = reportFile
+	}
+	color, _ := useColor("auto", report)
+	config := output.OutputConfig{
+		ShowWorkspaces: len(workspaces) > 0,
+		ToolVersion:    version,
+		ToolCommit:     commit,
+		Lockfile:       relativePath(packageLockPath),
+		ASCII:          asciiLocale(),
+		Color:          color,
+	}
+	switch checkLockOutput {
+	case "json":
+		err = output.OutputJSON(report, results, config)
+	case "sarif":
+		err = output.OutputSARIF(report, results, config)
+	case "html":
+		err = output.OutputHTML(report, results, config)
+	case "github":
+		err = output.OutputGitHub(report, results, config)
+	case "csv":
+		err = output.OutputCSV(report, results, config)
+	default:
+		err = output.OutputTable(report, results, config)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(exitError)
+	}
+	closeReport(reportFile)
+	for _, count := range output.CountResults(results).Checks {
+		if count > 0 {
+			os.Exit(exitFindings)
+		}
+	}
+}
+
+// checkLock checks the lockfile of the project in rootDir against its package.json and those of
+// its workspaces, returning the findings and the workspaces. Workspaces with a package-lock.json
+// of their own are checked against it, their findings located under the workspace's directory.
+func checkLock(rootDir string, packageLock *types.PackageLock) ([]types.ScanResult, []workspace.Workspace, error) {
+	root, err := readManifestDependencies(filepath.Join(rootDir, "package.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	workspaces, err := workspace.Discover(rootDir)
+	if err != nil && !errors.Is(err, workspace.ErrNoWorkspaces) {
+		return nil, nil, err
+	}
+
+	manifests := map[string]types.Package{"": root}
+	var separate []workspace.Workspace
+	for _, ws := range workspaces {
+		if ws.Lockfile != "" {
+			separate = append(separate, ws)
+			continue
+		}
+		manifests[ws.Dir], err = readManifestDependencies(filepath.Join(rootDir, filepath.FromSlash(ws.Dir), "package.json"))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	results := scanner.CheckLock(packageLock, manifests)
+	if len(workspaces) > 0 {
+		for i := range results {
+			for j := range results[i].Instances {
+				instance := &results[i].Instances[j]
+				instance.Workspace = workspace.Attribute(packageLock, workspaces, instance.Path)
+			}
+		}
+	}
+
+	for _, ws := range separate {
+		manifest, err := readManifestDependencies(filepath.Join(rootDir, filepath.FromSlash(ws.Dir), "package.json"))
+		if err != nil {
+			return nil, nil, err
+		}
+		wsLock, err := readPackageLock(ws.Lockfile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read lockfile of workspace '%s': %v", ws.Name, err)
+		}
+		wsResults := scanner.CheckLock(wsLock, map[string]types.Package{"": manifest})
+		workspace.Rebase(wsResults, ws)
+		results = append(results, wsResults...)
+	}
+	return results, workspaces, nil
+}
+
+// readManifestDependencies reads the dependency maps a package.json declares
+func readManifestDependencies(path string) (types.Package, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return types.Package{}, err
+	}
+	data, err = input.Normalize(data)
+	if err != nil {
+		return types.Package{}, fmt.Errorf("failed to decode %s: %v", path, err)
+	}
+	manifest, err := lockfile.ParseManifest(data)
+	if err != nil {
+		return types.Package{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return manifest.Packages[lockfile.ManifestPath], nil
+}
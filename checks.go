@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"scnpm/pkg/scanner"
+	"scnpm/pkg/scnpm"
+	"scnpm/pkg/typosquat"
+)
+
+// Check flags
+var (
+	sourcesMode       bool
+	typosquatMode     bool
+	typosquatDistance int
+	nearMatchMode     bool
+	requireIntegrity  bool
+	minIntegrityAlgo  string
+	detectScripts     bool
+	scanScripts       bool
+	nonRegistryMode   bool
+	flagLicenses      []string
+)
+
+func init() {
+	rootCmd.Flags().BoolVar(&sourcesMode, "sources", false, "Also list every package installed from somewhere other than the registry (local paths, links, git, remote tarballs)")
+	rootCmd.Flags().BoolVar(&nonRegistryMode, "non-registry", false, "Also list every package resolved from git or a remote tarball, with its URL, commit pinning and the packages that depend on it")
+	rootCmd.Flags().BoolVar(&typosquatMode, "typosquat", false, "Also flag installed packages whose names are a small edit away from a popular package (e.g. lodahs)")
+	rootCmd.Flags().BoolVar(&nearMatchMode, "near-match", false, "Also report installed packages whose names are 1-2 edits from a bad-package entry (e.g. evnet-stream), for triage")
+	rootCmd.Flags().BoolVar(&requireIntegrity, "require-integrity", false, "Also report every lockfile entry without an integrity hash, which npm can't verify at install time")
+	rootCmd.Flags().BoolVar(&detectScripts, "detect-scripts", false, "Also list every package that runs install scripts (hasInstallScript), where supply-chain malware usually executes")
+	rootCmd.Flags().BoolVar(&scanScripts, "scan-scripts", false, "Also check lifecycle scripts for suspicious commands (curl | sh, base64 eval, writes to ~/.ssh, env exfiltration); reads script bodies from node_modules next to the lockfile")
+	rootCmd.Flags().StringSliceVar(&flagLicenses, "flag-licenses", nil, "Also report packages whose license requires one of these SPDX identifiers (e.g. GPL-3.0,AGPL-3.0); honors --dev-only and --nested-only")
+	rootCmd.Flags().StringVar(&minIntegrityAlgo, "min-integrity-algo", "sha512", "Weakest integrity algorithm --require-integrity accepts (sha1, sha256, sha384, sha512)")
+	rootCmd.Flags().IntVar(&typosquatDistance, "typosquat-distance", typosquat.DefaultMaxDistance, "Largest edit distance --typosquat reports")
+}
+
+// validateCheckFlags rejects check settings that can't be honored
+func validateCheckFlags() error {
+	if scanner.IntegrityStrength(minIntegrityAlgo) == 0 {
+		return fmt.Errorf("--min-integrity-algo must be one of %s", strings.Join(scanner.IntegrityAlgorithms, ", "))
+	}
+	if err := validateLicenseIDs("--flag-licenses", flagLicenses); err != nil {
+		return err
+	}
+	if typosquatDistance < 1 {
+		return fmt.Errorf("--typosquat-distance must be at least 1")
+	}
+	return nil
+}
+
+// validateLicenseIDs rejects values of a license flag that aren't single SPDX identifiers
+func validateLicenseIDs(flag string, ids []string) error {
+	for _, id := range ids {
+		if strings.TrimSpace(id) == "" || strings.ContainsAny(id, "() ") {
+			return fmt.Errorf("%s takes SPDX license identifiers, not '%s'", flag, id)
+		}
+	}
+	return nil
+}
+
+// checkOptions returns the lockfile-wide checks enabled on the command line
+func checkOptions() scnpm.Checks {
+	return scnpm.Checks{
+		NearMatch:         nearMatchMode,
+		Typosquat:         typosquatMode,
+		TyposquatDistance: typosquatDistance,
+		ScanScripts:       scanScripts,
+		DetectScripts:     detectScripts,
+		FlagLicenses:      flagLicenses,
+		RequireIntegrity:  requireIntegrity,
+		MinIntegrityAlgo:  minIntegrityAlgo,
+		NonRegistry:       nonRegistryMode,
+		Sources:           sourcesMode,
+	}
+}
+
+// checksEnabled reports whether any lockfile-wide check was requested, which makes a scan
+// worthwhile even without bad-package queries
+func checksEnabled() bool {
+	return checkOptions().Enabled()
+}
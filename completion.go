@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"scnpm/pkg/scanner"
+
+	"github.com/spf13/cobra"
+)
+
+// completionShells are the shells scnpm completion writes scripts for
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// maxCompletionDepth bounds how deep under the current directory --file completion looks for
+// lockfiles, so that a tab in a large tree stays instant
+const maxCompletionDepth = 4
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish|powershell",
+	Short: "Generate the shell completion script",
+	Long: `Write the completion script for a shell to stdout. Besides subcommands and flags, it completes
+--output with the formats each command supports, --file with the lockfiles under the current
+directory and the package argument of scnpm why with the packages the lockfile installs.
+
+  # bash (needs the bash-completion package)
+  scnpm completion bash > /etc/bash_completion.d/scnpm
+
+  # zsh
+  scnpm completion zsh > "${fpath[1]}/_scnpm"
+
+  # fish
+  scnpm completion fish > ~/.config/fish/completions/scnpm.fish
+
+  # PowerShell
+  scnpm completion powershell | Out-String | Invoke-Expression`,
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs:             completionShells,
+	DisableFlagsInUseLine: true,
+	Run:                   runCompletion,
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+func runCompletion(cmd *cobra.Command, args []string) {
+	out := cmd.OutOrStdout()
+	var err error
+	switch args[0] {
+	case "bash":
+		err = rootCmd.GenBashCompletionV2(out, true)
+	case "zsh":
+		err = rootCmd.GenZshCompletion(out)
+	case "fish":
+		err = rootCmd.GenFishCompletion(out, true)
+	case "powershell":
+		err = rootCmd.GenPowerShellCompletionWithDesc(out)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing completion script: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+// completeLockfileFlags completes the -f/--file flag of cmd with lockfiles and its -o/--output
+// flag with formats
+func completeLockfileFlags(cmd *cobra.Command, formats []string) {
+	cmd.RegisterFlagCompletionFunc("file", completeLockfiles)
+	completeFlagValues(cmd, "output", formats)
+}
+
+// completeFlagValues completes the flag called name of cmd with values
+func completeFlagValues(cmd *cobra.Command, name string, values []string) {
+	cmd.RegisterFlagCompletionFunc(name, cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// completeLockfiles completes the lockfiles under the current directory whose path starts with
+// toComplete, outside node_modules and hidden directories. It falls back to the shell's file
+// completion when there are none, e.g. for archives or paths outside the tree.
+func completeLockfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var paths []string
+	filepath.WalkDir(".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			name := entry.Name()
+			if path != "." && (name == "node_modules" || strings.HasPrefix(name, ".") || strings.Count(path, string(filepath.Separator)) >= maxCompletionDepth-1) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		path = filepath.ToSlash(path)
+		if lockfileNames[entry.Name()] && strings.HasPrefix(path, toComplete) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if len(paths) == 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return paths, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePackageNames completes the first argument with the names of the packages installed by
+// the --file lockfile, which is only read when completion asks for them
+func completePackageNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 || packageLockPath == stdinPath {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	packageLock, err := readPackageLock(packageLockPath)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, instance := range scanner.Inventory(packageLock) {
+		if !seen[instance.Name] && strings.HasPrefix(instance.Name, toComplete) {
+			seen[instance.Name] = true
+			names = append(names, instance.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeLockfileArgs completes the first n arguments with lockfiles and the rest with files
+func completeLockfileArgs(n int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) < n {
+			return completeLockfiles(cmd, args, toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"scnpm/pkg/input"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileNames are the config files scnpm reads, in the order they're looked for
+var configFileNames = []string{".scnpm.yaml", ".scnpm.json"}
+
+// scanConfig is a config file: the values of scnpm's flags by their long name, as they'd be
+// given on the command line. Lists are kept whole for flags that take several values.
+type scanConfig struct {
+	Path   string
+	Values map[string][]string
+}
+
+// loadConfig reads the config file at path, YAML or JSON, and checks that every key names a flag
+// of cmd and every value parses as that flag's type
+func loadConfig(path string, cmd *cobra.Command) (*scanConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = input.Normalize(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %v", path, err)
+	}
+	// JSON is YAML too
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	config := &scanConfig{Path: path, Values: make(map[string][]string, len(document))}
+	keys := make([]string, 0, len(document))
+	for key := range document {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if env, ok := secretEnvs[key]; ok {
+			return nil, fmt.Errorf("%s: '%s' can only be set with $%s, which keeps the secret out of files", path, key, env)
+		}
+		flag := cmd.LocalFlags().Lookup(key)
+		if containsString(commandLineFlags, key) {
+			return nil, fmt.Errorf("%s: '%s' can only be given on the command line", path, key)
+		}
+		if flag == nil || key == "help" || key == "version" || key == "config" || key == "no-config" {
+			return nil, fmt.Errorf("%s: unknown option '%s'; keys are the long names of scnpm's flags", path, key)
+		}
+		values, err := configValues(flag.Value.Type(), document[key])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", path, key, err)
+		}
+		config.Values[key] = values
+	}
+	return config, nil
+}
+
+// configValues converts the config value of a flag of type flagType, as pflag names them, to the
+// values it would be given on the command line, checking that they parse as that type
+func configValues(flagType string, value interface{}) ([]string, error) {
+	var items []interface{}
+	switch value := value.(type) {
+	case nil:
+		return nil, fmt.Errorf("no value")
+	case []interface{}:
+		if flagType != "stringSlice" && flagType != "stringArray" {
+			return nil, fmt.Errorf("takes a single value, not a list")
+		}
+		items = value
+	case map[string]interface{}:
+		return nil, fmt.Errorf("expected a value, not a mapping")
+	default:
+		items = []interface{}{value}
+	}
+
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		switch item.(type) {
+		case []interface{}, map[string]interface{}, nil:
+			return nil, fmt.Errorf("expected a list of values")
+		}
+		value := fmt.Sprint(item)
+		var err error
+		switch flagType {
+		case "bool":
+			_, err = strconv.ParseBool(value)
+		case "int":
+			_, err = strconv.Atoi(value)
+		case "duration":
+			_, err = time.ParseDuration(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("'%s' isn't a valid %s", value, flagType)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// configPathFlags are the flags naming files, whose relative paths in a config file are relative
+// to the directory holding it rather than to wherever scnpm runs
+var configPathFlags = []string{"file", "packages-file", "osv-file", "ignore-file", "baseline", "format-file", "output-file", "summary-file"}
+
+// findConfig returns the config file nearest to dir: the first of configFileNames in dir or the
+// closest directory above it, or "" when there's none
+func findConfig(dir string) string {
+	for {
+		for _, name := range configFileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// applyConfig gives the flags of cmd that weren't set on the command line the values of the
+// config file: --config's, or the nearest one above the current directory unless --no-config.
+// Only the scan's own flags are taken, so subcommands with flags of the same name keep theirs.
+// It returns the config applied, nil when there's none.
+func applyConfig(cmd *cobra.Command) (*scanConfig, error) {
+	if noConfig {
+		if configFile != "" {
+			return nil, fmt.Errorf("--config and --no-config can't be used together")
+		}
+		return nil, nil
+	}
+	path := configFile
+	if path == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		if path = findConfig(dir); path == "" {
+			return nil, nil
+		}
+	}
+	root := cmd.Root()
+	config, err := loadConfig(path, root)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range config.Values {
+		flag := cmd.Flags().Lookup(key)
+		if flag == nil || flag != root.LocalFlags().Lookup(key) || flag.Changed {
+			continue
+		}
+		if containsString(configPathFlags, key) {
+			values = configPaths(filepath.Dir(path), values)
+		}
+		// Slices take the list whole; setting them item by item would split values on commas
+		if slice, ok := flag.Value.(interface{ Replace([]string) error }); ok {
+			err = slice.Replace(values)
+		} else {
+			err = flag.Value.Set(values[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", path, key, err)
+		}
+		flag.Changed = true
+		presetFlags[flag] = path
+	}
+	return config, nil
+}
+
+// configPaths resolves the relative paths among values against dir; "-" (stdin) stays as it is
+func configPaths(dir string, values []string) []string {
+	resolved := make([]string, len(values))
+	for i, value := range values {
+		if value == stdinPath || filepath.IsAbs(value) {
+			resolved[i] = value
+		} else {
+			resolved[i] = filepath.Join(dir, value)
+		}
+	}
+	return resolved
+}
+
+// envPrefix starts the environment variable of every flag: SCNPM_OUTPUT for the scan's --output
+const envPrefix = "SCNPM_"
+
+// commandLineFlags are only taken from the command line, never from the environment or a config
+// file: --watch runs every scan as a child process that inherits both, and would watch again
+var commandLineFlags = []string{"watch"}
+
+// secretEnvs are the environment variables holding secrets, by the flags that once took them.
+// Secrets are only read from the environment, never from the command line or config files.
+var secretEnvs = map[string]string{"notify-webhook": webhookURLEnv, "packages-url-auth": packagesURLAuthEnv}
+
+// flagEnv returns the environment variable of cmd's flag name: SCNPM_FAIL_ON_SEVERITY for the
+// scan's fail-on-severity, SCNPM_SERVE_LISTEN for serve's listen. Subcommands put their name in,
+// since flags such as --output take different values from command to command.
+func flagEnv(cmd *cobra.Command, name string) string {
+	words := append(strings.Fields(cmd.CommandPath())[1:], name)
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(strings.Join(words, "_"), "-", "_"))
+}
+
+// applyEnv gives the flags of cmd that weren't set on the command line the values of their
+// SCNPM_ environment variables, before applyConfig, so that they override the config file: the
+// scan's flags that cmd inherits, and cmd's own when it's a subcommand. Empty variables count as
+// unset.
+func applyEnv(cmd *cobra.Command) error {
+	root := cmd.Root()
+	if err := bindEnv(cmd, root, root.LocalFlags()); err != nil {
+		return err
+	}
+	if cmd == root {
+		return nil
+	}
+	return bindEnv(cmd, cmd, cmd.LocalFlags())
+}
+
+// bindEnv sets the flags of owner in flags that cmd takes from their environment variables
+func bindEnv(cmd, owner *cobra.Command, flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		env := flagEnv(owner, flag.Name)
+		value := os.Getenv(env)
+		if err != nil || value == "" || flag.Changed || flag.Name == "help" || flag.Name == "version" || containsString(commandLineFlags, flag.Name) || cmd.Flags().Lookup(flag.Name) != flag {
+			return
+		}
+		if setErr := flag.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("$%s: '%s' isn't a valid %s", env, value, flag.Value.Type())
+			return
+		}
+		flag.Changed = true
+		presetFlags[flag] = "$" + env
+	})
+	return err
+}
+
+// presetFlags are the flags applyEnv and applyConfig set, with the variable or config file each
+// value came from. They're Changed like the flags given on the command line, so that the lower
+// layers don't override them, but only the command line's conflict with one another.
+var presetFlags = make(map[*pflag.Flag]string)
+
+// fromCommandLine reports whether cmd's flag name was given on the command line, rather than
+// taken from the environment or a config file
+func fromCommandLine(cmd *cobra.Command, name string) bool {
+	flag := cmd.Flags().Lookup(name)
+	if flag == nil || !flag.Changed {
+		return false
+	}
+	_, preset := presetFlags[flag]
+	return !preset
+}
+
+// documentEnv names the environment variable of each flag of cmd and its subcommands in their
+// --help text
+func documentEnv(cmd *cobra.Command) {
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if containsString(commandLineFlags, flag.Name) {
+			return
+		}
+		flag.Usage += " [$" + flagEnv(cmd, flag.Name) + "]"
+	})
+	for _, sub := range cmd.Commands() {
+		documentEnv(sub)
+	}
+}
+
+// flagError explains that the flags which took secrets are gone in favor of their environment
+// variables; other flag errors stand as they are
+func flagError(cmd *cobra.Command, err error) error {
+	for flag, env := range secretEnvs {
+		if err.Error() == "unknown flag: --"+flag {
+			return fmt.Errorf("--%s was removed; set $%s instead, which keeps the secret out of shell history and process listings", flag, env)
+		}
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"scnpm/pkg/diff"
+	"scnpm/pkg/output"
+	"scnpm/pkg/scanner"
+	"scnpm/pkg/types"
+
+	"github.com/spf13/cobra"
+)
+
+// diffOutput is the format of the diff report: table or json
+var diffOutput string
+
+var diffCmd = &cobra.Command{
+	Use:   "diff OLD-LOCKFILE NEW-LOCKFILE [badpak.json | package@version ...]",
+	Short: "Compare two lockfiles and report the risks the new one introduces",
+	Long: `Scan two lockfiles, such as the base and head of a pull request, and report the findings
+only the new one has (introduced), only the old one has (removed) and both have (pre-existing).
+Every package whose version or integrity changed is listed too, whatever the bad-package lists
+say: a new integrity at the same version means the tarball was republished, a classic sign of
+a compromised package.
+
+Bad packages come from the lists and package@version arguments after the lockfiles, --packages-file,
+--packages and the built-in database, as for a scan. The exit status is 1 only when the new
+lockfile introduced risks.
+
+  scnpm diff base/package-lock.json package-lock.json badpak.json`,
+	Args:              cobra.MinimumNArgs(2),
+	ValidArgsFunction: completeLockfileArgs(2),
+	Run:               runDiff,
+}
+
+func init() {
+	diffCmd.Flags().StringSliceVar(&packagesFiles, "packages-file", []string{}, "Path to a file (or directory of files) listing bad packages to scan for; repeat to merge several lists")
+	diffCmd.Flags().StringSliceVarP(&packagesFlag, "packages", "p", []string{}, "List of packages to scan for (format: package@version, or a bare name for any version)")
+	diffCmd.Flags().BoolVar(&noBuiltin, "no-builtin", false, "Don't scan for the packages in the built-in advisory database")
+	diffCmd.Flags().StringVarP(&diffOutput, "output", "o", "table", "Output format (table, json)")
+	completeFlagValues(diffCmd, "output", []string{"table", "json"})
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) {
+	if diffOutput != "table" && diffOutput != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", diffOutput)
+		os.Exit(exitError)
+	}
+	queries, warnings, err := loadQueries(cmd.Context(), args[2:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	var results [2][]types.ScanResult
+	var inventories [2][]types.PackageInstance
+	for i, path := range args[:2] {
+		packageLock, err := readPackageLock(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading '%s': %v\n", path, err)
+			os.Exit(exitError)
+		}
+		results[i] = scanner.ExpandPatterns(scanner.ScanPackages(packageLock, queries, scanner.FilterConfig{}))
+		inventories[i] = scanner.Inventory(packageLock)
+	}
+	result := diff.Compare(results[0], results[1], inventories[0], inventories[1])
+	result.Old, result.New = args[0], args[1]
+
+	printWarnings(warnings)
+	if diffOutput == "json" {
+		err = output.OutputDiffJSON(os.Stdout, result)
+	} else {
+		color, _ := useColor("auto", os.Stdout)
+		err = output.OutputDiff(os.Stdout, result, output.OutputConfig{Color: color, ASCII: asciiLocale()})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(exitError)
+	}
+	if len(result.Introduced) > 0 {
+		os.Exit(exitFindings)
+	}
+}
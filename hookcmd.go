@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// hookTypes are the git hooks scnpm hook installs
+var hookTypes = []string{"pre-commit", "pre-push"}
+
+// hookMarker identifies the hooks scnpm installed, so that they're replaced and removed but
+// others aren't
+const hookMarker = "# Installed by scnpm hook install"
+
+// chainedSuffix is appended to the name of a hook that was there before scnpm's, which runs it
+// first
+const chainedSuffix = ".scnpm-chained"
+
+// zeroSHA is the object name git gives a ref that doesn't exist, such as a branch a push deletes
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+// Options of scnpm hook
+var (
+	hookType  string
+	hookForce bool
+	hookPrint bool
+)
+
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Install a git hook that scans lockfiles before they're committed or pushed",
+	Long: `Install a pre-commit or pre-push git hook that scans the lockfiles of each commit for known-bad
+packages and stops the commit or push when it finds one (or can't scan). git commit --no-verify
+and git push --no-verify skip it.
+
+  scnpm hook install
+  scnpm hook install --type pre-push -- --packages-url https://example.com/badpak.json
+  scnpm hook uninstall`,
+}
+
+var hookInstallCmd = &cobra.Command{
+	Use:   "install [-- SCAN-FLAGS...]",
+	Short: "Install the git hook",
+	Long: `Write a hook into the repository's hooks directory that runs scnpm hook run. Flags after --
+are passed to every scan, such as the team's --packages-url or --packages-file; without them the
+built-in database is used. scnpm has to be on the PATH when the hook runs.
+
+A hook that's already there is kept and run before scnpm's, unless --force replaces it. --print
+writes the command for hook managers such as husky or pre-commit instead of installing anything.`,
+	Run: runHookInstall,
+}
+
+var hookUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the git hook, restoring the hook it chained",
+	Args:  cobra.NoArgs,
+	Run:   runHookUninstall,
+}
+
+var hookRunCmd = &cobra.Command{
+	Use:   "run [-- SCAN-FLAGS...]",
+	Short: "Scan the lockfiles a commit or push changes (run by the hook)",
+	Long: `Scan every lockfile the commit stages (pre-commit), or that the pushed commits change (pre-push),
+as git has it rather than as it's on disk. Flags after -- are passed to each scan. The refs
+being pushed are read from stdin, as git gives them to a pre-push hook; without them the
+lockfiles of HEAD are scanned. The exit status is that of the first scan to fail.`,
+	Run: runHookRun,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{hookInstallCmd, hookUninstallCmd, hookRunCmd} {
+		cmd.Flags().StringVar(&hookType, "type", "pre-commit", "Hook to use: "+strings.Join(hookTypes, " or "))
+		completeFlagValues(cmd, "type", hookTypes)
+		hookCmd.AddCommand(cmd)
+	}
+	hookInstallCmd.Flags().BoolVar(&hookForce, "force", false, "Replace an existing hook instead of running it before scnpm's")
+	hookInstallCmd.Flags().BoolVar(&hookPrint, "print", false, "Print the command for husky or the pre-commit framework instead of installing a hook")
+	rootCmd.AddCommand(hookCmd)
+}
+
+// checkHookType exits when --type isn't a hook scnpm installs
+func checkHookType() {
+	if !containsString(hookTypes, hookType) {
+		fmt.Fprintf(os.Stderr, "Error: --type must be %s, got '%s'\n", strings.Join(hookTypes, " or "), hookType)
+		os.Exit(exitError)
+	}
+}
+
+func runHookInstall(cmd *cobra.Command, args []string) {
+	checkHookType()
+	command := hookCommand(args)
+	if hookPrint {
+		fmt.Printf(`# husky (.husky/%s) or any hook manager that runs shell commands:
+%s
+
+# pre-commit (.pre-commit-config.yaml):
+repos:
+  - repo: local
+    hooks:
+      - id: scnpm
+        name: scnpm
+        entry: %s
+        language: system
+        pass_filenames: false
+        always_run: true
+        stages: [%s]
+`, hookType, command, command, hookType)
+		return
+	}
+
+	hookPath, err := gitHookPath(hookType)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if existing, err := os.ReadFile(hookPath); err == nil && !bytes.Contains(existing, []byte(hookMarker)) && !hookForce {
+		// Run the hook that's there before scnpm's
+		if _, err := os.Stat(hookPath + chainedSuffix); err == nil {
+			fmt.Fprintf(os.Stderr, "Error: %s and %s both exist; pass --force to replace the first\n", hookPath, hookPath+chainedSuffix)
+			os.Exit(exitError)
+		}
+		if err := os.Rename(hookPath, hookPath+chainedSuffix); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		fmt.Printf("Moved the existing hook to %s; it runs before scnpm\n", hookPath+chainedSuffix)
+	}
+	if err := os.MkdirAll(filepath.Dir(hookPath), 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if err := os.WriteFile(hookPath, []byte(hookScript(hookType, command)), 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	// WriteFile keeps the mode of a file it replaces
+	if err := os.Chmod(hookPath, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	fmt.Printf("Installed %s\n", hookPath)
+}
+
+func runHookUninstall(cmd *cobra.Command, args []string) {
+	checkHookType()
+	hookPath, err := gitHookPath(hookType)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	existing, err := os.ReadFile(hookPath)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "Error: there's no %s hook\n", hookType)
+		os.Exit(exitError)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if !bytes.Contains(existing, []byte(hookMarker)) {
+		fmt.Fprintf(os.Stderr, "Error: %s wasn't installed by scnpm; leaving it alone\n", hookPath)
+		os.Exit(exitError)
+	}
+	if err := os.Remove(hookPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	fmt.Printf("Removed %s\n", hookPath)
+	if _, err := os.Stat(hookPath + chainedSuffix); err == nil {
+		if err := os.Rename(hookPath+chainedSuffix, hookPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		fmt.Printf("Restored the hook it ran before scnpm\n")
+	}
+}
+
+// hookCommand returns the command line of scnpm hook run with the scan flags args, quoted for sh
+func hookCommand(args []string) string {
+	command := "scnpm hook run --type " + hookType
+	if len(args) > 0 {
+		command += " --"
+		for _, arg := range args {
+			command += " " + shellQuote(arg)
+		}
+	}
+	return command
+}
+
+// shellQuote quotes s for sh when it has anything but letters, digits and a few safe symbols
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=.,/:@+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// hookScript returns the hook that runs command, after the hook it chained when there's one. A
+// pre-push hook reads the pushed refs from stdin, so both hooks are given a copy.
+func hookScript(hookType, command string) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "%s: scans the lockfiles of each %s for known-bad packages.\n", hookMarker, strings.TrimPrefix(hookType, "pre-"))
+	fmt.Fprintf(&b, "# Skip it once with --no-verify, or remove it with scnpm hook uninstall --type %s.\n", hookType)
+	if hookType == "pre-push" {
+		b.WriteString("refs=$(cat)\n")
+		fmt.Fprintf(&b, "if [ -x \"$0%s\" ]; then\n", chainedSuffix)
+		fmt.Fprintf(&b, "\tprintf '%%s\\n' \"$refs\" | \"$0%s\" \"$@\" || exit $?\n", chainedSuffix)
+	} else {
+		fmt.Fprintf(&b, "if [ -x \"$0%s\" ]; then\n", chainedSuffix)
+		fmt.Fprintf(&b, "\t\"$0%s\" \"$@\" || exit $?\n", chainedSuffix)
+	}
+	b.WriteString("fi\n")
+	b.WriteString("if ! command -v scnpm >/dev/null 2>&1; then\n")
+	b.WriteString("\techo \"scnpm isn't on the PATH; the lockfiles weren't scanned\" >&2\n")
+	b.WriteString("\texit 0\n")
+	b.WriteString("fi\n")
+	if hookType == "pre-push" {
+		fmt.Fprintf(&b, "printf '%%s\\n' \"$refs\" | %s\n", command)
+	} else {
+		fmt.Fprintf(&b, "exec %s\n", command)
+	}
+	return b.String()
+}
+
+// gitHookPath returns the path of a hook of the repository in the working directory, in the
+// hooks directory git uses (core.hooksPath or .git/hooks)
+func gitHookPath(hookType string) (string, error) {
+	hooks, err := git(".", "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", fmt.Errorf("not in a git repository: %v", err)
+	}
+	return filepath.Join(strings.TrimSpace(hooks), hookType), nil
+}
+
+func runHookRun(cmd *cobra.Command, args []string) {
+	checkHookType()
+	root, err := git(".", "rev-parse", "--show-toplevel")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: not in a git repository: %v\n", err)
+		os.Exit(exitError)
+	}
+	root = strings.TrimSpace(root)
+
+	var revisions []string // Lockfiles to scan as "<tree-ish>:<path>"
+	if hookType == "pre-commit" {
+		revisions, err = stagedLockfiles(root)
+	} else {
+		revisions, err = pushedLockfiles(root)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	status := exitClean
+	for _, revision := range revisions {
+		data, err := git(root, "show", revision)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		display := revision
+		if i := strings.Index(revision, ":"); i > 12 {
+			display = revision[:12] + revision[i:]
+		}
+		fmt.Fprintf(os.Stderr, "scnpm: scanning %s\n", display)
+		child := exec.Command(executable, append(append([]string{"--risk-only"}, args...), "--file", stdinPath)...)
+		child.Dir = root
+		child.Stdin = strings.NewReader(data)
+		child.Stdout, child.Stderr = os.Stdout, os.Stderr
+		err = child.Run()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if status == exitClean {
+				status = exitErr.ExitCode()
+			}
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	if status != exitClean {
+		fmt.Fprintf(os.Stderr, "scnpm: stopping the %s; fix the lockfiles above or skip the check with --no-verify\n", strings.TrimPrefix(hookType, "pre-"))
+		os.Exit(status)
+	}
+}
+
+// isLockfilePath reports whether a path of the repository is a lockfile scnpm scans, outside
+// node_modules
+func isLockfilePath(p string) bool {
+	return lockfileNames[path.Base(p)] && !strings.Contains("/"+p, "/node_modules/")
+}
+
+// stagedLockfiles returns the lockfiles the index adds or changes, as ":<path>"
+func stagedLockfiles(root string) ([]string, error) {
+	out, err := git(root, "diff", "--cached", "--name-only", "--diff-filter=ACMR", "-z")
+	if err != nil {
+		return nil, err
+	}
+	var revisions []string
+	for _, p := range strings.Split(out, "\x00") {
+		if p != "" && isLockfilePath(p) {
+			revisions = append(revisions, ":"+p)
+		}
+	}
+	return revisions, nil
+}
+
+// pushedLockfiles returns the lockfiles the pushed commits change, as "<commit>:<path>", reading
+// the refs being pushed from stdin the way git gives them to a pre-push hook ("<local ref>
+// <local sha> <remote ref> <remote sha>" lines). Every lockfile of a new branch is scanned, and
+// those of HEAD when stdin has no refs.
+func pushedLockfiles(root string) ([]string, error) {
+	type push struct{ local, remote string }
+	var pushes []push
+	if !isTerminal(os.Stdin) {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 4 && fields[1] != zeroSHA {
+				pushes = append(pushes, push{local: fields[1], remote: fields[3]})
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if len(pushes) == 0 {
+		pushes = append(pushes, push{local: "HEAD", remote: zeroSHA})
+	}
+
+	seen := make(map[string]bool)
+	var revisions []string
+	for _, p := range pushes {
+		var out string
+		var err error
+		if p.remote != zeroSHA {
+			out, err = git(root, "diff", "--name-only", "--diff-filter=ACMR", "-z", p.remote, p.local)
+		}
+		if p.remote == zeroSHA || err != nil {
+			// A new branch, or the remote commit isn't here to compare with
+			out, err = git(root, "ls-tree", "-r", "--name-only", "-z", p.local)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, file := range strings.Split(out, "\x00") {
+			revision := p.local + ":" + file
+			if file != "" && isLockfilePath(file) && !seen[revision] {
+				seen[revision] = true
+				revisions = append(revisions, revision)
+			}
+		}
+	}
+	return revisions, nil
+}
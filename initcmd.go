@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"scnpm/pkg/types"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Options of scnpm init, which become the values of the config file it writes
+var (
+	initLockfile       string
+	initOutput         string
+	initFailOnSeverity string
+	initPackagesURL    string
+	initPackagesFiles  []string
+	initForce          bool
+	initYes            bool
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write a starter .scnpm.yaml config file",
+	Long: `Write a commented .scnpm.yaml in the current directory with the lockfile to scan, the output
+format, the severity that fails the scan and where the team's bad-package list lives. Every key
+of the file is the long name of a scnpm flag, so any other flag can be added to it later.
+
+When stdin is a terminal, init asks for each option the flags don't give, offering the flag's
+value as the default; --yes takes the flags and defaults as they are. The file is checked by
+loading it back before it's written. An existing config is only replaced with --force.
+
+  scnpm init
+  scnpm init --yes --lockfile yarn.lock --fail-on-severity high --packages-url https://example.com/badpak.json`,
+	Args: cobra.NoArgs,
+	Run:  runInit,
+}
+
+func init() {
+	initCmd.Flags().StringVar(&initLockfile, "lockfile", "", "Lockfile to scan (default: the lockfile in the current directory, or package-lock.json)")
+	initCmd.Flags().StringVar(&initOutput, "output", "table", "Default output format ("+strings.Join(outputFormats, ", ")+")")
+	initCmd.Flags().StringVar(&initFailOnSeverity, "fail-on-severity", "", "Only fail for risks at or above this severity (critical, high, moderate, low)")
+	initCmd.Flags().StringVar(&initPackagesURL, "packages-url", "", "HTTPS URL of the team's bad-package list")
+	initCmd.Flags().StringSliceVar(&initPackagesFiles, "packages-file", nil, "Path to the team's bad-package list in the repository")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "Replace an existing config file")
+	initCmd.Flags().BoolVarP(&initYes, "yes", "y", false, "Don't ask; take the flags and defaults as they are")
+	completeFlagValues(initCmd, "output", outputFormats)
+	completeFlagValues(initCmd, "fail-on-severity", types.Severities)
+	rootCmd.AddCommand(initCmd)
+}
+
+func runInit(cmd *cobra.Command, args []string) {
+	path := configFileNames[0]
+	if _, err := os.Stat(path); err == nil && !initForce {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists; pass --force to replace it\n", path)
+		os.Exit(exitError)
+	}
+	if initLockfile == "" {
+		initLockfile = detectLockfile(".")
+	}
+
+	if !initYes && isTerminal(os.Stdin) {
+		prompt := newPrompter(os.Stdin, os.Stdout)
+		for _, option := range []struct {
+			flag     string
+			question string
+			value    *string
+		}{
+			{"lockfile", "Lockfile to scan", &initLockfile},
+			{"output", "Output format (" + strings.Join(outputFormats, ", ") + ")", &initOutput},
+			{"fail-on-severity", "Lowest severity that fails the scan (critical, high, moderate, low; empty for any)", &initFailOnSeverity},
+			{"packages-url", "HTTPS URL of the team's bad-package list (empty for none)", &initPackagesURL},
+		} {
+			if !cmd.Flags().Changed(option.flag) {
+				*option.value = prompt.ask(option.question, *option.value)
+			}
+		}
+		if !cmd.Flags().Changed("packages-file") && initPackagesURL == "" {
+			if file := prompt.ask("Path to the team's bad-package list in the repository (empty for none)", ""); file != "" {
+				initPackagesFiles = []string{file}
+			}
+		}
+		if prompt.err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", prompt.err)
+			os.Exit(exitError)
+		}
+	}
+
+	if !containsString(outputFormats, initOutput) {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", initOutput)
+		os.Exit(exitError)
+	}
+	if initFailOnSeverity != "" {
+		severity, ok := types.NormalizeSeverity(initFailOnSeverity)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: --fail-on-severity must be one of %s\n", strings.Join(types.Severities, ", "))
+			os.Exit(exitError)
+		}
+		initFailOnSeverity = severity
+	}
+	if initPackagesURL != "" {
+		if u, err := url.Parse(initPackagesURL); err != nil || u.Scheme != "https" {
+			fmt.Fprintf(os.Stderr, "Error: --packages-url '%s' isn't an https URL\n", initPackagesURL)
+			os.Exit(exitError)
+		}
+	}
+
+	if err := writeConfig(path, starterConfig()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	fmt.Printf("Wrote %s\n", path)
+}
+
+// detectLockfile returns the name of the first lockfile found in dir, or package-lock.json
+func detectLockfile(dir string) string {
+	for _, name := range []string{"package-lock.json", "npm-shrinkwrap.json", "pnpm-lock.yaml", "yarn.lock"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return name
+		}
+	}
+	return "package-lock.json"
+}
+
+// starterConfig renders the config file of scnpm init's options, with a comment on each option.
+// Options left unset are written commented out, as examples.
+func starterConfig() string {
+	var b strings.Builder
+	b.WriteString("# scnpm configuration. Every key is the long name of a scnpm flag; run `scnpm --help` for\n")
+	b.WriteString("# the full list.\n")
+
+	option := func(comment, key, value, example string) {
+		fmt.Fprintf(&b, "\n# %s\n", comment)
+		if value == "" {
+			fmt.Fprintf(&b, "# %s: %s\n", key, example)
+		} else {
+			fmt.Fprintf(&b, "%s: %s\n", key, yamlScalar(value))
+		}
+	}
+	option("Lockfile to scan: package-lock.json, npm-shrinkwrap.json, yarn.lock or pnpm-lock.yaml (--file)",
+		"file", initLockfile, "package-lock.json")
+	option("Report format: "+strings.Join(outputFormats, ", ")+" (--output)",
+		"output", initOutput, "table")
+	option("Only fail the scan for risks at or above this severity: critical, high, moderate or low. Risks\n"+
+		"# without a severity don't count unless default-severity gives them one (--fail-on-severity)",
+		"fail-on-severity", initFailOnSeverity, "high")
+	option("The team's bad-package list, fetched over HTTPS on every scan; its Authorization header\n"+
+		"# comes from $"+packagesURLAuthEnv+" (--packages-url)",
+		"packages-url", initPackagesURL, "https://example.com/badpak.json")
+
+	fmt.Fprintf(&b, "\n# Bad-package lists kept in the repository, merged with the built-in database (--packages-file)\n")
+	if len(initPackagesFiles) == 0 {
+		b.WriteString("# packages-file:\n#   - badpak.json\n")
+	} else {
+		b.WriteString("packages-file:\n")
+		for _, file := range initPackagesFiles {
+			fmt.Fprintf(&b, "  - %s\n", yamlScalar(file))
+		}
+	}
+	return b.String()
+}
+
+// yamlScalar returns value as a YAML scalar, quoted when it would otherwise read as something else
+func yamlScalar(value string) string {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%q", value)
+	}
+	return strings.TrimSuffix(string(data), "\n")
+}
+
+// writeConfig writes a config file at path after loading it back through loadConfig from a
+// temporary file next to it, so that an invalid config never replaces a working one
+func writeConfig(path, content string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if _, err := loadConfig(tmp.Name(), rootCmd); err != nil {
+		return fmt.Errorf("the generated config doesn't load: %v", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// prompter asks questions on a terminal; the first error reading an answer is kept in err, and
+// later questions take their defaults
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	err error
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{in: bufio.NewReader(in), out: out}
+}
+
+// ask prints question with its default and returns the answer, or the default when it's empty
+func (p *prompter) ask(question, defaultValue string) string {
+	if p.err != nil {
+		return defaultValue
+	}
+	if defaultValue != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	answer, err := p.in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		p.err = err
+		return defaultValue
+	}
+	if answer = strings.TrimSpace(answer); answer != "" {
+		return answer
+	}
+	return defaultValue
+}
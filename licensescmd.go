@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"scnpm/pkg/output"
+	"scnpm/pkg/scanner"
+
+	"github.com/spf13/cobra"
+)
+
+// License inventory flags
+var (
+	licensesOutput     string
+	denyLicenses       []string
+	licensesProduction bool
+)
+
+var licensesCmd = &cobra.Command{
+	Use:   "licenses",
+	Short: "Print the licenses of the packages a lockfile installs",
+	Long: `Group the packages a lockfile installs by the license their lockfile entry records: each
+license with how many packages use it and which. Packages without a license, or with UNKNOWN,
+are flagged. Every name@version counts once, however many copies are installed.
+
+--deny names licenses the project can't accept. Licenses are SPDX expressions, so
+"(MIT OR GPL-3.0)" leaves a choice and is only denied when every alternative is, while
+"MIT AND GPL-3.0" requires both; a denied GPL-3.0 also covers GPL-3.0-only, -or-later and +.
+The exit status is 1 when a package's license is denied.
+
+lockfileVersion 1 lockfiles and yarn.lock don't record licenses.
+
+  scnpm licenses -f package-lock.json
+  scnpm licenses --production --deny GPL-3.0-only,AGPL-3.0 --output csv > licenses.csv`,
+	Args: cobra.NoArgs,
+	Run:  runLicenses,
+}
+
+func init() {
+	licensesCmd.Flags().StringVarP(&packageLockPath, "file", "f", "package-lock.json", "Path to package-lock.json, yarn.lock or pnpm-lock.yaml (use - for stdin)")
+	licensesCmd.Flags().StringVarP(&licensesOutput, "output", "o", "table", "Output format ("+strings.Join(output.LicenseFormats, ", ")+")")
+	licensesCmd.Flags().StringSliceVar(&denyLicenses, "deny", nil, "Fail when a package's license requires one of these SPDX identifiers (e.g. GPL-3.0-only,AGPL-3.0)")
+	licensesCmd.Flags().BoolVar(&licensesProduction, "production", false, "Leave out packages only needed in development")
+	completeLockfileFlags(licensesCmd, output.LicenseFormats)
+	rootCmd.AddCommand(licensesCmd)
+}
+
+func runLicenses(cmd *cobra.Command, args []string) {
+	if !containsString(output.LicenseFormats, licensesOutput) {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", licensesOutput)
+		os.Exit(exitError)
+	}
+	if err := validateLicenseIDs("--deny", denyLicenses); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	packageLock, err := readPackageLock(packageLockPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading '%s': %v\n", packageLockPath, err)
+		os.Exit(exitError)
+	}
+
+	instances := scanner.List(packageLock, scanner.FilterConfig{})
+	if licensesProduction {
+		production := instances[:0]
+		for _, instance := range instances {
+			if !instance.IsDev {
+				production = append(production, instance)
+			}
+		}
+		instances = production
+	}
+	inventory := scanner.Licenses(instances, denyLicenses)
+	if inventory.Packages > 0 && inventory.Unknown == inventory.Packages {
+		printWarnings([]string{fmt.Sprintf("'%s' records no licenses, so every package is %s; regenerate it with npm 7 or later", relativePath(packageLockPath), scanner.UnknownLicense)})
+	}
+
+	switch licensesOutput {
+	case "json":
+		err = output.OutputLicensesJSON(os.Stdout, inventory, relativePath(packageLockPath))
+	case "csv":
+		err = output.OutputLicensesCSV(os.Stdout, inventory)
+	default:
+		color, _ := useColor("auto", os.Stdout)
+		err = output.OutputLicenses(os.Stdout, inventory, output.OutputConfig{Color: color, ASCII: asciiLocale()})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(exitError)
+	}
+	if inventory.Denied > 0 {
+		os.Exit(exitFindings)
+	}
+}
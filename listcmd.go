@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"scnpm/pkg/output"
+	"scnpm/pkg/scanner"
+
+	"github.com/spf13/cobra"
+)
+
+// listOutput is the format of the list: table, json or csv
+var listOutput string
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List every package installed by a lockfile",
+	Long: `Print an inventory of the lockfile: every installed package with its version, install path,
+whether it's a dev dependency, its nesting depth, the host it was resolved from and its license.
+Nothing is matched against bad-package lists; --dev-only, --nested-only, --min-depth and
+--max-depth narrow the list as they narrow a scan.
+
+  scnpm list -f package-lock.json
+  scnpm list --dev-only --output csv > dev-packages.csv`,
+	Args: cobra.NoArgs,
+	Run:  runList,
+}
+
+func init() {
+	listCmd.Flags().StringVarP(&packageLockPath, "file", "f", "package-lock.json", "Path to package-lock.json, yarn.lock or pnpm-lock.yaml (use - for stdin)")
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "table", "Output format ("+strings.Join(output.ListFormats, ", ")+")")
+	listCmd.Flags().BoolVar(&showDevOnly, "dev-only", false, "List only development dependencies")
+	listCmd.Flags().BoolVar(&showNestedOnly, "nested-only", false, "List only nested dependencies")
+	listCmd.Flags().IntVar(&minDepth, "min-depth", 0, "Minimum nesting depth to list")
+	listCmd.Flags().IntVar(&maxDepth, "max-depth", unlimitedDepth, "Maximum nesting depth to list (0 for direct dependencies only, -1 for no limit)")
+	completeLockfileFlags(listCmd, output.ListFormats)
+	rootCmd.AddCommand(listCmd)
+}
+
+func runList(cmd *cobra.Command, args []string) {
+	if !containsString(output.ListFormats, listOutput) {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", listOutput)
+		os.Exit(exitError)
+	}
+	if maxDepth < unlimitedDepth {
+		fmt.Fprintf(os.Stderr, "Error: --max-depth must be %d (no limit) or more\n", unlimitedDepth)
+		os.Exit(exitError)
+	}
+	config := scanner.FilterConfig{ShowDevOnly: showDevOnly, ShowNestedOnly: showNestedOnly, MinDepth: minDepth}
+	if maxDepth != unlimitedDepth {
+		config.MaxDepth = &maxDepth
+	}
+
+	packageLock, err := readPackageLock(packageLockPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading '%s': %v\n", packageLockPath, err)
+		os.Exit(exitError)
+	}
+	if warning := depthBoundsWarning(config); warning != "" {
+		printWarnings([]string{warning})
+	}
+	instances := scanner.List(packageLock, config)
+
+	switch listOutput {
+	case "json":
+		err = output.OutputListJSON(os.Stdout, instances, relativePath(packageLockPath))
+	case "csv":
+		err = output.OutputListCSV(os.Stdout, instances)
+	default:
+		err = output.OutputList(os.Stdout, instances)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing list: %v\n", err)
+		os.Exit(exitError)
+	}
+}
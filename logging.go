@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"scnpm/pkg/types"
+
+	"github.com/spf13/cobra"
+)
+
+// logLevels are the values --log-level accepts
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// logFormats are the values --log-format accepts
+var logFormats = []string{"text", "json"}
+
+// setupLogging installs the default logger from --log-level and --log-format before any command
+// runs. Logs go to stderr, so that they never mix with a report on stdout. --verbose is
+// --log-level info.
+func setupLogging(cmd *cobra.Command, args []string) {
+	level := logLevel
+	if verbose && !cmd.Flags().Changed("log-level") {
+		level = "info"
+	}
+	logger, err := newLogger(os.Stderr, level, logFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	slog.SetDefault(logger)
+}
+
+// newLogger returns a logger writing records at or above level to w, as text or JSON lines
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	minimum, ok := logLevels[strings.ToLower(level)]
+	if !ok {
+		return nil, fmt.Errorf("--log-level must be one of debug, info, warn, error; got '%s'", level)
+	}
+	options := &slog.HandlerOptions{Level: minimum}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("--log-format must be one of %s; got '%s'", strings.Join(logFormats, ", "), format)
+	}
+}
+
+// logVerbose logs a diagnostic about the loaded inputs at info level, which --verbose enables
+func logVerbose(format string, args ...interface{}) {
+	slog.Info(fmt.Sprintf(format, args...))
+}
+
+// logPhase logs at debug level how long a phase of the scan took
+func logPhase(phase string, start time.Time) {
+	slog.Debug("phase finished", "phase", phase, "duration", time.Since(start))
+}
+
+// logQueries logs every query at debug level, with the lists it came from
+func logQueries(queries []types.PackageQuery) {
+	for _, query := range queries {
+		slog.Debug("query", "name", query.Name, "version", query.Version, "source", query.Source, "sources", query.Sources)
+	}
+}
+
+// logLockfile logs at debug level what a parsed lockfile contains
+func logLockfile(name string, packageLock *types.PackageLock) {
+	slog.Debug("lockfile loaded", "path", name, "lockfileVersion", packageLock.LockfileVersion,
+		"packages", len(packageLock.Packages), "dependencies", len(packageLock.Dependencies))
+}
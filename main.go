@@ -1,14 +1,28 @@
-	package main
+package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"text/template"
+	"time"
 
+	"scnpm/pkg/audit"
+	"scnpm/pkg/baseline"
+	"scnpm/pkg/cache"
+	"scnpm/pkg/ignore"
+	"scnpm/pkg/input"
 	"scnpm/pkg/output"
 	"scnpm/pkg/scanner"
+	"scnpm/pkg/scnpm"
 	"scnpm/pkg/types"
 
 	"github.com/spf13/cobra"
@@ -21,6 +35,37 @@ var (
 	date    = "unknown"
 )
 
+// stdinPath is the --file value that reads the lockfile from standard input
+const stdinPath = "-"
+
+// outputFormats are the values --output accepts
+var outputFormats = []string{"table", "json", "ndjson", "sarif", "html", "cyclonedx", "spdx", "github"}
+
+// summaryFormats are the output formats --summary-only can cut down to the summary
+var summaryFormats = []string{"table", "json", "ndjson", "github"}
+
+// countFormats are the output formats --count-only can cut down to the counts
+var countFormats = []string{"table", "json"}
+
+// stepSummaryEnv names the file GitHub Actions renders as the job summary
+const stepSummaryEnv = "GITHUB_STEP_SUMMARY"
+
+// templateFormat is the output format of --format and --format-file
+const templateFormat = "template"
+
+// cliSource is the query source recorded for packages given with --packages or as arguments
+const cliSource = "cli"
+
+// Exit codes: a scan that runs to the end exits exitClean or exitFindings, depending on
+// --fail-on-found, --fail-on-severity and --max-risks; anything that stops it exits exitError,
+// except for an interrupt or --timeout, which exit exitInterrupted after reporting what was
+// scanned so far
+const (
+	exitClean       = 0
+	exitFindings    = 1
+	exitError       = 2
+	exitInterrupted = 3
+)
 
 var rootCmd = &cobra.Command{
 	Use:     "scnpm [badpak.json]",
@@ -31,46 +76,188 @@ This tool helps identify packages that may have been affected by malware, supply
 or other security vulnerabilities. Finding packages indicates potential security risks.
 
 Usage examples:
+  scnpm                                                  # Scan against the built-in advisory database
   scnpm badpak.json                                      # Scan bad packages from JSON file
   scnpm --file /path/to/package-lock.json badpak.json   # Custom package-lock path
   scnpm --packages-file /path/to/badpak.json            # Alternative flag syntax with path
   scnpm --file ~/project/package-lock.json ~/lists/badpak.json  # Files from different directories
-  scnpm package@1.0.0 another@2.0.0                      # Direct package arguments`,
-	Run: runScan,
+  scnpm package@1.0.0 another@2.0.0                      # Direct package arguments
+
+Exit status: 0 when no risk fails the scan, 1 when one does (see --fail-on-found,
+--fail-on-severity and --max-risks), 2 when the scan couldn't run (bad flags or input), 3 when
+it was interrupted (Ctrl-C or --timeout) and the results it reports are partial.`,
+	// Anything that isn't a subcommand is a packages file or package@version
+	Args:             cobra.ArbitraryArgs,
+	PersistentPreRun: setup,
+	Run:              runScan,
 }
 
 var (
-	packageLockPath string
-	packagesFlag    []string
-	packagesFile    string
-	outputFormat    string
-	showAllVersions bool
-	showDevOnly     bool
-	showNestedOnly  bool
-	minDepth        int
-	showMetadata    bool
-	showDependencies bool
-	showEngines     bool
-	searchInDeps    bool
-	riskOnly        bool
-	showSafe        bool
+	packageLockPath     string
+	packagesFlag        []string
+	packagesFiles       []string
+	outputFormat        string
+	outputFile          string
+	summaryFile         string
+	notifyFormat        string
+	formatTemplate      string
+	formatFile          string
+	noGitHubDetect      bool
+	summaryOnly         bool
+	countOnly           bool
+	colorMode           string
+	asciiOutput         bool
+	groupBy             string
+	tableColumns        []string
+	wideTable           bool
+	showChains          bool
+	suggestFixes        bool
+	truncatePaths       int
+	defaultSeverity     string
+	minSeverity         string
+	sortOrder           string
+	quiet               bool
+	failOnFound         bool
+	failOnSeverity      string
+	maxRisks            int
+	annotationThreshold string
+	namespacePrefix     string
+	showAllVersions     bool
+	showDevOnly         bool
+	showNestedOnly      bool
+	minDepth            int
+	maxDepth            int
+	showMetadata        bool
+	showDependencies    bool
+	showReferences      bool
+	showEngines         bool
+	searchInDeps        bool
+	riskOnly            bool
+	showSafe            bool
+	scanWorkspacesFlag  bool
+	manifestMode        bool
+	packagesFormat      string
+	osvFile             string
+	noBuiltin           bool
+	auditMode           bool
+	registryURL         string
+	ghsaMode            bool
+	osvMode             bool
+	cacheTTL            time.Duration
+	noCache             bool
+	offline             bool
+	verifyKey           string
+	insecureSkipVerify  bool
+	packagesURL         string
+	packagesURLTimeout  time.Duration
+	allowStaleCache     bool
+	ignoreFile          string
+	baselineFile        string
+	noRecursePackages   bool
+	fuzzyMatch          bool
+	matchUnscoped       bool
+	ignoreCase          bool
+	regexQueries        []string
+	verbose             bool
+	watchMode           bool
+	scanTimeout         time.Duration
+	scanConcurrency     int
+	logLevel            string
+	logFormat           string
+	configFile          string
+	noConfig            bool
+	noProgress          bool
 )
 
 func init() {
-	rootCmd.Flags().StringVarP(&packageLockPath, "file", "f", "package-lock.json", "Path to package-lock.json file")
-	rootCmd.Flags().StringSliceVarP(&packagesFlag, "packages", "p", []string{}, "List of packages to scan (format: package@version)")
-	rootCmd.Flags().StringVar(&packagesFile, "packages-file", "", "Path to JSON file containing array of bad packages to scan (e.g., badpak.json)")
-	rootCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+	rootCmd.Flags().StringVarP(&packageLockPath, "file", "f", "package-lock.json", "Path to package-lock.json or pnpm-lock.yaml file (use - for stdin)")
+	rootCmd.Flags().StringSliceVarP(&packagesFlag, "packages", "p", []string{}, "List of packages to scan (format: package@version, or a bare name for any version)")
+	rootCmd.Flags().StringSliceVar(&packagesFiles, "packages-file", []string{}, "Path to a file (or directory of files) listing bad packages to scan (e.g., badpak.json); repeat to merge several lists")
+	rootCmd.Flags().BoolVar(&noRecursePackages, "no-recurse-packages", false, "Don't descend into subdirectories when a packages file is a directory")
+	rootCmd.Flags().StringVar(&packagesFormat, "packages-format", "", "Format of the packages file (json, yaml, csv, text, or snyk / ossindex reports); detected from the file extension by default")
+	rootCmd.Flags().StringVar(&packagesURL, "packages-url", "", "HTTPS URL of a bad-packages list to fetch (any --packages-format); its Authorization header comes from $"+packagesURLAuthEnv)
+	rootCmd.Flags().DurationVar(&packagesURLTimeout, "packages-url-timeout", 30*time.Second, "Timeout for fetching --packages-url")
+	rootCmd.Flags().BoolVar(&allowStaleCache, "allow-stale-cache", false, "Use the last cached copy of --packages-url when it can't be fetched")
+	rootCmd.Flags().StringVar(&osvFile, "osv-file", "", "Path to an OSV advisory, JSON array of advisories, or zip export; npm entries become package queries")
+	rootCmd.Flags().BoolVar(&noBuiltin, "no-builtin", false, "Don't scan for the packages in the built-in advisory database")
+	rootCmd.Flags().BoolVar(&auditMode, "audit", false, "Also look up every installed package in the registry's bulk advisory endpoint (token from $"+registryTokenEnv+")")
+	rootCmd.Flags().StringVar(&registryURL, "registry", audit.DefaultRegistry, "npm registry used by --audit and to resolve dist-tag entries (package@latest)")
+	rootCmd.Flags().BoolVar(&ghsaMode, "ghsa", false, "Also look up every installed package in the GitHub Advisory Database (token from $"+githubTokenEnv+")")
+	rootCmd.Flags().BoolVar(&osvMode, "osv", false, "Also look up every installed package with the OSV.dev API")
+	rootCmd.Flags().DurationVar(&cacheTTL, "cache-ttl", cache.DefaultTTL, "How long cached responses from online sources stay fresh")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "Don't read or write the response cache")
+	rootCmd.Flags().BoolVar(&offline, "offline", false, "Resolve dist-tag entries (package@latest) from the cache only, without contacting the registry")
+	rootCmd.PersistentFlags().StringVar(&verifyKey, "verify-key", "", "minisign or ssh-ed25519 public key (or key file) that must have signed --packages-url lists and update-db databases (<url>.sig)")
+	rootCmd.PersistentFlags().BoolVar(&insecureSkipVerify, "insecure-skip-verify", false, "Use downloads whose signature is missing or invalid, with a warning")
+	rootCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format ("+strings.Join(outputFormats, ", ")+")")
+	rootCmd.Flags().StringVar(&formatTemplate, "format", "", "Print a line per finding rendered through this Go template, e.g. '{{.Package.Name}} {{.Instance.Version}} {{.Instance.Path}}'")
+	rootCmd.Flags().StringVar(&formatFile, "format-file", "", "Like --format, with the template read from this file")
+	rootCmd.Flags().BoolVar(&noGitHubDetect, "no-github-annotations", false, "Don't switch to --output github when running in GitHub Actions ($GITHUB_ACTIONS)")
+	rootCmd.Flags().StringVar(&annotationThreshold, "annotation-threshold", output.DefaultErrorSeverity, "Lowest severity annotated as an error with --output github; lower ones become warnings")
+	rootCmd.Flags().BoolVar(&summaryOnly, "summary-only", false, "Print only the security summary, without a line per package ("+strings.Join(summaryFormats, ", ")+" output)")
+	rootCmd.Flags().BoolVar(&countOnly, "count-only", false, "Print only the number of risks found; with --output json, an object of the risk, safe and reference counts")
+	rootCmd.Flags().StringVar(&colorMode, "color", "auto", "Color the table: auto (when stdout is a terminal and $NO_COLOR is unset), always or never")
+	rootCmd.Flags().BoolVar(&asciiOutput, "ascii", false, "Print plain tokens (RISK, REF, SAFE, OK, WARN) instead of emoji; on by default when the locale isn't UTF-8")
+	rootCmd.Flags().StringVar(&groupBy, "group-by", "", "Group findings into sections with subtotals: "+strings.Join(output.GroupBys, ", ")+"; JSON output tags each instance with its group")
+	rootCmd.Flags().StringSliceVar(&tableColumns, "columns", nil, "Table columns to show, in order, from: "+strings.Join(output.TableColumns, ", "))
+	rootCmd.Flags().BoolVar(&suggestFixes, "suggest-fixes", false, "Suggest a fix for every risky package: the npm ls command to investigate, an upgrade for direct dependencies and package.json overrides for transitive ones")
+	rootCmd.Flags().BoolVar(&showChains, "chains", false, "Add a table column with the dependency chain that pulls in each finding (express > body-parser > evil@1.0.0)")
+	rootCmd.Flags().BoolVar(&wideTable, "wide", false, "Don't truncate anything in the table, overriding --truncate-paths")
+	rootCmd.Flags().IntVar(&truncatePaths, "truncate-paths", 0, "Shorten install paths longer than this many characters from the middle with an ellipsis (0 for no limit)")
+	rootCmd.Flags().StringVar(&defaultSeverity, "default-severity", "", "Severity of bad-package entries that don't carry one (critical, high, moderate, low); they're 'unknown' otherwise")
+	rootCmd.Flags().StringVar(&minSeverity, "min-severity", "", "Drop bad-package entries below this severity from the report, the summary and --quiet's exit status")
+	rootCmd.Flags().StringVar(&sortOrder, "sort", sortSeverity, "Order of the bad-package entries: severity (most severe first) or list (as the lists give them)")
+	rootCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Print nothing; the exit status still says whether risks were found")
+	rootCmd.Flags().BoolVar(&failOnFound, "fail-on-found", true, "Exit with status 1 when risks are found; --fail-on-found=false always exits 0 after a scan")
+	rootCmd.Flags().StringVar(&failOnSeverity, "fail-on-severity", "", "Only fail for risks at or above this severity (critical, high, moderate, low); risks without one don't count unless --default-severity gives them one")
+	rootCmd.Flags().IntVar(&maxRisks, "max-risks", 0, "Tolerate up to this many failing risks before exiting with status 1")
+	rootCmd.Flags().StringVar(&outputFile, "output-file", "", "Write the report to this file instead of stdout; warnings and the summary still go to stderr")
+	rootCmd.Flags().StringVar(&summaryFile, "summary-file", "", "Also write a compact JSON summary (counts, risky packages, lockfiles, duration) to this file, whatever the output format")
+	rootCmd.Flags().StringVar(&notifyFormat, "notify-format", output.NotifyJSON, "Payload of the notification posted to $"+webhookURLEnv+": json, or slack for a Slack incoming webhook")
+	rootCmd.Flags().StringVar(&namespacePrefix, "namespace-prefix", output.DefaultNamespacePrefix, "URI prefix of the SPDX document namespace (--output spdx); the namespace is derived from the lockfile's contents")
 	rootCmd.Flags().BoolVar(&showAllVersions, "all-versions", false, "Show all versions found, not just first match")
 	rootCmd.Flags().BoolVar(&showDevOnly, "dev-only", false, "Show only development dependencies")
 	rootCmd.Flags().BoolVar(&showNestedOnly, "nested-only", false, "Show only nested dependencies")
 	rootCmd.Flags().IntVar(&minDepth, "min-depth", 0, "Minimum nesting depth to show")
+	rootCmd.Flags().IntVar(&maxDepth, "max-depth", unlimitedDepth, "Maximum nesting depth to show (0 for direct dependencies only, -1 for no limit)")
 	rootCmd.Flags().BoolVar(&showMetadata, "metadata", false, "Include comprehensive metadata (resolved, integrity, license)")
-	rootCmd.Flags().BoolVar(&showDependencies, "show-deps", false, "Include dependencies and peerDependencies")
+	rootCmd.Flags().BoolVar(&showDependencies, "show-deps", false, "Also report references from peerDependencies, which the consumer rather than the package installs")
+	rootCmd.Flags().BoolVar(&showReferences, "show-references", false, "List references that resolve to a found installed instance as findings of their own, rather than in its referencedBy")
 	rootCmd.Flags().BoolVar(&showEngines, "show-engines", false, "Include engines and other technical metadata")
+	rootCmd.Flags().StringArrayVar(&regexQueries, "regex", []string{}, "Flag every package whose whole name matches this regular expression, in any version (repeatable)")
+	rootCmd.Flags().BoolVar(&fuzzyMatch, "fuzzy", false, "Also match package names containing the query (or contained in it); off by default to avoid false positives")
+	rootCmd.Flags().BoolVar(&ignoreCase, "ignore-case", false, "Match package names case-insensitively (e.g. JSONStream in legacy lockfiles vs jsonstream)")
+	rootCmd.Flags().BoolVar(&matchUnscoped, "match-unscoped", false, "Also match unscoped names against scoped packages (tinycolor vs @ctrl/tinycolor) and the reverse")
 	rootCmd.Flags().BoolVar(&searchInDeps, "search-in-deps", true, "Search within dependency requirements of other packages (enabled by default for comprehensive malware detection)")
 	rootCmd.Flags().BoolVar(&riskOnly, "risk-only", false, "Show only packages that pose security risks (hide safe packages)")
 	rootCmd.Flags().BoolVar(&showSafe, "show-safe", true, "Show packages that were not found (safe packages)")
+	rootCmd.Flags().BoolVar(&manifestMode, "manifest", false, "Allow --file to be a package.json and scan its declared dependency ranges")
+	rootCmd.Flags().BoolVar(&scanWorkspacesFlag, "workspaces", false, "Discover workspaces from the root package.json and report findings per workspace")
+	rootCmd.Flags().StringVar(&ignoreFile, "ignore-file", "", "JSON file of accepted findings to suppress (e.g. "+ignore.DefaultFile+")")
+	rootCmd.Flags().StringVar(&baselineFile, "baseline", "", "Baseline of known findings (scnpm baseline write); they're still reported, but don't count as risks or fail --quiet")
+	rootCmd.Flags().IntVar(&scanConcurrency, "concurrency", runtime.NumCPU(), "How many lockfiles, of a snapshot or of --workspaces, are read and scanned at once")
+	rootCmd.Flags().DurationVar(&scanTimeout, "timeout", 0, "Stop the scan after this long (e.g. 5m) and report what it found so far, exiting 3; 0 for no limit")
+	rootCmd.Flags().BoolVar(&watchMode, "watch", false, "Keep running and scan again whenever the lockfile or a packages file changes, ringing the bell when new risks appear")
+	rootCmd.Flags().BoolVar(&verbose, "verbose", false, "Log diagnostic details about loaded inputs to stderr (same as --log-level info)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "warn", "Lowest level of the logs written to stderr: debug, info, warn or error")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Format of the logs written to stderr ("+strings.Join(logFormats, ", ")+")")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file to read instead of the nearest "+strings.Join(configFileNames, " or ")+" in this directory or above; flags and SCNPM_ variables override it")
+	rootCmd.PersistentFlags().BoolVar(&noConfig, "no-config", false, "Don't read any config file")
+	rootCmd.PersistentFlags().BoolVar(&noProgress, "no-progress", false, "Don't show a progress line on stderr during long scans and verification; it's only shown on a terminal")
+
+	completeLockfileFlags(rootCmd, outputFormats)
+	completeFlagValues(rootCmd, "color", []string{"auto", "always", "never"})
+	completeFlagValues(rootCmd, "sort", []string{sortSeverity, sortList})
+	completeFlagValues(rootCmd, "group-by", output.GroupBys)
+	completeFlagValues(rootCmd, "notify-format", output.NotifyFormats)
+	completeFlagValues(rootCmd, "log-format", logFormats)
+	completeFlagValues(rootCmd, "log-level", []string{"debug", "info", "warn", "error"})
+	for _, flag := range []string{"default-severity", "min-severity", "fail-on-severity", "annotation-threshold"} {
+		completeFlagValues(rootCmd, flag, types.Severities)
+	}
+
+	// baseline write scans exactly as the root command does, to record what it would find
+	baselineWriteCmd.Flags().AddFlagSet(rootCmd.Flags())
 
 	// Add version template
 	rootCmd.SetVersionTemplate(`{{with .Name}}{{printf "%s " .}}{{end}}{{printf "version %s" .Version}}
@@ -81,161 +268,804 @@ Date: ` + date + `
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	// The first interrupt cancels the scan, which still reports what it found; a second one
+	// kills scnpm as usual
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	context.AfterFunc(ctx, stop)
+	documentEnv(rootCmd)
+	rootCmd.SetFlagErrorFunc(flagError)
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
+	}
+}
+
+// setup gives the flags left unset the values of their environment variables, then those of the
+// config file, and starts logging, before any command
+func setup(cmd *cobra.Command, args []string) {
+	if err := applyEnv(cmd); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	config, err := applyConfig(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	setupLogging(cmd, args)
+	if config != nil {
+		logVerbose("loaded config '%s'", config.Path)
 	}
 }
 
 func runScan(cmd *cobra.Command, args []string) {
-	// Parse package queries from various sources
-	var packageQueries []types.PackageQuery
-	var packagesToScan []string
-	
-	// 1. Check if first argument is a JSON file (new positional syntax)
-	if len(args) > 0 && strings.HasSuffix(args[0], ".json") {
-		packages, err := readPackagesFromFile(args[0])
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading packages file '%s': %v\n", args[0], err)
-			os.Exit(1)
-		}
-		packagesToScan = append(packagesToScan, packages...)
-		args = args[1:] // Remove the JSON file from args
+	scanStart := time.Now()
+	// A --format template is checked first, so that a typo doesn't cost a scan
+	tmpl, err := loadFormatTemplate(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
 	}
-	
-	// 2. Check --packages-file flag
-	if packagesFile != "" {
-		packages, err := readPackagesFromFile(packagesFile)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading packages file '%s': %v\n", packagesFile, err)
-			os.Exit(1)
+	if watchMode {
+		if baselineOut != "" {
+			fmt.Fprintf(os.Stderr, "Error: --watch can't be used to write a baseline\n")
+			os.Exit(exitError)
 		}
-		packagesToScan = append(packagesToScan, packages...)
+		runWatch(args)
+		return
 	}
-	
-	// 3. Add packages from --packages flag
-	packagesToScan = append(packagesToScan, packagesFlag...)
-	
-	// 4. Add remaining command line arguments as packages
-	packagesToScan = append(packagesToScan, args...)
-	
-	// Parse all packages into queries
-	for _, pkg := range packagesToScan {
-		query, err := parsePackageQuery(pkg)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing package '%s': %v\n", pkg, err)
+	if baselineOut != "" {
+		// Nothing is reported; the whole scan is collected and recorded
+		outputFormat = "table"
+	} else if tmpl != nil {
+		outputFormat = templateFormat
+	} else if os.Getenv("GITHUB_ACTIONS") == "true" && !noGitHubDetect && !countOnly && !fromCommandLine(cmd, "output") {
+		// Findings show up as annotations on the pull request
+		outputFormat = "github"
+	}
+	errorSeverity, ok := types.NormalizeSeverity(annotationThreshold)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: --annotation-threshold must be one of %s\n", strings.Join(types.Severities, ", "))
+		os.Exit(exitError)
+	}
+
+	for _, flag := range []struct {
+		name  string
+		value *string
+	}{{"default-severity", &defaultSeverity}, {"min-severity", &minSeverity}, {"fail-on-severity", &failOnSeverity}} {
+		if *flag.value == "" {
 			continue
 		}
-		packageQueries = append(packageQueries, query)
+		severity, ok := types.NormalizeSeverity(*flag.value)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: --%s must be one of %s\n", flag.name, strings.Join(types.Severities, ", "))
+			os.Exit(exitError)
+		}
+		*flag.value = severity
 	}
-	
-	if len(packageQueries) == 0 {
-		fmt.Fprintf(os.Stderr, "No packages specified. Use one of the following methods:\n")
+	if maxRisks < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --max-risks must be 0 or more\n")
+		os.Exit(exitError)
+	}
+	if sortOrder != sortSeverity && sortOrder != sortList {
+		fmt.Fprintf(os.Stderr, "Error: --sort must be %s or %s, got '%s'\n", sortSeverity, sortList, sortOrder)
+		os.Exit(exitError)
+	}
+	if scanConcurrency < 1 {
+		fmt.Fprintf(os.Stderr, "Error: --concurrency must be 1 or more\n")
+		os.Exit(exitError)
+	}
+	if scanTimeout < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --timeout must be 0 (no limit) or more\n")
+		os.Exit(exitError)
+	}
+	ctx := cmd.Context()
+	if scanTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, scanTimeout)
+		defer cancel()
+	}
+
+	if err := validateCheckFlags(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if offline && (auditMode || ghsaMode || osvMode || packagesURL != "") {
+		fmt.Fprintf(os.Stderr, "Error: --offline can't be combined with --audit, --ghsa, --osv or --packages-url\n")
+		os.Exit(exitError)
+	}
+
+	webhook, err := webhookURL()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	packageQueries, warnings, err := loadQueries(ctx, args)
+	if err != nil && ctx.Err() != nil {
+		fmt.Fprintf(os.Stderr, "Error: scan %s before it started: %v\n", interruption(ctx), err)
+		os.Exit(exitInterrupted)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	githubToken := os.Getenv(githubTokenEnv)
+	if ghsaMode && githubToken == "" {
+		fmt.Fprintf(os.Stderr, "Error: --ghsa needs a GitHub token in $%s (anonymous API access is limited to 60 requests an hour)\n", githubTokenEnv)
+		os.Exit(exitError)
+	}
+
+	if len(packageQueries) == 0 && !auditMode && !ghsaMode && !osvMode && !checksEnabled() {
+		fmt.Fprintf(os.Stderr, "No packages specified and the built-in database is disabled. Use one of the following methods:\n")
 		fmt.Fprintf(os.Stderr, "  scnpm badpak.json\n")
 		fmt.Fprintf(os.Stderr, "  scnpm --packages-file badpak.json\n")
+		fmt.Fprintf(os.Stderr, "  scnpm --packages-url https://example.com/badpak.json\n")
+		fmt.Fprintf(os.Stderr, "  scnpm --osv-file advisories.json\n")
 		fmt.Fprintf(os.Stderr, "  scnpm --packages package@1.0.0,another@2.0.0\n")
 		fmt.Fprintf(os.Stderr, "  scnpm package@1.0.0 another@2.0.0\n")
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "  scnpm --audit\n")
+		fmt.Fprintf(os.Stderr, "  scnpm --ghsa\n")
+		fmt.Fprintf(os.Stderr, "  scnpm --osv\n")
+		fmt.Fprintf(os.Stderr, "  scnpm --sources\n")
+		fmt.Fprintf(os.Stderr, "  scnpm --non-registry\n")
+		fmt.Fprintf(os.Stderr, "  scnpm --typosquat\n")
+		fmt.Fprintf(os.Stderr, "  scnpm --require-integrity\n")
+		fmt.Fprintf(os.Stderr, "  scnpm --detect-scripts\n")
+		fmt.Fprintf(os.Stderr, "  scnpm --scan-scripts\n")
+		fmt.Fprintf(os.Stderr, "  scnpm --flag-licenses GPL-3.0,AGPL-3.0\n")
+		os.Exit(exitError)
 	}
-	
-	// Resolve package-lock.json path (support both relative and absolute paths)
-	absPackageLockPath, err := filepath.Abs(packageLockPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error resolving path '%s': %v\n", packageLockPath, err)
-		os.Exit(1)
+
+	if !ignoreCase {
+		warnings = append(warnings, mixedCaseWarnings(packageQueries)...)
+	}
+
+	var ignoreRules []ignore.Rule
+	if ignoreFile != "" {
+		var err error
+		ignoreRules, err = ignore.Load(ignoreFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		logVerbose("loaded %d suppressions from '%s'", len(ignoreRules), ignoreFile)
+	}
+	var known *baseline.Baseline
+	if baselineFile != "" {
+		fingerprints, err := baseline.Load(baselineFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		known = baseline.New(fingerprints)
+		logVerbose("loaded %d baseline findings from '%s'", len(fingerprints), baselineFile)
+	}
+
+	// Resolve package-lock.json path (support both relative and absolute paths, "-" reads stdin)
+	absPackageLockPath := packageLockPath
+	if packageLockPath != stdinPath {
+		var err error
+		absPackageLockPath, err = filepath.Abs(packageLockPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error resolving path '%s': %v\n", packageLockPath, err)
+			os.Exit(exitError)
+		}
+
+		// Check if package-lock.json exists
+		if _, err := os.Stat(absPackageLockPath); os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error: package-lock.json not found at '%s'\n", absPackageLockPath)
+			os.Exit(exitError)
+		}
+	}
+
+	if u, err := url.Parse(namespacePrefix); err != nil || !u.IsAbs() {
+		fmt.Fprintf(os.Stderr, "Error: --namespace-prefix must be an absolute URI, got '%s'\n", namespacePrefix)
+		os.Exit(exitError)
+	}
+
+	if !containsString(outputFormats, outputFormat) && outputFormat != templateFormat {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", outputFormat)
+		os.Exit(exitError)
+	}
+	if countOnly && !containsString(countFormats, outputFormat) {
+		fmt.Fprintf(os.Stderr, "Error: --count-only works with %s output, not %s\n", strings.Join(countFormats, ", "), outputFormat)
+		os.Exit(exitError)
+	}
+	if summaryOnly && !quiet && !containsString(summaryFormats, outputFormat) {
+		fmt.Fprintf(os.Stderr, "Error: --summary-only works with %s output, not %s\n", strings.Join(summaryFormats, ", "), outputFormat)
+		os.Exit(exitError)
+	}
+
+	if groupBy != "" && !containsString(output.GroupBys, groupBy) {
+		fmt.Fprintf(os.Stderr, "Error: --group-by must be one of %s; got '%s'\n", strings.Join(output.GroupBys, ", "), groupBy)
+		os.Exit(exitError)
+	}
+
+	for _, column := range tableColumns {
+		if !containsString(output.TableColumns, column) {
+			fmt.Fprintf(os.Stderr, "Error: unknown column '%s'; valid columns are %s\n", column, strings.Join(output.TableColumns, ", "))
+			os.Exit(exitError)
+		}
+	}
+	if truncatePaths < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --truncate-paths must be 0 (no limit) or more\n")
+		os.Exit(exitError)
+	}
+	if wideTable {
+		truncatePaths = 0
 	}
 
-	// Check if package-lock.json exists
-	if _, err := os.Stat(absPackageLockPath); os.IsNotExist(err) {
-		fmt.Fprintf(os.Stderr, "Error: package-lock.json not found at '%s'\n", absPackageLockPath)
-		os.Exit(1)
+	// The report file is created before scanning, so that an unwritable path fails fast. --quiet
+	// still writes it, but prints nothing.
+	var report io.Writer = os.Stdout
+	if quiet {
+		report = io.Discard
 	}
-	
-	// Read and parse package-lock.json
-	packageLock, err := readPackageLock(absPackageLockPath)
+	var reportFile *os.File
+	if outputFile != "" {
+		reportFile, err = os.Create(outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+			os.Exit(exitError)
+		}
+		report = reportFile
+	}
+	color, err := useColor(colorMode, report)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading package-lock.json: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
 	}
-	
+
 	// Create filter and output configs
 	filterConfig := scanner.FilterConfig{
 		ShowDevOnly:    showDevOnly,
 		ShowNestedOnly: showNestedOnly,
 		MinDepth:       minDepth,
+		Fuzzy:          fuzzyMatch,
+		MatchUnscoped:  matchUnscoped,
+		IgnoreCase:     ignoreCase,
+		PeerReferences: showDependencies,
+		ShowReferences: showReferences,
+		SuggestFixes:   suggestFixes,
+	}
+	if maxDepth < unlimitedDepth {
+		fmt.Fprintf(os.Stderr, "Error: --max-depth must be %d (no limit) or more\n", unlimitedDepth)
+		os.Exit(exitError)
+	}
+	if maxDepth != unlimitedDepth {
+		filterConfig.MaxDepth = &maxDepth
+	}
+	if warning := depthBoundsWarning(filterConfig); warning != "" {
+		warnings = append(warnings, warning)
 	}
 
+	isArchive := input.IsArchive(absPackageLockPath)
 	outputConfig := output.OutputConfig{
-		ShowSafe: showSafe,
-		RiskOnly: riskOnly,
+		ShowSafe:        showSafe,
+		RiskOnly:        riskOnly,
+		ShowWorkspaces:  scanWorkspacesFlag,
+		ShowChains:      showChains,
+		ShowLockfiles:   isArchive,
+		ToolVersion:     version,
+		ToolCommit:      commit,
+		Lockfile:        relativePath(absPackageLockPath),
+		NamespacePrefix: namespacePrefix,
+		ErrorSeverity:   errorSeverity,
+		SummaryOnly:     summaryOnly,
+		GroupBy:         groupBy,
+		Columns:         tableColumns,
+		TruncatePaths:   truncatePaths,
+		Color:           color,
+		ASCII:           asciiOutput || !cmd.Flags().Changed("ascii") && asciiLocale(),
+	}
+
+	options := scnpm.Options{
+		Filter:          filterConfig,
+		Checks:          checkOptions(),
+		DefaultSeverity: defaultSeverity,
+		MinSeverity:     minSeverity,
+		SortBySeverity:  sortOrder == sortSeverity,
+		Ignore:          ignoreRules,
+		Baseline:        known,
+		Manifest:        manifestMode,
+		Concurrency:     scanConcurrency,
+	}
+
+	// NDJSON output is written as each lockfile is scanned, rather than once all of them are
+	var batches chan output.ResultBatch
+	streamed := make(chan struct{})
+	var streamedTotals output.Totals
+	var streamedResults []types.ScanResult // Kept for --summary-file and the notification
+	scanWarnings := len(warnings)
+	if outputFormat == "ndjson" {
+		printWarnings(warnings)
+		outputConfig.Warnings = warnings
+		batches = make(chan output.ResultBatch)
+		go func(config output.OutputConfig) {
+			totals, err := output.OutputNDJSON(report, batches, config)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+				os.Exit(exitError)
+			}
+			streamedTotals = totals
+			close(streamed)
+		}(outputConfig)
+
+		options.Emit = func(lockfile string, results []types.ScanResult, warnings []string) {
+			printWarnings(warnings)
+			if summaryFile != "" || webhook != "" {
+				streamedResults = append(streamedResults, results...)
+			}
+			batches <- output.ResultBatch{Lockfile: lockfile, Results: results, Warnings: warnings}
+		}
+	}
+
+	var lockfiles []scnpm.Lockfile
+	if isArchive {
+		var archiveWarnings []string
+		lockfiles, archiveWarnings, err = readArchive(absPackageLockPath)
+		warnings = append(warnings, archiveWarnings...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error scanning archive '%s': %v\n", absPackageLockPath, err)
+			os.Exit(exitError)
+		}
+		options.TagLockfiles = true
+		if auditMode || ghsaMode || osvMode {
+			warnings = append(warnings, "--audit, --ghsa and --osv are not supported for archives; only the bad-package lists were checked")
+		}
+	} else {
+		lockfile := scnpm.Lockfile{Name: outputConfig.Lockfile, Reader: os.Stdin, NoLines: true}
+		if absPackageLockPath != stdinPath {
+			file, err := os.Open(absPackageLockPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading package-lock.json: %v\n", err)
+				os.Exit(exitError)
+			}
+			defer file.Close()
+			lockfile = scnpm.Lockfile{Name: outputConfig.Lockfile, Reader: file}
+		}
+		lockfiles = []scnpm.Lockfile{lockfile}
+		options.Dir = workspaceRoot(absPackageLockPath)
+		options.Workspaces = scanWorkspacesFlag
+		if auditMode || ghsaMode || osvMode {
+			options.Lookup = func(ctx context.Context, packageLock *types.PackageLock) ([]types.PackageQuery, []string) {
+				return onlineQueries(ctx, packageLock, githubToken)
+			}
+		}
+	}
+
+	// The progress line is cleared before the report is written; only NDJSON streamed to the
+	// same terminal would cross it
+	var bar *progress
+	if outputFormat != "ndjson" || report != os.Stdout || !isTerminal(os.Stdout) {
+		bar = newProgress("lockfiles", outputConfig.ASCII)
+		options.Progress = func(done, total, risks int) {
+			bar.update(done, total, fmt.Sprintf(", %d risks so far", risks))
+		}
+	}
+
+	start := time.Now()
+	scan, err := scnpm.Scan(ctx, scnpm.ScanRequest{Lockfiles: lockfiles, Queries: packageQueries, Options: options})
+	bar.finish()
+	// Only a scan cut short by ctx comes back with both a report and an error
+	if scan == nil {
+		fmt.Fprintf(os.Stderr, "Error scanning '%s': %v\n", packageLockPath, manifestHint(err))
+		os.Exit(exitError)
+	}
+	interrupted := err != nil
+	if interrupted {
+		scan.Warnings = append(scan.Warnings, fmt.Sprintf("scan %s; the results are partial", interruption(ctx)))
+		scan.Totals.Interrupted = true
+		outputConfig.Interrupted = true
+	}
+	logPhase("scan", start)
+	warnings = append(warnings, scan.Warnings...)
+
+	if options.Emit != nil {
+		// Each lockfile was streamed as it was scanned; what's left is the caveats about the
+		// archive and the baseline
+		printWarnings(warnings[scanWarnings:])
+		batches <- output.ResultBatch{Warnings: warnings[scanWarnings:], Interrupted: interrupted}
+		close(batches)
+		<-streamed
+		closeReport(reportFile)
+		writeSummaryFile(streamedResults, streamedTotals, scanStart, outputConfig)
+		notify(webhook, streamedResults, streamedTotals, outputConfig)
+		os.Exit(exitStatus(streamedTotals))
 	}
 
-	// Scan for packages
-	results := scanner.ScanPackages(packageLock, packageQueries, filterConfig)
+	results := scan.Results
+	if baselineOut != "" {
+		if interrupted {
+			// A baseline of part of the findings would fail the next scan on the rest
+			fmt.Fprintf(os.Stderr, "Error: scan %s; the baseline wasn't written\n", interruption(ctx))
+			os.Exit(exitInterrupted)
+		}
+		printWarnings(warnings)
+		writeBaseline(results, outputConfig.Lockfile)
+		return
+	}
+	outputConfig.Warnings = warnings
+	outputConfig.Inventory = scan.Inventory
 
-	// Output results
-	switch outputFormat {
-	case "json":
-		output.OutputJSON(results)
-	case "table":
-		output.OutputTable(results, outputConfig)
+	// Output results; the table, HTML report and annotations carry their warnings, unless they go
+	// to a file or only the counts are printed
+	if reportFile != nil || countOnly || outputFormat != "table" && outputFormat != "html" && outputFormat != "github" {
+		printWarnings(warnings)
+	}
+	start = time.Now()
+	switch {
+	case countOnly:
+		err = output.OutputCount(report, results, outputFormat == "json")
+	case outputFormat == templateFormat:
+		err = output.OutputTemplate(report, tmpl, results, outputConfig)
 	default:
-		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", outputFormat)
-		os.Exit(1)
+		err = scnpm.Write(report, outputFormat, scan, outputConfig)
+		if path := os.Getenv(stepSummaryEnv); outputFormat == "github" && path != "" && err == nil {
+			err = appendStepSummary(path, results, outputConfig)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(exitError)
+	}
+	logPhase("output", start)
+	if reportFile != nil && !quiet {
+		fmt.Fprintln(os.Stderr, output.Summary(results, outputConfig))
 	}
+	closeReport(reportFile)
+	writeSummaryFile(results, scan.Totals, scanStart, outputConfig)
+	notify(webhook, results, scan.Totals, outputConfig)
+	os.Exit(exitStatus(scan.Totals))
 }
 
-func parsePackageQuery(input string) (types.PackageQuery, error) {
-	parts := strings.Split(input, "@")
-	if len(parts) < 2 {
-		return types.PackageQuery{}, fmt.Errorf("invalid format, expected package@version")
+// writeSummaryFile writes the --summary-file, if there is one, of a scan that started at start
+func writeSummaryFile(results []types.ScanResult, totals output.Totals, start time.Time, config output.OutputConfig) {
+	if summaryFile == "" {
+		return
+	}
+	if err := output.WriteSummaryFile(summaryFile, results, totals, time.Since(start), config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing summary file: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+// loadQueries collects the bad-package queries of a scan: leading packages files in args and
+// --packages-file, --packages-url, --osv-file, --packages and the rest of args, --regex and the
+// built-in database, merged so that an entry in several of them is scanned once. Dist-tag
+// entries are resolved to versions. It also returns caveats about what couldn't be loaded.
+func loadQueries(ctx context.Context, args []string) ([]types.PackageQuery, []string, error) {
+	var packageQueries []types.PackageQuery
+	start := time.Now()
+
+	// 1. Collect bad-package lists: leading positional packages files (new syntax) and every --packages-file
+	var listPaths []string
+	for len(args) > 0 && isPackagesFile(args[0]) {
+		listPaths = append(listPaths, args[0])
+		args = args[1:] // Remove the packages file from args
+	}
+	listPaths = append(listPaths, packagesFiles...)
+	listPaths, err := expandPackagesPaths(listPaths, !noRecursePackages)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 2. Read and merge them, collapsing entries that appear in several lists
+	for _, listPath := range listPaths {
+		queries, err := readPackagesFromFile(listPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read packages file '%s': %v", listPath, err)
+		}
+		logVerbose("loaded %d entries from '%s'", len(queries), listPath)
+		for i := range queries {
+			queries[i].Source = listPath
+		}
+		packageQueries = scnpm.MergeQueries(packageQueries, queries)
+	}
+
+	// 3. Fetch --packages-url
+	var warnings []string
+	if packagesURL != "" {
+		auth := os.Getenv(packagesURLAuthEnv)
+		key, err := loadVerifyKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		client := &http.Client{Timeout: packagesURLTimeout}
+		queries, fetchWarnings, err := readPackagesFromURL(ctx, client, openCache(), key, packagesURL, auth, allowStaleCache)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read packages URL: %v", err)
+		}
+		warnings = append(warnings, fetchWarnings...)
+		packageQueries = scnpm.MergeQueries(packageQueries, queries)
+	}
+
+	// 4. Check --osv-file flag
+	if osvFile != "" {
+		queries, err := readOSVFile(osvFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read OSV file '%s': %v", osvFile, err)
+		}
+		packageQueries = scnpm.MergeQueries(packageQueries, queries)
 	}
 
-	// Handle scoped packages like @types/node@1.0.0
-	if strings.HasPrefix(input, "@") && len(parts) >= 3 {
-		return types.PackageQuery{
-			Name:    "@" + parts[1],
-			Version: strings.Join(parts[2:], "@"),
-		}, nil
+	// 5. Add packages from --packages flag and remaining command line arguments
+	var packagesToScan []string
+	packagesToScan = append(packagesToScan, packagesFlag...)
+	packagesToScan = append(packagesToScan, args...)
+
+	// Parse all packages into queries
+	for _, pkg := range packagesToScan {
+		query, err := scnpm.ParseQuery(pkg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing package '%s': %v\n", pkg, err)
+			continue
+		}
+		query.Source = cliSource
+		packageQueries = scnpm.MergeQueries(packageQueries, []types.PackageQuery{query})
+	}
+	for _, pattern := range regexQueries {
+		query, err := scnpm.ParseQuery(scanner.RegexPrefix + pattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --regex: %v", err)
+		}
+		query.Source = cliSource
+		packageQueries = scnpm.MergeQueries(packageQueries, []types.PackageQuery{query})
 	}
 
-	return types.PackageQuery{
-		Name:    parts[0],
-		Version: strings.Join(parts[1:], "@"),
-	}, nil
+	// 6. Add the built-in advisory database unless disabled
+	if !noBuiltin {
+		db, err := loadAdvisoryDatabase()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load advisory database: %v", err)
+		}
+		queries := db.Queries()
+		logVerbose("loaded %d entries from the advisory database (updated %s)", len(queries), db.Updated.Format("2006-01-02"))
+		packageQueries = scnpm.MergeQueries(packageQueries, queries)
+	}
+
+	packageQueries, tagWarnings := resolveDistTags(ctx, packageQueries)
+	warnings = append(warnings, tagWarnings...)
+	logQueries(packageQueries)
+	logPhase("load queries", start)
+	return packageQueries, warnings, nil
 }
 
-func readPackageLock(path string) (*types.PackageLock, error) {
-	data, err := os.ReadFile(path)
+// interruption says what cut a scan short: the --timeout or an interrupt
+func interruption(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Sprintf("timed out after %s (--timeout)", scanTimeout)
+	}
+	return "interrupted"
+}
+
+// exitStatus returns the exit code of a scan with totals: exitInterrupted when it was cut short,
+// whatever it found, and otherwise exitFindings when more than --max-risks risks at or above
+// --fail-on-severity were found, unless --fail-on-found is off
+func exitStatus(totals output.Totals) int {
+	if totals.Interrupted {
+		return exitInterrupted
+	}
+	if !failOnFound {
+		return exitClean
+	}
+	failing := totals.Risks
+	if failOnSeverity != "" {
+		failing = 0
+		for severity, count := range totals.RisksBySeverity {
+			if types.SeverityRank(severity) >= types.SeverityRank(failOnSeverity) {
+				failing += count
+			}
+		}
+	}
+	if failing > maxRisks {
+		return exitFindings
+	}
+	return exitClean
+}
+
+// loadFormatTemplate parses the template of --format or --format-file, or returns nil when
+// neither is given. Of two flags that can't be combined, one given on the command line replaces
+// the other's value from the environment or a config file.
+func loadFormatTemplate(cmd *cobra.Command) (*template.Template, error) {
+	text, file := formatTemplate, formatFile
+	formatGiven := fromCommandLine(cmd, "format") || fromCommandLine(cmd, "format-file")
+	switch {
+	case fromCommandLine(cmd, "output") && !formatGiven:
+		return nil, nil
+	case fromCommandLine(cmd, "format") && !fromCommandLine(cmd, "format-file"):
+		file = ""
+	case fromCommandLine(cmd, "format-file") && !fromCommandLine(cmd, "format"):
+		text = ""
+	}
+
+	switch {
+	case text != "" && file != "":
+		return nil, fmt.Errorf("--format and --format-file can't be combined")
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --format-file: %v", err)
+		}
+		text = strings.TrimSuffix(string(data), "\n")
+	case text == "":
+		return nil, nil
+	}
+	if cmd.Flags().Changed("output") && fromCommandLine(cmd, "output") == formatGiven {
+		return nil, fmt.Errorf("--format and --format-file replace --output; use one or the other")
+	}
+
+	tmpl, err := output.ParseTemplate(text)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid --format template: %v", err)
 	}
+	return tmpl, nil
+}
 
-	var packageLock types.PackageLock
-	if err := json.Unmarshal(data, &packageLock); err != nil {
-		return nil, err
+// appendStepSummary adds a Markdown summary of the results to the job summary file
+func appendStepSummary(path string, results []types.ScanResult, config output.OutputConfig) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := output.OutputGitHubSummary(file, results, config); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// useColor decides whether the table written to report is colored. --color always and never
+// win; auto colors only a terminal, and not when $NO_COLOR is set (https://no-color.org).
+func useColor(mode string, report io.Writer) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		file, ok := report.(*os.File)
+		return ok && isTerminal(file), nil
+	default:
+		return false, fmt.Errorf("--color must be auto, always or never, got '%s'", mode)
+	}
+}
+
+// isTerminal reports whether file is a terminal rather than a pipe, a regular file or the null
+// device, which is a character device too
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
+// asciiLocale reports whether the locale names a character set other than UTF-8, in which the
+// table's emoji are unlikely to render. An unset locale says nothing either way.
+func asciiLocale() bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := strings.ToLower(os.Getenv(name)); locale != "" {
+			return !strings.Contains(locale, "utf-8") && !strings.Contains(locale, "utf8")
+		}
+	}
+	return false
+}
+
+// closeReport closes the --output-file report, if there is one, and says where it went
+func closeReport(file *os.File) {
+	if file == nil {
+		return
+	}
+	if err := file.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(exitError)
 	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Report written to %s\n", file.Name())
+	}
+}
+
+// Values of --sort
+const (
+	sortSeverity = "severity"
+	sortList     = "list"
+)
 
-	return &packageLock, nil
+// printWarnings reports warnings on stderr, where they are seen even when stdout is redirected
+func printWarnings(warnings []string) {
+	if quiet {
+		return
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
 }
 
-// readPackagesFromFile reads packages from a JSON file
-func readPackagesFromFile(filePath string) ([]string, error) {
-	// Resolve to absolute path for better error messages and consistency
-	absPath, err := filepath.Abs(filePath)
+// relativePath returns path relative to the working directory with forward slashes, as code
+// scanning tools expect; paths outside it and stdin are returned as they are
+func relativePath(path string) string {
+	if path == stdinPath {
+		return path
+	}
+	wd, err := os.Getwd()
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve path '%s': %v", filePath, err)
+		return filepath.ToSlash(path)
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(path)
 	}
+	return filepath.ToSlash(rel)
+}
 
-	data, err := os.ReadFile(absPath)
+// readPackageLock reads and parses a lockfile, or stdin when path is "-"
+func readPackageLock(path string) (*types.PackageLock, error) {
+	var data []byte
+	var err error
+	if path == stdinPath {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read packages file '%s': %v", absPath, err)
+		return nil, err
 	}
 
-	var packages []string
-	if err := json.Unmarshal(data, &packages); err != nil {
-		return nil, fmt.Errorf("failed to parse packages JSON from '%s': %v", absPath, err)
+	packageLock, err := parseLockfile(path, data)
+	if err != nil {
+		return nil, err
 	}
+	logLockfile(path, packageLock)
 
-	return packages, nil
-}
\ No newline at end of file
+	// Line numbers are only meaningful when the user can open the source file
+	if path == stdinPath {
+		packageLock.Lines = nil
+	}
+
+	return packageLock, nil
+}
+
+// parseLockfile parses lockfile content like scnpm.ParseLockfile, taking package.json manifests
+// when --manifest is set
+func parseLockfile(name string, data []byte) (*types.PackageLock, error) {
+	packageLock, err := scnpm.ParseLockfile(name, data, manifestMode)
+	return packageLock, manifestHint(err)
+}
+
+// manifestHint replaces the error about a package.json given as a lockfile with how to fix it
+func manifestHint(err error) error {
+	if errors.Is(err, scnpm.ErrManifest) {
+		return fmt.Errorf("%v; point --file at package-lock.json (generate one with `npm install --package-lock-only`) "+
+			"or pass --manifest to scan the declared dependency ranges instead", scnpm.ErrManifest)
+	}
+	return err
+}
+
+// unlimitedDepth is the --max-depth value that disables the limit
+const unlimitedDepth = -1
+
+// depthBoundsWarning explains depth filters that exclude every dependency, so an empty report
+// isn't mistaken for a clean one
+func depthBoundsWarning(config scanner.FilterConfig) string {
+	if config.MaxDepth == nil {
+		return ""
+	}
+	lowest := config.MinDepth
+	if config.ShowNestedOnly && lowest < 1 {
+		lowest = 1
+	}
+	if lowest <= *config.MaxDepth {
+		return ""
+	}
+	if config.ShowNestedOnly && config.MinDepth < 1 {
+		return fmt.Sprintf("--nested-only and --max-depth %d exclude every dependency; nothing was reported", *config.MaxDepth)
+	}
+	return fmt.Sprintf("--min-depth %d and --max-depth %d exclude every dependency; nothing was reported", config.MinDepth, *config.MaxDepth)
+}
@@ -1,75 +1,64 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"scnpm/pkg/cache"
+	"scnpm/pkg/scanner"
+	"scnpm/pkg/signature"
 	"scnpm/pkg/types"
+
+	"github.com/fsnotify/fsnotify"
 )
 
-func TestParsePackageQuery(t *testing.T) {
-	tests := []struct {
-		name    string
-		input   string
-		want    types.PackageQuery
-		wantErr bool
-	}{
-		{
-			name:  "simple package",
-			input: "react@18.2.0",
-			want: types.PackageQuery{
-				Name:    "react",
-				Version: "18.2.0",
-			},
-			wantErr: false,
-		},
-		{
-			name:  "scoped package",
-			input: "@types/node@18.0.0",
-			want: types.PackageQuery{
-				Name:    "@types/node",
-				Version: "18.0.0",
-			},
-			wantErr: false,
-		},
-		{
-			name:  "package with complex version",
-			input: "package@^1.2.3",
-			want: types.PackageQuery{
-				Name:    "package",
-				Version: "^1.2.3",
-			},
-			wantErr: false,
-		},
-		{
-			name:    "invalid format - no version",
-			input:   "react",
-			wantErr: true,
-		},
-		{
-			name:  "multiple @ symbols in version",
-			input: "package@1.0.0@beta",
-			want: types.PackageQuery{
-				Name:    "package",
-				Version: "1.0.0@beta",
-			},
-			wantErr: false,
-		},
-	}
+// runMainEnv makes the test binary run main with the arguments after "--", so that tests can
+// check the exit status of real command lines
+const runMainEnv = "SCNPM_TEST_RUN_MAIN"
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := parsePackageQuery(tt.input)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("parsePackageQuery() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !tt.wantErr && (got.Name != tt.want.Name || got.Version != tt.want.Version) {
-				t.Errorf("parsePackageQuery() = %v, want %v", got, tt.want)
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		for i, arg := range os.Args {
+			if arg == "--" {
+				os.Args = append([]string{"scnpm"}, os.Args[i+1:]...)
+				break
 			}
-		})
+		}
+		main()
+		os.Exit(exitClean)
+	}
+	os.Exit(m.Run())
+}
+
+// runCLI runs scnpm with args in dir and returns its exit status and combined output
+func runCLI(t *testing.T, dir string, args ...string) (int, string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^$", "--"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), runMainEnv+"=1", "GITHUB_ACTIONS=", "NO_COLOR=1")
+	out, err := cmd.CombinedOutput()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode(), string(out)
+	} else if err != nil {
+		t.Fatalf("running scnpm %v: %v", args, err)
 	}
+	return 0, string(out)
 }
 
 func TestReadPackagesFromFile(t *testing.T) {
@@ -94,8 +83,8 @@ func TestReadPackagesFromFile(t *testing.T) {
 	}
 
 	for i, pkg := range packages {
-		if pkg != expected[i] {
-			t.Errorf("Package[%d] = %q, want %q", i, pkg, expected[i])
+		if got := pkg.Name + "@" + pkg.Version; got != expected[i] {
+			t.Errorf("Package[%d] = %q, want %q", i, got, expected[i])
 		}
 	}
 
@@ -161,9 +150,1652 @@ func TestReadPackageLock(t *testing.T) {
 		t.Errorf("Number of packages = %d, want %d", len(packageLock.Packages), 1)
 	}
 
+	if line := packageLock.Lines["node_modules/react"]; line != 6 {
+		t.Errorf("Line of node_modules/react = %d, want %d", line, 6)
+	}
+
 	// Test non-existent file
 	_, err = readPackageLock("non-existent-file.json")
 	if err == nil {
 		t.Error("Expected error for non-existent file, got nil")
 	}
-}
\ No newline at end of file
+}
+
+func TestReadPackageLockRejectsManifest(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "package.json")
+
+	content := `{
+		"name": "test-project",
+		"scripts": {"test": "jest"},
+		"dependencies": {"react": "^18.2.0"}
+	}`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if _, err := readPackageLock(testFile); err == nil {
+		t.Error("Expected error for package.json passed as lockfile, got nil")
+	}
+
+	manifestMode = true
+	defer func() { manifestMode = false }()
+
+	packageLock, err := readPackageLock(testFile)
+	if err != nil {
+		t.Fatalf("readPackageLock() with --manifest returned error: %v", err)
+	}
+	if len(packageLock.Packages) != 1 {
+		t.Errorf("Number of packages = %d, want %d", len(packageLock.Packages), 1)
+	}
+}
+
+func TestReadPackagesFromFileObjectForm(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "badpak.json")
+
+	content := `{"@ctrl/tinycolor": ["4.1.1", "4.1.2"], "debug": ["4.4.2"]}`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	packages, err := readPackagesFromFile(testFile)
+	if err != nil {
+		t.Fatalf("readPackagesFromFile() returned error: %v", err)
+	}
+
+	expected := []string{"@ctrl/tinycolor@4.1.1", "@ctrl/tinycolor@4.1.2", "debug@4.4.2"}
+	if len(packages) != len(expected) {
+		t.Fatalf("readPackagesFromFile() returned %v, want %v", packages, expected)
+	}
+	for i, pkg := range packages {
+		if got := pkg.Name + "@" + pkg.Version; got != expected[i] {
+			t.Errorf("Package[%d] = %q, want %q", i, got, expected[i])
+		}
+	}
+
+	// Mixed/malformed object values must name the offending key
+	mixedFile := filepath.Join(tmpDir, "mixed.json")
+	if err := os.WriteFile(mixedFile, []byte(`{"debug": ["4.4.2"], "chalk": "5.6.1"}`), 0644); err != nil {
+		t.Fatalf("Failed to create mixed test file: %v", err)
+	}
+
+	_, err = readPackagesFromFile(mixedFile)
+	if err == nil || !strings.Contains(err.Error(), "'chalk'") {
+		t.Errorf("Expected error naming 'chalk', got %v", err)
+	}
+}
+
+func TestReadPackagesFromFileRichEntries(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "badpak.json")
+
+	content := `[
+		{"name": "event-stream", "version": "3.3.6", "severity": "Critical", "advisory": "GHSA-mh6f-8j2x-4483", "note": "flatmap-stream payload"},
+		{"name": "ua-parser-js", "version": "0.7.29", "severity": "medium"},
+		"debug@4.4.2"
+	]`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	queries, err := readPackagesFromFile(testFile)
+	if err != nil {
+		t.Fatalf("readPackagesFromFile() returned error: %v", err)
+	}
+
+	want := []types.PackageQuery{
+		{Name: "event-stream", Version: "3.3.6", Severity: "critical", Advisory: "GHSA-mh6f-8j2x-4483", Note: "flatmap-stream payload"},
+		{Name: "ua-parser-js", Version: "0.7.29", Severity: "moderate"},
+		{Name: "debug", Version: "4.4.2"},
+	}
+	if len(queries) != len(want) {
+		t.Fatalf("readPackagesFromFile() returned %d queries, want %d", len(queries), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(queries[i], want[i]) {
+			t.Errorf("Query[%d] = %+v, want %+v", i, queries[i], want[i])
+		}
+	}
+
+	invalidFile := filepath.Join(tmpDir, "invalid-severity.json")
+	if err := os.WriteFile(invalidFile, []byte(`[{"name": "coa", "version": "2.0.3", "severity": "urgent"}]`), 0644); err != nil {
+		t.Fatalf("Failed to create invalid test file: %v", err)
+	}
+	if _, err := readPackagesFromFile(invalidFile); err == nil || !strings.Contains(err.Error(), "urgent") {
+		t.Errorf("Expected error naming the unknown severity, got %v", err)
+	}
+}
+
+func TestReadPackagesFromFileIntegrity(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "iocs.json")
+
+	content := `[{"name": "foo", "integrity": "sha512-AAAA"}, {"name": "foo", "version": "1.0.0", "integrity": "sha512-BBBB"}]`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	queries, err := readPackagesFromFile(testFile)
+	if err != nil {
+		t.Fatalf("readPackagesFromFile() returned error: %v", err)
+	}
+	want := []types.PackageQuery{
+		{Name: "foo", Integrity: "sha512-AAAA"},
+		{Name: "foo", Version: "1.0.0", Integrity: "sha512-BBBB"},
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("readPackagesFromFile() = %+v, want %+v", queries, want)
+	}
+
+	badFile := filepath.Join(tmpDir, "bad.json")
+	if err := os.WriteFile(badFile, []byte(`[{"name": "foo", "integrity": "md5-AAAA"}]`), 0644); err != nil {
+		t.Fatalf("Failed to create bad test file: %v", err)
+	}
+	if _, err := readPackagesFromFile(badFile); err == nil || !strings.Contains(err.Error(), "md5") {
+		t.Errorf("Expected error naming the unsupported algorithm, got %v", err)
+	}
+}
+
+func TestReadPackagesFromFileCSV(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	csvFile := filepath.Join(tmpDir, "iocs.csv")
+	csvContent := "name,version,severity,reference\n" +
+		"\n" +
+		"\"@ctrl/tinycolor\",4.1.1,critical,\"https://example.com/advisory?a=1,2\"\n" +
+		"node-ipc,,high,\n" +
+		"debug@4.4.2,,,\n"
+	if err := os.WriteFile(csvFile, []byte(csvContent), 0644); err != nil {
+		t.Fatalf("Failed to create CSV file: %v", err)
+	}
+
+	jsonFile := filepath.Join(tmpDir, "iocs.json")
+	jsonContent := `[
+		{"name": "@ctrl/tinycolor", "version": "4.1.1", "severity": "critical", "advisory": "https://example.com/advisory?a=1,2"},
+		{"name": "node-ipc", "severity": "high"},
+		"debug@4.4.2"
+	]`
+	if err := os.WriteFile(jsonFile, []byte(jsonContent), 0644); err != nil {
+		t.Fatalf("Failed to create JSON file: %v", err)
+	}
+
+	fromCSV, err := readPackagesFromFile(csvFile)
+	if err != nil {
+		t.Fatalf("readPackagesFromFile() returned error for CSV: %v", err)
+	}
+	fromJSON, err := readPackagesFromFile(jsonFile)
+	if err != nil {
+		t.Fatalf("readPackagesFromFile() returned error for JSON: %v", err)
+	}
+
+	if len(fromCSV) != 3 || len(fromCSV) != len(fromJSON) {
+		t.Fatalf("CSV queries = %+v, JSON queries = %+v", fromCSV, fromJSON)
+	}
+	for i := range fromJSON {
+		if !reflect.DeepEqual(fromCSV[i], fromJSON[i]) {
+			t.Errorf("Query[%d] from CSV = %+v, from JSON = %+v", i, fromCSV[i], fromJSON[i])
+		}
+	}
+	if fromCSV[1].Version != "" {
+		t.Errorf("Row without version parsed as %q, want any version", fromCSV[1].Version)
+	}
+
+	// Parse errors report the row number
+	badFile := filepath.Join(tmpDir, "bad.csv")
+	if err := os.WriteFile(badFile, []byte("lodash,4.17.21\n,1.0.0\n"), 0644); err != nil {
+		t.Fatalf("Failed to create bad CSV file: %v", err)
+	}
+	if _, err := readPackagesFromFile(badFile); err == nil || !strings.Contains(err.Error(), "row 2") {
+		t.Errorf("Expected error naming row 2, got %v", err)
+	}
+}
+
+func TestReadPackagesFromFileText(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	content := "# Shai-Hulud wave, copied from the advisory post\n" +
+		"\n" +
+		"  @ctrl/tinycolor@4.1.1  \n" +
+		"ngx-bootstrap@18.1.4\n" +
+		"@ctrl/tinycolor@4.1.1\n"
+
+	for _, name := range []string{"iocs.txt", "iocs.list"} {
+		t.Run(name, func(t *testing.T) {
+			testFile := filepath.Join(tmpDir, name)
+			if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			queries, err := readPackagesFromFile(testFile)
+			if err != nil {
+				t.Fatalf("readPackagesFromFile() returned error: %v", err)
+			}
+
+			want := []types.PackageQuery{
+				{Name: "@ctrl/tinycolor", Version: "4.1.1"},
+				{Name: "ngx-bootstrap", Version: "18.1.4"},
+			}
+			if len(queries) != len(want) {
+				t.Fatalf("readPackagesFromFile() returned %+v, want %+v", queries, want)
+			}
+			for i := range want {
+				if !reflect.DeepEqual(queries[i], want[i]) {
+					t.Errorf("Query[%d] = %+v, want %+v", i, queries[i], want[i])
+				}
+			}
+		})
+	}
+
+	badFile := filepath.Join(tmpDir, "bad.txt")
+	if err := os.WriteFile(badFile, []byte("debug@4.4.2\n@chalk\n"), 0644); err != nil {
+		t.Fatalf("Failed to create bad test file: %v", err)
+	}
+	if _, err := readPackagesFromFile(badFile); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected error naming line 2, got %v", err)
+	}
+}
+
+func TestReadPackagesFromFileYAML(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name: "sequence.yaml",
+			content: "- debug@4.4.2\n" +
+				"- name: \"@ctrl/tinycolor\"\n" +
+				"  version: 4.1.1\n" +
+				"  severity: critical\n" +
+				"  note: Shai-Hulud\n",
+		},
+		{
+			name: "mapping.yml",
+			content: "debug:\n" +
+				"  - 4.4.2\n" +
+				"\"@ctrl/tinycolor\":\n" +
+				"  versions: [4.1.1]\n" +
+				"  severity: critical\n" +
+				"  note: Shai-Hulud\n",
+		},
+	}
+
+	want := []types.PackageQuery{
+		{Name: "debug", Version: "4.4.2"},
+		{Name: "@ctrl/tinycolor", Version: "4.1.1", Severity: types.SeverityCritical, Note: "Shai-Hulud"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(tmpDir, tt.name)
+			if err := os.WriteFile(testFile, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			queries, err := readPackagesFromFile(testFile)
+			if err != nil {
+				t.Fatalf("readPackagesFromFile() returned error: %v", err)
+			}
+			if !reflect.DeepEqual(queries, want) {
+				t.Errorf("readPackagesFromFile() = %+v, want %+v", queries, want)
+			}
+		})
+	}
+
+	// Validation errors report the YAML line
+	badFile := filepath.Join(tmpDir, "bad.yaml")
+	if err := os.WriteFile(badFile, []byte("- debug@4.4.2\n- name: chalk\n  version: 5.6.1\n  severity: urgent\n"), 0644); err != nil {
+		t.Fatalf("Failed to create bad test file: %v", err)
+	}
+	if _, err := readPackagesFromFile(badFile); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected error naming line 2, got %v", err)
+	}
+}
+
+func TestExpandPackagesPaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"badpaks/2025-09-shai-hulud.json": `["@ctrl/tinycolor@4.1.1"]`,
+		"badpaks/2025-09-chalk.yaml":      "- debug@4.4.2\n",
+		"badpaks/README.md":               "not a list",
+		"badpaks/old/2021-ua-parser.txt":  "ua-parser-js@0.7.29\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	dir := filepath.Join(tmpDir, "badpaks")
+	single := filepath.Join(tmpDir, "single.json")
+
+	got, err := expandPackagesPaths([]string{dir, single}, true)
+	if err != nil {
+		t.Fatalf("expandPackagesPaths() returned error: %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "2025-09-chalk.yaml"),
+		filepath.Join(dir, "2025-09-shai-hulud.json"),
+		filepath.Join(dir, "old", "2021-ua-parser.txt"),
+		single,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandPackagesPaths() = %v, want %v", got, want)
+	}
+
+	got, err = expandPackagesPaths([]string{dir}, false)
+	if err != nil {
+		t.Fatalf("expandPackagesPaths() without recursion returned error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("expandPackagesPaths() without recursion = %v, want the 2 top-level lists", got)
+	}
+
+	empty := filepath.Join(tmpDir, "empty")
+	if err := os.Mkdir(empty, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if _, err := expandPackagesPaths([]string{empty}, true); err == nil {
+		t.Error("Expected error for an empty packages directory, got nil")
+	}
+}
+
+func TestDirectoryArguments(t *testing.T) {
+	dir := t.TempDir()
+	lockfile := `{"lockfileVersion": 3, "packages": {"": {"name": "app"}, "node_modules/chalk": {"version": "5.3.0"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "package-lock.json"), []byte(lockfile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range []string{"chalk", "lists"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lists", "team.txt"), []byte("chalk@5.3.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{args: []string{"--no-builtin", "chalk"}, want: exitFindings},
+		{args: []string{"--no-builtin", "./lists"}, want: exitFindings},
+		{args: []string{"--no-builtin", "lists/"}, want: exitFindings},
+		{args: []string{"--no-builtin", "./chalk"}, want: exitError},
+	}
+	for _, tt := range tests {
+		if got, out := runCLI(t, dir, tt.args...); got != tt.want {
+			t.Errorf("scnpm %s exited %d, want %d:\n%s", strings.Join(tt.args, " "), got, tt.want, out)
+		}
+	}
+}
+
+func TestReadPackagesFromURL(t *testing.T) {
+	c := &cache.Cache{Dir: t.TempDir(), TTL: time.Hour}
+
+	failing := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`["lodash@4.17.20", "@types/node@18.0.0"]`))
+	}))
+	defer server.Close()
+
+	rawURL := server.URL + "/badpak.json?signature=abc"
+	queries, warnings, err := readPackagesFromURL(context.Background(), server.Client(), c, nil, rawURL, "Bearer secret", false)
+	if err != nil {
+		t.Fatalf("readPackagesFromURL() error = %v", err)
+	}
+	if len(queries) != 2 || len(warnings) != 0 {
+		t.Fatalf("readPackagesFromURL() = %+v, %v, want 2 queries and no warnings", queries, warnings)
+	}
+	if queries[0].Source != server.URL+"/badpak.json" || queries[0].FetchedAt == "" {
+		t.Errorf("readPackagesFromURL() source = %q fetched at %q, want URL without query string and a fetch time", queries[0].Source, queries[0].FetchedAt)
+	}
+
+	if _, _, err := readPackagesFromURL(context.Background(), server.Client(), c, nil, rawURL, "", false); err == nil {
+		t.Error("readPackagesFromURL() expected error without authorization")
+	}
+
+	failing = true
+	if _, _, err := readPackagesFromURL(context.Background(), server.Client(), c, nil, rawURL, "Bearer secret", false); err == nil {
+		t.Error("readPackagesFromURL() expected error when the server fails")
+	}
+	queries, warnings, err = readPackagesFromURL(context.Background(), server.Client(), c, nil, rawURL, "Bearer secret", true)
+	if err != nil {
+		t.Fatalf("readPackagesFromURL() with stale cache error = %v", err)
+	}
+	if len(queries) != 2 || len(warnings) != 1 {
+		t.Errorf("readPackagesFromURL() with stale cache = %+v, %v, want cached queries and a warning", queries, warnings)
+	}
+
+	if _, _, err := readPackagesFromURL(context.Background(), server.Client(), c, nil, server.URL+"/other.json", "Bearer secret", true); err == nil {
+		t.Error("readPackagesFromURL() expected error when nothing is cached")
+	}
+	if _, _, err := readPackagesFromURL(context.Background(), server.Client(), nil, nil, rawURL, "Bearer secret", true); err == nil {
+		t.Error("readPackagesFromURL() expected error with stale cache allowed but the cache disabled")
+	}
+	if _, _, err := readPackagesFromURL(context.Background(), http.DefaultClient, c, nil, "http://example.com/badpak.json", "", false); err == nil {
+		t.Error("readPackagesFromURL() expected error for plain http URL")
+	}
+}
+
+func TestWebhookURL(t *testing.T) {
+	defer func(format string) { notifyFormat = format }(notifyFormat)
+	notifyFormat = "json"
+
+	t.Setenv(webhookURLEnv, "")
+	if got, err := webhookURL(); err != nil || got != "" {
+		t.Errorf("webhookURL() without one = %q, %v", got, err)
+	}
+	t.Setenv(webhookURLEnv, "https://hooks.example.com/services/T0/B0/secret")
+	if got, err := webhookURL(); err != nil || got != "https://hooks.example.com/services/T0/B0/secret" {
+		t.Errorf("webhookURL() from the environment = %q, %v", got, err)
+	}
+
+	t.Setenv(webhookURLEnv, "http://hooks.example.com/secret")
+	if _, err := webhookURL(); err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("webhookURL() for http = %v, want an error without the URL", err)
+	}
+	t.Setenv(webhookURLEnv, "https://chat.example.com/hook")
+	notifyFormat = "teams"
+	if _, err := webhookURL(); err == nil {
+		t.Error("webhookURL() expected error for an unknown --notify-format")
+	}
+}
+
+func TestPostNotification(t *testing.T) {
+	attempts := 0
+	var body []byte
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	client := newNotifyClient()
+	client.HTTP, client.Backoff = server.Client(), 0
+	if err := postNotification(context.Background(), client, server.URL+"/hook", []byte(`{"text":"hi"}`)); err != nil {
+		t.Fatalf("postNotification() error = %v", err)
+	}
+	if attempts != 2 || string(body) != `{"text":"hi"}` {
+		t.Errorf("postNotification() took %d attempts and delivered %q, want a retry and the payload", attempts, body)
+	}
+
+	server.Close()
+	err := postNotification(context.Background(), client, server.URL+"/secret", []byte(`{}`))
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("postNotification() to a closed server = %v, want an error without the URL", err)
+	}
+}
+
+func TestMixedCaseWarnings(t *testing.T) {
+	queries := []types.PackageQuery{
+		{Name: "JSONStream", Version: "1.3.1"},
+		{Name: "JSONStream", Version: "1.3.2"},
+		{Name: "@Acme/utils"},
+		{Name: "event-stream", Version: "3.3.6"},
+		{Name: "re:[A-Z]+"},
+	}
+
+	got := mixedCaseWarnings(queries)
+	if len(got) != 2 || !strings.Contains(got[0], "'JSONStream'") || !strings.Contains(got[1], "'@Acme/utils'") {
+		t.Errorf("mixedCaseWarnings() = %q, want one warning each for JSONStream and @Acme/utils", got)
+	}
+}
+
+func TestFetchDatabase(t *testing.T) {
+	database := []byte(`{"schemaVersion": 1, "updated": "2999-01-01T00:00:00Z", "entries": [{"name": "evil", "versions": ["1.0.0"]}]}`)
+	sum := sha256.Sum256(database)
+	checksum := hex.EncodeToString(sum[:]) + "  advisories.json\n"
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/advisories.json", "/tampered.json":
+			w.Write(database)
+		case "/advisories.json.sha256":
+			w.Write([]byte(checksum))
+		case "/tampered.json.sha256":
+			w.Write([]byte(strings.Repeat("0", 64)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	db, data, _, err := fetchDatabase(context.Background(), server.Client(), nil, server.URL+"/advisories.json")
+	if err != nil {
+		t.Fatalf("fetchDatabase() error = %v", err)
+	}
+	if len(db.Entries) != 1 || string(data) != string(database) {
+		t.Errorf("fetchDatabase() = %+v, want the served database", db)
+	}
+
+	if _, _, _, err := fetchDatabase(context.Background(), server.Client(), nil, server.URL+"/tampered.json"); err == nil {
+		t.Error("fetchDatabase() expected error for checksum mismatch")
+	}
+	if _, _, _, err := fetchDatabase(context.Background(), server.Client(), nil, server.URL+"/missing.json"); err == nil {
+		t.Error("fetchDatabase() expected error for missing database")
+	}
+}
+
+func TestReadPackagesFromURLSigned(t *testing.T) {
+	// Signed with: ssh-keygen -Y sign -f key -n file list.json
+	list, _ := os.ReadFile("pkg/signature/testdata/list.json")
+	sig, _ := os.ReadFile("pkg/signature/testdata/list.json.sshsig")
+	key, err := signature.LoadPublicKey("pkg/signature/testdata/ssh.pub")
+	if err != nil {
+		t.Fatalf("LoadPublicKey() error = %v", err)
+	}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/list.json", "/unsigned.json":
+			w.Write(list)
+		case "/list.json.sig":
+			w.Write(sig)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	c := &cache.Cache{Dir: t.TempDir(), TTL: time.Hour}
+
+	queries, _, err := readPackagesFromURL(context.Background(), server.Client(), c, key, server.URL+"/list.json", "", false)
+	if err != nil {
+		t.Fatalf("readPackagesFromURL() error = %v", err)
+	}
+	if len(queries) != 1 || queries[0].SignedBy != key.Fingerprint() {
+		t.Errorf("readPackagesFromURL() = %+v, want one query signed by %s", queries, key.Fingerprint())
+	}
+
+	if _, _, err := readPackagesFromURL(context.Background(), server.Client(), c, key, server.URL+"/unsigned.json", "", false); err == nil {
+		t.Error("readPackagesFromURL() expected error for an unsigned list")
+	}
+
+	insecureSkipVerify = true
+	defer func() { insecureSkipVerify = false }()
+	queries, warnings, err := readPackagesFromURL(context.Background(), server.Client(), c, key, server.URL+"/unsigned.json", "", false)
+	if err != nil || len(queries) != 1 || len(warnings) != 1 || queries[0].SignedBy != "" {
+		t.Errorf("readPackagesFromURL() with --insecure-skip-verify = %+v, %v, %v, want unsigned queries and a warning", queries, warnings, err)
+	}
+	if _, _, ok := c.GetStale(packagesCacheNamespace, server.URL+"/unsigned.json"); ok {
+		t.Error("readPackagesFromURL() cached a list that failed verification")
+	}
+	if _, _, ok := c.GetStale(packagesCacheNamespace, server.URL+"/list.json"); !ok {
+		t.Error("readPackagesFromURL() didn't cache the verified list")
+	}
+}
+
+func TestResolveDistTags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chalk" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"name": "chalk", "dist-tags": {"latest": "5.3.0"}}`))
+	}))
+	defer server.Close()
+
+	defer func(registry string) { registryURL, noCache = registry, false }(registryURL)
+	registryURL, noCache = server.URL, true
+
+	queries := []types.PackageQuery{
+		{Name: "chalk", Version: "latest"},
+		{Name: "missing", Version: "next"},
+		{Name: "lodash", Version: "4.17.20"},
+	}
+	got, warnings := resolveDistTags(context.Background(), queries)
+
+	want := []types.PackageQuery{
+		{Name: "chalk", Version: "5.3.0", Tag: "latest"},
+		{Name: "missing", Version: "next", Tag: "next"},
+		{Name: "lodash", Version: "4.17.20"},
+	}
+	if got[1].Error == "" || len(warnings) != 1 {
+		t.Errorf("resolveDistTags() = %+v, %v, want an error result and a warning for missing@next", got[1], warnings)
+	}
+	got[1].Error = ""
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveDistTags() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDepthBoundsWarning(t *testing.T) {
+	zero, one := 0, 1
+	tests := []struct {
+		name        string
+		config      scanner.FilterConfig
+		wantWarning bool
+	}{
+		{"no limit", scanner.FilterConfig{MinDepth: 3}, false},
+		{"direct only", scanner.FilterConfig{MaxDepth: &zero}, false},
+		{"equal bounds", scanner.FilterConfig{MinDepth: 1, MaxDepth: &one}, false},
+		{"min above max", scanner.FilterConfig{MinDepth: 2, MaxDepth: &one}, true},
+		{"nested with direct only", scanner.FilterConfig{ShowNestedOnly: true, MaxDepth: &zero}, true},
+		{"nested within bounds", scanner.FilterConfig{ShowNestedOnly: true, MaxDepth: &one}, false},
+	}
+
+	for _, tt := range tests {
+		if got := depthBoundsWarning(tt.config); (got != "") != tt.wantWarning {
+			t.Errorf("%s: depthBoundsWarning() = %q, want warning %v", tt.name, got, tt.wantWarning)
+		}
+	}
+}
+
+func TestReadArchive(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "repo.zip")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := zip.NewWriter(file)
+	for _, name := range []string{"a/package-lock.json", "README.md", "b/yarn.lock"} {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(`{"lockfileVersion":3,"packages":{"":{},"node_modules/event-stream":{"version":"3.3.6"}}}`))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	lockfiles, warnings, err := readArchive(archivePath)
+	if err != nil || len(warnings) != 0 {
+		t.Fatalf("readArchive() = %v, %v, want the lockfiles without warnings", warnings, err)
+	}
+	var names []string
+	for _, lockfile := range lockfiles {
+		names = append(names, lockfile.Name)
+	}
+	if strings.Join(names, ",") != "a/package-lock.json,b/yarn.lock" {
+		t.Errorf("readArchive() names = %v, want the two lockfiles by their path in the archive", names)
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		level, format string
+		want          string // Output of a debug and an info record
+		wantErr       bool
+	}{
+		{level: "warn", format: "text", want: ""},
+		{level: "info", format: "text", want: "level=INFO msg=loaded count=3\n"},
+		{level: "DEBUG", format: "text", want: "level=DEBUG msg=considered count=3\nlevel=INFO msg=loaded count=3\n"},
+		{level: "info", format: "json", want: `{"level":"INFO","msg":"loaded","count":3}` + "\n"},
+		{level: "verbose", format: "text", wantErr: true},
+		{level: "info", format: "yaml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		logger, err := newLogger(&buf, tt.level, tt.format)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("newLogger(%s, %s) error = %v, wantErr %v", tt.level, tt.format, err, tt.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		logger.Debug("considered", "count", 3)
+		logger.Info("loaded", "count", 3)
+		// Drop the timestamps, which differ between runs
+		got := regexp.MustCompile(`time=\S+ |"time":"[^"]+",`).ReplaceAllString(buf.String(), "")
+		if got != tt.want {
+			t.Errorf("newLogger(%s, %s) wrote %q, want %q", tt.level, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestWatchLoop(t *testing.T) {
+	dir := t.TempDir()
+	lockfile := filepath.Join(dir, "package-lock.json")
+	events := make(chan fsnotify.Event)
+	errs := make(chan error)
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := make(chan []string)
+	done := make(chan bool)
+	go func() {
+		watchLoop(ctx, events, errs, func(path string) bool { return path == lockfile }, 50*time.Millisecond, func(changed []string) {
+			runs <- changed
+		})
+		close(done)
+	}()
+
+	// npm writes the lockfile several times in a row; that's a single scan
+	events <- fsnotify.Event{Name: lockfile, Op: fsnotify.Create}
+	events <- fsnotify.Event{Name: lockfile, Op: fsnotify.Write}
+	events <- fsnotify.Event{Name: filepath.Join(dir, "README.md"), Op: fsnotify.Write}
+	events <- fsnotify.Event{Name: lockfile, Op: fsnotify.Write}
+	select {
+	case changed := <-runs:
+		if len(changed) != 1 || !strings.HasSuffix(changed[0], "package-lock.json") {
+			t.Errorf("changed = %v, want the lockfile", changed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no scan after the lockfile changed")
+	}
+	select {
+	case changed := <-runs:
+		t.Errorf("second scan for %v, want the writes debounced into one", changed)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// Unwatched files and permission changes don't trigger a scan
+	events <- fsnotify.Event{Name: filepath.Join(dir, "README.md"), Op: fsnotify.Write}
+	events <- fsnotify.Event{Name: lockfile, Op: fsnotify.Chmod}
+	select {
+	case changed := <-runs:
+		t.Errorf("scan for %v, want none", changed)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watchLoop didn't stop when its context was canceled")
+	}
+}
+
+func TestWatchStatus(t *testing.T) {
+	summary := func(risks int, risky ...string) *watchSummary {
+		s := &watchSummary{Risky: risky}
+		s.Summary.Risks = risks
+		return s
+	}
+	tests := []struct {
+		name     string
+		previous *watchSummary
+		current  *watchSummary
+		want     string
+	}{
+		{"first run", nil, summary(1, "evil"), "\a1 new risky package(s): evil (1 risks in total)"},
+		{"clean", nil, summary(0), "No risks; waiting for changes"},
+		{"unchanged", summary(2, "evil"), summary(2, "evil"), "No new risks (2 risks in total); waiting for changes"},
+		{"new risk", summary(1, "evil"), summary(3, "evil", "worse"), "\a1 new risky package(s): worse (3 risks in total)"},
+		{"fixed", summary(1, "evil"), summary(0), "No risks; waiting for changes"},
+	}
+	for _, tt := range tests {
+		if got := watchStatus(tt.previous, tt.current); got != tt.want {
+			t.Errorf("%s: watchStatus() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWithoutWatchFlag(t *testing.T) {
+	got := withoutWatchFlag([]string{"--watch", "-f", "app/package-lock.json", "--watch=true", "badpak.json"})
+	want := []string{"--watch=false", "-f", "app/package-lock.json", "badpak.json"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("withoutWatchFlag() = %v, want %v", got, want)
+	}
+}
+
+func TestScanServer(t *testing.T) {
+	lockfile := `{
+		"name": "app",
+		"lockfileVersion": 3,
+		"packages": {
+			"": {"name": "app"},
+			"node_modules/evil": {"version": "1.0.0"},
+			"node_modules/meh": {"version": "2.0.0", "dev": true}
+		}
+	}`
+	startup := []types.PackageQuery{{Name: "evil", Version: "1.0.0", Source: "badpak.json", Sources: []string{"badpak.json"}}}
+	server := httptest.NewServer(newScanServer(startup, 4096, 2))
+	defer server.Close()
+
+	post := func(query, body string) (int, map[string]any) {
+		t.Helper()
+		resp, err := http.Post(server.URL+"/scan"+query, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var document map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+			t.Fatalf("POST /scan%s: invalid JSON response: %v", query, err)
+		}
+		return resp.StatusCode, document
+	}
+	risks := func(document map[string]any) float64 {
+		summary, _ := document["summary"].(map[string]any)
+		risks, _ := summary["risks"].(float64)
+		return risks
+	}
+
+	status, document := post("", lockfile)
+	if status != http.StatusOK || risks(document) != 1 || document["schemaVersion"] == nil {
+		t.Errorf("POST /scan = %d with %d risks, want 200 with the startup list's risk in a versioned report", status, int(risks(document)))
+	}
+	status, document = post("?packages=meh@2.0.0", lockfile)
+	if status != http.StatusOK || risks(document) != 2 {
+		t.Errorf("POST /scan?packages= found %d risks, want the request's package too", int(risks(document)))
+	}
+	status, document = post("?packages=meh@2.0.0&dev-only", lockfile)
+	if status != http.StatusOK || risks(document) != 1 {
+		t.Errorf("POST /scan?dev-only found %d risks, want only the dev dependency", int(risks(document)))
+	}
+	envelope := `{"lockfile": ` + lockfile + `, "lockfileName": "app/package-lock.json", "packages": ["meh@2.0.0"], "options": {"noDefaults": true}}`
+	status, document = post("", envelope)
+	if status != http.StatusOK || risks(document) != 1 || !reflect.DeepEqual(document["lockfiles"], []any{"app/package-lock.json"}) {
+		t.Errorf("POST /scan envelope = %d, %d risks in %v, want only the request's package in app/package-lock.json", status, int(risks(document)), document["lockfiles"])
+	}
+	if len(startup[0].Sources) != 1 {
+		t.Errorf("startup query sources = %v, want them untouched by requests", startup[0].Sources)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		query string
+		body  string
+		want  int
+	}{
+		{"invalid lockfile", "", "{", http.StatusBadRequest},
+		{"empty body", "", "", http.StatusBadRequest},
+		{"invalid option", "?fuzzy=maybe", lockfile, http.StatusBadRequest},
+		{"nothing to scan for", "?no-defaults", lockfile, http.StatusBadRequest},
+		{"too large", "", lockfile + strings.Repeat(" ", 4096), http.StatusRequestEntityTooLarge},
+	} {
+		if status, document := post(tt.query, tt.body); status != tt.want || document["error"] == nil {
+			t.Errorf("%s: POST /scan = %d, %v, want %d with an error", tt.name, status, document, tt.want)
+		}
+	}
+
+	for path, want := range map[string]string{"/healthz": `"queries":1`, "/version": `"version":"dev"`} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), want) {
+			t.Errorf("GET %s = %d %s, want %s", path, resp.StatusCode, body, want)
+		}
+	}
+	resp, err := http.Get(server.URL + "/scan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /scan = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestScanServerInterrupted(t *testing.T) {
+	handler := newScanServer([]types.PackageQuery{{Name: "evil"}}, 4096, 1)
+	lockfile := `{"lockfileVersion": 3, "packages": {"": {"name": "app"}, "node_modules/evil": {"version": "1.0.0"}}}`
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	for _, tt := range []struct {
+		name string
+		ctx  context.Context
+		want int
+	}{
+		{"client gone", canceled, statusClientClosedRequest},
+		{"timed out", expired, http.StatusServiceUnavailable},
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/scan", strings.NewReader(lockfile)).WithContext(tt.ctx)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != tt.want || !strings.Contains(recorder.Body.String(), "scan interrupted") {
+			t.Errorf("%s: POST /scan = %d %s, want %d, not an invalid lockfile", tt.name, recorder.Code, recorder.Body, tt.want)
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range completionShells {
+		status, out := runCLI(t, t.TempDir(), "completion", shell)
+		if status != exitClean || !strings.Contains(out, "__complete") {
+			t.Errorf("scnpm completion %s = %d, want a script calling scnpm __complete:\n%s", shell, status, out)
+		}
+	}
+	if status, _ := runCLI(t, t.TempDir(), "completion", "tcsh"); status != exitError {
+		t.Errorf("scnpm completion tcsh = %d, want %d", status, exitError)
+	}
+
+	dir := t.TempDir()
+	lockfile := `{"lockfileVersion": 3, "packages": {"": {"name": "app"}, "node_modules/evil": {"version": "1.0.0"}, "node_modules/express": {"version": "4.0.0"}}}`
+	for _, path := range []string{"package-lock.json", "web/package-lock.json", "web/node_modules/x/package-lock.json"} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(lockfile), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"--output", ""}, outputFormats},
+		{[]string{"list", "-o", ""}, []string{"table", "json", "csv"}},
+		{[]string{"--fail-on-severity", ""}, []string{"critical", "high", "moderate", "low"}},
+		{[]string{"--file", ""}, []string{"package-lock.json", "web/package-lock.json"}},
+		{[]string{"stats", "-f", "web/"}, []string{"web/package-lock.json"}},
+		{[]string{"why", "e"}, []string{"evil", "express"}},
+		{[]string{"why", "-f", "web/package-lock.json", "ex"}, []string{"express"}},
+		{[]string{"why", "evil", ""}, nil},
+	}
+	for _, tt := range tests {
+		status, out := runCLI(t, dir, append([]string{"__complete"}, tt.args...)...)
+		// The candidates are followed by a line with the shell directive, like ":4"
+		var got []string
+		for _, line := range strings.Split(out, "\n") {
+			if strings.HasPrefix(line, ":") {
+				break
+			}
+			got = append(got, line)
+		}
+		if status != exitClean || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("scnpm __complete %v = %d, %q, want %q", tt.args, status, got, tt.want)
+		}
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "report.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	tests := []struct {
+		mode    string
+		noColor string
+		report  io.Writer
+		want    bool
+		wantErr bool
+	}{
+		{mode: "always", noColor: "1", report: file, want: true},
+		{mode: "never", report: file, want: false},
+		{mode: "auto", report: file, want: false},
+		{mode: "auto", report: &bytes.Buffer{}, want: false},
+		{mode: "auto", noColor: "1", report: os.Stdout, want: false},
+		{mode: "sometimes", report: file, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("NO_COLOR", tt.noColor)
+		got, err := useColor(tt.mode, tt.report)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("useColor(%s) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("useColor(%s) with NO_COLOR=%q = %v, want %v", tt.mode, tt.noColor, got, tt.want)
+		}
+	}
+}
+
+func TestASCIILocale(t *testing.T) {
+	tests := []struct {
+		lcAll, lcCtype, lang string
+		want                 bool
+	}{
+		{want: false},
+		{lang: "en_US.UTF-8", want: false},
+		{lang: "C.utf8", want: false},
+		{lang: "C", want: true},
+		{lang: "en_US.UTF-8", lcCtype: "POSIX", want: true},
+		{lcAll: "de_DE.UTF-8", lcCtype: "POSIX", lang: "C", want: false},
+		{lang: "en_US.ISO-8859-1", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("LC_ALL", tt.lcAll)
+		t.Setenv("LC_CTYPE", tt.lcCtype)
+		t.Setenv("LANG", tt.lang)
+		if got := asciiLocale(); got != tt.want {
+			t.Errorf("asciiLocale() with LC_ALL=%q LC_CTYPE=%q LANG=%q = %v, want %v", tt.lcAll, tt.lcCtype, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	lockfile := `{
+		"name": "app",
+		"lockfileVersion": 3,
+		"packages": {
+			"": {"name": "app"},
+			"node_modules/evil": {"version": "1.0.0"},
+			"node_modules/meh": {"version": "2.0.0"}
+		}
+	}`
+	list := `[{"name": "evil", "version": "1.0.0", "severity": "critical"}, {"name": "meh", "version": "2.0.0", "severity": "low"}]`
+	if err := os.WriteFile(filepath.Join(dir, "package-lock.json"), []byte(lockfile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "badpak.json"), []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "clean", args: []string{"--no-builtin", "safe@1.0.0"}, want: exitClean},
+		{name: "findings", args: []string{"--no-builtin", "badpak.json"}, want: exitFindings},
+		{name: "quiet findings", args: []string{"--no-builtin", "-q", "badpak.json"}, want: exitFindings},
+		{name: "json findings", args: []string{"--no-builtin", "-o", "json", "badpak.json"}, want: exitFindings},
+		{name: "ndjson findings", args: []string{"--no-builtin", "-o", "ndjson", "badpak.json"}, want: exitFindings},
+		{name: "fail-on-found off", args: []string{"--no-builtin", "--fail-on-found=false", "badpak.json"}, want: exitClean},
+		{name: "below severity", args: []string{"--no-builtin", "--fail-on-severity", "critical", "meh@2.0.0"}, want: exitClean},
+		{name: "at severity", args: []string{"--no-builtin", "--fail-on-severity", "high", "badpak.json"}, want: exitFindings},
+		{name: "unknown severity", args: []string{"--no-builtin", "--fail-on-severity", "low", "evil@1.0.0"}, want: exitClean},
+		{name: "default severity", args: []string{"--no-builtin", "--fail-on-severity", "low", "--default-severity", "high", "evil@1.0.0"}, want: exitFindings},
+		{name: "within max risks", args: []string{"--no-builtin", "--max-risks", "2", "badpak.json"}, want: exitClean},
+		{name: "over max risks", args: []string{"--no-builtin", "--max-risks", "1", "badpak.json"}, want: exitFindings},
+		{name: "max risks counts failing severities", args: []string{"--no-builtin", "--max-risks", "1", "--fail-on-severity", "high", "badpak.json"}, want: exitClean},
+		{name: "missing lockfile", args: []string{"--no-builtin", "-f", "missing.json", "badpak.json"}, want: exitError},
+		{name: "invalid list", args: []string{"--no-builtin", "--packages-file", "package-lock.json"}, want: exitError},
+		{name: "invalid severity", args: []string{"--no-builtin", "--fail-on-severity", "urgent", "badpak.json"}, want: exitError},
+		{name: "negative max risks", args: []string{"--no-builtin", "--max-risks", "-1", "badpak.json"}, want: exitError},
+		{name: "timed out", args: []string{"--no-builtin", "--timeout", "1ns", "badpak.json"}, want: exitInterrupted},
+		{name: "timed out without findings", args: []string{"--no-builtin", "--timeout", "1ns", "--fail-on-found=false", "safe@1.0.0"}, want: exitInterrupted},
+		{name: "negative timeout", args: []string{"--no-builtin", "--timeout", "-1s", "badpak.json"}, want: exitError},
+		{name: "no concurrency", args: []string{"--no-builtin", "--concurrency", "0", "badpak.json"}, want: exitError},
+		{name: "unknown flag", args: []string{"--no-such-flag"}, want: exitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, out := runCLI(t, dir, tt.args...); got != tt.want {
+				t.Errorf("scnpm %s exited %d, want %d:\n%s", strings.Join(tt.args, " "), got, tt.want, out)
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	dir := t.TempDir()
+	lockfile := `{"lockfileVersion": 3, "packages": {"": {"name": "app"}, "node_modules/evil": {"version": "1.0.0"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "package-lock.json"), []byte(lockfile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	status, out := runCLI(t, dir, "--no-builtin", "--timeout", "1ns", "-o", "json", "evil@1.0.0")
+	if status != exitInterrupted {
+		t.Fatalf("scnpm --timeout 1ns exited %d, want %d:\n%s", status, exitInterrupted, out)
+	}
+	for _, want := range []string{"scan timed out after 1ns (--timeout); the results are partial", `"interrupted": true`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("scnpm --timeout 1ns output lacks %q:\n%s", want, out)
+		}
+	}
+
+	status, out = runCLI(t, dir, "--no-builtin", "--timeout", "1ns", "evil@1.0.0")
+	if status != exitInterrupted || !strings.Contains(out, "INTERRUPTED, RESULTS ARE PARTIAL") {
+		t.Errorf("scnpm --timeout 1ns = %d, want %d and a summary marked partial:\n%s", status, exitInterrupted, out)
+	}
+}
+
+func TestCountOnly(t *testing.T) {
+	dir := t.TempDir()
+	lockfile := `{"lockfileVersion": 3, "packages": {"": {"name": "app"}, "node_modules/evil": {"version": "1.0.0"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "package-lock.json"), []byte(lockfile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args       []string
+		wantStatus int
+		wantOutput string
+	}{
+		{args: []string{"--no-builtin", "--count-only", "evil@1.0.0", "safe@1.0.0"}, wantStatus: exitFindings, wantOutput: "1\n"},
+		{args: []string{"--no-builtin", "--count-only", "safe@1.0.0"}, wantStatus: exitClean, wantOutput: "0\n"},
+		{args: []string{"--no-builtin", "--count-only", "-o", "json", "evil@1.0.0", "safe@1.0.0"}, wantStatus: exitFindings, wantOutput: `{"risks":1,"safe":1,"references":0}` + "\n"},
+		{args: []string{"--no-builtin", "--count-only", "-o", "sarif", "evil@1.0.0"}, wantStatus: exitError, wantOutput: "Error: --count-only works with table, json output, not sarif\n"},
+	}
+	for _, tt := range tests {
+		status, out := runCLI(t, dir, tt.args...)
+		if status != tt.wantStatus || out != tt.wantOutput {
+			t.Errorf("scnpm %s = %d, %q; want %d, %q", strings.Join(tt.args, " "), status, out, tt.wantStatus, tt.wantOutput)
+		}
+	}
+}
+
+func TestCheckLock(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"package.json":              `{"name": "mono", "workspaces": ["packages/*"], "dependencies": {"debug": "^4.0.0"}}`,
+		"packages/web/package.json": `{"name": "web", "dependencies": {"ms": "^3.0.0"}}`,
+		"packages/api/package.json": `{"name": "api", "dependencies": {"express": "^4.18.0"}}`,
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {
+			"": {"name": "mono", "dependencies": {"debug": "^4.0.0"}},
+			"packages/web": {"name": "web", "dependencies": {"ms": "^2.0.0"}},
+			"node_modules/web": {"resolved": "packages/web", "link": true},
+			"node_modules/api": {"resolved": "packages/api", "link": true},
+			"node_modules/debug": {"version": "4.3.4"},
+			"node_modules/ms": {"version": "2.1.3"}}}`,
+		// The api workspace keeps a lockfile of its own, which lacks express
+		"packages/api/package-lock.json": `{"lockfileVersion": 3, "packages": {"": {"name": "api"}}}`,
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status, out := runCLI(t, dir, "check-lock", "-o", "csv")
+	want := "finding,package,version,queried,severity,dev,reference,lockfile,line,path,advisory,sources\n" +
+		"Stale locked versions,ms,2.1.3,,,false,false,package-lock.json,7,node_modules/ms,,\n" +
+		"Declared but not locked,express,^4.18.0,,,false,true,package-lock.json,,packages/api/package.json -> express,,\n"
+	if status != exitFindings || out != want {
+		t.Errorf("scnpm check-lock = %d, %q; want %d, %q", status, out, exitFindings, want)
+	}
+
+	// With ms within range and express locked, the lockfile is in step
+	files["packages/web/package.json"] = `{"name": "web", "dependencies": {"ms": "^2.1.0"}}`
+	files["packages/api/package.json"] = `{"name": "api"}`
+	for _, path := range []string{"packages/web/package.json", "packages/api/package.json"} {
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(files[path]), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if status, out := runCLI(t, dir, "check-lock", "-o", "json"); status != exitClean || !strings.Contains(out, `"checks": {}`) {
+		t.Errorf("scnpm check-lock = %d, %s; want a clean report", status, out)
+	}
+	if status, out := runCLI(t, t.TempDir(), "check-lock"); status != exitError || !strings.Contains(out, "package-lock.json") {
+		t.Errorf("scnpm check-lock without a lockfile = %d, %q; want an error", status, out)
+	}
+}
+
+// gitRepo returns a new git repository, skipping the test when git isn't installed, and a function
+// running git in it as Jane Doe
+func gitRepo(t *testing.T) (string, func(args ...string)) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Jane Doe", "-c", "user.email=jane@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q")
+	return dir, run
+}
+
+func TestBlame(t *testing.T) {
+	dir, run := gitRepo(t)
+	commit := func(subject, path, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		run("add", "-A")
+		run("commit", "-q", "-m", subject)
+	}
+
+	v1 := `{"lockfileVersion": 1, "dependencies": {
+		"express": {"version": "4.18.2"},
+		"ms": {"version": "2.1.3"}%s}}`
+	commit("Add a shrinkwrap", "npm-shrinkwrap.json", fmt.Sprintf(v1, ""))
+	commit("Add debug", "npm-shrinkwrap.json", fmt.Sprintf(v1, `,
+		"debug": {"version": "4.3.4"}`))
+	run("mv", "npm-shrinkwrap.json", "package-lock.json")
+	run("commit", "-q", "-m", "Switch to package-lock.json")
+	commit("Upgrade the lockfile", "package-lock.json", `{"lockfileVersion": 3, "packages": {
+		"": {"name": "app"},
+		"node_modules/express": {"version": "4.18.2"},
+		"node_modules/ms": {"version": "2.1.3"},
+		"node_modules/debug": {"version": "4.3.5"}}}`)
+
+	status, out := runCLI(t, dir, "blame", "debug@4.3.4")
+	if status != exitClean || !regexp.MustCompile(`Introduced:  [0-9a-f]{12} \S+ \S+ \S+ Jane Doe: Add debug \(as npm-shrinkwrap.json\)\n`+
+		`Last absent: [0-9a-f]{12} \S+ \S+ \S+ Jane Doe: Add a shrinkwrap \(as npm-shrinkwrap.json\)\n$`).MatchString(out) {
+		t.Errorf("scnpm blame debug@4.3.4 = %d, %q", status, out)
+	}
+
+	// 4.3.5 only arrives with the upgraded lockfile format, after the rename
+	status, out = runCLI(t, dir, "blame", "debug@4.3.5", "-o", "json")
+	var blame struct {
+		Introduced struct{ Subject, Lockfile string }
+	}
+	if status != exitClean || json.Unmarshal([]byte(out), &blame) != nil ||
+		blame.Introduced.Subject != "Upgrade the lockfile" || blame.Introduced.Lockfile != "package-lock.json" {
+		t.Errorf("scnpm blame debug@4.3.5 = %d, %s", status, out)
+	}
+	if !strings.Contains(out, `"lastAbsent": {`) {
+		t.Errorf("scnpm blame debug@4.3.5 = %s, want the rename as the last commit without it", out)
+	}
+
+	if status, out := runCLI(t, dir, "blame", "debug@^5.0.0"); status != exitNotInstalled {
+		t.Errorf("scnpm blame debug@^5.0.0 = %d, %q; want %d", status, out, exitNotInstalled)
+	}
+	if status, out := runCLI(t, t.TempDir(), "blame", "debug"); status != exitError || !strings.Contains(out, "git repository") {
+		t.Errorf("scnpm blame outside a repository = %d, %q; want an error", status, out)
+	}
+}
+
+// expressLockfiles install express, which depends on debug 2.6.9, in each lockfile format
+var expressLockfiles = map[string]string{
+	"package-lock.json": `{"lockfileVersion": 3, "packages": {
+		"": {"name": "app", "dependencies": {"express": "^4.18.2"}},
+		"node_modules/express": {"version": "4.18.2", "dependencies": {"debug": "2.6.9"}},
+		"node_modules/debug": {"version": "2.6.9"}}}`,
+	"yarn.lock": `# yarn lockfile v1
+
+
+debug@2.6.9:
+  version "2.6.9"
+
+express@^4.18.2:
+  version "4.18.2"
+  dependencies:
+    debug "2.6.9"
+`,
+	"pnpm-lock.yaml": `lockfileVersion: '9.0'
+
+importers:
+
+  .:
+    dependencies:
+      express:
+        specifier: ^4.18.2
+        version: 4.18.2
+
+packages:
+
+  debug@2.6.9:
+    resolution: {integrity: sha512-debug}
+
+  express@4.18.2:
+    resolution: {integrity: sha512-express}
+
+snapshots:
+
+  debug@2.6.9: {}
+
+  express@4.18.2:
+    dependencies:
+      debug: 2.6.9
+`,
+}
+
+func TestWhyLockfileFormats(t *testing.T) {
+	for name, content := range expressLockfiles {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			status, out := runCLI(t, dir, "why", "debug", "-f", name)
+			if status != exitClean || strings.Contains(out, "nothing depends on it") || !strings.Contains(out, "── express@4.18.2 ") {
+				t.Errorf("scnpm why debug = %d, %q; want express depending on it", status, out)
+			}
+		})
+	}
+}
+
+func TestTreeLockfileFormats(t *testing.T) {
+	for name, content := range expressLockfiles {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			status, out := runCLI(t, dir, "tree", "debug@2.6.9", "-f", name, "--ascii")
+			if status != exitFindings || !strings.HasSuffix(out, "`-- express@4.18.2\n    `-- debug@2.6.9 RISK\n") {
+				t.Errorf("scnpm tree debug@2.6.9 = %d, %q; want debug under express", status, out)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		want    map[string][]string
+		wantErr string
+	}{
+		{
+			name:    "yaml",
+			content: "file: yarn.lock\nquiet: true\nmax-risks: 2\npackages-file:\n  - a.json\n  - b.json\n",
+			want:    map[string][]string{"file": {"yarn.lock"}, "quiet": {"true"}, "max-risks": {"2"}, "packages-file": {"a.json", "b.json"}},
+		},
+		{
+			name:    "json",
+			content: `{"output": "sarif", "packages-url-timeout": "10s", "log-level": "debug"}`,
+			want:    map[string][]string{"output": {"sarif"}, "packages-url-timeout": {"10s"}, "log-level": {"debug"}},
+		},
+		{name: "empty", content: "# nothing yet\n", want: map[string][]string{}},
+		{name: "unknown option", content: "lockfile: yarn.lock\n", wantErr: "unknown option 'lockfile'"},
+		{name: "invalid bool", content: "quiet: sometimes\n", wantErr: "'sometimes' isn't a valid bool"},
+		{name: "list for a single value", content: "output: [json, sarif]\n", wantErr: "takes a single value"},
+		{name: "no value", content: "output:\n", wantErr: "no value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			config, err := loadConfig(path, rootCmd)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadConfig() error = %v", err)
+			}
+			if !reflect.DeepEqual(config.Values, tt.want) {
+				t.Errorf("loadConfig() = %v, want %v", config.Values, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	lockfile := `{"lockfileVersion": 3, "packages": {"": {"name": "app"}, "node_modules/evil": {"version": "1.0.0"}}}`
+	files := map[string]string{
+		"package-lock.json":  lockfile,
+		".scnpm.yaml":        "file: package-lock.json\nno-builtin: true\ncount-only: true\noutput: json\npackages:\n  - evil@1.0.0\n",
+		"sub/.keep":          "",
+		"nearer/.scnpm.json": `{"file": "../package-lock.json", "no-builtin": true, "count-only": true, "packages": ["evil@1.0.0", "safe@1.0.0"]}`,
+		"broken/.scnpm.yaml": "lockfile: yarn.lock\n",
+		"json/.scnpm.yaml":   "file: ../package-lock.json\nno-builtin: true\noutput: json\npackages: [evil@1.0.0]\n",
+		"format/.scnpm.yaml": "file: ../package-lock.json\nno-builtin: true\nformat: '{{.Package.Name}}'\npackages: [evil@1.0.0]\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sub := filepath.Join(dir, "sub")
+
+	tests := []struct {
+		name       string
+		dir        string
+		args       []string
+		wantStatus int
+		wantOutput string
+	}{
+		{name: "config above", dir: sub, wantStatus: exitFindings, wantOutput: `{"risks":1,"safe":0,"references":0}` + "\n"},
+		{name: "flag over config", dir: sub, args: []string{"-o", "table"}, wantStatus: exitFindings, wantOutput: "1\n"},
+		{name: "list flag over config", dir: sub, args: []string{"--packages", "safe@1.0.0"}, wantStatus: exitClean, wantOutput: `{"risks":0,"safe":1,"references":0}` + "\n"},
+		{name: "nearest config", dir: filepath.Join(dir, "nearer"), wantStatus: exitFindings, wantOutput: "1\n"},
+		{name: "explicit config", dir: sub, args: []string{"--config", "../nearer/.scnpm.json", "-o", "json"}, wantStatus: exitFindings, wantOutput: `{"risks":1,"safe":1,"references":0}` + "\n"},
+		{name: "no config", dir: sub, args: []string{"--no-config", "--no-builtin", "--count-only", "evil@1.0.0"}, wantStatus: exitError, wantOutput: "package-lock.json"},
+		{name: "config and no config", dir: sub, args: []string{"--config", "../.scnpm.yaml", "--no-config"}, wantStatus: exitError, wantOutput: "Error: --config and --no-config can't be used together\n"},
+		{name: "invalid config", dir: filepath.Join(dir, "broken"), wantStatus: exitError, wantOutput: "unknown option 'lockfile'"},
+		{name: "missing config", dir: sub, args: []string{"--config", "missing.yaml"}, wantStatus: exitError, wantOutput: "missing.yaml"},
+		// A flag replaces the config's value of one it can't be combined with
+		{name: "format flag over config output", dir: filepath.Join(dir, "json"), args: []string{"--format", "{{.Package.Name}}"}, wantStatus: exitFindings, wantOutput: "evil\n"},
+		{name: "output flag over config format", dir: filepath.Join(dir, "format"), args: []string{"-o", "json"}, wantStatus: exitFindings, wantOutput: `"schemaVersion"`},
+		{name: "format and output flags", dir: filepath.Join(dir, "format"), args: []string{"-o", "json", "--format", "{{.Package.Name}}"}, wantStatus: exitError, wantOutput: "--format and --format-file replace --output"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, out := runCLI(t, tt.dir, tt.args...)
+			if status != tt.wantStatus || !strings.Contains(out, tt.wantOutput) {
+				t.Errorf("scnpm %v = %d, %q; want %d, %q", tt.args, status, out, tt.wantStatus, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestEnvPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	lockfile := `{"lockfileVersion": 3, "packages": {"": {"name": "app"}, "node_modules/evil": {"version": "1.0.0"}}}`
+	files := map[string]string{
+		"package-lock.json":  lockfile,
+		".scnpm.yaml":        "no-builtin: true\ncount-only: true\noutput: table\npackages: [evil@1.0.0]\n",
+		"secret/.scnpm.yaml": "notify-webhook: https://hooks.example.com/secret\n",
+		"watch/.scnpm.yaml":  "watch: true\n",
+		"list/.scnpm.yaml":   "file: ../package-lock.json\nno-builtin: true\npackages: [evil@1.0.0]\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		dir        string
+		env        map[string]string
+		args       []string
+		wantStatus int
+		wantOutput string
+	}{
+		{name: "config", wantStatus: exitFindings, wantOutput: "1\n"},
+		{name: "env over config", env: map[string]string{"SCNPM_OUTPUT": "json"}, wantStatus: exitFindings, wantOutput: `{"risks":1,"safe":0,"references":0}` + "\n"},
+		{name: "list env over config", env: map[string]string{"SCNPM_PACKAGES": "safe@1.0.0,other@2.0.0"}, wantStatus: exitClean, wantOutput: "0\n"},
+		{name: "flag over env", env: map[string]string{"SCNPM_OUTPUT": "json"}, args: []string{"-o", "table"}, wantStatus: exitFindings, wantOutput: "1\n"},
+		{name: "bool env", env: map[string]string{"SCNPM_FAIL_ON_FOUND": "false"}, wantStatus: exitClean, wantOutput: "1\n"},
+		{name: "empty env", env: map[string]string{"SCNPM_OUTPUT": ""}, wantStatus: exitFindings, wantOutput: "1\n"},
+		{name: "env without config", env: map[string]string{"SCNPM_NO_CONFIG": "true", "SCNPM_NO_BUILTIN": "1", "SCNPM_PACKAGES": "evil@1.0.0", "SCNPM_COUNT_ONLY": "true"}, wantStatus: exitFindings, wantOutput: "1\n"},
+		{name: "invalid env", env: map[string]string{"SCNPM_MAX_RISKS": "some"}, wantStatus: exitError, wantOutput: "Error: $SCNPM_MAX_RISKS: 'some' isn't a valid int\n"},
+		{name: "secret flag", args: []string{"--notify-webhook", "https://hooks.example.com/secret"}, wantStatus: exitError, wantOutput: "--notify-webhook was removed; set $SCNPM_WEBHOOK_URL instead"},
+		{name: "secret in config", dir: "secret", wantStatus: exitError, wantOutput: "'notify-webhook' can only be set with $SCNPM_WEBHOOK_URL"},
+		// Watching is for the command line only; every scan of a watch inherits the rest
+		{name: "watch env", env: map[string]string{"SCNPM_WATCH": "true"}, wantStatus: exitFindings, wantOutput: "1\n"},
+		{name: "watch in config", dir: "watch", wantStatus: exitError, wantOutput: "'watch' can only be given on the command line"},
+		// A flag replaces the variable of one it can't be combined with
+		{name: "format flag over output env", dir: "list", env: map[string]string{"SCNPM_OUTPUT": "json"}, args: []string{"--format", "{{.Package.Name}}"}, wantStatus: exitFindings, wantOutput: "evil\n"},
+		{name: "output flag over format env", dir: "list", env: map[string]string{"SCNPM_FORMAT": "{{.Package.Name}}"}, args: []string{"-o", "json"}, wantStatus: exitFindings, wantOutput: `"schemaVersion"`},
+		{name: "format and output env", dir: "list", env: map[string]string{"SCNPM_FORMAT": "{{.Package.Name}}", "SCNPM_OUTPUT": "json"}, wantStatus: exitError, wantOutput: "--format and --format-file replace --output"},
+		// Subcommands' own flags have variables of their own, so the scan's don't leak into them
+		{name: "subcommand env", env: map[string]string{"SCNPM_STATS_OUTPUT": "json"}, args: []string{"stats"}, wantStatus: exitClean, wantOutput: `"packages": 1`},
+		{name: "scan env in subcommand", env: map[string]string{"SCNPM_OUTPUT": "json"}, args: []string{"stats"}, wantStatus: exitClean, wantOutput: "Packages installed"},
+		{name: "invalid subcommand env", env: map[string]string{"SCNPM_VERIFY_CONCURRENCY": "many"}, args: []string{"verify"}, wantStatus: exitError, wantOutput: "Error: $SCNPM_VERIFY_CONCURRENCY: 'many' isn't a valid int\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			status, out := runCLI(t, filepath.Join(dir, tt.dir), tt.args...)
+			if status != tt.wantStatus || !strings.Contains(out, tt.wantOutput) {
+				t.Errorf("scnpm %v with %v = %d, %q; want %d, %q", tt.args, tt.env, status, out, tt.wantStatus, tt.wantOutput)
+			}
+		})
+	}
+
+	status, out := runCLI(t, dir, "--help")
+	if status != exitClean || !strings.Contains(out, "[$SCNPM_FAIL_ON_SEVERITY]") || strings.Contains(out, "$SCNPM_WATCH") {
+		t.Errorf("scnpm --help = %d, doesn't name the environment variables:\n%s", status, out)
+	}
+	status, out = runCLI(t, dir, "serve", "--help")
+	if status != exitClean || !strings.Contains(out, "[$SCNPM_SERVE_LISTEN]") || !strings.Contains(out, "[$SCNPM_NO_PROGRESS]") {
+		t.Errorf("scnpm serve --help = %d, doesn't name the environment variables:\n%s", status, out)
+	}
+}
+
+func TestInit(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "pnpm-lock.yaml"), []byte("lockfileVersion: '9.0'\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	status, out := runCLI(t, dir, "init", "--fail-on-severity", "HIGH", "--packages-url", "https://example.com/badpak.json", "--packages-file", "lists/extra.json")
+	if status != exitClean || out != "Wrote .scnpm.yaml\n" {
+		t.Fatalf("scnpm init = %d, %q", status, out)
+	}
+	config, err := loadConfig(filepath.Join(dir, ".scnpm.yaml"), rootCmd)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	want := map[string][]string{
+		"file":             {"pnpm-lock.yaml"},
+		"output":           {"table"},
+		"fail-on-severity": {"high"},
+		"packages-url":     {"https://example.com/badpak.json"},
+		"packages-file":    {"lists/extra.json"},
+	}
+	if !reflect.DeepEqual(config.Values, want) {
+		t.Errorf("scnpm init wrote %v, want %v", config.Values, want)
+	}
+
+	if status, out := runCLI(t, dir, "init", "--output", "json"); status != exitError || !strings.Contains(out, "--force") {
+		t.Errorf("scnpm init over an existing config = %d, %q; want a refusal", status, out)
+	}
+	if status, out := runCLI(t, dir, "init", "--output", "json", "--force"); status != exitClean {
+		t.Errorf("scnpm init --force = %d, %q", status, out)
+	}
+	if config, err := loadConfig(filepath.Join(dir, ".scnpm.yaml"), rootCmd); err != nil || config.Values["output"][0] != "json" {
+		t.Errorf("scnpm init --force didn't replace the config: %v, %v", config, err)
+	}
+	if status, _ := runCLI(t, dir, "init", "--force", "--packages-url", "http://example.com/badpak.json"); status != exitError {
+		t.Errorf("scnpm init with an http URL = %d, want %d", status, exitError)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	var buf bytes.Buffer
+	p := &progress{out: &buf, unit: "lockfiles", stop: make(chan struct{})}
+	p.update(34, 212, ", 3 risks so far")
+	p.draw()
+	p.update(212, 212, ", 5 risks so far")
+	p.draw()
+	p.finish()
+	want := "\r⠋ [███░░░░░░░░░░░░░░░░░] 34/212 lockfiles, 3 risks so far\033[K" +
+		"\r⠙ [████████████████████] 212/212 lockfiles, 5 risks so far\033[K" +
+		"\r\033[K"
+	if buf.String() != want {
+		t.Errorf("progress drew %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	p = &progress{out: &buf, unit: "packages", ascii: true, stop: make(chan struct{})}
+	p.update(3, 0, "")
+	p.draw()
+	p.update(1, 4, "")
+	p.draw()
+	if want := "\r| 3 packages\033[K\r/ [#####---------------] 1/4 packages\033[K"; buf.String() != want {
+		t.Errorf("progress drew %q, want %q", buf.String(), want)
+	}
+
+	// Nothing is drawn, nor cleared, for an operation that ends before the first draw
+	buf.Reset()
+	p = &progress{out: &buf, unit: "lockfiles", stop: make(chan struct{})}
+	p.ticker.Add(1)
+	go p.tick()
+	p.update(1, 1, "")
+	p.finish()
+	if buf.Len() != 0 {
+		t.Errorf("progress of a quick operation drew %q, want nothing", buf.String())
+	}
+
+	var none *progress
+	none.update(1, 2, "")
+	none.finish()
+}
+
+func TestPrompter(t *testing.T) {
+	var out bytes.Buffer
+	prompt := newPrompter(strings.NewReader("yarn.lock\n\n"), &out)
+	if got := prompt.ask("Lockfile to scan", "package-lock.json"); got != "yarn.lock" {
+		t.Errorf("ask() = %q, want the answer", got)
+	}
+	if got := prompt.ask("Output format", "table"); got != "table" {
+		t.Errorf("ask() = %q, want the default for an empty answer", got)
+	}
+	// Input ran out; the rest are defaults
+	if got := prompt.ask("Packages URL", ""); got != "" || prompt.err != nil {
+		t.Errorf("ask() = %q, %v; want the default at the end of input", got, prompt.err)
+	}
+	if want := "Lockfile to scan [package-lock.json]: Output format [table]: Packages URL: "; out.String() != want {
+		t.Errorf("prompts = %q, want %q", out.String(), want)
+	}
+}
+
+func TestHook(t *testing.T) {
+	dir, run := gitRepo(t)
+	hooks := filepath.Join(dir, ".git", "hooks")
+	if err := os.WriteFile(filepath.Join(hooks, "pre-commit"), []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The existing hook is chained, not replaced
+	if status, out := runCLI(t, dir, "hook", "install", "--", "--no-builtin", "--packages", "evil@1.0.0"); status != exitClean {
+		t.Fatalf("scnpm hook install = %d, %q", status, out)
+	}
+	script, err := os.ReadFile(filepath.Join(hooks, "pre-commit"))
+	if err != nil || !strings.Contains(string(script), "exec scnpm hook run --type pre-commit -- --no-builtin --packages evil@1.0.0\n") {
+		t.Errorf("pre-commit hook = %q, %v", script, err)
+	}
+	if _, err := os.Stat(filepath.Join(hooks, "pre-commit"+chainedSuffix)); err != nil {
+		t.Errorf("the existing hook wasn't chained: %v", err)
+	}
+	if status, out := runCLI(t, dir, "hook", "install", "--force"); status != exitClean {
+		t.Errorf("scnpm hook install again = %d, %q", status, out)
+	}
+
+	// Only the staged lockfile counts, not the one on disk
+	lockfile := filepath.Join(dir, "package-lock.json")
+	bad := `{"lockfileVersion": 3, "packages": {"": {"name": "app"}, "node_modules/evil": {"version": "1.0.0"}}}`
+	if err := os.WriteFile(lockfile, []byte(bad), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", "package-lock.json")
+	if err := os.WriteFile(lockfile, []byte(`{"lockfileVersion": 3, "packages": {"": {"name": "app"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	status, out := runCLI(t, dir, "hook", "run", "--", "--no-builtin", "--packages", "evil@1.0.0")
+	if status != exitFindings || !strings.Contains(out, "scanning :package-lock.json") || !strings.Contains(out, "--no-verify") {
+		t.Errorf("scnpm hook run with a bad staged lockfile = %d, %q", status, out)
+	}
+	run("add", "package-lock.json")
+	if status, out := runCLI(t, dir, "hook", "run", "--", "--no-builtin", "--packages", "evil@1.0.0"); status != exitClean {
+		t.Errorf("scnpm hook run with a clean staged lockfile = %d, %q", status, out)
+	}
+
+	// Uninstalling restores the chained hook, and leaves hooks scnpm didn't install alone
+	if status, out := runCLI(t, dir, "hook", "uninstall"); status != exitClean {
+		t.Fatalf("scnpm hook uninstall = %d, %q", status, out)
+	}
+	if script, err := os.ReadFile(filepath.Join(hooks, "pre-commit")); err != nil || string(script) != "#!/bin/sh\nexit 0\n" {
+		t.Errorf("pre-commit hook after uninstalling = %q, %v; want the original", script, err)
+	}
+	if status, out := runCLI(t, dir, "hook", "uninstall"); status != exitError || !strings.Contains(out, "wasn't installed by scnpm") {
+		t.Errorf("scnpm hook uninstall of a foreign hook = %d, %q", status, out)
+	}
+}
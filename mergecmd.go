@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"scnpm/pkg/output"
+
+	"github.com/spf13/cobra"
+)
+
+// mergeOutput is the format of the merged report: json, markdown or table
+var mergeOutput string
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge REPORT.json|DIRECTORY ...",
+	Short: "Combine JSON reports into one",
+	Long: `Roll up JSON reports (--output json), such as those of a nightly job scanning many
+repositories, into a single report. Every input is validated against the report schema
+(scnpm schema). Results for the same bad package are merged and every finding keeps the
+lockfile it was found in, so that findings several inputs share, such as those of a lockfile
+scanned twice, are reported once. The summary is counted again over the merged results.
+
+A directory stands for the .json files directly inside it. The merged report is JSON, which
+merges again, a Markdown summary to publish, or a table. The exit status is 1 when the merged
+report has risks.
+
+  scnpm merge results/*.json --output-file combined.json
+  scnpm merge results/ --output markdown > summary.md`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: cobra.NoFileCompletions,
+	Run:               runMerge,
+}
+
+func init() {
+	mergeCmd.Flags().StringVarP(&mergeOutput, "output", "o", "json", "Output format ("+strings.Join(output.MergeFormats, ", ")+")")
+	mergeCmd.Flags().StringVar(&outputFile, "output-file", "", "Write the merged report to this file instead of stdout")
+	completeFlagValues(mergeCmd, "output", output.MergeFormats)
+	rootCmd.AddCommand(mergeCmd)
+}
+
+func runMerge(cmd *cobra.Command, args []string) {
+	if !containsString(output.MergeFormats, mergeOutput) {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", mergeOutput)
+		os.Exit(exitError)
+	}
+	paths, err := mergeInputs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	var files []output.ReportFile
+	for _, path := range paths {
+		// A merged report written next to its inputs must not be merged into the next one
+		if outputFile != "" && sameFile(path, outputFile) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		files = append(files, output.ReportFile{Name: relativePath(path), Data: data})
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no reports to merge\n")
+		os.Exit(exitError)
+	}
+	merged, err := output.MergeReports(files)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	logVerbose("merged %d reports, dropping %d duplicate findings", len(files), merged.Duplicates)
+
+	var report io.Writer = os.Stdout
+	var reportFile *os.File
+	if outputFile != "" {
+		reportFile, err = os.Create(outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+			os.Exit(exitError)
+		}
+		report = reportFile
+	}
+	config := output.OutputConfig{
+		ShowSafe:      true,
+		ShowLockfiles: true,
+		ToolVersion:   version,
+		ToolCommit:    commit,
+		Warnings:      merged.Warnings,
+		ASCII:         asciiLocale(),
+	}
+	switch mergeOutput {
+	case "markdown":
+		err = output.OutputGitHubSummary(report, merged.Results, config)
+	case "table":
+		err = output.OutputTable(report, merged.Results, config)
+	default:
+		err = output.OutputReportJSON(report, merged.Report, config)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(exitError)
+	}
+	closeReport(reportFile)
+	if output.CountResults(merged.Results).Risks > 0 {
+		os.Exit(exitFindings)
+	}
+}
+
+// mergeInputs expands the arguments of scnpm merge: files as given, directories to the .json
+// files directly inside them, in lexical order
+func mergeInputs(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(arg, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no .json reports in '%s'", arg)
+		}
+		sort.Strings(matches)
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+// sameFile reports whether two paths name the same existing file
+func sameFile(a, b string) bool {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(b)
+	return err == nil && os.SameFile(infoA, infoB)
+}
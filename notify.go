@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"scnpm/pkg/output"
+	"scnpm/pkg/remote"
+	"scnpm/pkg/types"
+)
+
+// webhookURLEnv holds the URL notified when a scan finds risks. Webhook URLs usually embed their
+// secret, so it's only read from the environment, keeping it out of shell history, process
+// listings and committed config files.
+const webhookURLEnv = "SCNPM_WEBHOOK_URL"
+
+// notifyTimeout bounds each attempt to deliver a notification
+const notifyTimeout = 10 * time.Second
+
+// webhookURL returns the URL of $SCNPM_WEBHOOK_URL, checked up front so that a typo is reported
+// before the scan rather than swallowed as a warning after it
+func webhookURL() (string, error) {
+	if !containsString(output.NotifyFormats, notifyFormat) {
+		return "", fmt.Errorf("--notify-format must be one of %s, got '%s'", strings.Join(output.NotifyFormats, ", "), notifyFormat)
+	}
+	rawURL := os.Getenv(webhookURLEnv)
+	if rawURL == "" {
+		return "", nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		// Don't echo the URL, it's likely to hold a secret
+		return "", fmt.Errorf("invalid webhook URL")
+	}
+	if u.Scheme != "https" {
+		return "", fmt.Errorf("refusing to notify a webhook over %s: only https URLs are supported", u.Scheme)
+	}
+	return rawURL, nil
+}
+
+// notify posts the summary and top findings of a scan to the webhook, if there is one and the
+// scan found risks. Delivery failures are only warned about: the scan's outcome stands either way.
+func notify(webhook string, results []types.ScanResult, totals output.Totals, config output.OutputConfig) {
+	if webhook == "" || totals.Risks == 0 {
+		return
+	}
+	payload, err := output.Notification(results, totals, config, notifyFormat)
+	if err == nil {
+		// The scan is over, even if it was interrupted, so the notification isn't tied to its context
+		err = postNotification(context.Background(), newNotifyClient(), webhook, payload)
+	}
+	if err != nil {
+		printWarnings([]string{fmt.Sprintf("failed to notify the webhook: %v", err)})
+	}
+}
+
+// newNotifyClient returns the retrying HTTP client used to deliver notifications
+func newNotifyClient() *remote.Client {
+	return &remote.Client{
+		HTTP:       &http.Client{Timeout: notifyTimeout},
+		MaxRetries: 2,
+		Backoff:    time.Second,
+		UserAgent:  "scnpm/" + version,
+	}
+}
+
+// postNotification sends a JSON payload to the webhook
+func postNotification(ctx context.Context, client *remote.Client, webhook string, payload []byte) error {
+	_, err := client.Do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		// Keep the URL, and the secret it likely holds, out of the warning
+		return urlErr.Err
+	}
+	return err
+}
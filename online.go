@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"scnpm/pkg/audit"
+	"scnpm/pkg/cache"
+	"scnpm/pkg/disttag"
+	"scnpm/pkg/ghsa"
+	"scnpm/pkg/osv"
+	"scnpm/pkg/remote"
+	"scnpm/pkg/scanner"
+	"scnpm/pkg/scnpm"
+	"scnpm/pkg/types"
+)
+
+// registryTokenEnv holds the registry auth token for --audit; it is only read from the
+// environment so it doesn't end up in shell history or process listings
+const registryTokenEnv = "NPM_TOKEN"
+
+// githubTokenEnv holds the token for --ghsa
+const githubTokenEnv = "GITHUB_TOKEN"
+
+// onlineTimeout bounds each request to an online advisory source
+const onlineTimeout = 30 * time.Second
+
+// newRemoteClient returns the retrying HTTP client used for online advisory sources
+func newRemoteClient() *remote.Client {
+	return &remote.Client{
+		HTTP:       &http.Client{Timeout: onlineTimeout},
+		MaxRetries: 3,
+		Backoff:    time.Second,
+		UserAgent:  "scnpm/" + version,
+	}
+}
+
+// openCache returns the shared response cache, or nil when --no-cache is set or
+// there is no usable cache directory
+func openCache() *cache.Cache {
+	if noCache {
+		return nil
+	}
+	c, err := cache.Default(cacheTTL)
+	if err != nil {
+		logVerbose("response cache disabled: %v", err)
+		return nil
+	}
+	return c
+}
+
+// onlineQueries looks up the packages of a lockfile in the online advisory sources of --audit,
+// --ghsa and --osv, and returns their advisories as queries with warnings about the lookups that
+// failed
+func onlineQueries(ctx context.Context, packageLock *types.PackageLock, githubToken string) ([]types.PackageQuery, []string) {
+	start := time.Now()
+	var queries []types.PackageQuery
+	var warnings []string
+	for _, lookup := range []struct {
+		enabled bool
+		query   func() ([]types.PackageQuery, []string)
+	}{
+		{auditMode, func() ([]types.PackageQuery, []string) { return auditQueries(ctx, packageLock) }},
+		{ghsaMode, func() ([]types.PackageQuery, []string) { return ghsaQueries(ctx, packageLock, githubToken) }},
+		{osvMode, func() ([]types.PackageQuery, []string) { return osvQueries(ctx, packageLock) }},
+	} {
+		if lookup.enabled {
+			more, lookupWarnings := lookup.query()
+			warnings = append(warnings, lookupWarnings...)
+			queries = scnpm.MergeQueries(queries, more)
+		}
+	}
+	logPhase("online lookups", start)
+	return queries, warnings
+}
+
+// auditQueries asks the registry which installed packages have advisories and returns them as
+// queries. Registry failures come back as a warning so the rest of the scan still stands.
+func auditQueries(ctx context.Context, packageLock *types.PackageLock) ([]types.PackageQuery, []string) {
+	installed := scanner.InstalledPackages(packageLock)
+	if len(installed) == 0 {
+		return nil, nil
+	}
+
+	client := &audit.Client{
+		Remote:   newRemoteClient(),
+		Registry: registryURL,
+		Token:    os.Getenv(registryTokenEnv),
+	}
+
+	queries, err := client.Advisories(ctx, installed)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("npm audit lookup failed, results exclude registry advisories: %v", err)}
+	}
+	logVerbose("registry reported %d advisories for %d installed packages", len(queries), len(installed))
+
+	return queries, nil
+}
+
+// ghsaQueries looks up the installed packages in the GitHub Advisory Database. Lookups are
+// cached per package@version; API failures come back as a warning like --audit.
+func ghsaQueries(ctx context.Context, packageLock *types.PackageLock, token string) ([]types.PackageQuery, []string) {
+	installed := scanner.InstalledPackages(packageLock)
+	if len(installed) == 0 {
+		return nil, nil
+	}
+
+	client := &ghsa.Client{Remote: newRemoteClient(), Token: token, Cache: openCache()}
+
+	queries, err := client.Advisories(ctx, installed)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("GitHub advisory lookup failed, results exclude GitHub advisories: %v", err)}
+	}
+	logVerbose("GitHub reported %d advisories for %d installed packages", len(queries), len(installed))
+
+	return queries, nil
+}
+
+// osvQueries looks up the installed packages with the OSV.dev API. Responses are cached;
+// API failures come back as a warning like --audit.
+func osvQueries(ctx context.Context, packageLock *types.PackageLock) ([]types.PackageQuery, []string) {
+	installed := scanner.InstalledPackages(packageLock)
+	if len(installed) == 0 {
+		return nil, nil
+	}
+
+	client := &osv.Client{Remote: newRemoteClient(), Cache: openCache()}
+
+	queries, err := client.Advisories(ctx, installed)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("OSV lookup failed, results exclude OSV advisories: %v", err)}
+	}
+	logVerbose("OSV reported %d advisories for %d installed packages", len(queries), len(installed))
+
+	return queries, nil
+}
+
+// resolveDistTags substitutes the version a dist-tag entry like chalk@latest currently points at,
+// keeping the tag on the query so reports stay interpretable after the tag moves. An entry whose
+// tag can't be resolved becomes an error result with a warning; the rest of the scan still stands.
+func resolveDistTags(ctx context.Context, queries []types.PackageQuery) ([]types.PackageQuery, []string) {
+	var client *disttag.Client
+	var warnings []string
+	for i, query := range queries {
+		if !disttag.IsTag(query.Version) || scanner.IsPattern(query.Name) {
+			continue
+		}
+		if client == nil {
+			client = &disttag.Client{
+				Remote:   newRemoteClient(),
+				Registry: registryURL,
+				Token:    os.Getenv(registryTokenEnv),
+				Cache:    openCache(),
+				Offline:  offline,
+			}
+		}
+
+		resolved, err := client.Resolve(ctx, query.Name, query.Version)
+		queries[i].Tag = query.Version
+		if err != nil {
+			queries[i].Error = fmt.Sprintf("dist-tag '%s' could not be resolved: %v", query.Version, err)
+			warnings = append(warnings, fmt.Sprintf("%s@%s was not checked: %s", query.Name, query.Version, queries[i].Error))
+			continue
+		}
+		logVerbose("resolved %s@%s to %s", query.Name, query.Version, resolved)
+		queries[i].Version = resolved
+	}
+	return queries, warnings
+}
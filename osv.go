@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"scnpm/pkg/input"
+	"scnpm/pkg/osv"
+	"scnpm/pkg/types"
+)
+
+// readOSVFile loads npm package queries from an OSV advisory, a JSON array of advisories,
+// or a zip/tar.gz export with one advisory per .json member (as published by osv.dev)
+func readOSVFile(path string) ([]types.PackageQuery, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve OSV file path '%s': %v", path, err)
+	}
+
+	var advisories []osv.Advisory
+	if input.IsArchive(absPath) {
+		members, skipped, err := input.ReadArchive(absPath, func(name string) bool {
+			return strings.HasSuffix(name, ".json")
+		}, maxArchiveEntrySize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OSV export '%s': %v", absPath, err)
+		}
+		for _, name := range skipped {
+			logVerbose("skipped OSV export member '%s': larger than %d MB", name, maxArchiveEntrySize>>20)
+		}
+		for _, member := range members {
+			parsed, err := parseOSV(member.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse OSV advisory '%s' in '%s': %v", member.Name, absPath, err)
+			}
+			advisories = append(advisories, parsed...)
+		}
+	} else {
+		data, err := os.ReadFile(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OSV file '%s': %v", absPath, err)
+		}
+		advisories, err = parseOSV(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse OSV file '%s': %v", absPath, err)
+		}
+	}
+
+	queries, skipped := osv.Queries(advisories)
+	for i := range queries {
+		queries[i].Source = path
+	}
+	logVerbose("loaded %d npm queries from %d OSV advisories in '%s'", len(queries), len(advisories), path)
+	if skipped > 0 {
+		logVerbose("skipped %d OSV affected entries from non-npm ecosystems", skipped)
+	}
+
+	return queries, nil
+}
+
+func parseOSV(data []byte) ([]osv.Advisory, error) {
+	data, err := input.Normalize(data)
+	if err != nil {
+		return nil, err
+	}
+	return osv.Parse(data)
+}
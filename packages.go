@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"scnpm/pkg/scanner"
+	"scnpm/pkg/scnpm"
+	"scnpm/pkg/types"
+)
+
+// readPackagesFromFile reads package queries from a JSON file. The file holds either an array
+// whose elements are "package@version" strings or entry objects with optional severity, advisory
+// and note fields, or an object mapping package names to arrays of bad versions.
+func readPackagesFromFile(filePath string) ([]types.PackageQuery, error) {
+	// Resolve to absolute path for better error messages and consistency
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path '%s': %v", filePath, err)
+	}
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read packages file '%s': %v", absPath, err)
+	}
+
+	return parsePackages(absPath, data)
+}
+
+// parsePackages parses a bad-package list in the --packages-format, or the format its name (a
+// file path or URL) says
+func parsePackages(name string, data []byte) ([]types.PackageQuery, error) {
+	return scnpm.ParseQueries(name, packagesFormat, data)
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isPackagesFile reports whether a positional argument names a bad-package list (or a directory
+// of them) rather than a package. A directory only counts when it's written as a path ("./chalk",
+// "lists/"), so a package that happens to share its name with a directory is still scanned.
+func isPackagesFile(arg string) bool {
+	if scnpm.ListFormat(arg) != "" {
+		return true
+	}
+	if !looksLikePath(arg) {
+		return false
+	}
+	info, err := os.Stat(arg)
+	return err == nil && info.IsDir()
+}
+
+// looksLikePath reports whether arg is written as a filesystem path rather than a package name.
+// Scoped names ("@scope/name") contain a slash too, so they never count.
+func looksLikePath(arg string) bool {
+	if strings.HasPrefix(arg, "@") {
+		return false
+	}
+	return arg == "." || arg == ".." || strings.ContainsRune(arg, '/') || strings.ContainsRune(arg, filepath.Separator)
+}
+
+// expandPackagesPaths replaces each directory among paths with the list files inside it, in
+// lexical order. Subdirectories are searched when recurse is set. A directory without any list
+// file is an error, so a typo'd path can't silently turn into an empty scan.
+func expandPackagesPaths(paths []string, recurse bool) ([]string, error) {
+	var expanded []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			// Missing files are reported when they are read
+			expanded = append(expanded, path)
+			continue
+		}
+
+		var files []string
+		err = filepath.WalkDir(path, func(file string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if file != path && !recurse {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if scnpm.ListFormat(file) != "" {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read packages directory '%s': %v", path, err)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("packages directory '%s' contains no bad-package lists (*.json, *.yaml, *.csv, *.txt)", path)
+		}
+		expanded = append(expanded, files...)
+	}
+
+	return expanded, nil
+}
+
+// mixedCaseWarnings flags queries with uppercase letters: npm names are lowercase, so these
+// are likely typos that silently never match without --ignore-case
+func mixedCaseWarnings(queries []types.PackageQuery) []string {
+	var warnings []string
+	seen := make(map[string]bool)
+	for _, query := range queries {
+		if strings.HasPrefix(query.Name, scanner.RegexPrefix) || query.Name == strings.ToLower(query.Name) || seen[query.Name] {
+			continue
+		}
+		seen[query.Name] = true
+		warnings = append(warnings, fmt.Sprintf("'%s' contains uppercase letters but npm package names are lowercase; use --ignore-case to match it regardless of case", query.Name))
+	}
+	return warnings
+}
@@ -0,0 +1,95 @@
+// Package advisories holds scnpm's advisory database: a curated list of npm package versions
+// known to have been compromised, embedded into the binary.
+package advisories
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+// SchemaVersion is the database format this build understands
+const SchemaVersion = 1
+
+// BuiltinSource is the query source recorded for entries from the database
+const BuiltinSource = "builtin"
+
+//go:embed builtin.json
+var builtinData []byte
+
+// Database is a versioned list of compromised package versions
+type Database struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Updated       time.Time `json:"updated"`
+	Entries       []Entry   `json:"entries"`
+}
+
+// Entry lists the compromised versions of one package from a single incident
+type Entry struct {
+	Name     string   `json:"name"`
+	Versions []string `json:"versions"`
+	Severity string   `json:"severity,omitempty"`
+	Advisory string   `json:"advisory,omitempty"`
+	Note     string   `json:"note,omitempty"`
+}
+
+// Builtin returns the database embedded at build time
+func Builtin() (*Database, error) {
+	db, err := Parse(builtinData)
+	if err != nil {
+		return nil, fmt.Errorf("embedded advisory database is invalid: %v", err)
+	}
+	return db, nil
+}
+
+// Parse decodes and validates a database
+func Parse(data []byte) (*Database, error) {
+	var db Database
+	if err := json.Unmarshal(data, &db); err != nil {
+		return nil, err
+	}
+
+	if db.SchemaVersion != SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version %d (expected %d)", db.SchemaVersion, SchemaVersion)
+	}
+	if db.Updated.IsZero() {
+		return nil, fmt.Errorf("missing updated timestamp")
+	}
+	for i, entry := range db.Entries {
+		if entry.Name == "" {
+			return nil, fmt.Errorf("entry %d: missing name", i)
+		}
+		if len(entry.Versions) == 0 {
+			return nil, fmt.Errorf("entry %d (%s): no versions", i, entry.Name)
+		}
+		if entry.Severity != "" {
+			if _, ok := types.NormalizeSeverity(entry.Severity); !ok {
+				return nil, fmt.Errorf("entry %d (%s): unknown severity '%s'", i, entry.Name, entry.Severity)
+			}
+		}
+	}
+
+	return &db, nil
+}
+
+// Queries expands the database into one query per compromised version
+func (db *Database) Queries() []types.PackageQuery {
+	var queries []types.PackageQuery
+	for _, entry := range db.Entries {
+		severity, _ := types.NormalizeSeverity(entry.Severity)
+		for _, version := range entry.Versions {
+			queries = append(queries, types.PackageQuery{
+				Name:     entry.Name,
+				Version:  version,
+				Severity: severity,
+				Advisory: entry.Advisory,
+				Note:     entry.Note,
+				Source:   BuiltinSource,
+			})
+		}
+	}
+	return queries
+}
@@ -0,0 +1,137 @@
+package advisories
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuiltin(t *testing.T) {
+	db, err := Builtin()
+	if err != nil {
+		t.Fatalf("Builtin() error = %v", err)
+	}
+
+	queries := db.Queries()
+	if len(queries) == 0 {
+		t.Fatal("Builtin().Queries() returned no queries")
+	}
+	seen := make(map[string]bool)
+	for _, query := range queries {
+		if query.Source != BuiltinSource {
+			t.Errorf("query %s@%s source = %q, want %q", query.Name, query.Version, query.Source, BuiltinSource)
+		}
+		key := query.Name + "@" + query.Version
+		if seen[key] {
+			t.Errorf("duplicate builtin entry %s", key)
+		}
+		seen[key] = true
+	}
+	if !seen["event-stream@3.3.6"] {
+		t.Error("Builtin() is missing event-stream@3.3.6")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			data: `{"schemaVersion": 1, "updated": "2025-01-01T00:00:00Z", "entries": [{"name": "a", "versions": ["1.0.0"], "severity": "medium"}]}`,
+		},
+		{
+			name:    "future schema",
+			data:    `{"schemaVersion": 2, "updated": "2025-01-01T00:00:00Z", "entries": []}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing timestamp",
+			data:    `{"schemaVersion": 1, "entries": []}`,
+			wantErr: true,
+		},
+		{
+			name:    "entry without versions",
+			data:    `{"schemaVersion": 1, "updated": "2025-01-01T00:00:00Z", "entries": [{"name": "a"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown severity",
+			data:    `{"schemaVersion": 1, "updated": "2025-01-01T00:00:00Z", "entries": [{"name": "a", "versions": ["1.0.0"], "severity": "severe"}]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	builtin, _ := Builtin()
+	path := filepath.Join(t.TempDir(), "advisories.json")
+
+	db, err := Load(path)
+	if err != nil || db.Updated != builtin.Updated {
+		t.Fatalf("Load() without a download = %v, %v, want the built-in database", db, err)
+	}
+
+	newer := `{"schemaVersion": 1, "updated": "2999-01-01T00:00:00Z", "entries": [{"name": "evil", "versions": ["1.0.0"]}]}`
+	if err := Save(path, []byte(newer)); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	db, err = Load(path)
+	if err != nil || len(db.Entries) != 1 {
+		t.Errorf("Load() with a newer download = %v, %v, want the downloaded database", db, err)
+	}
+	if got := db.NewEntries(builtin); got != 1 {
+		t.Errorf("NewEntries() = %d, want 1", got)
+	}
+
+	older := `{"schemaVersion": 1, "updated": "2000-01-01T00:00:00Z", "entries": []}`
+	os.WriteFile(path, []byte(older), 0o644)
+	if db, err = Load(path); err != nil || db.Updated != builtin.Updated {
+		t.Errorf("Load() with an older download = %v, %v, want the built-in database", db, err)
+	}
+
+	os.WriteFile(path, []byte("{corrupt"), 0o644)
+	db, err = Load(path)
+	if err == nil || db == nil || db.Updated != builtin.Updated {
+		t.Errorf("Load() with a corrupt download = %v, %v, want an error and the built-in database", db, err)
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("hello\n")
+	sum := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+	tests := []struct {
+		name     string
+		checksum string
+		wantErr  bool
+	}{
+		{name: "bare digest", checksum: sum},
+		{name: "sha256sum format", checksum: sum + "  advisories.json\n"},
+		{name: "uppercase", checksum: strings.ToUpper(sum)},
+		{name: "mismatch", checksum: strings.Repeat("0", 64), wantErr: true},
+		{name: "malformed", checksum: "not-a-digest", wantErr: true},
+		{name: "empty", checksum: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyChecksum(data, []byte(tt.checksum))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
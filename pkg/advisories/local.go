@@ -0,0 +1,107 @@
+package advisories
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultPath returns where update-db stores the downloaded database:
+// $XDG_DATA_HOME/scnpm/advisories.json, falling back to ~/.local/share
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "scnpm", "advisories.json"), nil
+}
+
+// Load returns the database at path when it is newer than the embedded one, and the
+// embedded database otherwise (including when nothing has been downloaded yet)
+func Load(path string) (*Database, error) {
+	builtin, err := Builtin()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return builtin, nil
+	}
+	if err != nil {
+		return builtin, fmt.Errorf("failed to read advisory database '%s': %v", path, err)
+	}
+
+	local, err := Parse(data)
+	if err != nil {
+		return builtin, fmt.Errorf("advisory database '%s' is invalid, run update-db again: %v", path, err)
+	}
+	if local.Updated.After(builtin.Updated) {
+		return local, nil
+	}
+	return builtin, nil
+}
+
+// Save writes a database via a temporary file so scans never read a partial copy
+func Save(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".advisories-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// VerifyChecksum checks data against a sha256sum-style checksum file ("<hex digest>  <name>")
+func VerifyChecksum(data, checksumFile []byte) error {
+	fields := strings.Fields(string(checksumFile))
+	if len(fields) == 0 {
+		return fmt.Errorf("empty checksum file")
+	}
+
+	want, err := hex.DecodeString(fields[0])
+	if err != nil || len(want) != sha256.Size {
+		return fmt.Errorf("malformed sha256 checksum '%s'", fields[0])
+	}
+	got := sha256.Sum256(data)
+	if hex.EncodeToString(got[:]) != strings.ToLower(fields[0]) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", strings.ToLower(fields[0]), hex.EncodeToString(got[:]))
+	}
+	return nil
+}
+
+// NewEntries counts the package versions in db that old doesn't list
+func (db *Database) NewEntries(old *Database) int {
+	known := make(map[string]bool)
+	if old != nil {
+		for _, query := range old.Queries() {
+			known[query.Name+"@"+query.Version] = true
+		}
+	}
+
+	count := 0
+	for _, query := range db.Queries() {
+		if !known[query.Name+"@"+query.Version] {
+			count++
+		}
+	}
+	return count
+}
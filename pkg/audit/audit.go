@@ -0,0 +1,148 @@
+// Package audit looks up installed packages in the npm registry's bulk advisory
+// endpoint, the API behind `npm audit`.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"scnpm/pkg/remote"
+	"scnpm/pkg/types"
+)
+
+// DefaultRegistry is the public npm registry
+const DefaultRegistry = "https://registry.npmjs.org"
+
+// Source is the query source recorded for advisories from the registry
+const Source = "npm-audit"
+
+// DefaultBatchSize is how many package names are sent per request
+const DefaultBatchSize = 500
+
+const bulkPath = "/-/npm/v1/security/advisories/bulk"
+
+// Client queries a registry's bulk advisory endpoint
+type Client struct {
+	Remote    *remote.Client
+	Registry  string // Defaults to DefaultRegistry
+	Token     string // Sent as a bearer token when set
+	BatchSize int    // Defaults to DefaultBatchSize
+}
+
+// advisory is one entry of the bulk endpoint's response
+type advisory struct {
+	ID                 int    `json:"id"`
+	URL                string `json:"url"`
+	Title              string `json:"title"`
+	Severity           string `json:"severity"`
+	VulnerableVersions string `json:"vulnerable_versions"`
+}
+
+// Advisories sends the installed name → versions map to the registry in batches and returns
+// one query per advisory, with the advisory's vulnerable range as the version
+func (c *Client) Advisories(ctx context.Context, installed map[string][]string) ([]types.PackageQuery, error) {
+	names := make([]string, 0, len(installed))
+	for name := range installed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	batchSize := c.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	var queries []types.PackageQuery
+	for start := 0; start < len(names); start += batchSize {
+		end := start + batchSize
+		if end > len(names) {
+			end = len(names)
+		}
+
+		batch := make(map[string][]string, end-start)
+		for _, name := range names[start:end] {
+			batch[name] = installed[name]
+		}
+
+		response, err := c.bulk(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names[start:end] {
+			for _, adv := range response[name] {
+				queries = append(queries, adv.query(name))
+			}
+		}
+	}
+
+	return queries, nil
+}
+
+func (c *Client) bulk(ctx context.Context, batch map[string][]string) (map[string][]advisory, error) {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := c.Registry
+	if registry == "" {
+		registry = DefaultRegistry
+	}
+	url := strings.TrimSuffix(registry, "/") + bulkPath
+
+	remoteClient := c.Remote
+	if remoteClient == nil {
+		remoteClient = &remote.Client{}
+	}
+	data, err := remoteClient.Do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.Token)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("bulk advisory request to %s failed: %v", registry, err)
+	}
+
+	var response map[string][]advisory
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("invalid bulk advisory response from %s: %v", registry, err)
+	}
+	return response, nil
+}
+
+func (a advisory) query(name string) types.PackageQuery {
+	severity := a.Severity
+	if severity == "info" {
+		severity = types.SeverityLow
+	}
+	severity, _ = types.NormalizeSeverity(severity)
+
+	// GitHub-hosted advisories are identified by their GHSA id, which fits the table better
+	id := a.URL
+	if i := strings.LastIndex(a.URL, "/"); i >= 0 && strings.HasPrefix(a.URL[i+1:], "GHSA-") {
+		id = a.URL[i+1:]
+	}
+	if id == "" {
+		id = fmt.Sprintf("npm-%d", a.ID)
+	}
+
+	return types.PackageQuery{
+		Name:     name,
+		Version:  a.VulnerableVersions,
+		Severity: severity,
+		Advisory: id,
+		Note:     a.Title,
+		Source:   Source,
+	}
+}
@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestAdvisories(t *testing.T) {
+	var batches []map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != bulkPath {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var batch map[string][]string
+		json.NewDecoder(r.Body).Decode(&batch)
+		batches = append(batches, batch)
+
+		response := map[string][]advisory{}
+		if _, ok := batch["lodash"]; ok {
+			response["lodash"] = []advisory{{
+				ID:                 1106913,
+				URL:                "https://github.com/advisories/GHSA-35jh-r3h4-6jhm",
+				Title:              "Command Injection in lodash",
+				Severity:           "high",
+				VulnerableVersions: "<4.17.21",
+			}}
+		}
+		if _, ok := batch["minimist"]; ok {
+			response["minimist"] = []advisory{{ID: 42, Severity: "info", VulnerableVersions: ">=1.0.0 <1.2.6"}}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := &Client{Registry: server.URL + "/", Token: "token", BatchSize: 2}
+	queries, err := client.Advisories(context.Background(), map[string][]string{
+		"lodash":   {"4.17.20"},
+		"minimist": {"1.2.5"},
+		"react":    {"18.2.0"},
+	})
+	if err != nil {
+		t.Fatalf("Advisories() error = %v", err)
+	}
+
+	want := []types.PackageQuery{
+		{Name: "lodash", Version: "<4.17.21", Severity: types.SeverityHigh, Advisory: "GHSA-35jh-r3h4-6jhm", Note: "Command Injection in lodash", Source: Source},
+		{Name: "minimist", Version: ">=1.0.0 <1.2.6", Severity: types.SeverityLow, Advisory: "npm-42", Source: Source},
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("Advisories() = %+v, want %+v", queries, want)
+	}
+	if len(batches) != 2 {
+		t.Errorf("Advisories() sent %d batches, want 2", len(batches))
+	}
+
+	client.Token = ""
+	if _, err := client.Advisories(context.Background(), map[string][]string{"lodash": {"4.17.20"}}); err == nil {
+		t.Error("Advisories() expected error when the registry rejects the request")
+	}
+}
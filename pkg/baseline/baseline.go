@@ -0,0 +1,201 @@
+package baseline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"scnpm/pkg/input"
+	"scnpm/pkg/types"
+)
+
+// FormatVersion is the version of the baseline file format
+const FormatVersion = 1
+
+// Fingerprint identifies a finding by what was found and where it's installed, but not by the
+// line it's on or its position in the lockfile, so that unrelated changes to the lockfile don't
+// make a known finding look new
+type Fingerprint struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Path     string `json:"path"`
+	Lockfile string `json:"lockfile,omitempty"`
+}
+
+// String describes a fingerprint for warnings
+func (f Fingerprint) String() string {
+	s := fmt.Sprintf("%s@%s at %s", f.Name, f.Version, f.Path)
+	if f.Lockfile != "" {
+		s += " in " + f.Lockfile
+	}
+	return s
+}
+
+// File is the contents of a baseline file
+type File struct {
+	Version  int           `json:"version"`
+	Findings []Fingerprint `json:"findings"`
+}
+
+// Load reads a baseline file
+func Load(filePath string) ([]Fingerprint, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline '%s': %v", filePath, err)
+	}
+	fingerprints, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid baseline '%s': %v", filePath, err)
+	}
+	return fingerprints, nil
+}
+
+// Parse decodes and validates a baseline file
+func Parse(data []byte) ([]Fingerprint, error) {
+	data, err := input.Normalize(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if file.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported version %d, expected %d", file.Version, FormatVersion)
+	}
+	for i, fingerprint := range file.Findings {
+		if fingerprint.Name == "" || fingerprint.Path == "" {
+			return nil, fmt.Errorf("entry %d: missing name or path", i)
+		}
+		file.Findings[i].Path = NormalizePath(fingerprint.Path)
+		file.Findings[i].Lockfile = NormalizePath(fingerprint.Lockfile)
+	}
+	return file.Findings, nil
+}
+
+// Write saves fingerprints as a baseline file, sorted so that regenerating it gives a small diff
+func Write(filePath string, fingerprints []Fingerprint) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false) // Keep the "->" of reference paths readable
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(File{Version: FormatVersion, Findings: fingerprints}); err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, buf.Bytes(), 0o644)
+}
+
+// NormalizePath cleans an install path, or each side of a reference's "declaring -> dependency"
+// path, and uses forward slashes whatever the platform it was recorded on
+func NormalizePath(p string) string {
+	if p == "" {
+		return ""
+	}
+	parts := strings.Split(p, " -> ")
+	for i, part := range parts {
+		part = strings.ReplaceAll(part, `\`, "/")
+		if part != "" {
+			part = strings.TrimPrefix(path.Clean(part), "./")
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// Of returns the fingerprint of an instance of a finding for the named package. lockfile is the
+// scanned lockfile, used unless the instance says which one it came from.
+func Of(name string, instance types.PackageInstance, lockfile string) Fingerprint {
+	if instance.Name != "" {
+		name = instance.Name
+	}
+	if instance.Lockfile != "" {
+		lockfile = instance.Lockfile
+	}
+	return Fingerprint{Name: name, Version: instance.Version, Path: NormalizePath(instance.Path), Lockfile: NormalizePath(lockfile)}
+}
+
+// Record returns the sorted, distinct fingerprints of the bad-package findings of a scan that
+// weren't suppressed
+func Record(results []types.ScanResult, lockfile string) []Fingerprint {
+	seen := make(map[Fingerprint]bool)
+	fingerprints := []Fingerprint{}
+	for _, result := range results {
+		if result.Check != "" || !result.Found {
+			continue
+		}
+		for _, instance := range result.Instances {
+			fingerprint := Of(result.Package.Name, instance, lockfile)
+			if instance.Suppressed || seen[fingerprint] {
+				continue
+			}
+			seen[fingerprint] = true
+			fingerprints = append(fingerprints, fingerprint)
+		}
+	}
+	sort.Slice(fingerprints, func(i, j int) bool {
+		a, b := fingerprints[i], fingerprints[j]
+		if a.Lockfile != b.Lockfile {
+			return a.Lockfile < b.Lockfile
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Path < b.Path
+	})
+	return fingerprints
+}
+
+// Baseline matches findings against the fingerprints of a baseline file, remembering which ones
+// it has seen so that the stale entries can be listed once every lockfile was scanned
+type Baseline struct {
+	fingerprints []Fingerprint
+	known        map[Fingerprint]bool
+	matched      map[Fingerprint]bool
+}
+
+// New returns a baseline of fingerprints
+func New(fingerprints []Fingerprint) *Baseline {
+	known := make(map[Fingerprint]bool, len(fingerprints))
+	for _, fingerprint := range fingerprints {
+		known[fingerprint] = true
+	}
+	return &Baseline{fingerprints: fingerprints, known: known, matched: make(map[Fingerprint]bool)}
+}
+
+// Apply marks the instances of bad-package findings recorded in the baseline. lockfile is the
+// scanned lockfile, as passed to Record.
+func (b *Baseline) Apply(results []types.ScanResult, lockfile string) {
+	for i := range results {
+		if results[i].Check != "" {
+			continue
+		}
+		for j := range results[i].Instances {
+			instance := &results[i].Instances[j]
+			fingerprint := Of(results[i].Package.Name, *instance, lockfile)
+			if b.known[fingerprint] {
+				instance.Baseline = true
+				b.matched[fingerprint] = true
+			}
+		}
+	}
+}
+
+// Stale returns the entries that didn't match a finding in any of the results applied so far,
+// which can be pruned
+func (b *Baseline) Stale() []Fingerprint {
+	var stale []Fingerprint
+	for _, fingerprint := range b.fingerprints {
+		if !b.matched[fingerprint] {
+			stale = append(stale, fingerprint)
+		}
+	}
+	return stale
+}
@@ -0,0 +1,109 @@
+package baseline
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "valid", data: `{"version": 1, "findings": [{"name": "debug", "version": "4.4.2", "path": "node_modules/debug", "lockfile": "package-lock.json"}]}`},
+		{name: "empty", data: `{"version": 1, "findings": []}`},
+		{name: "unknown version", data: `{"version": 2, "findings": []}`, wantErr: true},
+		{name: "missing path", data: `{"version": 1, "findings": [{"name": "debug", "version": "4.4.2"}]}`, wantErr: true},
+		{name: "not an object", data: `[]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	tests := map[string]string{
+		"node_modules/debug":                    "node_modules/debug",
+		`node_modules\a\node_modules\debug`:     "node_modules/a/node_modules/debug",
+		"./node_modules//debug/":                "node_modules/debug",
+		` -> debug`:                             " -> debug",
+		`node_modules\a -> debug`:               "node_modules/a -> debug",
+		"packages/web/../web/package-lock.json": "packages/web/package-lock.json",
+	}
+	for path, want := range tests {
+		if got := NormalizePath(path); got != want {
+			t.Errorf("NormalizePath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestRecordAndApply(t *testing.T) {
+	debug := types.PackageQuery{Name: "debug", Version: "4.4.2"}
+	scan := func(instances ...types.PackageInstance) []types.ScanResult {
+		return []types.ScanResult{
+			{Package: debug, Found: true, Instances: instances},
+			{Package: types.PackageQuery{Name: "lodahs"}, Check: types.CheckTyposquat, Found: true, Instances: []types.PackageInstance{{Name: "lodahs", Path: "node_modules/lodahs"}}},
+		}
+	}
+	recorded := scan(
+		types.PackageInstance{Name: "debug", Version: "4.4.2", Path: "node_modules/debug", LineNumber: 10},
+		types.PackageInstance{Name: "debug", Version: "4.4.2", Path: "node_modules/a/node_modules/debug", LineNumber: 20},
+		types.PackageInstance{Name: "debug", Version: "4.4.2", Path: "node_modules/b/node_modules/debug", Suppressed: true},
+	)
+
+	fingerprints := Record(recorded, "package-lock.json")
+	want := []Fingerprint{
+		{Name: "debug", Version: "4.4.2", Path: "node_modules/a/node_modules/debug", Lockfile: "package-lock.json"},
+		{Name: "debug", Version: "4.4.2", Path: "node_modules/debug", Lockfile: "package-lock.json"},
+	}
+	if !reflect.DeepEqual(fingerprints, want) {
+		t.Fatalf("Record() = %+v, want the unsuppressed bad-package findings, sorted", fingerprints)
+	}
+
+	file := filepath.Join(t.TempDir(), "baseline.json")
+	if err := Write(file, fingerprints); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	loaded, err := Load(file)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// The lockfile was reordered and a new finding appeared; the nested one is gone
+	results := scan(
+		types.PackageInstance{Name: "debug", Version: "4.4.2", Path: "node_modules/c/node_modules/debug", LineNumber: 5},
+		types.PackageInstance{Name: "debug", Version: "4.4.2", Path: "node_modules/debug", LineNumber: 42},
+	)
+	known := New(loaded)
+	known.Apply(results, "package-lock.json")
+
+	if results[0].Instances[0].Baseline {
+		t.Errorf("new finding %s was marked as in the baseline", results[0].Instances[0].Path)
+	}
+	if !results[0].Instances[1].Baseline {
+		t.Errorf("known finding %s on another line wasn't marked as in the baseline", results[0].Instances[1].Path)
+	}
+	if results[1].Instances[0].Baseline {
+		t.Errorf("check finding was marked as in the baseline")
+	}
+	if stale := known.Stale(); len(stale) != 1 || stale[0].Path != "node_modules/a/node_modules/debug" {
+		t.Errorf("Stale() = %+v, want the nested debug that's gone", stale)
+	}
+
+	// Another lockfile with the same finding isn't covered
+	other := scan(types.PackageInstance{Name: "debug", Version: "4.4.2", Path: "node_modules/debug"})
+	New(loaded).Apply(other, "web/package-lock.json")
+	if other[0].Instances[0].Baseline {
+		t.Errorf("finding in web/package-lock.json matched a baseline entry of package-lock.json")
+	}
+}
@@ -0,0 +1,174 @@
+// Package cache is a small on-disk cache for responses from remote advisory sources,
+// so repeated scans don't repeat the same lookups.
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultTTL is how long entries stay fresh unless configured otherwise
+const DefaultTTL = 24 * time.Hour
+
+// header starts every entry file and is followed by the hex sha256 of the content and a newline
+const header = "scnpm-cache sha256:"
+
+// Cache stores entries as files under Dir, grouped by namespace and named by the key's hash.
+// Each entry records a hash of its content, so truncated or corrupted files read as misses.
+type Cache struct {
+	Dir string
+	TTL time.Duration
+}
+
+// Stats describes what a cache holds
+type Stats struct {
+	Entries    int
+	Size       int64
+	Namespaces map[string]int // Entries per namespace
+}
+
+// Default returns a cache in the user cache directory (~/.cache/scnpm on Linux)
+func Default(ttl time.Duration) (*Cache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{Dir: filepath.Join(dir, "scnpm"), TTL: ttl}, nil
+}
+
+// Get returns the entry for key when it exists, is intact and is younger than the TTL
+func (c *Cache) Get(namespace, key string) ([]byte, bool) {
+	data, storedAt, ok := c.GetStale(namespace, key)
+	if !ok || time.Since(storedAt) > c.TTL {
+		return nil, false
+	}
+	return data, true
+}
+
+// GetStale returns the entry for key regardless of its age, with the time it was stored.
+// It is meant for falling back to old data when a source can't be reached.
+func (c *Cache) GetStale(namespace, key string) ([]byte, time.Time, bool) {
+	path := c.path(namespace, key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+
+	data, err := decode(raw)
+	if err != nil {
+		// Drop the broken entry so the next Put starts clean
+		os.Remove(path)
+		return nil, time.Time{}, false
+	}
+	return data, info.ModTime(), true
+}
+
+// Put stores an entry via a temporary file so concurrent scans never read a partial write
+func (c *Cache) Put(namespace, key string, data []byte) error {
+	path := c.path(namespace, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(encode(data)); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Info counts the entries and bytes in the cache
+func (c *Cache) Info() (Stats, error) {
+	stats := Stats{Namespaces: make(map[string]int)}
+	namespaces, err := os.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, err
+	}
+
+	for _, namespace := range namespaces {
+		if !namespace.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(c.Dir, namespace.Name()))
+		if err != nil {
+			return stats, err
+		}
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || !info.Mode().IsRegular() || strings.HasPrefix(entry.Name(), ".tmp-") {
+				continue
+			}
+			stats.Entries++
+			stats.Size += info.Size()
+			stats.Namespaces[namespace.Name()]++
+		}
+	}
+	return stats, nil
+}
+
+// NamespaceNames returns the namespaces in stats in sorted order
+func (s Stats) NamespaceNames() []string {
+	names := make([]string, 0, len(s.Namespaces))
+	for name := range s.Namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Clear removes every entry
+func (c *Cache) Clear() error {
+	return os.RemoveAll(c.Dir)
+}
+
+func (c *Cache) path(namespace, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, namespace, hex.EncodeToString(sum[:]))
+}
+
+func encode(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	buf.WriteString(hex.EncodeToString(sum[:]))
+	buf.WriteByte('\n')
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func decode(raw []byte) ([]byte, error) {
+	newline := bytes.IndexByte(raw, '\n')
+	if newline < 0 || !bytes.HasPrefix(raw, []byte(header)) {
+		return nil, fmt.Errorf("missing cache header")
+	}
+	want := string(raw[len(header):newline])
+	data := raw[newline+1:]
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != want {
+		return nil, fmt.Errorf("content hash mismatch")
+	}
+	return data, nil
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetPut(t *testing.T) {
+	c := &Cache{Dir: t.TempDir(), TTL: time.Hour}
+
+	if _, ok := c.Get("ghsa", "lodash@4.17.20"); ok {
+		t.Fatal("Get() hit on an empty cache")
+	}
+	if err := c.Put("ghsa", "lodash@4.17.20", []byte("[]")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	data, ok := c.Get("ghsa", "lodash@4.17.20")
+	if !ok || string(data) != "[]" {
+		t.Errorf("Get() = %q, %v, want the stored entry", data, ok)
+	}
+	if _, ok := c.Get("osv", "lodash@4.17.20"); ok {
+		t.Error("Get() hit across namespaces")
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	c := &Cache{Dir: t.TempDir(), TTL: time.Hour}
+	c.Put("packages", "https://example.com/badpak.json", []byte("[]"))
+
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(c.path("packages", "https://example.com/badpak.json"), old, old)
+
+	if _, ok := c.Get("packages", "https://example.com/badpak.json"); ok {
+		t.Error("Get() returned an expired entry")
+	}
+	data, storedAt, ok := c.GetStale("packages", "https://example.com/badpak.json")
+	if !ok || string(data) != "[]" || !storedAt.Equal(old) {
+		t.Errorf("GetStale() = %q, %v, %v, want the expired entry and its age", data, storedAt, ok)
+	}
+}
+
+func TestCorruptionRecovery(t *testing.T) {
+	c := &Cache{Dir: t.TempDir(), TTL: time.Hour}
+	path := c.path("osv-vulns", "GHSA-1")
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "truncated", content: string(encode([]byte(`{"id": "GHSA-1"}`)))[:60]},
+		{name: "modified", content: string(encode([]byte(`{"id": "GHSA-1"}`))) + "garbage"},
+		{name: "no header", content: `{"id": "GHSA-1"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.Put("osv-vulns", "GHSA-1", nil)
+			os.WriteFile(path, []byte(tt.content), 0o644)
+
+			if _, ok := c.Get("osv-vulns", "GHSA-1"); ok {
+				t.Error("Get() returned a corrupted entry")
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Error("Get() left the corrupted entry behind")
+			}
+			if err := c.Put("osv-vulns", "GHSA-1", []byte("fresh")); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if data, ok := c.Get("osv-vulns", "GHSA-1"); !ok || string(data) != "fresh" {
+				t.Errorf("Get() after recovery = %q, %v, want the new entry", data, ok)
+			}
+		})
+	}
+}
+
+func TestInfoAndClear(t *testing.T) {
+	c := &Cache{Dir: t.TempDir() + "/scnpm", TTL: time.Hour}
+
+	stats, err := c.Info()
+	if err != nil || stats.Entries != 0 {
+		t.Fatalf("Info() on a missing directory = %+v, %v, want empty stats", stats, err)
+	}
+
+	c.Put("ghsa", "a@1.0.0", []byte("[]"))
+	c.Put("ghsa", "b@1.0.0", []byte("[]"))
+	c.Put("osv-vulns", "GHSA-1", []byte("{}"))
+
+	stats, err = c.Info()
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	if stats.Entries != 3 || stats.Namespaces["ghsa"] != 2 || stats.Size == 0 {
+		t.Errorf("Info() = %+v, want 3 entries, 2 in ghsa", stats)
+	}
+	if names := stats.NamespaceNames(); len(names) != 2 || names[0] != "ghsa" {
+		t.Errorf("NamespaceNames() = %v, want [ghsa osv-vulns]", names)
+	}
+
+	if err := c.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if stats, _ := c.Info(); stats.Entries != 0 {
+		t.Errorf("Info() after Clear() = %+v, want no entries", stats)
+	}
+}
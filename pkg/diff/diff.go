@@ -0,0 +1,124 @@
+package diff
+
+import (
+	"sort"
+
+	"scnpm/pkg/types"
+)
+
+// Finding is a bad-package instance found in one or both lockfiles
+type Finding struct {
+	Package  types.PackageQuery    `json:"package"`
+	Instance types.PackageInstance `json:"instance"`
+}
+
+// Change is an installed package whose version or integrity differs between the lockfiles. An
+// integrity change at the same version means the registry served different contents under a
+// version that should be immutable, which is how republished tarballs show up.
+type Change struct {
+	Name         string `json:"name"`
+	Path         string `json:"path"`
+	OldVersion   string `json:"oldVersion,omitempty"` // Empty when the package was added
+	NewVersion   string `json:"newVersion,omitempty"` // Empty when the package was removed
+	OldIntegrity string `json:"oldIntegrity,omitempty"`
+	NewIntegrity string `json:"newIntegrity,omitempty"`
+	SameVersion  bool   `json:"sameVersion,omitempty"` // True if only the integrity changed
+}
+
+// Result sorts the findings of two scans by whether they're new and lists the packages that
+// changed between them
+type Result struct {
+	Old         string    `json:"old"` // Lockfile compared against
+	New         string    `json:"new"`
+	Introduced  []Finding `json:"introduced"`  // Only in the new lockfile
+	Removed     []Finding `json:"removed"`     // Only in the old lockfile
+	PreExisting []Finding `json:"preExisting"` // In both
+	Changed     []Change  `json:"changed"`
+}
+
+// Compare classifies the active findings of the scans of an old and a new lockfile, and lists
+// the installed packages that were added, removed, or changed version or integrity, by install
+// path
+func Compare(oldResults, newResults []types.ScanResult, oldInventory, newInventory []types.PackageInstance) Result {
+	result := Result{Introduced: []Finding{}, Removed: []Finding{}, PreExisting: []Finding{}, Changed: []Change{}}
+
+	oldFindings := findings(oldResults)
+	newFindings := findings(newResults)
+	for _, key := range findingKeys(newFindings) {
+		if _, ok := oldFindings[key]; ok {
+			result.PreExisting = append(result.PreExisting, newFindings[key])
+		} else {
+			result.Introduced = append(result.Introduced, newFindings[key])
+		}
+	}
+	for _, key := range findingKeys(oldFindings) {
+		if _, ok := newFindings[key]; !ok {
+			result.Removed = append(result.Removed, oldFindings[key])
+		}
+	}
+
+	oldPackages := byPath(oldInventory)
+	newPackages := byPath(newInventory)
+	var paths []string
+	for path := range oldPackages {
+		paths = append(paths, path)
+	}
+	for path := range newPackages {
+		if _, ok := oldPackages[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		before, hadBefore := oldPackages[path]
+		after, hasAfter := newPackages[path]
+		if hadBefore && hasAfter && before.Version == after.Version && before.Integrity == after.Integrity {
+			continue
+		}
+		change := Change{Name: after.Name, Path: path, NewVersion: after.Version, NewIntegrity: after.Integrity}
+		if hadBefore {
+			change.Name, change.OldVersion, change.OldIntegrity = before.Name, before.Version, before.Integrity
+			change.SameVersion = hasAfter && before.Version == after.Version
+		}
+		result.Changed = append(result.Changed, change)
+	}
+	return result
+}
+
+// findings keys the active instances of bad-package results by query and location, so that the
+// same finding in both lockfiles is recognized even when it moved to another line
+func findings(results []types.ScanResult) map[string]Finding {
+	found := make(map[string]Finding)
+	for _, result := range results {
+		if result.Check != "" {
+			continue
+		}
+		for _, instance := range result.Instances {
+			if instance.Suppressed {
+				continue
+			}
+			key := result.Package.Name + "@" + result.Package.Version + "\x00" + instance.Path + "\x00" + instance.Version
+			found[key] = Finding{Package: result.Package, Instance: instance}
+		}
+	}
+	return found
+}
+
+// byPath indexes installed packages by install path
+func byPath(inventory []types.PackageInstance) map[string]types.PackageInstance {
+	packages := make(map[string]types.PackageInstance, len(inventory))
+	for _, instance := range inventory {
+		packages[instance.Path] = instance
+	}
+	return packages
+}
+
+// findingKeys returns the keys of findings in order
+func findingKeys(found map[string]Finding) []string {
+	keys := make([]string, 0, len(found))
+	for key := range found {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
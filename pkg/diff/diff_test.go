@@ -0,0 +1,73 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestCompare(t *testing.T) {
+	query := types.PackageQuery{Name: "evil", Version: "1.0.0"}
+	old := []types.ScanResult{{
+		Package: query,
+		Found:   true,
+		Instances: []types.PackageInstance{
+			{Name: "evil", Version: "1.0.0", Path: "node_modules/evil", LineNumber: 10},
+			{Name: "evil", Version: "1.0.0", Path: "node_modules/a/node_modules/evil"},
+			{Name: "evil", Version: "1.0.0", Path: "node_modules/b/node_modules/evil", Suppressed: true},
+		},
+	}}
+	new := []types.ScanResult{
+		{
+			Package: query,
+			Found:   true,
+			Instances: []types.PackageInstance{
+				{Name: "evil", Version: "1.0.0", Path: "node_modules/evil", LineNumber: 12},
+				{Name: "evil", Version: "1.0.0", Path: "node_modules/c/node_modules/evil"},
+			},
+		},
+		{Package: types.PackageQuery{Name: "lodahs"}, Check: types.CheckTyposquat, Found: true, Instances: []types.PackageInstance{{Name: "lodahs", Path: "node_modules/lodahs"}}},
+	}
+	oldInventory := []types.PackageInstance{
+		{Name: "evil", Version: "1.0.0", Path: "node_modules/evil", Integrity: "sha512-a"},
+		{Name: "left-pad", Version: "1.3.0", Path: "node_modules/left-pad", Integrity: "sha512-b"},
+		{Name: "lodash", Version: "4.17.20", Path: "node_modules/lodash", Integrity: "sha512-c"},
+		{Name: "gone", Version: "1.0.0", Path: "node_modules/gone"},
+	}
+	newInventory := []types.PackageInstance{
+		{Name: "evil", Version: "1.0.0", Path: "node_modules/evil", Integrity: "sha512-a"},
+		{Name: "left-pad", Version: "1.3.0", Path: "node_modules/left-pad", Integrity: "sha512-x"},
+		{Name: "lodash", Version: "4.17.21", Path: "node_modules/lodash", Integrity: "sha512-d"},
+		{Name: "new", Version: "2.0.0", Path: "node_modules/new"},
+	}
+
+	result := Compare(old, new, oldInventory, newInventory)
+
+	paths := func(findings []Finding) []string {
+		var list []string
+		for _, finding := range findings {
+			list = append(list, finding.Instance.Path)
+		}
+		return list
+	}
+	if got, want := paths(result.Introduced), []string{"node_modules/c/node_modules/evil"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Introduced = %v, want %v", got, want)
+	}
+	if got, want := paths(result.Removed), []string{"node_modules/a/node_modules/evil"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Removed = %v, want %v", got, want)
+	}
+	if got, want := paths(result.PreExisting), []string{"node_modules/evil"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PreExisting = %v, want %v (moving to another line doesn't make a finding new)", got, want)
+	}
+
+	wantChanged := []Change{
+		{Name: "gone", Path: "node_modules/gone", OldVersion: "1.0.0"},
+		{Name: "left-pad", Path: "node_modules/left-pad", OldVersion: "1.3.0", NewVersion: "1.3.0", OldIntegrity: "sha512-b", NewIntegrity: "sha512-x", SameVersion: true},
+		{Name: "lodash", Path: "node_modules/lodash", OldVersion: "4.17.20", NewVersion: "4.17.21", OldIntegrity: "sha512-c", NewIntegrity: "sha512-d"},
+		{Name: "new", Path: "node_modules/new", NewVersion: "2.0.0"},
+	}
+	if !reflect.DeepEqual(result.Changed, wantChanged) {
+		t.Errorf("Changed = %+v, want %+v", result.Changed, wantChanged)
+	}
+}
@@ -0,0 +1,94 @@
+// Package disttag resolves npm dist-tags such as latest or next to the versions they point at,
+// using the registry's packument.
+package disttag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"scnpm/pkg/cache"
+	"scnpm/pkg/registry"
+	"scnpm/pkg/remote"
+	"scnpm/pkg/semver"
+)
+
+const cacheNamespace = "dist-tags"
+
+// tagPattern matches what npm accepts as a dist-tag name
+var tagPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9._-]*$`)
+
+// hexPattern matches abbreviated or full commit SHAs, which look like tags but aren't
+var hexPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// IsTag reports whether a queried version is a dist-tag rather than a version or range
+func IsTag(version string) bool {
+	if !tagPattern.MatchString(version) || hexPattern.MatchString(version) || semver.IsRange(version) {
+		return false
+	}
+	_, err := semver.Parse(version)
+	return err != nil
+}
+
+// Client looks up dist-tags in a registry
+type Client struct {
+	Remote   *remote.Client
+	Registry string       // Defaults to audit.DefaultRegistry
+	Token    string       // Sent as a bearer token when set
+	Cache    *cache.Cache // Optional; tags are cached per package
+	Offline  bool         // Only answer from the cache, whatever its age
+}
+
+// Resolve returns the version a package's dist-tag points at
+func (c *Client) Resolve(ctx context.Context, name, tag string) (string, error) {
+	tags, err := c.tags(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	version, ok := tags[tag]
+	if !ok {
+		return "", fmt.Errorf("%s has no dist-tag '%s'", name, tag)
+	}
+	return version, nil
+}
+
+// tags returns the dist-tags of a package, from the cache when it has them
+func (c *Client) tags(ctx context.Context, name string) (map[string]string, error) {
+	if c.Cache != nil {
+		data, ok := c.Cache.Get(cacheNamespace, name)
+		if !ok && c.Offline {
+			data, _, ok = c.Cache.GetStale(cacheNamespace, name)
+		}
+		var tags map[string]string
+		if ok && json.Unmarshal(data, &tags) == nil {
+			return tags, nil
+		}
+	}
+	if c.Offline {
+		return nil, fmt.Errorf("dist-tags of %s aren't cached and --offline is set", name)
+	}
+
+	tags, err := c.fetch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if c.Cache != nil {
+		if data, err := json.Marshal(tags); err == nil {
+			c.Cache.Put(cacheNamespace, name, data)
+		}
+	}
+	return tags, nil
+}
+
+// fetch reads the dist-tags from the abbreviated packument
+func (c *Client) fetch(ctx context.Context, name string) (map[string]string, error) {
+	var packument struct {
+		DistTags map[string]string `json:"dist-tags"`
+	}
+	registryClient := &registry.Client{Remote: c.Remote, Registry: c.Registry, Token: c.Token}
+	if err := registryClient.Packument(ctx, name, &packument); err != nil {
+		return nil, err
+	}
+	return packument.DistTags, nil
+}
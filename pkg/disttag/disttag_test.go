@@ -0,0 +1,94 @@
+package disttag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"scnpm/pkg/cache"
+)
+
+func TestIsTag(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"latest", true},
+		{"next", true},
+		{"beta-2", true},
+		{"v4", false},
+		{"1.2.3", false},
+		{"v1.2.3", false},
+		{"1.2", false},
+		{"^1.0.0", false},
+		{"x", false},
+		{"", false},
+		{"abc1234", false},
+		{"file:../local", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsTag(tt.version); got != tt.want {
+			t.Errorf("IsTag(%q) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Accept") != "application/vnd.npm.install-v1+json" {
+			http.Error(w, "want the abbreviated packument", http.StatusNotAcceptable)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/chalk":
+			json.NewEncoder(w).Encode(map[string]any{"dist-tags": map[string]string{"latest": "5.3.0", "next": "6.0.0-beta.1"}})
+		case "/@ctrl%2Ftinycolor":
+			json.NewEncoder(w).Encode(map[string]any{"dist-tags": map[string]string{"latest": "4.1.1"}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := &cache.Cache{Dir: t.TempDir(), TTL: time.Hour}
+	client := &Client{Registry: server.URL + "/", Cache: c}
+
+	tests := []struct {
+		name, tag, want string
+	}{
+		{"chalk", "latest", "5.3.0"},
+		{"chalk", "next", "6.0.0-beta.1"},
+		{"@ctrl/tinycolor", "latest", "4.1.1"},
+	}
+	for _, tt := range tests {
+		got, err := client.Resolve(context.Background(), tt.name, tt.tag)
+		if err != nil || got != tt.want {
+			t.Errorf("Resolve(%q, %q) = %q, %v, want %q", tt.name, tt.tag, got, err, tt.want)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("Resolve() sent %d requests, want 2 (one per package, then cached)", requests)
+	}
+
+	if _, err := client.Resolve(context.Background(), "chalk", "canary"); err == nil {
+		t.Error("Resolve() expected error for a missing tag")
+	}
+	if _, err := client.Resolve(context.Background(), "missing", "latest"); err == nil {
+		t.Error("Resolve() expected error for an unknown package")
+	}
+
+	offline := &Client{Registry: server.URL, Cache: c, Offline: true}
+	if got, err := offline.Resolve(context.Background(), "chalk", "latest"); err != nil || got != "5.3.0" {
+		t.Errorf("offline Resolve() = %q, %v, want the cached 5.3.0", got, err)
+	}
+	before := requests
+	if _, err := offline.Resolve(context.Background(), "react", "latest"); err == nil || requests != before {
+		t.Errorf("offline Resolve() of an uncached package = %v after %d requests, want an error without requests", err, requests-before)
+	}
+}
@@ -0,0 +1,219 @@
+// Package ghsa looks up installed packages in the GitHub Advisory Database REST API.
+package ghsa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"scnpm/pkg/cache"
+	"scnpm/pkg/remote"
+	"scnpm/pkg/semver"
+	"scnpm/pkg/types"
+)
+
+// DefaultAPI is the public GitHub REST API
+const DefaultAPI = "https://api.github.com"
+
+// Source is the query source recorded for advisories from GitHub
+const Source = "ghsa"
+
+// batchSize is how many package@version pairs go into one request's affects filter
+const batchSize = 100
+
+const cacheNamespace = "ghsa"
+
+// Client queries the global security advisories endpoint
+type Client struct {
+	Remote *remote.Client
+	API    string       // Defaults to DefaultAPI
+	Token  string       // Required; anonymous access is rate limited to 60 requests an hour
+	Cache  *cache.Cache // Optional; results are cached per package@version
+}
+
+// advisory is the part of a global security advisory scnpm uses
+type advisory struct {
+	GHSAID          string  `json:"ghsa_id"`
+	Summary         string  `json:"summary"`
+	Severity        string  `json:"severity"`
+	WithdrawnAt     *string `json:"withdrawn_at"`
+	Vulnerabilities []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		VulnerableVersionRange string `json:"vulnerable_version_range"`
+	} `json:"vulnerabilities"`
+}
+
+var nextLinkPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// Advisories returns one query per advisory affecting an installed package, with the
+// vulnerable range as the version. Cached package@version lookups aren't requested again.
+func (c *Client) Advisories(ctx context.Context, installed map[string][]string) ([]types.PackageQuery, error) {
+	if c.Token == "" {
+		return nil, fmt.Errorf("a GitHub token is required")
+	}
+
+	var specs []string
+	for name, versions := range installed {
+		for _, version := range versions {
+			specs = append(specs, name+"@"+version)
+		}
+	}
+	sort.Strings(specs)
+
+	var queries []types.PackageQuery
+	var pending []string
+	for _, spec := range specs {
+		if cached, ok := c.cached(spec); ok {
+			queries = append(queries, cached...)
+		} else {
+			pending = append(pending, spec)
+		}
+	}
+
+	for start := 0; start < len(pending); start += batchSize {
+		end := start + batchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		advisories, err := c.fetch(ctx, pending[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range pending[start:end] {
+			matched := matching(advisories, spec)
+			queries = append(queries, matched...)
+			if c.Cache != nil {
+				if data, err := json.Marshal(matched); err == nil {
+					c.Cache.Put(cacheNamespace, spec, data)
+				}
+			}
+		}
+	}
+
+	return dedupe(queries), nil
+}
+
+func (c *Client) cached(spec string) ([]types.PackageQuery, bool) {
+	if c.Cache == nil {
+		return nil, false
+	}
+	data, ok := c.Cache.Get(cacheNamespace, spec)
+	if !ok {
+		return nil, false
+	}
+	var queries []types.PackageQuery
+	if err := json.Unmarshal(data, &queries); err != nil {
+		return nil, false
+	}
+	return queries, true
+}
+
+// fetch returns every advisory affecting the given package@version pairs, following pagination
+func (c *Client) fetch(ctx context.Context, specs []string) ([]advisory, error) {
+	api := c.API
+	if api == "" {
+		api = DefaultAPI
+	}
+	params := url.Values{}
+	params.Set("ecosystem", "npm")
+	params.Set("per_page", "100")
+	params.Set("affects", strings.Join(specs, ","))
+	next := strings.TrimSuffix(api, "/") + "/advisories?" + params.Encode()
+
+	remoteClient := c.Remote
+	if remoteClient == nil {
+		remoteClient = &remote.Client{}
+	}
+
+	var advisories []advisory
+	for next != "" {
+		pageURL := next
+		data, header, err := remoteClient.DoWithHeader(ctx, func() (*http.Request, error) {
+			req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Accept", "application/vnd.github+json")
+			req.Header.Set("Authorization", "Bearer "+c.Token)
+			return req, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("GitHub advisory request failed: %v", err)
+		}
+
+		var page []advisory
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("invalid GitHub advisory response: %v", err)
+		}
+		advisories = append(advisories, page...)
+
+		next = ""
+		if m := nextLinkPattern.FindStringSubmatch(header.Get("Link")); m != nil {
+			next = m[1]
+		}
+	}
+
+	return advisories, nil
+}
+
+// matching converts the advisories whose vulnerable range covers the package@version into queries
+func matching(advisories []advisory, spec string) []types.PackageQuery {
+	i := strings.LastIndex(spec, "@")
+	name, version := spec[:i], spec[i+1:]
+	installed, err := semver.Parse(version)
+	if err != nil {
+		return nil
+	}
+
+	matched := []types.PackageQuery{}
+	for _, adv := range advisories {
+		if adv.WithdrawnAt != nil {
+			continue
+		}
+		for _, vuln := range adv.Vulnerabilities {
+			if vuln.Package.Ecosystem != "npm" || vuln.Package.Name != name {
+				continue
+			}
+			// GitHub separates comparators with commas: ">= 1.0.0, < 1.2.3"
+			versionRange := strings.Join(strings.Fields(strings.ReplaceAll(vuln.VulnerableVersionRange, ",", " ")), " ")
+			r, err := semver.ParseRange(versionRange)
+			if err != nil || !r.Satisfies(installed) {
+				continue
+			}
+
+			severity, _ := types.NormalizeSeverity(adv.Severity)
+			matched = append(matched, types.PackageQuery{
+				Name:     name,
+				Version:  versionRange,
+				Severity: severity,
+				Advisory: adv.GHSAID,
+				Note:     adv.Summary,
+				Source:   Source,
+			})
+		}
+	}
+	return matched
+}
+
+// dedupe drops repeated advisory/range pairs, which occur when several installed versions share an advisory
+func dedupe(queries []types.PackageQuery) []types.PackageQuery {
+	seen := make(map[string]bool)
+	var unique []types.PackageQuery
+	for _, query := range queries {
+		key := query.Advisory + " " + query.Name + "@" + query.Version
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, query)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,72 @@
+package ghsa
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"scnpm/pkg/cache"
+	"scnpm/pkg/types"
+)
+
+func TestAdvisories(t *testing.T) {
+	requests := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "bad credentials", http.StatusUnauthorized)
+			return
+		}
+		if !strings.Contains(r.URL.Query().Get("affects"), "lodash@4.17.20") {
+			t.Errorf("affects = %q, want the installed packages", r.URL.Query().Get("affects"))
+		}
+
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/advisories?page=2&affects=lodash@4.17.20>; rel="next"`, server.URL))
+			w.Write([]byte(`[{"ghsa_id": "GHSA-35jh-r3h4-6jhm", "summary": "Command Injection in lodash", "severity": "high",
+				"vulnerabilities": [{"package": {"ecosystem": "npm", "name": "lodash"}, "vulnerable_version_range": "< 4.17.21"}]}]`))
+			return
+		}
+		w.Write([]byte(`[
+			{"ghsa_id": "GHSA-old", "severity": "low", "vulnerabilities": [{"package": {"ecosystem": "npm", "name": "lodash"}, "vulnerable_version_range": "< 4.0.0"}]},
+			{"ghsa_id": "GHSA-gone", "severity": "low", "withdrawn_at": "2024-01-01T00:00:00Z",
+				"vulnerabilities": [{"package": {"ecosystem": "npm", "name": "lodash"}, "vulnerable_version_range": ">= 4.0.0"}]},
+			{"ghsa_id": "GHSA-mini", "severity": "medium", "vulnerabilities": [{"package": {"ecosystem": "npm", "name": "minimist"}, "vulnerable_version_range": ">= 1.0.0, < 1.2.6"}]}
+		]`))
+	}))
+	defer server.Close()
+
+	client := &Client{API: server.URL, Token: "token", Cache: &cache.Cache{Dir: t.TempDir(), TTL: time.Hour}}
+	installed := map[string][]string{"lodash": {"4.17.20", "4.17.19"}, "minimist": {"1.2.5"}}
+
+	queries, err := client.Advisories(context.Background(), installed)
+	if err != nil {
+		t.Fatalf("Advisories() error = %v", err)
+	}
+	want := []types.PackageQuery{
+		{Name: "lodash", Version: "< 4.17.21", Severity: types.SeverityHigh, Advisory: "GHSA-35jh-r3h4-6jhm", Note: "Command Injection in lodash", Source: Source},
+		{Name: "minimist", Version: ">= 1.0.0 < 1.2.6", Severity: types.SeverityModerate, Advisory: "GHSA-mini", Source: Source},
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("Advisories() = %+v, want %+v", queries, want)
+	}
+	if requests != 2 {
+		t.Errorf("Advisories() made %d requests, want 2 pages", requests)
+	}
+
+	// A second run is served from the cache
+	queries, err = client.Advisories(context.Background(), installed)
+	if err != nil || !reflect.DeepEqual(queries, want) || requests != 2 {
+		t.Errorf("Advisories() from cache = %+v, %v after %d requests, want the same queries without requests", queries, err, requests)
+	}
+
+	if _, err := (&Client{API: server.URL}).Advisories(context.Background(), installed); err == nil {
+		t.Error("Advisories() expected error without a token")
+	}
+}
@@ -0,0 +1,144 @@
+package ignore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"scnpm/pkg/input"
+	"scnpm/pkg/scanner"
+	"scnpm/pkg/semver"
+	"scnpm/pkg/types"
+)
+
+// DefaultFile is the conventional name of an ignore file
+const DefaultFile = ".scnpmignore.json"
+
+// dateLayout is the format of the expires field
+const dateLayout = "2006-01-02"
+
+// Rule suppresses findings that were triaged as accepted (a same-named fork, a dev-only tool)
+type Rule struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"` // Exact version or npm range; empty matches any version
+	Path    string `json:"path,omitempty"`    // Glob matched against the install path; empty matches any path
+	Reason  string `json:"reason"`
+	Expires string `json:"expires,omitempty"` // YYYY-MM-DD; the rule applies through this date
+}
+
+// Load reads an ignore file holding a JSON array of rules
+func Load(filePath string) ([]Rule, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ignore file '%s': %v", filePath, err)
+	}
+	rules, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ignore file '%s': %v", filePath, err)
+	}
+	return rules, nil
+}
+
+// Parse decodes and validates ignore rules
+func Parse(data []byte) ([]Rule, error) {
+	data, err := input.Normalize(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("entry %d: missing name", i)
+		}
+		if rule.Reason == "" {
+			return nil, fmt.Errorf("entry %d ('%s'): missing reason", i, rule.Name)
+		}
+		if semver.IsRange(rule.Version) {
+			if _, err := semver.ParseRange(rule.Version); err != nil {
+				return nil, fmt.Errorf("entry %d ('%s'): invalid version range '%s': %v", i, rule.Name, rule.Version, err)
+			}
+		}
+		if rule.Path != "" {
+			if _, err := path.Match(rule.Path, ""); err != nil {
+				return nil, fmt.Errorf("entry %d ('%s'): invalid path glob '%s': %v", i, rule.Name, rule.Path, err)
+			}
+		}
+		if rule.Expires != "" {
+			if _, err := time.Parse(dateLayout, rule.Expires); err != nil {
+				return nil, fmt.Errorf("entry %d ('%s'): invalid expires date '%s', expected YYYY-MM-DD", i, rule.Name, rule.Expires)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+// Expired reports whether the rule's expiry date has passed at now
+func (r Rule) Expired(now time.Time) bool {
+	if r.Expires == "" {
+		return false
+	}
+	expires, err := time.Parse(dateLayout, r.Expires)
+	if err != nil {
+		return false
+	}
+	return !now.UTC().Before(expires.AddDate(0, 0, 1))
+}
+
+// matches reports whether the rule covers an instance of the named package
+func (r Rule) matches(name string, instance types.PackageInstance) bool {
+	if r.Name != name {
+		return false
+	}
+	if !scanner.MatchesVersion(instance.Version, r.Version) {
+		return false
+	}
+	if r.Path != "" {
+		if ok, _ := path.Match(r.Path, instance.Path); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply marks the instances covered by an unexpired rule as suppressed. It returns the expired
+// rules that would otherwise have matched a finding, so they can be called out.
+func Apply(results []types.ScanResult, rules []Rule, now time.Time) []Rule {
+	var expired []Rule
+	seen := make(map[int]bool)
+
+	for i := range results {
+		for j := range results[i].Instances {
+			instance := &results[i].Instances[j]
+			// The query may only have named it loosely (patterns, --fuzzy, --match-unscoped)
+			name := instance.Name
+			if name == "" {
+				name = results[i].Package.Name
+			}
+			for k, rule := range rules {
+				if !rule.matches(name, *instance) {
+					continue
+				}
+				if rule.Expired(now) {
+					if !seen[k] {
+						seen[k] = true
+						expired = append(expired, rule)
+					}
+					continue
+				}
+				instance.Suppressed = true
+				instance.SuppressedReason = rule.Reason
+				break
+			}
+		}
+	}
+
+	return expired
+}
@@ -0,0 +1,111 @@
+package ignore
+
+import (
+	"testing"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "valid", data: `[{"name": "debug", "version": "<5.0.0", "path": "node_modules/*", "reason": "fork", "expires": "2030-01-31"}]`},
+		{name: "name only", data: `[{"name": "debug", "reason": "dev tool"}]`},
+		{name: "missing name", data: `[{"reason": "fork"}]`, wantErr: true},
+		{name: "missing reason", data: `[{"name": "debug"}]`, wantErr: true},
+		{name: "invalid range", data: `[{"name": "debug", "version": ">=1.x.2.3", "reason": "fork"}]`, wantErr: true},
+		{name: "invalid glob", data: `[{"name": "debug", "path": "[", "reason": "fork"}]`, wantErr: true},
+		{name: "invalid date", data: `[{"name": "debug", "reason": "fork", "expires": "31/01/2030"}]`, wantErr: true},
+		{name: "not an array", data: `{"name": "debug"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	results := []types.ScanResult{
+		{
+			Package: types.PackageQuery{Name: "debug", Version: "4.4.2"},
+			Found:   true,
+			Instances: []types.PackageInstance{
+				{Version: "4.4.2", Path: "node_modules/debug"},
+				{Version: "4.4.2", Path: "node_modules/a/node_modules/debug"},
+			},
+		},
+		{
+			Package:   types.PackageQuery{Name: "chalk", Version: "5.6.1"},
+			Found:     true,
+			Instances: []types.PackageInstance{{Version: "5.6.1", Path: "node_modules/chalk"}},
+		},
+		{
+			Package:   types.PackageQuery{Name: "color", Version: "5.0.1"},
+			Found:     true,
+			Instances: []types.PackageInstance{{Version: "5.0.1", Path: "node_modules/color"}},
+		},
+	}
+	rules := []Rule{
+		{Name: "debug", Path: "node_modules/debug", Reason: "internal fork"},
+		{Name: "chalk", Version: ">=5.0.0", Reason: "accepted", Expires: "2026-03-01"},
+		{Name: "color", Reason: "old triage", Expires: "2026-02-28"},
+	}
+
+	expired := Apply(results, rules, now)
+
+	if !results[0].Instances[0].Suppressed || results[0].Instances[0].SuppressedReason != "internal fork" {
+		t.Errorf("top-level debug = %+v, want suppressed by path", results[0].Instances[0])
+	}
+	if results[0].Instances[1].Suppressed {
+		t.Errorf("nested debug was suppressed, but the path glob doesn't match it")
+	}
+	if !results[1].Instances[0].Suppressed {
+		t.Errorf("chalk = %+v, want suppressed on its expiry date", results[1].Instances[0])
+	}
+	if results[2].Instances[0].Suppressed {
+		t.Errorf("color = %+v, want unsuppressed after expiry", results[2].Instances[0])
+	}
+	if len(expired) != 1 || expired[0].Name != "color" {
+		t.Errorf("Apply() expired = %+v, want the color rule", expired)
+	}
+}
+
+func TestApplyInstanceName(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	// --match-unscoped reaches @types/node from the query "node"
+	results := []types.ScanResult{{
+		Package: types.PackageQuery{Name: "node"},
+		Found:   true,
+		Instances: []types.PackageInstance{
+			{Name: "@types/node", Version: "20.1.0", Path: "node_modules/@types/node", MatchReason: "scope-relaxed"},
+			{Version: "20.1.0", Path: "node_modules/node"},
+		},
+	}}
+
+	Apply(results, []Rule{{Name: "@types/node", Reason: "typings only"}}, now)
+	if scoped := results[0].Instances[0]; !scoped.Suppressed || scoped.SuppressedReason != "typings only" {
+		t.Errorf("@types/node = %+v, want suppressed by the rule naming it", scoped)
+	}
+	if results[0].Instances[1].Suppressed {
+		t.Errorf("node was suppressed by a rule for @types/node")
+	}
+
+	results[0].Instances[0].Suppressed = false
+	Apply(results, []Rule{{Name: "node", Reason: "query name"}}, now)
+	if results[0].Instances[0].Suppressed {
+		t.Errorf("@types/node was suppressed by a rule for the query's name")
+	}
+	if !results[0].Instances[1].Suppressed {
+		t.Errorf("node without a recorded name = %+v, want matched by the query's name", results[0].Instances[1])
+	}
+}
@@ -0,0 +1,149 @@
+package input
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// ArchiveMember is a file read from inside an archive
+type ArchiveMember struct {
+	Name string
+	Data []byte
+}
+
+// IsArchive reports whether a path names a supported archive (.zip, .tar, .tar.gz, .tgz)
+func IsArchive(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadArchive reads every member of a zip or tar(.gz) archive accepted by match into memory.
+// Members under node_modules are never considered, and members larger than maxSize are
+// skipped (their names are returned) so a hostile archive can't exhaust memory.
+func ReadArchive(name string, match func(member string) bool, maxSize int64) ([]ArchiveMember, []string, error) {
+	if strings.HasSuffix(strings.ToLower(name), ".zip") {
+		return readZip(name, match, maxSize)
+	}
+	return readTar(name, match, maxSize)
+}
+
+func wanted(member string, match func(string) bool) bool {
+	for _, part := range strings.Split(member, "/") {
+		if part == "node_modules" {
+			return false
+		}
+	}
+	return match(path.Base(member))
+}
+
+// readLimited reads at most maxSize bytes, reporting false if the content is larger
+func readLimited(r io.Reader, maxSize int64) ([]byte, bool, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, false, nil
+	}
+	return data, true, nil
+}
+
+func readZip(name string, match func(string) bool, maxSize int64) ([]ArchiveMember, []string, error) {
+	reader, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reader.Close()
+
+	var members []ArchiveMember
+	var skipped []string
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || !wanted(file.Name, match) {
+			continue
+		}
+		if file.UncompressedSize64 > uint64(maxSize) {
+			skipped = append(skipped, file.Name)
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open archive member '%s': %v", file.Name, err)
+		}
+		// The declared size can lie, so enforce the limit while reading too
+		data, ok, err := readLimited(rc, maxSize)
+		rc.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read archive member '%s': %v", file.Name, err)
+		}
+		if !ok {
+			skipped = append(skipped, file.Name)
+			continue
+		}
+		members = append(members, ArchiveMember{Name: file.Name, Data: data})
+	}
+
+	return members, skipped, nil
+}
+
+func readTar(name string, match func(string) bool, maxSize int64) ([]ArchiveMember, []string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var members []ArchiveMember
+	var skipped []string
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if header.Typeflag != tar.TypeReg || !wanted(header.Name, match) {
+			continue
+		}
+		if header.Size > maxSize {
+			skipped = append(skipped, header.Name)
+			continue
+		}
+
+		data, ok, err := readLimited(tr, maxSize)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read archive member '%s': %v", header.Name, err)
+		}
+		if !ok {
+			skipped = append(skipped, header.Name)
+			continue
+		}
+		members = append(members, ArchiveMember{Name: header.Name, Data: data})
+	}
+
+	return members, skipped, nil
+}
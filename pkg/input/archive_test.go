@@ -0,0 +1,114 @@
+package input
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var archiveFixture = map[string]string{
+	"repo/package-lock.json":                  `{"lockfileVersion": 3}`,
+	"repo/packages/web/yarn.lock":             "# yarn lockfile v1\n",
+	"repo/node_modules/foo/package-lock.json": `{"lockfileVersion": 3}`,
+	"repo/README.md":                          "# readme",
+	"repo/packages/huge/pnpm-lock.yaml":       strings.Repeat("x", 2048),
+}
+
+func isLockfile(name string) bool {
+	return name == "package-lock.json" || name == "yarn.lock" || name == "pnpm-lock.yaml"
+}
+
+func writeZip(t *testing.T, path string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create zip: %v", err)
+	}
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+	for name, content := range archiveFixture {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to finish zip: %v", err)
+	}
+}
+
+func writeTarGz(t *testing.T, path string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create tarball: %v", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for name, content := range archiveFixture {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+}
+
+func TestReadArchive(t *testing.T) {
+	tmpDir := t.TempDir()
+	zipPath := filepath.Join(tmpDir, "repo.zip")
+	tarPath := filepath.Join(tmpDir, "repo.tar.gz")
+	writeZip(t, zipPath)
+	writeTarGz(t, tarPath)
+
+	for _, archive := range []string{zipPath, tarPath} {
+		t.Run(filepath.Base(archive), func(t *testing.T) {
+			members, skipped, err := ReadArchive(archive, isLockfile, 1024)
+			if err != nil {
+				t.Fatalf("ReadArchive() returned error: %v", err)
+			}
+
+			var names []string
+			for _, member := range members {
+				names = append(names, member.Name)
+			}
+			want := map[string]bool{"repo/package-lock.json": true, "repo/packages/web/yarn.lock": true}
+			if len(names) != len(want) {
+				t.Errorf("ReadArchive() members = %v, want %v", names, want)
+			}
+			for _, name := range names {
+				if !want[name] {
+					t.Errorf("Unexpected member %s", name)
+				}
+			}
+
+			if !reflect.DeepEqual(skipped, []string{"repo/packages/huge/pnpm-lock.yaml"}) {
+				t.Errorf("ReadArchive() skipped = %v, want the oversized member", skipped)
+			}
+		})
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	for name, want := range map[string]bool{
+		"snapshot.zip":      true,
+		"snapshot.TAR.GZ":   true,
+		"snapshot.tgz":      true,
+		"package-lock.json": false,
+	} {
+		if got := IsArchive(name); got != want {
+			t.Errorf("IsArchive(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package input
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+	bomUTF32LE = []byte{0xFF, 0xFE, 0x00, 0x00}
+	bomUTF32BE = []byte{0x00, 0x00, 0xFE, 0xFF}
+)
+
+// Normalize converts file contents to plain UTF-8 so they can be handed to a JSON decoder.
+// A leading UTF-8 byte order mark is stripped and UTF-16 (with or without a BOM) is
+// transcoded; anything else that isn't valid UTF-8 is rejected with a descriptive error.
+func Normalize(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF32LE), bytes.HasPrefix(data, bomUTF32BE):
+		return nil, fmt.Errorf("unsupported encoding UTF-32, re-save the file as UTF-8")
+	case bytes.HasPrefix(data, bomUTF8):
+		data = data[len(bomUTF8):]
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return decodeUTF16(data[len(bomUTF16LE):], binary.LittleEndian)
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return decodeUTF16(data[len(bomUTF16BE):], binary.BigEndian)
+	case looksLikeUTF16(data, 1):
+		return decodeUTF16(data, binary.LittleEndian)
+	case looksLikeUTF16(data, 0):
+		return decodeUTF16(data, binary.BigEndian)
+	}
+
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("file is not valid UTF-8 (possibly Latin-1 or another legacy encoding), re-save it as UTF-8")
+	}
+	return data, nil
+}
+
+// looksLikeUTF16 detects BOM-less UTF-16 by the NUL high byte of the first ASCII character
+// (JSON and text lists always start with one). zeroAt is the index of the NUL byte.
+func looksLikeUTF16(data []byte, zeroAt int) bool {
+	if len(data) < 2 || len(data)%2 != 0 {
+		return false
+	}
+	return data[zeroAt] == 0 && data[1-zeroAt] != 0
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) ([]byte, error) {
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("invalid UTF-16 content: odd number of bytes")
+	}
+
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+
+	var buf bytes.Buffer
+	for _, r := range utf16.Decode(units) {
+		if r == utf8.RuneError {
+			return nil, fmt.Errorf("invalid UTF-16 content: unpaired surrogate")
+		}
+		buf.WriteRune(r)
+	}
+	return buf.Bytes(), nil
+}
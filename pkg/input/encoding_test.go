@@ -0,0 +1,83 @@
+package input
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+func encodeUTF16(s string, order binary.AppendByteOrder, bom bool) []byte {
+	var out []byte
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	for _, u := range units {
+		out = order.AppendUint16(out, u)
+	}
+	return out
+}
+
+func TestNormalize(t *testing.T) {
+	const content = `["debug@4.3.4", "tïnycolor@1.0.0"]`
+
+	tests := []struct {
+		name    string
+		input   []byte
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "plain UTF-8",
+			input: []byte(content),
+			want:  content,
+		},
+		{
+			name:  "UTF-8 with BOM",
+			input: append([]byte{0xEF, 0xBB, 0xBF}, content...),
+			want:  content,
+		},
+		{
+			name:  "UTF-16LE with BOM",
+			input: encodeUTF16(content, binary.LittleEndian, true),
+			want:  content,
+		},
+		{
+			name:  "UTF-16BE with BOM",
+			input: encodeUTF16(content, binary.BigEndian, true),
+			want:  content,
+		},
+		{
+			name:  "UTF-16LE without BOM",
+			input: encodeUTF16(content, binary.LittleEndian, false),
+			want:  content,
+		},
+		{
+			name:  "UTF-16BE without BOM",
+			input: encodeUTF16(content, binary.BigEndian, false),
+			want:  content,
+		},
+		{
+			name:    "UTF-32LE",
+			input:   []byte{0xFF, 0xFE, 0x00, 0x00, '[', 0, 0, 0},
+			wantErr: true,
+		},
+		{
+			name:    "Latin-1",
+			input:   []byte("[\"t\xefnycolor@1.0.0\"]"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Normalize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("Normalize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
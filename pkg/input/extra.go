@@ -0,0 +1,19 @@
+package input
+
+import "encoding/json"
+
+// UnmodeledFields decodes the JSON object raw and returns its fields other than modeled, or nil
+// if there are none, for an importer to pass through in PackageQuery.Extra
+func UnmodeledFields(raw json.RawMessage, modeled ...string) (map[string]any, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	for _, name := range modeled {
+		delete(fields, name)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
@@ -0,0 +1,25 @@
+package input
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnmodeledFields(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want map[string]any
+	}{
+		{`{"id": "SNYK-1", "title": "Malicious", "cvssScore": 9.8}`, map[string]any{"id": "SNYK-1", "cvssScore": 9.8}},
+		{`{"title": "Malicious"}`, nil},
+	}
+	for _, tt := range tests {
+		got, err := UnmodeledFields([]byte(tt.raw), "title", "url")
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("UnmodeledFields(%s) = %v, %v, want %v", tt.raw, got, err, tt.want)
+		}
+	}
+	if _, err := UnmodeledFields([]byte(`[]`), "title"); err == nil {
+		t.Error("UnmodeledFields([]) succeeded, want an error for a non-object")
+	}
+}
@@ -0,0 +1,164 @@
+// Package license evaluates SPDX license expressions such as "MIT OR GPL-2.0" against a list
+// of disallowed license identifiers.
+package license
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Violates reports whether a package under expression can't be used without accepting one of the
+// denied licenses: an OR leaves a choice, so it only violates when every alternative does, while
+// AND requires all of its licenses. Identifiers compare case-insensitively, and a denied "GPL-3.0"
+// also covers "GPL-3.0-only", "GPL-3.0-or-later" and "GPL-3.0+". Expressions that don't parse
+// are compared whole.
+func Violates(expression string, denied []string) bool {
+	deny := make(map[string]bool, len(denied))
+	for _, id := range denied {
+		deny[normalize(id)] = true
+	}
+
+	p := &parser{tokens: tokenize(expression)}
+	node, err := p.expression()
+	if err != nil || p.pos < len(p.tokens) {
+		return deny[normalize(expression)]
+	}
+	return !node.allowed(deny)
+}
+
+// idPattern matches an SPDX license or exception identifier, including LicenseRef- ones
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9.+:-]+$`)
+
+// IsExpression reports whether s is a syntactically valid SPDX license expression, such as "MIT"
+// or "(MIT OR Apache-2.0) AND BSD-3-Clause", as opposed to free text like "SEE LICENSE IN LICENSE"
+func IsExpression(s string) bool {
+	tokens := tokenize(s)
+	for i, token := range tokens {
+		if token != "(" && token != ")" && !idPattern.MatchString(token) {
+			return false
+		}
+		// Violates tolerates a dangling WITH; an expression needs its exception
+		if strings.EqualFold(token, "with") && (i+1 == len(tokens) || !idPattern.MatchString(tokens[i+1])) {
+			return false
+		}
+	}
+	p := &parser{tokens: tokens}
+	_, err := p.expression()
+	return err == nil && p.pos == len(tokens)
+}
+
+// normalize folds case and the -only/-or-later/+ variants of an identifier
+func normalize(id string) string {
+	id = strings.ToLower(strings.TrimSpace(id))
+	id = strings.TrimSuffix(id, "+")
+	id = strings.TrimSuffix(id, "-or-later")
+	id = strings.TrimSuffix(id, "-only")
+	return id
+}
+
+// node is a parsed expression: a license identifier, or an AND/OR of sub-expressions
+type node struct {
+	id       string
+	operator string
+	operands []node
+}
+
+// allowed reports whether the expression can be satisfied without a denied license
+func (n node) allowed(deny map[string]bool) bool {
+	switch n.operator {
+	case "and":
+		for _, operand := range n.operands {
+			if !operand.allowed(deny) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for _, operand := range n.operands {
+			if operand.allowed(deny) {
+				return true
+			}
+		}
+		return false
+	default:
+		return !deny[normalize(n.id)]
+	}
+}
+
+// tokenize splits an expression into identifiers, operators and parentheses
+func tokenize(expression string) []string {
+	expression = strings.ReplaceAll(expression, "(", " ( ")
+	expression = strings.ReplaceAll(expression, ")", " ) ")
+	return strings.Fields(expression)
+}
+
+// parser is a recursive descent parser for SPDX expressions; AND binds tighter than OR
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return strings.ToLower(p.tokens[p.pos])
+	}
+	return ""
+}
+
+func (p *parser) expression() (node, error) {
+	return p.binary("or", p.term)
+}
+
+func (p *parser) term() (node, error) {
+	return p.binary("and", p.factor)
+}
+
+// binary parses operands joined by operator
+func (p *parser) binary(operator string, operand func() (node, error)) (node, error) {
+	first, err := operand()
+	if err != nil {
+		return node{}, err
+	}
+	operands := []node{first}
+	for p.peek() == operator {
+		p.pos++
+		next, err := operand()
+		if err != nil {
+			return node{}, err
+		}
+		operands = append(operands, next)
+	}
+	if len(operands) == 1 {
+		return first, nil
+	}
+	return node{operator: operator, operands: operands}, nil
+}
+
+func (p *parser) factor() (node, error) {
+	switch token := p.peek(); token {
+	case "":
+		return node{}, fmt.Errorf("unexpected end of expression")
+	case "(":
+		p.pos++
+		inner, err := p.expression()
+		if err != nil {
+			return node{}, err
+		}
+		if p.peek() != ")" {
+			return node{}, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return inner, nil
+	case ")", "and", "or", "with":
+		return node{}, fmt.Errorf("unexpected '%s'", token)
+	}
+
+	id := p.tokens[p.pos]
+	p.pos++
+	if p.peek() == "with" {
+		// Exceptions like "GPL-2.0 WITH Classpath-exception-2.0" still carry the base license
+		p.pos += 2
+	}
+	return node{id: id}, nil
+}
@@ -0,0 +1,60 @@
+package license
+
+import "testing"
+
+func TestViolates(t *testing.T) {
+	denied := []string{"GPL-3.0", "AGPL-3.0"}
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{"MIT", false},
+		{"GPL-3.0", true},
+		{"gpl-3.0", true},
+		{"GPL-3.0-only", true},
+		{"GPL-3.0-or-later", true},
+		{"GPL-3.0+", true},
+		{"GPL-2.0", false},
+		{"LGPL-3.0", false},
+		{"MIT OR GPL-3.0", false},
+		{"GPL-3.0 OR AGPL-3.0", true},
+		{"MIT AND GPL-3.0", true},
+		{"(MIT OR Apache-2.0) AND AGPL-3.0-only", true},
+		{"(MIT AND GPL-3.0) OR Apache-2.0", false},
+		{"MIT OR Apache-2.0 AND GPL-3.0", false},
+		{"GPL-3.0 WITH GCC-exception-3.1", true},
+		{"(MIT", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := Violates(tt.expression, denied); got != tt.want {
+			t.Errorf("Violates(%q) = %v, want %v", tt.expression, got, tt.want)
+		}
+	}
+}
+
+func TestIsExpression(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"MIT", true},
+		{"GPL-3.0+", true},
+		{"(MIT OR Apache-2.0) AND BSD-3-Clause", true},
+		{"GPL-2.0 WITH Classpath-exception-2.0", true},
+		{"LicenseRef-Proprietary", true},
+		{"SEE LICENSE IN LICENSE.md", false},
+		{"MIT OR", false},
+		{"(MIT", false},
+		{"GPL-2.0 WITH", false},
+		{"MIT/X11", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsExpression(tt.s); got != tt.want {
+			t.Errorf("IsExpression(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
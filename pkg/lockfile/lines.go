@@ -0,0 +1,151 @@
+package lockfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// LineIndex records the line on which every `packages` key (lockfileVersion 2+)
+// and every `dependencies` key (lockfileVersion 1, recursively) starts.
+// Keys are install paths in the same form the scanner reports them,
+// e.g. "node_modules/express/node_modules/debug".
+func LineIndex(data []byte) (map[string]int, error) {
+	idx := &lineIndexer{
+		dec:   json.NewDecoder(bytes.NewReader(data)),
+		lines: make(map[string]int),
+	}
+	for i, b := range data {
+		if b == '\n' {
+			idx.newlines = append(idx.newlines, i)
+		}
+	}
+
+	if err := idx.expectDelim('{'); err != nil {
+		return nil, err
+	}
+	for idx.dec.More() {
+		key, err := idx.key()
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "packages":
+			err = idx.packages()
+		case "dependencies":
+			err = idx.dependencies("")
+		default:
+			err = idx.skip()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return idx.lines, nil
+}
+
+type lineIndexer struct {
+	dec      *json.Decoder
+	newlines []int
+	lines    map[string]int
+}
+
+// line converts the decoder's current input offset to a 1-based line number
+func (idx *lineIndexer) line() int {
+	offset := int(idx.dec.InputOffset())
+	return sort.SearchInts(idx.newlines, offset) + 1
+}
+
+func (idx *lineIndexer) record(path string) {
+	// lockfileVersion 2 files carry both shapes; the packages entry comes first and wins
+	if _, exists := idx.lines[path]; !exists {
+		idx.lines[path] = idx.line()
+	}
+}
+
+func (idx *lineIndexer) packages() error {
+	if err := idx.expectDelim('{'); err != nil {
+		return err
+	}
+	for idx.dec.More() {
+		path, err := idx.key()
+		if err != nil {
+			return err
+		}
+		idx.record(path)
+		if err := idx.skip(); err != nil {
+			return err
+		}
+	}
+	return idx.expectDelim('}')
+}
+
+func (idx *lineIndexer) dependencies(basePath string) error {
+	if err := idx.expectDelim('{'); err != nil {
+		return err
+	}
+	for idx.dec.More() {
+		name, err := idx.key()
+		if err != nil {
+			return err
+		}
+		path := "node_modules/" + name
+		if basePath != "" {
+			path = basePath + "/node_modules/" + name
+		}
+		idx.record(path)
+
+		if err := idx.expectDelim('{'); err != nil {
+			return err
+		}
+		for idx.dec.More() {
+			field, err := idx.key()
+			if err != nil {
+				return err
+			}
+			if field == "dependencies" {
+				err = idx.dependencies(path)
+			} else {
+				err = idx.skip()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err := idx.expectDelim('}'); err != nil {
+			return err
+		}
+	}
+	return idx.expectDelim('}')
+}
+
+func (idx *lineIndexer) key() (string, error) {
+	tok, err := idx.dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("expected object key, got %v", tok)
+	}
+	return key, nil
+}
+
+func (idx *lineIndexer) expectDelim(want json.Delim) error {
+	tok, err := idx.dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+// skip consumes the next value without recording anything
+func (idx *lineIndexer) skip() error {
+	var raw json.RawMessage
+	return idx.dec.Decode(&raw)
+}
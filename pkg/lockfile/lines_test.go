@@ -0,0 +1,94 @@
+package lockfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLineIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]int
+	}{
+		{
+			name: "lockfileVersion 2 packages",
+			content: `{
+  "name": "test-project",
+  "lockfileVersion": 2,
+  "packages": {
+    "": {
+      "name": "test-project"
+    },
+    "node_modules/react": {
+      "version": "18.2.0"
+    },
+    "node_modules/express/node_modules/debug": {
+      "version": "2.6.9"
+    }
+  }
+}`,
+			want: map[string]int{
+				"":                   5,
+				"node_modules/react": 8,
+				"node_modules/express/node_modules/debug": 11,
+			},
+		},
+		{
+			name: "lockfileVersion 1 nested dependencies",
+			content: `{
+  "lockfileVersion": 1,
+  "dependencies": {
+    "express": {
+      "version": "4.18.2",
+      "dependencies": {
+        "debug": {
+          "version": "2.6.9"
+        }
+      }
+    },
+    "@types/node": {
+      "version": "18.0.0"
+    }
+  }
+}`,
+			want: map[string]int{
+				"node_modules/express":                    4,
+				"node_modules/express/node_modules/debug": 7,
+				"node_modules/@types/node":                12,
+			},
+		},
+		{
+			name: "packages entry wins over legacy dependencies",
+			content: `{
+  "packages": {
+    "node_modules/react": {"version": "18.2.0"}
+  },
+  "dependencies": {
+    "react": {"version": "18.2.0"}
+  }
+}`,
+			want: map[string]int{
+				"node_modules/react": 3,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LineIndex([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("LineIndex() returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LineIndex() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineIndexInvalid(t *testing.T) {
+	if _, err := LineIndex([]byte(`["not", "an", "object"]`)); err == nil {
+		t.Error("Expected error for non-object document, got nil")
+	}
+}
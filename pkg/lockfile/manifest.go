@@ -0,0 +1,87 @@
+package lockfile
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"scnpm/pkg/types"
+)
+
+// manifestProbe captures just enough of a JSON document to tell a lockfile from a package.json
+type manifestProbe struct {
+	LockfileVersion *int                       `json:"lockfileVersion"`
+	Packages        map[string]json.RawMessage `json:"packages"`
+	Dependencies    map[string]json.RawMessage `json:"dependencies"`
+	DevDependencies map[string]string          `json:"devDependencies"`
+	Scripts         map[string]string          `json:"scripts"`
+}
+
+// IsManifest reports whether a JSON document looks like a package.json manifest rather than
+// a lockfile: it lacks lockfile structure but has manifest fields such as scripts or
+// dependencies declared as version range strings.
+func IsManifest(data []byte) bool {
+	var probe manifestProbe
+	if err := json.Unmarshal(data, &probe); err != nil {
+		// devDependencies or scripts with non-string values can't be a manifest either
+		return false
+	}
+	if probe.LockfileVersion != nil || len(probe.Packages) > 0 {
+		return false
+	}
+
+	if len(probe.Scripts) > 0 || len(probe.DevDependencies) > 0 {
+		return true
+	}
+	for _, dep := range probe.Dependencies {
+		var spec string
+		if json.Unmarshal(dep, &spec) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ManifestPath is the entry key under which ParseManifest stores the declared dependencies
+const ManifestPath = "package.json"
+
+// ParseManifest converts a package.json into a PackageLock with a single entry declaring the
+// manifest's dependency ranges, so the scanner reports them as references from package.json
+func ParseManifest(data []byte) (*types.PackageLock, error) {
+	var manifest struct {
+		Name                 string            `json:"name"`
+		Version              string            `json:"version"`
+		Dependencies         map[string]string `json:"dependencies"`
+		DevDependencies      map[string]string `json:"devDependencies"`
+		PeerDependencies     map[string]string `json:"peerDependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %v", err)
+	}
+
+	return &types.PackageLock{
+		Name:            manifest.Name,
+		Version:         manifest.Version,
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			ManifestPath: {
+				Version:              manifest.Version,
+				Dependencies:         manifest.Dependencies,
+				DevDependencies:      manifest.DevDependencies,
+				PeerDependencies:     manifest.PeerDependencies,
+				OptionalDependencies: manifest.OptionalDependencies,
+			},
+		},
+	}, nil
+}
+
+// IsEmpty reports whether a lockfile contains no installed packages at all.
+// The root project entry ("") doesn't count as an installed package.
+func IsEmpty(packageLock *types.PackageLock) bool {
+	for path := range packageLock.Packages {
+		if path != "" {
+			return false
+		}
+	}
+	return len(packageLock.Dependencies) == 0
+}
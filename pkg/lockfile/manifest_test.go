@@ -0,0 +1,86 @@
+package lockfile
+
+import (
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestIsManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{
+			name:    "package.json with dependency ranges",
+			content: `{"name": "app", "dependencies": {"react": "^18.2.0"}}`,
+			want:    true,
+		},
+		{
+			name:    "package.json with only scripts",
+			content: `{"name": "app", "scripts": {"test": "jest"}}`,
+			want:    true,
+		},
+		{
+			name:    "package.json with only devDependencies",
+			content: `{"name": "app", "devDependencies": {"jest": "^29.0.0"}}`,
+			want:    true,
+		},
+		{
+			name:    "lockfileVersion 1",
+			content: `{"lockfileVersion": 1, "dependencies": {"react": {"version": "18.2.0"}}}`,
+			want:    false,
+		},
+		{
+			name:    "lockfileVersion 3",
+			content: `{"lockfileVersion": 3, "packages": {"node_modules/react": {"version": "18.2.0"}}}`,
+			want:    false,
+		},
+		{
+			name:    "empty object",
+			content: `{}`,
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsManifest([]byte(tt.content)); got != tt.want {
+				t.Errorf("IsManifest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	packageLock, err := ParseManifest([]byte(`{
+		"name": "app",
+		"dependencies": {"react": "^18.2.0"},
+		"optionalDependencies": {"fsevents": "^2.3.0"},
+		"devDependencies": {"jest": "^29.0.0"}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseManifest() returned error: %v", err)
+	}
+
+	root := packageLock.Packages[ManifestPath]
+	if root.Dependencies["react"] != "^18.2.0" {
+		t.Errorf("root dependencies = %v, want react", root.Dependencies)
+	}
+	if root.OptionalDependencies["fsevents"] != "^2.3.0" {
+		t.Errorf("root optionalDependencies = %v, want fsevents", root.OptionalDependencies)
+	}
+	if root.DevDependencies["jest"] != "^29.0.0" {
+		t.Errorf("root devDependencies = %v, want jest", root.DevDependencies)
+	}
+}
+
+func TestIsEmpty(t *testing.T) {
+	if !IsEmpty(&types.PackageLock{Packages: map[string]types.Package{"": {}}}) {
+		t.Error("Expected lockfile with only a root entry to be empty")
+	}
+	if IsEmpty(&types.PackageLock{Dependencies: map[string]types.Dependency{"react": {}}}) {
+		t.Error("Expected lockfile with dependencies not to be empty")
+	}
+}
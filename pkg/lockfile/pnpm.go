@@ -0,0 +1,347 @@
+package lockfile
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"scnpm/pkg/types"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pnpmStoreDir is where pnpm places the real package directories inside node_modules
+const pnpmStoreDir = "node_modules/.pnpm"
+
+// pnpmLock is the subset of pnpm-lock.yaml (lockfile versions 5, 6 and 9) that scnpm understands
+type pnpmLock struct {
+	LockfileVersion string                  `yaml:"lockfileVersion"`
+	Importers       map[string]pnpmImporter `yaml:"importers"`
+	Packages        map[string]pnpmPackage  `yaml:"packages"`
+	Snapshots       map[string]pnpmPackage  `yaml:"snapshots"`
+
+	// Single-project lockfiles (no importers section) keep these at the top level
+	Dependencies         map[string]yaml.Node `yaml:"dependencies"`
+	DevDependencies      map[string]yaml.Node `yaml:"devDependencies"`
+	OptionalDependencies map[string]yaml.Node `yaml:"optionalDependencies"`
+}
+
+type pnpmImporter struct {
+	Dependencies         map[string]yaml.Node `yaml:"dependencies"`
+	DevDependencies      map[string]yaml.Node `yaml:"devDependencies"`
+	OptionalDependencies map[string]yaml.Node `yaml:"optionalDependencies"`
+}
+
+type pnpmPackage struct {
+	Resolution struct {
+		Integrity string `yaml:"integrity"`
+		Tarball   string `yaml:"tarball"`
+	} `yaml:"resolution"`
+	Version              string            `yaml:"version"`
+	Dev                  bool              `yaml:"dev"`
+	Dependencies         map[string]string `yaml:"dependencies"`
+	OptionalDependencies map[string]string `yaml:"optionalDependencies"`
+	PeerDependencies     map[string]string `yaml:"peerDependencies"`
+	Engines              any               `yaml:"engines"`
+	License              string            `yaml:"license"`
+	RequiresBuild        bool              `yaml:"requiresBuild"`
+}
+
+// IsPnpm reports whether a lockfile is a pnpm-lock.yaml, judged by file name or content
+func IsPnpm(path string, data []byte) bool {
+	if base := filepath.Base(path); base == "pnpm-lock.yaml" || base == "pnpm-lock.yml" {
+		return true
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("lockfileVersion:"))
+}
+
+// ParsePnpm converts a pnpm-lock.yaml into the PackageLock shape used by the scanner.
+// Every package is keyed by its on-disk location under node_modules/.pnpm, importers
+// (workspace projects) become entries whose dependencies point at the resolved versions,
+// Resolutions records the entry each dependency resolves to, and Importers records which
+// projects pull in each package, directly or transitively.
+func ParsePnpm(data []byte) (*types.PackageLock, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var lock pnpmLock
+	if err := doc.Decode(&lock); err != nil {
+		return nil, err
+	}
+
+	legacy := strings.HasPrefix(lock.LockfileVersion, "5")
+	if lock.Importers == nil {
+		lock.Importers = map[string]pnpmImporter{
+			".": {
+				Dependencies:         lock.Dependencies,
+				DevDependencies:      lock.DevDependencies,
+				OptionalDependencies: lock.OptionalDependencies,
+			},
+		}
+	}
+
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages:        make(map[string]types.Package),
+		Importers:       make(map[string][]string),
+		Resolutions:     make(map[string]map[string]string),
+	}
+
+	// lockfile v9 splits metadata (packages) from the dependency graph (snapshots)
+	graph := make(map[string]map[string]string)
+	pathsByID := make(map[string]string)
+	for key, pkg := range lock.Packages {
+		name, version, ok := parsePnpmKey(key, legacy)
+		if !ok {
+			continue
+		}
+		id := name + "@" + version
+		path := pnpmPath(name, version)
+		pathsByID[id] = path
+
+		deps := mergeDeps(pkg.Dependencies, pkg.OptionalDependencies)
+		graph[id] = deps
+		packageLock.Packages[path] = types.Package{
+			Version:          version,
+			Resolved:         pkg.Resolution.Tarball,
+			Integrity:        pkg.Resolution.Integrity,
+			Dev:              pkg.Dev,
+			Dependencies:     normalizeDeps(deps, legacy),
+			PeerDependencies: pkg.PeerDependencies,
+			Engines:          pkg.Engines,
+			License:          pkg.License,
+			HasInstallScript: pkg.RequiresBuild,
+		}
+	}
+	for key, snapshot := range lock.Snapshots {
+		name, version, ok := parsePnpmKey(key, legacy)
+		if !ok {
+			continue
+		}
+		id := name + "@" + version
+		deps := mergeDeps(snapshot.Dependencies, snapshot.OptionalDependencies)
+		graph[id] = mergeDeps(graph[id], deps)
+
+		path := pnpmPath(name, version)
+		if pkg, exists := packageLock.Packages[path]; exists {
+			pkg.Dependencies = normalizeDeps(graph[id], legacy)
+			packageLock.Packages[path] = pkg
+		}
+	}
+
+	for dir, importer := range lock.Importers {
+		direct := make(map[string]string)
+		devDirect := make(map[string]string)
+		for name, node := range importer.Dependencies {
+			direct[name] = importerVersion(node)
+		}
+		for name, node := range importer.OptionalDependencies {
+			direct[name] = importerVersion(node)
+		}
+		for name, node := range importer.DevDependencies {
+			devDirect[name] = importerVersion(node)
+		}
+
+		importerPath := dir
+		if dir == "." {
+			importerPath = ""
+		}
+		packageLock.Packages[importerPath] = types.Package{
+			Dependencies:    normalizeDeps(direct, legacy),
+			DevDependencies: normalizeDeps(devDirect, legacy),
+		}
+		for name, version := range mergeDeps(direct, devDirect) {
+			target, ok := pathsByID[dependencyID(name, version, legacy)]
+			if linked, isLink := strings.CutPrefix(version, "link:"); isLink {
+				// Another workspace project of the lockfile
+				target = path.Join(dir, linked)
+				_, ok = lock.Importers[target]
+				if target == "." {
+					target = ""
+				}
+			}
+			if ok {
+				addResolution(packageLock, importerPath, name, target)
+			}
+		}
+
+		// Walk everything reachable from this importer so findings can be attributed to it
+		visited := make(map[string]bool)
+		queue := []string{}
+		for name, version := range mergeDeps(direct, devDirect) {
+			queue = append(queue, dependencyID(name, version, legacy))
+		}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+			if path, ok := pathsByID[id]; ok {
+				packageLock.Importers[path] = append(packageLock.Importers[path], dir)
+			}
+			for name, version := range graph[id] {
+				queue = append(queue, dependencyID(name, version, legacy))
+			}
+		}
+	}
+	for path := range packageLock.Importers {
+		sort.Strings(packageLock.Importers[path])
+	}
+	for id, deps := range graph {
+		from, ok := pathsByID[id]
+		if !ok {
+			continue
+		}
+		for name, version := range deps {
+			if target, ok := pathsByID[dependencyID(name, version, legacy)]; ok {
+				addResolution(packageLock, from, name, target)
+			}
+		}
+	}
+
+	packageLock.Lines = pnpmLineIndex(&doc, legacy)
+	return packageLock, nil
+}
+
+// parsePnpmKey splits a packages/snapshots key into name and version. Keys look like
+// "/name/1.0.0_peer@2" (v5), "/@scope/name@1.0.0(peer@2)" (v6) or "name@1.0.0(peer@2)" (v9).
+func parsePnpmKey(key string, legacy bool) (string, string, bool) {
+	key = strings.TrimPrefix(key, "/")
+	if i := strings.Index(key, "("); i > 0 {
+		key = key[:i]
+	}
+
+	if legacy {
+		i := strings.LastIndex(key, "/")
+		if i <= 0 {
+			return "", "", false
+		}
+		return key[:i], stripPeerSuffix(key[i+1:], legacy), true
+	}
+
+	i := strings.LastIndex(key, "@")
+	if i <= 0 {
+		return "", "", false
+	}
+	return key[:i], key[i+1:], true
+}
+
+// dependencyID resolves a dependency version reference to the "name@version" graph key.
+// Aliased dependencies reference the real package key instead of a bare version.
+func dependencyID(name, version string, legacy bool) string {
+	plain := stripPeerSuffix(version, legacy)
+	if strings.HasPrefix(plain, "/") || (!legacy && strings.Contains(strings.TrimPrefix(plain, "@"), "@")) {
+		if realName, realVersion, ok := parsePnpmKey(plain, legacy); ok {
+			return realName + "@" + realVersion
+		}
+	}
+	return name + "@" + plain
+}
+
+// stripPeerSuffix drops the peer dependencies pnpm appends to a version or package path:
+// "(peer@2)" from v6 on, "_peer@2" before. The v5 suffix is only looked for after the last "/",
+// since package names such as string_decoder contain underscores too.
+func stripPeerSuffix(version string, legacy bool) string {
+	if i := strings.Index(version, "("); i > 0 {
+		version = version[:i]
+	}
+	if legacy {
+		start := strings.LastIndex(version, "/") + 1
+		if i := strings.Index(version[start:], "_"); i > 0 {
+			version = version[:start+i]
+		}
+	}
+	return version
+}
+
+// normalizeDeps strips pnpm's peer-dependency suffixes so dependency versions read like npm's
+func normalizeDeps(deps map[string]string, legacy bool) map[string]string {
+	if len(deps) == 0 {
+		return nil
+	}
+	normalized := make(map[string]string, len(deps))
+	for name, version := range deps {
+		normalized[name] = stripPeerSuffix(version, legacy)
+	}
+	return normalized
+}
+
+// addResolution records that the dependency name of the entry at from resolves to the entry at to
+func addResolution(packageLock *types.PackageLock, from, name, to string) {
+	if packageLock.Resolutions[from] == nil {
+		packageLock.Resolutions[from] = make(map[string]string)
+	}
+	packageLock.Resolutions[from][name] = to
+}
+
+func mergeDeps(maps ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, m := range maps {
+		for name, version := range m {
+			merged[name] = version
+		}
+	}
+	return merged
+}
+
+// importerVersion reads an importer dependency, which is a plain version in lockfile v5
+// and a {specifier, version} mapping from v6 on
+func importerVersion(node yaml.Node) string {
+	if node.Kind == yaml.ScalarNode {
+		return node.Value
+	}
+	var dep struct {
+		Version string `yaml:"version"`
+	}
+	if err := node.Decode(&dep); err != nil {
+		return ""
+	}
+	return dep.Version
+}
+
+// pnpmPath returns the on-disk location pnpm uses for a package inside its virtual store
+func pnpmPath(name, version string) string {
+	storeName := strings.ReplaceAll(name, "/", "+") + "@" + version
+	return fmt.Sprintf("%s/%s/node_modules/%s", pnpmStoreDir, storeName, name)
+}
+
+// pnpmLineIndex records the line of every packages key and importer
+func pnpmLineIndex(doc *yaml.Node, legacy bool) map[string]int {
+	lines := make(map[string]int)
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return lines
+	}
+
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if value.Kind != yaml.MappingNode {
+			continue
+		}
+		switch key.Value {
+		case "packages":
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				entry := value.Content[j]
+				if name, version, ok := parsePnpmKey(entry.Value, legacy); ok {
+					lines[pnpmPath(name, version)] = entry.Line
+				}
+			}
+		case "importers":
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				entry := value.Content[j]
+				if entry.Value == "." {
+					lines[""] = entry.Line
+				} else {
+					lines[entry.Value] = entry.Line
+				}
+			}
+		}
+	}
+	return lines
+}
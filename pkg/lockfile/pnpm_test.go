@@ -0,0 +1,289 @@
+package lockfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+const pnpmV9 = `lockfileVersion: '9.0'
+
+importers:
+
+  .:
+    devDependencies:
+      typescript:
+        specifier: ^5.0.0
+        version: 5.4.5
+
+  packages/web:
+    dependencies:
+      '@ctrl/tinycolor':
+        specifier: ^4.1.0
+        version: 4.1.1
+      react-dom:
+        specifier: ^18.2.0
+        version: 18.2.0(react@18.2.0)
+
+packages:
+
+  '@ctrl/tinycolor@4.1.1':
+    resolution: {integrity: sha512-tiny}
+
+  react-dom@18.2.0:
+    resolution: {integrity: sha512-dom}
+    peerDependencies:
+      react: ^18.2.0
+
+  react@18.2.0:
+    resolution: {integrity: sha512-react}
+
+  typescript@5.4.5:
+    resolution: {integrity: sha512-ts}
+
+snapshots:
+
+  '@ctrl/tinycolor@4.1.1': {}
+
+  react-dom@18.2.0(react@18.2.0):
+    dependencies:
+      react: 18.2.0
+
+  react@18.2.0: {}
+
+  typescript@5.4.5: {}
+`
+
+const pnpmV6 = `lockfileVersion: '6.0'
+
+dependencies:
+  debug:
+    specifier: ^4.3.4
+    version: 4.3.4
+
+packages:
+
+  /debug@4.3.4:
+    resolution: {integrity: sha512-debug}
+    dependencies:
+      ms: 2.1.2
+    dev: false
+
+  /ms@2.1.2:
+    resolution: {integrity: sha512-ms}
+    dev: false
+`
+
+const pnpmV5 = `lockfileVersion: 5.4
+
+specifiers:
+  debug: ^4.3.4
+
+dependencies:
+  debug: 4.3.4_supports-color@8.1.1
+
+packages:
+
+  /debug/4.3.4_supports-color@8.1.1:
+    resolution: {integrity: sha512-debug}
+    dependencies:
+      ms: 2.1.2
+    dev: false
+
+  /ms/2.1.2:
+    resolution: {integrity: sha512-ms}
+    dev: false
+`
+
+// pnpmV5Underscores has package names with underscores, which v5 also uses for peer suffixes
+const pnpmV5Underscores = `lockfileVersion: 5.4
+
+specifiers:
+  string_decoder: ^1.3.0
+  my_pkg: ^2.0.0
+  decoder-alias: npm:string_decoder@^1.3.0
+
+dependencies:
+  string_decoder: 1.3.0
+  my_pkg: 2.0.0_react@18.2.0
+  decoder-alias: /string_decoder/1.3.0
+
+packages:
+
+  /string_decoder/1.3.0:
+    resolution: {integrity: sha512-decoder}
+    dependencies:
+      safe_buffer: 5.2.1
+    dev: false
+
+  /safe_buffer/5.2.1:
+    resolution: {integrity: sha512-buffer}
+    dev: false
+
+  /my_pkg/2.0.0_react@18.2.0:
+    resolution: {integrity: sha512-mypkg}
+    peerDependencies:
+      react: ^18.0.0
+    dependencies:
+      react: 18.2.0
+    dev: false
+
+  /react/18.2.0:
+    resolution: {integrity: sha512-react}
+    dev: false
+`
+
+func TestParsePnpmV9(t *testing.T) {
+	packageLock, err := ParsePnpm([]byte(pnpmV9))
+	if err != nil {
+		t.Fatalf("ParsePnpm() returned error: %v", err)
+	}
+
+	tinycolor, ok := packageLock.Packages["node_modules/.pnpm/@ctrl+tinycolor@4.1.1/node_modules/@ctrl/tinycolor"]
+	if !ok {
+		t.Fatalf("Expected @ctrl/tinycolor in packages, got %v", packageLock.Packages)
+	}
+	if tinycolor.Version != "4.1.1" || tinycolor.Integrity != "sha512-tiny" {
+		t.Errorf("tinycolor = %+v, want version 4.1.1 with integrity", tinycolor)
+	}
+
+	reactDom := packageLock.Packages["node_modules/.pnpm/react-dom@18.2.0/node_modules/react-dom"]
+	if !reflect.DeepEqual(reactDom.Dependencies, map[string]string{"react": "18.2.0"}) {
+		t.Errorf("react-dom dependencies = %v, want react 18.2.0 from snapshots", reactDom.Dependencies)
+	}
+
+	web := packageLock.Packages["packages/web"]
+	if web.Dependencies["react-dom"] != "18.2.0" {
+		t.Errorf("importer dependency react-dom = %q, want peer suffix stripped", web.Dependencies["react-dom"])
+	}
+
+	importers := map[string][]string{
+		"node_modules/.pnpm/react@18.2.0/node_modules/react":                    {"packages/web"},
+		"node_modules/.pnpm/typescript@5.4.5/node_modules/typescript":           {"."},
+		"node_modules/.pnpm/@ctrl+tinycolor@4.1.1/node_modules/@ctrl/tinycolor": {"packages/web"},
+	}
+	for path, want := range importers {
+		if got := packageLock.Importers[path]; !reflect.DeepEqual(got, want) {
+			t.Errorf("Importers[%q] = %v, want %v", path, got, want)
+		}
+	}
+
+	resolutions := map[string]map[string]string{
+		"": {"typescript": "node_modules/.pnpm/typescript@5.4.5/node_modules/typescript"},
+		"packages/web": {
+			"@ctrl/tinycolor": "node_modules/.pnpm/@ctrl+tinycolor@4.1.1/node_modules/@ctrl/tinycolor",
+			"react-dom":       "node_modules/.pnpm/react-dom@18.2.0/node_modules/react-dom",
+		},
+		"node_modules/.pnpm/react-dom@18.2.0/node_modules/react-dom": {"react": "node_modules/.pnpm/react@18.2.0/node_modules/react"},
+	}
+	if !reflect.DeepEqual(packageLock.Resolutions, resolutions) {
+		t.Errorf("Resolutions = %v, want %v", packageLock.Resolutions, resolutions)
+	}
+
+	if line := packageLock.Lines["node_modules/.pnpm/react@18.2.0/node_modules/react"]; line != 30 {
+		t.Errorf("Line of react = %d, want 30", line)
+	}
+	if line := packageLock.Lines["packages/web"]; line != 11 {
+		t.Errorf("Line of packages/web importer = %d, want 11", line)
+	}
+}
+
+func TestParsePnpmLegacyVersions(t *testing.T) {
+	for name, content := range map[string]string{"v6": pnpmV6, "v5": pnpmV5} {
+		t.Run(name, func(t *testing.T) {
+			packageLock, err := ParsePnpm([]byte(content))
+			if err != nil {
+				t.Fatalf("ParsePnpm() returned error: %v", err)
+			}
+
+			debug, ok := packageLock.Packages["node_modules/.pnpm/debug@4.3.4/node_modules/debug"]
+			if !ok || debug.Version != "4.3.4" {
+				t.Fatalf("Expected debug@4.3.4 in packages, got %v", packageLock.Packages)
+			}
+
+			msPath := "node_modules/.pnpm/ms@2.1.2/node_modules/ms"
+			if got := packageLock.Importers[msPath]; !reflect.DeepEqual(got, []string{"."}) {
+				t.Errorf("Importers[%q] = %v, want transitive attribution to root", msPath, got)
+			}
+		})
+	}
+}
+
+func TestParsePnpmV5Underscores(t *testing.T) {
+	packageLock, err := ParsePnpm([]byte(pnpmV5Underscores))
+	if err != nil {
+		t.Fatalf("ParsePnpm() returned error: %v", err)
+	}
+
+	for path, version := range map[string]string{
+		"node_modules/.pnpm/string_decoder@1.3.0/node_modules/string_decoder": "1.3.0",
+		"node_modules/.pnpm/safe_buffer@5.2.1/node_modules/safe_buffer":       "5.2.1",
+		"node_modules/.pnpm/my_pkg@2.0.0/node_modules/my_pkg":                 "2.0.0",
+	} {
+		if pkg, ok := packageLock.Packages[path]; !ok || pkg.Version != version {
+			t.Errorf("Packages[%q] = %+v, %v; want version %s", path, pkg, ok, version)
+		}
+	}
+
+	decoder := packageLock.Packages["node_modules/.pnpm/string_decoder@1.3.0/node_modules/string_decoder"]
+	if !reflect.DeepEqual(decoder.Dependencies, map[string]string{"safe_buffer": "5.2.1"}) {
+		t.Errorf("string_decoder dependencies = %v, want safe_buffer 5.2.1", decoder.Dependencies)
+	}
+	root := packageLock.Packages[""]
+	if root.Dependencies["my_pkg"] != "2.0.0" || root.Dependencies["string_decoder"] != "1.3.0" {
+		t.Errorf("root dependencies = %v, want the peer suffix stripped and names kept whole", root.Dependencies)
+	}
+	bufferPath := "node_modules/.pnpm/safe_buffer@5.2.1/node_modules/safe_buffer"
+	if got := packageLock.Importers[bufferPath]; !reflect.DeepEqual(got, []string{"."}) {
+		t.Errorf("Importers[%q] = %v, want transitive attribution to root", bufferPath, got)
+	}
+	if got := packageLock.Resolutions[""]["decoder-alias"]; got != "node_modules/.pnpm/string_decoder@1.3.0/node_modules/string_decoder" {
+		t.Errorf("decoder-alias resolves to %q, want the aliased string_decoder", got)
+	}
+}
+
+func TestParsePnpmWorkspaceLinks(t *testing.T) {
+	packageLock, err := ParsePnpm([]byte(`lockfileVersion: '9.0'
+
+importers:
+
+  .:
+    dependencies:
+      web:
+        specifier: workspace:*
+        version: link:packages/web
+
+  packages/web:
+    dependencies:
+      shared:
+        specifier: workspace:*
+        version: link:../shared
+      missing:
+        specifier: workspace:*
+        version: link:../missing
+
+  packages/shared: {}
+`))
+	if err != nil {
+		t.Fatalf("ParsePnpm() returned error: %v", err)
+	}
+
+	want := map[string]map[string]string{
+		"":             {"web": "packages/web"},
+		"packages/web": {"shared": "packages/shared"},
+	}
+	if !reflect.DeepEqual(packageLock.Resolutions, want) {
+		t.Errorf("Resolutions = %v, want links resolved to the importers they point to", packageLock.Resolutions)
+	}
+}
+
+func TestIsPnpm(t *testing.T) {
+	if !IsPnpm("/repo/pnpm-lock.yaml", nil) {
+		t.Error("Expected pnpm-lock.yaml to be detected by name")
+	}
+	if !IsPnpm("-", []byte(pnpmV9)) {
+		t.Error("Expected pnpm content on stdin to be detected")
+	}
+	if IsPnpm("package-lock.json", []byte(`{"lockfileVersion": 3}`)) {
+		t.Error("Expected package-lock.json not to be detected as pnpm")
+	}
+}
@@ -0,0 +1,261 @@
+package lockfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"scnpm/pkg/types"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IsYarn reports whether a lockfile is a yarn.lock (classic v1 or berry), judged by file name or content
+func IsYarn(path string, data []byte) bool {
+	if filepath.Base(path) == "yarn.lock" {
+		return true
+	}
+	return bytes.Contains(data, []byte("# yarn lockfile v1")) || bytes.Contains(data, []byte("\n__metadata:"))
+}
+
+// ParseYarn converts a yarn.lock into the PackageLock shape used by the scanner.
+// yarn.lock doesn't record install locations, so entries are keyed by "name@version"
+// and carry their package name explicitly, and Resolutions records the entry each
+// dependency resolves to.
+func ParseYarn(data []byte) (*types.PackageLock, error) {
+	if bytes.Contains(data, []byte("__metadata:")) {
+		return parseYarnBerry(data)
+	}
+	return parseYarnClassic(data)
+}
+
+func newYarnLock() *types.PackageLock {
+	return &types.PackageLock{
+		LockfileVersion: 3,
+		Packages:        make(map[string]types.Package),
+		Lines:           make(map[string]int),
+	}
+}
+
+// yarnName extracts the package name from a specifier like "@babel/core@^7.0.0" or "lodash@npm:^4.17.21"
+func yarnName(spec string) string {
+	if i := strings.Index(spec[1:], "@"); i >= 0 {
+		return spec[:i+1]
+	}
+	return spec
+}
+
+// parseYarnClassic parses the custom yarn v1 format:
+//
+//	"@babel/code-frame@^7.0.0", "@babel/code-frame@^7.10.4":
+//	  version "7.12.13"
+//	  integrity sha512-...
+//	  dependencies:
+//	    "@babel/highlight" "^7.12.13"
+func parseYarnClassic(data []byte) (*types.PackageLock, error) {
+	packageLock := newYarnLock()
+
+	var key string
+	var pkg types.Package
+	var line int
+	var section string
+	var specs []string
+	resolved := make(map[string]string) // Entry key of every "name@range" spec
+
+	flush := func() {
+		if key != "" {
+			packageLock.Packages[key] = pkg
+			for _, spec := range specs {
+				resolved[spec] = key
+			}
+		}
+		key, pkg, section, specs = "", types.Package{}, "", nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		raw := scanner.Text()
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+
+		switch {
+		case indent == 0:
+			flush()
+			for _, field := range strings.Split(strings.TrimSuffix(trimmed, ":"), ",") {
+				spec, err := unquote(strings.TrimSpace(field))
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+				}
+				specs = append(specs, spec)
+			}
+			pkg.Name = yarnName(specs[0])
+			line = lineNumber
+		case indent == 2:
+			section = ""
+			field, value, _ := strings.Cut(trimmed, " ")
+			value, err := unquote(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			switch strings.TrimSuffix(field, ":") {
+			case "version":
+				pkg.Version = value
+				key = pkg.Name + "@" + value
+				if _, seen := packageLock.Lines[key]; !seen {
+					packageLock.Lines[key] = line
+				}
+			case "resolved":
+				pkg.Resolved = value
+			case "integrity":
+				pkg.Integrity = value
+			case "dependencies", "optionalDependencies", "peerDependencies":
+				section = strings.TrimSuffix(field, ":")
+			}
+		case indent >= 4 && section != "":
+			nameField, rangeField, _ := strings.Cut(trimmed, " ")
+			name, err := unquote(nameField)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			spec, err := unquote(strings.TrimSpace(rangeField))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			switch section {
+			case "peerDependencies":
+				if pkg.PeerDependencies == nil {
+					pkg.PeerDependencies = make(map[string]string)
+				}
+				pkg.PeerDependencies[name] = spec
+			case "optionalDependencies":
+				if pkg.OptionalDependencies == nil {
+					pkg.OptionalDependencies = make(map[string]string)
+				}
+				pkg.OptionalDependencies[name] = spec
+			default:
+				if pkg.Dependencies == nil {
+					pkg.Dependencies = make(map[string]string)
+				}
+				pkg.Dependencies[name] = spec
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	resolveYarnDependencies(packageLock, resolved)
+	return packageLock, nil
+}
+
+func unquote(s string) (string, error) {
+	if strings.HasPrefix(s, `"`) {
+		return strconv.Unquote(s)
+	}
+	return s, nil
+}
+
+// yarnBerryEntry is a single resolution in a yarn 2+ lockfile
+type yarnBerryEntry struct {
+	Version              string            `yaml:"version"`
+	Resolution           string            `yaml:"resolution"`
+	Dependencies         map[string]string `yaml:"dependencies"`
+	OptionalDependencies map[string]string `yaml:"optionalDependencies"`
+	PeerDependencies     map[string]string `yaml:"peerDependencies"`
+	LinkType             string            `yaml:"linkType"`
+	Checksum             string            `yaml:"checksum"`
+}
+
+func parseYarnBerry(data []byte) (*types.PackageLock, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	packageLock := newYarnLock()
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return packageLock, nil
+	}
+
+	resolved := make(map[string]string) // Entry key of every "name@range" spec
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		keyNode, valueNode := root.Content[i], root.Content[i+1]
+		if keyNode.Value == "__metadata" {
+			continue
+		}
+
+		var entry yarnBerryEntry
+		if err := valueNode.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("line %d: %v", keyNode.Line, err)
+		}
+
+		specs := strings.Split(keyNode.Value, ",")
+		name := yarnName(strings.TrimSpace(specs[0]))
+		if entry.Resolution != "" {
+			name = yarnName(entry.Resolution)
+		}
+		key := name + "@" + entry.Version
+
+		packageLock.Packages[key] = types.Package{
+			Name:                 name,
+			Version:              entry.Version,
+			Resolved:             entry.Resolution,
+			Integrity:            entry.Checksum, // berry's own tarball hash, not an SRI string
+			Link:                 entry.LinkType == "soft",
+			Dependencies:         stripProtocol(entry.Dependencies),
+			PeerDependencies:     stripProtocol(entry.PeerDependencies),
+			OptionalDependencies: stripProtocol(entry.OptionalDependencies),
+		}
+		if _, seen := packageLock.Lines[key]; !seen {
+			packageLock.Lines[key] = keyNode.Line
+		}
+		for _, spec := range specs {
+			resolved[strings.TrimSpace(spec)] = key
+		}
+	}
+
+	resolveYarnDependencies(packageLock, resolved)
+	return packageLock, nil
+}
+
+// resolveYarnDependencies records the entry every dependency resolves to, found by the
+// "name@range" specs entries are listed under. berry lists them as "name@npm:range", the
+// prefix ParseYarn strips from dependency ranges, so that form is tried as well.
+func resolveYarnDependencies(packageLock *types.PackageLock, resolved map[string]string) {
+	packageLock.Resolutions = make(map[string]map[string]string)
+	for key, pkg := range packageLock.Packages {
+		for _, deps := range []map[string]string{pkg.Dependencies, pkg.OptionalDependencies, pkg.PeerDependencies} {
+			for name, spec := range deps {
+				target, ok := resolved[name+"@"+spec]
+				if !ok {
+					target, ok = resolved[name+"@npm:"+spec]
+				}
+				if ok {
+					addResolution(packageLock, key, name, target)
+				}
+			}
+		}
+	}
+}
+
+// stripProtocol removes berry's "npm:" range prefix so ranges read like npm's
+func stripProtocol(deps map[string]string) map[string]string {
+	if len(deps) == 0 {
+		return nil
+	}
+	stripped := make(map[string]string, len(deps))
+	for name, spec := range deps {
+		stripped[name] = strings.TrimPrefix(spec, "npm:")
+	}
+	return stripped
+}
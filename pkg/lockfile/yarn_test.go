@@ -0,0 +1,168 @@
+package lockfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+const yarnClassic = `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@babel/code-frame@^7.0.0", "@babel/code-frame@^7.10.4":
+  version "7.12.13"
+  resolved "https://registry.yarnpkg.com/@babel/code-frame/-/code-frame-7.12.13.tgz#dcfc826beef65e75c50e21d3837d7d95798dd658"
+  integrity sha512-HV1Cm0Q3ZrpCR93tkWOYiuYIgLxZXZFVG2VgK+MBWjUqZTundupbfx2aXarXuw5Ko5aMcjtJgbSs4vUGBS5v6g==
+  dependencies:
+    "@babel/highlight" "^7.12.13"
+
+lodash@^4.17.20:
+  version "4.17.21"
+  resolved "https://registry.yarnpkg.com/lodash/-/lodash-4.17.21.tgz"
+  integrity sha512-v2kDEe57lecTulaDIuNTPy3Ry4gLGJ6Z1O3vE1krgXZNrsQ+LFTGHVxVjcXPs17LhbZVGedAJv8XZ1tvj5FvSg==
+`
+
+const yarnBerry = `# This file is generated by running "yarn install" inside your project.
+
+__metadata:
+  version: 6
+  cacheKey: 8
+
+"debug@npm:^4.3.4":
+  version: 4.3.4
+  resolution: "debug@npm:4.3.4"
+  dependencies:
+    ms: "npm:2.1.2"
+  checksum: 3dbad3f94ea64f34431a9cbf0bafb61853eda57bff2880036153438f50fb5a84f27683ba0d8e5426bf41a8c6ff03879488120cf5b3a761e77953169c0600a708
+  languageName: node
+  linkType: hard
+
+"ms@npm:2.1.2":
+  version: 2.1.2
+  resolution: "ms@npm:2.1.2"
+  languageName: node
+  linkType: hard
+`
+
+func TestParseYarnClassic(t *testing.T) {
+	packageLock, err := ParseYarn([]byte(yarnClassic))
+	if err != nil {
+		t.Fatalf("ParseYarn() returned error: %v", err)
+	}
+
+	codeFrame, ok := packageLock.Packages["@babel/code-frame@7.12.13"]
+	if !ok {
+		t.Fatalf("Expected @babel/code-frame@7.12.13 in packages, got %v", packageLock.Packages)
+	}
+	if codeFrame.Name != "@babel/code-frame" || codeFrame.Version != "7.12.13" {
+		t.Errorf("code-frame = %+v, want name and version populated", codeFrame)
+	}
+	if !reflect.DeepEqual(codeFrame.Dependencies, map[string]string{"@babel/highlight": "^7.12.13"}) {
+		t.Errorf("code-frame dependencies = %v", codeFrame.Dependencies)
+	}
+
+	lodash := packageLock.Packages["lodash@4.17.21"]
+	if lodash.Integrity == "" || lodash.Resolved == "" {
+		t.Errorf("lodash = %+v, want resolved and integrity", lodash)
+	}
+
+	if line := packageLock.Lines["@babel/code-frame@7.12.13"]; line != 5 {
+		t.Errorf("Line of @babel/code-frame = %d, want 5", line)
+	}
+	if line := packageLock.Lines["lodash@4.17.21"]; line != 12 {
+		t.Errorf("Line of lodash = %d, want 12", line)
+	}
+}
+
+func TestParseYarnBerry(t *testing.T) {
+	packageLock, err := ParseYarn([]byte(yarnBerry))
+	if err != nil {
+		t.Fatalf("ParseYarn() returned error: %v", err)
+	}
+
+	debug, ok := packageLock.Packages["debug@4.3.4"]
+	if !ok {
+		t.Fatalf("Expected debug@4.3.4 in packages, got %v", packageLock.Packages)
+	}
+	if !reflect.DeepEqual(debug.Dependencies, map[string]string{"ms": "2.1.2"}) {
+		t.Errorf("debug dependencies = %v, want npm: protocol stripped", debug.Dependencies)
+	}
+	if debug.Integrity == "" || debug.Link {
+		t.Errorf("debug = %+v, want the checksum as integrity and a hard link", debug)
+	}
+	if line := packageLock.Lines["ms@2.1.2"]; line != 16 {
+		t.Errorf("Line of ms = %d, want 16", line)
+	}
+}
+
+// yarnTwoVersions installs two versions of debug, which express and the project ask for by range
+const yarnTwoVersions = `# yarn lockfile v1
+
+
+debug@2.6.9:
+  version "2.6.9"
+  dependencies:
+    ms "2.0.0"
+
+debug@^4.3.4:
+  version "4.3.4"
+  dependencies:
+    ms "2.1.2"
+
+express@^4.18.2:
+  version "4.18.2"
+  dependencies:
+    debug "2.6.9"
+
+ms@2.0.0:
+  version "2.0.0"
+
+ms@2.1.2:
+  version "2.1.2"
+`
+
+func TestParseYarnResolutions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]map[string]string
+	}{
+		{
+			name:    "classic",
+			content: yarnTwoVersions,
+			want: map[string]map[string]string{
+				"debug@2.6.9":    {"ms": "ms@2.0.0"},
+				"debug@4.3.4":    {"ms": "ms@2.1.2"},
+				"express@4.18.2": {"debug": "debug@2.6.9"},
+			},
+		},
+		{
+			name:    "berry",
+			content: yarnBerry,
+			want:    map[string]map[string]string{"debug@4.3.4": {"ms": "ms@2.1.2"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packageLock, err := ParseYarn([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("ParseYarn() returned error: %v", err)
+			}
+			if !reflect.DeepEqual(packageLock.Resolutions, tt.want) {
+				t.Errorf("Resolutions = %v, want %v", packageLock.Resolutions, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsYarn(t *testing.T) {
+	if !IsYarn("/repo/yarn.lock", nil) {
+		t.Error("Expected yarn.lock to be detected by name")
+	}
+	if !IsYarn("-", []byte(yarnClassic)) || !IsYarn("-", []byte(yarnBerry)) {
+		t.Error("Expected yarn content on stdin to be detected")
+	}
+	if IsYarn("package-lock.json", []byte(`{}`)) {
+		t.Error("Expected package-lock.json not to be detected as yarn")
+	}
+}
@@ -0,0 +1,103 @@
+// Package ossindex converts Sonatype OSS Index component reports into scnpm package queries.
+package ossindex
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"scnpm/pkg/input"
+	"scnpm/pkg/semver"
+	"scnpm/pkg/types"
+)
+
+// npmPrefix starts the package URL of every npm component
+const npmPrefix = "pkg:npm/"
+
+// component is one entry of a component report (POST /api/v3/component-report)
+type component struct {
+	Coordinates     string            `json:"coordinates"`
+	Vulnerabilities []json.RawMessage `json:"vulnerabilities"`
+}
+
+// vulnerability is the part of an OSS Index vulnerability scnpm models
+type vulnerability struct {
+	ID            string   `json:"id"`
+	Title         string   `json:"title"`
+	CVSSScore     float64  `json:"cvssScore"`
+	Reference     string   `json:"reference"`
+	VersionRanges []string `json:"versionRanges"`
+}
+
+// Parse converts a component report into one query per npm component and vulnerability.
+// Components of other ecosystems are skipped and counted.
+func Parse(data []byte) ([]types.PackageQuery, int, error) {
+	var components []component
+	if err := json.Unmarshal(data, &components); err != nil {
+		return nil, 0, err
+	}
+
+	var queries []types.PackageQuery
+	skipped := 0
+	for _, c := range components {
+		name, version, ok := parseCoordinates(c.Coordinates)
+		if !ok {
+			skipped++
+			continue
+		}
+
+		for _, raw := range c.Vulnerabilities {
+			query, err := convert(raw, name, version)
+			if err != nil {
+				return nil, 0, fmt.Errorf("%s: %v", c.Coordinates, err)
+			}
+			queries = append(queries, query)
+		}
+	}
+
+	return queries, skipped, nil
+}
+
+// parseCoordinates splits "pkg:npm/%40scope/name@1.0.0" into name and version
+func parseCoordinates(coordinates string) (string, string, bool) {
+	if !strings.HasPrefix(coordinates, npmPrefix) {
+		return "", "", false
+	}
+	purl := strings.ReplaceAll(strings.TrimPrefix(coordinates, npmPrefix), "%40", "@")
+	i := strings.LastIndex(purl, "@")
+	if i <= 0 {
+		return "", "", false
+	}
+	return purl[:i], purl[i+1:], true
+}
+
+// convert maps one vulnerability of a component onto a query. The vulnerable ranges are used
+// when the report includes them, otherwise the component's own version.
+func convert(raw json.RawMessage, name, version string) (types.PackageQuery, error) {
+	var v vulnerability
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return types.PackageQuery{}, err
+	}
+
+	if ranges := strings.Join(v.VersionRanges, " || "); ranges != "" {
+		if _, err := semver.ParseRange(ranges); err == nil {
+			version = ranges
+		}
+	}
+
+	query := types.PackageQuery{
+		Name:     name,
+		Version:  version,
+		Severity: types.SeverityFromCVSS(v.CVSSScore),
+		Advisory: v.Reference,
+		Note:     v.Title,
+	}
+
+	extra, err := input.UnmodeledFields(raw, "title", "reference", "versionRanges")
+	if err != nil {
+		return types.PackageQuery{}, err
+	}
+	query.Extra = extra
+
+	return query, nil
+}
@@ -0,0 +1,51 @@
+package ossindex
+
+import (
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestParse(t *testing.T) {
+	report := `[
+	  {
+	    "coordinates": "pkg:npm/%40ctrl/tinycolor@4.1.1",
+	    "reference": "https://ossindex.sonatype.org/component/pkg:npm/%40ctrl/tinycolor@4.1.1",
+	    "vulnerabilities": [
+	      {"id": "sonatype-2025-1", "displayName": "sonatype-2025-1", "title": "Embedded malicious code", "cvssScore": 9.8, "reference": "https://ossindex.sonatype.org/vulnerability/sonatype-2025-1"}
+	    ]
+	  },
+	  {
+	    "coordinates": "pkg:npm/lodash@4.17.15",
+	    "vulnerabilities": [
+	      {"id": "CVE-2020-8203", "title": "Prototype Pollution", "cvssScore": 7.4, "versionRanges": ["<4.17.16"]}
+	    ]
+	  },
+	  {"coordinates": "pkg:maven/org.example/lib@1.0.0", "vulnerabilities": [{"id": "x"}]},
+	  {"coordinates": "pkg:npm/left-pad@1.3.0", "vulnerabilities": []}
+	]`
+
+	queries, skipped, err := Parse([]byte(report))
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	if skipped != 1 {
+		t.Errorf("Parse() skipped %d components, want 1", skipped)
+	}
+	if len(queries) != 2 {
+		t.Fatalf("Parse() returned %d queries, want 2: %+v", len(queries), queries)
+	}
+
+	tinycolor := queries[0]
+	if tinycolor.Name != "@ctrl/tinycolor" || tinycolor.Version != "4.1.1" || tinycolor.Severity != types.SeverityCritical {
+		t.Errorf("tinycolor query = %+v", tinycolor)
+	}
+	if tinycolor.Extra["displayName"] != "sonatype-2025-1" {
+		t.Errorf("tinycolor extra = %v, want unmodeled fields passed through", tinycolor.Extra)
+	}
+
+	lodash := queries[1]
+	if lodash.Version != "<4.17.16" || lodash.Severity != types.SeverityHigh {
+		t.Errorf("lodash query = %+v", lodash)
+	}
+}
@@ -0,0 +1,331 @@
+package osv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"scnpm/pkg/cache"
+	"scnpm/pkg/remote"
+	"scnpm/pkg/semver"
+	"scnpm/pkg/types"
+)
+
+// DefaultAPI is the public OSV.dev API
+const DefaultAPI = "https://api.osv.dev"
+
+// Source is the query source recorded for advisories from the OSV API
+const Source = "osv"
+
+// maxBatchSize is the most queries OSV accepts in one querybatch request
+const maxBatchSize = 1000
+
+// defaultConcurrency is how many requests are in flight at once when Client.Concurrency is unset
+const defaultConcurrency = 8
+
+const (
+	cacheNamespaceQueries = "osv-queries"
+	cacheNamespaceVulns   = "osv-vulns"
+)
+
+// Client looks up packages with the OSV API: querybatch to find the advisory ids affecting
+// each package@version, then the full advisory for every id
+type Client struct {
+	Remote      *remote.Client
+	API         string       // Defaults to DefaultAPI
+	Cache       *cache.Cache // Optional; caches ids per package@version and advisories per id
+	Concurrency int
+}
+
+type batchQuery struct {
+	Package struct {
+		Name      string `json:"name"`
+		Ecosystem string `json:"ecosystem"`
+	} `json:"package"`
+	Version   string `json:"version"`
+	PageToken string `json:"page_token,omitempty"`
+}
+
+type batchResult struct {
+	Vulns []struct {
+		ID string `json:"id"`
+	} `json:"vulns"`
+	NextPageToken string `json:"next_page_token"`
+}
+
+// Advisories returns one query per advisory affecting an installed package
+func (c *Client) Advisories(ctx context.Context, installed map[string][]string) ([]types.PackageQuery, error) {
+	var specs []string
+	for name, versions := range installed {
+		for _, version := range versions {
+			specs = append(specs, name+"@"+version)
+		}
+	}
+	sort.Strings(specs)
+
+	ids := make(map[string][]string, len(specs))
+	var pending []string
+	for _, spec := range specs {
+		if cached, ok := c.cachedIDs(spec); ok {
+			ids[spec] = cached
+		} else {
+			pending = append(pending, spec)
+		}
+	}
+
+	var chunks [][]string
+	for start := 0; start < len(pending); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		chunks = append(chunks, pending[start:end])
+	}
+
+	var mu sync.Mutex
+	err := c.parallel(len(chunks), func(i int) error {
+		found, err := c.queryBatch(ctx, chunks[i])
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for spec, specIDs := range found {
+			ids[spec] = specIDs
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var uniqueIDs []string
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		for _, id := range ids[spec] {
+			if !seen[id] {
+				seen[id] = true
+				uniqueIDs = append(uniqueIDs, id)
+			}
+		}
+	}
+
+	advisories := make(map[string]Advisory, len(uniqueIDs))
+	err = c.parallel(len(uniqueIDs), func(i int) error {
+		advisory, err := c.vuln(ctx, uniqueIDs[i])
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		advisories[uniqueIDs[i]] = advisory
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var queries []types.PackageQuery
+	seenQueries := make(map[string]bool)
+	for _, spec := range specs {
+		for _, id := range ids[spec] {
+			for _, query := range queriesFor(advisories[id], spec) {
+				key := query.Advisory + " " + query.Name + "@" + query.Version
+				if !seenQueries[key] {
+					seenQueries[key] = true
+					queries = append(queries, query)
+				}
+			}
+		}
+	}
+
+	return queries, nil
+}
+
+// queriesFor converts an advisory into queries for one package@version. OSV has already decided
+// that the version is affected, so when the advisory's ranges can't express that in npm range
+// syntax the query falls back to the exact installed version.
+func queriesFor(advisory Advisory, spec string) []types.PackageQuery {
+	i := strings.LastIndex(spec, "@")
+	name, version := spec[:i], spec[i+1:]
+	installed, versionErr := semver.Parse(version)
+
+	all, _ := Queries([]Advisory{advisory})
+	var queries []types.PackageQuery
+	for _, query := range all {
+		if query.Name != name {
+			continue
+		}
+		if query.Version != "" {
+			r, err := semver.ParseRange(query.Version)
+			if err != nil || versionErr != nil || !r.Satisfies(installed) {
+				query.Version = version
+			}
+		}
+		query.Source = Source
+		queries = append(queries, query)
+	}
+	return queries
+}
+
+// queryBatch returns the advisory ids affecting each package@version in one querybatch request,
+// following per-query pagination for packages with many advisories
+func (c *Client) queryBatch(ctx context.Context, specs []string) (map[string][]string, error) {
+	queries := make([]batchQuery, len(specs))
+	for i, spec := range specs {
+		at := strings.LastIndex(spec, "@")
+		queries[i].Package.Name = spec[:at]
+		queries[i].Package.Ecosystem = Ecosystem
+		queries[i].Version = spec[at+1:]
+	}
+
+	found := make(map[string][]string, len(specs))
+	for len(queries) > 0 {
+		var response struct {
+			Results []batchResult `json:"results"`
+		}
+		if err := c.post(ctx, "/v1/querybatch", map[string]interface{}{"queries": queries}, &response); err != nil {
+			return nil, err
+		}
+		if len(response.Results) != len(queries) {
+			return nil, fmt.Errorf("OSV querybatch returned %d results for %d queries", len(response.Results), len(queries))
+		}
+
+		var next []batchQuery
+		for i, result := range response.Results {
+			spec := queries[i].Package.Name + "@" + queries[i].Version
+			if found[spec] == nil {
+				found[spec] = []string{}
+			}
+			for _, vuln := range result.Vulns {
+				found[spec] = append(found[spec], vuln.ID)
+			}
+			if result.NextPageToken != "" {
+				query := queries[i]
+				query.PageToken = result.NextPageToken
+				next = append(next, query)
+			}
+		}
+		queries = next
+	}
+
+	if c.Cache != nil {
+		for spec, specIDs := range found {
+			if data, err := json.Marshal(specIDs); err == nil {
+				c.Cache.Put(cacheNamespaceQueries, spec, data)
+			}
+		}
+	}
+	return found, nil
+}
+
+// vuln fetches the full advisory for an id, from the cache when possible
+func (c *Client) vuln(ctx context.Context, id string) (Advisory, error) {
+	if c.Cache != nil {
+		if data, ok := c.Cache.Get(cacheNamespaceVulns, id); ok {
+			var advisory Advisory
+			if err := json.Unmarshal(data, &advisory); err == nil {
+				return advisory, nil
+			}
+		}
+	}
+
+	data, err := c.remote().Do(ctx, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, c.api()+"/v1/vulns/"+url.PathEscape(id), nil)
+	})
+	if err != nil {
+		return Advisory{}, fmt.Errorf("OSV request for %s failed: %v", id, err)
+	}
+	var advisory Advisory
+	if err := json.Unmarshal(data, &advisory); err != nil {
+		return Advisory{}, fmt.Errorf("invalid OSV advisory %s: %v", id, err)
+	}
+
+	if c.Cache != nil {
+		c.Cache.Put(cacheNamespaceVulns, id, data)
+	}
+	return advisory, nil
+}
+
+func (c *Client) cachedIDs(spec string) ([]string, bool) {
+	if c.Cache == nil {
+		return nil, false
+	}
+	data, ok := c.Cache.Get(cacheNamespaceQueries, spec)
+	if !ok {
+		return nil, false
+	}
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, false
+	}
+	return ids, true
+}
+
+func (c *Client) post(ctx context.Context, path string, body interface{}, response interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	data, err := c.remote().Do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, c.api()+path, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("OSV request failed: %v", err)
+	}
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("invalid OSV response: %v", err)
+	}
+	return nil
+}
+
+// parallel runs fn for 0..n-1 with at most Concurrency calls in flight and returns the first error
+func (c *Client) parallel(n int, fn func(i int) error) error {
+	concurrency := c.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	slots := make(chan struct{}, concurrency)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := fn(i); err != nil {
+				once.Do(func() { firstErr = err })
+			}
+		}(i)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+func (c *Client) api() string {
+	if c.API == "" {
+		return DefaultAPI
+	}
+	return strings.TrimSuffix(c.API, "/")
+}
+
+func (c *Client) remote() *remote.Client {
+	if c.Remote == nil {
+		return &remote.Client{}
+	}
+	return c.Remote
+}
@@ -0,0 +1,77 @@
+package osv
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"scnpm/pkg/cache"
+	"scnpm/pkg/types"
+)
+
+func TestClientAdvisories(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/v1/querybatch":
+			var body struct {
+				Queries []batchQuery `json:"queries"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+
+			results := make([]map[string]interface{}, len(body.Queries))
+			for i, query := range body.Queries {
+				var vulns []map[string]string
+				switch {
+				case query.Package.Name == "lodash" && query.PageToken == "":
+					vulns = []map[string]string{{"id": "GHSA-35jh-r3h4-6jhm"}}
+					results[i] = map[string]interface{}{"vulns": vulns, "next_page_token": "more"}
+					continue
+				case query.Package.Name == "lodash":
+					vulns = []map[string]string{{"id": "GHSA-weird"}}
+				}
+				results[i] = map[string]interface{}{"vulns": vulns}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+		case "/v1/vulns/GHSA-35jh-r3h4-6jhm":
+			w.Write([]byte(`{"id": "GHSA-35jh-r3h4-6jhm", "summary": "Command Injection in lodash",
+				"affected": [{"package": {"ecosystem": "npm", "name": "lodash"},
+					"ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "4.17.21"}]}]}],
+				"database_specific": {"severity": "HIGH"}}`))
+		case "/v1/vulns/GHSA-weird":
+			w.Write([]byte(`{"id": "GHSA-weird", "affected": [{"package": {"ecosystem": "npm", "name": "lodash"},
+				"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "release-4"}]}]}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{API: server.URL, Cache: &cache.Cache{Dir: t.TempDir(), TTL: time.Hour}, Concurrency: 2}
+	installed := map[string][]string{"lodash": {"4.17.20"}, "react": {"18.2.0"}}
+
+	queries, err := client.Advisories(context.Background(), installed)
+	if err != nil {
+		t.Fatalf("Advisories() error = %v", err)
+	}
+	want := []types.PackageQuery{
+		{Name: "lodash", Version: "<4.17.21", Severity: types.SeverityHigh, Advisory: "GHSA-35jh-r3h4-6jhm", Note: "Command Injection in lodash", Source: Source},
+		// The range can't be evaluated, so the query pins the installed version OSV reported
+		{Name: "lodash", Version: "4.17.20", Advisory: "GHSA-weird", Source: Source},
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("Advisories() = %+v, want %+v", queries, want)
+	}
+
+	before := atomic.LoadInt32(&requests)
+	queries, err = client.Advisories(context.Background(), installed)
+	if err != nil || !reflect.DeepEqual(queries, want) || atomic.LoadInt32(&requests) != before {
+		t.Errorf("Advisories() from cache = %+v, %v, want the same queries without requests", queries, err)
+	}
+}
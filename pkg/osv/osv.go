@@ -0,0 +1,179 @@
+// Package osv converts advisories in the OSV schema (https://ossf.github.io/osv-schema/)
+// into scnpm package queries.
+package osv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"scnpm/pkg/types"
+)
+
+// Ecosystem is the OSV ecosystem name for packages published to the npm registry
+const Ecosystem = "npm"
+
+// Advisory is the part of an OSV record scnpm uses
+type Advisory struct {
+	ID               string           `json:"id"`
+	Summary          string           `json:"summary"`
+	Withdrawn        string           `json:"withdrawn"`
+	Affected         []Affected       `json:"affected"`
+	DatabaseSpecific databaseSpecific `json:"database_specific"`
+}
+
+// Affected describes the affected versions of one package
+type Affected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges            []Range          `json:"ranges"`
+	Versions          []string         `json:"versions"`
+	EcosystemSpecific databaseSpecific `json:"ecosystem_specific"`
+	DatabaseSpecific  databaseSpecific `json:"database_specific"`
+}
+
+// Range is an ordered list of events that open and close affected intervals
+type Range struct {
+	Type   string  `json:"type"`
+	Events []Event `json:"events"`
+}
+
+// Event is a single range event; exactly one field is set
+type Event struct {
+	Introduced   string `json:"introduced"`
+	Fixed        string `json:"fixed"`
+	LastAffected string `json:"last_affected"`
+	Limit        string `json:"limit"`
+}
+
+// databaseSpecific holds the severity GitHub and other databases attach outside the CVSS vector
+type databaseSpecific struct {
+	Severity string `json:"severity"`
+}
+
+// Parse decodes a single OSV advisory or a JSON array of advisories
+func Parse(data []byte) ([]Advisory, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var advisories []Advisory
+		if err := json.Unmarshal(data, &advisories); err != nil {
+			return nil, err
+		}
+		return advisories, nil
+	}
+
+	var advisory Advisory
+	if err := json.Unmarshal(data, &advisory); err != nil {
+		return nil, err
+	}
+	if advisory.ID == "" {
+		return nil, fmt.Errorf("not an OSV advisory: missing id")
+	}
+	return []Advisory{advisory}, nil
+}
+
+// Queries converts the npm entries of the advisories into package queries, each carrying
+// the advisory id, summary and severity. Withdrawn advisories are dropped. It also returns
+// the number of affected entries skipped because they belong to another ecosystem.
+func Queries(advisories []Advisory) ([]types.PackageQuery, int) {
+	var queries []types.PackageQuery
+	skipped := 0
+
+	for _, advisory := range advisories {
+		if advisory.Withdrawn != "" {
+			continue
+		}
+
+		for _, affected := range advisory.Affected {
+			if affected.Package.Ecosystem != Ecosystem {
+				skipped++
+				continue
+			}
+
+			severity, _ := types.NormalizeSeverity(firstNonEmpty(
+				affected.EcosystemSpecific.Severity,
+				affected.DatabaseSpecific.Severity,
+				advisory.DatabaseSpecific.Severity,
+			))
+
+			queries = append(queries, types.PackageQuery{
+				Name:     affected.Package.Name,
+				Version:  VersionRange(affected),
+				Severity: severity,
+				Advisory: advisory.ID,
+				Note:     advisory.Summary,
+			})
+		}
+	}
+
+	return queries, skipped
+}
+
+// VersionRange renders the affected versions as an npm range such as ">=1.0.0 <1.2.3 || 2.0.0".
+// An empty string means every version is affected.
+func VersionRange(affected Affected) string {
+	var parts []string
+	for _, r := range affected.Ranges {
+		// GIT ranges are commit hashes and can't be compared to published versions
+		if r.Type == "GIT" {
+			continue
+		}
+		parts = append(parts, intervals(r.Events)...)
+	}
+	parts = append(parts, affected.Versions...)
+
+	for _, part := range parts {
+		if part == "*" {
+			return ""
+		}
+	}
+	return strings.Join(parts, " || ")
+}
+
+// intervals walks the events in order, turning each introduced/fixed pair into a comparator set
+func intervals(events []Event) []string {
+	var parts []string
+	lower := ""
+	open := false
+
+	for _, event := range events {
+		switch {
+		case event.Introduced != "":
+			lower = event.Introduced
+			open = true
+		case event.Fixed != "" && open:
+			parts = append(parts, interval(lower, "<"+event.Fixed))
+			open = false
+		case event.LastAffected != "" && open:
+			parts = append(parts, interval(lower, "<="+event.LastAffected))
+			open = false
+		}
+	}
+	if open {
+		parts = append(parts, interval(lower, ""))
+	}
+
+	return parts
+}
+
+func interval(lower, upper string) string {
+	if lower != "" && lower != "0" {
+		upper = strings.TrimSpace(">=" + lower + " " + upper)
+	}
+	if upper == "" {
+		return "*"
+	}
+	return upper
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+package osv
+
+import (
+	"reflect"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+const advisoryJSON = `{
+  "id": "GHSA-xxxx-yyyy-zzzz",
+  "summary": "Prototype pollution in lodash",
+  "affected": [
+    {
+      "package": {"ecosystem": "npm", "name": "lodash"},
+      "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "4.17.12"}]}]
+    },
+    {
+      "package": {"ecosystem": "PyPI", "name": "lodash-py"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}]}]
+    }
+  ],
+  "database_specific": {"severity": "HIGH"}
+}`
+
+func TestParseAndQueries(t *testing.T) {
+	advisories, err := Parse([]byte(advisoryJSON))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	queries, skipped := Queries(advisories)
+	want := []types.PackageQuery{{
+		Name:     "lodash",
+		Version:  "<4.17.12",
+		Severity: types.SeverityHigh,
+		Advisory: "GHSA-xxxx-yyyy-zzzz",
+		Note:     "Prototype pollution in lodash",
+	}}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("Queries() = %+v, want %+v", queries, want)
+	}
+	if skipped != 1 {
+		t.Errorf("Queries() skipped = %d, want 1", skipped)
+	}
+}
+
+func TestParseArray(t *testing.T) {
+	advisories, err := Parse([]byte(`[{"id": "MAL-1"}, {"id": "MAL-2", "withdrawn": "2024-01-01T00:00:00Z",
+		"affected": [{"package": {"ecosystem": "npm", "name": "evil"}}]}]`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(advisories) != 2 {
+		t.Fatalf("Parse() returned %d advisories, want 2", len(advisories))
+	}
+	if queries, _ := Queries(advisories); len(queries) != 0 {
+		t.Errorf("Queries() = %+v, want withdrawn advisory dropped", queries)
+	}
+
+	if _, err := Parse([]byte(`{"name": "not-osv"}`)); err == nil {
+		t.Error("Parse() expected error for record without id")
+	}
+}
+
+func TestVersionRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		affected Affected
+		want     string
+	}{
+		{
+			name:     "introduced and fixed",
+			affected: Affected{Ranges: []Range{{Type: "SEMVER", Events: []Event{{Introduced: "1.0.0"}, {Fixed: "1.2.3"}}}}},
+			want:     ">=1.0.0 <1.2.3",
+		},
+		{
+			name:     "last affected",
+			affected: Affected{Ranges: []Range{{Type: "SEMVER", Events: []Event{{Introduced: "2.0.0"}, {LastAffected: "2.1.0"}}}}},
+			want:     ">=2.0.0 <=2.1.0",
+		},
+		{
+			name: "several intervals",
+			affected: Affected{Ranges: []Range{{Type: "SEMVER", Events: []Event{
+				{Introduced: "0"}, {Fixed: "1.0.1"}, {Introduced: "2.0.0"}, {Fixed: "2.0.3"},
+			}}}},
+			want: "<1.0.1 || >=2.0.0 <2.0.3",
+		},
+		{
+			name:     "explicit versions",
+			affected: Affected{Versions: []string{"1.0.0", "1.0.1"}},
+			want:     "1.0.0 || 1.0.1",
+		},
+		{
+			name:     "all versions",
+			affected: Affected{Ranges: []Range{{Type: "SEMVER", Events: []Event{{Introduced: "0"}}}}},
+			want:     "",
+		},
+		{
+			name: "git ranges ignored",
+			affected: Affected{Ranges: []Range{
+				{Type: "GIT", Events: []Event{{Introduced: "abc123"}}},
+				{Type: "ECOSYSTEM", Events: []Event{{Introduced: "3.0.0"}}},
+			}},
+			want: ">=3.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VersionRange(tt.affected); got != tt.want {
+				t.Errorf("VersionRange() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package output
+
+import "strings"
+
+// asciiGlyphs maps the emoji and symbols of the table to plain tokens, for consoles that render
+// them as boxes. Markers in front of a word that already says what it is are dropped, so that
+// "🚨 RISK" becomes "RISK" and "✅ 1 PACKAGES SAFE" becomes "1 PACKAGES SAFE".
+var asciiGlyphs = strings.NewReplacer(
+	"⚠️  WARNING:", "WARN:",
+	"✅ GOOD:", "OK:",
+	"🚨 ", "", "⚠️ ", "", "ℹ️ ", "", "✅ ", "", "❗ ", "", "🔇 ", "", "📌 ", "",
+	"📜 ", "", "🔎 ", "", "🎭 ", "", "💀 ", "", "⚖️ ", "", "🔓 ", "", "🔐 ", "", "🌐 ", "", "📦 ", "", "❓ ", "", "⏳ ", "", "👻 ", "", "⏹ ", "",
+	"▶ ", "> ", "≈ ", "~ ", " → ", " -> ", "✓", "yes", "…", "...",
+	"├── ", "|-- ", "└── ", "`-- ", "│   ", "|   ", "█", "#",
+)
+
+// plain returns s with its glyphs replaced by ASCII tokens when config.ASCII is set
+func plain(s string, config OutputConfig) string {
+	if !config.ASCII {
+		return s
+	}
+	return asciiGlyphs.Replace(s)
+}
@@ -0,0 +1,56 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// BlameCommit is a commit of the lockfile's history
+type BlameCommit struct {
+	Hash     string    `json:"hash"`
+	Author   string    `json:"author"`
+	Date     time.Time `json:"date"`
+	Subject  string    `json:"subject"`
+	Lockfile string    `json:"lockfile"` // Path of the lockfile at the commit, relative to the repository
+}
+
+// Blame says when a package entered a lockfile
+type Blame struct {
+	Package    string       `json:"package"`
+	Lockfile   string       `json:"lockfile"`
+	Introduced *BlameCommit `json:"introduced"` // First commit whose lockfile installs the package; nil when none does
+	LastAbsent *BlameCommit `json:"lastAbsent"` // Commit of the lockfile before Introduced; nil when the lockfile was added with the package
+}
+
+// OutputBlame writes the commit that introduced a package to the lockfile and the last one
+// without it, naming the lockfile when it was renamed since
+func OutputBlame(out io.Writer, blame Blame) error {
+	w := &errWriter{w: out}
+	fmt.Fprintf(w, "%s entered %s in:\n", blame.Package, blame.Lockfile)
+	outputBlameCommit(w, "Introduced: ", blame.Introduced, blame.Lockfile)
+	if blame.LastAbsent != nil {
+		outputBlameCommit(w, "Last absent:", blame.LastAbsent, blame.Lockfile)
+	} else {
+		fmt.Fprintln(w, "Last absent: none, the lockfile was added with it")
+	}
+	return w.err
+}
+
+// outputBlameCommit writes a commit as hash, date, author and subject
+func outputBlameCommit(w io.Writer, label string, commit *BlameCommit, lockfile string) {
+	hash := commit.Hash
+	if len(hash) > 12 {
+		hash = hash[:12]
+	}
+	line := fmt.Sprintf("%s %s %s %s: %s", label, hash, commit.Date.Format("2006-01-02 15:04:05 -0700"), commit.Author, commit.Subject)
+	if commit.Lockfile != lockfile {
+		line += " (as " + commit.Lockfile + ")"
+	}
+	fmt.Fprintln(w, line)
+}
+
+// OutputBlameJSON writes when a package entered the lockfile as JSON
+func OutputBlameJSON(w io.Writer, blame Blame) error {
+	return writeJSON(w, "JSON", blame)
+}
@@ -0,0 +1,39 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestOutputBlame(t *testing.T) {
+	date := time.Date(2018, 9, 9, 13, 4, 5, 0, time.UTC)
+	blame := Blame{
+		Package:  "flatmap-stream",
+		Lockfile: "package-lock.json",
+		Introduced: &BlameCommit{Hash: "0123456789abcdef0123456789abcdef01234567", Author: "Jane Doe", Date: date,
+			Subject: "Bump event-stream", Lockfile: "npm-shrinkwrap.json"},
+	}
+
+	var buf bytes.Buffer
+	if err := OutputBlame(&buf, blame); err != nil {
+		t.Fatalf("OutputBlame() error = %v", err)
+	}
+	want := `flatmap-stream entered package-lock.json in:
+Introduced:  0123456789ab 2018-09-09 13:04:05 +0000 Jane Doe: Bump event-stream (as npm-shrinkwrap.json)
+Last absent: none, the lockfile was added with it
+`
+	if buf.String() != want {
+		t.Errorf("OutputBlame() =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := OutputBlameJSON(&buf, blame); err != nil {
+		t.Fatalf("OutputBlameJSON() error = %v", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil || doc["lastAbsent"] != nil || doc["introduced"] == nil {
+		t.Errorf("OutputBlameJSON() = %s, want a null lastAbsent", buf.String())
+	}
+}
@@ -0,0 +1,57 @@
+package output
+
+import (
+	"strings"
+
+	"scnpm/pkg/types"
+)
+
+// ANSI escape sequences used to color the table
+const (
+	colorRed     = "\x1b[31m"
+	colorBoldRed = "\x1b[1;31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorCyan    = "\x1b[36m"
+	colorReset   = "\x1b[0m"
+)
+
+// colorize wraps s in an ANSI color when config.Color is set
+func colorize(s, color string, config OutputConfig) string {
+	if !config.Color || color == "" {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// statusColor picks the color of a table status by its marker: red for risks and errors, yellow
+// for references and other cautions, green for safe packages
+func statusColor(status string) string {
+	switch {
+	case strings.HasPrefix(status, "🚨"), strings.HasPrefix(status, "❗"):
+		return colorRed
+	case strings.HasPrefix(status, "⚠️"):
+		return colorYellow
+	case strings.HasPrefix(status, "✅"):
+		return colorGreen
+	default:
+		return ""
+	}
+}
+
+// severityColor picks the color of a severity cell: bold red for critical, red for high, yellow
+// for moderate and cyan for low
+func severityColor(severity string) string {
+	switch severity {
+	case types.SeverityCritical:
+		return colorBoldRed
+	case types.SeverityHigh:
+		return colorRed
+	case types.SeverityModerate:
+		return colorYellow
+	case types.SeverityLow:
+		return colorCyan
+	default:
+		return ""
+	}
+}
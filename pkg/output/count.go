@@ -0,0 +1,49 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"scnpm/pkg/types"
+)
+
+// Counts is the JSON summary --count-only prints
+type Counts struct {
+	Risks      int `json:"risks"` // As in Totals
+	Safe       int `json:"safe"`
+	References int `json:"references"` // Dependency references among the findings counted as risks
+}
+
+// CountFindings returns the counts of a scan, from the same totals as every report's summary
+func CountFindings(results []types.ScanResult) Counts {
+	totals := CountResults(results)
+	counts := Counts{Risks: totals.Risks, Safe: totals.Safe}
+	for _, result := range results {
+		if result.Check != "" || result.Package.Error != "" {
+			continue
+		}
+		for _, instance := range newInstances(result) {
+			if instance.IsReference {
+				counts.References++
+			}
+		}
+	}
+	return counts
+}
+
+// OutputCount writes the number of risks found on a line of its own, or with asJSON the Counts
+// object, for shell scripts
+func OutputCount(w io.Writer, results []types.ScanResult, asJSON bool) error {
+	counts := CountFindings(results)
+	if !asJSON {
+		_, err := fmt.Fprintln(w, counts.Risks)
+		return err
+	}
+	data, err := json.Marshal(counts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %v", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -0,0 +1,32 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"scnpm/pkg/types"
+)
+
+// OutputCSV writes one row per finding as CSV with a header row, for spreadsheets and ticketing
+// imports. Check findings have no severity or queried version.
+func OutputCSV(out io.Writer, results []types.ScanResult, config OutputConfig) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"finding", "package", "version", "queried", "severity", "dev", "reference", "lockfile", "line", "path", "advisory", "sources"})
+	for _, finding := range findings(results, config) {
+		queried, severity := finding.Package.Version, finding.Severity
+		if finding.Check != "" {
+			queried, severity = "", ""
+		}
+		line := ""
+		if finding.Instance.LineNumber > 0 {
+			line = strconv.Itoa(finding.Instance.LineNumber)
+		}
+		w.Write([]string{githubTitle(finding), finding.Instance.Name, finding.Instance.Version, queried, severity,
+			strconv.FormatBool(finding.Instance.IsDev), strconv.FormatBool(finding.Instance.IsReference),
+			finding.Lockfile, line, finding.Instance.Path, finding.Package.Advisory, strings.Join(finding.Package.Sources, " ")})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,43 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestOutputCSV(t *testing.T) {
+	results := []types.ScanResult{
+		{Check: "non-registry", Found: true, Instances: []types.PackageInstance{{Name: "fork", Version: "1.0.0", Path: "node_modules/fork"}}},
+		{
+			Package: types.PackageQuery{Name: "evil", Version: "^1.0.0", Severity: types.SeverityHigh, Advisory: "GHSA-xxxx-xxxx-xxxx", Sources: []string{"builtin", "osv"}},
+			Found:   true,
+			Instances: []types.PackageInstance{
+				{Name: "evil", Version: "1.0.1", Path: "node_modules/evil", IsDev: true, LineNumber: 12},
+				{Name: "evil", Version: "1.0.1", Path: "node_modules/x/node_modules/evil", Lockfile: "api/package-lock.json"},
+			},
+		},
+		{Package: types.PackageQuery{Name: "left-pad", Version: "1.3.0"}},
+	}
+
+	var buf bytes.Buffer
+	if err := OutputCSV(&buf, results, OutputConfig{Lockfile: "package-lock.json"}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"finding", "package", "version", "queried", "severity", "dev", "reference", "lockfile", "line", "path", "advisory", "sources"},
+		{"Compromised package", "evil", "1.0.1", "^1.0.0", "high", "true", "false", "package-lock.json", "12", "node_modules/evil", "GHSA-xxxx-xxxx-xxxx", "builtin osv"},
+		{"Compromised package", "evil", "1.0.1", "^1.0.0", "high", "false", "false", "api/package-lock.json", "", "node_modules/x/node_modules/evil", "GHSA-xxxx-xxxx-xxxx", "builtin osv"},
+		{githubTitle(Finding{Check: "non-registry"}), "fork", "1.0.0", "", "", "false", "false", "package-lock.json", "", "node_modules/fork", "", ""},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("OutputCSV() rows =\n%q\nwant\n%q", rows, want)
+	}
+}
@@ -0,0 +1,326 @@
+package output
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"scnpm/pkg/license"
+	"scnpm/pkg/types"
+)
+
+// cycloneDXVersion is the CycloneDX specification version scnpm emits
+const cycloneDXVersion = "1.5"
+
+// cdxBOM is the root of a CycloneDX document
+type cdxBOM struct {
+	BOMFormat       string             `json:"bomFormat"`
+	SpecVersion     string             `json:"specVersion"`
+	SerialNumber    string             `json:"serialNumber"`
+	Version         int                `json:"version"`
+	Metadata        cdxMetadata        `json:"metadata"`
+	Components      []cdxComponent     `json:"components"`
+	Vulnerabilities []cdxVulnerability `json:"vulnerabilities,omitempty"`
+}
+
+type cdxMetadata struct {
+	Timestamp string   `json:"timestamp"`
+	Tools     cdxTools `json:"tools"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	Scope      string        `json:"scope,omitempty"`
+	Hashes     []cdxHash     `json:"hashes,omitempty"`
+	Licenses   []cdxLicense  `json:"licenses,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+// cdxLicense is either an SPDX expression or, for free text, a named license
+type cdxLicense struct {
+	Expression string          `json:"expression,omitempty"`
+	License    *cdxLicenseName `json:"license,omitempty"`
+}
+
+type cdxLicenseName struct {
+	Name string `json:"name"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxVulnerability struct {
+	ID          string        `json:"id"`
+	Source      *cdxSource    `json:"source,omitempty"`
+	Ratings     []cdxRating   `json:"ratings,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Advisories  []cdxAdvisory `json:"advisories,omitempty"`
+	Analysis    cdxAnalysis   `json:"analysis"`
+	Affects     []cdxAffect   `json:"affects,omitempty"`
+	Properties  []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxSource struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+type cdxRating struct {
+	Severity string `json:"severity"`
+}
+
+type cdxAdvisory struct {
+	URL string `json:"url"`
+}
+
+// cdxAnalysis is the VEX statement of a vulnerability
+type cdxAnalysis struct {
+	State         string   `json:"state"`
+	Justification string   `json:"justification,omitempty"`
+	Response      []string `json:"response,omitempty"`
+	Detail        string   `json:"detail,omitempty"`
+}
+
+type cdxAffect struct {
+	Ref string `json:"ref"`
+}
+
+// cdxHashAlgorithms maps integrity algorithms onto CycloneDX hash algorithms
+var cdxHashAlgorithms = map[string]string{
+	"sha1":   "SHA-1",
+	"sha256": "SHA-256",
+	"sha384": "SHA-384",
+	"sha512": "SHA-512",
+}
+
+// OutputCycloneDX writes the installed packages to w as a CycloneDX 1.5 SBOM, with a vulnerability
+// and VEX statement per bad-package query: exploitable where it was found, not_affected where it
+// wasn't
+func OutputCycloneDX(w io.Writer, results []types.ScanResult, config OutputConfig) error {
+	serial, err := newSerialNumber()
+	if err != nil {
+		return fmt.Errorf("failed to generate serial number: %v", err)
+	}
+	return writeJSON(w, "CycloneDX", buildCycloneDX(results, config, serial, time.Now()))
+}
+
+// newSerialNumber returns a random (version 4) UUID URN
+func newSerialNumber() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// buildCycloneDX converts the inventory and results into a CycloneDX BOM
+func buildCycloneDX(results []types.ScanResult, config OutputConfig, serial string, timestamp time.Time) cdxBOM {
+	bom := cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  cycloneDXVersion,
+		SerialNumber: serial,
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: timestamp.UTC().Format(time.RFC3339),
+			Tools: cdxTools{Components: []cdxComponent{
+				{Type: "application", Name: "scnpm", Version: config.ToolVersion},
+			}},
+		},
+		Components: []cdxComponent{},
+	}
+
+	// A name@version installed at several paths is one component, listing each of its paths
+	index := make(map[string]int)
+	for _, instance := range config.Inventory {
+		if instance.Version == "" {
+			continue
+		}
+		ref := purl(instance.Name, instance.Version)
+		i, seen := index[ref]
+		if !seen {
+			i = len(bom.Components)
+			index[ref] = i
+			bom.Components = append(bom.Components, cdxComponent{
+				Type:     "library",
+				BOMRef:   ref,
+				Name:     instance.Name,
+				Version:  instance.Version,
+				Scope:    "excluded",
+				Hashes:   cdxHashes(instance.Integrity),
+				Licenses: cdxLicenses(instance.License),
+				PURL:     ref,
+			})
+		}
+		component := &bom.Components[i]
+		if !instance.IsDev {
+			// Dev dependencies are excluded from the runtime, unless also installed for production
+			component.Scope = "required"
+		}
+		component.Properties = append(component.Properties, cdxProperty{Name: "scnpm:path", Value: lockfilePath(instance)})
+	}
+
+	for _, result := range results {
+		if result.Check != "" {
+			continue
+		}
+		bom.Vulnerabilities = append(bom.Vulnerabilities, cdxVulnerabilityFor(result, index))
+	}
+	return bom
+}
+
+// cdxVulnerabilityFor describes a bad-package query and whether the project is affected by it
+func cdxVulnerabilityFor(result types.ScanResult, components map[string]int) cdxVulnerability {
+	query := result.Package
+	vulnerability := cdxVulnerability{
+		ID:          query.Advisory,
+		Ratings:     []cdxRating{{Severity: cdxSeverity(query.Severity)}},
+		Description: query.Note,
+		Properties:  []cdxProperty{{Name: "scnpm:package", Value: query.Name + "@" + targetLabel(query)}},
+	}
+	if vulnerability.ID == "" || strings.HasPrefix(vulnerability.ID, "https://") {
+		vulnerability.ID = query.Name + "@" + sarifVersionLabel(query)
+	}
+	if link := advisoryURL(query.Advisory); link != "" {
+		vulnerability.Source = &cdxSource{URL: link}
+		vulnerability.Advisories = []cdxAdvisory{{URL: link}}
+	}
+	for _, source := range query.Sources {
+		vulnerability.Properties = append(vulnerability.Properties, cdxProperty{Name: "scnpm:source", Value: source})
+	}
+
+	// Only installed instances affect a component; references merely admit a bad version
+	affected := make(map[string]bool)
+	var installed, suppressed int
+	var reasons []string
+	for _, instance := range result.Instances {
+		if instance.IsReference {
+			continue
+		}
+		installed++
+		if instance.Suppressed {
+			suppressed++
+			reasons = append(reasons, instance.SuppressedReason)
+			continue
+		}
+		ref := purl(instance.Name, instance.Version)
+		if _, ok := components[ref]; ok && !affected[ref] {
+			affected[ref] = true
+			vulnerability.Affects = append(vulnerability.Affects, cdxAffect{Ref: ref})
+		}
+	}
+
+	switch {
+	case query.Error != "":
+		vulnerability.Analysis = cdxAnalysis{State: "in_triage", Detail: query.Error}
+	case installed == 0 && result.Found:
+		vulnerability.Analysis = cdxAnalysis{State: "in_triage", Detail: "not installed, but a dependency declaration admits the bad version"}
+	case installed == 0:
+		vulnerability.Analysis = cdxAnalysis{State: "not_affected", Justification: "code_not_present", Detail: "not installed"}
+	case suppressed == installed:
+		vulnerability.Analysis = cdxAnalysis{State: "exploitable", Response: []string{"will_not_fix"}, Detail: strings.Join(reasons, "; ")}
+	default:
+		vulnerability.Analysis = cdxAnalysis{State: "exploitable", Response: []string{"update"}}
+	}
+	return vulnerability
+}
+
+// purl returns the package URL of an npm package, e.g. pkg:npm/%40babel/core@7.0.0
+func purl(name, version string) string {
+	namespace := ""
+	if i := strings.Index(name, "/"); strings.HasPrefix(name, "@") && i > 0 {
+		namespace, name = "%40"+url.PathEscape(name[1:i])+"/", name[i+1:]
+	}
+	// Unlike URL paths, purls reserve "+" (build metadata in semver)
+	return "pkg:npm/" + namespace + url.PathEscape(name) + "@" + strings.ReplaceAll(url.PathEscape(version), "+", "%2B")
+}
+
+// cdxHashes converts an SRI integrity value into hashes; unknown algorithms are skipped
+func cdxHashes(integrity string) []cdxHash {
+	var hashes []cdxHash
+	for _, digest := range integrityDigests(integrity) {
+		if alg, ok := cdxHashAlgorithms[digest.Algorithm]; ok {
+			hashes = append(hashes, cdxHash{Alg: alg, Content: digest.Hex})
+		}
+	}
+	return hashes
+}
+
+// digest is one hash of an SRI integrity value, hex-encoded
+type digest struct {
+	Algorithm string // As in the integrity value, e.g. "sha512"
+	Hex       string
+}
+
+// integrityDigests decodes the hashes of an SRI integrity value like "sha512-<base64>",
+// skipping malformed ones
+func integrityDigests(integrity string) []digest {
+	var digests []digest
+	for _, field := range strings.Fields(integrity) {
+		algorithm, value, ok := strings.Cut(field, "-")
+		if !ok {
+			continue
+		}
+		// Options ("sha512-<base64>?opt") aren't part of the hash
+		value, _, _ = strings.Cut(value, "?")
+		raw, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			continue
+		}
+		digests = append(digests, digest{Algorithm: algorithm, Hex: hex.EncodeToString(raw)})
+	}
+	return digests
+}
+
+// cdxLicenses records a license field as an SPDX expression when it is one, otherwise as a name
+func cdxLicenses(value string) []cdxLicense {
+	switch {
+	case value == "":
+		return nil
+	case license.IsExpression(value):
+		return []cdxLicense{{Expression: value}}
+	default:
+		return []cdxLicense{{License: &cdxLicenseName{Name: value}}}
+	}
+}
+
+// cdxSeverity maps a query's severity onto a CycloneDX rating
+func cdxSeverity(severity string) string {
+	switch severity {
+	case "":
+		return "unknown"
+	case types.SeverityModerate:
+		return "medium"
+	default:
+		return severity
+	}
+}
+
+// lockfilePath locates an instance as lockfile:path when several lockfiles were scanned
+func lockfilePath(instance types.PackageInstance) string {
+	if instance.Lockfile != "" {
+		return instance.Lockfile + ":" + instance.Path
+	}
+	return instance.Path
+}
@@ -0,0 +1,148 @@
+package output
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+// cdxSerialPattern is the schema's pattern for serialNumber, a UUID URN
+var cdxSerialPattern = regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-[1-5][0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// objects returns the elements of an array property
+func objects(value any) []any {
+	array, _ := value.([]any)
+	return array
+}
+
+// validateCycloneDX checks a document against the CycloneDX 1.5 schema, plus what the schema
+// leaves open: a 1.5 document, and bom-refs that are unique and resolve
+func validateCycloneDX(t *testing.T, data []byte) {
+	t.Helper()
+	bom := checkPublishedSchema(t, "bom-1.5.schema.json", data)
+	if bom["bomFormat"] != "CycloneDX" || bom["specVersion"] != "1.5" {
+		t.Errorf("bomFormat, specVersion = %v, %v, want CycloneDX 1.5", bom["bomFormat"], bom["specVersion"])
+	}
+
+	refs := make(map[string]bool)
+	for _, c := range objects(bom["components"]) {
+		ref, _ := c.(map[string]any)["bom-ref"].(string)
+		if ref == "" || refs[ref] {
+			t.Errorf("bom-ref %q is empty or duplicated", ref)
+		}
+		refs[ref] = true
+	}
+	for _, v := range objects(bom["vulnerabilities"]) {
+		for _, a := range objects(v.(map[string]any)["affects"]) {
+			if ref, _ := a.(map[string]any)["ref"].(string); !refs[ref] {
+				t.Errorf("affects ref %q doesn't name a component", ref)
+			}
+		}
+	}
+}
+
+func TestBuildCycloneDX(t *testing.T) {
+	config := OutputConfig{
+		ToolVersion: "1.2.3",
+		Inventory: []types.PackageInstance{
+			{Name: "@babel/core", Version: "7.0.0", Path: "node_modules/@babel/core", IsDev: true, Integrity: "sha512-AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJygpKissLS4vMDEyMzQ1Njc4OTo7PD0+Pw==", License: "MIT"},
+			{Name: "lodash", Version: "4.17.20", Path: "node_modules/lodash", Integrity: "sha1-AAECAwQFBgcICQoLDA0ODxAREhM=", License: "SEE LICENSE IN LICENSE"},
+			{Name: "lodash", Version: "4.17.20", Path: "node_modules/a/node_modules/lodash", IsDev: true},
+			{Name: "ms", Version: "2.1.2", Path: "node_modules/ms", License: "(MIT OR Apache-2.0)", Lockfile: "web/package-lock.json"},
+			{Name: "lib", Path: "node_modules/lib"},
+		},
+	}
+	results := []types.ScanResult{
+		{
+			Package:   types.PackageQuery{Name: "lodash", Version: "4.17.20", Severity: types.SeverityModerate, Advisory: "GHSA-35jh-r3h4-6jhm", Note: "Command injection", Sources: []string{"builtin"}},
+			Found:     true,
+			Instances: []types.PackageInstance{config.Inventory[1], config.Inventory[2]},
+		},
+		{Package: types.PackageQuery{Name: "left-pad", Version: "1.3.0"}},
+		{
+			Package:   types.PackageQuery{Name: "ms", Advisory: "https://example.com/ms"},
+			Found:     true,
+			Instances: []types.PackageInstance{{Name: "ms", Version: "2.1.2", Path: "node_modules/ms", Suppressed: true, SuppressedReason: "vendored fork"}},
+		},
+		{
+			Package:   types.PackageQuery{Name: "debug", Version: "4.3.4"},
+			Found:     true,
+			Instances: []types.PackageInstance{{Name: "debug", Version: "^4.0.0", Path: "node_modules/x -> debug", IsReference: true}},
+		},
+		{Package: types.PackageQuery{Name: "chalk", Tag: "latest", Error: "registry unreachable"}},
+		{Package: types.PackageQuery{Name: "lodahs"}, Found: true, Check: types.CheckTyposquat, Instances: []types.PackageInstance{{Name: "lodahs"}}},
+	}
+
+	bom := buildCycloneDX(results, config, "urn:uuid:3e671687-395b-41f5-a30f-a58921a69b79", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	data, err := json.Marshal(bom)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	validateCycloneDX(t, data)
+
+	if len(bom.Components) != 3 || len(bom.Vulnerabilities) != 5 {
+		t.Fatalf("bom = %+v, want 3 components (lodash once, no unversioned link) and 5 vulnerabilities (no check)", bom)
+	}
+	babel, lodash, ms := bom.Components[0], bom.Components[1], bom.Components[2]
+	if babel.PURL != "pkg:npm/%40babel/core@7.0.0" || babel.Scope != "excluded" || babel.Hashes[0].Alg != "SHA-512" || babel.Hashes[0].Content[:8] != "00010203" {
+		t.Errorf("@babel/core = %+v, want a scoped purl, dev scope and a hex SHA-512", babel)
+	}
+	if lodash.Scope != "required" || len(lodash.Properties) != 2 || lodash.Licenses[0].License == nil {
+		t.Errorf("lodash = %+v, want a required component with both paths and a named license", lodash)
+	}
+	if ms.Licenses[0].Expression != "(MIT OR Apache-2.0)" || ms.Properties[0].Value != "web/package-lock.json:node_modules/ms" {
+		t.Errorf("ms = %+v, want an expression license and the lockfile in its path", ms)
+	}
+
+	states := make(map[string]cdxAnalysis)
+	for _, vulnerability := range bom.Vulnerabilities {
+		states[vulnerability.ID] = vulnerability.Analysis
+	}
+	tests := []struct {
+		id, state, detail string
+	}{
+		{"GHSA-35jh-r3h4-6jhm", "exploitable", ""},
+		{"left-pad@1.3.0", "not_affected", "not installed"},
+		{"ms@any", "exploitable", "vendored fork"},
+		{"debug@4.3.4", "in_triage", "not installed, but a dependency declaration admits the bad version"},
+		{"chalk@any", "in_triage", "registry unreachable"},
+	}
+	for _, tt := range tests {
+		if got := states[tt.id]; got.State != tt.state || got.Detail != tt.detail {
+			t.Errorf("analysis of %s = %+v, want %s (%q)", tt.id, got, tt.state, tt.detail)
+		}
+	}
+	if bom.Vulnerabilities[0].Affects[0].Ref != lodash.BOMRef || bom.Vulnerabilities[0].Ratings[0].Severity != "medium" {
+		t.Errorf("lodash vulnerability = %+v, want it to affect lodash at medium severity", bom.Vulnerabilities[0])
+	}
+}
+
+func TestPURL(t *testing.T) {
+	tests := []struct {
+		name, version, want string
+	}{
+		{"lodash", "4.17.21", "pkg:npm/lodash@4.17.21"},
+		{"@ctrl/tinycolor", "4.1.1", "pkg:npm/%40ctrl/tinycolor@4.1.1"},
+		{"left-pad", "1.0.0+build", "pkg:npm/left-pad@1.0.0%2Bbuild"},
+	}
+
+	for _, tt := range tests {
+		if got := purl(tt.name, tt.version); got != tt.want {
+			t.Errorf("purl(%q, %q) = %q, want %q", tt.name, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestNewSerialNumber(t *testing.T) {
+	first, err := newSerialNumber()
+	if err != nil {
+		t.Fatalf("newSerialNumber() error = %v", err)
+	}
+	second, _ := newSerialNumber()
+	if !cdxSerialPattern.MatchString(first) || first == second {
+		t.Errorf("newSerialNumber() = %q, %q, want distinct version 4 UUID URNs", first, second)
+	}
+}
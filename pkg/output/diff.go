@@ -0,0 +1,74 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"scnpm/pkg/diff"
+)
+
+// OutputDiff writes the comparison of two lockfiles as a table: the findings each side introduced
+// and removed, the ones both have, and every package whose version or integrity changed
+func OutputDiff(out io.Writer, result diff.Result, config OutputConfig) error {
+	w := &errWriter{w: out}
+	fmt.Fprintf(w, "Comparing %s -> %s\n", result.Old, result.New)
+
+	for _, section := range []struct {
+		title    string
+		findings []diff.Finding
+	}{
+		{"INTRODUCED RISKS", result.Introduced},
+		{"REMOVED RISKS", result.Removed},
+		{"PRE-EXISTING RISKS", result.PreExisting},
+	} {
+		if len(section.findings) == 0 {
+			continue
+		}
+		fmt.Fprintln(w, strings.Repeat("-", 120))
+		fmt.Fprintf(w, "%s (%d):\n", section.title, len(section.findings))
+		for _, finding := range section.findings {
+			status := plain(instanceStatus(finding.Instance), config)
+			fmt.Fprintf(w, "  %s %s %s %s\n", colorize(padRight(status, 10), statusColor(instanceStatus(finding.Instance)), config),
+				padRight(finding.Instance.Name, 30), padRight(finding.Instance.Version, 15), instancePath(finding.Instance))
+		}
+	}
+
+	integrityChanges := 0
+	if len(result.Changed) > 0 {
+		fmt.Fprintln(w, strings.Repeat("-", 120))
+		fmt.Fprintf(w, "CHANGED PACKAGES (%d):\n", len(result.Changed))
+		for _, change := range result.Changed {
+			var note string
+			switch {
+			case change.SameVersion:
+				integrityChanges++
+				note = colorize(plain("⚠️  integrity changed at the same version", config), colorYellow, config)
+			case change.OldVersion == "":
+				note = "added " + change.NewVersion
+			case change.NewVersion == "":
+				note = "removed " + change.OldVersion
+			default:
+				note = plain(change.OldVersion+" → "+change.NewVersion, config)
+			}
+			fmt.Fprintf(w, "  %s %s %s\n", padRight(change.Name, 30), padRight(change.Path, 50), note)
+		}
+	}
+
+	fmt.Fprintln(w, strings.Repeat("=", 120))
+	summary := fmt.Sprintf("DIFF SUMMARY: 🚨 %d INTRODUCED | %d REMOVED | %d PRE-EXISTING | %d CHANGED", len(result.Introduced), len(result.Removed), len(result.PreExisting), len(result.Changed))
+	if integrityChanges > 0 {
+		summary += fmt.Sprintf(" | ⚠️ %d INTEGRITY CHANGES", integrityChanges)
+	}
+	verdict := colorGreen
+	if len(result.Introduced) > 0 {
+		verdict = colorRed
+	}
+	fmt.Fprintln(w, colorize(plain(summary, config), verdict, config))
+	return w.err
+}
+
+// OutputDiffJSON writes the comparison of two lockfiles as JSON
+func OutputDiffJSON(w io.Writer, result diff.Result) error {
+	return writeJSON(w, "JSON", result)
+}
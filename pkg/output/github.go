@@ -0,0 +1,119 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"scnpm/pkg/types"
+)
+
+// DefaultErrorSeverity is the lowest severity GitHub output annotates as an error
+const DefaultErrorSeverity = types.SeverityHigh
+
+// OutputGitHub writes results as GitHub Actions workflow commands: an annotation per active
+// finding at its lockfile line (unless config.SummaryOnly), a warning per caveat and a notice
+// with the summary
+func OutputGitHub(w io.Writer, results []types.ScanResult, config OutputConfig) error {
+	ew := &errWriter{w: w}
+	var list []Finding
+	if !config.SummaryOnly {
+		list = findings(results, config)
+	}
+	for _, finding := range list {
+		properties := []string{"file=" + escapeProperty(finding.Lockfile)}
+		if finding.Instance.LineNumber > 0 {
+			properties = append(properties, fmt.Sprintf("line=%d", finding.Instance.LineNumber))
+		}
+		properties = append(properties, "title="+escapeProperty(githubTitle(finding)))
+		fmt.Fprintf(ew, "::%s %s::%s\n", githubLevel(finding, config), strings.Join(properties, ","), escapeData(sarifText(finding.Result, finding.Instance)))
+	}
+	for _, warning := range config.Warnings {
+		fmt.Fprintf(ew, "::warning title=scnpm::%s\n", escapeData(warning))
+	}
+	fmt.Fprintf(ew, "::notice title=scnpm::%s\n", escapeData(Summary(results, config)))
+	return ew.err
+}
+
+// OutputGitHubSummary writes a Markdown summary of the results for the job's summary page
+// ($GITHUB_STEP_SUMMARY)
+func OutputGitHubSummary(w io.Writer, results []types.ScanResult, config OutputConfig) error {
+	ew := &errWriter{w: w}
+	fmt.Fprintf(ew, "## scnpm security scan\n\n%s\n\n", Summary(results, config))
+
+	list := findings(results, config)
+	if len(list) > 0 {
+		fmt.Fprintln(ew, "| Finding | Package | Version | Severity | Location | Advisory |")
+		fmt.Fprintln(ew, "| --- | --- | --- | --- | --- | --- |")
+		for _, finding := range list {
+			severity := finding.Severity
+			if finding.Check != "" {
+				severity = "-"
+			}
+			location := finding.Lockfile + ":" + finding.Instance.Path
+			if finding.Instance.LineNumber > 0 {
+				location = fmt.Sprintf("%s:%d %s", finding.Lockfile, finding.Instance.LineNumber, finding.Instance.Path)
+			}
+			advisory := finding.Package.Advisory
+			if url := advisoryURL(advisory); url != "" {
+				advisory = fmt.Sprintf("[%s](%s)", advisory, url)
+			}
+			fmt.Fprintf(ew, "| %s | %s | %s | %s | %s | %s |\n",
+				markdownCell(githubTitle(finding)), markdownCell(finding.Instance.Name), markdownCell(finding.Instance.Version),
+				severity, markdownCell(location), markdownCell(advisory))
+		}
+		fmt.Fprintln(ew)
+	}
+	outputRemediationMarkdown(ew, results)
+
+	for _, warning := range config.Warnings {
+		fmt.Fprintf(ew, "> [!WARNING]\n> %s\n\n", warning)
+	}
+	return ew.err
+}
+
+// githubLevel picks the annotation command for a finding. Bad-package queries at or above
+// config.ErrorSeverity, or without a severity, are errors; lower ones, dependency references and
+// check findings are warnings. Findings in the baseline are notices.
+func githubLevel(finding Finding, config OutputConfig) string {
+	threshold := config.ErrorSeverity
+	if threshold == "" {
+		threshold = DefaultErrorSeverity
+	}
+	severity := finding.Package.Severity
+	switch {
+	case finding.Instance.Baseline:
+		// Already known; annotating it as an error would fail the review on every pull request
+		return "notice"
+	case finding.Check != "" || finding.Instance.IsReference:
+		return "warning"
+	case severity != "" && types.SeverityRank(severity) < types.SeverityRank(threshold):
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// githubTitle names what kind of finding an annotation is
+func githubTitle(finding Finding) string {
+	if finding.Check != "" {
+		title := checkSections[finding.Check].Title
+		return strings.ToUpper(title[:1]) + strings.ToLower(title[1:])
+	}
+	return "Compromised package"
+}
+
+// escapeData escapes the message of a workflow command
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property value of a workflow command
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// markdownCell keeps a value from breaking out of its Markdown table cell
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
@@ -0,0 +1,77 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestOutputGitHub(t *testing.T) {
+	results := []types.ScanResult{
+		{
+			Package: types.PackageQuery{Name: "lodash", Version: "4.17.20", Severity: types.SeverityHigh, Advisory: "GHSA-35jh-r3h4-6jhm"},
+			Found:   true,
+			Instances: []types.PackageInstance{
+				{Name: "lodash", Version: "4.17.20", Path: "node_modules/lodash", LineNumber: 12},
+				{Name: "lodash", Version: "^4.17.0", Path: "node_modules/a -> lodash", LineNumber: 30, IsReference: true, ReferenceType: "dependencies"},
+				{Name: "lodash", Version: "4.17.20", Path: "node_modules/b/node_modules/lodash", Suppressed: true},
+			},
+		},
+		{
+			Package:   types.PackageQuery{Name: "minimist", Version: "1.2.5", Severity: types.SeverityModerate},
+			Found:     true,
+			Instances: []types.PackageInstance{{Name: "minimist", Version: "1.2.5", Path: "node_modules/minimist", Lockfile: "web/package-lock.json"}},
+		},
+		{Package: types.PackageQuery{Name: "left-pad", Version: "1.3.0"}},
+	}
+	config := OutputConfig{Lockfile: "package-lock.json", Warnings: []string{"100% of\nqueries"}}
+
+	var buf bytes.Buffer
+	if err := OutputGitHub(&buf, results, config); err != nil {
+		t.Fatalf("OutputGitHub() error = %v", err)
+	}
+	want := []string{
+		"::error file=package-lock.json,line=12,title=Compromised package::lodash@4.17.20 found at node_modules/lodash",
+		"::warning file=package-lock.json,line=30,title=Compromised package::lodash@^4.17.0 is declared in dependencies at node_modules/a -> lodash and admits the bad version 4.17.20",
+		"::warning file=web/package-lock.json,title=Compromised package::minimist@1.2.5 found at node_modules/minimist",
+		"::warning title=scnpm::100%25 of%0Aqueries",
+		"::notice title=scnpm::SECURITY SUMMARY: 🚨 2 RISKS DETECTED (1 high, 1 moderate) | ✅ 1 PACKAGES SAFE",
+	}
+	if got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("OutputGitHub() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	buf.Reset()
+	config.ErrorSeverity = types.SeverityModerate
+	if err := OutputGitHub(&buf, results, config); err != nil {
+		t.Fatalf("OutputGitHub() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "::error file=web/package-lock.json,") {
+		t.Errorf("OutputGitHub() with a moderate threshold = %s, want minimist annotated as an error", buf.String())
+	}
+
+	buf.Reset()
+	if err := OutputGitHubSummary(&buf, results, config); err != nil {
+		t.Fatalf("OutputGitHubSummary() error = %v", err)
+	}
+	for _, want := range []string{
+		"| Compromised package | lodash | 4.17.20 | high | package-lock.json:12 node_modules/lodash | [GHSA-35jh-r3h4-6jhm](https://github.com/advisories/GHSA-35jh-r3h4-6jhm) |",
+		"| Compromised package | minimist | 1.2.5 | moderate | web/package-lock.json:node_modules/minimist |  |",
+		"> [!WARNING]",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("OutputGitHubSummary() lacks %q:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "node_modules/b/") {
+		t.Errorf("OutputGitHubSummary() lists a suppressed instance:\n%s", buf.String())
+	}
+}
+
+func TestEscapeProperty(t *testing.T) {
+	if got, want := escapeProperty("a:b,c%\n"), "a%3Ab%2Cc%25%0A"; got != want {
+		t.Errorf("escapeProperty() = %q, want %q", got, want)
+	}
+}
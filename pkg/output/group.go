@@ -0,0 +1,116 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"scnpm/pkg/types"
+)
+
+// Ways of grouping findings with OutputConfig.GroupBy
+const (
+	GroupByPackage  = "package"  // One group per package name
+	GroupByVersion  = "version"  // One group per package name and found version
+	GroupByLockfile = "lockfile" // One group per lockfile, for archives of several projects
+	GroupByPath     = "path"     // One group per install path prefix, the directory above node_modules
+)
+
+// GroupBys are the values OutputConfig.GroupBy accepts besides "", which keeps the flat layout
+var GroupBys = []string{GroupByPackage, GroupByVersion, GroupByLockfile, GroupByPath}
+
+// groupKey returns the group a finding belongs to under groupBy
+func groupKey(finding Finding, groupBy string) string {
+	switch groupBy {
+	case GroupByVersion:
+		return finding.Instance.Name + "@" + finding.Instance.Version
+	case GroupByLockfile:
+		return finding.Lockfile
+	case GroupByPath:
+		return pathPrefix(finding.Instance.Path)
+	default:
+		return finding.Package.Name
+	}
+}
+
+// pathPrefix returns the directory an instance is installed under, e.g. "packages/app" for
+// "packages/app/node_modules/lodash", or "." for the root project's node_modules. References
+// belong to the directory of the package declaring them.
+func pathPrefix(path string) string {
+	path, _, _ = strings.Cut(path, " -> ")
+	if i := strings.Index(path, "node_modules/"); i >= 0 {
+		path = path[:i]
+	}
+	if path = strings.TrimSuffix(path, "/"); path == "" {
+		return "."
+	}
+	return path
+}
+
+// withGroups returns a copy of results with every instance tagged with its group, for the JSON
+// reports. Results are returned as they are when config.GroupBy is empty.
+func withGroups(results []types.ScanResult, config OutputConfig) []types.ScanResult {
+	if config.GroupBy == "" {
+		return results
+	}
+	grouped := make([]types.ScanResult, len(results))
+	for i, result := range results {
+		result.Instances = append([]types.PackageInstance(nil), result.Instances...)
+		for j, instance := range result.Instances {
+			result.Instances[j].Group = groupKey(newFinding(result, instance, config), config.GroupBy)
+		}
+		grouped[i] = result
+	}
+	return grouped
+}
+
+// outputGroupedRows prints the table header and a section per group of bad-package findings,
+// each closed by a subtotal. Queries that couldn't be checked, and safe ones when shown, follow
+// in sections of their own.
+func outputGroupedRows(w io.Writer, results []types.ScanResult, config OutputConfig) {
+	groups := make(map[string][]Finding)
+	var keys []string
+	for _, finding := range findings(results, config) {
+		if finding.Check != "" {
+			continue
+		}
+		key := groupKey(finding, config.GroupBy)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], finding)
+	}
+	sort.Strings(keys)
+
+	title := strings.ToUpper(config.GroupBy)
+	var rows []tableRow
+	for _, key := range keys {
+		rows = append(rows, tableRow{Section: fmt.Sprintf("%s: %s", title, key)})
+		packages := make(map[string]bool)
+		for _, finding := range groups[key] {
+			rows = append(rows, instanceRow(finding.Result, finding.Instance, config))
+			packages[finding.Package.Name] = true
+		}
+		rows = append(rows, tableRow{Section: fmt.Sprintf("  Subtotal: %d findings in %d packages", len(groups[key]), len(packages))})
+	}
+
+	var failed, safe []tableRow
+	for _, result := range results {
+		switch {
+		case result.Check != "":
+		case result.Package.Error != "":
+			failed = append(failed, errorRow(result))
+		case !result.Found && config.ShowSafe && !config.RiskOnly:
+			safe = append(safe, safeRow(result))
+		}
+	}
+	if len(failed) > 0 {
+		rows = append(append(rows, tableRow{Section: "NOT CHECKED"}), failed...)
+	}
+	if len(safe) > 0 {
+		rows = append(append(rows, tableRow{Section: "NOT FOUND"}), safe...)
+	}
+
+	writeRows(w, rows, config)
+}
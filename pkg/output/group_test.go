@@ -0,0 +1,92 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestPathPrefix(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"node_modules/lodash", "."},
+		{"node_modules/a/node_modules/lodash", "."},
+		{"packages/app/node_modules/lodash", "packages/app"},
+		{"packages/app -> lodash", "packages/app"},
+		{"", "."},
+	}
+
+	for _, tt := range tests {
+		if got := pathPrefix(tt.path); got != tt.want {
+			t.Errorf("pathPrefix(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func groupedResults() []types.ScanResult {
+	return []types.ScanResult{
+		{
+			Package: types.PackageQuery{Name: "lodash", Version: "4.17.20"},
+			Found:   true,
+			Instances: []types.PackageInstance{
+				{Name: "lodash", Version: "4.17.20", Path: "node_modules/lodash"},
+				{Name: "lodash", Version: "4.17.20", Path: "packages/app/node_modules/lodash"},
+			},
+		},
+		{
+			Package:   types.PackageQuery{Name: "event-stream", Version: "3.3.6"},
+			Found:     true,
+			Instances: []types.PackageInstance{{Name: "event-stream", Version: "3.3.6", Path: "packages/app/node_modules/event-stream"}},
+		},
+		{Package: types.PackageQuery{Name: "chalk", Error: "registry unreachable"}},
+	}
+}
+
+func TestOutputTableGroupBy(t *testing.T) {
+	var buf bytes.Buffer
+	if err := OutputTable(&buf, groupedResults(), OutputConfig{GroupBy: GroupByPath, ASCII: true}); err != nil {
+		t.Fatalf("OutputTable() error = %v", err)
+	}
+	out := buf.String()
+
+	sections := []string{"PATH: .\n", "Subtotal: 1 findings in 1 packages", "PATH: packages/app\n", "Subtotal: 2 findings in 2 packages", "NOT CHECKED\n"}
+	last := -1
+	for _, section := range sections {
+		i := strings.Index(out, section)
+		if i <= last {
+			t.Fatalf("OutputTable() = %s, want %q after the previous section", out, section)
+		}
+		last = i
+	}
+}
+
+func TestOutputJSONGroupBy(t *testing.T) {
+	var buf bytes.Buffer
+	if err := OutputJSON(&buf, groupedResults(), OutputConfig{GroupBy: GroupByVersion}); err != nil {
+		t.Fatalf("OutputJSON() error = %v", err)
+	}
+	var report jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range report.Results {
+		for _, instance := range result.Instances {
+			if want := instance.Name + "@" + instance.Version; instance.Group != want {
+				t.Errorf("%s group = %q, want %q", instance.Path, instance.Group, want)
+			}
+		}
+	}
+
+	buf.Reset()
+	if err := OutputJSON(&buf, groupedResults(), OutputConfig{}); err != nil {
+		t.Fatalf("OutputJSON() error = %v", err)
+	}
+	if strings.Contains(buf.String(), `"group"`) {
+		t.Errorf("OutputJSON() = %s, want no groups without GroupBy", buf.String())
+	}
+}
@@ -0,0 +1,176 @@
+package output
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+//go:embed report.html
+var reportTemplate string
+
+var htmlTemplate = template.Must(template.New("report").Parse(reportTemplate))
+
+// htmlReport is the data rendered by report.html
+type htmlReport struct {
+	Generated   string
+	ToolVersion string
+	Totals      Totals
+	Severities  string   // Breakdown of the risks by severity
+	Checks      []string // Summary entries of the checks with findings
+	Warnings    []string
+	Findings    []htmlFinding
+	Lockfiles   []string
+	Sources     []string
+}
+
+// htmlFinding is a row of the findings table
+type htmlFinding struct {
+	Category    string // "Bad package" or the title of a lockfile-wide check
+	Package     string
+	Target      string
+	Status      string
+	Severity    string // Also selects the row's color
+	Version     string
+	Dev         bool
+	Line        int
+	Lockfile    string
+	Path        string
+	Detail      string
+	Advisory    string
+	AdvisoryURL string
+	Suppressed  bool
+}
+
+// OutputHTML writes results to w as a self-contained HTML report, with a sortable and filterable
+// findings table that works offline
+func OutputHTML(w io.Writer, results []types.ScanResult, config OutputConfig) error {
+	return renderHTML(w, buildHTMLReport(results, config, time.Now()))
+}
+
+// renderHTML writes a report through the embedded template
+func renderHTML(w io.Writer, report htmlReport) error {
+	return htmlTemplate.Execute(w, report)
+}
+
+// buildHTMLReport collects what the report shows; generated is the time stamped on it
+func buildHTMLReport(results []types.ScanResult, config OutputConfig, generated time.Time) htmlReport {
+	totals := CountResults(results)
+	report := htmlReport{
+		Generated:   generated.UTC().Format("2006-01-02 15:04 MST"),
+		ToolVersion: config.ToolVersion,
+		Totals:      totals,
+		Severities:  severityBreakdown(totals.RisksBySeverity),
+		Warnings:    config.Warnings,
+		Findings:    []htmlFinding{},
+	}
+	for _, check := range types.Checks {
+		if totals.Checks[check] > 0 {
+			report.Checks = append(report.Checks, fmt.Sprintf(checkSections[check].Summary, totals.Checks[check]))
+		}
+	}
+
+	sources := make(map[string]bool)
+	for _, result := range results {
+		query := result.Package
+		if result.Check == "" {
+			for _, source := range query.Sources {
+				sources[source] = true
+			}
+			if query.Source != "" {
+				sources[query.Source] = true
+			}
+		}
+
+		if query.Error != "" {
+			report.Findings = append(report.Findings, htmlFinding{
+				Category:    "Bad package",
+				Package:     query.Name,
+				Target:      targetLabel(query),
+				Status:      "❗ ERROR",
+				Severity:    "error",
+				Detail:      query.Error,
+				Advisory:    query.Advisory,
+				AdvisoryURL: advisoryURL(query.Advisory),
+			})
+			continue
+		}
+
+		for _, instance := range result.Instances {
+			lockfile := instance.Lockfile
+			if lockfile == "" {
+				lockfile = config.Lockfile
+			}
+
+			finding := htmlFinding{
+				Package:    instance.Name,
+				Version:    instance.Version,
+				Dev:        instance.IsDev,
+				Line:       instance.LineNumber,
+				Lockfile:   lockfile,
+				Path:       instancePath(instance),
+				Suppressed: instance.Suppressed,
+			}
+			if result.Check != "" {
+				label, note := checkDetail(result.Check, instance)
+				finding.Category = checkSections[result.Check].Title
+				finding.Status = label
+				finding.Severity = "check"
+				finding.Path = instance.Path
+				finding.Detail = strings.TrimPrefix(note, " ")
+			} else {
+				finding.Category = "Bad package"
+				finding.Target = targetLabel(query)
+				finding.Status = instanceStatus(instance)
+				finding.Severity = severityLabel(query.Severity)
+				finding.Detail = query.Note
+				finding.Advisory = query.Advisory
+				finding.AdvisoryURL = advisoryURL(query.Advisory)
+			}
+			if instance.Suppressed {
+				finding.Status = "🔇 SUPPRESSED"
+				finding.Detail = instance.SuppressedReason
+			}
+			report.Findings = append(report.Findings, finding)
+		}
+	}
+
+	report.Lockfiles = scannedLockfiles(results, config)
+	report.Sources = sortedKeys(sources)
+	return report
+}
+
+// scannedLockfiles lists the scanned lockfile first, then the lockfiles found inside it (archive
+// members) that findings came from
+func scannedLockfiles(results []types.ScanResult, config OutputConfig) []string {
+	members := make(map[string]bool)
+	for _, result := range results {
+		for _, instance := range result.Instances {
+			if instance.Lockfile != "" && instance.Lockfile != config.Lockfile {
+				members[instance.Lockfile] = true
+			}
+		}
+	}
+
+	var lockfiles []string
+	if config.Lockfile != "" {
+		lockfiles = append(lockfiles, config.Lockfile)
+	}
+	return append(lockfiles, sortedKeys(members)...)
+}
+
+// sortedKeys returns the keys of a set in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
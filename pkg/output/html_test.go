@@ -0,0 +1,90 @@
+package output
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+func TestRenderHTML(t *testing.T) {
+	results := []types.ScanResult{
+		{
+			Package: types.PackageQuery{Name: "lodash", Version: "4.17.20", Severity: types.SeverityHigh, Advisory: "GHSA-35jh-r3h4-6jhm", Note: "Command injection", Source: "badpak.json"},
+			Found:   true,
+			Instances: []types.PackageInstance{
+				{Name: "lodash", Version: "4.17.20", Path: "node_modules/lodash", LineNumber: 12, HasInstallScript: true},
+				{Name: "lodash", Version: "4.17.20", Path: "node_modules/a/node_modules/lodash", LineNumber: 40, IsDev: true, Suppressed: true, SuppressedReason: "not reachable"},
+			},
+		},
+		{Package: types.PackageQuery{Name: "left-pad", Version: "1.3.0", Source: "cli"}},
+		{Package: types.PackageQuery{Name: "chalk", Tag: "latest", Error: "registry unreachable", Source: "cli"}},
+		{
+			Package:   types.PackageQuery{Name: "<script>", Sources: []string{"builtin", "https://example.com/bad.json"}},
+			Found:     true,
+			Instances: []types.PackageInstance{{Name: "<script>", Version: "1.0.0", Path: "node_modules/<script>", Lockfile: "web/package-lock.json"}},
+		},
+		{
+			Package:   types.PackageQuery{Name: "lodahs"},
+			Found:     true,
+			Check:     types.CheckTyposquat,
+			Instances: []types.PackageInstance{{Name: "lodahs", Version: "1.0.0", Path: "node_modules/lodahs", Resembles: "lodash", Distance: 1}},
+		},
+	}
+	config := OutputConfig{ToolVersion: "1.2.3", Lockfile: "package-lock.json", Warnings: []string{"--scan-scripts found no script bodies to check"}}
+
+	var got bytes.Buffer
+	if err := renderHTML(&got, buildHTMLReport(results, config, time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC))); err != nil {
+		t.Fatalf("renderHTML() error = %v", err)
+	}
+	if strings.Contains(got.String(), "<script>\"") || strings.Contains(got.String(), "node_modules/<script>") {
+		t.Error("renderHTML() didn't escape a package name")
+	}
+
+	golden := filepath.Join("testdata", "report.golden.html")
+	if *update {
+		if err := os.WriteFile(golden, got.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("reading golden file: %v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("renderHTML() differs from %s; run go test -update and review the diff", golden)
+	}
+}
+
+func TestBuildHTMLReport(t *testing.T) {
+	results := []types.ScanResult{
+		{
+			Package:   types.PackageQuery{Name: "lodash", Version: "4.17.20", Source: "b.json", Sources: []string{"a.json", "b.json"}},
+			Found:     true,
+			Instances: []types.PackageInstance{{Name: "lodash", Version: "4.17.20", Path: "node_modules/lodash", Lockfile: "z/package-lock.json"}},
+		},
+		{
+			Package:   types.PackageQuery{Name: "qs"},
+			Found:     true,
+			Instances: []types.PackageInstance{{Name: "qs", Version: "1.0.0", Path: "node_modules/qs"}},
+		},
+	}
+
+	report := buildHTMLReport(results, OutputConfig{Lockfile: "repo.zip"}, time.Time{})
+	if got := strings.Join(report.Lockfiles, ","); got != "repo.zip,z/package-lock.json" {
+		t.Errorf("Lockfiles = %s, want the scanned file first", got)
+	}
+	if got := strings.Join(report.Sources, ","); got != "a.json,b.json" {
+		t.Errorf("Sources = %s, want a.json,b.json", got)
+	}
+	if report.Totals.Risks != 2 || report.Findings[1].Severity != "unknown" || report.Findings[1].Lockfile != "repo.zip" {
+		t.Errorf("report = %+v, want 2 risks, the second of unknown severity in repo.zip", report)
+	}
+}
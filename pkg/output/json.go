@@ -0,0 +1,90 @@
+package output
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+// JSONSchemaVersion is the version of the JSON report's shape. Bump it, and update
+// report.schema.json, whenever a field is added, removed, renamed or changes type.
+const JSONSchemaVersion = 8
+
+// JSONSchema is the JSON Schema of the current JSON report
+//
+//go:embed report.schema.json
+var JSONSchema []byte
+
+// jsonReport is the envelope of the JSON output
+type jsonReport struct {
+	SchemaVersion int                `json:"schemaVersion"`
+	Tool          jsonTool           `json:"tool"`
+	ScannedAt     string             `json:"scannedAt"` // RFC 3339
+	Lockfiles     []string           `json:"lockfiles"`
+	Results       []types.ScanResult `json:"results"`
+	Summary       Totals             `json:"summary"`
+	Warnings      []string           `json:"warnings"`
+}
+
+type jsonTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+}
+
+// OutputJSON writes results to w as a versioned JSON report (see JSONSchema), or only its
+// summary object with config.SummaryOnly. The scan time is reportTime.
+func OutputJSON(w io.Writer, results []types.ScanResult, config OutputConfig) error {
+	if config.SummaryOnly {
+		totals := CountResults(results)
+		totals.Interrupted = config.Interrupted
+		return writeJSON(w, "JSON", totals)
+	}
+	scannedAt, err := reportTime()
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, "JSON", buildJSONReport(withGroups(results, config), config, scannedAt))
+}
+
+// writeJSON writes a document to w as indented JSON; format names it in errors
+func writeJSON(w io.Writer, format string, document any) error {
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %v", format, err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// buildJSONReport wraps results in the report envelope; scannedAt is the time stamped on it
+func buildJSONReport(results []types.ScanResult, config OutputConfig, scannedAt time.Time) jsonReport {
+	report := jsonReport{
+		SchemaVersion: JSONSchemaVersion,
+		Tool:          jsonTool{Name: "scnpm", Version: config.ToolVersion, Commit: config.ToolCommit},
+		ScannedAt:     scannedAt.UTC().Format(time.RFC3339),
+		Lockfiles:     scannedLockfiles(results, config),
+		Results:       make([]types.ScanResult, len(results)),
+		Summary:       CountResults(results),
+		Warnings:      config.Warnings,
+	}
+	report.Summary.Interrupted = config.Interrupted
+	// Empty lists are [] rather than null, so consumers can iterate without checking
+	if report.Lockfiles == nil {
+		report.Lockfiles = []string{}
+	}
+	if report.Warnings == nil {
+		report.Warnings = []string{}
+	}
+	for i, result := range results {
+		if result.Instances == nil {
+			result.Instances = []types.PackageInstance{}
+		}
+		report.Results[i] = result
+	}
+	return report
+}
@@ -0,0 +1,229 @@
+package output
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+// reportShapes records a fingerprint of the JSON report's Go types for each schema version. When
+// TestJSONSchemaVersion fails, the shape changed: bump JSONSchemaVersion, update
+// report.schema.json and add the new fingerprint here.
+var reportShapes = map[int]string{
+	1: "ee6dd12ca03e5e5dd45660ee0456307d",
+	2: "9d5b6d36313713a5c283382f5059f0b6",
+	3: "c3fc36283c7b37844ef4d88067a4449e",
+	4: "7c1eb33d7f24484e905655308bf2b176",
+	5: "3a8cec2719e40e1c86e11ada98d14e59",
+	6: "8f38a5dcb7be1eb90b0a4c6777d6a09f",
+	7: "1b877197e54a4ce171e479858cfa601d",
+	8: "e4f6bc4f47b054a7cf1a22941cbec512",
+}
+
+// checkSchema reports where value doesn't conform to schema, a part of report.schema.json
+func checkSchema(t *testing.T, path string, schema map[string]any, value any) {
+	t.Helper()
+	v := &schemaValidator{root: loadSchema(t)}
+	v.validate(path, schema, value)
+	for _, err := range v.errors {
+		t.Error(err)
+	}
+}
+
+func loadSchema(t *testing.T) map[string]any {
+	t.Helper()
+	var schema map[string]any
+	if err := json.Unmarshal(JSONSchema, &schema); err != nil {
+		t.Fatalf("report.schema.json: %v", err)
+	}
+	return schema
+}
+
+func TestBuildJSONReport(t *testing.T) {
+	results := []types.ScanResult{
+		{
+			Package: types.PackageQuery{Name: "lodash", Version: "4.17.20", Severity: types.SeverityHigh, Advisory: "GHSA-35jh-r3h4-6jhm", Sources: []string{"builtin"}},
+			Found:   true,
+			Instances: []types.PackageInstance{
+				{Name: "lodash", Version: "4.17.20", Path: "node_modules/lodash", LineNumber: 12, MatchReason: "exact", InstallSource: types.SourceRegistry},
+				{Name: "lodash", Version: "4.17.20", Path: "node_modules/lodash", Lockfile: "web/package-lock.json", Suppressed: true, SuppressedReason: "accepted"},
+			},
+			TotalInstances: 2,
+			Remediation:    &types.Remediation{Investigate: "npm ls lodash", SafeVersion: "4.17.21", Overrides: map[string]string{"lodash": ">=4.17.21"}},
+		},
+		{Package: types.PackageQuery{Name: "left-pad", Version: "1.3.0"}},
+		{Package: types.PackageQuery{Name: "chalk", Tag: "latest", Error: "registry unreachable"}},
+		{
+			Package:   types.PackageQuery{Name: "lodahs"},
+			Found:     true,
+			Check:     types.CheckTyposquat,
+			Instances: []types.PackageInstance{{Name: "lodahs", Version: "1.0.0", Path: "node_modules/lodahs", Resembles: "lodash", Distance: 1}},
+		},
+	}
+	config := OutputConfig{ToolVersion: "1.2.3", ToolCommit: "abc1234", Lockfile: "repo.zip"}
+
+	report := buildJSONReport(results, config, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatal(err)
+	}
+	schema := loadSchema(t)
+	checkSchema(t, "report", schema, document)
+
+	if report.SchemaVersion != JSONSchemaVersion || report.Tool.Commit != "abc1234" || report.ScannedAt != "2024-05-01T12:00:00Z" {
+		t.Errorf("envelope = %+v, want the schema version, build commit and scan time", report)
+	}
+	if got := strings.Join(report.Lockfiles, ","); got != "repo.zip,web/package-lock.json" {
+		t.Errorf("Lockfiles = %s, want the archive and its member", got)
+	}
+	if report.Summary.Risks != 1 || report.Summary.Safe != 1 || report.Summary.Errors != 1 || report.Summary.Checks[types.CheckTyposquat] != 1 {
+		t.Errorf("Summary = %+v, want 1 risk, 1 safe, 1 error and 1 typosquat", report.Summary)
+	}
+	if !strings.Contains(string(data), `"instances":[]`) || !strings.Contains(string(data), `"warnings":[]`) {
+		t.Error("empty lists should be [] rather than null")
+	}
+}
+
+func TestOutputJSONSummaryOnly(t *testing.T) {
+	results := []types.ScanResult{
+		{Package: types.PackageQuery{Name: "lodash", Version: "4.17.20", Severity: types.SeverityHigh}, Found: true, Instances: []types.PackageInstance{{Name: "lodash", Version: "4.17.20"}}},
+		{Package: types.PackageQuery{Name: "left-pad", Version: "1.3.0"}},
+	}
+	var buf bytes.Buffer
+	if err := OutputJSON(&buf, results, OutputConfig{SummaryOnly: true}); err != nil {
+		t.Fatalf("OutputJSON() error = %v", err)
+	}
+	var summary Totals
+	if err := json.Unmarshal(buf.Bytes(), &summary); err != nil {
+		t.Fatalf("OutputJSON() = %s, want the summary object: %v", buf.String(), err)
+	}
+	if summary.Risks != 1 || summary.Safe != 1 || summary.RisksBySeverity[types.SeverityHigh] != 1 {
+		t.Errorf("OutputJSON() = %s, want 1 high risk and 1 safe package", buf.String())
+	}
+	if strings.Contains(buf.String(), "lodash") || strings.Contains(buf.String(), "interrupted") {
+		t.Errorf("OutputJSON() = %s, want no results and no interrupted marker", buf.String())
+	}
+
+	buf.Reset()
+	if err := OutputJSON(&buf, results, OutputConfig{SummaryOnly: true, Interrupted: true}); err != nil {
+		t.Fatalf("OutputJSON() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `"interrupted": true`) {
+		t.Errorf("OutputJSON() of an interrupted scan = %s, want it marked interrupted", buf.String())
+	}
+}
+
+// jsonFields returns the JSON names of a struct's fields, and which of them are always present
+func jsonFields(typ reflect.Type) (names, required []string) {
+	for i := 0; i < typ.NumField(); i++ {
+		tag := typ.Field(i).Tag.Get("json")
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			name = typ.Field(i).Name
+		}
+		names = append(names, name)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(names)
+	sort.Strings(required)
+	return names, required
+}
+
+// schemaFields returns the property names of a schema object, and its required ones
+func schemaFields(object map[string]any) (names, required []string) {
+	for name := range object["properties"].(map[string]any) {
+		names = append(names, name)
+	}
+	for _, name := range objects(object["required"]) {
+		required = append(required, name.(string))
+	}
+	sort.Strings(names)
+	sort.Strings(required)
+	return names, required
+}
+
+func TestJSONSchemaMatchesTypes(t *testing.T) {
+	schema := loadSchema(t)
+	defs := schema["$defs"].(map[string]any)
+	tests := []struct {
+		typ    reflect.Type
+		object map[string]any
+	}{
+		{reflect.TypeOf(jsonReport{}), schema},
+		{reflect.TypeOf(jsonTool{}), schema["properties"].(map[string]any)["tool"].(map[string]any)},
+		{reflect.TypeOf(types.ScanResult{}), defs["result"].(map[string]any)},
+		{reflect.TypeOf(types.PackageQuery{}), defs["query"].(map[string]any)},
+		{reflect.TypeOf(types.PackageInstance{}), defs["instance"].(map[string]any)},
+		{reflect.TypeOf(types.Remediation{}), defs["remediation"].(map[string]any)},
+		{reflect.TypeOf(Totals{}), defs["summary"].(map[string]any)},
+	}
+
+	for _, tt := range tests {
+		names, required := jsonFields(tt.typ)
+		schemaNames, schemaRequired := schemaFields(tt.object)
+		if !reflect.DeepEqual(names, schemaNames) {
+			t.Errorf("%s fields = %v, schema has %v", tt.typ.Name(), names, schemaNames)
+		}
+		if !reflect.DeepEqual(required, schemaRequired) {
+			t.Errorf("%s always-present fields = %v, schema requires %v", tt.typ.Name(), required, schemaRequired)
+		}
+	}
+
+	if version := schema["properties"].(map[string]any)["schemaVersion"].(map[string]any)["const"]; version != float64(JSONSchemaVersion) {
+		t.Errorf("schema is for version %v, want %d", version, JSONSchemaVersion)
+	}
+	for _, check := range types.Checks {
+		found := false
+		for _, allowed := range objects(defs["result"].(map[string]any)["properties"].(map[string]any)["check"].(map[string]any)["enum"]) {
+			found = found || allowed == check
+		}
+		if !found {
+			t.Errorf("schema doesn't list the %s check", check)
+		}
+	}
+}
+
+// shape describes the JSON names and kinds of a struct's fields, recursively; Go type names
+// don't matter
+func shape(typ reflect.Type, seen map[reflect.Type]bool) string {
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.Slice:
+		return "[" + shape(typ.Elem(), seen) + "]"
+	case reflect.Map:
+		return "{" + shape(typ.Key(), seen) + ":" + shape(typ.Elem(), seen) + "}"
+	case reflect.Struct:
+		if seen[typ] {
+			return typ.Name()
+		}
+		seen[typ] = true
+		var fields []string
+		for i := 0; i < typ.NumField(); i++ {
+			fields = append(fields, typ.Field(i).Tag.Get("json")+"="+shape(typ.Field(i).Type, seen))
+		}
+		return "(" + strings.Join(fields, ";") + ")"
+	default:
+		return typ.Kind().String()
+	}
+}
+
+func TestJSONSchemaVersion(t *testing.T) {
+	sum := sha256.Sum256([]byte(shape(reflect.TypeOf(jsonReport{}), map[reflect.Type]bool{})))
+	got := hex.EncodeToString(sum[:16])
+	if want := reportShapes[JSONSchemaVersion]; got != want {
+		t.Errorf("the JSON report's shape is %s, but version %d recorded %s: bump JSONSchemaVersion, update report.schema.json and record the new shape in reportShapes", got, JSONSchemaVersion, want)
+	}
+}
@@ -0,0 +1,80 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"scnpm/pkg/scanner"
+)
+
+// LicenseFormats lists the output formats of scnpm licenses
+var LicenseFormats = []string{"table", "json", "csv"}
+
+// licensesDocument is the JSON form of scnpm licenses
+type licensesDocument struct {
+	Lockfile string `json:"lockfile,omitempty"`
+	scanner.LicenseInventory
+}
+
+// OutputLicenses writes a license inventory as a table: each license with its count and packages,
+// most used first, then a summary. Denied and unknown licenses are marked.
+func OutputLicenses(out io.Writer, inventory scanner.LicenseInventory, config OutputConfig) error {
+	w := &errWriter{w: out}
+	fmt.Fprintf(w, "%s %s %s\n", padRight("License", 40), padRight("Count", 6), "Packages")
+	fmt.Fprintln(w, strings.Repeat("-", 120))
+	for _, count := range inventory.Licenses {
+		label, color := count.License, ""
+		switch {
+		case count.Denied:
+			label, color = "🚨 "+label+" (denied)", colorRed
+		case count.Unknown:
+			label, color = "⚠️ "+label, colorYellow
+		}
+		packages := make([]string, 0, len(count.Packages))
+		for _, pkg := range count.Packages {
+			entry := pkg.Name + "@" + pkg.Version
+			if pkg.Dev {
+				entry += " (dev)"
+			}
+			packages = append(packages, entry)
+		}
+		fmt.Fprintf(w, "%s %s %s\n", colorize(padRight(plain(label, config), 40), color, config), padRight(strconv.Itoa(count.Count), 6), strings.Join(packages, ", "))
+	}
+
+	fmt.Fprintln(w, strings.Repeat("=", 120))
+	summary := fmt.Sprintf("LICENSE SUMMARY: %d PACKAGES UNDER %d LICENSES", inventory.Packages, len(inventory.Licenses))
+	if inventory.Denied > 0 {
+		summary += fmt.Sprintf(" | 🚨 %d DENIED", inventory.Denied)
+	}
+	if inventory.Unknown > 0 {
+		summary += fmt.Sprintf(" | ⚠️ %d UNKNOWN", inventory.Unknown)
+	}
+	verdict := ""
+	if inventory.Denied > 0 {
+		verdict = colorRed
+	}
+	fmt.Fprintln(w, colorize(plain(summary, config), verdict, config))
+	return w.err
+}
+
+// OutputLicensesJSON writes the license inventory of lockfile as JSON
+func OutputLicensesJSON(w io.Writer, inventory scanner.LicenseInventory, lockfile string) error {
+	return writeJSON(w, "JSON", licensesDocument{Lockfile: lockfile, LicenseInventory: inventory})
+}
+
+// OutputLicensesCSV writes a license inventory as CSV with a header row, one row per package
+func OutputLicensesCSV(out io.Writer, inventory scanner.LicenseInventory) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"license", "name", "version", "dev", "denied", "unknown"})
+	for _, count := range inventory.Licenses {
+		for _, pkg := range count.Packages {
+			w.Write([]string{count.License, pkg.Name, pkg.Version, strconv.FormatBool(pkg.Dev),
+				strconv.FormatBool(count.Denied), strconv.FormatBool(count.Unknown)})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,46 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"scnpm/pkg/scanner"
+)
+
+func TestOutputLicenses(t *testing.T) {
+	inventory := scanner.LicenseInventory{Packages: 4, Unknown: 1, Denied: 1, Licenses: []scanner.LicenseCount{
+		{License: "MIT", Count: 2, Packages: []scanner.LicensedPackage{{Name: "debug", Version: "4.3.4"}, {Name: "ms", Version: "2.1.3", Dev: true}}},
+		{License: "GPL-3.0-only", Count: 1, Denied: true, Packages: []scanner.LicensedPackage{{Name: "gpl", Version: "1.0.0"}}},
+		{License: scanner.UnknownLicense, Count: 1, Unknown: true, Packages: []scanner.LicensedPackage{{Name: "none", Version: "0.1.0"}}},
+	}}
+
+	var buf bytes.Buffer
+	if err := OutputLicenses(&buf, inventory, OutputConfig{ASCII: true}); err != nil {
+		t.Fatalf("OutputLicenses() error = %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"MIT" + strings.Repeat(" ", 38) + "2      debug@4.3.4, ms@2.1.3 (dev)\n",
+		"GPL-3.0-only (denied)" + strings.Repeat(" ", 20) + "1      gpl@1.0.0\n",
+		"UNKNOWN" + strings.Repeat(" ", 34) + "1      none@0.1.0\n",
+		"LICENSE SUMMARY: 4 PACKAGES UNDER 3 LICENSES | 1 DENIED | 1 UNKNOWN\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("OutputLicenses() = %q, want it to contain %q", got, want)
+		}
+	}
+
+	buf.Reset()
+	if err := OutputLicensesCSV(&buf, inventory); err != nil {
+		t.Fatal(err)
+	}
+	want := "license,name,version,dev,denied,unknown\n" +
+		"MIT,debug,4.3.4,false,false,false\n" +
+		"MIT,ms,2.1.3,true,false,false\n" +
+		"GPL-3.0-only,gpl,1.0.0,false,true,false\n" +
+		"UNKNOWN,none,0.1.0,false,false,true\n"
+	if buf.String() != want {
+		t.Errorf("OutputLicensesCSV() = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,99 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"scnpm/pkg/types"
+)
+
+// ListFormats lists the output formats of scnpm list
+var ListFormats = []string{"table", "json", "csv"}
+
+// listEntry is one installed package of scnpm list
+type listEntry struct {
+	Name     string `json:"name"`
+	Alias    string `json:"alias,omitempty"`
+	Version  string `json:"version"`
+	Path     string `json:"path"`
+	Dev      bool   `json:"dev"`
+	Depth    int    `json:"depth"`
+	Host     string `json:"host,omitempty"` // Host the package was resolved from, or its install source when that isn't a URL
+	License  string `json:"license,omitempty"`
+	Resolved string `json:"resolved,omitempty"`
+}
+
+// listDocument is the JSON form of scnpm list
+type listDocument struct {
+	Lockfile string      `json:"lockfile,omitempty"`
+	Total    int         `json:"total"`
+	Packages []listEntry `json:"packages"`
+}
+
+// OutputList writes the installed packages of a lockfile as a table, ending with their count
+func OutputList(out io.Writer, instances []types.PackageInstance) error {
+	w := &errWriter{w: out}
+	fmt.Fprintf(w, "%s %s %s %s %s %s %s\n", padRight("Package", 30), padRight("Version", 15), padRight("Dev", 5),
+		padRight("Depth", 6), padRight("Host", 25), padRight("License", 15), "Path")
+	fmt.Fprintln(w, strings.Repeat("-", 120))
+	for _, entry := range listEntries(instances) {
+		dev := ""
+		if entry.Dev {
+			dev = "yes"
+		}
+		fmt.Fprintf(w, "%s %s %s %s %s %s %s\n", padRight(entry.Name, 30), padRight(entry.Version, 15), padRight(dev, 5),
+			padRight(strconv.Itoa(entry.Depth), 6), padRight(entry.Host, 25), padRight(entry.License, 15), entry.Path)
+	}
+	fmt.Fprintln(w, strings.Repeat("=", 120))
+	fmt.Fprintf(w, "%d packages installed\n", len(instances))
+	return w.err
+}
+
+// OutputListJSON writes the installed packages of lockfile as JSON
+func OutputListJSON(w io.Writer, instances []types.PackageInstance, lockfile string) error {
+	return writeJSON(w, "JSON", listDocument{Lockfile: lockfile, Total: len(instances), Packages: listEntries(instances)})
+}
+
+// OutputListCSV writes the installed packages of a lockfile as CSV with a header row
+func OutputListCSV(out io.Writer, instances []types.PackageInstance) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"name", "alias", "version", "path", "dev", "depth", "host", "license", "resolved"})
+	for _, entry := range listEntries(instances) {
+		w.Write([]string{entry.Name, entry.Alias, entry.Version, entry.Path, strconv.FormatBool(entry.Dev),
+			strconv.Itoa(entry.Depth), entry.Host, entry.License, entry.Resolved})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// listEntries converts installed instances into list entries
+func listEntries(instances []types.PackageInstance) []listEntry {
+	entries := make([]listEntry, 0, len(instances))
+	for _, instance := range instances {
+		entries = append(entries, listEntry{
+			Name:     instance.Name,
+			Alias:    instance.Alias,
+			Version:  instance.Version,
+			Path:     instance.Path,
+			Dev:      instance.IsDev,
+			Depth:    instance.Depth,
+			Host:     resolvedHost(instance),
+			License:  instance.License,
+			Resolved: instance.Resolved,
+		})
+	}
+	return entries
+}
+
+// resolvedHost returns the host an instance was downloaded from, or its install source (file,
+// link, git) when it wasn't resolved to a URL
+func resolvedHost(instance types.PackageInstance) string {
+	if u, err := url.Parse(instance.Resolved); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return instance.InstallSource
+}
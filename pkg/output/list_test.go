@@ -0,0 +1,47 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestOutputList(t *testing.T) {
+	instances := []types.PackageInstance{
+		{Name: "debug", Version: "4.3.4", Path: "node_modules/debug", Resolved: "https://registry.npmjs.org/debug/-/debug-4.3.4.tgz", License: "MIT", InstallSource: types.SourceRegistry},
+		{Name: "ms", Version: "2.1.2", Path: "node_modules/debug/node_modules/ms", IsDev: true, IsNested: true, Depth: 1, InstallSource: types.SourceRegistry},
+		{Name: "vendor", Version: "1.0.0", Path: "node_modules/vendor", Resolved: "file:vendor/vendor-1.0.0.tgz", InstallSource: types.SourceFile},
+	}
+
+	var table bytes.Buffer
+	if err := OutputList(&table, instances); err != nil {
+		t.Fatalf("OutputList() error = %v", err)
+	}
+	if !strings.Contains(table.String(), "registry.npmjs.org") || !strings.Contains(table.String(), "3 packages installed") {
+		t.Errorf("OutputList() = %q, want the resolved host and the count", table.String())
+	}
+
+	var doc bytes.Buffer
+	if err := OutputListJSON(&doc, instances, "package-lock.json"); err != nil {
+		t.Fatalf("OutputListJSON() error = %v", err)
+	}
+	var got listDocument
+	if err := json.Unmarshal(doc.Bytes(), &got); err != nil {
+		t.Fatalf("list isn't JSON: %v\n%s", err, doc.String())
+	}
+	if got.Total != 3 || got.Packages[0].Host != "registry.npmjs.org" || !got.Packages[1].Dev || got.Packages[2].Host != types.SourceFile {
+		t.Errorf("OutputListJSON() = %+v, want 3 packages with their hosts and dev flags", got)
+	}
+
+	var csv bytes.Buffer
+	if err := OutputListCSV(&csv, instances); err != nil {
+		t.Fatalf("OutputListCSV() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 4 || lines[2] != "ms,,2.1.2,node_modules/debug/node_modules/ms,true,1,registry,," {
+		t.Errorf("OutputListCSV() = %q, want a header and a row per package", csv.String())
+	}
+}
@@ -0,0 +1,115 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+// Report is a JSON report read back, for rendering it again or merging it with others
+type Report struct {
+	SchemaVersion int // The version it was written with; it's upgraded to JSONSchemaVersion when read
+	ToolVersion   string
+	ToolCommit    string
+	ScannedAt     time.Time
+	Lockfiles     []string
+	Results       []types.ScanResult
+	Warnings      []string
+}
+
+// ReadReport reads a JSON report (--output json) of any schema version. Reports of older versions
+// are upgraded to the current shape first; then the report must conform to JSONSchema.
+func ReadReport(data []byte) (Report, error) {
+	upgraded, version, err := upgradeReport(data)
+	if err != nil {
+		return Report{}, err
+	}
+	if err := ValidateReport(upgraded); err != nil {
+		return Report{}, err
+	}
+	var document jsonReport
+	if err := json.Unmarshal(upgraded, &document); err != nil {
+		return Report{}, err
+	}
+	scannedAt, err := time.Parse(time.RFC3339, document.ScannedAt)
+	if err != nil {
+		return Report{}, err
+	}
+	return Report{
+		SchemaVersion: version,
+		ToolVersion:   document.Tool.Version,
+		ToolCommit:    document.Tool.Commit,
+		ScannedAt:     scannedAt,
+		Lockfiles:     document.Lockfiles,
+		Results:       document.Results,
+		Warnings:      document.Warnings,
+	}, nil
+}
+
+// upgradeReport rewrites a JSON report of an older schema version in the current one, returning
+// it with the version it was written in
+func upgradeReport(data []byte) ([]byte, int, error) {
+	var document map[string]any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, 0, fmt.Errorf("not a JSON report: %v", err)
+	}
+	number, ok := document["schemaVersion"].(float64)
+	if !ok {
+		return nil, 0, fmt.Errorf("not a scnpm JSON report: it has no schemaVersion")
+	}
+	version := int(number)
+	switch {
+	case float64(version) != number || version < 1:
+		return nil, 0, fmt.Errorf("invalid schemaVersion %v", number)
+	case version > JSONSchemaVersion:
+		return nil, 0, fmt.Errorf("schema version %d is newer than this scnpm supports (%d); upgrade scnpm", version, JSONSchemaVersion)
+	case version == JSONSchemaVersion:
+		return data, version, nil
+	}
+
+	// Version 5 added the summary's baseline count
+	if summary, ok := document["summary"].(map[string]any); ok && version < 5 {
+		summary["baseline"] = 0
+	}
+	// Version 6 made referencedBy a list, as references to one instance were folded into it
+	results, _ := document["results"].([]any)
+	for _, result := range results {
+		result, _ := result.(map[string]any)
+		instances, _ := result["instances"].([]any)
+		for _, instance := range instances {
+			instance, _ := instance.(map[string]any)
+			if referencedBy, ok := instance["referencedBy"].(string); ok && version < 6 {
+				if referencedBy == "" {
+					delete(instance, "referencedBy")
+				} else {
+					instance["referencedBy"] = []string{referencedBy}
+				}
+			}
+		}
+	}
+	// Version 7 only added instance.declared, and version 8 summary.interrupted
+	document["schemaVersion"] = JSONSchemaVersion
+	upgraded, err := json.Marshal(document)
+	if err != nil {
+		return nil, 0, err
+	}
+	return upgraded, version, nil
+}
+
+// OutputReportJSON writes a report read back or merged as a JSON report of the current schema
+// version, keeping its scan time and lockfiles
+func OutputReportJSON(w io.Writer, report Report, config OutputConfig) error {
+	config.Lockfile = ""
+	config.Warnings = report.Warnings
+	document := buildJSONReport(report.Results, config, report.ScannedAt)
+	document.Lockfiles = append([]string{}, report.Lockfiles...)
+	return writeJSON(w, "JSON", document)
+}
+
+// OutputReportHTML writes a report read back as an HTML report stamped with its scan time
+func OutputReportHTML(w io.Writer, report Report, config OutputConfig) error {
+	return renderHTML(w, buildHTMLReport(report.Results, config, report.ScannedAt))
+}
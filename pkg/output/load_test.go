@@ -0,0 +1,76 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+func TestReadReport(t *testing.T) {
+	scannedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	results := []types.ScanResult{{
+		Package:        types.PackageQuery{Name: "evil", Version: "1.0.0", Severity: types.SeverityHigh},
+		Found:          true,
+		TotalInstances: 1,
+		Instances:      []types.PackageInstance{{Name: "evil", Version: "1.0.0", Path: "node_modules/evil", ReferencedBy: []string{"(root)"}}},
+	}}
+	current := reportFile(t, "current.json", "package-lock.json", scannedAt, results, "a warning")
+
+	report, err := ReadReport(current.Data)
+	if err != nil {
+		t.Fatalf("ReadReport() error = %v", err)
+	}
+	if report.SchemaVersion != JSONSchemaVersion || report.ToolVersion != "1.0.0" || !report.ScannedAt.Equal(scannedAt) ||
+		len(report.Lockfiles) != 1 || len(report.Warnings) != 1 || len(report.Results) != 1 {
+		t.Errorf("ReadReport() = %+v, want the report as written", report)
+	}
+
+	// A version 5 report: referencedBy was a single path, and an empty one meant none
+	old := strings.Replace(string(current.Data), fmt.Sprintf(`"schemaVersion":%d`, JSONSchemaVersion), `"schemaVersion":5`, 1)
+	old = strings.Replace(old, `"referencedBy":["(root)"]`, `"referencedBy":"(root)"`, 1)
+	if old == string(current.Data) || !strings.Contains(old, `"referencedBy":"(root)"`) {
+		t.Fatal("couldn't rewrite the report as version 5")
+	}
+	report, err = ReadReport([]byte(old))
+	if err != nil {
+		t.Fatalf("ReadReport(version 5) error = %v", err)
+	}
+	if instance := report.Results[0].Instances[0]; report.SchemaVersion != 5 || len(instance.ReferencedBy) != 1 || instance.ReferencedBy[0] != "(root)" {
+		t.Errorf("ReadReport(version 5) = version %d, referencedBy %q, want version 5 upgraded to a list", report.SchemaVersion, instance.ReferencedBy)
+	}
+
+	// A version 4 report has no baseline count in its summary either
+	older := strings.Replace(old, `"schemaVersion":5`, `"schemaVersion":4`, 1)
+	older = strings.Replace(older, `"baseline":0,`, ``, 1)
+	older = strings.Replace(older, `,"baseline":0`, ``, 1)
+	if _, err := ReadReport([]byte(older)); err != nil {
+		t.Errorf("ReadReport(version 4) error = %v", err)
+	}
+
+	// Rendered again, the report keeps its scan time and validates as the current version
+	var buf bytes.Buffer
+	if err := OutputReportJSON(&buf, report, OutputConfig{ToolVersion: "1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateReport(buf.Bytes()); err != nil || !strings.Contains(buf.String(), `"scannedAt": "2024-05-01T12:00:00Z"`) {
+		t.Errorf("OutputReportJSON() = %s, %v, want a current report scanned at the saved time", buf.String(), err)
+	}
+}
+
+func TestReadReportInvalid(t *testing.T) {
+	for data, want := range map[string]string{
+		`not json`:                             "not a JSON report",
+		`{"results": []}`:                      "no schemaVersion",
+		`{"schemaVersion": 1.5}`:               "invalid schemaVersion",
+		`{"schemaVersion": 99, "results": []}`: "newer than this scnpm supports",
+		`{"schemaVersion": 5, "results": []}`:  "doesn't match the report schema",
+	} {
+		if _, err := ReadReport([]byte(data)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ReadReport(%s) error = %v, want %q", data, err, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"scnpm/pkg/types"
+)
+
+// MergeFormats are the formats scnpm merge writes
+var MergeFormats = []string{"json", "markdown", "table"}
+
+// ReportFile is a JSON report to merge and where it was read from
+type ReportFile struct {
+	Name string
+	Data []byte
+}
+
+// MergedReport is several JSON reports rolled into one. Its Lockfiles are every lockfile the
+// inputs scanned, in input order, ScannedAt is when the most recent input was scanned and each
+// warning is prefixed with its input.
+type MergedReport struct {
+	Report
+	Duplicates int // Findings dropped because an earlier input reported them too
+}
+
+// MergeReports reads JSON reports of any schema version (see ReadReport) and combines them. Results for the same
+// query or check are merged, their sources combined, and every finding is attributed to the
+// lockfile it was found in, so that findings several inputs share, such as those of a lockfile
+// scanned twice, are kept once. Results stay in the order the inputs first report them.
+func MergeReports(files []ReportFile) (MergedReport, error) {
+	var merged MergedReport
+	index := make(map[string]int)
+	seen := make(map[string]map[string]bool) // Findings of each result, by their JSON
+	owners := make(map[string]string)        // The first input to scan each lockfile
+	reported := make(map[string]bool)        // Warnings, to drop repeats
+	collisions := make(map[string][]string)  // Inputs sharing a lockfile after the first
+
+	for _, file := range files {
+		report, err := ReadReport(file.Data)
+		if err != nil {
+			return MergedReport{}, fmt.Errorf("%s: %v", file.Name, err)
+		}
+		if report.ScannedAt.After(merged.ScannedAt) {
+			merged.ScannedAt = report.ScannedAt
+		}
+		for _, lockfile := range report.Lockfiles {
+			if owner, ok := owners[lockfile]; !ok {
+				owners[lockfile] = file.Name
+				merged.Lockfiles = append(merged.Lockfiles, lockfile)
+			} else if owner != file.Name {
+				collisions[lockfile] = append(collisions[lockfile], file.Name)
+			}
+		}
+		for _, warning := range report.Warnings {
+			warning = file.Name + ": " + warning
+			if !reported[warning] {
+				reported[warning] = true
+				merged.Warnings = append(merged.Warnings, warning)
+			}
+		}
+
+		// Findings of a single lockfile don't name it; the report's first lockfile is the one
+		// scanned
+		primary := ""
+		if len(report.Lockfiles) > 0 {
+			primary = report.Lockfiles[0]
+		}
+		for _, result := range report.Results {
+			for i := range result.Instances {
+				if result.Instances[i].Lockfile == "" {
+					result.Instances[i].Lockfile = primary
+				}
+			}
+			key := result.Check + "|" + result.Package.Name + "@" + result.Package.Version + "#" + result.Package.Integrity
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(merged.Results)
+				seen[key] = make(map[string]bool)
+				for _, instance := range result.Instances {
+					seen[key][instanceFingerprint(instance)] = true
+				}
+				merged.Results = append(merged.Results, result)
+				continue
+			}
+
+			existing := &merged.Results[i]
+			mergeQuery(&existing.Package, result.Package)
+			if existing.Package.Error != "" && result.Package.Error == "" {
+				// One input could check the query, so it's no longer an error
+				existing.Package.Error = ""
+			}
+			existing.Found = existing.Found || result.Found
+			if existing.Remediation == nil {
+				existing.Remediation = result.Remediation
+			}
+			added := 0
+			for _, instance := range result.Instances {
+				fingerprint := instanceFingerprint(instance)
+				if seen[key][fingerprint] {
+					merged.Duplicates++
+					continue
+				}
+				seen[key][fingerprint] = true
+				existing.Instances = append(existing.Instances, instance)
+				added++
+			}
+			existing.TotalInstances += result.TotalInstances - (len(result.Instances) - added)
+		}
+	}
+
+	for _, lockfile := range merged.Lockfiles {
+		if inputs := collisions[lockfile]; len(inputs) > 0 {
+			merged.Warnings = append(merged.Warnings, fmt.Sprintf("'%s' is in %s and %s; findings they share in it are reported once (scan with distinct --file paths to keep different lockfiles apart)",
+				lockfile, owners[lockfile], strings.Join(inputs, ", ")))
+		}
+	}
+	return merged, nil
+}
+
+// mergeQuery folds the metadata of another copy of a query into query: the higher severity,
+// missing advisory and note, and every source
+func mergeQuery(query *types.PackageQuery, other types.PackageQuery) {
+	if types.SeverityRank(other.Severity) > types.SeverityRank(query.Severity) {
+		query.Severity = other.Severity
+	}
+	if query.Advisory == "" {
+		query.Advisory = other.Advisory
+	}
+	if query.Note == "" {
+		query.Note = other.Note
+	}
+	for _, source := range other.Sources {
+		found := false
+		for _, s := range query.Sources {
+			found = found || s == source
+		}
+		if !found {
+			query.Sources = append(query.Sources, source)
+		}
+	}
+}
+
+// instanceFingerprint identifies a finding for de-duplication: identical findings have identical
+// JSON
+func instanceFingerprint(instance types.PackageInstance) string {
+	data, _ := json.Marshal(instance)
+	return string(data)
+}
@@ -0,0 +1,103 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+// reportFile renders results scanned in lockfile as a JSON report named name
+func reportFile(t *testing.T, name, lockfile string, scannedAt time.Time, results []types.ScanResult, warnings ...string) ReportFile {
+	t.Helper()
+	data, err := json.Marshal(buildJSONReport(results, OutputConfig{ToolVersion: "1.0.0", Lockfile: lockfile, Warnings: warnings}, scannedAt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ReportFile{Name: name, Data: data}
+}
+
+func TestMergeReports(t *testing.T) {
+	evil := func(source string, paths ...string) types.ScanResult {
+		result := types.ScanResult{Package: types.PackageQuery{Name: "evil", Version: "1.0.0", Severity: types.SeverityHigh, Sources: []string{source}}, Found: true, TotalInstances: len(paths)}
+		for _, path := range paths {
+			result.Instances = append(result.Instances, types.PackageInstance{Name: "evil", Version: "1.0.0", Path: path})
+		}
+		return result
+	}
+	safe := types.ScanResult{Package: types.PackageQuery{Name: "left-pad", Version: "1.3.0"}}
+	first := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	merged, err := MergeReports([]ReportFile{
+		reportFile(t, "web.json", "web/package-lock.json", first, []types.ScanResult{evil("badpak.json", "node_modules/evil"), safe}, "web is empty"),
+		reportFile(t, "api.json", "api/package-lock.json", first.Add(time.Hour), []types.ScanResult{safe, evil("osv", "node_modules/evil", "node_modules/x/node_modules/evil")}),
+		// The web lockfile again, as another job scanned it
+		reportFile(t, "web-again.json", "web/package-lock.json", first, []types.ScanResult{evil("badpak.json", "node_modules/evil")}),
+	})
+	if err != nil {
+		t.Fatalf("MergeReports() error = %v", err)
+	}
+
+	if len(merged.Results) != 2 || merged.Results[0].Package.Name != "evil" || merged.Results[1].Package.Name != "left-pad" {
+		t.Fatalf("Results = %+v, want evil then left-pad, in the order first reported", merged.Results)
+	}
+	result := merged.Results[0]
+	var locations []string
+	for _, instance := range result.Instances {
+		locations = append(locations, instance.Lockfile+":"+instance.Path)
+	}
+	want := "web/package-lock.json:node_modules/evil,api/package-lock.json:node_modules/evil,api/package-lock.json:node_modules/x/node_modules/evil"
+	if got := strings.Join(locations, ","); got != want || result.TotalInstances != 3 {
+		t.Errorf("instances = %s (%d total), want %s: each attributed to its lockfile, the shared one once", got, result.TotalInstances, want)
+	}
+	if strings.Join(result.Package.Sources, ",") != "badpak.json,osv" {
+		t.Errorf("Sources = %v, want both lists", result.Package.Sources)
+	}
+	if merged.Duplicates != 1 {
+		t.Errorf("Duplicates = %d, want 1", merged.Duplicates)
+	}
+	if strings.Join(merged.Lockfiles, ",") != "web/package-lock.json,api/package-lock.json" || !merged.ScannedAt.Equal(first.Add(time.Hour)) {
+		t.Errorf("Lockfiles = %v, ScannedAt = %v, want both lockfiles and the latest scan", merged.Lockfiles, merged.ScannedAt)
+	}
+	if len(merged.Warnings) != 2 || merged.Warnings[0] != "web.json: web is empty" || !strings.Contains(merged.Warnings[1], "web.json and web-again.json") {
+		t.Errorf("Warnings = %q, want the input's warning and the shared lockfile", merged.Warnings)
+	}
+
+	// The merged report is a report of its own
+	var buf bytes.Buffer
+	if err := OutputReportJSON(&buf, merged.Report, OutputConfig{ToolVersion: "1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateReport(buf.Bytes()); err != nil {
+		t.Errorf("merged report: %v", err)
+	}
+	var report jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Summary.Risks != 1 || report.Summary.Safe != 1 || report.ScannedAt != "2024-05-01T13:00:00Z" {
+		t.Errorf("merged summary = %+v at %s, want it counted again", report.Summary, report.ScannedAt)
+	}
+	again, err := MergeReports([]ReportFile{{Name: "merged.json", Data: buf.Bytes()}})
+	if err != nil || len(again.Results[0].Instances) != 3 {
+		t.Errorf("merging the merged report = %+v, %v, want the same findings", again.Results, err)
+	}
+}
+
+func TestMergeReportsInvalid(t *testing.T) {
+	valid := reportFile(t, "ok.json", "package-lock.json", time.Now(), nil)
+	for _, data := range []string{
+		`not json`,
+		`{"schemaVersion": 1, "results": []}`,
+		`{"schemaVersion": 99, "results": []}`,
+		strings.Replace(string(valid.Data), `"warnings":[]`, `"warnings":[], "extra": true`, 1),
+	} {
+		_, err := MergeReports([]ReportFile{valid, {Name: "bad.json", Data: []byte(data)}})
+		if err == nil || !strings.HasPrefix(err.Error(), "bad.json: ") {
+			t.Errorf("MergeReports(%.40s) error = %v, want one naming bad.json", data, err)
+		}
+	}
+}
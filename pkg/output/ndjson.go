@@ -0,0 +1,172 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+// ResultBatch is what scanning one lockfile produced, handed to OutputNDJSON as soon as the
+// lockfile is done
+type ResultBatch struct {
+	Lockfile string             // Lockfile the results came from; empty for a batch of warnings only
+	Results  []types.ScanResult // Query results (with patterns expanded) followed by check results
+	Warnings []string
+	// Interrupted marks the scan as cut short, so that the summary says its results are partial
+	Interrupted bool
+}
+
+// ndjsonRecord is a result or warning line of NDJSON output
+type ndjsonRecord struct {
+	Type     string            `json:"type"` // "result" or "warning"
+	Lockfile string            `json:"lockfile,omitempty"`
+	Result   *types.ScanResult `json:"result,omitempty"`
+	Message  string            `json:"message,omitempty"`
+}
+
+// ndjsonSummary is the last line of NDJSON output: the JSON report's envelope, without results
+type ndjsonSummary struct {
+	Type          string   `json:"type"` // "summary"
+	SchemaVersion int      `json:"schemaVersion"`
+	Tool          jsonTool `json:"tool"`
+	ScannedAt     string   `json:"scannedAt"`
+	Lockfiles     []string `json:"lockfiles"`
+	Summary       Totals   `json:"summary"`
+	Warnings      []string `json:"warnings"`
+}
+
+// streamedResult is what NDJSON output remembers of a result once it's written, to count it in
+// the summary without holding on to its instances
+type streamedResult struct {
+	result types.ScanResult // Without instances; Found if any lockfile had it
+	active int              // Instances that weren't suppressed, across lockfiles
+	fresh  int              // Active instances that aren't in the baseline either
+}
+
+// OutputNDJSON writes newline-delimited JSON to w as batches arrive, until batches is closed, and
+// returns the totals of all of them. It stops reading batches at the first error. The summary
+// is stamped with reportTime.
+func OutputNDJSON(w io.Writer, batches <-chan ResultBatch, config OutputConfig) (Totals, error) {
+	scannedAt, err := reportTime()
+	if err != nil {
+		return Totals{}, err
+	}
+	return writeNDJSON(w, batches, config, scannedAt)
+}
+
+// writeNDJSON writes a line per warning and per result with findings as soon as their batch
+// arrives. Once batches is closed, it writes the queries no lockfile had (errors always, safe
+// ones unless hidden), then a summary that counts each query once across lockfiles. With
+// config.SummaryOnly, only the summary is written.
+func writeNDJSON(w io.Writer, batches <-chan ResultBatch, config OutputConfig, scannedAt time.Time) (Totals, error) {
+	records := io.Writer(w)
+	if config.SummaryOnly {
+		records = io.Discard
+	}
+	encoder := json.NewEncoder(records)
+	summary := ndjsonSummary{
+		Type:          "summary",
+		SchemaVersion: JSONSchemaVersion,
+		Tool:          jsonTool{Name: "scnpm", Version: config.ToolVersion, Commit: config.ToolCommit},
+		ScannedAt:     scannedAt.UTC().Format(time.RFC3339),
+		Lockfiles:     []string{},
+		Warnings:      []string{},
+	}
+	if config.Lockfile != "" {
+		summary.Lockfiles = append(summary.Lockfiles, config.Lockfile)
+	}
+
+	warn := func(warnings []string) error {
+		for _, warning := range warnings {
+			if err := encoder.Encode(ndjsonRecord{Type: "warning", Message: warning}); err != nil {
+				return err
+			}
+			summary.Warnings = append(summary.Warnings, warning)
+		}
+		return nil
+	}
+	if err := warn(config.Warnings); err != nil {
+		return Totals{}, err
+	}
+
+	// The same query turns up in every lockfile's batch; results are keyed by query so that the
+	// summary counts it once
+	streamed := make(map[string]*streamedResult)
+	var order []string
+	foundPatterns := make(map[string]bool)
+	interrupted := config.Interrupted
+	for batch := range batches {
+		if err := warn(batch.Warnings); err != nil {
+			return Totals{}, err
+		}
+		interrupted = interrupted || batch.Interrupted
+		if batch.Lockfile != "" && !contains(summary.Lockfiles, batch.Lockfile) {
+			summary.Lockfiles = append(summary.Lockfiles, batch.Lockfile)
+		}
+
+		batchConfig := config
+		if batch.Lockfile != "" {
+			batchConfig.Lockfile = batch.Lockfile
+		}
+		for _, result := range withGroups(batch.Results, batchConfig) {
+			if result.Found {
+				if result.Instances == nil {
+					result.Instances = []types.PackageInstance{}
+				}
+				if err := encoder.Encode(ndjsonRecord{Type: "result", Lockfile: batch.Lockfile, Result: &result}); err != nil {
+					return Totals{}, err
+				}
+				if result.Package.Pattern != "" {
+					foundPatterns[result.Package.Pattern] = true
+				}
+			}
+
+			query, err := json.Marshal(result.Package)
+			if err != nil {
+				return Totals{}, err
+			}
+			key := result.Check + "\x00" + string(query)
+			s, ok := streamed[key]
+			if !ok {
+				s = &streamedResult{result: result}
+				s.result.Instances = nil
+				streamed[key] = s
+				order = append(order, key)
+			}
+			s.result.Found = s.result.Found || result.Found
+			s.active += len(activeInstances(result))
+			s.fresh += len(newInstances(result))
+		}
+	}
+
+	summary.Summary = Totals{RisksBySeverity: make(map[string]int), Checks: make(map[string]int), Interrupted: interrupted}
+	for _, key := range order {
+		s := streamed[key]
+		if !s.result.Found && foundPatterns[s.result.Package.Name] {
+			// A pattern that matched nothing in one lockfile but something in another
+			continue
+		}
+		summary.Summary.add(s.result, s.active, s.fresh)
+
+		if s.result.Found || s.result.Package.Error == "" && (!config.ShowSafe || config.RiskOnly) {
+			continue
+		}
+		s.result.Instances = []types.PackageInstance{}
+		if err := encoder.Encode(ndjsonRecord{Type: "result", Result: &s.result}); err != nil {
+			return Totals{}, err
+		}
+	}
+
+	return summary.Summary, json.NewEncoder(w).Encode(summary)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
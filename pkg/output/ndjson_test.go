@@ -0,0 +1,122 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+func TestWriteNDJSON(t *testing.T) {
+	lodash := types.PackageQuery{Name: "lodash", Version: "4.17.20", Severity: types.SeverityHigh}
+	leftPad := types.PackageQuery{Name: "left-pad", Version: "1.3.0"}
+	ctrl := types.PackageQuery{Name: "@ctrl/*"}
+	chalk := types.PackageQuery{Name: "chalk", Tag: "latest", Error: "registry unreachable"}
+	batch := func(lockfile string, results ...types.ScanResult) ResultBatch {
+		return ResultBatch{Lockfile: lockfile, Results: append(results, types.ScanResult{Package: leftPad}, types.ScanResult{Package: chalk})}
+	}
+	found := func(query types.PackageQuery, instances ...types.PackageInstance) types.ScanResult {
+		return types.ScanResult{Package: query, Found: true, Instances: instances, TotalInstances: len(instances)}
+	}
+
+	batches := make(chan ResultBatch, 4)
+	batches <- batch("a/package-lock.json",
+		found(lodash, types.PackageInstance{Version: "4.17.20", Path: "node_modules/lodash"}),
+		types.ScanResult{Package: ctrl},
+	)
+	batches <- batch("b/package-lock.json",
+		found(lodash, types.PackageInstance{Version: "4.17.20", Path: "node_modules/lodash", Suppressed: true}),
+		found(types.PackageQuery{Name: "@ctrl/tinycolor", Pattern: "@ctrl/*"}, types.PackageInstance{Version: "4.1.1", Path: "node_modules/@ctrl/tinycolor"}),
+		found(types.PackageQuery{Name: "lodahs"}, types.PackageInstance{Version: "1.0.0", Path: "node_modules/lodahs"}),
+	)
+	batches <- ResultBatch{Warnings: []string{"archive member 'd/yarn.lock' contains no installed packages"}}
+	batches <- batch("c/package-lock.json")
+	close(batches)
+
+	config := OutputConfig{ShowSafe: true, ToolVersion: "1.2.3", Lockfile: "repo.zip", Warnings: []string{"'--regex' matched nothing"}}
+	var buf bytes.Buffer
+	if _, err := writeNDJSON(&buf, batches, config, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("writeNDJSON() error = %v", err)
+	}
+
+	var records []map[string]any
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var record map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %q isn't a JSON object: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+
+	var got []string
+	for _, record := range records {
+		switch record["type"] {
+		case "result":
+			result := record["result"].(map[string]any)
+			lockfile, _ := record["lockfile"].(string)
+			got = append(got, "result "+result["package"].(map[string]any)["name"].(string)+" "+lockfile)
+		case "warning":
+			got = append(got, "warning")
+		default:
+			got = append(got, record["type"].(string))
+		}
+	}
+	want := []string{
+		"warning",
+		"result lodash a/package-lock.json",
+		"result lodash b/package-lock.json",
+		"result @ctrl/tinycolor b/package-lock.json",
+		"result lodahs b/package-lock.json",
+		"warning",
+		"result left-pad ",
+		"result chalk ",
+		"summary",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("records = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("record %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	summary := records[len(records)-1]
+	schema := loadSchema(t)
+	checkSchema(t, "summary", schema["$defs"].(map[string]any)["summary"].(map[string]any), summary["summary"])
+	totals := summary["summary"].(map[string]any)
+	if totals["risks"] != float64(3) || totals["safe"] != float64(1) || totals["errors"] != float64(1) {
+		t.Errorf("summary = %v, want lodash, @ctrl/tinycolor and lodahs counted once each, left-pad safe and chalk an error", totals)
+	}
+	if lockfiles := summary["lockfiles"].([]any); len(lockfiles) != 4 || lockfiles[0] != "repo.zip" {
+		t.Errorf("lockfiles = %v, want the archive and its three members", lockfiles)
+	}
+	if warnings := summary["warnings"].([]any); len(warnings) != 2 {
+		t.Errorf("warnings = %v, want both", warnings)
+	}
+	if summary["schemaVersion"] != float64(JSONSchemaVersion) || summary["scannedAt"] != "2024-05-01T12:00:00Z" {
+		t.Errorf("summary envelope = %v, want the schema version and scan time", summary)
+	}
+}
+
+func TestWriteNDJSONRiskOnly(t *testing.T) {
+	batches := make(chan ResultBatch, 1)
+	batches <- ResultBatch{Lockfile: "package-lock.json", Results: []types.ScanResult{{Package: types.PackageQuery{Name: "left-pad", Version: "1.3.0"}}}, Interrupted: true}
+	close(batches)
+
+	var buf bytes.Buffer
+	totals, err := writeNDJSON(&buf, batches, OutputConfig{ShowSafe: true, RiskOnly: true}, time.Now())
+	if err != nil {
+		t.Fatalf("writeNDJSON() error = %v", err)
+	}
+	if lines := bytes.Count(buf.Bytes(), []byte("\n")); lines != 1 {
+		t.Errorf("writeNDJSON() wrote %d lines, want only the summary:\n%s", lines, buf.String())
+	}
+	if !totals.Interrupted || !bytes.Contains(buf.Bytes(), []byte(`"interrupted":true`)) {
+		t.Errorf("writeNDJSON() = %s, want the summary of an interrupted scan marked", buf.String())
+	}
+}
@@ -0,0 +1,172 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+// Payload formats of --notify-webhook
+const (
+	NotifyJSON  = "json"
+	NotifySlack = "slack"
+)
+
+// NotifyFormats lists the payload formats of --notify-webhook
+var NotifyFormats = []string{NotifyJSON, NotifySlack}
+
+// maxNotifyFindings caps how many findings a notification lists; chat messages get unreadable
+// (and Slack rejects blocks) well before a large scan runs out of them
+const maxNotifyFindings = 10
+
+// notifyFinding is one risky instance in a notification
+type notifyFinding struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Severity string `json:"severity,omitempty"`
+	Advisory string `json:"advisory,omitempty"`
+	Path     string `json:"path"`
+	Lockfile string `json:"lockfile,omitempty"`
+}
+
+// notification is the JSON payload of --notify-webhook
+type notification struct {
+	Tool      jsonTool        `json:"tool"`
+	ScannedAt string          `json:"scannedAt"` // RFC 3339
+	Lockfiles []string        `json:"lockfiles"`
+	Summary   Totals          `json:"summary"`
+	Findings  []notifyFinding `json:"findings"` // The most severe risks first, at most maxNotifyFindings
+	Omitted   int             `json:"omittedFindings"`
+}
+
+// slackMessage is a Slack incoming-webhook message; Text is the fallback shown in notifications
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Notification returns the --notify-webhook payload of a scan in format (one of NotifyFormats):
+// its totals and its most severe risks. totals are the scan's, as for WriteSummaryFile.
+func Notification(results []types.ScanResult, totals Totals, config OutputConfig, format string) ([]byte, error) {
+	findings := notifyFindings(results, config)
+	omitted := 0
+	if len(findings) > maxNotifyFindings {
+		omitted = len(findings) - maxNotifyFindings
+		findings = findings[:maxNotifyFindings]
+	}
+	lockfiles := scannedLockfiles(results, config)
+
+	switch format {
+	case NotifyJSON:
+		scannedAt, err := reportTime()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(notification{
+			Tool:      jsonTool{Name: "scnpm", Version: config.ToolVersion, Commit: config.ToolCommit},
+			ScannedAt: scannedAt.UTC().Format(time.RFC3339),
+			Lockfiles: lockfiles,
+			Summary:   totals,
+			Findings:  findings,
+			Omitted:   omitted,
+		})
+	case NotifySlack:
+		return json.Marshal(slackNotification(findings, omitted, totals, lockfiles))
+	default:
+		return nil, fmt.Errorf("unknown notification format '%s'", format)
+	}
+}
+
+// notifyFindings lists the risky instances of bad-package results, most severe first
+func notifyFindings(results []types.ScanResult, config OutputConfig) []notifyFinding {
+	var findings []notifyFinding
+	for _, result := range results {
+		if result.Check != "" {
+			continue
+		}
+		for _, instance := range newInstances(result) {
+			name := instance.Name
+			if name == "" {
+				name = result.Package.Name
+			}
+			lockfile := instance.Lockfile
+			if lockfile == "" {
+				lockfile = config.Lockfile
+			}
+			findings = append(findings, notifyFinding{
+				Name:     name,
+				Version:  instance.Version,
+				Severity: result.Package.Severity,
+				Advisory: result.Package.Advisory,
+				Path:     instance.Path,
+				Lockfile: lockfile,
+			})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if rankA, rankB := types.SeverityRank(a.Severity), types.SeverityRank(b.Severity); rankA != rankB {
+			return rankA > rankB
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Path < b.Path
+	})
+	return findings
+}
+
+// slackNotification formats a notification as a Slack message: a headline with the totals and a
+// bulleted list of the findings
+func slackNotification(findings []notifyFinding, omitted int, totals Totals, lockfiles []string) slackMessage {
+	headline := fmt.Sprintf("scnpm found %d risks", totals.Risks)
+	if len(lockfiles) > 0 {
+		headline += " in " + strings.Join(lockfiles, ", ")
+	}
+	summary := "*" + slackEscape(headline) + "*"
+	if breakdown := severityBreakdown(totals.RisksBySeverity); breakdown != "" {
+		summary += "\n" + breakdown
+	}
+
+	var lines []string
+	for _, finding := range findings {
+		line := fmt.Sprintf("• `%s@%s`", slackEscape(finding.Name), slackEscape(finding.Version))
+		if finding.Severity != "" {
+			line += " (" + finding.Severity + ")"
+		}
+		line += " at " + slackEscape(finding.Path)
+		if finding.Advisory != "" {
+			line += " — " + slackEscape(finding.Advisory)
+		}
+		lines = append(lines, line)
+	}
+	if omitted > 0 {
+		lines = append(lines, fmt.Sprintf("…and %d more", omitted))
+	}
+
+	message := slackMessage{
+		Text:   headline,
+		Blocks: []slackBlock{{Type: "section", Text: &slackText{Type: "mrkdwn", Text: summary}}},
+	}
+	if len(lines) > 0 {
+		message.Blocks = append(message.Blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: strings.Join(lines, "\n")}})
+	}
+	return message
+}
+
+// slackEscape escapes the characters Slack's mrkdwn treats as markup
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
@@ -0,0 +1,79 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestNotification(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "0")
+	results := []types.ScanResult{
+		{Package: types.PackageQuery{Name: "chalk", Version: "5.6.1", Severity: types.SeverityLow}, Found: true, Instances: []types.PackageInstance{{Name: "chalk", Version: "5.6.1", Path: "node_modules/chalk"}}},
+		{Package: types.PackageQuery{Name: "debug", Version: "4.4.2", Severity: types.SeverityCritical, Advisory: "GHSA-8mgj-vmr8-frr6"}, Found: true, Instances: []types.PackageInstance{{Name: "debug", Version: "4.4.2", Path: "node_modules/debug"}}},
+		{Package: types.PackageQuery{Name: "color", Version: "5.0.1"}, Found: true, Instances: []types.PackageInstance{{Name: "color", Version: "5.0.1", Path: "node_modules/color", Baseline: true}}},
+		{Package: types.PackageQuery{Name: "lodahs"}, Check: types.CheckTyposquat, Found: true, Instances: []types.PackageInstance{{Name: "lodahs", Path: "node_modules/lodahs"}}},
+	}
+	config := OutputConfig{ToolVersion: "1.2.3", Lockfile: "package-lock.json"}
+
+	data, err := Notification(results, CountResults(results), config, NotifyJSON)
+	if err != nil {
+		t.Fatalf("Notification(json) error = %v", err)
+	}
+	var got notification
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("notification isn't JSON: %v\n%s", err, data)
+	}
+	if got.Summary.Risks != 2 || got.Omitted != 0 || got.ScannedAt != "1970-01-01T00:00:00Z" {
+		t.Errorf("notification = %+v, want 2 risks, nothing omitted, scanned at the epoch", got)
+	}
+	if len(got.Findings) != 2 || got.Findings[0].Name != "debug" || got.Findings[1].Name != "chalk" {
+		t.Errorf("Findings = %+v, want debug (critical) before chalk (low), without the baseline and check findings", got.Findings)
+	}
+	if got.Findings[0].Lockfile != "package-lock.json" || got.Findings[0].Advisory != "GHSA-8mgj-vmr8-frr6" {
+		t.Errorf("Findings[0] = %+v, want the scanned lockfile and the advisory", got.Findings[0])
+	}
+
+	data, err = Notification(results, CountResults(results), config, NotifySlack)
+	if err != nil {
+		t.Fatalf("Notification(slack) error = %v", err)
+	}
+	var message slackMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		t.Fatalf("Slack message isn't JSON: %v\n%s", err, data)
+	}
+	if message.Text != "scnpm found 2 risks in package-lock.json" || len(message.Blocks) != 2 {
+		t.Fatalf("Slack message = %+v, want the headline as text, a summary and a findings block", message)
+	}
+	if findings := message.Blocks[1].Text.Text; !strings.HasPrefix(findings, "• `debug@4.4.2` (critical) at node_modules/debug — GHSA-8mgj-vmr8-frr6\n") {
+		t.Errorf("findings block = %q, want debug listed first with its severity and advisory", findings)
+	}
+
+	if _, err := Notification(results, CountResults(results), config, "teams"); err == nil {
+		t.Error("Notification() expected error for an unknown format")
+	}
+}
+
+func TestNotificationOmitsFindings(t *testing.T) {
+	var instances []types.PackageInstance
+	for i := 0; i < maxNotifyFindings+3; i++ {
+		instances = append(instances, types.PackageInstance{Name: "debug", Version: "4.4.2", Path: fmt.Sprintf("node_modules/pkg%02d/node_modules/debug", i)})
+	}
+	results := []types.ScanResult{{Package: types.PackageQuery{Name: "debug", Version: "4.4.2"}, Found: true, Instances: instances}}
+
+	data, err := Notification(results, CountResults(results), OutputConfig{}, NotifySlack)
+	if err != nil {
+		t.Fatalf("Notification() error = %v", err)
+	}
+	var message slackMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(message.Blocks[1].Text.Text, "\n")
+	if len(lines) != maxNotifyFindings+1 || lines[len(lines)-1] != "…and 3 more" {
+		t.Errorf("findings block has %d lines ending %q, want %d findings and a count of the rest", len(lines), lines[len(lines)-1], maxNotifyFindings)
+	}
+}
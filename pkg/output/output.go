@@ -1,127 +1,832 @@
 package output
 
 import (
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
+	"scnpm/pkg/semver"
 	"scnpm/pkg/types"
 )
 
 // OutputConfig contains configuration for output formatting
 type OutputConfig struct {
-	ShowSafe bool
-	RiskOnly bool
+	ShowSafe        bool
+	RiskOnly        bool
+	ShowWorkspaces  bool
+	ShowLockfiles   bool
+	ShowChains      bool                    // Add the chain column: how a direct dependency pulls each finding in
+	ShowAdvisories  bool                    // Set automatically when any query carries severity or advisory metadata
+	ShowMatch       bool                    // Set automatically when any finding matched by something weaker than the exact name
+	SummaryOnly     bool                    // Report only the totals, without a line per finding
+	Warnings        []string                // Caveats that make an all-clear summary misleading
+	ToolVersion     string                  // scnpm version recorded in machine-readable reports
+	ToolCommit      string                  // Commit scnpm was built from, recorded in JSON reports
+	Lockfile        string                  // Scanned lockfile as a path relative to the working directory, for reports that locate findings
+	Inventory       []types.PackageInstance // Every installed package, for bills of materials
+	NamespacePrefix string                  // URI under which SPDX document namespaces are created
+	ErrorSeverity   string                  // Lowest severity annotated as an error in GitHub output; DefaultErrorSeverity when empty
+	Color           bool                    // Color the table's statuses and summary with ANSI escapes; other formats ignore it
+	ASCII           bool                    // Replace the emoji and symbols of the table and summary with plain tokens
+	GroupBy         string                  // One of GroupBys to split the table into sections and tag JSON instances; "" for neither
+	Columns         []string                // TableColumns the table shows, in order; the default layout when empty
+	TruncatePaths   int                     // Longest install path the table shows before eliding its middle; 0 for no limit
+	Interrupted     bool                    // The scan was cut short; summaries mark the results as partial
 }
 
-// OutputTable displays results in table format
-func OutputTable(results []types.ScanResult, config OutputConfig) {
-	fmt.Printf("%-30s %-15s %-8s %-15s %-8s %-8s %s\n", "Package", "Target Ver", "Status", "Found Ver", "Dev", "Line#", "Path")
-	fmt.Println(strings.Repeat("-", 120))
+// tableRow holds the cells of a single table line
+type tableRow struct {
+	Package      string
+	Target       string
+	Status       string
+	Match        string
+	Severity     string
+	Found        string
+	Dev          string
+	Line         string
+	Workspace    string
+	Lockfile     string
+	Advisory     string
+	ReferencedBy string
+	Path         string
+	Chain        string
+	Section      string // A line of its own, such as a group header, printed instead of the cells
+}
+
+// tableColumn is a column of the table
+type tableColumn struct {
+	cell  func(row tableRow) string
+	width int // Narrowest the column gets; it widens to fit its longest cell
+}
+
+// TableColumns are the columns OutputConfig.Columns can pick, in their default order
+var TableColumns = []string{"package", "target", "status", "match", "severity", "found", "dev", "line", "workspace", "lockfile", "advisory", "referencedBy", "path", "chain"}
+
+// columnsByName defines each of TableColumns
+var columnsByName = map[string]tableColumn{
+	"package":      {func(row tableRow) string { return row.Package }, 30},
+	"target":       {func(row tableRow) string { return row.Target }, 15},
+	"status":       {func(row tableRow) string { return row.Status }, 8},
+	"match":        {func(row tableRow) string { return row.Match }, 16},
+	"severity":     {func(row tableRow) string { return row.Severity }, 10},
+	"found":        {func(row tableRow) string { return row.Found }, 15},
+	"dev":          {func(row tableRow) string { return row.Dev }, 8},
+	"line":         {func(row tableRow) string { return row.Line }, 8},
+	"workspace":    {func(row tableRow) string { return row.Workspace }, 20},
+	"lockfile":     {func(row tableRow) string { return row.Lockfile }, 30},
+	"advisory":     {func(row tableRow) string { return row.Advisory }, 20},
+	"referencedBy": {func(row tableRow) string { return row.ReferencedBy }, 15},
+	"path":         {func(row tableRow) string { return row.Path }, 0},
+	"chain":        {func(row tableRow) string { return row.Chain }, 0},
+}
+
+// tableColumnNames returns the names of the columns the table shows: config.Columns, or else
+// the default layout with the optional columns the results and flags enabled, ending in the path
+func tableColumnNames(config OutputConfig) []string {
+	if len(config.Columns) > 0 {
+		return config.Columns
+	}
+	names := []string{"package", "target", "status"}
+	if config.ShowMatch {
+		names = append(names, "match")
+	}
+	if config.ShowAdvisories {
+		names = append(names, "severity")
+	}
+	names = append(names, "found", "dev", "line")
+	if config.ShowWorkspaces {
+		names = append(names, "workspace")
+	}
+	if config.ShowLockfiles {
+		names = append(names, "lockfile")
+	}
+	if config.ShowAdvisories {
+		names = append(names, "advisory")
+	}
+	names = append(names, "path")
+	if config.ShowChains {
+		names = append(names, "chain")
+	}
+	return names
+}
+
+// writeRows prints the header and rows of the table, with every column as wide as its widest cell
+// in terminal columns, so that emoji, CJK characters and long scoped names don't shift the rest
+func writeRows(w io.Writer, rows []tableRow, config OutputConfig) {
+	rows = append([]tableRow{tableHeader}, rows...)
+	if config.ASCII {
+		// Replaced before measuring, which would otherwise be done for the glyphs
+		for i := range rows {
+			row := &rows[i]
+			for _, cell := range []*string{&row.Target, &row.Status, &row.Match, &row.Found, &row.Dev, &row.Path, &row.Section} {
+				*cell = asciiGlyphs.Replace(*cell)
+			}
+		}
+	}
+
+	names := tableColumnNames(config)
+	columns := make([]tableColumn, len(names))
+	for i, name := range names {
+		columns[i] = columnsByName[name]
+	}
+	for i, column := range columns {
+		for _, row := range rows {
+			if row.Section != "" {
+				continue
+			}
+			if width := displayWidth(column.cell(row)); width > columns[i].width {
+				columns[i].width = width
+			}
+		}
+	}
+
+	for i, row := range rows {
+		if row.Section != "" {
+			fmt.Fprintln(w, row.Section)
+			continue
+		}
+		var line strings.Builder
+		for j, column := range columns {
+			// The last column isn't padded, so that lines don't end in spaces
+			cell, separator := column.cell(row), ""
+			if j < len(columns)-1 {
+				cell, separator = padRight(cell, column.width), " "
+			}
+			if i > 0 {
+				// Colored after padding, so that the escapes don't count toward the width
+				switch names[j] {
+				case "status":
+					cell = colorize(cell, statusColor(row.Status), config)
+				case "severity":
+					cell = colorize(cell, severityColor(row.Severity), config)
+				}
+			}
+			line.WriteString(cell + separator)
+		}
+		fmt.Fprintln(w, line.String())
+		if i == 0 {
+			fmt.Fprintln(w, strings.Repeat("-", 120))
+		}
+	}
+}
+
+// reportTime returns the time reports are stamped with: $SOURCE_DATE_EPOCH when set, so that
+// repeated scans of the same lockfile give byte-identical reports, and otherwise now
+func reportTime() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Now(), nil
+	}
+	var seconds int64
+	if _, err := fmt.Sscan(epoch, &seconds); err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH '%s'", epoch)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// errWriter remembers the first write that failed and skips the rest, so that output printed
+// line by line can check for errors once at the end
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	n, err := e.w.Write(p)
+	e.err = err
+	return n, err
+}
+
+// withColumns enables the optional table columns the results need
+func withColumns(results []types.ScanResult, config OutputConfig) OutputConfig {
+	for _, result := range results {
+		if result.Package.Severity != "" || result.Package.Advisory != "" {
+			config.ShowAdvisories = true
+		}
+		for _, instance := range result.Instances {
+			if instance.MatchReason != "" && instance.MatchReason != "exact" {
+				config.ShowMatch = true
+			}
+		}
+	}
+	return config
+}
+
+// OutputTable writes results to out in table format
+func OutputTable(out io.Writer, results []types.ScanResult, config OutputConfig) error {
+	w := &errWriter{w: out}
+	config = withColumns(results, config)
+
+	totals := CountResults(results)
+	if !config.SummaryOnly {
+		if config.GroupBy != "" {
+			outputGroupedRows(w, results, config)
+		} else {
+			outputRows(w, results, config)
+		}
+		outputRemediation(w, results)
+		// Lockfile-wide checks get their own sections, apart from the bad-package findings
+		outputChecks(w, results, totals.Checks, config)
+		fmt.Fprintln(w, strings.Repeat("=", 120))
+	}
+
+	// Security Summary
+	verdict := colorGreen
+	if totals.Risks > 0 {
+		verdict = colorRed
+	}
+	fmt.Fprintln(w, colorize(summaryLine(totals, config), verdict, config))
+	if totals.Checks[types.CheckInstallScript] > 0 {
+		fmt.Fprintln(w, plain(fmt.Sprintf("📜 %d packages run install scripts, %d of them flagged as risks", totals.Checks[types.CheckInstallScript], riskyScripts(results)), config))
+	}
+	if totals.Risks > 0 {
+		fmt.Fprintln(w, colorize(plain(fmt.Sprintf("⚠️  WARNING: Found %d potentially compromised packages in your project!", totals.Risks), config), colorRed, config))
+	} else if len(config.Warnings) == 0 {
+		fmt.Fprintln(w, colorize(plain("✅ GOOD: No known compromised packages detected in your project.", config), colorGreen, config))
+	}
+	for _, warning := range config.Warnings {
+		fmt.Fprintln(w, colorize(plain("⚠️  WARNING: "+warning, config), colorYellow, config))
+	}
+
+	if config.ShowWorkspaces {
+		outputWorkspaceSummary(w, results, config)
+	}
+	return w.err
+}
+
+// tableHeader names the columns of the table
+var tableHeader = tableRow{
+	Package:      "Package",
+	Target:       "Target Ver",
+	Status:       "Status",
+	Match:        "Match",
+	Severity:     "Severity",
+	Found:        "Found Ver",
+	Dev:          "Dev",
+	Line:         "Line#",
+	Workspace:    "Workspace",
+	Lockfile:     "Lockfile",
+	Advisory:     "Advisory",
+	ReferencedBy: "Referenced By",
+	Path:         "Path",
+	Chain:        "Chain",
+}
 
+// outputRows prints the table header and a row per bad-package query, or per instance found
+func outputRows(w io.Writer, results []types.ScanResult, config OutputConfig) {
+	var rows []tableRow
+
+	previousName := ""
 	for _, result := range results {
+		if result.Check != "" {
+			continue
+		}
+
+		// Consecutive queries for the same package (several bad versions) are grouped under one name
+		displayName := result.Package.Name
+		if displayName == previousName {
+			displayName = ""
+		}
+		previousName = result.Package.Name
+
+		if result.Package.Error != "" {
+			// The query couldn't be checked, which must not read as safe
+			row := errorRow(result)
+			row.Package = displayName
+			rows = append(rows, row)
+			continue
+		}
+
 		if !result.Found {
 			// Only show safe packages if showSafe is true and riskOnly is false
 			if config.ShowSafe && !config.RiskOnly {
-				fmt.Printf("%-30s %-15s %-8s %-15s %-8s %-8s %s\n",
-					result.Package.Name,
-					result.Package.Version,
-					"✅ SAFE",
-					"Not Found",
-					"-",
-					"-",
-					"Package not detected in project",
-				)
+				row := safeRow(result)
+				row.Package = displayName
+				rows = append(rows, row)
 			}
 			continue
 		}
 
+		// Suppressed instances were accepted in an ignore file; they only show up in the summary
+		active := activeInstances(result)
+		if len(active) == 0 {
+			continue
+		}
+
 		// Group instances by version for cleaner output
 		versionGroups := make(map[string][]types.PackageInstance)
-		for _, instance := range result.Instances {
+		var versions []string
+		for _, instance := range active {
+			if _, ok := versionGroups[instance.Version]; !ok {
+				versions = append(versions, instance.Version)
+			}
 			versionGroups[instance.Version] = append(versionGroups[instance.Version], instance)
 		}
+		semver.SortStrings(versions)
 
 		first := true
-		for version, instances := range versionGroups {
-			for i, instance := range instances {
-				packageName := result.Package.Name
-				expectedVersion := result.Package.Version
-
+		for _, version := range versions {
+			for i, instance := range versionGroups[version] {
+				row := instanceRow(result, instance, config)
+				row.Package = displayName
 				if !first || i > 0 {
-					packageName = ""
-					expectedVersion = ""
+					row.Package = ""
+					// Each found version repeats the alternative it matched
+					if instance.MatchedVersion == "" || i > 0 {
+						row.Target = ""
+					}
 				}
+				rows = append(rows, row)
+				first = false
+			}
+		}
 
-				devStatus := "-"
-				if instance.IsDev {
-					devStatus = "✓"
-				}
+		if len(active) > 1 {
+			rows = append(rows, tableRow{Found: fmt.Sprintf("(%d total)", len(active))})
+		}
+	}
 
-				lineStatus := "-"
-				if instance.LineNumber > 0 {
-					lineStatus = fmt.Sprintf("L%d", instance.LineNumber)
-				}
+	writeRows(w, rows, config)
+}
 
-				// Determine security status
-				status := "🚨 RISK"
-				if instance.IsReference {
-					status = "⚠️ REF"
-				}
+// instanceRow returns the table row of an instance of a bad-package query
+func instanceRow(result types.ScanResult, instance types.PackageInstance, config OutputConfig) tableRow {
+	target := targetLabel(result.Package)
+	if instance.MatchedVersion != "" {
+		// Highlight the alternative of "1.0.0||1.0.1" that this version matched
+		target = "▶ " + instance.MatchedVersion
+	}
 
-				fmt.Printf("%-30s %-15s %-8s %-15s %-8s %-8s %s\n",
-					packageName,
-					expectedVersion,
-					status,
-					version,
-					devStatus,
-					lineStatus,
-					instance.Path,
-				)
-				first = false
+	dev := "-"
+	if instance.IsDev {
+		dev = "✓"
+	}
+
+	line := "-"
+	if instance.LineNumber > 0 {
+		line = fmt.Sprintf("L%d", instance.LineNumber)
+	}
+
+	referencedBy := "-"
+	if len(instance.ReferencedBy) > 0 {
+		referencedBy = strings.Join(instance.ReferencedBy, ", ")
+	}
+	instance.Path = shortenPath(instance.Path, config.TruncatePaths)
+
+	return tableRow{
+		Package:      result.Package.Name,
+		Target:       target,
+		Status:       instanceStatus(instance),
+		Match:        matchLabel(instance.MatchReason),
+		Severity:     severityLabel(result.Package.Severity),
+		Found:        instance.Version,
+		Dev:          dev,
+		Line:         line,
+		Workspace:    instance.Workspace,
+		Lockfile:     instance.Lockfile,
+		Advisory:     result.Package.Advisory,
+		ReferencedBy: referencedBy,
+		Path:         instancePath(instance),
+		Chain:        chainLabel(instance.Chains),
+	}
+}
+
+// errorRow returns the table row of a query that couldn't be checked
+func errorRow(result types.ScanResult) tableRow {
+	return tableRow{
+		Package:      result.Package.Name,
+		Target:       targetLabel(result.Package),
+		Status:       "❗ ERROR",
+		Match:        "-",
+		Severity:     severityLabel(result.Package.Severity),
+		Found:        "-",
+		Dev:          "-",
+		Line:         "-",
+		Workspace:    "-",
+		Lockfile:     "-",
+		Advisory:     result.Package.Advisory,
+		ReferencedBy: "-",
+		Path:         result.Package.Error,
+		Chain:        "-",
+	}
+}
+
+// safeRow returns the table row of a query that matched nothing
+func safeRow(result types.ScanResult) tableRow {
+	return tableRow{
+		Package:      result.Package.Name,
+		Target:       targetLabel(result.Package),
+		Status:       "✅ SAFE",
+		Match:        "-",
+		Severity:     "-",
+		Found:        "Not Found",
+		Dev:          "-",
+		Line:         "-",
+		Workspace:    "-",
+		Lockfile:     "-",
+		Advisory:     "-",
+		ReferencedBy: "-",
+		Path:         "Package not detected in project",
+		Chain:        "-",
+	}
+}
+
+// Summary returns the table's security summary line, for reports written elsewhere
+func Summary(results []types.ScanResult, config OutputConfig) string {
+	return summaryLine(CountResults(results), withColumns(results, config))
+}
+
+// summaryLine lists the totals of a scan on one line
+func summaryLine(totals Totals, config OutputConfig) string {
+	summary := fmt.Sprintf("SECURITY SUMMARY: 🚨 %d RISKS DETECTED", totals.Risks)
+	if config.ShowAdvisories && totals.Risks > 0 {
+		summary += fmt.Sprintf(" (%s)", severityBreakdown(totals.RisksBySeverity))
+	}
+	summary += fmt.Sprintf(" | ✅ %d PACKAGES SAFE", totals.Safe)
+	if totals.Suppressed > 0 {
+		summary += fmt.Sprintf(" | 🔇 %d SUPPRESSED", totals.Suppressed)
+	}
+	if totals.Baseline > 0 {
+		summary += fmt.Sprintf(" | 📌 %d BASELINE", totals.Baseline)
+	}
+	if totals.Errors > 0 {
+		summary += fmt.Sprintf(" | ❗ %d NOT CHECKED", totals.Errors)
+	}
+	for _, check := range types.Checks {
+		if totals.Checks[check] > 0 {
+			summary += " | " + fmt.Sprintf(checkSections[check].Summary, totals.Checks[check])
+		}
+	}
+	if config.Interrupted {
+		summary += " | ⏹ INTERRUPTED, RESULTS ARE PARTIAL"
+	}
+	return plain(summary, config)
+}
+
+// instanceStatus returns the status of a bad-package instance, escalating installed ones whose
+// circumstances make them more dangerous
+func instanceStatus(instance types.PackageInstance) string {
+	switch {
+	case instance.Baseline:
+		// Known and accepted for now in a baseline file; shown, but not counted as a risk
+		return "📌 BASELINE"
+	case instance.IsReference:
+		return referenceStatus(instance.ReferenceType)
+	case instance.IntegrityMatch:
+		// The exact malicious tarball is installed, not just a same-numbered version
+		return "🚨 IOC"
+	case instance.HasInstallScript:
+		// A bad version that runs code on install executes before anyone looks at it
+		return "🚨 SCRIPT"
+	case mutableSource(instance.InstallSource) && instance.PinnedCommit == "":
+		// A git ref or tarball URL can change contents under the same version
+		return "🚨 MUTABLE"
+	default:
+		return "🚨 RISK"
+	}
+}
+
+// instancePath returns the path shown for an instance, with its alias and mutable source noted
+func instancePath(instance types.PackageInstance) string {
+	path := instance.Path
+	if instance.Alias != "" {
+		// Aliases install a package under another name; show what it really is
+		path += fmt.Sprintf(" (%s is npm:%s)", instance.Alias, instance.Name)
+	}
+	if mutableSource(instance.InstallSource) {
+		path += " " + sourceNote(instance)
+	}
+	return path
+}
+
+// chainLabel renders the first dependency chain of a finding as "express > body-parser > evil@1.0.0",
+// noting how many more there are
+func chainLabel(chains [][]string) string {
+	if len(chains) == 0 {
+		return "-"
+	}
+	label := strings.Join(chains[0], " > ")
+	if len(chains) > 1 {
+		label += fmt.Sprintf(" (+%d more)", len(chains)-1)
+	}
+	return label
+}
+
+// shortenPath elides the middle of a path longer than max runes, keeping the start and the
+// package at its end; max 0 leaves it as it is
+func shortenPath(path string, max int) string {
+	runes := []rune(path)
+	if max <= 0 || len(runes) <= max {
+		return path
+	}
+	if max == 1 {
+		return "…"
+	}
+	head := (max - 1) / 2
+	return string(runes[:head]) + "…" + string(runes[len(runes)-(max-1-head):])
+}
+
+// Totals counts results for a report summary
+type Totals struct {
+	Risks           int            `json:"risks"`
+	Safe            int            `json:"safe"`
+	Suppressed      int            `json:"suppressed"`
+	Baseline        int            `json:"baseline"` // Queries whose active findings are all recorded in the --baseline file
+	Errors          int            `json:"errors"`
+	RisksBySeverity map[string]int `json:"risksBySeverity"`       // Keyed by severity, "unknown" when the source didn't say
+	Checks          map[string]int `json:"checks"`                // Active findings of each lockfile-wide check
+	Interrupted     bool           `json:"interrupted,omitempty"` // The scan was cut short, so the counts are partial
+}
+
+// CountResults counts bad-package queries by outcome and the active findings of each check
+func CountResults(results []types.ScanResult) Totals {
+	totals := Totals{RisksBySeverity: make(map[string]int), Checks: make(map[string]int)}
+	for _, result := range results {
+		totals.add(result, len(activeInstances(result)), len(newInstances(result)))
+	}
+	return totals
+}
+
+// add counts a result, given how many of its instances aren't suppressed and how many of those
+// aren't in the baseline either
+func (totals *Totals) add(result types.ScanResult, active, fresh int) {
+	switch {
+	case result.Check != "":
+		totals.Checks[result.Check] += active
+	case result.Package.Error != "":
+		totals.Errors++
+	case !result.Found:
+		totals.Safe++
+	case active == 0:
+		totals.Suppressed++
+	case fresh == 0:
+		totals.Baseline++
+	default:
+		totals.Risks++
+		totals.RisksBySeverity[severityLabel(result.Package.Severity)]++
+	}
+}
+
+// checkSection describes how a lockfile-wide check is reported in the table
+type checkSection struct {
+	Title   string // Heading of the section listing the check's findings
+	Summary string // Summary line entry, formatted with the number of findings
+}
+
+var checkSections = map[string]checkSection{
+	types.CheckNearMatch:        {Title: "NEAR MATCHES", Summary: "🔎 %d NEAR MATCHES"},
+	types.CheckTyposquat:        {Title: "POSSIBLE TYPOSQUATS", Summary: "🎭 %d POSSIBLE TYPOSQUATS"},
+	types.CheckSuspiciousScript: {Title: "SUSPICIOUS SCRIPTS", Summary: "💀 %d SUSPICIOUS SCRIPTS"},
+	types.CheckInstallScript:    {Title: "INSTALL SCRIPTS", Summary: "📜 %d INSTALL SCRIPTS"},
+	types.CheckLicense:          {Title: "LICENSE POLICY VIOLATIONS", Summary: "⚖️ %d LICENSE POLICY VIOLATIONS"},
+	types.CheckMissingIntegrity: {Title: "MISSING INTEGRITY", Summary: "🔓 %d WITHOUT INTEGRITY"},
+	types.CheckWeakIntegrity:    {Title: "WEAK INTEGRITY", Summary: "🔐 %d WEAK INTEGRITY"},
+	types.CheckNonRegistry:      {Title: "GIT AND TARBALL DEPENDENCIES", Summary: "🌐 %d GIT/TARBALL"},
+	types.CheckInstallSource:    {Title: "NON-REGISTRY SOURCES", Summary: "📦 %d NON-REGISTRY"},
+	types.CheckLockMissing:      {Title: "DECLARED BUT NOT LOCKED", Summary: "❓ %d NOT LOCKED"},
+	types.CheckLockStale:        {Title: "STALE LOCKED VERSIONS", Summary: "⏳ %d STALE"},
+	types.CheckLockOrphan:       {Title: "ORPHANED PACKAGES", Summary: "👻 %d ORPHANED"},
+}
+
+// outputChecks prints a section per lockfile-wide check with active findings, given their counts
+func outputChecks(w io.Writer, results []types.ScanResult, counts map[string]int, config OutputConfig) {
+	byCheck := make(map[string][]types.ScanResult)
+	for _, result := range results {
+		if result.Check != "" {
+			byCheck[result.Check] = append(byCheck[result.Check], result)
+		}
+	}
+
+	for _, check := range types.Checks {
+		if counts[check] == 0 {
+			continue
+		}
+		fmt.Fprintln(w, strings.Repeat("-", 120))
+		fmt.Fprintf(w, "%s (%d):\n", checkSections[check].Title, counts[check])
+		for _, result := range byCheck[check] {
+			for _, instance := range activeInstances(result) {
+				version := instance.Version
+				if version == "" {
+					version = "-"
+				}
+				label, note := checkDetail(check, instance)
+				label = plain(label, config)
+				fmt.Fprintf(w, "  %s %s %s %s%s\n", padRight(instance.Name, 30), padRight(version, 15), padRight(label, 16), instance.Path, note)
 			}
 		}
+	}
+}
 
-		if result.TotalInstances > 1 {
-			fmt.Printf("%-30s %-15s %-8s %-15s %-8s %-8s %s\n",
-				"",
-				"",
-				"",
-				fmt.Sprintf("(%d total)", result.TotalInstances),
-				"",
-				"",
-				"",
-			)
+// checkDetail returns what a lockfile-wide check found about an instance: a short label, and a
+// note printed after the path
+func checkDetail(check string, instance types.PackageInstance) (label, note string) {
+	switch check {
+	case types.CheckInstallSource, types.CheckMissingIntegrity:
+		if instance.Resolved != "" {
+			note = " -> " + instance.Resolved
+		}
+		return instance.InstallSource, note
+	case types.CheckInstallScript:
+		if instance.IsDev {
+			return "dev", ""
+		}
+		return "prod", ""
+	case types.CheckWeakIntegrity:
+		return instance.IntegrityAlgo, ""
+	case types.CheckLicense:
+		label = "prod"
+		if instance.IsDev {
+			label = "dev"
+		}
+		return label, " (" + instance.License + ")"
+	case types.CheckNonRegistry:
+		note = " -> " + instance.Resolved
+		if instance.PinnedCommit == "" {
+			note += " (unpinned)"
+		}
+		if len(instance.DependedOnBy) > 0 {
+			note += " <- " + strings.Join(instance.DependedOnBy, ", ")
+		}
+		return instance.InstallSource, note
+	case types.CheckSuspiciousScript:
+		return instance.Heuristic, fmt.Sprintf(" [%s] %s", instance.Script, instance.Snippet)
+	case types.CheckTyposquat, types.CheckNearMatch:
+		return fmt.Sprintf("≈ %s (%d)", instance.Resembles, instance.Distance), ""
+	case types.CheckLockMissing:
+		return instance.ReferenceType, ""
+	case types.CheckLockStale:
+		return instance.ReferenceType, fmt.Sprintf(" (%s wants %s)", strings.Join(instance.ReferencedBy, ", "), instance.Declared)
+	case types.CheckLockOrphan:
+		if instance.IsDev {
+			return "dev", ""
 		}
+		return "prod", ""
+	default:
+		return "", ""
 	}
+}
 
-	// Security Summary
-	totalRisks := 0
-	totalSafe := 0
+// riskyScripts counts the packages reported by the install-script check that are also installed
+// instances of a bad-package finding
+func riskyScripts(results []types.ScanResult) int {
+	risky := make(map[string]bool)
 	for _, result := range results {
-		if result.Found {
-			totalRisks++
-		} else {
-			totalSafe++
+		if result.Check != "" {
+			continue
+		}
+		for _, instance := range activeInstances(result) {
+			if !instance.IsReference {
+				risky[instance.Lockfile+"\x00"+instance.Path] = true
+			}
+		}
+	}
+
+	count := 0
+	for _, result := range results {
+		if result.Check != types.CheckInstallScript {
+			continue
+		}
+		for _, instance := range activeInstances(result) {
+			if risky[instance.Lockfile+"\x00"+instance.Path] {
+				count++
+			}
 		}
 	}
+	return count
+}
+
+// mutableSource reports whether an install source can change contents under the same version
+func mutableSource(source string) bool {
+	return source == types.SourceGit || source == types.SourceRemoteTarball
+}
+
+// sourceNote describes a git or remote tarball source and whether it's pinned to a commit, which
+// mitigates its mutability
+func sourceNote(instance types.PackageInstance) string {
+	if instance.PinnedCommit != "" {
+		return fmt.Sprintf("(%s, pinned to %.12s)", instance.InstallSource, instance.PinnedCommit)
+	}
+	return fmt.Sprintf("(%s, unpinned)", instance.InstallSource)
+}
+
+// activeInstances returns the instances of a finding that weren't suppressed by an ignore file
+func activeInstances(result types.ScanResult) []types.PackageInstance {
+	var active []types.PackageInstance
+	for _, instance := range result.Instances {
+		if !instance.Suppressed {
+			active = append(active, instance)
+		}
+	}
+	return active
+}
+
+// newInstances returns the active instances of a finding that aren't recorded in the baseline
+func newInstances(result types.ScanResult) []types.PackageInstance {
+	var fresh []types.PackageInstance
+	for _, instance := range activeInstances(result) {
+		if !instance.Baseline {
+			fresh = append(fresh, instance)
+		}
+	}
+	return fresh
+}
 
-	fmt.Println(strings.Repeat("=", 120))
-	fmt.Printf("SECURITY SUMMARY: 🚨 %d RISKS DETECTED | ✅ %d PACKAGES SAFE\n", totalRisks, totalSafe)
-	if totalRisks > 0 {
-		fmt.Printf("⚠️  WARNING: Found %d potentially compromised packages in your project!\n", totalRisks)
-	} else {
-		fmt.Printf("✅ GOOD: No known compromised packages detected in your project.\n")
+// referenceStatus labels a dependency reference by how likely it is to end up installed: optional
+// dependencies may be skipped on install, and peers are left to the consuming project
+func referenceStatus(referenceType string) string {
+	switch referenceType {
+	case "optionalDependencies":
+		return "⚠️ OPT"
+	case "peerDependencies":
+		return "ℹ️ PEER"
+	default:
+		return "⚠️ REF"
 	}
 }
 
-// OutputJSON displays results in JSON format
-func OutputJSON(results []types.ScanResult) {
-	data, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
-		os.Exit(1)
+// matchLabel flags cross-scope matches, which usually point at a different publisher's package
+func matchLabel(reason string) string {
+	if reason == "scope-relaxed" {
+		return "⚠️ " + reason
 	}
-	fmt.Println(string(data))
-}
\ No newline at end of file
+	return reason
+}
+
+// targetLabel returns the target version shown for a query, "any" for name-only queries. Dist-tag
+// queries show the tag and the version it resolved to.
+func targetLabel(query types.PackageQuery) string {
+	switch {
+	case query.Tag != "" && query.Error == "":
+		return query.Tag + " → " + query.Version
+	case query.Version == "":
+		return "any"
+	}
+	return query.Version
+}
+
+// advisoryURL links an advisory given as a GHSA id or an https URL, or returns ""
+func advisoryURL(advisory string) string {
+	switch {
+	case strings.HasPrefix(advisory, "https://"):
+		return advisory
+	case strings.HasPrefix(advisory, "GHSA-"):
+		return "https://github.com/advisories/" + advisory
+	default:
+		return ""
+	}
+}
+
+// severityLabel returns the severity shown for a query, "unknown" when the source didn't provide one
+func severityLabel(severity string) string {
+	if severity == "" {
+		return "unknown"
+	}
+	return severity
+}
+
+// severityBreakdown renders counts like "2 critical, 1 high, 1 unknown", most severe first
+func severityBreakdown(counts map[string]int) string {
+	var parts []string
+	for _, severity := range append(types.Severities, "unknown") {
+		if counts[severity] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[severity], severity))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// outputWorkspaceSummary breaks the risk count down per workspace so findings can be routed to owners
+func outputWorkspaceSummary(w io.Writer, results []types.ScanResult, config OutputConfig) {
+	risksByWorkspace := make(map[string]int)
+	for _, result := range results {
+		// Count each affected package once per workspace
+		seen := make(map[string]bool)
+		for _, instance := range activeInstances(result) {
+			if !seen[instance.Workspace] {
+				seen[instance.Workspace] = true
+				risksByWorkspace[instance.Workspace]++
+			}
+		}
+	}
+
+	if len(risksByWorkspace) == 0 {
+		return
+	}
+
+	workspaces := make([]string, 0, len(risksByWorkspace))
+	for workspace := range risksByWorkspace {
+		workspaces = append(workspaces, workspace)
+	}
+	sort.Strings(workspaces)
+
+	fmt.Fprintln(w, "WORKSPACE SUMMARY:")
+	for _, workspace := range workspaces {
+		fmt.Fprintf(w, "  %s %s\n", padRight(workspace, 30), plain(fmt.Sprintf("🚨 %d RISKS", risksByWorkspace[workspace]), config))
+	}
+}
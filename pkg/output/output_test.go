@@ -0,0 +1,375 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"scnpm/pkg/types"
+)
+
+func TestOutputTable(t *testing.T) {
+	results := []types.ScanResult{
+		{
+			Package:        types.PackageQuery{Name: "event-stream", Version: "3.3.6", Severity: types.SeverityCritical},
+			Found:          true,
+			Instances:      []types.PackageInstance{{Name: "event-stream", Version: "3.3.6", Path: "node_modules/event-stream", LineNumber: 7, Chains: [][]string{{"gulp", "event-stream@3.3.6"}, {"nodemon", "ps-tree", "event-stream@3.3.6"}}}},
+			TotalInstances: 1,
+		},
+		{Package: types.PackageQuery{Name: "left-pad", Version: "1.3.0"}},
+	}
+
+	tests := []struct {
+		name    string
+		config  OutputConfig
+		want    []string
+		notWant []string
+	}{
+		{
+			name:   "risks and safe packages",
+			config: OutputConfig{ShowSafe: true},
+			want: []string{
+				"event-stream                   3.3.6           🚨 RISK  critical   3.3.6           -        L7",
+				"✅ SAFE",
+				"SECURITY SUMMARY: 🚨 1 RISKS DETECTED (1 critical) | ✅ 1 PACKAGES SAFE",
+				"⚠️  WARNING: Found 1 potentially compromised packages in your project!",
+			},
+		},
+		{
+			name:    "risk only",
+			config:  OutputConfig{ShowSafe: true, RiskOnly: true, Warnings: []string{"lockfile is empty"}},
+			want:    []string{"🚨 RISK", "⚠️  WARNING: lockfile is empty"},
+			notWant: []string{"✅ SAFE"},
+		},
+		{
+			name:    "summary only",
+			config:  OutputConfig{ShowSafe: true, SummaryOnly: true},
+			want:    []string{"SECURITY SUMMARY: 🚨 1 RISKS DETECTED (1 critical) | ✅ 1 PACKAGES SAFE"},
+			notWant: []string{"event-stream", "✅ SAFE ", "===="},
+		},
+		{
+			name:   "color",
+			config: OutputConfig{ShowSafe: true, Color: true},
+			want: []string{
+				"\x1b[31m🚨 RISK \x1b[0m \x1b[1;31mcritical  \x1b[0m",
+				"\x1b[32m✅ SAFE \x1b[0m",
+				"\x1b[31mSECURITY SUMMARY: 🚨 1 RISKS DETECTED (1 critical) | ✅ 1 PACKAGES SAFE\x1b[0m",
+			},
+		},
+		{
+			name:    "columns",
+			config:  OutputConfig{ShowSafe: true, Columns: []string{"path", "package", "referencedBy"}},
+			want:    []string{"Path                            Package                        Referenced By\n", "node_modules/event-stream       event-stream                   -\n"},
+			notWant: []string{"Status", "L7"},
+		},
+		{
+			name:   "truncated paths",
+			config: OutputConfig{TruncatePaths: 12},
+			want:   []string{"node_…stream\n"},
+		},
+		{
+			name:   "chains",
+			config: OutputConfig{ShowChains: true},
+			want:   []string{"Path                      Chain\n", "node_modules/event-stream gulp > event-stream@3.3.6 (+1 more)\n"},
+		},
+		{
+			name:    "no color",
+			config:  OutputConfig{ShowSafe: true},
+			notWant: []string{"\x1b["},
+		},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := OutputTable(&buf, results, tt.config); err != nil {
+			t.Fatalf("%s: OutputTable() error = %v", tt.name, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("%s: OutputTable() lacks %q:\n%s", tt.name, want, buf.String())
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(buf.String(), notWant) {
+				t.Errorf("%s: OutputTable() contains %q:\n%s", tt.name, notWant, buf.String())
+			}
+		}
+	}
+
+	if got, want := Summary(results, OutputConfig{}), "SECURITY SUMMARY: 🚨 1 RISKS DETECTED (1 critical) | ✅ 1 PACKAGES SAFE"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	if got := Summary(results, OutputConfig{Interrupted: true}); !strings.HasSuffix(got, " | ⏹ INTERRUPTED, RESULTS ARE PARTIAL") {
+		t.Errorf("Summary() of an interrupted scan = %q, want it marked partial", got)
+	}
+}
+
+func TestOutputTableGolden(t *testing.T) {
+	results := []types.ScanResult{
+		{
+			Package: types.PackageQuery{Name: "event-stream", Version: "3.3.6", Severity: types.SeverityCritical},
+			Found:   true,
+			Instances: []types.PackageInstance{
+				{Name: "event-stream", Version: "3.3.6", Path: "node_modules/event-stream", LineNumber: 7},
+				{Name: "event-stream", Version: "3.3.5", Path: "node_modules/old/node_modules/event-stream", LineNumber: 12},
+				{Name: "event-stream", Version: "^3.3.0", Path: "node_modules/a -> event-stream", LineNumber: 20, IsReference: true},
+				{Name: "event-stream", Version: "^3.3.0", Path: "node_modules/b -> event-stream", LineNumber: 31, IsReference: true, ReferenceType: "peerDependencies", IsDev: true},
+			},
+		},
+		{
+			Package:   types.PackageQuery{Name: "@very-long-scope-name/compromised-package", Version: "1.0.0"},
+			Found:     true,
+			Instances: []types.PackageInstance{{Name: "@very-long-scope-name/compromised-package", Version: "1.0.0", Path: "node_modules/@very-long-scope-name/compromised-package", HasInstallScript: true}},
+		},
+		{
+			Package:   types.PackageQuery{Name: "日本語パッケージ", Version: "2.0.0"},
+			Found:     true,
+			Instances: []types.PackageInstance{{Name: "日本語パッケージ", Version: "2.0.0", Path: "node_modules/日本語パッケージ"}},
+		},
+		{Package: types.PackageQuery{Name: "chalk", Tag: "latest", Error: "registry unreachable"}},
+		{Package: types.PackageQuery{Name: "left-pad", Version: "1.3.0"}},
+	}
+
+	var got bytes.Buffer
+	if err := OutputTable(&got, results, OutputConfig{ShowSafe: true}); err != nil {
+		t.Fatalf("OutputTable() error = %v", err)
+	}
+
+	// Every row's path starts in the same terminal column, whatever came before it
+	column := -1
+	for _, line := range strings.Split(got.String(), "\n") {
+		if i := strings.LastIndex(line, " node_modules/"); i >= 0 {
+			if width := displayWidth(line[:i]); column == -1 {
+				column = width
+			} else if width != column {
+				t.Errorf("path starts in column %d, want %d: %q", width, column, line)
+			}
+		}
+	}
+
+	golden := filepath.Join("testdata", "table.golden.txt")
+	if *update {
+		if err := os.WriteFile(golden, got.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("reading golden file: %v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("OutputTable() differs from %s; run go test -update and review the diff:\n%s", golden, got.String())
+	}
+}
+
+func TestOutputTableASCII(t *testing.T) {
+	results := []types.ScanResult{
+		{
+			Package: types.PackageQuery{Name: "event-stream", Version: "3.3.6||3.3.7", Severity: types.SeverityCritical},
+			Found:   true,
+			Instances: []types.PackageInstance{
+				{Name: "event-stream", Version: "3.3.6", MatchedVersion: "3.3.6", Path: "node_modules/event-stream", IsDev: true, IntegrityMatch: true, Workspace: "web"},
+				{Name: "event-stream", Version: "3.3.6", Path: "node_modules/a/node_modules/event-stream", HasInstallScript: true, MatchReason: "scope-relaxed"},
+				{Name: "event-stream", Version: "3.3.6", Path: "node_modules/a -> event-stream", IsReference: true, ReferenceType: "peerDependencies"},
+				{Name: "event-stream", Version: "^3.3.0", Path: "node_modules/b -> event-stream", IsReference: true, ReferenceType: "optionalDependencies"},
+				{Name: "event-stream", Version: "3.3.6", Path: "node_modules/c/node_modules/event-stream", Suppressed: true},
+			},
+		},
+		{Package: types.PackageQuery{Name: "chalk", Tag: "latest", Version: "5.0.0"}},
+		{Package: types.PackageQuery{Name: "debug", Error: "registry unreachable"}},
+	}
+	for _, check := range types.Checks {
+		results = append(results, types.ScanResult{
+			Package:   types.PackageQuery{Name: "lodahs"},
+			Found:     true,
+			Check:     check,
+			Instances: []types.PackageInstance{{Name: "lodahs", Version: "1.0.0", Path: "node_modules/lodahs", Resembles: "lodash", Distance: 1}},
+		})
+	}
+	config := OutputConfig{ShowSafe: true, ShowWorkspaces: true, ASCII: true, Warnings: []string{"lockfile is old"}}
+
+	var buf bytes.Buffer
+	if err := OutputTable(&buf, results, config); err != nil {
+		t.Fatalf("OutputTable() error = %v", err)
+	}
+	for i, b := range buf.Bytes() {
+		if b >= utf8.RuneSelf {
+			line := strings.Count(buf.String()[:i], "\n") + 1
+			t.Fatalf("OutputTable() in ASCII mode wrote a multi-byte character on line %d:\n%s", line, buf.String())
+		}
+	}
+	for _, want := range []string{"IOC", "SCRIPT", "PEER", "OPT", "SAFE", "ERROR", "latest -> 5.0.0", "> 3.3.6", "~ lodash (1)", "SECURITY SUMMARY: 1 RISKS DETECTED", "WARN: lockfile is old"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("OutputTable() in ASCII mode lacks %q:\n%s", want, buf.String())
+		}
+	}
+	if got := Summary(results, config); strings.ContainsAny(got, "🚨✅🔇") {
+		t.Errorf("Summary() = %q, want no emoji in ASCII mode", got)
+	}
+}
+
+// failingWriter accepts a number of writes, then fails
+type failingWriter struct {
+	writes int
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if f.writes == 0 {
+		return 0, errors.New("disk full")
+	}
+	f.writes--
+	return len(p), nil
+}
+
+func TestOutputWriteErrors(t *testing.T) {
+	results := []types.ScanResult{{Package: types.PackageQuery{Name: "left-pad", Version: "1.3.0"}}}
+	config := OutputConfig{ShowSafe: true}
+	tests := []struct {
+		name   string
+		writes int // Successful writes before the failure
+		output func(w *failingWriter) error
+	}{
+		{"table", 2, func(w *failingWriter) error { return OutputTable(w, results, config) }},
+		{"json", 0, func(w *failingWriter) error { return OutputJSON(w, results, config) }},
+		{"sarif", 0, func(w *failingWriter) error { return OutputSARIF(w, results, config) }},
+		{"html", 1, func(w *failingWriter) error { return OutputHTML(w, results, config) }},
+	}
+
+	for _, tt := range tests {
+		if err := tt.output(&failingWriter{writes: tt.writes}); err == nil || !strings.Contains(err.Error(), "disk full") {
+			t.Errorf("%s: error = %v, want the write error", tt.name, err)
+		}
+	}
+}
+
+func TestOutputRemediation(t *testing.T) {
+	results := []types.ScanResult{
+		{
+			Package:     types.PackageQuery{Name: "evil", Version: "1.0.0"},
+			Found:       true,
+			Instances:   []types.PackageInstance{{Name: "evil", Version: "1.0.0", Path: "node_modules/a/node_modules/evil"}},
+			Remediation: &types.Remediation{Investigate: "npm ls evil", SafeVersion: "1.2.0", Overrides: map[string]string{"evil": ">=1.2.0"}},
+		},
+		{
+			Package:     types.PackageQuery{Name: "bad", Version: "2.0.0"},
+			Found:       true,
+			Instances:   []types.PackageInstance{{Name: "bad", Version: "2.0.0", Path: "node_modules/bad"}},
+			Remediation: &types.Remediation{Investigate: "npm ls bad", Upgrade: "npm install bad@latest"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := OutputTable(&buf, results, OutputConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"SUGGESTED FIXES (2):\n  evil\n    Investigate: npm ls evil\n    Override:    evil to >=1.2.0",
+		"  bad\n    Investigate: npm ls bad\n    Upgrade:     npm install bad@latest\n    No safe version is in the lockfile yet",
+		"  Add to package.json, then run npm install:\n    \"overrides\": {\n      \"evil\": \">=1.2.0\"\n    }\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("OutputTable() lacks %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := OutputGitHubSummary(&buf, results, OutputConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"- **evil**: investigate with `npm ls evil`, override to `>=1.2.0`\n",
+		"- **bad**: investigate with `npm ls bad`, upgrade with `npm install bad@latest` (no safe version is in the lockfile yet)\n",
+		"```json\n  \"overrides\": {\n    \"evil\": \">=1.2.0\"\n  }\n```",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("OutputGitHubSummary() lacks %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	results[0].Remediation, results[1].Remediation = nil, nil
+	if err := OutputTable(&buf, results, OutputConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "SUGGESTED FIXES") {
+		t.Errorf("OutputTable() = %s, want no fixes without remediations", buf.String())
+	}
+}
+
+func TestOutputBaseline(t *testing.T) {
+	results := []types.ScanResult{
+		{
+			Package:   types.PackageQuery{Name: "debug", Version: "4.4.2"},
+			Found:     true,
+			Instances: []types.PackageInstance{{Name: "debug", Version: "4.4.2", Path: "node_modules/debug", Baseline: true}},
+		},
+		{
+			Package: types.PackageQuery{Name: "chalk", Version: "5.6.1"},
+			Found:   true,
+			Instances: []types.PackageInstance{
+				{Name: "chalk", Version: "5.6.1", Path: "node_modules/chalk", Baseline: true},
+				{Name: "chalk", Version: "5.6.1", Path: "node_modules/a/node_modules/chalk"},
+			},
+		},
+	}
+
+	totals := CountResults(results)
+	if totals.Risks != 1 || totals.Baseline != 1 {
+		t.Errorf("CountResults() = %+v, want chalk's new finding as the only risk and debug in the baseline", totals)
+	}
+
+	var buf bytes.Buffer
+	if err := OutputTable(&buf, results, OutputConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"📌 BASELINE 4.4.2", "🚨 RISK     5.6.1", "SECURITY SUMMARY: 🚨 1 RISKS DETECTED | ✅ 0 PACKAGES SAFE | 📌 1 BASELINE"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("OutputTable() lacks %q:\n%s", want, buf.String())
+		}
+	}
+
+	if got := githubLevel(Finding{Result: results[0], Instance: results[0].Instances[0]}, OutputConfig{}); got != "notice" {
+		t.Errorf("githubLevel() of a baseline finding = %q, want notice", got)
+	}
+}
+
+func TestOutputCount(t *testing.T) {
+	results := []types.ScanResult{
+		{
+			Package: types.PackageQuery{Name: "debug", Version: "4.4.2"},
+			Found:   true,
+			Instances: []types.PackageInstance{
+				{Name: "debug", Version: "4.4.2", Path: "node_modules/debug"},
+				{Name: "debug", Version: "^4.4.0", Path: "node_modules/a -> debug", IsReference: true},
+				{Name: "debug", Version: "^4.4.0", Path: "node_modules/b -> debug", IsReference: true, Suppressed: true},
+			},
+		},
+		{
+			Package:   types.PackageQuery{Name: "chalk", Version: "5.6.1"},
+			Found:     true,
+			Instances: []types.PackageInstance{{Name: "chalk", Version: "^5.6.0", Path: " -> chalk", IsReference: true}},
+		},
+		{Package: types.PackageQuery{Name: "color", Version: "5.0.1"}},
+		{Package: types.PackageQuery{Name: "lodahs"}, Check: types.CheckTyposquat, Found: true, Instances: []types.PackageInstance{{Name: "lodahs", Path: "node_modules/lodahs"}}},
+	}
+
+	var buf bytes.Buffer
+	if err := OutputCount(&buf, results, false); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "2\n" {
+		t.Errorf("OutputCount() = %q, want the number of risks alone", buf.String())
+	}
+
+	buf.Reset()
+	if err := OutputCount(&buf, results, true); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"risks":2,"safe":1,"references":2}` + "\n"; buf.String() != want {
+		t.Errorf("OutputCount() in JSON = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,110 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"scnpm/pkg/types"
+)
+
+// remediated returns the results with active findings that carry a remediation (--suggest-fixes)
+func remediated(results []types.ScanResult) []types.ScanResult {
+	var list []types.ScanResult
+	for _, result := range results {
+		if result.Remediation != nil && len(activeInstances(result)) > 0 {
+			list = append(list, result)
+		}
+	}
+	return list
+}
+
+// overridesBlock merges the overrides of every remediation into a "overrides" entry ready to paste
+// into package.json, or returns "" when none has one
+func overridesBlock(list []types.ScanResult) string {
+	overrides := make(map[string]string)
+	for _, result := range list {
+		for name, version := range result.Remediation.Overrides {
+			overrides[name] = version
+		}
+	}
+	if len(overrides) == 0 {
+		return ""
+	}
+	var buf strings.Builder
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false) // Keep ">=" readable
+	encoder.SetIndent("", "  ")
+	encoder.Encode(map[string]map[string]string{"overrides": overrides})
+	// Without the enclosing braces, so that it pastes into an existing package.json
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	return strings.Join(lines[1:len(lines)-1], "\n")
+}
+
+// outputRemediation prints a section with the suggested fix of every risky package, closed by the
+// overrides for the transitive ones
+func outputRemediation(w io.Writer, results []types.ScanResult) {
+	list := remediated(results)
+	if len(list) == 0 {
+		return
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 120))
+	fmt.Fprintf(w, "SUGGESTED FIXES (%d):\n", len(list))
+	for _, result := range list {
+		remediation := result.Remediation
+		fmt.Fprintf(w, "  %s\n", result.Package.Name)
+		fmt.Fprintf(w, "    Investigate: %s\n", remediation.Investigate)
+		if remediation.Upgrade != "" {
+			fmt.Fprintf(w, "    Upgrade:     %s\n", remediation.Upgrade)
+		}
+		if len(remediation.Overrides) > 0 {
+			fmt.Fprintf(w, "    Override:    %s to %s, as a transitive dependency (below)\n", result.Package.Name, overrideValue(remediation))
+		}
+		if remediation.SafeVersion == "" {
+			fmt.Fprintln(w, "    No safe version is in the lockfile yet; pick one from `npm view "+result.Package.Name+" versions`")
+		}
+	}
+	if block := overridesBlock(list); block != "" {
+		fmt.Fprintln(w, "  Add to package.json, then run npm install:")
+		for _, line := range strings.Split(block, "\n") {
+			fmt.Fprintln(w, "  "+line)
+		}
+	}
+}
+
+// outputRemediationMarkdown writes the suggested fixes as a Markdown section
+func outputRemediationMarkdown(w io.Writer, results []types.ScanResult) {
+	list := remediated(results)
+	if len(list) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "### Suggested fixes")
+	fmt.Fprintln(w)
+	for _, result := range list {
+		remediation := result.Remediation
+		fmt.Fprintf(w, "- **%s**: investigate with `%s`", result.Package.Name, remediation.Investigate)
+		if remediation.Upgrade != "" {
+			fmt.Fprintf(w, ", upgrade with `%s`", remediation.Upgrade)
+		}
+		if len(remediation.Overrides) > 0 {
+			fmt.Fprintf(w, ", override to `%s`", overrideValue(remediation))
+		}
+		if remediation.SafeVersion == "" {
+			fmt.Fprint(w, " (no safe version is in the lockfile yet)")
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w)
+	if block := overridesBlock(list); block != "" {
+		fmt.Fprintf(w, "Add to package.json:\n\n```json\n%s\n```\n\n", block)
+	}
+}
+
+// overrideValue returns the version a remediation overrides its package to
+func overrideValue(remediation *types.Remediation) string {
+	for _, version := range remediation.Overrides {
+		return version
+	}
+	return ""
+}
@@ -0,0 +1,259 @@
+package output
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"scnpm/pkg/types"
+)
+
+// SARIF 2.1.0 document identifiers
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// sarifFingerprint names scnpm's partial fingerprint; GitHub deduplicates alerts across runs by it
+const sarifFingerprint = "scnpmFinding/v1"
+
+// sarifLog is the root of a SARIF document
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string              `json:"id"`
+	Name                 string              `json:"name,omitempty"`
+	ShortDescription     sarifMessage        `json:"shortDescription"`
+	FullDescription      *sarifMessage       `json:"fullDescription,omitempty"`
+	HelpURI              string              `json:"helpUri,omitempty"`
+	DefaultConfiguration sarifConfiguration  `json:"defaultConfiguration"`
+	Properties           sarifRuleProperties `json:"properties"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifRuleProperties struct {
+	Tags             []string `json:"tags"`
+	SecuritySeverity string   `json:"security-severity,omitempty"` // GitHub ranks alerts by this CVSS-like score
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string             `json:"ruleId"`
+	RuleIndex           int                `json:"ruleIndex"`
+	Level               string             `json:"level"`
+	Message             sarifMessage       `json:"message"`
+	Locations           []sarifLocation    `json:"locations"`
+	PartialFingerprints map[string]string  `json:"partialFingerprints"`
+	Suppressions        []sarifSuppression `json:"suppressions,omitempty"`
+	BaselineState       string             `json:"baselineState,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+// OutputSARIF writes results to w as a SARIF 2.1.0 log for code scanning tools such as GitHub's:
+// a rule per bad-package query or check that found something, and a result per instance located
+// in its lockfile
+func OutputSARIF(w io.Writer, results []types.ScanResult, config OutputConfig) error {
+	return writeJSON(w, "SARIF", buildSARIF(results, config))
+}
+
+// buildSARIF converts results into a SARIF log
+func buildSARIF(results []types.ScanResult, config OutputConfig) sarifLog {
+	driver := sarifDriver{
+		Name:           "scnpm",
+		Version:        config.ToolVersion,
+		InformationURI: "https://github.com/GigacoreLLC/scnpm",
+		Rules:          []sarifRule{},
+	}
+	run := sarifRun{Results: []sarifResult{}}
+	ruleIndex := make(map[string]int)
+
+	for _, result := range results {
+		instances := result.Instances
+		if len(instances) == 0 {
+			continue
+		}
+
+		rule := sarifRuleFor(result)
+		index, seen := ruleIndex[rule.ID]
+		if !seen {
+			index = len(driver.Rules)
+			ruleIndex[rule.ID] = index
+			driver.Rules = append(driver.Rules, rule)
+		}
+
+		for _, instance := range instances {
+			lockfile := config.Lockfile
+			if instance.Lockfile != "" {
+				lockfile = instance.Lockfile
+			}
+			location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: lockfile}}}
+			if instance.LineNumber > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: instance.LineNumber}
+			}
+
+			level := rule.DefaultConfiguration.Level
+			if instance.IsReference && level == "error" {
+				// A declaration that admits a bad version isn't an installed one
+				level = "warning"
+			}
+
+			entry := sarifResult{
+				RuleID:    rule.ID,
+				RuleIndex: index,
+				Level:     level,
+				Message:   sarifMessage{Text: sarifText(result, instance)},
+				Locations: []sarifLocation{location},
+				PartialFingerprints: map[string]string{
+					sarifFingerprint: fingerprint(rule.ID, lockfile, instance.Path, instance.Version, instance.Script, instance.Heuristic),
+				},
+			}
+			if instance.Suppressed {
+				entry.Suppressions = []sarifSuppression{{Kind: "external", Justification: instance.SuppressedReason}}
+			}
+			if instance.Baseline {
+				entry.BaselineState = "unchanged"
+			}
+			run.Results = append(run.Results, entry)
+		}
+	}
+
+	run.Tool = sarifTool{Driver: driver}
+	return sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}}
+}
+
+// sarifRuleFor describes the query or check behind a result as a rule
+func sarifRuleFor(result types.ScanResult) sarifRule {
+	if result.Check != "" {
+		title := checkSections[result.Check].Title
+		return sarifRule{
+			ID:                   "check/" + result.Check,
+			Name:                 title,
+			ShortDescription:     sarifMessage{Text: strings.ToUpper(title[:1]) + strings.ToLower(title[1:])},
+			DefaultConfiguration: sarifConfiguration{Level: "note"},
+			Properties:           sarifRuleProperties{Tags: []string{"supply-chain", result.Check}},
+		}
+	}
+
+	query := result.Package
+	target := query.Name + "@" + targetLabel(query)
+	rule := sarifRule{
+		ID:                   query.Name + "@" + sarifVersionLabel(query),
+		ShortDescription:     sarifMessage{Text: "Compromised package " + target},
+		DefaultConfiguration: sarifConfiguration{Level: sarifLevel(query.Severity)},
+		Properties: sarifRuleProperties{
+			Tags:             []string{"security", "supply-chain"},
+			SecuritySeverity: securitySeverity(query.Severity),
+		},
+	}
+	if query.Note != "" {
+		rule.FullDescription = &sarifMessage{Text: query.Note}
+	}
+	rule.HelpURI = advisoryURL(query.Advisory)
+	return rule
+}
+
+// sarifVersionLabel is the version part of a rule ID: the query's version, or "any"
+func sarifVersionLabel(query types.PackageQuery) string {
+	if query.Version == "" {
+		return "any"
+	}
+	return query.Version
+}
+
+// sarifText is the message of a SARIF result
+func sarifText(result types.ScanResult, instance types.PackageInstance) string {
+	if result.Check != "" {
+		label, note := checkDetail(result.Check, instance)
+		text := fmt.Sprintf("%s@%s at %s", instance.Name, instance.Version, instance.Path)
+		if label != "" {
+			text += ": " + label
+		}
+		return text + note
+	}
+	if instance.IsReference {
+		return fmt.Sprintf("%s@%s is declared in %s at %s and admits the bad version %s", instance.Name, instance.Version, instance.ReferenceType, instance.Path, targetLabel(result.Package))
+	}
+	return fmt.Sprintf("%s@%s found at %s", instance.Name, instance.Version, instance.Path)
+}
+
+// sarifLevel maps a query's severity onto a SARIF level; entries without one are treated as
+// compromised packages, which are errors
+func sarifLevel(severity string) string {
+	switch severity {
+	case types.SeverityModerate:
+		return "warning"
+	case types.SeverityLow:
+		return "note"
+	default:
+		return "error"
+	}
+}
+
+// securitySeverity maps a query's severity onto the score GitHub uses to rank security alerts
+func securitySeverity(severity string) string {
+	switch severity {
+	case types.SeverityCritical, "":
+		return "9.5"
+	case types.SeverityHigh:
+		return "8.0"
+	case types.SeverityModerate:
+		return "5.5"
+	default:
+		return "2.0"
+	}
+}
+
+// fingerprint identifies a finding across runs, independent of its line number
+func fingerprint(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,126 @@
+package output
+
+import (
+	"encoding/json"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+// validateSARIF checks a document against the SARIF 2.1.0 schema, plus what the schema leaves
+// open but GitHub's ingestion relies on: unique rule ids, a ruleIndex that points at the result's
+// rule, and non-empty messages and locations
+func validateSARIF(t *testing.T, data []byte) {
+	t.Helper()
+	log := checkPublishedSchema(t, "sarif-schema-2.1.0.json", data)
+	runs, _ := log["runs"].([]any)
+	if len(runs) == 0 {
+		t.Fatal("runs is empty")
+	}
+
+	for _, r := range runs {
+		run := r.(map[string]any)
+		driver := run["tool"].(map[string]any)["driver"].(map[string]any)
+		if name, _ := driver["name"].(string); name == "" {
+			t.Error("driver name is empty")
+		}
+
+		rules, _ := driver["rules"].([]any)
+		ids := make(map[string]bool)
+		for _, r := range rules {
+			id, _ := r.(map[string]any)["id"].(string)
+			if id == "" || ids[id] {
+				t.Errorf("rule id %q is empty or duplicated", id)
+			}
+			ids[id] = true
+		}
+
+		results, _ := run["results"].([]any)
+		for _, r := range results {
+			result := r.(map[string]any)
+			if text, _ := result["message"].(map[string]any)["text"].(string); text == "" {
+				t.Error("result message text is empty")
+			}
+
+			index, ok := result["ruleIndex"].(float64)
+			if !ok || int(index) < 0 || int(index) >= len(rules) {
+				t.Fatalf("ruleIndex %v is out of range of %d rules", result["ruleIndex"], len(rules))
+			}
+			if rules[int(index)].(map[string]any)["id"] != result["ruleId"] {
+				t.Errorf("ruleIndex %v points at %v, want %v", index, rules[int(index)].(map[string]any)["id"], result["ruleId"])
+			}
+
+			for _, l := range result["locations"].([]any) {
+				physical := l.(map[string]any)["physicalLocation"].(map[string]any)
+				if uri, _ := physical["artifactLocation"].(map[string]any)["uri"].(string); uri == "" {
+					t.Error("artifactLocation uri is empty")
+				}
+			}
+		}
+	}
+}
+
+func TestBuildSARIF(t *testing.T) {
+	results := []types.ScanResult{
+		{
+			Package: types.PackageQuery{Name: "lodash", Version: "4.17.20", Severity: types.SeverityModerate, Advisory: "GHSA-35jh-r3h4-6jhm", Note: "Command injection"},
+			Found:   true,
+			Instances: []types.PackageInstance{
+				{Name: "lodash", Version: "4.17.20", Path: "node_modules/lodash", LineNumber: 12},
+				{Name: "lodash", Version: "4.17.20", Path: "node_modules/a/node_modules/lodash", LineNumber: 40, Suppressed: true, SuppressedReason: "not reachable"},
+			},
+		},
+		{
+			Package:   types.PackageQuery{Name: "lodash", Version: "4.17.21"},
+			Found:     true,
+			Instances: []types.PackageInstance{{Name: "lodash", Version: "^4.17.0", Path: "node_modules/b -> lodash", IsReference: true, ReferenceType: "dependencies"}},
+		},
+		{Package: types.PackageQuery{Name: "left-pad", Version: "1.3.0"}},
+		{
+			Package:   types.PackageQuery{Name: "lodahs"},
+			Found:     true,
+			Check:     types.CheckTyposquat,
+			Instances: []types.PackageInstance{{Name: "lodahs", Version: "1.0.0", Path: "node_modules/lodahs", Resembles: "lodash", Distance: 1, Lockfile: "app/package-lock.json"}},
+		},
+	}
+
+	log := buildSARIF(results, OutputConfig{ToolVersion: "1.2.3", Lockfile: "package-lock.json"})
+	data, err := json.Marshal(log)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	validateSARIF(t, data)
+
+	run := log.Runs[0]
+	if run.Tool.Driver.Version != "1.2.3" || len(run.Tool.Driver.Rules) != 3 || len(run.Results) != 4 {
+		t.Fatalf("run = %+v, want 3 rules (no rule for left-pad) and 4 results", run)
+	}
+
+	rule := run.Tool.Driver.Rules[0]
+	if rule.ID != "lodash@4.17.20" || rule.DefaultConfiguration.Level != "warning" || rule.HelpURI != "https://github.com/advisories/GHSA-35jh-r3h4-6jhm" {
+		t.Errorf("rule = %+v, want a warning for the moderate lodash advisory", rule)
+	}
+
+	installed, suppressed, reference, check := run.Results[0], run.Results[1], run.Results[2], run.Results[3]
+	if installed.Locations[0].PhysicalLocation.Region.StartLine != 12 || installed.Locations[0].PhysicalLocation.ArtifactLocation.URI != "package-lock.json" {
+		t.Errorf("installed location = %+v, want package-lock.json line 12", installed.Locations[0])
+	}
+	if len(suppressed.Suppressions) != 1 || suppressed.Suppressions[0].Justification != "not reachable" {
+		t.Errorf("suppressed = %+v, want an external suppression with the ignore reason", suppressed)
+	}
+	if installed.PartialFingerprints[sarifFingerprint] == suppressed.PartialFingerprints[sarifFingerprint] {
+		t.Error("two instances share a fingerprint")
+	}
+	if reference.RuleIndex != 1 || reference.Level != "warning" || reference.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("reference = %+v, want rule 1 at warning level without a region", reference)
+	}
+	if check.RuleID != "check/typosquat" || check.Level != "note" || check.Locations[0].PhysicalLocation.ArtifactLocation.URI != "app/package-lock.json" {
+		t.Errorf("check = %+v, want a note located in its own lockfile", check)
+	}
+
+	// Fingerprints don't depend on line numbers, so alerts survive edits elsewhere in the lockfile
+	results[0].Instances[0].LineNumber = 99
+	if again := buildSARIF(results, OutputConfig{Lockfile: "package-lock.json"}); again.Runs[0].Results[0].PartialFingerprints[sarifFingerprint] != installed.PartialFingerprints[sarifFingerprint] {
+		t.Error("fingerprint changed with the line number")
+	}
+}
@@ -0,0 +1,366 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxSchemaErrors is how many violations ValidateReport lists before giving up
+const maxSchemaErrors = 5
+
+// schemaValidator checks a document against a JSON Schema, collecting the violations. It knows
+// the draft-07 keywords used by report.schema.json and by the CycloneDX, SARIF and SPDX schemas
+// the tests check those formats against; annotations and unknown keywords are ignored, and $ref
+// only resolves within root and, by file name, into documents.
+type schemaValidator struct {
+	root      map[string]any
+	documents map[string]map[string]any // Schemas a $ref such as "spdx.schema.json#/..." can name
+	errors    []string
+}
+
+func (v *schemaValidator) errorf(format string, args ...any) {
+	v.errors = append(v.errors, fmt.Sprintf(format, args...))
+}
+
+// matches reports whether value conforms to schema, without recording the violations
+func (v *schemaValidator) matches(schema any, value any) bool {
+	sub := &schemaValidator{root: v.root, documents: v.documents}
+	sub.validateAny("", schema, value)
+	return len(sub.errors) == 0
+}
+
+// validateAny validates against a schema that may also be a boolean: true allows anything,
+// false nothing
+func (v *schemaValidator) validateAny(path string, schema any, value any) {
+	switch schema := schema.(type) {
+	case map[string]any:
+		v.validate(path, schema, value)
+	case bool:
+		if !schema {
+			v.errorf("%s isn't allowed", path)
+		}
+	}
+}
+
+func (v *schemaValidator) validate(path string, schema map[string]any, value any) {
+	if ref, ok := schema["$ref"].(string); ok {
+		target, root, ok := v.resolve(ref)
+		if !ok {
+			v.errorf("%s: the schema has no %s", path, ref)
+			return
+		}
+		// The target's own references are relative to the document it's in
+		outer := v.root
+		v.root = root
+		v.validateAny(path, target, value)
+		v.root = outer
+		return
+	}
+	if want, ok := schema["const"]; ok && !reflect.DeepEqual(value, want) {
+		v.errorf("%s = %v, want %v", path, value, want)
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, allowed := range enum {
+			found = found || reflect.DeepEqual(value, allowed)
+		}
+		if !found {
+			v.errorf("%s = %v, want one of %v", path, value, enum)
+		}
+	}
+	v.validateCombinations(path, schema, value)
+
+	if want, ok := schema["type"]; ok && !hasType(value, want) {
+		v.errorf("%s is %s, want %s", path, jsonType(value), typeNames(want))
+		return
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		v.validateObject(path, schema, value)
+	case []any:
+		v.validateArray(path, schema, value)
+	case string:
+		v.validateString(path, schema, value)
+	case float64:
+		v.validateNumber(path, schema, value)
+	}
+}
+
+// validateCombinations applies allOf, anyOf, oneOf, not and if/then/else
+func (v *schemaValidator) validateCombinations(path string, schema map[string]any, value any) {
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, sub := range all {
+			v.validateAny(path, sub, value)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		matched := false
+		for _, sub := range anyOf {
+			matched = matched || v.matches(sub, value)
+		}
+		if !matched {
+			v.errorf("%s matches none of the schemas anyOf allows", path)
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		matched := 0
+		for _, sub := range oneOf {
+			if v.matches(sub, value) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			v.errorf("%s matches %d of the schemas oneOf allows, want exactly 1", path, matched)
+		}
+	}
+	if not, ok := schema["not"]; ok && v.matches(not, value) {
+		v.errorf("%s matches a schema it mustn't", path)
+	}
+	if condition, ok := schema["if"]; ok {
+		if v.matches(condition, value) {
+			if then, ok := schema["then"]; ok {
+				v.validateAny(path, then, value)
+			}
+		} else if otherwise, ok := schema["else"]; ok {
+			v.validateAny(path, otherwise, value)
+		}
+	}
+}
+
+func (v *schemaValidator) validateObject(path string, schema map[string]any, object map[string]any) {
+	required, _ := schema["required"].([]any)
+	for _, name := range required {
+		if _, ok := object[name.(string)]; !ok {
+			v.errorf("%s lacks required property %q", path, name)
+		}
+	}
+	if minimum, ok := schema["minProperties"].(float64); ok && float64(len(object)) < minimum {
+		v.errorf("%s has %d properties, want at least %v", path, len(object), minimum)
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	patterns, _ := schema["patternProperties"].(map[string]any)
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property := object[name]
+		matched := false
+		if sub, ok := properties[name]; ok {
+			v.validateAny(path+"."+name, sub, property)
+			matched = true
+		}
+		for pattern, sub := range patterns {
+			if regexp.MustCompile(pattern).MatchString(name) {
+				v.validateAny(path+"."+name, sub, property)
+				matched = true
+			}
+		}
+		if matched {
+			continue
+		}
+		if additional, ok := schema["additionalProperties"].(map[string]any); ok {
+			v.validate(path+"."+name, additional, property)
+		} else if schema["additionalProperties"] == false {
+			v.errorf("%s has property %q, which the schema doesn't allow", path, name)
+		}
+	}
+}
+
+func (v *schemaValidator) validateArray(path string, schema map[string]any, array []any) {
+	if minimum, ok := schema["minItems"].(float64); ok && float64(len(array)) < minimum {
+		v.errorf("%s has %d items, want at least %v", path, len(array), minimum)
+	}
+	if maximum, ok := schema["maxItems"].(float64); ok && float64(len(array)) > maximum {
+		v.errorf("%s has %d items, want at most %v", path, len(array), maximum)
+	}
+	if schema["uniqueItems"] == true {
+		for i := range array {
+			for j := i + 1; j < len(array); j++ {
+				if reflect.DeepEqual(array[i], array[j]) {
+					v.errorf("%s[%d] repeats %s[%d], but the items must be unique", path, j, path, i)
+				}
+			}
+		}
+	}
+
+	// items is either a schema for every item or, as a list, one for each leading item with
+	// additionalItems for the rest
+	tuple, isTuple := schema["items"].([]any)
+	for i, item := range array {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case !isTuple:
+			if items, ok := schema["items"]; ok {
+				v.validateAny(itemPath, items, item)
+			}
+		case i < len(tuple):
+			v.validateAny(itemPath, tuple[i], item)
+		default:
+			if additional, ok := schema["additionalItems"]; ok {
+				v.validateAny(itemPath, additional, item)
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) validateString(path string, schema map[string]any, s string) {
+	length := float64(utf8.RuneCountInString(s))
+	if minimum, ok := schema["minLength"].(float64); ok && length < minimum {
+		v.errorf("%s = %q, want at least %v characters", path, s, minimum)
+	}
+	if maximum, ok := schema["maxLength"].(float64); ok && length > maximum {
+		v.errorf("%s = %q, want at most %v characters", path, s, maximum)
+	}
+	if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(s) {
+		v.errorf("%s = %q, want a match of %s", path, s, pattern)
+	}
+	switch schema["format"] {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			v.errorf("%s: %v", path, err)
+		}
+	case "uri":
+		if u, err := url.Parse(s); err != nil || !u.IsAbs() {
+			v.errorf("%s = %q, want an absolute URI", path, s)
+		}
+	case "uri-reference", "iri-reference":
+		if _, err := url.Parse(s); err != nil {
+			v.errorf("%s = %q, want a URI reference: %v", path, s, err)
+		}
+	}
+}
+
+func (v *schemaValidator) validateNumber(path string, schema map[string]any, n float64) {
+	if minimum, ok := schema["minimum"].(float64); ok && n < minimum {
+		v.errorf("%s = %v, want at least %v", path, n, minimum)
+	}
+	if maximum, ok := schema["maximum"].(float64); ok && n > maximum {
+		v.errorf("%s = %v, want at most %v", path, n, maximum)
+	}
+	if minimum, ok := schema["exclusiveMinimum"].(float64); ok && n <= minimum {
+		v.errorf("%s = %v, want more than %v", path, n, minimum)
+	}
+	if maximum, ok := schema["exclusiveMaximum"].(float64); ok && n >= maximum {
+		v.errorf("%s = %v, want less than %v", path, n, maximum)
+	}
+}
+
+// resolve looks up a "#/..." JSON pointer in the root schema, or one in a document named before
+// the "#", and returns the schema it points at along with that document
+func (v *schemaValidator) resolve(ref string) (any, map[string]any, bool) {
+	name, pointer, _ := strings.Cut(ref, "#")
+	root := v.root
+	if name != "" {
+		var ok bool
+		if root, ok = v.documents[name]; !ok {
+			return nil, nil, false
+		}
+	}
+	var node any = root
+	for _, token := range strings.Split(pointer, "/")[1:] {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		object, ok := node.(map[string]any)
+		if !ok {
+			return nil, nil, false
+		}
+		if node, ok = object[token]; !ok {
+			return nil, nil, false
+		}
+	}
+	return node, root, true
+}
+
+// hasType reports whether value is of the JSON type, or one of the list of types, want names
+func hasType(value any, want any) bool {
+	if types, ok := want.([]any); ok {
+		for _, t := range types {
+			if hasType(value, t) {
+				return true
+			}
+		}
+		return false
+	}
+	switch want {
+	case "object", "array", "string", "boolean", "null", "number":
+		return jsonType(value) == typeNames(want)
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	}
+	return true
+}
+
+// typeNames describes a schema's type keyword for error messages: "an object", "a string or null"
+func typeNames(want any) string {
+	if types, ok := want.([]any); ok {
+		names := make([]string, len(types))
+		for i, t := range types {
+			names[i] = typeNames(t)
+		}
+		return strings.Join(names, " or ")
+	}
+	switch want {
+	case "object", "array", "integer":
+		return "an " + want.(string)
+	case "null":
+		return "null"
+	}
+	return fmt.Sprintf("a %v", want)
+}
+
+// jsonType names the JSON type of a decoded value for error messages
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// ValidateReport checks that data is a JSON report (--output json) conforming to JSONSchema
+func ValidateReport(data []byte) error {
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("not JSON: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(JSONSchema, &schema); err != nil {
+		return fmt.Errorf("invalid report schema: %v", err)
+	}
+	if object, ok := document.(map[string]any); ok {
+		if version, ok := object["schemaVersion"].(float64); ok && version != JSONSchemaVersion {
+			return fmt.Errorf("schema version %v isn't supported; this scnpm writes version %d", version, JSONSchemaVersion)
+		}
+	}
+
+	v := &schemaValidator{root: schema}
+	v.validate("report", schema, document)
+	if len(v.errors) > maxSchemaErrors {
+		v.errors = append(v.errors[:maxSchemaErrors], fmt.Sprintf("and %d more", len(v.errors)-maxSchemaErrors))
+	}
+	if len(v.errors) > 0 {
+		return fmt.Errorf("doesn't match the report schema: %s", strings.Join(v.errors, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package output
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// publishedSchemas maps the schemas of standard formats kept in testdata to where they're
+// published. They're meant to be unmodified copies; the ones transcribed without network access
+// say so in their $comment until they're replaced with a download.
+var publishedSchemas = map[string]string{
+	"sarif-schema-2.1.0.json": "https://docs.oasis-open.org/sarif/sarif/v2.1.0/errata01/os/schemas/sarif-schema-2.1.0.json",
+	"bom-1.5.schema.json":     "https://raw.githubusercontent.com/CycloneDX/specification/1.5/schema/bom-1.5.schema.json",
+	// Referred to by bom-1.5.schema.json
+	"spdx.schema.json":     "https://raw.githubusercontent.com/CycloneDX/specification/1.5/schema/spdx.schema.json",
+	"jsf-0.82.schema.json": "https://raw.githubusercontent.com/CycloneDX/specification/1.5/schema/jsf-0.82.schema.json",
+	"spdx-schema-2.3.json": "https://raw.githubusercontent.com/spdx/spdx-spec/v2.3/schemas/spdx-schema.json",
+}
+
+// checkPublishedSchema validates data against a schema of a standard format kept in testdata and
+// returns the decoded document for the checks the schema can't express
+func checkPublishedSchema(t *testing.T, name string, data []byte) map[string]any {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("%v; download it unmodified from %s", err, publishedSchemas[name])
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if comment, _ := schema["$comment"].(string); strings.HasPrefix(comment, "Transcribed") {
+		t.Logf("%s is a transcription, not the published schema; replace it with %s", name, publishedSchemas[name])
+	}
+	var document map[string]any
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	// The schemas next to it are the ones its references can name
+	documents := make(map[string]map[string]any)
+	others, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range others {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var other map[string]any
+		if err := json.Unmarshal(raw, &other); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		documents[filepath.Base(path)] = other
+	}
+
+	v := &schemaValidator{root: schema, documents: documents}
+	v.validate("document", schema, document)
+	for _, err := range v.errors {
+		t.Errorf("%s: %s", name, err)
+	}
+	return document
+}
+
+func TestSchemaValidator(t *testing.T) {
+	schema := map[string]any{}
+	if err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"id": {"$ref": "#/definitions/id"},
+			"count": {"type": ["integer", "null"], "minimum": 1},
+			"tags": {"type": "array", "uniqueItems": true, "items": {"type": "string", "minLength": 1}},
+			"pair": {"type": "array", "items": [{"type": "string"}], "additionalItems": false},
+			"license": {"oneOf": [{"required": ["id"]}, {"required": ["name"]}]},
+			"url": {"type": "string", "format": "uri"},
+			"signature": {"$ref": "jsf.json#/definitions/signature"}
+		},
+		"additionalProperties": false,
+		"definitions": {"id": {"type": "string", "pattern": "^[a-z]+$"}}
+	}`), &schema); err != nil {
+		t.Fatal(err)
+	}
+	// A reference into another document resolves that document's own references against it
+	jsf := map[string]any{}
+	if err := json.Unmarshal([]byte(`{
+		"definitions": {
+			"signature": {"$ref": "#/definitions/signer"},
+			"signer": {"type": "object", "required": ["algorithm"]}
+		}
+	}`), &jsf); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		document string
+		want     string // A fragment of the only error, or "" for none
+	}{
+		{document: `{"id": "abc", "count": 2, "tags": ["a", "b"], "pair": ["x"], "license": {"id": "MIT"}, "url": "https://example.com"}`},
+		{document: `{"count": null}`},
+		{document: `{"id": "ABC"}`, want: "want a match of"},
+		{document: `{"count": 1.5}`, want: "want an integer or null"},
+		{document: `{"count": 0}`, want: "want at least 1"},
+		{document: `{"tags": ["a", "a"]}`, want: "must be unique"},
+		{document: `{"tags": [""]}`, want: "at least 1 characters"},
+		{document: `{"pair": ["x", "y"]}`, want: "isn't allowed"},
+		{document: `{"license": {"id": "MIT", "name": "MIT"}}`, want: "matches 2 of the schemas"},
+		{document: `{"license": {}}`, want: "matches 0 of the schemas"},
+		{document: `{"url": "/relative"}`, want: "want an absolute URI"},
+		{document: `{"extra": true}`, want: "doesn't allow"},
+		{document: `{"signature": {"algorithm": "ES256"}}`},
+		{document: `{"signature": {}}`, want: "algorithm"},
+	}
+	for _, tt := range tests {
+		var document any
+		if err := json.Unmarshal([]byte(tt.document), &document); err != nil {
+			t.Fatal(err)
+		}
+		v := &schemaValidator{root: schema, documents: map[string]map[string]any{"jsf.json": jsf}}
+		v.validate("document", schema, document)
+		switch {
+		case tt.want == "" && len(v.errors) > 0:
+			t.Errorf("validate(%s) = %v, want no errors", tt.document, v.errors)
+		case tt.want != "" && (len(v.errors) != 1 || !strings.Contains(v.errors[0], tt.want)):
+			t.Errorf("validate(%s) = %v, want one error with %q", tt.document, v.errors, tt.want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"scnpm/pkg/license"
+	"scnpm/pkg/types"
+)
+
+// DefaultNamespacePrefix is where SPDX document namespaces live unless --namespace-prefix says
+// otherwise
+const DefaultNamespacePrefix = "https://spdx.org/spdxdocs/scnpm"
+
+// spdxNoAssertion marks SPDX fields scnpm can't determine
+const spdxNoAssertion = "NOASSERTION"
+
+// spdxDocument is the root of an SPDX 2.3 JSON document
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	SourceInfo       string            `json:"sourceInfo,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+	Annotations      []spdxAnnotation  `json:"annotations,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxAnnotation struct {
+	AnnotationDate string `json:"annotationDate"`
+	AnnotationType string `json:"annotationType"`
+	Annotator      string `json:"annotator"`
+	Comment        string `json:"comment"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxChecksumAlgorithms maps integrity algorithms onto SPDX checksum algorithms
+var spdxChecksumAlgorithms = map[string]string{
+	"sha1":   "SHA1",
+	"sha256": "SHA256",
+	"sha384": "SHA384",
+	"sha512": "SHA512",
+}
+
+// OutputSPDX writes the installed packages to w as an SPDX 2.3 document, annotating those that
+// matched a bad-package entry. The document is stamped with reportTime, so that with a fixed
+// --namespace-prefix repeated runs on the same lockfile are identical.
+func OutputSPDX(w io.Writer, results []types.ScanResult, config OutputConfig) error {
+	created, err := reportTime()
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, "SPDX", buildSPDX(results, config, created))
+}
+
+// buildSPDX converts the inventory and results into an SPDX document. Its namespace is derived
+// from the lockfile's contents, so the same lockfile always gets the same namespace.
+func buildSPDX(results []types.ScanResult, config OutputConfig, created time.Time) spdxDocument {
+	timestamp := created.UTC().Format(time.RFC3339)
+	creator := "Tool: scnpm"
+	if config.ToolVersion != "" {
+		creator += "-" + config.ToolVersion
+	}
+
+	name := config.Lockfile
+	if name == "" {
+		name = "lockfile"
+	}
+	prefix := config.NamespacePrefix
+	if prefix == "" {
+		prefix = DefaultNamespacePrefix
+	}
+
+	document := spdxDocument{
+		SPDXVersion:  "SPDX-2.3",
+		DataLicense:  "CC0-1.0",
+		SPDXID:       "SPDXRef-DOCUMENT",
+		Name:         name,
+		CreationInfo: spdxCreationInfo{Created: timestamp, Creators: []string{creator}},
+		Packages:     []spdxPackage{},
+	}
+
+	// One package per lockfile entry, so that each install path can be annotated
+	contents := []string{name}
+	index := make(map[string]int)
+	for _, instance := range config.Inventory {
+		key := instance.Lockfile + "\x00" + instance.Path
+		contents = append(contents, key, instance.Name, instance.Version, instance.Resolved, instance.Integrity, instance.License)
+
+		pkg := spdxPackage{
+			Name:             instance.Name,
+			SPDXID:           "SPDXRef-Package-" + fingerprint(key),
+			VersionInfo:      instance.Version,
+			DownloadLocation: downloadLocation(instance.Resolved),
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  spdxNoAssertion,
+			CopyrightText:    spdxNoAssertion,
+			SourceInfo:       "installed at " + lockfilePath(instance),
+		}
+		if license.IsExpression(instance.License) {
+			pkg.LicenseDeclared = instance.License
+		}
+		for _, digest := range integrityDigests(instance.Integrity) {
+			if algorithm, ok := spdxChecksumAlgorithms[digest.Algorithm]; ok {
+				pkg.Checksums = append(pkg.Checksums, spdxChecksum{Algorithm: algorithm, ChecksumValue: digest.Hex})
+			}
+		}
+		if instance.Version != "" {
+			pkg.ExternalRefs = append(pkg.ExternalRefs, spdxExternalRef{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  purl(instance.Name, instance.Version),
+			})
+		}
+
+		index[key] = len(document.Packages)
+		document.Packages = append(document.Packages, pkg)
+		document.Relationships = append(document.Relationships, spdxRelationship{
+			SPDXElementID:      document.SPDXID,
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: pkg.SPDXID,
+		})
+	}
+	document.DocumentNamespace = fmt.Sprintf("%s/%s-%s", strings.TrimSuffix(prefix, "/"), spdxNamespaceName(name), fingerprint(contents...))
+
+	for _, result := range results {
+		if result.Check != "" {
+			continue
+		}
+		for _, instance := range result.Instances {
+			i, ok := index[instance.Lockfile+"\x00"+instance.Path]
+			if instance.IsReference || !ok {
+				continue
+			}
+			document.Packages[i].Annotations = append(document.Packages[i].Annotations, spdxAnnotation{
+				AnnotationDate: timestamp,
+				AnnotationType: "REVIEW",
+				Annotator:      creator,
+				Comment:        spdxComment(result.Package, instance),
+			})
+		}
+	}
+	if document.Relationships == nil {
+		document.Relationships = []spdxRelationship{}
+	}
+	return document
+}
+
+// spdxComment describes the bad-package entry an instance matched
+func spdxComment(query types.PackageQuery, instance types.PackageInstance) string {
+	comment := fmt.Sprintf("scnpm: matches bad-package entry %s@%s", query.Name, targetLabel(query))
+	var details []string
+	if query.Advisory != "" {
+		details = append(details, query.Advisory)
+	}
+	if query.Severity != "" {
+		details = append(details, query.Severity)
+	}
+	if len(details) > 0 {
+		comment += " (" + strings.Join(details, ", ") + ")"
+	}
+	if query.Note != "" {
+		comment += ": " + query.Note
+	}
+	if instance.Suppressed {
+		comment += fmt.Sprintf(" [suppressed: %s]", instance.SuppressedReason)
+	}
+	return comment
+}
+
+// downloadLocation returns a resolved spec SPDX accepts as a download location (a URL or VCS
+// location), or NOASSERTION
+func downloadLocation(resolved string) string {
+	for _, prefix := range []string{"https://", "http://", "git+"} {
+		if strings.HasPrefix(resolved, prefix) {
+			return resolved
+		}
+	}
+	return spdxNoAssertion
+}
+
+// spdxNamespaceName makes a lockfile path usable in a namespace URI
+func spdxNamespaceName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, name)
+}
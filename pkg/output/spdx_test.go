@@ -0,0 +1,131 @@
+package output
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+// Constraints of the SPDX 2.3 specification that its JSON schema doesn't express
+var (
+	spdxIDPattern      = regexp.MustCompile(`^SPDXRef-[a-zA-Z0-9.-]+$`)
+	spdxDatePattern    = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z$`)
+	spdxCreatorPattern = regexp.MustCompile(`^(Person|Organization|Tool): .+`)
+)
+
+// validateSPDX checks a document against the SPDX 2.3 JSON schema, plus the specification's rules
+// the schema leaves open: the document header, the formats of ids, dates and creators, and SPDX
+// ids that are unique and resolve
+func validateSPDX(t *testing.T, data []byte) {
+	t.Helper()
+	doc := checkPublishedSchema(t, "spdx-schema-2.3.json", data)
+	if doc["spdxVersion"] != "SPDX-2.3" || doc["dataLicense"] != "CC0-1.0" || doc["SPDXID"] != "SPDXRef-DOCUMENT" {
+		t.Errorf("document header = %v %v %v, want SPDX-2.3, CC0-1.0, SPDXRef-DOCUMENT", doc["spdxVersion"], doc["dataLicense"], doc["SPDXID"])
+	}
+	if namespace, _ := doc["documentNamespace"].(string); !strings.Contains(namespace, "://") || strings.Contains(namespace, "#") {
+		t.Errorf("documentNamespace = %q, want an absolute URI without a fragment", namespace)
+	}
+	creation := doc["creationInfo"].(map[string]any)
+	if created, _ := creation["created"].(string); !spdxDatePattern.MatchString(created) {
+		t.Errorf("created = %q, want YYYY-MM-DDThh:mm:ssZ", created)
+	}
+	for _, creator := range objects(creation["creators"]) {
+		if s, _ := creator.(string); !spdxCreatorPattern.MatchString(s) {
+			t.Errorf("creator = %q, want Person:, Organization: or Tool:", s)
+		}
+	}
+
+	ids := map[string]bool{"SPDXRef-DOCUMENT": true}
+	for _, p := range objects(doc["packages"]) {
+		pkg := p.(map[string]any)
+		id, _ := pkg["SPDXID"].(string)
+		if !spdxIDPattern.MatchString(id) || ids[id] {
+			t.Errorf("package SPDXID %q is malformed or duplicated", id)
+		}
+		ids[id] = true
+		for _, a := range objects(pkg["annotations"]) {
+			if date, _ := a.(map[string]any)["annotationDate"].(string); !spdxDatePattern.MatchString(date) {
+				t.Errorf("annotationDate = %q, want YYYY-MM-DDThh:mm:ssZ", date)
+			}
+		}
+	}
+
+	for _, r := range objects(doc["relationships"]) {
+		relationship := r.(map[string]any)
+		for _, end := range []string{"spdxElementId", "relatedSpdxElement"} {
+			if id, _ := relationship[end].(string); !ids[id] {
+				t.Errorf("relationship %s %q doesn't name an element", end, id)
+			}
+		}
+	}
+}
+
+func TestBuildSPDX(t *testing.T) {
+	config := OutputConfig{
+		ToolVersion:     "1.2.3",
+		Lockfile:        "app/package-lock.json",
+		NamespacePrefix: "https://sbom.example.com/spdx/",
+		Inventory: []types.PackageInstance{
+			{Name: "lodash", Version: "4.17.20", Path: "node_modules/lodash", Resolved: "https://registry.npmjs.org/lodash/-/lodash-4.17.20.tgz", Integrity: "sha512-AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJygpKissLS4vMDEyMzQ1Njc4OTo7PD0+Pw==", License: "MIT"},
+			{Name: "lodash", Version: "4.17.20", Path: "node_modules/a/node_modules/lodash", License: "SEE LICENSE IN LICENSE"},
+			{Name: "lib", Path: "node_modules/lib", Resolved: "file:../lib"},
+		},
+	}
+	results := []types.ScanResult{
+		{
+			Package: types.PackageQuery{Name: "lodash", Version: "4.17.20", Severity: types.SeverityHigh, Advisory: "GHSA-35jh-r3h4-6jhm", Note: "Command injection"},
+			Found:   true,
+			Instances: []types.PackageInstance{
+				{Name: "lodash", Version: "4.17.20", Path: "node_modules/lodash"},
+				{Name: "lodash", Version: "4.17.20", Path: "node_modules/a/node_modules/lodash", Suppressed: true, SuppressedReason: "not reachable"},
+				{Name: "lodash", Version: "^4.17.0", Path: "node_modules/a -> lodash", IsReference: true},
+			},
+		},
+	}
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	document := buildSPDX(results, config, created)
+	data, err := json.Marshal(document)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	validateSPDX(t, data)
+
+	if len(document.Packages) != 3 || len(document.Relationships) != 3 {
+		t.Fatalf("document = %+v, want a package and a DESCRIBES relationship per entry", document)
+	}
+	installed, nested, link := document.Packages[0], document.Packages[1], document.Packages[2]
+	if installed.DownloadLocation != config.Inventory[0].Resolved || installed.LicenseDeclared != "MIT" || installed.Checksums[0].Algorithm != "SHA512" || installed.ExternalRefs[0].ReferenceLocator != "pkg:npm/lodash@4.17.20" {
+		t.Errorf("lodash = %+v, want its resolved URL, license, checksum and purl", installed)
+	}
+	if nested.LicenseDeclared != spdxNoAssertion || nested.DownloadLocation != spdxNoAssertion {
+		t.Errorf("nested lodash = %+v, want NOASSERTION for free-text licenses and missing URLs", nested)
+	}
+	if link.DownloadLocation != spdxNoAssertion || link.VersionInfo != "" || link.ExternalRefs != nil {
+		t.Errorf("lib = %+v, want no download location, version or purl for a local directory", link)
+	}
+
+	wantComment := "scnpm: matches bad-package entry lodash@4.17.20 (GHSA-35jh-r3h4-6jhm, high): Command injection"
+	if len(installed.Annotations) != 1 || installed.Annotations[0].Comment != wantComment || installed.Annotations[0].Annotator != "Tool: scnpm-1.2.3" {
+		t.Errorf("lodash annotations = %+v, want %q", installed.Annotations, wantComment)
+	}
+	if len(nested.Annotations) != 1 || !strings.HasSuffix(nested.Annotations[0].Comment, "[suppressed: not reachable]") {
+		t.Errorf("nested lodash annotations = %+v, want the suppression noted", nested.Annotations)
+	}
+
+	if !strings.HasPrefix(document.DocumentNamespace, "https://sbom.example.com/spdx/app-package-lock.json-") {
+		t.Errorf("DocumentNamespace = %q, want it under the prefix and named after the lockfile", document.DocumentNamespace)
+	}
+	if again := buildSPDX(results, config, created); !reflect.DeepEqual(again, document) {
+		t.Error("buildSPDX() differs between runs on the same lockfile")
+	}
+	config.Inventory[0].Version = "4.17.21"
+	if changed := buildSPDX(results, config, created); changed.DocumentNamespace == document.DocumentNamespace {
+		t.Error("DocumentNamespace didn't change with the lockfile's contents")
+	}
+}
@@ -0,0 +1,64 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"scnpm/pkg/scanner"
+)
+
+// statsDocument is the JSON form of scnpm stats
+type statsDocument struct {
+	Lockfile string `json:"lockfile,omitempty"`
+	scanner.Stats
+}
+
+// OutputStats writes the statistics of a lockfile as a table: the counts, a histogram of nesting
+// depths and the most duplicated packages
+func OutputStats(out io.Writer, stats scanner.Stats, config OutputConfig) error {
+	w := &errWriter{w: out}
+	for _, row := range []struct {
+		label string
+		value int
+	}{
+		{"Packages installed", stats.Packages},
+		{"Unique names", stats.UniqueNames},
+		{"Unique name@version", stats.UniqueVersions},
+		{"Production", stats.Prod},
+		{"Development", stats.Dev},
+		{"With install scripts", stats.InstallScripts},
+		{"Without integrity", stats.MissingIntegrity},
+		{"Names in several versions", stats.DuplicatedNames},
+	} {
+		fmt.Fprintf(w, "%s %d\n", padRight(row.label, 28), row.value)
+	}
+
+	if len(stats.Depths) > 0 {
+		fmt.Fprintln(w, strings.Repeat("-", 120))
+		fmt.Fprintln(w, "DEPTH:")
+		widest := 0
+		for _, depth := range stats.Depths {
+			widest = max(widest, depth.Count)
+		}
+		for _, depth := range stats.Depths {
+			bar := strings.Repeat("█", (depth.Count*50+widest-1)/widest)
+			fmt.Fprintf(w, "  %s %s %d\n", padRight(fmt.Sprint(depth.Depth), 4), plain(bar, config), depth.Count)
+		}
+	}
+
+	if len(stats.Duplicates) > 0 {
+		fmt.Fprintln(w, strings.Repeat("-", 120))
+		fmt.Fprintf(w, "MOST DUPLICATED (top %d):\n", len(stats.Duplicates))
+		for _, duplicate := range stats.Duplicates {
+			fmt.Fprintf(w, "  %s %s %s\n", padRight(duplicate.Name, 30),
+				padRight(fmt.Sprintf("%d versions, %d copies", len(duplicate.Versions), duplicate.Copies), 26), strings.Join(duplicate.Versions, ", "))
+		}
+	}
+	return w.err
+}
+
+// OutputStatsJSON writes the statistics of lockfile as JSON
+func OutputStatsJSON(w io.Writer, stats scanner.Stats, lockfile string) error {
+	return writeJSON(w, "JSON", statsDocument{Lockfile: lockfile, Stats: stats})
+}
@@ -0,0 +1,34 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"scnpm/pkg/scanner"
+)
+
+func TestOutputStats(t *testing.T) {
+	stats := scanner.Stats{
+		Packages:        8,
+		Depths:          []scanner.DepthCount{{Depth: 0, Count: 4}, {Depth: 1, Count: 2}},
+		DuplicatedNames: 1,
+		Duplicates:      []scanner.Duplicate{{Name: "b", Versions: []string{"1.0.0", "2.0.0"}, Copies: 3}},
+	}
+
+	var buf bytes.Buffer
+	if err := OutputStats(&buf, stats, OutputConfig{ASCII: true}); err != nil {
+		t.Fatalf("OutputStats() error = %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"Packages installed           8\n",
+		"  0    " + strings.Repeat("#", 50) + " 4\n",
+		"  1    " + strings.Repeat("#", 25) + " 2\n",
+		"  b                              2 versions, 3 copies       1.0.0, 2.0.0\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("OutputStats() = %q, want it to contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+// summaryFile is the compact summary --summary-file writes for later CI stages (badges, chat
+// notifications), whatever the output format
+type summaryFile struct {
+	Tool       jsonTool `json:"tool"`
+	ScannedAt  string   `json:"scannedAt"` // RFC 3339
+	DurationMS int64    `json:"durationMs"`
+	Lockfiles  []string `json:"lockfiles"`
+	Summary    Totals   `json:"summary"`
+	Risky      []string `json:"riskyPackages"` // Names of the packages counted as risks, sorted
+}
+
+// WriteSummaryFile writes the summary of a scan that took duration to path. totals are the
+// scan's, which a streamed scan counts as it goes rather than from results. The file is
+// written via a temporary file, so that a killed job never leaves a truncated summary behind.
+func WriteSummaryFile(path string, results []types.ScanResult, totals Totals, duration time.Duration, config OutputConfig) error {
+	scannedAt, err := reportTime()
+	if err != nil {
+		return err
+	}
+	risky := make(map[string]bool)
+	for _, result := range results {
+		if result.Check == "" && len(newInstances(result)) > 0 {
+			risky[result.Package.Name] = true
+		}
+	}
+	summary := summaryFile{
+		Tool:       jsonTool{Name: "scnpm", Version: config.ToolVersion, Commit: config.ToolCommit},
+		ScannedAt:  scannedAt.UTC().Format(time.RFC3339),
+		DurationMS: duration.Milliseconds(),
+		Lockfiles:  scannedLockfiles(results, config),
+		Summary:    totals,
+		Risky:      sortedKeys(risky),
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal summary: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".scnpm-summary-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
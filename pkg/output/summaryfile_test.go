@@ -0,0 +1,61 @@
+package output
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"scnpm/pkg/types"
+)
+
+func TestWriteSummaryFile(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "0")
+	results := []types.ScanResult{
+		{Package: types.PackageQuery{Name: "debug", Version: "4.4.2", Severity: types.SeverityHigh}, Found: true, Instances: []types.PackageInstance{{Name: "debug", Version: "4.4.2", Path: "node_modules/debug", Lockfile: "web/package-lock.json"}}},
+		{Package: types.PackageQuery{Name: "chalk", Version: "5.6.1"}, Found: true, Instances: []types.PackageInstance{{Name: "chalk", Version: "5.6.1", Path: "node_modules/chalk", Baseline: true}}},
+		{Package: types.PackageQuery{Name: "color", Version: "5.0.1"}},
+		{Package: types.PackageQuery{Name: "lodahs"}, Check: types.CheckTyposquat, Found: true, Instances: []types.PackageInstance{{Name: "lodahs", Path: "node_modules/lodahs"}}},
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "summary.json")
+	if err := os.WriteFile(path, []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := OutputConfig{ToolVersion: "1.2.3", Lockfile: "package-lock.json"}
+	if err := WriteSummaryFile(path, results, CountResults(results), 1500*time.Millisecond, config); err != nil {
+		t.Fatalf("WriteSummaryFile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got summaryFile
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("summary file isn't JSON: %v\n%s", err, data)
+	}
+	if got.Tool.Version != "1.2.3" || got.ScannedAt != "1970-01-01T00:00:00Z" || got.DurationMS != 1500 {
+		t.Errorf("summary = %+v, want version 1.2.3 scanned at the epoch in 1500ms", got)
+	}
+	if want := []string{"package-lock.json", "web/package-lock.json"}; !reflect.DeepEqual(got.Lockfiles, want) {
+		t.Errorf("Lockfiles = %v, want %v", got.Lockfiles, want)
+	}
+	if got.Summary.Risks != 1 || got.Summary.Baseline != 1 || got.Summary.Safe != 1 || got.Summary.RisksBySeverity[types.SeverityHigh] != 1 {
+		t.Errorf("Summary = %+v, want 1 high risk, 1 in the baseline and 1 safe", got.Summary)
+	}
+	if want := []string{"debug"}; !reflect.DeepEqual(got.Risky, want) {
+		t.Errorf("Risky = %v, want %v", got.Risky, want)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want the summary alone without temporary files", len(entries))
+	}
+}
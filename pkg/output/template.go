@@ -0,0 +1,84 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+	"strings"
+	"text/template"
+
+	"scnpm/pkg/types"
+)
+
+// Finding is what a --format template is executed with, once per active instance of each
+// result with findings
+type Finding struct {
+	Result   types.ScanResult      // The result the instance belongs to
+	Package  types.PackageQuery    // Bad-package query that matched, or the package a check flagged
+	Instance types.PackageInstance // The installed package or dependency reference that was found
+	Lockfile string                // Lockfile the instance is in: the archive member, or the scanned lockfile
+	Severity string                // The query's severity, "unknown" when the source didn't say
+	Check    string                // Lockfile-wide check that produced the finding; empty for bad-package queries
+}
+
+// templateFuncs are the helpers --format templates can call besides the text/template builtins
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"pathbase": path.Base,
+}
+
+// ParseTemplate parses a --format template, so that mistakes are reported before scanning
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("format").Funcs(templateFuncs).Parse(text)
+}
+
+// OutputTemplate writes a line per finding to w, rendered through tmpl
+func OutputTemplate(w io.Writer, tmpl *template.Template, results []types.ScanResult, config OutputConfig) error {
+	for _, finding := range findings(results, config) {
+		if err := tmpl.Execute(w, finding); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newFinding describes an instance of a result as a Finding
+func newFinding(result types.ScanResult, instance types.PackageInstance, config OutputConfig) Finding {
+	lockfile := instance.Lockfile
+	if lockfile == "" {
+		lockfile = config.Lockfile
+	}
+	return Finding{
+		Result:   result,
+		Package:  result.Package,
+		Instance: instance,
+		Lockfile: lockfile,
+		Severity: severityLabel(result.Package.Severity),
+		Check:    result.Check,
+	}
+}
+
+// findings lists the active instances of every result, bad-package queries before checks as in
+// the table
+func findings(results []types.ScanResult, config OutputConfig) []Finding {
+	var list []Finding
+	for _, checks := range []bool{false, true} {
+		for _, result := range results {
+			if (result.Check != "") != checks {
+				continue
+			}
+			for _, instance := range activeInstances(result) {
+				list = append(list, newFinding(result, instance, config))
+			}
+		}
+	}
+	return list
+}
@@ -0,0 +1,73 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestOutputTemplate(t *testing.T) {
+	results := []types.ScanResult{
+		{Package: types.PackageQuery{Name: "lodahs"}, Found: true, Check: types.CheckTyposquat, Instances: []types.PackageInstance{{Name: "lodahs", Version: "1.0.0", Path: "node_modules/lodahs", Resembles: "lodash"}}},
+		{
+			Package: types.PackageQuery{Name: "lodash", Version: "4.17.20", Severity: types.SeverityHigh},
+			Found:   true,
+			Instances: []types.PackageInstance{
+				{Name: "lodash", Version: "4.17.20", Path: "node_modules/lodash", LineNumber: 12},
+				{Name: "lodash", Version: "4.17.20", Path: "node_modules/a/node_modules/lodash", Suppressed: true},
+				{Name: "lodash", Version: "4.17.20", Path: "node_modules/lodash", Lockfile: "web/package-lock.json"},
+			},
+		},
+		{Package: types.PackageQuery{Name: "left-pad", Version: "1.3.0"}},
+	}
+	config := OutputConfig{Lockfile: "app/package-lock.json"}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "fields",
+			template: "{{.Package.Name}} {{.Instance.Version}} {{.Instance.Path}}",
+			want:     "lodash 4.17.20 node_modules/lodash\nlodash 4.17.20 node_modules/lodash\nlodahs 1.0.0 node_modules/lodahs\n",
+		},
+		{
+			name:     "helpers",
+			template: "{{.Severity | upper}} {{pathbase .Lockfile}} {{json .Instance.LineNumber}}{{with .Check}} {{.}}{{end}}",
+			want:     "HIGH package-lock.json 12\nHIGH package-lock.json 0\nUNKNOWN package-lock.json 0 typosquat\n",
+		},
+		{
+			name:     "lockfile",
+			template: "{{.Lockfile}}",
+			want:     "app/package-lock.json\nweb/package-lock.json\napp/package-lock.json\n",
+		},
+		{
+			name:     "json",
+			template: `{{json .Package.Name}}`,
+			want:     "\"lodash\"\n\"lodash\"\n\"lodahs\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := ParseTemplate(tt.template)
+		if err != nil {
+			t.Fatalf("%s: ParseTemplate() error = %v", tt.name, err)
+		}
+		var buf bytes.Buffer
+		if err := OutputTemplate(&buf, tmpl, results, config); err != nil {
+			t.Fatalf("%s: OutputTemplate() error = %v", tt.name, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s: OutputTemplate() = %q, want %q", tt.name, buf.String(), tt.want)
+		}
+	}
+
+	if _, err := ParseTemplate("{{.Package.Name"); err == nil {
+		t.Error("ParseTemplate() accepted an unclosed action")
+	}
+	if _, err := ParseTemplate("{{basename .Lockfile}}"); err == nil {
+		t.Error("ParseTemplate() accepted an undefined function")
+	}
+}
@@ -0,0 +1,55 @@
+package output
+
+import (
+	"fmt"
+	"io"
+
+	"scnpm/pkg/scanner"
+)
+
+// treeDocument is the JSON form of scnpm tree
+type treeDocument struct {
+	Lockfile string            `json:"lockfile,omitempty"`
+	Tree     *scanner.TreeNode `json:"tree"`
+}
+
+// OutputTree writes a dependency tree in the style of npm ls, with the risky packages marked
+func OutputTree(out io.Writer, root *scanner.TreeNode, config OutputConfig) error {
+	w := &errWriter{w: out}
+	fmt.Fprintln(w, treeLabel(root, config))
+	outputTreeNodes(w, root.Children, "", config)
+	return w.err
+}
+
+// outputTreeNodes prints nodes indented under prefix with box-drawing branches
+func outputTreeNodes(w io.Writer, nodes []*scanner.TreeNode, prefix string, config OutputConfig) {
+	for i, node := range nodes {
+		branch, indent := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s\n", prefix, plain(branch, config), treeLabel(node, config))
+		outputTreeNodes(w, node.Children, prefix+plain(indent, config), config)
+	}
+}
+
+// treeLabel returns the line of a package in the tree: name@version, marked when it's deduped or
+// risky
+func treeLabel(node *scanner.TreeNode, config OutputConfig) string {
+	label := node.Name
+	if node.Version != "" {
+		label += "@" + node.Version
+	}
+	if node.Deduped {
+		label += " deduped"
+	}
+	if node.Risky {
+		label = colorize(label+plain(" 🚨 RISK", config), colorRed, config)
+	}
+	return label
+}
+
+// OutputTreeJSON writes the dependency tree of lockfile as JSON
+func OutputTreeJSON(w io.Writer, root *scanner.TreeNode, lockfile string) error {
+	return writeJSON(w, "JSON", treeDocument{Lockfile: lockfile, Tree: root})
+}
@@ -0,0 +1,63 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"scnpm/pkg/scanner"
+)
+
+func TestOutputTree(t *testing.T) {
+	debug := &scanner.TreeNode{Name: "debug", Version: "2.6.9", Path: "node_modules/debug", Risky: true}
+	root := &scanner.TreeNode{Name: "app", Version: "1.0.0", Children: []*scanner.TreeNode{
+		{Name: "express", Version: "4.18.2", Path: "node_modules/express", Children: []*scanner.TreeNode{
+			{Name: "body-parser", Version: "1.20.0", Path: "node_modules/body-parser", Children: []*scanner.TreeNode{
+				{Name: "debug", Version: "2.6.9", Path: "node_modules/debug", Risky: true, Deduped: true},
+			}},
+			debug,
+		}},
+		{Name: "koa", Version: "2.0.0", Path: "node_modules/koa", Children: []*scanner.TreeNode{
+			{Name: "body-parser", Version: "1.20.0", Path: "node_modules/body-parser", Deduped: true},
+		}},
+	}}
+
+	tests := []struct {
+		name   string
+		config OutputConfig
+		want   string
+	}{
+		{
+			name: "unicode",
+			want: `app@1.0.0
+├── express@4.18.2
+│   ├── body-parser@1.20.0
+│   │   └── debug@2.6.9 deduped 🚨 RISK
+│   └── debug@2.6.9 🚨 RISK
+└── koa@2.0.0
+    └── body-parser@1.20.0 deduped
+`,
+		},
+		{
+			name:   "ascii",
+			config: OutputConfig{ASCII: true},
+			want: "app@1.0.0\n" +
+				"|-- express@4.18.2\n" +
+				"|   |-- body-parser@1.20.0\n" +
+				"|   |   `-- debug@2.6.9 deduped RISK\n" +
+				"|   `-- debug@2.6.9 RISK\n" +
+				"`-- koa@2.0.0\n" +
+				"    `-- body-parser@1.20.0 deduped\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := OutputTree(&buf, root, tt.config); err != nil {
+				t.Fatalf("OutputTree() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("OutputTree() =\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"scnpm/pkg/verify"
+)
+
+// VerifyReport is the outcome of scnpm verify
+type VerifyReport struct {
+	Lockfile string          `json:"lockfile,omitempty"`
+	Registry string          `json:"registry"`
+	Results  []verify.Result `json:"results"`
+	Skipped  int             `json:"skipped"` // Installed packages without an integrity hash or not from the registry
+}
+
+// VerifyCounts counts the results of a verification by status
+func VerifyCounts(results []verify.Result) map[string]int {
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	return counts
+}
+
+// OutputVerify writes the packages whose hashes don't match the registry's and the ones the
+// registry no longer has, each in its own section, then a summary
+func OutputVerify(out io.Writer, report VerifyReport, config OutputConfig) error {
+	w := &errWriter{w: out}
+	fmt.Fprintf(w, "Verifying %s against %s\n", report.Lockfile, report.Registry)
+
+	for _, section := range []struct {
+		status string
+		title  string
+	}{
+		{verify.StatusMismatch, "🚨 INTEGRITY MISMATCHES"},
+		{verify.StatusUnpublished, "🚨 NOT PUBLISHED IN THE REGISTRY"},
+		{verify.StatusUnchecked, "ℹ️ NO COMPARABLE HASH"},
+	} {
+		var results []verify.Result
+		for _, result := range report.Results {
+			if result.Status == section.status {
+				results = append(results, result)
+			}
+		}
+		if len(results) == 0 {
+			continue
+		}
+		fmt.Fprintln(w, strings.Repeat("-", 120))
+		title := plain(fmt.Sprintf("%s (%d):", section.title, len(results)), config)
+		if section.status == verify.StatusUnchecked {
+			fmt.Fprintln(w, title)
+		} else {
+			fmt.Fprintln(w, colorize(title, colorRed, config))
+		}
+		for _, result := range results {
+			fmt.Fprintf(w, "  %s %s %s\n", padRight(result.Name, 30), padRight(result.Version, 15), strings.Join(result.Paths, ", "))
+			if section.status != verify.StatusUnpublished {
+				fmt.Fprintf(w, "    lockfile:  %s\n", result.Lockfile)
+				fmt.Fprintf(w, "    registry:  %s\n", result.Published)
+			}
+		}
+	}
+
+	counts := VerifyCounts(report.Results)
+	fmt.Fprintln(w, strings.Repeat("=", 120))
+	summary := fmt.Sprintf("VERIFY SUMMARY: 🚨 %d MISMATCHED | 🚨 %d UNPUBLISHED | ✅ %d VERIFIED", counts[verify.StatusMismatch], counts[verify.StatusUnpublished], counts[verify.StatusVerified])
+	if counts[verify.StatusUnchecked] > 0 {
+		summary += fmt.Sprintf(" | %d UNCHECKED", counts[verify.StatusUnchecked])
+	}
+	if counts[verify.StatusError] > 0 {
+		summary += fmt.Sprintf(" | %d FAILED LOOKUPS", counts[verify.StatusError])
+	}
+	if report.Skipped > 0 {
+		summary += fmt.Sprintf(" | %d SKIPPED", report.Skipped)
+	}
+	verdict := colorGreen
+	if counts[verify.StatusMismatch]+counts[verify.StatusUnpublished] > 0 {
+		verdict = colorRed
+	}
+	fmt.Fprintln(w, colorize(plain(summary, config), verdict, config))
+	return w.err
+}
+
+// OutputVerifyJSON writes the outcome of scnpm verify as JSON
+func OutputVerifyJSON(w io.Writer, report VerifyReport) error {
+	return writeJSON(w, "JSON", report)
+}
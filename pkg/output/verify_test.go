@@ -0,0 +1,41 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"scnpm/pkg/verify"
+)
+
+func TestOutputVerify(t *testing.T) {
+	report := VerifyReport{
+		Lockfile: "package-lock.json",
+		Registry: "https://registry.npmjs.org",
+		Results: []verify.Result{
+			{Name: "debug", Version: "4.3.4", Paths: []string{"node_modules/debug"}, Status: verify.StatusMismatch, Lockfile: "sha512-evil", Published: "sha512-good"},
+			{Name: "left-pad", Version: "1.0.0", Paths: []string{"node_modules/left-pad"}, Status: verify.StatusUnpublished, Lockfile: "sha512-gone"},
+			{Name: "ms", Version: "2.1.2", Paths: []string{"node_modules/ms"}, Status: verify.StatusVerified},
+		},
+		Skipped: 2,
+	}
+
+	var buf bytes.Buffer
+	if err := OutputVerify(&buf, report, OutputConfig{}); err != nil {
+		t.Fatalf("OutputVerify() error = %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"INTEGRITY MISMATCHES (1):",
+		"    lockfile:  sha512-evil\n    registry:  sha512-good\n",
+		"NOT PUBLISHED IN THE REGISTRY (1):",
+		"VERIFY SUMMARY: 🚨 1 MISMATCHED | 🚨 1 UNPUBLISHED | ✅ 1 VERIFIED | 2 SKIPPED",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("OutputVerify() = %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "ms ") {
+		t.Errorf("OutputVerify() = %q, want verified packages left out", got)
+	}
+}
@@ -0,0 +1,88 @@
+package output
+
+import (
+	"fmt"
+	"io"
+
+	"scnpm/pkg/scanner"
+)
+
+// whyNode is a package in the tree of dependents printed by OutputWhy
+type whyNode struct {
+	link     scanner.ChainLink
+	children []*whyNode
+}
+
+// child returns the dependent of n installed at link's path, adding it when it's new
+func (n *whyNode) child(link scanner.ChainLink) *whyNode {
+	for _, c := range n.children {
+		if c.link.Path == link.Path {
+			return c
+		}
+	}
+	c := &whyNode{link: link}
+	n.children = append(n.children, c)
+	return c
+}
+
+// whyDocument is the JSON form of scnpm why
+type whyDocument struct {
+	Package   string                `json:"package"`
+	Lockfile  string                `json:"lockfile,omitempty"`
+	Instances []scanner.Explanation `json:"instances"`
+}
+
+// OutputWhy writes every install location of a package, each followed by a tree of its
+// dependents up to the project's direct dependencies, like npm explain
+func OutputWhy(out io.Writer, explanations []scanner.Explanation, config OutputConfig) error {
+	w := &errWriter{w: out}
+	for i, explanation := range explanations {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		instance := explanation.Instance
+		line := fmt.Sprintf("%s@%s %s", instance.Name, instance.Version, instance.Path)
+		if instance.IsDev {
+			line += " (dev)"
+		}
+		switch {
+		case explanation.Direct:
+			line += " - direct dependency"
+		case len(explanation.Chains) == 1 && len(explanation.Chains[0]) == 1:
+			line += " - nothing depends on it"
+		}
+		fmt.Fprintln(w, line)
+
+		// Chains run from a direct dependency down to the package; the tree runs up from it
+		root := &whyNode{}
+		for _, chain := range explanation.Chains {
+			node := root
+			for j := len(chain) - 2; j >= 0; j-- {
+				node = node.child(chain[j])
+			}
+		}
+		outputWhyTree(w, root.children, "", config)
+	}
+	return w.err
+}
+
+// outputWhyTree prints dependents indented under prefix with box-drawing branches
+func outputWhyTree(w io.Writer, nodes []*whyNode, prefix string, config OutputConfig) {
+	for i, node := range nodes {
+		branch, indent := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s@%s %s\n", prefix, plain(branch, config), node.link.Name, node.link.Version, node.link.Path)
+		outputWhyTree(w, node.children, prefix+plain(indent, config), config)
+	}
+}
+
+// OutputWhyJSON writes every install location of the package named name in lockfile and the
+// chains leading to it as JSON
+func OutputWhyJSON(w io.Writer, name, lockfile string, explanations []scanner.Explanation) error {
+	if explanations == nil {
+		explanations = []scanner.Explanation{}
+	}
+	return writeJSON(w, "JSON", whyDocument{Package: name, Lockfile: lockfile, Instances: explanations})
+}
@@ -0,0 +1,53 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"scnpm/pkg/scanner"
+	"scnpm/pkg/types"
+)
+
+func TestOutputWhy(t *testing.T) {
+	debug := scanner.ChainLink{Name: "debug", Version: "4.3.4", Path: "node_modules/debug"}
+	bodyParser := scanner.ChainLink{Name: "body-parser", Version: "1.20.0", Path: "node_modules/body-parser"}
+	explanations := []scanner.Explanation{
+		{
+			Instance: types.PackageInstance{Name: "debug", Version: "4.3.4", Path: "node_modules/debug", IsDev: true},
+			Chains: [][]scanner.ChainLink{
+				{{Name: "express", Version: "4.18.2", Path: "node_modules/express"}, bodyParser, debug},
+				{{Name: "koa", Version: "2.0.0", Path: "node_modules/koa"}, bodyParser, debug},
+			},
+		},
+		{
+			Instance: types.PackageInstance{Name: "debug", Version: "3.2.7", Path: "node_modules/koa/node_modules/debug"},
+			Direct:   true,
+			Chains:   [][]scanner.ChainLink{{{Name: "debug", Version: "3.2.7", Path: "node_modules/koa/node_modules/debug"}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := OutputWhy(&buf, explanations, OutputConfig{}); err != nil {
+		t.Fatalf("OutputWhy() error = %v", err)
+	}
+	want := `debug@4.3.4 node_modules/debug (dev)
+└── body-parser@1.20.0 node_modules/body-parser
+    ├── express@4.18.2 node_modules/express
+    └── koa@2.0.0 node_modules/koa
+
+debug@3.2.7 node_modules/koa/node_modules/debug - direct dependency
+`
+	if buf.String() != want {
+		t.Errorf("OutputWhy() =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := OutputWhyJSON(&buf, "express", "package-lock.json", nil); err != nil {
+		t.Fatalf("OutputWhyJSON() error = %v", err)
+	}
+	var doc whyDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil || doc.Package != "express" || doc.Instances == nil {
+		t.Errorf("OutputWhyJSON() = %s, want an empty instances array", buf.String())
+	}
+}
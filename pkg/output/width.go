@@ -0,0 +1,58 @@
+package output
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// wideRanges are the code points terminals draw two columns wide: East Asian wide and fullwidth
+// characters, and emoji shown as pictures by default
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F}, {0x231A, 0x231B}, {0x23E9, 0x23EC}, {0x23F0, 0x23F0}, {0x23F3, 0x23F3},
+	{0x25FD, 0x25FE}, {0x2614, 0x2615}, {0x2648, 0x2653}, {0x267F, 0x267F}, {0x2693, 0x2693},
+	{0x26A1, 0x26A1}, {0x26AA, 0x26AB}, {0x26BD, 0x26BE}, {0x26C4, 0x26C5}, {0x26CE, 0x26CE},
+	{0x26D4, 0x26D4}, {0x26EA, 0x26EA}, {0x26F2, 0x26F3}, {0x26F5, 0x26F5}, {0x26FA, 0x26FA},
+	{0x26FD, 0x26FD}, {0x2705, 0x2705}, {0x270A, 0x270B}, {0x2728, 0x2728}, {0x274C, 0x274C},
+	{0x274E, 0x274E}, {0x2753, 0x2755}, {0x2757, 0x2757}, {0x2795, 0x2797}, {0x27B0, 0x27B0},
+	{0x27BF, 0x27BF}, {0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55}, {0x2E80, 0x303E},
+	{0x3041, 0x33FF}, {0x3400, 0x4DBF}, {0x4E00, 0x9FFF}, {0xA000, 0xA4CF}, {0xAC00, 0xD7A3},
+	{0xF900, 0xFAFF}, {0xFE30, 0xFE4F}, {0xFF00, 0xFF60}, {0xFFE0, 0xFFE6}, {0x1F300, 0x1F64F},
+	{0x1F680, 0x1F6FF}, {0x1F900, 0x1F9FF}, {0x1FA70, 0x1FAFF}, {0x20000, 0x2FFFD}, {0x30000, 0x3FFFD},
+}
+
+// emojiPresentation is the variation selector that asks for a symbol like ⚠ to be drawn as an
+// emoji, two columns wide
+const emojiPresentation = 0xFE0F
+
+// isWide reports whether r is drawn two columns wide
+func isWide(r rune) bool {
+	i := sort.Search(len(wideRanges), func(i int) bool { return wideRanges[i][1] >= r })
+	return i < len(wideRanges) && wideRanges[i][0] <= r
+}
+
+// displayWidth returns how many terminal columns s takes, unlike len (bytes) or the widths of
+// fmt verbs (runes): emoji and CJK characters take two, combining marks and joiners none
+func displayWidth(s string) int {
+	runes := []rune(s)
+	width := 0
+	for i, r := range runes {
+		switch {
+		case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+			// Combining marks, variation selectors and zero-width joiners
+		case isWide(r) || i+1 < len(runes) && runes[i+1] == emojiPresentation:
+			width += 2
+		default:
+			width++
+		}
+	}
+	return width
+}
+
+// padRight pads s with spaces to width terminal columns; longer strings are left as they are
+func padRight(s string, width int) string {
+	if pad := width - displayWidth(s); pad > 0 {
+		return s + strings.Repeat(" ", pad)
+	}
+	return s
+}
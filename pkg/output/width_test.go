@@ -0,0 +1,75 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"RISK", 4},
+		{"🚨 RISK", 7},
+		{"✅ SAFE", 7},
+		{"⚠️ REF", 6},
+		{"ℹ️ PEER", 7},
+		{"≈ lodash", 8},
+		{"latest → 5.0.0", 14},
+		{"日本語", 6},
+		{"é", 1},
+		{"é", 1},
+		{"👩‍💻", 4},
+	}
+
+	for _, tt := range tests {
+		if got := displayWidth(tt.s); got != tt.want {
+			t.Errorf("displayWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+
+	if got := padRight("🚨 RISK", 8); got != "🚨 RISK " {
+		t.Errorf("padRight() = %q, want one space of padding", got)
+	}
+	if got := padRight("@scope/a-very-long-package-name", 10); got != "@scope/a-very-long-package-name" {
+		t.Errorf("padRight() = %q, want a longer string left as it is", got)
+	}
+}
+
+func TestShortenPath(t *testing.T) {
+	tests := []struct {
+		path string
+		max  int
+		want string
+	}{
+		{"node_modules/a/node_modules/event-stream", 0, "node_modules/a/node_modules/event-stream"},
+		{"node_modules/lodash", 19, "node_modules/lodash"},
+		{"node_modules/a/node_modules/event-stream", 20, "node_modu…ent-stream"},
+		{"node_modules/日本語パッケージ", 10, "node…パッケージ"},
+		{"node_modules/lodash", 1, "…"},
+	}
+
+	for _, tt := range tests {
+		if got := shortenPath(tt.path, tt.max); got != tt.want {
+			t.Errorf("shortenPath(%q, %d) = %q, want %q", tt.path, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestWriteRowsWidths(t *testing.T) {
+	rows := []tableRow{
+		{Package: "@scope/a-package-with-a-very-long-name", Target: "1.0.0"},
+		{Package: "@scope/a-shorter-but-long-package", Target: "2.0.0"},
+	}
+	var buf bytes.Buffer
+	writeRows(&buf, rows, OutputConfig{})
+
+	lines := strings.Split(buf.String(), "\n")
+	for i, target := range map[int]string{0: "Target Ver", 2: "1.0.0", 3: "2.0.0"} {
+		if got := strings.Index(lines[i], target); got != 39 {
+			t.Errorf("%q starts at column %d, want 39, after the widest package", lines[i], got)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Package registry reads packuments, the per-package metadata documents an npm registry serves,
+// for the checks that need more than the lockfile: dist-tags and published tarball hashes.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"scnpm/pkg/audit"
+	"scnpm/pkg/remote"
+)
+
+// ErrNotFound is returned for packages the registry doesn't have
+var ErrNotFound = errors.New("not found")
+
+// Client fetches packuments from a registry
+type Client struct {
+	Remote   *remote.Client
+	Registry string // Defaults to audit.DefaultRegistry
+	Token    string // Sent as a bearer token when set
+}
+
+// Packument fetches the abbreviated packument of a package, which is all an install needs, and
+// decodes it into v. A package the registry doesn't have is ErrNotFound.
+func (c *Client) Packument(ctx context.Context, name string, v any) error {
+	registry := c.Registry
+	if registry == "" {
+		registry = audit.DefaultRegistry
+	}
+	// Scoped names keep their "@" but escape the "/"
+	packumentURL := strings.TrimSuffix(registry, "/") + "/" + strings.Replace(url.PathEscape(name), "%40", "@", 1)
+
+	remoteClient := c.Remote
+	if remoteClient == nil {
+		remoteClient = &remote.Client{}
+	}
+	data, err := remoteClient.Do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, packumentURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.npm.install-v1+json")
+		if c.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.Token)
+		}
+		return req, nil
+	})
+	var statusErr *remote.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w in %s", name, ErrNotFound, registry)
+	}
+	if err != nil {
+		return fmt.Errorf("packument request for %s to %s failed: %v", name, registry, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid packument for %s from %s: %v", name, registry, err)
+	}
+	return nil
+}
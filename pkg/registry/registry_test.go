@@ -0,0 +1,55 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPackument(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/vnd.npm.install-v1+json" {
+			http.Error(w, "want the abbreviated packument", http.StatusNotAcceptable)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/@ctrl%2Ftinycolor":
+			w.Write([]byte(`{"name": "@ctrl/tinycolor", "dist-tags": {"latest": "4.1.1"}}`))
+		case "/broken":
+			w.Write([]byte(`{"name":`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{Registry: server.URL + "/", Token: "token"}
+	var packument struct {
+		DistTags map[string]string `json:"dist-tags"`
+	}
+	if err := client.Packument(context.Background(), "@ctrl/tinycolor", &packument); err != nil {
+		t.Fatalf("Packument() returned error: %v", err)
+	}
+	if packument.DistTags["latest"] != "4.1.1" {
+		t.Errorf("Packument() dist-tags = %v, want latest 4.1.1", packument.DistTags)
+	}
+
+	if err := client.Packument(context.Background(), "missing", &packument); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Packument() for a missing package = %v, want ErrNotFound", err)
+	}
+	if err := client.Packument(context.Background(), "broken", &packument); err == nil || !strings.Contains(err.Error(), "invalid packument") {
+		t.Errorf("Packument() for a broken packument = %v, want an invalid packument error", err)
+	}
+
+	anonymous := &Client{Registry: server.URL}
+	if err := anonymous.Packument(context.Background(), "@ctrl/tinycolor", &packument); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Packument() without the token = %v, want a request error", err)
+	}
+}
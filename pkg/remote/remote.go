@@ -0,0 +1,148 @@
+// Package remote holds the HTTP plumbing shared by scnpm's online advisory sources:
+// retries with exponential backoff on rate limiting and transient server errors.
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxResponseSize caps how much of a response body is read
+const maxResponseSize = 64 << 20
+
+// Client sends requests with retries. The zero value uses http.DefaultClient and no retries.
+type Client struct {
+	HTTP       *http.Client
+	MaxRetries int           // Retries after the first attempt for 429 and 5xx responses
+	Backoff    time.Duration // Delay before the first retry, doubled for each subsequent one
+	UserAgent  string
+
+	sleep func(time.Duration) // Replaced in tests
+}
+
+// StatusError is returned for responses that are neither successful nor retried into success
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("server returned %s: %s", e.Status, e.Body)
+	}
+	return fmt.Sprintf("server returned %s", e.Status)
+}
+
+// Do sends the request built by newRequest and returns the body of a 2xx response.
+// newRequest is called again for every attempt so request bodies can be re-read. Every attempt
+// and the waits between them end when ctx is done.
+func (c *Client) Do(ctx context.Context, newRequest func() (*http.Request, error)) ([]byte, error) {
+	body, _, err := c.DoWithHeader(ctx, newRequest)
+	return body, err
+}
+
+// DoWithHeader is Do that also returns the response headers, e.g. for following pagination links
+func (c *Client) DoWithHeader(ctx context.Context, newRequest func() (*http.Request, error)) ([]byte, http.Header, error) {
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	delay := c.Backoff
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, nil, err
+		}
+		req = req.WithContext(ctx)
+		if c.UserAgent != "" {
+			req.Header.Set("User-Agent", c.UserAgent)
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read response: %v", err)
+		}
+		if len(body) > maxResponseSize {
+			return nil, nil, fmt.Errorf("response is larger than %d MB", maxResponseSize>>20)
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return body, resp.Header, nil
+		}
+
+		statusErr := &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: truncate(string(body), 200)}
+		if !retryable(resp) || attempt >= c.MaxRetries {
+			return nil, nil, statusErr
+		}
+
+		wait := delay
+		if retryAfter := retryAfterDelay(resp.Header.Get("Retry-After")); retryAfter > 0 {
+			wait = retryAfter
+		}
+		if err := c.wait(ctx, wait); err != nil {
+			return nil, nil, err
+		}
+		delay *= 2
+	}
+}
+
+// wait sleeps for d before a retry, or until ctx is done
+func (c *Client) wait(ctx context.Context, d time.Duration) error {
+	if c.sleep != nil {
+		c.sleep(d)
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryable reports whether a failed response is worth retrying: rate limiting (including
+// GitHub's secondary limits, sent as 403 with Retry-After) and server errors
+func retryable(resp *http.Response) bool {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true
+	case resp.StatusCode == http.StatusForbidden:
+		return resp.Header.Get("Retry-After") != ""
+	default:
+		return false
+	}
+}
+
+// retryAfterDelay parses a Retry-After header given in seconds or as an HTTP date
+func retryAfterDelay(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		return time.Until(when)
+	}
+	return 0
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,105 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDoRetries(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch attempts {
+		case 1:
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	var waits []time.Duration
+	client := &Client{MaxRetries: 3, Backoff: time.Second, sleep: func(d time.Duration) { waits = append(waits, d) }}
+	body, err := client.Do(context.Background(), func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, server.URL, nil)
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if string(body) != "ok" || attempts != 3 {
+		t.Errorf("Do() = %q after %d attempts, want \"ok\" after 3", body, attempts)
+	}
+	// The first wait honors Retry-After, the second uses the doubled backoff
+	if len(waits) != 2 || waits[0] != 7*time.Second || waits[1] != 2*time.Second {
+		t.Errorf("Do() waited %v, want [7s 2s]", waits)
+	}
+}
+
+func TestDoGivesUp(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.URL.Path == "/forbidden" {
+			http.Error(w, "no token", http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &Client{MaxRetries: 2, sleep: func(time.Duration) {}}
+	_, err := client.Do(context.Background(), func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, server.URL, nil)
+	})
+	if statusErr, ok := err.(*StatusError); !ok || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Do() error = %v, want a 503 StatusError", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Do() made %d attempts, want 3", attempts)
+	}
+
+	attempts = 0
+	_, err = client.Do(context.Background(), func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, server.URL+"/forbidden", nil)
+	})
+	if statusErr, ok := err.(*StatusError); !ok || statusErr.StatusCode != http.StatusForbidden || attempts != 1 {
+		t.Errorf("Do() error = %v after %d attempts, want a 403 StatusError without retries", err, attempts)
+	}
+}
+
+func TestDoCanceled(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// The retry waits an hour unless the context ends it
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	client := &Client{MaxRetries: 3, Backoff: time.Hour}
+	start := time.Now()
+	_, err := client.Do(ctx, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, server.URL, nil)
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || attempts != 1 || time.Since(start) > time.Minute {
+		t.Errorf("Do() = %v after %d attempts, want the deadline to end the wait for the retry", err, attempts)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts = 0
+	if _, err := client.Do(canceled, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, server.URL, nil)
+	}); !errors.Is(err, context.Canceled) || attempts != 0 {
+		t.Errorf("Do() with a canceled context = %v after %d attempts, want context.Canceled without a request", err, attempts)
+	}
+}
@@ -0,0 +1,155 @@
+package scanner
+
+import (
+	"sort"
+	"strings"
+
+	"scnpm/pkg/types"
+)
+
+// MaxChains is the most dependency chains recorded for a finding
+const MaxChains = 5
+
+// ChainIndex answers why a package is installed: the chains of dependencies leading to it from
+// the project's direct dependencies
+type ChainIndex struct {
+	labels  map[string]string   // Package name at each install path
+	parents map[string][]string // Install paths of the packages depending on each install path, sorted
+}
+
+// NewChainIndex builds the reverse-dependency index of a lockfile from its dependency maps and
+// what each dependency resolves to. npm links lend their dependents to the workspace they point
+// to.
+func NewChainIndex(packageLock *types.PackageLock) *ChainIndex {
+	labels, parents := dependencyGraph(packageLock)
+	for path, pkg := range packageLock.Packages {
+		if pkg.Link && pkg.Resolved != "" && packageLock.Resolutions == nil {
+			parents[pkg.Resolved] = append(parents[pkg.Resolved], parents[path]...)
+		}
+	}
+	for _, from := range parents {
+		sort.Strings(from)
+	}
+	return &ChainIndex{labels: labels, parents: parents}
+}
+
+// Chains returns up to MaxChains chains of package names leading to the package at path, each
+// from a direct dependency of the project (or a package nothing depends on) down to the package
+// itself. The root project (path "") has a single empty chain.
+func (c *ChainIndex) Chains(path string) [][]string {
+	chains := c.pathChains(path, MaxChains)
+	for _, chain := range chains {
+		for i, step := range chain {
+			chain[i] = c.labels[step]
+		}
+	}
+	return chains
+}
+
+// pathChains returns up to limit (any number when limit is 0) chains of install paths leading to
+// the package at path. The shortest chain through each direct dependency is found breadth-first;
+// every install path is visited once, so cycles and diamonds end the search rather than repeat it.
+func (c *ChainIndex) pathChains(path string, limit int) [][]string {
+	if path == "" {
+		return [][]string{{}}
+	}
+
+	type step struct {
+		path string
+		next *step // The step below, toward the package the search started from
+	}
+	queue := []*step{{path: path}}
+	seen := map[string]bool{path: true}
+	var chains [][]string
+	for len(queue) > 0 && (limit == 0 || len(chains) < limit) {
+		current := queue[0]
+		queue = queue[1:]
+
+		parents := c.parents[current.path]
+		if len(parents) == 0 || parents[0] == "" {
+			// A direct dependency; "" sorts first among the dependents
+			var chain []string
+			for s := current; s != nil; s = s.next {
+				chain = append(chain, s.path)
+			}
+			chains = append(chains, chain)
+			continue
+		}
+		for _, parent := range parents {
+			if !seen[parent] {
+				seen[parent] = true
+				queue = append(queue, &step{path: parent, next: current})
+			}
+		}
+	}
+	return chains
+}
+
+// InstanceChains returns the chains leading to a finding, ending in its name and version. A
+// reference's chains lead to the package declaring it.
+func (c *ChainIndex) InstanceChains(instance types.PackageInstance) [][]string {
+	path, last := instance.Path, instance.Name+"@"+instance.Version
+	if declaring, _, ok := strings.Cut(instance.Path, " -> "); ok && instance.IsReference {
+		path = declaring
+	}
+	chains := c.Chains(path)
+	for i, chain := range chains {
+		if !instance.IsReference {
+			chain = chain[:len(chain)-1]
+		}
+		chains[i] = append(chain[:len(chain):len(chain)], last)
+	}
+	return chains
+}
+
+// ChainLink is one package of a dependency chain
+type ChainLink struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Path    string `json:"path"`
+}
+
+// Explanation says why one install location of a package is there
+type Explanation struct {
+	Instance types.PackageInstance `json:"instance"`
+	Direct   bool                  `json:"direct"` // The project depends on it directly; a single chain of the package alone means nothing does otherwise
+	Chains   [][]ChainLink         `json:"chains"` // From a direct dependency down to the package itself, the shortest through each
+}
+
+// Explain returns why every installed instance of the package named name is in the lockfile,
+// in path order. Aliases match by their real name or the name they're installed under. An empty
+// version matches every installed version.
+func Explain(packageLock *types.PackageLock, name, version string) []Explanation {
+	inventory := Inventory(packageLock)
+	versions := make(map[string]string, len(inventory))
+	for _, instance := range inventory {
+		versions[instance.Path] = instance.Version
+	}
+
+	var index *ChainIndex
+	var explanations []Explanation
+	for _, instance := range inventory {
+		if instance.Name != name && instance.Alias != name || !MatchesVersion(instance.Version, version) {
+			continue
+		}
+		if index == nil {
+			index = NewChainIndex(packageLock)
+		}
+		parents := index.parents[instance.Path]
+		explanation := Explanation{Instance: instance, Direct: len(parents) > 0 && parents[0] == ""}
+		for _, paths := range index.pathChains(instance.Path, 0) {
+			chain := make([]ChainLink, 0, len(paths))
+			for _, path := range paths {
+				linkVersion, ok := versions[path]
+				if !ok {
+					// A workspace source the chain passes through
+					linkVersion = packageLock.Packages[path].Version
+				}
+				chain = append(chain, ChainLink{Name: index.labels[path], Version: linkVersion, Path: path})
+			}
+			explanation.Chains = append(explanation.Chains, chain)
+		}
+		explanations = append(explanations, explanation)
+	}
+	return explanations
+}
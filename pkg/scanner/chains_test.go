@@ -0,0 +1,221 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestChains(t *testing.T) {
+	packageLock := &types.PackageLock{
+		Name:            "app",
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"":                                   {Name: "app", Dependencies: map[string]string{"express": "^4.0.0", "koa": "^2.0.0", "web": "*"}},
+			"node_modules/express":               {Version: "4.18.0", Dependencies: map[string]string{"body-parser": "^1.0.0"}},
+			"node_modules/koa":                   {Version: "2.0.0", Dependencies: map[string]string{"body-parser": "^1.0.0", "cyclic": "1.0.0", "evil": "0.9.0"}},
+			"node_modules/koa/node_modules/evil": {Version: "0.9.0"},
+			"node_modules/body-parser":           {Version: "1.20.0", Dependencies: map[string]string{"evil": "1.0.0"}},
+			"node_modules/evil":                  {Version: "1.0.0", Dependencies: map[string]string{"body-parser": "^1.0.0"}},
+			"node_modules/cyclic":                {Version: "1.0.0", Dependencies: map[string]string{"loop": "1.0.0"}},
+			"node_modules/loop":                  {Version: "1.0.0", Dependencies: map[string]string{"cyclic": "1.0.0"}},
+			"node_modules/orphan":                {Version: "1.0.0"},
+			"node_modules/web":                   {Resolved: "packages/web", Link: true},
+			"packages/web":                       {Name: "web", Dependencies: map[string]string{"react": "^18.0.0"}},
+			"node_modules/react":                 {Version: "18.2.0"},
+		},
+	}
+	index := NewChainIndex(packageLock)
+
+	tests := []struct {
+		path string
+		want [][]string
+	}{
+		{"node_modules/express", [][]string{{"express"}}},
+		{"node_modules/evil", [][]string{{"express", "body-parser", "evil"}, {"koa", "body-parser", "evil"}}},
+		{"node_modules/koa/node_modules/evil", [][]string{{"koa", "evil"}}},
+		{"node_modules/loop", [][]string{{"koa", "cyclic", "loop"}}},
+		{"node_modules/orphan", [][]string{{"orphan"}}},
+		{"node_modules/react", [][]string{{"web", "react"}}},
+		{"", [][]string{{}}},
+	}
+
+	for _, tt := range tests {
+		if got := index.Chains(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Chains(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"":                                    {Dependencies: map[string]string{"debug": "^4.0.0", "koa": "^2.0.0"}},
+			"node_modules/debug":                  {Version: "4.3.4", Dependencies: map[string]string{"ms": "^2.1.0"}},
+			"node_modules/koa":                    {Version: "2.0.0", Dependencies: map[string]string{"debug": "^3.0.0", "ms": "^2.1.0"}},
+			"node_modules/koa/node_modules/debug": {Version: "3.2.7", Dependencies: map[string]string{"koa": "^2.0.0"}},
+			"node_modules/ms":                     {Version: "2.1.2"},
+		},
+	}
+
+	got := Explain(packageLock, "debug", "")
+	if len(got) != 2 {
+		t.Fatalf("Explain(debug) = %+v, want both installed versions", got)
+	}
+	if !got[0].Direct || !reflect.DeepEqual(got[0].Chains, [][]ChainLink{{{Name: "debug", Version: "4.3.4", Path: "node_modules/debug"}}}) {
+		t.Errorf("Explain(debug)[0] = %+v, want the direct dependency alone", got[0])
+	}
+	// koa depends on the nested debug, which depends on koa again
+	want := [][]ChainLink{{{Name: "koa", Version: "2.0.0", Path: "node_modules/koa"}, {Name: "debug", Version: "3.2.7", Path: "node_modules/koa/node_modules/debug"}}}
+	if got[1].Direct || !reflect.DeepEqual(got[1].Chains, want) {
+		t.Errorf("Explain(debug)[1] = %+v, want the chain through koa despite the cycle", got[1])
+	}
+
+	if ms := Explain(packageLock, "ms", ""); len(ms) != 1 || len(ms[0].Chains) != 2 {
+		t.Errorf("Explain(ms) = %+v, want chains through debug and koa", ms)
+	}
+	if only := Explain(packageLock, "debug", "^3.0.0"); len(only) != 1 || only[0].Instance.Version != "3.2.7" {
+		t.Errorf("Explain(debug@^3.0.0) = %+v, want the 3.x instance", only)
+	}
+	if none := Explain(packageLock, "express", ""); len(none) != 0 {
+		t.Errorf("Explain(express) = %+v, want nothing", none)
+	}
+}
+
+func TestScanPackagesChains(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"":                         {Dependencies: map[string]string{"express": "^4.0.0"}},
+			"node_modules/express":     {Version: "4.18.0", Dependencies: map[string]string{"body-parser": "^1.0.0"}},
+			"node_modules/body-parser": {Version: "1.20.0", Dependencies: map[string]string{"evil": "1.0.0"}},
+			"node_modules/body-parser/node_modules/evil": {Version: "1.0.0"},
+		},
+	}
+	results := ScanPackages(packageLock, []types.PackageQuery{{Name: "evil", Version: "1.0.0"}}, FilterConfig{ShowReferences: true})
+
+	want := map[string][][]string{
+		"node_modules/body-parser/node_modules/evil": {{"express", "body-parser", "evil@1.0.0"}},
+		"node_modules/body-parser -> evil":           {{"express", "body-parser", "evil@1.0.0"}},
+	}
+	if len(results[0].Instances) != len(want) {
+		t.Fatalf("ScanPackages() found %d instances, want %d", len(results[0].Instances), len(want))
+	}
+	for _, instance := range results[0].Instances {
+		if !reflect.DeepEqual(instance.Chains, want[instance.Path]) {
+			t.Errorf("%s chains = %v, want %v", instance.Path, instance.Chains, want[instance.Path])
+		}
+	}
+}
+
+// yarnLock and pnpmLock are express depending on debug 2.6.9 while the project wants debug 4, as
+// lockfile.ParseYarn and lockfile.ParsePnpm record them
+var (
+	yarnLock = &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"debug@2.6.9":    {Name: "debug", Version: "2.6.9", Dependencies: map[string]string{"ms": "2.0.0"}},
+			"debug@4.3.4":    {Name: "debug", Version: "4.3.4"},
+			"express@4.18.2": {Name: "express", Version: "4.18.2", Dependencies: map[string]string{"debug": "2.6.9"}},
+			"ms@2.0.0":       {Name: "ms", Version: "2.0.0"},
+		},
+		Resolutions: map[string]map[string]string{
+			"debug@2.6.9":    {"ms": "ms@2.0.0"},
+			"express@4.18.2": {"debug": "debug@2.6.9"},
+		},
+	}
+	pnpmLock = &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"": {Dependencies: map[string]string{"debug": "4.3.4", "express": "4.18.2"}},
+			"node_modules/.pnpm/debug@2.6.9/node_modules/debug":      {Version: "2.6.9"},
+			"node_modules/.pnpm/debug@4.3.4/node_modules/debug":      {Version: "4.3.4"},
+			"node_modules/.pnpm/express@4.18.2/node_modules/express": {Version: "4.18.2", Dependencies: map[string]string{"debug": "2.6.9"}},
+		},
+		Resolutions: map[string]map[string]string{
+			"": {
+				"debug":   "node_modules/.pnpm/debug@4.3.4/node_modules/debug",
+				"express": "node_modules/.pnpm/express@4.18.2/node_modules/express",
+			},
+			"node_modules/.pnpm/express@4.18.2/node_modules/express": {"debug": "node_modules/.pnpm/debug@2.6.9/node_modules/debug"},
+		},
+	}
+)
+
+func TestChainsResolutions(t *testing.T) {
+	tests := []struct {
+		name        string
+		packageLock *types.PackageLock
+		want        map[string][][]string
+	}{
+		{
+			name:        "yarn",
+			packageLock: yarnLock,
+			want: map[string][][]string{
+				"ms@2.0.0":    {{"express", "debug", "ms"}},
+				"debug@4.3.4": {{"debug"}},
+			},
+		},
+		{
+			name:        "pnpm",
+			packageLock: pnpmLock,
+			want: map[string][][]string{
+				"node_modules/.pnpm/debug@2.6.9/node_modules/debug": {{"express", "debug"}},
+				"node_modules/.pnpm/debug@4.3.4/node_modules/debug": {{"debug"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := NewChainIndex(tt.packageLock)
+			for path, want := range tt.want {
+				if got := index.Chains(path); !reflect.DeepEqual(got, want) {
+					t.Errorf("Chains(%q) = %v, want %v", path, got, want)
+				}
+			}
+
+			results := ScanPackages(tt.packageLock, []types.PackageQuery{{Name: "debug", Version: "2.6.9"}}, FilterConfig{ShowReferences: true})
+			if len(results[0].Instances) == 0 || results[0].Instances[0].IsReference {
+				t.Fatalf("ScanPackages() found %+v, want the installed instance", results[0].Instances)
+			}
+			if got, want := results[0].Instances[0].Chains, [][]string{{"express", "debug@2.6.9"}}; !reflect.DeepEqual(got, want) {
+				t.Errorf("debug@2.6.9 chains = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestExplainResolutions(t *testing.T) {
+	tests := []struct {
+		name        string
+		packageLock *types.PackageLock
+		want        [][]ChainLink
+	}{
+		{
+			name:        "yarn",
+			packageLock: yarnLock,
+			want:        [][]ChainLink{{{Name: "express", Version: "4.18.2", Path: "express@4.18.2"}, {Name: "debug", Version: "2.6.9", Path: "debug@2.6.9"}}},
+		},
+		{
+			name:        "pnpm",
+			packageLock: pnpmLock,
+			want: [][]ChainLink{{
+				{Name: "express", Version: "4.18.2", Path: "node_modules/.pnpm/express@4.18.2/node_modules/express"},
+				{Name: "debug", Version: "2.6.9", Path: "node_modules/.pnpm/debug@2.6.9/node_modules/debug"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Explain(tt.packageLock, "debug", "2.6.9")
+			if len(got) != 1 || got[0].Direct || !reflect.DeepEqual(got[0].Chains, tt.want) {
+				t.Errorf("Explain(debug@2.6.9) = %+v, want the chain through express", got)
+			}
+		})
+	}
+	if got := Explain(pnpmLock, "debug", "4.3.4"); len(got) != 1 || !got[0].Direct {
+		t.Errorf("Explain(debug@4.3.4) = %+v, want the root importer's direct dependency", got)
+	}
+}
@@ -0,0 +1,552 @@
+package scanner
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"scnpm/pkg/license"
+	"scnpm/pkg/scriptscan"
+	"scnpm/pkg/types"
+	"scnpm/pkg/typosquat"
+)
+
+// installedEntry is one installed package of a lockfile, whatever its format
+type installedEntry struct {
+	Path      string
+	Name      string
+	Alias     string // Name an npm: alias installed the package under
+	Version   string
+	Resolved  string
+	Integrity string
+	License   string
+	Link      bool
+	Bundled   bool
+	Install   bool              // Runs install scripts
+	Scripts   map[string]string // Script bodies, when the lockfile or node_modules provided them
+	Dev       bool
+	Line      int
+}
+
+// installedEntries returns every installed package of a lockfile in path order. The root project
+// and workspace sources (entries outside node_modules) aren't installed packages and are skipped.
+func installedEntries(packageLock *types.PackageLock) []installedEntry {
+	var entries []installedEntry
+
+	if packageLock.LockfileVersion >= 2 {
+		for path, pkg := range packageLock.Packages {
+			entry := packagesEntry(path, pkg, packageLock.Lines)
+			if entry.Name == "" {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	} else {
+		var walk func(deps map[string]types.Dependency, basePath string)
+		walk = func(deps map[string]types.Dependency, basePath string) {
+			for name, dep := range deps {
+				path := "node_modules/" + name
+				if basePath != "" {
+					path = basePath + "/node_modules/" + name
+				}
+				entries = append(entries, dependenciesEntry(path, name, dep, packageLock.Lines))
+				walk(dep.Dependencies, path)
+			}
+		}
+		walk(packageLock.Dependencies, "")
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+// packagesEntry returns the installed entry at path of a lockfileVersion 2+ packages map; its
+// Name is empty for the root project and workspace sources
+func packagesEntry(path string, pkg types.Package, lines map[string]int) installedEntry {
+	return installedEntry{
+		Path:      path,
+		Name:      entryName(path, pkg),
+		Alias:     installedAlias(path, pkg),
+		Version:   pkg.Version,
+		Resolved:  pkg.Resolved,
+		Integrity: pkg.Integrity,
+		License:   pkg.License,
+		Link:      pkg.Link,
+		Bundled:   pkg.InBundle,
+		Install:   pkg.HasInstallScript,
+		Scripts:   pkg.Scripts,
+		Dev:       pkg.Dev,
+		Line:      lines[path],
+	}
+}
+
+// dependenciesEntry returns the installed entry of a lockfileVersion 1 dependency named name at path
+func dependenciesEntry(path, name string, dep types.Dependency, lines map[string]int) installedEntry {
+	entry := installedEntry{
+		Path:      path,
+		Name:      name,
+		Version:   dep.Version,
+		Resolved:  v1Resolved(dep),
+		Integrity: dep.Integrity,
+		Bundled:   dep.Bundled,
+		Dev:       dep.Dev,
+		Line:      lines[path],
+	}
+	// lockfileVersion 1 records aliases in the version field
+	if realName, realVersion, ok := parseAlias(dep.Version); ok {
+		entry.Name, entry.Version, entry.Alias = realName, realVersion, name
+	}
+	return entry
+}
+
+// newInstance returns the instance of an installed entry with the fields every report shares:
+// where it's installed, how deep, whether it's a dev dependency and where it came from
+func newInstance(entry installedEntry) types.PackageInstance {
+	return types.PackageInstance{
+		Name:             entry.Name,
+		Alias:            entry.Alias,
+		Version:          entry.Version,
+		Path:             entry.Path,
+		LineNumber:       entry.Line,
+		IsDev:            entry.Dev,
+		IsNested:         strings.Contains(entry.Path, "/node_modules/"),
+		Depth:            strings.Count(entry.Path, "/node_modules/"),
+		InstallSource:    InstallSource(entry.Resolved, entry.Link),
+		HasInstallScript: entry.Install,
+	}
+}
+
+// gitPrefixes are the resolved-spec prefixes of git dependencies
+var gitPrefixes = []string{"git+", "git:", "git@", "github:", "gitlab:", "bitbucket:", "gist:"}
+
+// InstallSource classifies where an installed package came from by its resolved spec: one of
+// the types.Source* values. Registry tarballs are recognized by npm's "/-/" tarball path, so
+// any other URL is a remote tarball.
+func InstallSource(resolved string, link bool) string {
+	if link {
+		return types.SourceLink
+	}
+	for _, prefix := range gitPrefixes {
+		if strings.HasPrefix(resolved, prefix) {
+			return types.SourceGit
+		}
+	}
+
+	// yarn berry resolutions read "name@protocol:..."; only the protocol part matters
+	start := 0
+	if strings.HasPrefix(resolved, "@") {
+		start = 1
+	}
+	if i := strings.Index(resolved[start:], "@"); i >= 0 && !strings.Contains(resolved[:start+i], ":") {
+		resolved = resolved[start+i+1:]
+	}
+
+	switch {
+	case resolved == "", strings.HasPrefix(resolved, "npm:"), strings.HasPrefix(resolved, "patch:"):
+		return types.SourceRegistry
+	case strings.HasPrefix(resolved, "file:"):
+		return types.SourceFile
+	case strings.HasPrefix(resolved, "link:"), strings.HasPrefix(resolved, "portal:"), strings.HasPrefix(resolved, "workspace:"):
+		return types.SourceLink
+	case strings.HasPrefix(resolved, "http://"), strings.HasPrefix(resolved, "https://"):
+		if strings.Contains(resolved, "/-/") {
+			return types.SourceRegistry
+		}
+		return types.SourceRemoteTarball
+	}
+	for _, prefix := range gitPrefixes {
+		if strings.HasPrefix(resolved, prefix) {
+			return types.SourceGit
+		}
+	}
+	// A bare path is a local directory or tarball
+	return types.SourceFile
+}
+
+// Inventory returns every installed package of a lockfile in path order, with where it came from
+// and its integrity and license, for bills of materials
+func Inventory(packageLock *types.PackageLock) []types.PackageInstance {
+	entries := installedEntries(packageLock)
+	inventory := make([]types.PackageInstance, 0, len(entries))
+	for _, entry := range entries {
+		instance := newInstance(entry)
+		instance.Resolved, instance.Integrity, instance.License = entry.Resolved, entry.Integrity, entry.License
+		inventory = append(inventory, instance)
+	}
+	return inventory
+}
+
+// List returns the installed packages of a lockfile that pass the dev, nesting and depth filters
+// of config, in path order
+func List(packageLock *types.PackageLock, config FilterConfig) []types.PackageInstance {
+	return applyFilters(Inventory(packageLock), config)
+}
+
+// NonRegistryPackages reports every installed package that didn't come from the registry
+// (local directories and tarballs, links, git and remote tarballs), one result per package name
+// in name order, independent of any bad-package query
+func NonRegistryPackages(packageLock *types.PackageLock) []types.ScanResult {
+	return groupByName(types.CheckInstallSource, packageLock, func(entry installedEntry) (types.PackageInstance, bool) {
+		source := InstallSource(entry.Resolved, entry.Link)
+		if source == types.SourceRegistry {
+			return types.PackageInstance{}, false
+		}
+		return types.PackageInstance{Resolved: entry.Resolved, InstallSource: source}, true
+	})
+}
+
+// MutableSource reports whether an install source can serve different contents under the same
+// version: git refs and tarball URLs bypass the registry's immutable versions
+func MutableSource(source string) bool {
+	return source == types.SourceGit || source == types.SourceRemoteTarball
+}
+
+// commitPattern matches a full git commit SHA
+var commitPattern = regexp.MustCompile(`\b[0-9a-fA-F]{40}\b`)
+
+// PinnedCommit returns the full commit SHA a git or remote tarball spec is pinned to
+// ("git+ssh://git@github.com/o/r.git#<sha>", "https://codeload.github.com/o/r/tar.gz/<sha>"),
+// or "" when it follows a branch, tag or mutable URL
+func PinnedCommit(resolved string) string {
+	return strings.ToLower(commitPattern.FindString(resolved))
+}
+
+// NonRegistry reports every installed package resolved from git or a remote tarball, with its URL,
+// the commit it's pinned to if any, and the packages that depend on it, one result per package
+// name in name order
+func NonRegistry(packageLock *types.PackageLock) []types.ScanResult {
+	dependents := dependentsByPath(packageLock)
+	return groupByName(types.CheckNonRegistry, packageLock, func(entry installedEntry) (types.PackageInstance, bool) {
+		source := InstallSource(entry.Resolved, entry.Link)
+		if !MutableSource(source) {
+			return types.PackageInstance{}, false
+		}
+		return types.PackageInstance{
+			Resolved:      entry.Resolved,
+			InstallSource: source,
+			PinnedCommit:  PinnedCommit(entry.Resolved),
+			DependedOnBy:  dependents[entry.Path],
+		}, true
+	})
+}
+
+// dependentsByPath maps the install path of every package to the sorted names of the packages
+// that depend on it, resolved by resolveEntry. The root project is named after the lockfile, or
+// "(root)".
+func dependentsByPath(packageLock *types.PackageLock) map[string][]string {
+	labels, parents := dependencyGraph(packageLock)
+	dependents := make(map[string][]string)
+	for path, from := range parents {
+		var names []string
+		for _, parent := range from {
+			names = append(names, labels[parent])
+		}
+		sort.Strings(names)
+		unique := names[:0]
+		for i, name := range names {
+			if i == 0 || name != names[i-1] {
+				unique = append(unique, name)
+			}
+		}
+		dependents[path] = unique
+	}
+	return dependents
+}
+
+// dependencyGraph returns the name of the package at every install path, and the install paths of
+// the packages that depend on each one, resolved by resolveEntry. The root project is at path "",
+// named after the lockfile or "(root)".
+func dependencyGraph(packageLock *types.PackageLock) (labels map[string]string, parents map[string][]string) {
+	labels = make(map[string]string)
+	requires := make(map[string][]string)
+
+	if packageLock.LockfileVersion >= 2 {
+		for path, pkg := range packageLock.Packages {
+			label := entryName(path, pkg)
+			if label == "" {
+				// The root project or a workspace source
+				label = pkg.Name
+			}
+			if label == "" && path != "" {
+				label = path
+			}
+			labels[path] = label
+			for _, deps := range []map[string]string{pkg.Dependencies, pkg.OptionalDependencies, pkg.PeerDependencies, pkg.DevDependencies} {
+				for name := range deps {
+					requires[path] = append(requires[path], name)
+				}
+			}
+		}
+	} else {
+		var walk func(deps map[string]types.Dependency, basePath string)
+		walk = func(deps map[string]types.Dependency, basePath string) {
+			for name, dep := range deps {
+				path := "node_modules/" + name
+				if basePath != "" {
+					path = basePath + "/node_modules/" + name
+				}
+				labels[path] = name
+				if realName, _, ok := parseAlias(dep.Version); ok {
+					labels[path] = realName
+				}
+				for required := range dep.Requires {
+					requires[path] = append(requires[path], required)
+				}
+				walk(dep.Dependencies, path)
+			}
+		}
+		walk(packageLock.Dependencies, "")
+	}
+	if labels[""] == "" {
+		labels[""] = packageLock.Name
+		if labels[""] == "" {
+			labels[""] = "(root)"
+		}
+	}
+
+	parents = make(map[string][]string)
+	for path, names := range requires {
+		for _, name := range names {
+			if target := resolveEntry(packageLock, labels, path, name); target != "" {
+				parents[target] = append(parents[target], path)
+			}
+		}
+	}
+	return labels, parents
+}
+
+// resolveEntry returns the key of the entry a dependency of the entry at from resolves to, or ""
+// if none does. yarn and pnpm lockfiles record their resolutions; npm's are found up the
+// node_modules tree by resolveDependency, among the install paths of installed.
+func resolveEntry(packageLock *types.PackageLock, installed map[string]string, from, name string) string {
+	if packageLock.Resolutions != nil {
+		return packageLock.Resolutions[from][name]
+	}
+	return resolveDependency(installed, from, name)
+}
+
+// resolveDependency returns the install path a dependency of the package at from resolves to:
+// the first of from/node_modules/name, then the same in each parent package up to the root,
+// that is installed; "" if none is
+func resolveDependency(installed map[string]string, from, name string) string {
+	dir := from
+	for {
+		candidate := "node_modules/" + name
+		if dir != "" {
+			candidate = dir + "/node_modules/" + name
+		}
+		if _, ok := installed[candidate]; ok {
+			return candidate
+		}
+		if dir == "" {
+			return ""
+		}
+		if i := strings.LastIndex(dir, "node_modules/"); i > 0 {
+			dir = strings.TrimSuffix(dir[:i], "/")
+		} else {
+			// A top-level package or a workspace source; the root comes next
+			dir = ""
+		}
+	}
+}
+
+// Typosquats reports every installed package whose name is within maxDistance edits of a popular
+// package it isn't, such as lodahs or crossenv, one result per package name in name order
+func Typosquats(packageLock *types.PackageLock, maxDistance int) []types.ScanResult {
+	checked := make(map[string]typosquat.Match)
+	return groupByName(types.CheckTyposquat, packageLock, func(entry installedEntry) (types.PackageInstance, bool) {
+		match, seen := checked[entry.Name]
+		if !seen {
+			match, _ = typosquat.Check(entry.Name, maxDistance)
+			checked[entry.Name] = match
+		}
+		if match.Resembles == "" {
+			return types.PackageInstance{}, false
+		}
+		return types.PackageInstance{Resembles: match.Resembles, Distance: match.Distance}, true
+	})
+}
+
+// MissingIntegrity reports every installed package whose lockfile entry has no integrity hash,
+// so the installed tarball can't be verified, one result per package name in name order.
+// Links and workspaces have no tarball, and bundled dependencies are covered by their parent's
+// hash, so none of them is reported.
+func MissingIntegrity(packageLock *types.PackageLock) []types.ScanResult {
+	return groupByName(types.CheckMissingIntegrity, packageLock, func(entry installedEntry) (types.PackageInstance, bool) {
+		source := InstallSource(entry.Resolved, entry.Link)
+		if entry.Integrity != "" || entry.Bundled || source == types.SourceLink {
+			return types.PackageInstance{}, false
+		}
+		return types.PackageInstance{Resolved: entry.Resolved, InstallSource: source}, true
+	})
+}
+
+// InstallScripts reports every installed package that runs preinstall, install or postinstall
+// scripts (hasInstallScript in lockfileVersion 2+, requiresBuild in pnpm), one result per package
+// name in name order. lockfileVersion 1 and yarn lockfiles don't record this.
+func InstallScripts(packageLock *types.PackageLock) []types.ScanResult {
+	return groupByName(types.CheckInstallScript, packageLock, func(entry installedEntry) (types.PackageInstance, bool) {
+		return types.PackageInstance{HasInstallScript: true}, entry.Install
+	})
+}
+
+// SuspiciousScripts reports every installed package whose lifecycle scripts match one of the
+// scriptscan heuristics, with an instance per matching script and heuristic, one result per
+// package name in name order. Only entries with script bodies (see lockfile.LoadScripts) are
+// checked.
+func SuspiciousScripts(packageLock *types.PackageLock) []types.ScanResult {
+	return groupAllByName(types.CheckSuspiciousScript, packageLock, func(entry installedEntry) []types.PackageInstance {
+		var instances []types.PackageInstance
+		for _, finding := range scriptscan.Scan(entry.Scripts) {
+			instances = append(instances, types.PackageInstance{
+				HasInstallScript: true,
+				Script:           finding.Script,
+				Heuristic:        finding.Heuristic,
+				Snippet:          finding.Snippet,
+			})
+		}
+		return instances
+	})
+}
+
+// LicenseViolations reports every installed package whose license expression can't be satisfied
+// without one of the denied SPDX identifiers, one result per package name in name order. Unlike
+// the other checks it honors the dev-only, nested-only and depth filters of config. Only
+// lockfileVersion 2+ records licenses.
+func LicenseViolations(packageLock *types.PackageLock, denied []string, config FilterConfig) []types.ScanResult {
+	results := groupByName(types.CheckLicense, packageLock, func(entry installedEntry) (types.PackageInstance, bool) {
+		return types.PackageInstance{License: entry.License}, entry.License != "" && license.Violates(entry.License, denied)
+	})
+
+	filtered := results[:0]
+	for _, result := range results {
+		result.Instances = applyFilters(result.Instances, config)
+		result.TotalInstances = len(result.Instances)
+		if result.TotalInstances > 0 {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+// IntegrityAlgorithms lists the SRI hash algorithms npm lockfiles use, weakest first
+var IntegrityAlgorithms = []string{"sha1", "sha256", "sha384", "sha512"}
+
+// IntegrityStrength ranks an SRI hash algorithm: higher is stronger, 0 for unknown algorithms
+func IntegrityStrength(algorithm string) int {
+	for i, known := range IntegrityAlgorithms {
+		if known == algorithm {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// strongestAlgorithm returns the strongest algorithm of an integrity string, which may list
+// several hashes ("sha512-... sha1-..."); npm verifies against the strongest. It's "" when
+// the string holds no SRI hash (e.g. yarn berry checksums).
+func strongestAlgorithm(integrity string) string {
+	strongest := ""
+	for _, hash := range strings.Fields(integrity) {
+		algorithm, _, _ := strings.Cut(hash, "-")
+		if IntegrityStrength(algorithm) > IntegrityStrength(strongest) {
+			strongest = algorithm
+		}
+	}
+	return strongest
+}
+
+// WeakIntegrity reports every installed package whose integrity hash uses a weaker algorithm than
+// minAlgorithm (one of IntegrityAlgorithms), such as the sha1 hashes of old lockfiles, one result
+// per package name in name order
+func WeakIntegrity(packageLock *types.PackageLock, minAlgorithm string) []types.ScanResult {
+	return groupByName(types.CheckWeakIntegrity, packageLock, func(entry installedEntry) (types.PackageInstance, bool) {
+		algorithm := strongestAlgorithm(entry.Integrity)
+		if algorithm == "" || IntegrityStrength(algorithm) >= IntegrityStrength(minAlgorithm) {
+			return types.PackageInstance{}, false
+		}
+		return types.PackageInstance{Integrity: entry.Integrity, IntegrityAlgo: algorithm}, true
+	})
+}
+
+// NearMatchDistance is the largest edit distance NearMatches reports
+const NearMatchDistance = 2
+
+// NearMatches reports installed packages whose names are 1 or 2 edits away from a queried name
+// without matching it, such as evnet-stream for an event-stream advisory. Scoped names are
+// compared whole. Pattern queries, queried names shorter than typosquat.MinLength and popular
+// packages are skipped, as they'd mostly turn up unrelated packages.
+func NearMatches(packageLock *types.PackageLock, queries []types.PackageQuery, config FilterConfig) []types.ScanResult {
+	var names []string
+	seen := make(map[string]bool)
+	for _, query := range queries {
+		if !seen[query.Name] && !IsPattern(query.Name) && len(query.Name) >= typosquat.MinLength {
+			seen[query.Name] = true
+			names = append(names, query.Name)
+		}
+	}
+
+	return groupByName(types.CheckNearMatch, packageLock, func(entry installedEntry) (types.PackageInstance, bool) {
+		if seen[entry.Name] || typosquat.IsPopular(entry.Name) {
+			return types.PackageInstance{}, false
+		}
+		best := types.PackageInstance{Distance: NearMatchDistance + 1}
+		for _, name := range names {
+			if MatchPackageName(entry.Name, name, config) != "" {
+				// Matches (e.g. via --fuzzy) are findings, not near misses
+				return types.PackageInstance{}, false
+			}
+			if d := typosquat.Distance(entry.Name, name); d < best.Distance {
+				best.Resembles, best.Distance = name, d
+			}
+		}
+		return best, best.Resembles != ""
+	})
+}
+
+// groupByName runs a per-entry check over the installed packages and collects what it reports
+// into one result per package name, in name order. The check fills in check-specific fields; the
+// location fields are filled in from the entry.
+func groupByName(check string, packageLock *types.PackageLock, report func(installedEntry) (types.PackageInstance, bool)) []types.ScanResult {
+	return groupAllByName(check, packageLock, func(entry installedEntry) []types.PackageInstance {
+		if instance, ok := report(entry); ok {
+			return []types.PackageInstance{instance}
+		}
+		return nil
+	})
+}
+
+// groupAllByName is groupByName for checks that may report several instances per entry
+func groupAllByName(check string, packageLock *types.PackageLock, report func(installedEntry) []types.PackageInstance) []types.ScanResult {
+	byName := make(map[string]*types.ScanResult)
+	var names []string
+	for _, entry := range installedEntries(packageLock) {
+		for _, instance := range report(entry) {
+			instance.Name = entry.Name
+			instance.Version = entry.Version
+			instance.Path = entry.Path
+			instance.LineNumber = entry.Line
+			instance.IsDev = entry.Dev
+			instance.IsNested = strings.Contains(entry.Path, "/node_modules/")
+			instance.Depth = strings.Count(entry.Path, "/node_modules/")
+
+			result, seen := byName[entry.Name]
+			if !seen {
+				result = &types.ScanResult{Package: types.PackageQuery{Name: entry.Name}, Check: check}
+				byName[entry.Name] = result
+				names = append(names, entry.Name)
+			}
+			result.Instances = append(result.Instances, instance)
+		}
+	}
+
+	sort.Strings(names)
+	results := make([]types.ScanResult, 0, len(names))
+	for _, name := range names {
+		result := byName[name]
+		result.TotalInstances = len(result.Instances)
+		result.Found = true
+		results = append(results, *result)
+	}
+	return results
+}
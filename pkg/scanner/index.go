@@ -0,0 +1,88 @@
+package scanner
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"scnpm/pkg/types"
+)
+
+// nameIndex maps package names to the lockfile entries that can match them, so that a query for
+// an exact name visits its few candidates instead of every entry. Names are lowercased to serve
+// --ignore-case too; the candidates still go through the same matching as a full scan.
+type nameIndex struct {
+	packages     map[string][]string          // Packages keys of the entries installing each name (lockfileVersion 2+)
+	referrers    map[string][]string          // Packages keys of the entries depending on each name (lockfileVersion 2+)
+	dependencies map[string][]dependencyEntry // Entries of the dependencies tree installing each name (lockfileVersion 1)
+}
+
+// dependencyEntry is a package of a lockfileVersion 1 dependencies tree with its install path
+type dependencyEntry struct {
+	path string
+	name string
+	dep  types.Dependency
+}
+
+// newNameIndex indexes a lockfile's entries by the installed and aliased names they match, and
+// its packages by the names of their dependencies
+func newNameIndex(packageLock *types.PackageLock) *nameIndex {
+	index := &nameIndex{
+		packages:     make(map[string][]string),
+		referrers:    make(map[string][]string),
+		dependencies: make(map[string][]dependencyEntry),
+	}
+
+	if packageLock.LockfileVersion >= 2 {
+		for path, pkg := range packageLock.Packages {
+			addPath(index.packages, packageNameFromPath(path), path)
+			if pkg.Name != "" {
+				addPath(index.packages, pkg.Name, path)
+			}
+			for _, deps := range []map[string]string{pkg.Dependencies, pkg.OptionalDependencies, pkg.DevDependencies, pkg.PeerDependencies} {
+				for depName, depVersion := range deps {
+					addPath(index.referrers, depName, path)
+					if realName, _, ok := parseAlias(depVersion); ok {
+						addPath(index.referrers, realName, path)
+					}
+				}
+			}
+		}
+		return index
+	}
+
+	var walk func(deps map[string]types.Dependency, basePath string)
+	walk = func(deps map[string]types.Dependency, basePath string) {
+		for depName, dep := range deps {
+			path := dependencyPath(basePath, depName)
+			entry := dependencyEntry{path: path, name: depName, dep: dep}
+			index.dependencies[strings.ToLower(depName)] = append(index.dependencies[strings.ToLower(depName)], entry)
+			if realName, _, ok := parseAlias(dep.Version); ok && !strings.EqualFold(realName, depName) {
+				index.dependencies[strings.ToLower(realName)] = append(index.dependencies[strings.ToLower(realName)], entry)
+			}
+			walk(dep.Dependencies, path)
+		}
+	}
+	walk(packageLock.Dependencies, "")
+	return index
+}
+
+// addPath records path under name, once: every name of an entry is added before the next entry's
+func addPath(index map[string][]string, name, path string) {
+	if name == "" {
+		return
+	}
+	name = strings.ToLower(name)
+	if paths := index[name]; len(paths) > 0 && paths[len(paths)-1] == path {
+		return
+	}
+	index[name] = append(index[name], path)
+}
+
+// indexable reports whether the index finds every match of a query: its name must match exactly,
+// up to case. Patterns, fuzzy and scope-relaxed matching compare every name, as does debug
+// logging, which reports the names that nearly matched.
+func indexable(query types.PackageQuery, config FilterConfig) bool {
+	return !IsPattern(query.Name) && !config.Fuzzy && !config.MatchUnscoped &&
+		!slog.Default().Enabled(context.Background(), slog.LevelDebug)
+}
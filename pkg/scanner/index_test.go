@@ -0,0 +1,137 @@
+package scanner
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestNameIndexMatchesFullScan(t *testing.T) {
+	v3 := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"": {
+				Name:                 "app",
+				Dependencies:         map[string]string{"lodash": "^4.17.0", "my-lodash": "npm:lodash@4.17.15", "@types/node": "^18.0.0"},
+				DevDependencies:      map[string]string{"JSONStream": "^1.0.0", "lodash": "^4.17.0"},
+				OptionalDependencies: map[string]string{"fsevents": "^2.3.0"},
+				PeerDependencies:     map[string]string{"react": "^18.0.0"},
+			},
+			"node_modules/lodash":                     {Version: "4.17.21"},
+			"node_modules/my-lodash":                  {Name: "lodash", Version: "4.17.15"},
+			"node_modules/a/node_modules/lodash":      {Version: "4.17.20", Dependencies: map[string]string{"debug": "2.6.9"}},
+			"node_modules/a":                          {Version: "1.0.0", Dependencies: map[string]string{"lodash": "4.17.20"}},
+			"node_modules/@types/node":                {Version: "18.0.0", Dev: true, Integrity: "sha512-abc"},
+			"node_modules/JSONStream":                 {Version: "1.3.5", Dev: true},
+			"node_modules/fsevents":                   {Version: "2.3.2"},
+			"node_modules/react":                      {Version: "18.2.0"},
+			"debug@2.6.9":                             {Name: "debug", Version: "2.6.9"},
+			"node_modules/b/node_modules/@types/node": {Version: "16.0.0"},
+		},
+	}
+	v1 := &types.PackageLock{
+		LockfileVersion: 1,
+		Dependencies: map[string]types.Dependency{
+			"lodash":    {Version: "4.17.21"},
+			"my-lodash": {Version: "npm:lodash@4.17.15"},
+			"a": {Version: "1.0.0", Dependencies: map[string]types.Dependency{
+				"lodash":     {Version: "4.17.20"},
+				"JSONStream": {Version: "1.3.5", Integrity: "sha512-def"},
+			}},
+		},
+	}
+
+	queries := []types.PackageQuery{
+		{Name: "lodash"},
+		{Name: "lodash", Version: "4.17.15"},
+		{Name: "lodash", Version: "<4.17.21 || 4.17.21"},
+		{Name: "my-lodash"},
+		{Name: "@types/node", Version: "18.0.0"},
+		{Name: "@types/node", Integrity: "sha512-abc"},
+		{Name: "jsonstream"},
+		{Name: "JSONStream", Integrity: "sha512-def"},
+		{Name: "debug"},
+		{Name: "fsevents"},
+		{Name: "react"},
+		{Name: "vue"},
+	}
+	configs := []FilterConfig{{}, {IgnoreCase: true}, {PeerReferences: true}}
+
+	for _, lock := range []*types.PackageLock{v3, v1} {
+		index := newNameIndex(lock)
+		for _, config := range configs {
+			for _, query := range queries {
+				want := findPackageInstancesInLock(lock, nil, query, config)
+				got := findPackageInstancesInLock(lock, index, query, config)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("v%d %+v %+v: indexed %+v, want %+v", lock.LockfileVersion, query, config, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestIndexable(t *testing.T) {
+	tests := []struct {
+		name   string
+		config FilterConfig
+		want   bool
+	}{
+		{"lodash", FilterConfig{}, true},
+		{"Lodash", FilterConfig{IgnoreCase: true}, true},
+		{"lodash", FilterConfig{Fuzzy: true}, false},
+		{"node", FilterConfig{MatchUnscoped: true}, false},
+		{"@babel/*", FilterConfig{}, false},
+		{"re:^lodash", FilterConfig{}, false},
+	}
+	for _, tt := range tests {
+		if got := indexable(types.PackageQuery{Name: tt.name}, tt.config); got != tt.want {
+			t.Errorf("indexable(%q, %+v) = %v, want %v", tt.name, tt.config, got, tt.want)
+		}
+	}
+}
+
+// benchmarkLock generates a lockfile of packages installed once at the top level and again nested
+// under a few dependents, each depending on the next packages
+func benchmarkLock(packages int) *types.PackageLock {
+	lock := &types.PackageLock{LockfileVersion: 3, Packages: map[string]types.Package{"": {Name: "app", Dependencies: map[string]string{}}}}
+	for i := 0; i < packages; i++ {
+		name := fmt.Sprintf("pkg-%d", i)
+		deps := map[string]string{}
+		for j := 1; j <= 3; j++ {
+			deps[fmt.Sprintf("pkg-%d", (i+j)%packages)] = "^1.0.0"
+		}
+		lock.Packages["node_modules/"+name] = types.Package{Version: "1.0.0", Dependencies: deps}
+		lock.Packages[fmt.Sprintf("node_modules/pkg-%d/node_modules/%s", (i+7)%packages, name)] = types.Package{Version: "0.9.0"}
+		if i%10 == 0 {
+			lock.Packages[""].Dependencies[name] = "^1.0.0"
+		}
+	}
+	return lock
+}
+
+func BenchmarkScanPackages(b *testing.B) {
+	lock := benchmarkLock(5000)
+	var queries []types.PackageQuery
+	for i := 0; i < 200; i++ {
+		queries = append(queries, types.PackageQuery{Name: fmt.Sprintf("pkg-%d", i*23), Version: "0.9.0"})
+	}
+
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			index := newNameIndex(lock)
+			for _, query := range queries {
+				findPackageInstancesInLock(lock, index, query, FilterConfig{})
+			}
+		}
+	})
+	b.Run("full-scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, query := range queries {
+				findPackageInstancesInLock(lock, nil, query, FilterConfig{})
+			}
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package scanner
+
+import (
+	"sort"
+	"strings"
+
+	"scnpm/pkg/license"
+	"scnpm/pkg/semver"
+	"scnpm/pkg/types"
+)
+
+// UnknownLicense is the license Licenses files packages under when their lockfile entry records
+// none
+const UnknownLicense = "UNKNOWN"
+
+// LicenseInventory groups the packages a lockfile installs by license
+type LicenseInventory struct {
+	Packages int            `json:"packages"` // Distinct name@version pairs
+	Unknown  int            `json:"unknown"`  // Packages without a license, or with UNKNOWN
+	Denied   int            `json:"denied"`   // Packages whose license can't be used without a denied one
+	Licenses []LicenseCount `json:"licenses"` // Most packages first, then by license
+}
+
+// LicenseCount is a license and the packages under it
+type LicenseCount struct {
+	License  string            `json:"license"` // SPDX expression as the lockfile records it, or UnknownLicense
+	Count    int               `json:"count"`
+	Unknown  bool              `json:"unknown,omitempty"`
+	Denied   bool              `json:"denied,omitempty"` // The expression violates the denied list (see license.Violates)
+	Packages []LicensedPackage `json:"packages"`         // By name, then version
+}
+
+// LicensedPackage is one name@version of a license inventory
+type LicensedPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Dev     bool   `json:"dev"` // Every copy is only needed in development
+}
+
+// Licenses groups installed packages, such as those List returns, by their license. Copies of a
+// name@version count once; links to workspaces and local directories are left out. Licenses that
+// violate denied, evaluated as SPDX expressions, are marked Denied.
+func Licenses(instances []types.PackageInstance, denied []string) LicenseInventory {
+	type key struct{ name, version string }
+	packages := make(map[key]*LicensedPackage)
+	licenses := make(map[string][]*LicensedPackage)
+	for _, instance := range instances {
+		if instance.InstallSource == types.SourceLink {
+			continue
+		}
+		k := key{instance.Name, instance.Version}
+		if pkg, ok := packages[k]; ok {
+			pkg.Dev = pkg.Dev && instance.IsDev
+			continue
+		}
+		id := strings.TrimSpace(instance.License)
+		if id == "" || strings.EqualFold(id, UnknownLicense) {
+			id = UnknownLicense
+		}
+		pkg := &LicensedPackage{Name: instance.Name, Version: instance.Version, Dev: instance.IsDev}
+		packages[k] = pkg
+		licenses[id] = append(licenses[id], pkg)
+	}
+
+	inventory := LicenseInventory{Packages: len(packages), Licenses: []LicenseCount{}}
+	for id, list := range licenses {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Name != list[j].Name {
+				return list[i].Name < list[j].Name
+			}
+			return compareVersions(list[i].Version, list[j].Version) < 0
+		})
+		count := LicenseCount{License: id, Count: len(list), Unknown: id == UnknownLicense, Packages: make([]LicensedPackage, 0, len(list))}
+		count.Denied = !count.Unknown && len(denied) > 0 && license.Violates(id, denied)
+		for _, pkg := range list {
+			count.Packages = append(count.Packages, *pkg)
+		}
+		if count.Unknown {
+			inventory.Unknown += count.Count
+		}
+		if count.Denied {
+			inventory.Denied += count.Count
+		}
+		inventory.Licenses = append(inventory.Licenses, count)
+	}
+	sort.Slice(inventory.Licenses, func(i, j int) bool {
+		a, b := inventory.Licenses[i], inventory.Licenses[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.License < b.License
+	})
+	return inventory
+}
+
+// compareVersions orders versions by semver precedence where both parse, and as strings otherwise
+func compareVersions(a, b string) int {
+	va, errA := semver.Parse(a)
+	vb, errB := semver.Parse(b)
+	if errA == nil && errB == nil {
+		return semver.Compare(va, vb)
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,39 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestLicenses(t *testing.T) {
+	instances := []types.PackageInstance{
+		{Name: "ms", Version: "2.1.3", License: "MIT", Path: "node_modules/ms"},
+		{Name: "debug", Version: "4.3.4", License: "MIT", Path: "node_modules/debug"},
+		{Name: "ms", Version: "2.1.3", License: "MIT", Path: "node_modules/a/node_modules/ms", IsDev: true},
+		{Name: "ms", Version: "2.0.0", License: "MIT", Path: "node_modules/b/node_modules/ms", IsDev: true},
+		{Name: "dual", Version: "1.0.0", License: "(MIT OR GPL-3.0-only)"},
+		{Name: "gpl", Version: "1.0.0", License: "GPL-3.0-or-later"},
+		{Name: "both", Version: "1.0.0", License: "MIT AND AGPL-3.0"},
+		{Name: "none", Version: "0.1.0", IsDev: true},
+		{Name: "unknown", Version: "0.1.0", License: "unknown"},
+		{Name: "web", Path: "node_modules/web", InstallSource: types.SourceLink},
+	}
+
+	got := Licenses(instances, []string{"GPL-3.0", "AGPL-3.0"})
+	want := LicenseInventory{Packages: 8, Unknown: 2, Denied: 2, Licenses: []LicenseCount{
+		{License: "MIT", Count: 3, Packages: []LicensedPackage{{Name: "debug", Version: "4.3.4"}, {Name: "ms", Version: "2.0.0", Dev: true}, {Name: "ms", Version: "2.1.3"}}},
+		{License: UnknownLicense, Count: 2, Unknown: true, Packages: []LicensedPackage{{Name: "none", Version: "0.1.0", Dev: true}, {Name: "unknown", Version: "0.1.0"}}},
+		{License: "(MIT OR GPL-3.0-only)", Count: 1, Packages: []LicensedPackage{{Name: "dual", Version: "1.0.0"}}},
+		{License: "GPL-3.0-or-later", Count: 1, Denied: true, Packages: []LicensedPackage{{Name: "gpl", Version: "1.0.0"}}},
+		{License: "MIT AND AGPL-3.0", Count: 1, Denied: true, Packages: []LicensedPackage{{Name: "both", Version: "1.0.0"}}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Licenses() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if got := Licenses(nil, nil); got.Packages != 0 || got.Licenses == nil {
+		t.Errorf("Licenses(nil) = %+v, want an empty list rather than null", got)
+	}
+}
@@ -0,0 +1,172 @@
+package scanner
+
+import (
+	"sort"
+
+	"scnpm/pkg/semver"
+	"scnpm/pkg/types"
+)
+
+// manifestDependencyTypes are the dependency maps of a package.json, in the order they're checked
+var manifestDependencyTypes = []string{"dependencies", "devDependencies", "optionalDependencies", "peerDependencies"}
+
+// declaredDependencies returns the dependency map of a manifest entry named by referenceType
+func declaredDependencies(pkg types.Package, referenceType string) map[string]string {
+	switch referenceType {
+	case "dependencies":
+		return pkg.Dependencies
+	case "devDependencies":
+		return pkg.DevDependencies
+	case "optionalDependencies":
+		return pkg.OptionalDependencies
+	default:
+		return pkg.PeerDependencies
+	}
+}
+
+// CheckLock compares the dependencies package.json files declare with what the lockfile installs.
+// manifests maps the lockfile key of each project to its package.json: "" for the root and the
+// directory of each workspace. It reports declared dependencies the lockfile doesn't install
+// (lock-missing), installed versions outside the declared range (lock-stale), and installed
+// packages no manifest declares and no other package depends on (lock-orphan), the roots of
+// whatever a stale lockfile still drags in. Results are one per check and package name, in name
+// order.
+func CheckLock(packageLock *types.PackageLock, manifests map[string]types.Package) []types.ScanResult {
+	entries := make(map[string]installedEntry)
+	installed := make(map[string]string)
+	var paths []string
+	for _, entry := range installedEntries(packageLock) {
+		entries[entry.Path] = entry
+		installed[entry.Path] = entry.Name
+		paths = append(paths, entry.Path)
+	}
+
+	dirs := make([]string, 0, len(manifests))
+	for dir := range manifests {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	found := make(map[string]map[string]*types.ScanResult)
+	report := func(check, name string, instance types.PackageInstance) {
+		if found[check] == nil {
+			found[check] = make(map[string]*types.ScanResult)
+		}
+		result, ok := found[check][name]
+		if !ok {
+			result = &types.ScanResult{Package: types.PackageQuery{Name: name}, Check: check, Found: true}
+			found[check][name] = result
+		}
+		result.Instances = append(result.Instances, instance)
+		result.TotalInstances++
+	}
+
+	declared := make(map[string]bool) // Install paths some manifest's dependency resolves to
+	for _, dir := range dirs {
+		manifest := manifestFile(dir)
+		for _, referenceType := range manifestDependencyTypes {
+			deps := declaredDependencies(manifests[dir], referenceType)
+			names := make([]string, 0, len(deps))
+			for name := range deps {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				spec := deps[name]
+				path := resolveDependency(installed, dir, name)
+				if path != "" {
+					declared[path] = true
+				}
+				if referenceType == "peerDependencies" {
+					// npm leaves optional peers out, and the host package provides the rest
+					continue
+				}
+				if path == "" {
+					report(types.CheckLockMissing, name, types.PackageInstance{
+						Name:          name,
+						Version:       spec,
+						Path:          manifest + " -> " + name,
+						IsReference:   true,
+						ReferenceType: referenceType,
+						IsDev:         referenceType == "devDependencies",
+						Depth:         1,
+					})
+					continue
+				}
+				entry := entries[path]
+				if !satisfiesDeclared(entry, spec) {
+					instance := newInstance(entry)
+					instance.ReferenceType = referenceType
+					instance.ReferencedBy = []string{manifest}
+					instance.Declared = spec
+					report(types.CheckLockStale, entry.Name, instance)
+				}
+			}
+		}
+	}
+
+	// A package is orphaned when nothing but the lockfile's stale record of a manifest depends on it
+	_, parents := dependencyGraph(packageLock)
+	for _, path := range paths {
+		entry := entries[path]
+		if declared[path] || entry.Link || entry.Bundled {
+			continue
+		}
+		orphan := true
+		for _, parent := range parents[path] {
+			if _, isManifest := manifests[parent]; !isManifest {
+				orphan = false
+				break
+			}
+		}
+		if orphan {
+			report(types.CheckLockOrphan, entry.Name, newInstance(entry))
+		}
+	}
+
+	var results []types.ScanResult
+	for _, check := range []string{types.CheckLockMissing, types.CheckLockStale, types.CheckLockOrphan} {
+		names := make([]string, 0, len(found[check]))
+		for name := range found[check] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			results = append(results, *found[check][name])
+		}
+	}
+	return results
+}
+
+// manifestFile returns the path of the package.json of the project at a lockfile key
+func manifestFile(dir string) string {
+	if dir == "" {
+		return "package.json"
+	}
+	return dir + "/package.json"
+}
+
+// satisfiesDeclared reports whether an installed entry still fulfils the spec a package.json
+// declares for it. Specs that aren't semver versions or ranges, such as dist-tags, git URLs and
+// file: or workspace: paths, can't be checked against the lockfile and are taken as satisfied,
+// as are links.
+func satisfiesDeclared(entry installedEntry, spec string) bool {
+	if realName, realSpec, ok := parseAlias(spec); ok {
+		if realName != entry.Name {
+			return false
+		}
+		spec = realSpec
+	}
+	if entry.Link {
+		return true
+	}
+	if semver.IsRange(spec) {
+		if _, err := semver.ParseRange(spec); err != nil {
+			return true
+		}
+	} else if _, err := semver.Parse(spec); err != nil {
+		return true
+	}
+	return MatchesVersion(entry.Version, spec)
+}
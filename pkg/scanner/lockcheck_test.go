@@ -0,0 +1,107 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestCheckLock(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			// The lockfile's record of package.json, from before it was edited
+			"":                          {Name: "app", Dependencies: map[string]string{"express": "^4.18.0", "lodash": "^4.17.20", "request": "^2.88.0"}},
+			"packages/web":              {Name: "web", Dependencies: map[string]string{"ms": "^2.0.0"}},
+			"node_modules/web":          {Resolved: "packages/web", Link: true},
+			"node_modules/express":      {Version: "4.18.2", Dependencies: map[string]string{"debug": "2.6.9"}},
+			"node_modules/debug":        {Version: "2.6.9"},
+			"node_modules/lodash":       {Version: "4.17.20"},
+			"node_modules/underscore":   {Version: "4.17.21", Name: "lodash"},
+			"node_modules/request":      {Version: "2.88.2", Dependencies: map[string]string{"tough-cookie": "^2.5.0"}},
+			"node_modules/tough-cookie": {Version: "2.5.0"},
+			"node_modules/ms":           {Version: "2.1.3"},
+			"node_modules/latest":       {Version: "0.0.1"},
+			"node_modules/jest":         {Version: "29.7.0", Dev: true},
+		},
+		Lines: map[string]int{"node_modules/lodash": 12},
+	}
+	manifests := map[string]types.Package{
+		"": {
+			Dependencies:     map[string]string{"express": "^4.18.0", "lodash": "^4.17.21", "left-pad": "^1.3.0", "latest": "latest", "underscore": "npm:lodash@^4.0.0"},
+			DevDependencies:  map[string]string{"jest": "^29.0.0"},
+			PeerDependencies: map[string]string{"react": "^18.0.0"},
+		},
+		"packages/web": {Dependencies: map[string]string{"ms": "^3.0.0", "express": "4.18.2"}},
+	}
+
+	results := CheckLock(packageLock, manifests)
+	type finding struct{ Check, Name, Version, Path, Declared string }
+	var got []finding
+	for _, result := range results {
+		if result.TotalInstances != len(result.Instances) || !result.Found {
+			t.Errorf("%s result for %s: TotalInstances = %d of %d instances, Found = %v", result.Check, result.Package.Name, result.TotalInstances, len(result.Instances), result.Found)
+		}
+		for _, instance := range result.Instances {
+			got = append(got, finding{result.Check, instance.Name, instance.Version, instance.Path, instance.Declared})
+		}
+	}
+	want := []finding{
+		// A new dependency never installed; the peer react is the host's business
+		{types.CheckLockMissing, "left-pad", "^1.3.0", "package.json -> left-pad", ""},
+		// Raised ranges the locked versions no longer satisfy, including one of a workspace
+		{types.CheckLockStale, "lodash", "4.17.20", "node_modules/lodash", "^4.17.21"},
+		{types.CheckLockStale, "ms", "2.1.3", "node_modules/ms", "^3.0.0"},
+		// Dropped from package.json, with what it pulls in; tough-cookie still has a dependent
+		{types.CheckLockOrphan, "request", "2.88.2", "node_modules/request", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckLock() =\n%+v\nwant\n%+v", got, want)
+	}
+	stale := results[1].Instances[0]
+	if stale.ReferenceType != "dependencies" || !reflect.DeepEqual(stale.ReferencedBy, []string{"package.json"}) || stale.LineNumber != 12 {
+		t.Errorf("stale lodash = %+v, want the declaring manifest and the lockfile line", stale)
+	}
+	missing := results[0].Instances[0]
+	if !missing.IsReference || missing.ReferenceType != "dependencies" {
+		t.Errorf("missing left-pad = %+v, want a reference from package.json", missing)
+	}
+
+	// A lockfile in step with its package.json has nothing to report
+	packageLock.Packages[""] = types.Package{Name: "app", Dependencies: map[string]string{"express": "^4.18.0"}}
+	delete(packageLock.Packages, "node_modules/request")
+	delete(packageLock.Packages, "node_modules/tough-cookie")
+	manifests = map[string]types.Package{
+		"":             {Dependencies: map[string]string{"express": "^4.18.0", "lodash": "4.17.20", "latest": "latest", "underscore": "npm:underscore@^1.13.0"}, DevDependencies: map[string]string{"jest": "*"}},
+		"packages/web": {Dependencies: map[string]string{"ms": "^2.1.0"}},
+	}
+	if results := CheckLock(packageLock, manifests); len(results) != 1 || results[0].Instances[0].Alias != "underscore" {
+		t.Errorf("CheckLock() = %+v, want only the alias to a package other than the installed one", results)
+	}
+}
+
+func TestSatisfiesDeclared(t *testing.T) {
+	tests := []struct {
+		entry installedEntry
+		spec  string
+		want  bool
+	}{
+		{installedEntry{Name: "debug", Version: "4.3.4"}, "^4.0.0", true},
+		{installedEntry{Name: "debug", Version: "4.3.4"}, "~4.2.0", false},
+		{installedEntry{Name: "debug", Version: "4.3.4"}, "4.3.4", true},
+		{installedEntry{Name: "debug", Version: "4.3.4"}, "4.3.3", false},
+		{installedEntry{Name: "debug", Version: "4.3.4"}, "latest", true},
+		{installedEntry{Name: "debug", Version: "4.3.4"}, "github:debug-js/debug", true},
+		{installedEntry{Name: "debug", Version: "4.3.4"}, "", true},
+		{installedEntry{Name: "lib", Link: true}, "^2.0.0", true},
+		{installedEntry{Name: "lodash", Version: "4.17.21"}, "npm:lodash@^4.17.0", true},
+		{installedEntry{Name: "lodash", Version: "4.17.21"}, "npm:lodash@^3.0.0", false},
+		{installedEntry{Name: "lodash", Version: "4.17.21"}, "npm:underscore@^4.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := satisfiesDeclared(tt.entry, tt.spec); got != tt.want {
+			t.Errorf("satisfiesDeclared(%+v, %q) = %v, want %v", tt.entry, tt.spec, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package scanner
+
+import (
+	"fmt"
+
+	"scnpm/pkg/semver"
+	"scnpm/pkg/types"
+)
+
+// Remediate suggests how to get rid of the findings of a bad-package query, given the versions
+// of every package installed in the lockfile: the command to investigate why it's installed, the
+// highest installed version the query doesn't match, an upgrade command where it's a direct
+// dependency and an npm overrides entry where it's a transitive one. Patterns have no single
+// package to fix and get nil.
+func Remediate(result types.ScanResult, installed map[string][]string) *types.Remediation {
+	if IsPattern(result.Package.Name) || len(result.Instances) == 0 {
+		return nil
+	}
+	name := result.Package.Name
+	for _, instance := range result.Instances {
+		if !instance.IsReference && instance.Name != "" {
+			// The real name, for scope-relaxed matches and aliases
+			name = instance.Name
+			break
+		}
+	}
+
+	remediation := &types.Remediation{Investigate: "npm ls " + name}
+	var safe semver.Version
+	for _, version := range installed[name] {
+		parsed, err := semver.Parse(version)
+		if err != nil || MatchesVersion(version, result.Package.Version) {
+			continue
+		}
+		if remediation.SafeVersion == "" || semver.Compare(parsed, safe) > 0 {
+			remediation.SafeVersion, safe = version, parsed
+		}
+	}
+
+	direct, transitive := false, false
+	newer := remediation.SafeVersion != ""
+	for _, instance := range result.Instances {
+		// A chain of one is the package itself, a dependency of the project
+		isDirect := false
+		for _, chain := range instance.Chains {
+			isDirect = isDirect || len(chain) == 1
+		}
+		direct = direct || isDirect
+		transitive = transitive || !isDirect
+		if bad, err := semver.Parse(instance.Version); err == nil && newer && semver.Compare(safe, bad) <= 0 {
+			newer = false
+		}
+	}
+
+	target := remediation.SafeVersion
+	if target == "" {
+		target = "latest"
+	}
+	if direct {
+		remediation.Upgrade = fmt.Sprintf("npm install %s@%s", name, target)
+	}
+	if transitive && remediation.SafeVersion != "" {
+		// ">=" would let an older safe version back up to the bad one
+		override := remediation.SafeVersion
+		if newer {
+			override = ">=" + override
+		}
+		remediation.Overrides = map[string]string{name: override}
+	}
+	return remediation
+}
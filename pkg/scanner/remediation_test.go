@@ -0,0 +1,57 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestRemediate(t *testing.T) {
+	direct := types.PackageInstance{Name: "evil", Version: "1.0.0", Path: "node_modules/evil", Chains: [][]string{{"evil@1.0.0"}}}
+	transitive := types.PackageInstance{Name: "evil", Version: "1.0.0", Path: "node_modules/a/node_modules/evil", Chains: [][]string{{"a", "evil@1.0.0"}}}
+	query := types.PackageQuery{Name: "evil", Version: "1.0.0"}
+
+	tests := []struct {
+		name      string
+		result    types.ScanResult
+		installed []string
+		want      *types.Remediation
+	}{
+		{
+			name:      "direct",
+			result:    types.ScanResult{Package: query, Instances: []types.PackageInstance{direct}},
+			installed: []string{"1.0.0", "1.2.0", "1.1.0"},
+			want:      &types.Remediation{Investigate: "npm ls evil", SafeVersion: "1.2.0", Upgrade: "npm install evil@1.2.0"},
+		},
+		{
+			name:      "transitive",
+			result:    types.ScanResult{Package: query, Instances: []types.PackageInstance{transitive}},
+			installed: []string{"1.0.0", "1.2.0"},
+			want:      &types.Remediation{Investigate: "npm ls evil", SafeVersion: "1.2.0", Overrides: map[string]string{"evil": ">=1.2.0"}},
+		},
+		{
+			name:      "older safe version is pinned exactly",
+			result:    types.ScanResult{Package: query, Instances: []types.PackageInstance{transitive}},
+			installed: []string{"0.9.0", "1.0.0"},
+			want:      &types.Remediation{Investigate: "npm ls evil", SafeVersion: "0.9.0", Overrides: map[string]string{"evil": "0.9.0"}},
+		},
+		{
+			name:      "no safe version",
+			result:    types.ScanResult{Package: query, Instances: []types.PackageInstance{direct, transitive}},
+			installed: []string{"1.0.0"},
+			want:      &types.Remediation{Investigate: "npm ls evil", Upgrade: "npm install evil@latest"},
+		},
+		{
+			name:   "pattern",
+			result: types.ScanResult{Package: types.PackageQuery{Name: "@evil/*"}, Instances: []types.PackageInstance{direct}},
+		},
+	}
+
+	for _, tt := range tests {
+		got := Remediate(tt.result, map[string][]string{"evil": tt.installed})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Remediate() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package scanner
 
 import (
+	"context"
+	"log/slog"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
 
+	"scnpm/pkg/semver"
 	"scnpm/pkg/types"
 )
 
@@ -11,23 +17,81 @@ type FilterConfig struct {
 	ShowDevOnly    bool
 	ShowNestedOnly bool
 	MinDepth       int
+	MaxDepth       *int // Deepest nesting depth to show, 0 for direct dependencies only; nil for no limit
+	Fuzzy          bool // Also match names that contain the query or are contained in it
+	MatchUnscoped  bool // Also match an unscoped query against scoped packages of the same name, and the reverse
+	IgnoreCase     bool // Compare names case-insensitively, for legacy lockfiles and lists with mixed-case names
+	PeerReferences bool // Also report peerDependencies references; the consumer, not the package, installs those
+	ShowReferences bool // Report references that resolve to an installed instance found as instances of their own
+	SuggestFixes   bool // Also suggest a Remediation for every query with findings
 }
 
+// Match reasons recorded on each instance, from strongest to weakest
+const (
+	MatchExact        = "exact"         // The names are identical
+	MatchGlob         = "glob"          // A glob query such as @ctrl/* or *-loader matched
+	MatchScopeRelaxed = "scope-relaxed" // An unscoped query matched a scoped package, or the reverse
+	MatchSubstring    = "substring"     // One name contains the other (--fuzzy only)
+	MatchRegex        = "regex"         // A "re:" query's regular expression matched
+)
+
+// RegexPrefix marks a query name as a regular expression over package names
+const RegexPrefix = "re:"
+
+// namePatterns caches compiled regular expressions by query name
+var namePatterns sync.Map
+
 // ScanPackages scans for packages in the package-lock.json
 func ScanPackages(packageLock *types.PackageLock, queries []types.PackageQuery, config FilterConfig) []types.ScanResult {
+	results, _ := ScanPackagesContext(context.Background(), packageLock, queries, config)
+	return results
+}
+
+// ScanPackagesContext is ScanPackages, stopping when ctx is done. It then returns the results of
+// the queries scanned so far, which come first in the same order, and ctx.Err().
+func ScanPackagesContext(ctx context.Context, packageLock *types.PackageLock, queries []types.PackageQuery, config FilterConfig) ([]types.ScanResult, error) {
 	results := make([]types.ScanResult, len(queries))
+	var chains *ChainIndex            // Built for the first finding
+	var installed map[string][]string // Built for the first remediation
+	var paths map[string]string       // Install paths, built for the first finding with references
+	var names *nameIndex              // Built for the first query matching names exactly
 
 	for i, query := range queries {
+		if err := ctx.Err(); err != nil {
+			return results[:i], err
+		}
 		result := types.ScanResult{
 			Package:   query,
 			Found:     false,
 			Instances: []types.PackageInstance{},
 		}
 
+		if query.Error != "" {
+			results[i] = result
+			continue
+		}
+
 		// Search through the parsed packageLock data instead of re-reading file
-		instances := findPackageInstancesInLock(packageLock, query.Name, query.Version)
+		var index *nameIndex
+		if indexable(query, config) {
+			if names == nil {
+				names = newNameIndex(packageLock)
+			}
+			index = names
+		}
+		instances := findPackageInstancesInLock(packageLock, index, query, config)
+		if !config.ShowReferences && hasReferences(instances) {
+			if paths == nil {
+				paths = installPaths(packageLock)
+			}
+			instances = consolidateReferences(instances, packageLock, paths)
+		}
 
 		for _, instance := range instances {
+			if chains == nil {
+				chains = NewChainIndex(packageLock)
+			}
+			instance.Chains = chains.InstanceChains(instance)
 			result.Instances = append(result.Instances, instance)
 		}
 
@@ -35,148 +99,714 @@ func ScanPackages(packageLock *types.PackageLock, queries []types.PackageQuery,
 		result.Instances = applyFilters(result.Instances, config)
 		result.TotalInstances = len(result.Instances)
 		result.Found = result.TotalInstances > 0
+		if result.Found && config.SuggestFixes {
+			if installed == nil {
+				installed = InstalledPackages(packageLock)
+			}
+			result.Remediation = Remediate(result, installed)
+		}
 
 		results[i] = result
 	}
 
+	return results, nil
+}
+
+// MergeResults appends the instances from another scan of the same queries into results
+func MergeResults(results, more []types.ScanResult) []types.ScanResult {
+	for i, result := range more {
+		results[i].Instances = append(results[i].Instances, result.Instances...)
+		if results[i].Remediation == nil {
+			results[i].Remediation = result.Remediation
+		}
+		results[i].TotalInstances = len(results[i].Instances)
+		results[i].Found = results[i].TotalInstances > 0
+	}
 	return results
 }
 
-// findPackageInstancesInLock searches for package instances in the parsed PackageLock data
-func findPackageInstancesInLock(packageLock *types.PackageLock, packageName, version string) []types.PackageInstance {
+// InstalledPackages returns every installed package name with its distinct versions, in the order
+// first seen. Aliased packages are listed under their real name; workspace and link entries
+// without a version are skipped.
+func InstalledPackages(packageLock *types.PackageLock) map[string][]string {
+	installed := make(map[string][]string)
+	add := func(name, version string) {
+		if name == "" || version == "" {
+			return
+		}
+		for _, v := range installed[name] {
+			if v == version {
+				return
+			}
+		}
+		installed[name] = append(installed[name], version)
+	}
+
+	if packageLock.LockfileVersion >= 2 {
+		for path, pkg := range packageLock.Packages {
+			add(entryName(path, pkg), pkg.Version)
+		}
+	} else {
+		var walk func(deps map[string]types.Dependency)
+		walk = func(deps map[string]types.Dependency) {
+			for name, dep := range deps {
+				if realName, realVersion, ok := parseAlias(dep.Version); ok {
+					add(realName, realVersion)
+				} else {
+					add(name, dep.Version)
+				}
+				walk(dep.Dependencies)
+			}
+		}
+		walk(packageLock.Dependencies)
+	}
+
+	return installed
+}
+
+// packageNameFromPath returns the package installed at a path like "node_modules/a/node_modules/@scope/b",
+// or "" when the path isn't inside node_modules
+func packageNameFromPath(path string) string {
+	i := strings.LastIndex(path, "node_modules/")
+	if i < 0 {
+		return ""
+	}
+	return path[i+len("node_modules/"):]
+}
+
+// findPackageInstancesInLock searches for package instances in the parsed PackageLock data.
+// Queries with an integrity hash only match installed entries carrying that hash; dependency
+// references can't prove which artifact they resolve to and are skipped for them. Installed
+// instances come before references, each in path and then version order, however the lockfile's
+// maps are iterated. With an index, only the entries it lists under the query's name are visited.
+func findPackageInstancesInLock(packageLock *types.PackageLock, index *nameIndex, query types.PackageQuery, config FilterConfig) []types.PackageInstance {
 	var instances []types.PackageInstance
+	key := strings.ToLower(query.Name)
 
 	// Handle different lockfile versions
 	if packageLock.LockfileVersion >= 2 {
 		// Search in packages field (lockfileVersion 2+)
-		for path, pkg := range packageLock.Packages {
-			if matchesPackageInPath(path, packageName) && (version == "" || pkg.Version == version) {
-				instance := types.PackageInstance{
-					Version:     pkg.Version,
-					Path:        path,
-					LineNumber:  0, // Not available from parsed data
-					IsReference: false,
-					IsDev:       pkg.Dev,
-					IsNested:    strings.Contains(path, "/node_modules/"),
-					Depth:       strings.Count(path, "/node_modules/"),
+		if index != nil {
+			for _, path := range index.packages[key] {
+				if instance, ok := matchPackage(path, packageLock.Packages[path], query, packageLock.Lines, config); ok {
+					instances = append(instances, instance)
+				}
+			}
+		} else {
+			for path, pkg := range packageLock.Packages {
+				if instance, ok := matchPackage(path, pkg, query, packageLock.Lines, config); ok {
+					instances = append(instances, instance)
 				}
-				instances = append(instances, instance)
 			}
 		}
 
 		// Also check dependencies references in packages
-		for path, pkg := range packageLock.Packages {
-			for depName, depVersion := range pkg.Dependencies {
-				if MatchesPackageName(depName, packageName) && (version == "" || strings.Contains(depVersion, version)) {
-					instance := types.PackageInstance{
-						Version:       depVersion,
-						Path:          path + " -> " + depName,
-						LineNumber:    0,
-						IsReference:   true,
-						ReferenceType: "dependencies",
-						IsDev:         pkg.Dev,
-						IsNested:      strings.Contains(path, "/node_modules/"),
-						Depth:         strings.Count(path, "/node_modules/") + 1,
-					}
-					instances = append(instances, instance)
+		if query.Integrity == "" {
+			if index != nil {
+				for _, path := range index.referrers[key] {
+					instances = append(instances, packageReferences(path, packageLock.Packages[path], query, packageLock.Lines, config)...)
+				}
+			} else {
+				for path, pkg := range packageLock.Packages {
+					instances = append(instances, packageReferences(path, pkg, query, packageLock.Lines, config)...)
 				}
 			}
 		}
+	} else if index != nil {
+		for _, entry := range index.dependencies[key] {
+			if instance, ok := matchDependency(entry.path, entry.name, entry.dep, query, packageLock.Lines, config); ok {
+				instances = append(instances, instance)
+			}
+		}
 	} else {
 		// Search in dependencies field (lockfileVersion 1)
-		instances = append(instances, searchDependenciesRecursive(packageLock.Dependencies, packageName, version, "")...)
+		instances = append(instances, searchDependenciesRecursive(packageLock.Dependencies, query, "", packageLock.Lines, config)...)
 	}
 
+	sort.Slice(instances, func(i, j int) bool {
+		a, b := instances[i], instances[j]
+		if a.IsReference != b.IsReference {
+			return !a.IsReference
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Version < b.Version
+	})
 	return instances
 }
 
-// matchesPackageInPath checks if a path contains the specified package name
-func matchesPackageInPath(path, packageName string) bool {
-	// Extract package name from path like "node_modules/package-name" or "node_modules/@scope/package-name"
-	parts := strings.Split(path, "/")
-	for i, part := range parts {
-		if part == "node_modules" && i+1 < len(parts) {
-			// Handle scoped packages
-			if strings.HasPrefix(parts[i+1], "@") && i+2 < len(parts) {
-				fullName := parts[i+1] + "/" + parts[i+2]
-				if MatchesPackageName(fullName, packageName) {
-					return true
-				}
-			} else {
-				if MatchesPackageName(parts[i+1], packageName) {
-					return true
-				}
-			}
+// matchPackage checks whether the packages entry at path is an installed instance of the queried
+// package, name, version and integrity alike
+func matchPackage(path string, pkg types.Package, query types.PackageQuery, lines map[string]int, config FilterConfig) (types.PackageInstance, bool) {
+	reason := matchEntry(path, pkg, query.Name, config)
+	if reason == "" || !acceptCandidate(path, pkg.Version, pkg.Integrity, query, reason) {
+		return types.PackageInstance{}, false
+	}
+	instance := newInstance(packagesEntry(path, pkg, lines))
+	instance.MatchReason = reason
+	instance.MatchedVersion = matchedAlternative(pkg.Version, query.Version, MatchesVersion)
+	if query.Integrity != "" {
+		instance.Integrity = pkg.Integrity
+		instance.IntegrityMatch = true
+	}
+	instance.ExactFallback = versionFallback(pkg.Version, query.Version)
+	if MutableSource(instance.InstallSource) {
+		instance.Resolved = pkg.Resolved
+		instance.PinnedCommit = PinnedCommit(pkg.Resolved)
+	}
+	return instance, true
+}
+
+// packageReferences searches the dependency maps of the packages entry at path for references to
+// the queried package; peerDependencies only with config.PeerReferences
+func packageReferences(path string, pkg types.Package, query types.PackageQuery, lines map[string]int, config FilterConfig) []types.PackageInstance {
+	packageName, version := query.Name, query.Version
+	instances := findReferences(path, pkg.Dependencies, "dependencies", pkg.Dev, packageName, version, lines, config)
+	instances = append(instances, findReferences(path, pkg.OptionalDependencies, "optionalDependencies", pkg.Dev, packageName, version, lines, config)...)
+	instances = append(instances, findReferences(path, pkg.DevDependencies, "devDependencies", true, packageName, version, lines, config)...)
+	if config.PeerReferences {
+		instances = append(instances, findReferences(path, pkg.PeerDependencies, "peerDependencies", pkg.Dev, packageName, version, lines, config)...)
+	}
+	return instances
+}
+
+// rootReferrer stands for the root project in ReferencedBy
+const rootReferrer = "(root)"
+
+// hasReferences reports whether any of the instances is a dependency reference
+func hasReferences(instances []types.PackageInstance) bool {
+	for _, instance := range instances {
+		if instance.IsReference {
+			return true
 		}
 	}
 	return false
 }
 
-// searchDependenciesRecursive searches through the dependencies tree recursively (lockfileVersion 1)
-func searchDependenciesRecursive(deps map[string]types.Dependency, packageName, version, basePath string) []types.PackageInstance {
-	var instances []types.PackageInstance
+// installPaths returns the install paths of a lockfile's packages, for resolveEntry
+func installPaths(packageLock *types.PackageLock) map[string]string {
+	paths := make(map[string]string, len(packageLock.Packages))
+	for path, pkg := range packageLock.Packages {
+		paths[path] = pkg.Name
+	}
+	return paths
+}
 
-	for depName, dep := range deps {
-		currentPath := basePath
-		if currentPath == "" {
-			currentPath = "node_modules/" + depName
-		} else {
-			currentPath = currentPath + "/node_modules/" + depName
+// consolidateReferences folds every dependency reference that resolves to one of the installed
+// instances found, and admits its version, into that instance's ReferencedBy, so that a bad version installed once and
+// declared by three packages is one finding rather than four. It returns the installed instances
+// and the references that resolve elsewhere, to a version that wasn't found or to nothing.
+// paths holds every install path of packageLock.
+func consolidateReferences(instances []types.PackageInstance, packageLock *types.PackageLock, paths map[string]string) []types.PackageInstance {
+	found := make(map[string]int)
+	for i, instance := range instances {
+		if !instance.IsReference {
+			found[instance.Path] = i
+		}
+	}
+
+	shadowed := make([]bool, len(instances))
+	for i, instance := range instances {
+		if !instance.IsReference {
+			continue
+		}
+		from, name, _ := strings.Cut(instance.Path, " -> ")
+		target, ok := found[resolveEntry(packageLock, paths, from, name)]
+		if !ok {
+			continue
+		}
+		if admits, _ := MatchesReference(instance.Version, instances[target].Version); !admits {
+			// The declared range can't have resolved to that version; the lockfile is out of
+			// sync, which is worth seeing
+			continue
 		}
+		shadowed[i] = true
+		referrer := from
+		if referrer == "" {
+			referrer = rootReferrer
+		}
+		referencedBy := instances[target].ReferencedBy
+		if len(referencedBy) == 0 || referencedBy[len(referencedBy)-1] != referrer {
+			// References come in path order, so one package declaring it twice (dependencies
+			// and devDependencies) is adjacent
+			instances[target].ReferencedBy = append(referencedBy, referrer)
+		}
+	}
+
+	var kept []types.PackageInstance
+	for i, instance := range instances {
+		if !shadowed[i] {
+			kept = append(kept, instance)
+		}
+	}
+	return kept
+}
 
-		// Check if this dependency matches
-		if MatchesPackageName(depName, packageName) && (version == "" || dep.Version == version) {
+// findReferences searches one dependency map of the package at path for references to the queried package
+func findReferences(path string, deps map[string]string, referenceType string, isDev bool, packageName, version string, lines map[string]int, config FilterConfig) []types.PackageInstance {
+	var instances []types.PackageInstance
+
+	for depName, depVersion := range deps {
+		// "my-lodash": "npm:lodash@4.17.15" references lodash under another name
+		name, spec, alias := depName, depVersion, ""
+		if realName, realSpec, ok := parseAlias(depVersion); ok {
+			name, spec, alias = realName, realSpec, depName
+		}
+		reason := MatchPackageName(name, packageName, config)
+		if reason == "" && alias != "" {
+			reason = MatchPackageName(alias, packageName, config)
+		}
+		if reason == "" {
+			continue
+		}
+		matched, fallback := MatchesReference(spec, version)
+		slog.Debug("reference considered", "path", path+" -> "+depName, "query", packageName, "spec", spec, "wanted", version, "accepted", matched)
+		if matched {
 			instance := types.PackageInstance{
-				Version:     dep.Version,
-				Path:        currentPath,
-				LineNumber:  0,
-				IsReference: false,
-				IsDev:       dep.Dev,
-				IsNested:    strings.Contains(currentPath, "/node_modules/"),
-				Depth:       strings.Count(currentPath, "/node_modules/"),
+				Name:        name,
+				Alias:       alias,
+				MatchReason: reason,
+				MatchedVersion: matchedAlternative(spec, version, func(spec, alt string) bool {
+					matched, _ := MatchesReference(spec, alt)
+					return matched
+				}),
+				Version:       spec,
+				Path:          path + " -> " + depName,
+				LineNumber:    lines[path],
+				IsReference:   true,
+				ReferenceType: referenceType,
+				IsDev:         isDev,
+				IsNested:      strings.Contains(path, "/node_modules/"),
+				Depth:         strings.Count(path, "/node_modules/") + 1,
+				ExactFallback: fallback,
+			}
+			instances = append(instances, instance)
+		}
+	}
+
+	return instances
+}
+
+// acceptCandidate reports whether an installed entry whose name matched the query also matches its
+// version and integrity, and logs the decision at debug level
+func acceptCandidate(path, installedVersion, integrity string, query types.PackageQuery, reason string) bool {
+	rejected := ""
+	if !MatchesVersion(installedVersion, query.Version) {
+		rejected = "version doesn't match"
+	} else if !MatchesIntegrity(integrity, query.Integrity) {
+		rejected = "integrity doesn't match"
+	}
+	if rejected != "" {
+		slog.Debug("candidate rejected", "path", path, "query", query.Name, "version", installedVersion, "wanted", query.Version, "reason", rejected)
+		return false
+	}
+	slog.Debug("candidate accepted", "path", path, "query", query.Name, "version", installedVersion, "wanted", query.Version, "reason", reason)
+	return true
+}
+
+// matchedAlternative returns which of the "||"-separated alternatives of a queried version
+// (e.g. "1.0.0||1.0.1||1.0.2") matched, or "" when the query has no alternatives
+func matchedAlternative(installed, version string, matches func(installed, version string) bool) string {
+	if !strings.Contains(version, "||") {
+		return ""
+	}
+	for _, alternative := range strings.Split(version, "||") {
+		alternative = strings.TrimSpace(alternative)
+		if matches(installed, alternative) {
+			return alternative
+		}
+	}
+	return ""
+}
+
+// MatchesReference checks whether a dependency declaration like "^4.17.0" can resolve to the
+// queried version: an exact bad version must satisfy the declared range, a bad range must
+// intersect it. Declarations that aren't semver ranges (git URLs, tags, file: paths) fall back
+// to exact string comparison, which is reported as fallback.
+func MatchesReference(spec, version string) (matched, fallback bool) {
+	if version == "" {
+		return true, false
+	}
+
+	declared, err := semver.ParseRange(spec)
+	if err != nil {
+		return spec == version, true
+	}
+
+	if semver.IsRange(version) {
+		bad, err := semver.ParseRange(version)
+		if err != nil {
+			return spec == version, true
+		}
+		return declared.Intersects(bad), false
+	}
+
+	v, err := semver.Parse(version)
+	if err != nil {
+		return spec == version, true
+	}
+	return declared.Satisfies(v), false
+}
+
+// versionFallback reports whether an installed version that matched a queried one was compared
+// as a plain string, because it isn't semver (git SHAs, file: specs)
+func versionFallback(installed, version string) bool {
+	if version == "" {
+		return false
+	}
+	_, err := semver.Parse(installed)
+	return err != nil
+}
+
+// MatchesVersion checks an installed version against the queried version, which is either an
+// exact version or an npm range such as ">=1.0.0 <1.2.3". Ranges follow npm semantics, so
+// prereleases only match ranges that name a prerelease of the same version.
+func MatchesVersion(installed, version string) bool {
+	if version == "" || installed == version {
+		return true
+	}
+
+	v, err := semver.Parse(installed)
+	if err != nil {
+		return false
+	}
+
+	if !semver.IsRange(version) {
+		// Exact versions compare by precedence, so "1.0.0" matches "v1.0.0" and "1.0.0+build.1"
+		want, err := semver.Parse(version)
+		return err == nil && semver.Equal(v, want)
+	}
+
+	r, err := semver.ParseRange(version)
+	if err != nil {
+		return false
+	}
+	return r.Satisfies(v)
+}
+
+// entryName returns the package name of a packages entry; for npm: aliases that's the real
+// package rather than the name it was installed under
+func entryName(path string, pkg types.Package) string {
+	if pkg.Name != "" && (path == pkg.Name+"@"+pkg.Version || installedAlias(path, pkg) != "") {
+		return pkg.Name
+	}
+	return packageNameFromPath(path)
+}
+
+// installedAlias returns the name an aliased entry was installed under (node_modules/my-lodash
+// for "my-lodash": "npm:lodash@4.17.15"), or "" when the entry is installed under its own name.
+// lockfileVersion 2+ records the real name of such entries in their "name" field.
+func installedAlias(path string, pkg types.Package) string {
+	installedAs := packageNameFromPath(path)
+	if pkg.Name == "" || installedAs == "" || installedAs == pkg.Name {
+		return ""
+	}
+	return installedAs
+}
+
+// parseAlias splits an npm alias spec like "npm:lodash@4.17.15" or "npm:@scope/pkg@^1.0.0" into
+// the real package name and its version or range ("*" when the alias names no version)
+func parseAlias(spec string) (name, version string, ok bool) {
+	rest, found := strings.CutPrefix(spec, "npm:")
+	if !found || rest == "" {
+		return "", "", false
+	}
+	at := strings.LastIndex(rest, "@")
+	if at <= 0 {
+		return rest, "*", true
+	}
+	return rest[:at], rest[at+1:], true
+}
+
+// MatchesIntegrity checks an installed entry's SRI integrity string against the queried one.
+// Both may list several space-separated hashes (e.g. "sha512-... sha1-..."); any shared hash
+// matches. An empty query matches everything.
+func MatchesIntegrity(installed, integrity string) bool {
+	if integrity == "" {
+		return true
+	}
+	for _, want := range strings.Fields(integrity) {
+		for _, have := range strings.Fields(installed) {
+			if have == want {
+				return true
 			}
+		}
+	}
+	return false
+}
+
+// matchEntry checks whether a packages entry is an installed instance of the queried package and
+// returns the match reason, or "" when it isn't. Entries are normally keyed by install path;
+// lockfiles that don't record install locations (yarn.lock) key them by "name@version" and carry
+// the name explicitly.
+func matchEntry(path string, pkg types.Package, packageName string, config FilterConfig) string {
+	if pkg.Name != "" && path == pkg.Name+"@"+pkg.Version {
+		return MatchPackageName(pkg.Name, packageName, config)
+	}
+	// Aliases hide the real package under another install path, so match it by its real name too
+	if installedAlias(path, pkg) != "" {
+		if reason := MatchPackageName(pkg.Name, packageName, config); reason != "" {
+			return reason
+		}
+	}
+	return matchPackageInPath(path, packageName, config)
+}
+
+// matchPackageInPath checks if the package installed at a path like "node_modules/package-name"
+// or "node_modules/a/node_modules/@scope/package-name" is the specified package. Only the innermost
+// package counts; its parents are separate entries.
+func matchPackageInPath(path, packageName string, config FilterConfig) string {
+	name := packageNameFromPath(path)
+	if name == "" {
+		return ""
+	}
+	return MatchPackageName(name, packageName, config)
+}
+
+// searchDependenciesRecursive searches through the dependencies tree recursively (lockfileVersion 1)
+func searchDependenciesRecursive(deps map[string]types.Dependency, query types.PackageQuery, basePath string, lines map[string]int, config FilterConfig) []types.PackageInstance {
+	var instances []types.PackageInstance
+
+	for depName, dep := range deps {
+		currentPath := dependencyPath(basePath, depName)
+		if instance, ok := matchDependency(currentPath, depName, dep, query, lines, config); ok {
 			instances = append(instances, instance)
 		}
 
 		// Recursively search nested dependencies
 		if dep.Dependencies != nil {
-			instances = append(instances, searchDependenciesRecursive(dep.Dependencies, packageName, version, currentPath)...)
+			instances = append(instances, searchDependenciesRecursive(dep.Dependencies, query, currentPath, lines, config)...)
 		}
 	}
 
 	return instances
 }
 
-// MatchesPackageName checks if a package name matches the query with sophisticated matching logic
-func MatchesPackageName(packageName, queryName string) bool {
+// dependencyPath returns the install path of the dependency depName of the package at basePath,
+// "" standing for the root project
+func dependencyPath(basePath, depName string) string {
+	if basePath == "" {
+		return "node_modules/" + depName
+	}
+	return basePath + "/node_modules/" + depName
+}
+
+// matchDependency checks whether the dependencies tree entry depName installed at path is an
+// instance of the queried package (lockfileVersion 1)
+func matchDependency(path, depName string, dep types.Dependency, query types.PackageQuery, lines map[string]int, config FilterConfig) (types.PackageInstance, bool) {
+	// Aliases record "npm:lodash@4.17.15" as their version
+	name, installedVersion, alias := depName, dep.Version, ""
+	if realName, realVersion, ok := parseAlias(dep.Version); ok {
+		name, installedVersion, alias = realName, realVersion, depName
+	}
+	reason := MatchPackageName(name, query.Name, config)
+	if reason == "" && alias != "" {
+		reason = MatchPackageName(alias, query.Name, config)
+	}
+	if reason == "" || !acceptCandidate(path, installedVersion, dep.Integrity, query, reason) {
+		return types.PackageInstance{}, false
+	}
+	instance := newInstance(dependenciesEntry(path, depName, dep, lines))
+	instance.MatchReason = reason
+	instance.MatchedVersion = matchedAlternative(installedVersion, query.Version, MatchesVersion)
+	if query.Integrity != "" {
+		instance.Integrity = dep.Integrity
+		instance.IntegrityMatch = true
+	}
+	instance.ExactFallback = versionFallback(installedVersion, query.Version)
+	if MutableSource(instance.InstallSource) {
+		instance.Resolved = v1Resolved(dep)
+		instance.PinnedCommit = PinnedCommit(instance.Resolved)
+	}
+	return instance, true
+}
+
+// v1Resolved returns where a lockfileVersion 1 dependency was installed from; file: and git
+// dependencies record that in their version field rather than in resolved
+func v1Resolved(dep types.Dependency) string {
+	if dep.Resolved == "" && strings.Contains(dep.Version, ":") && !strings.HasPrefix(dep.Version, "npm:") {
+		return dep.Version
+	}
+	return dep.Resolved
+}
+
+// MatchPackageName checks if a package name matches the query and returns the match reason, or ""
+// when it doesn't. Names match exactly by default; substring matching needs config.Fuzzy.
+func MatchPackageName(packageName, queryName string, config FilterConfig) string {
+	if config.IgnoreCase {
+		packageName = strings.ToLower(packageName)
+		if strings.HasPrefix(queryName, RegexPrefix) {
+			// Lowercasing an expression could change its escapes (\D vs \d); use a flag instead
+			queryName = RegexPrefix + "(?i)" + strings.TrimPrefix(queryName, RegexPrefix)
+		} else {
+			queryName = strings.ToLower(queryName)
+		}
+	}
+
 	// Exact match
 	if packageName == queryName {
-		return true
+		return MatchExact
 	}
 
-	// Handle scoped packages - allow matching with or without @ prefix
-	if strings.HasPrefix(packageName, "@") && !strings.HasPrefix(queryName, "@") {
-		// Package is scoped, query is not - check if query matches the package part
-		parts := strings.Split(packageName, "/")
-		if len(parts) == 2 && parts[1] == queryName {
-			return true
+	if strings.HasPrefix(queryName, RegexPrefix) {
+		if re, err := CompileNamePattern(queryName); err == nil && re.MatchString(packageName) {
+			return MatchRegex
 		}
+		return ""
 	}
 
-	if !strings.HasPrefix(packageName, "@") && strings.HasPrefix(queryName, "@") {
-		// Query is scoped, package is not - check if package matches the scoped part
-		parts := strings.Split(queryName, "/")
-		if len(parts) == 2 && parts[1] == packageName {
-			return true
+	// Globs like @ctrl/* or *-loader match by pattern, and nothing else
+	if IsGlob(queryName) {
+		if re, err := compileGlob(queryName); err == nil && re.MatchString(packageName) {
+			return MatchGlob
 		}
+		return ""
+	}
+
+	// Scope relaxation is opt-in: "node" and "@types/node" are different packages from different
+	// publishers, so matching them across scopes is only a hint
+	if config.MatchUnscoped && strings.HasPrefix(packageName, "@") != strings.HasPrefix(queryName, "@") &&
+		unscopedName(packageName) == unscopedName(queryName) {
+		return MatchScopeRelaxed
 	}
 
 	// Partial matching for cases where package names might have variations
-	if strings.Contains(packageName, queryName) || strings.Contains(queryName, packageName) {
-		return true
+	if config.Fuzzy && (strings.Contains(packageName, queryName) || strings.Contains(queryName, packageName)) {
+		return MatchSubstring
 	}
 
-	return false
+	logNearMiss(packageName, queryName, config)
+	return ""
+}
+
+// logNearMiss explains at debug level why a name that a looser setting would have matched
+// didn't match the query
+func logNearMiss(packageName, queryName string, config FilterConfig) {
+	if !slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	var reason string
+	switch {
+	case strings.EqualFold(packageName, queryName):
+		reason = "differs only in case (--ignore-case)"
+	case unscopedName(packageName) == unscopedName(queryName):
+		reason = "differs only in scope (--match-unscoped)"
+	case strings.Contains(packageName, queryName) || strings.Contains(queryName, packageName):
+		reason = "contains the query or is contained in it (--fuzzy)"
+	default:
+		return
+	}
+	slog.Debug("name rejected", "package", packageName, "query", queryName, "reason", reason)
+}
+
+// CompileNamePattern compiles a "re:" query name. The expression must match the whole package
+// name, as if wrapped in ^(...)$; use ".*" on either side to search within names.
+func CompileNamePattern(queryName string) (*regexp.Regexp, error) {
+	if re, ok := namePatterns.Load(queryName); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	pattern := strings.TrimPrefix(queryName, RegexPrefix)
+	// Validate the expression on its own so errors quote what the user wrote
+	if _, err := regexp.Compile(pattern); err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, err
+	}
+	namePatterns.Store(queryName, re)
+	return re, nil
+}
+
+// unscopedName strips the scope from a name like @types/node
+func unscopedName(name string) string {
+	if !strings.HasPrefix(name, "@") {
+		return name
+	}
+	parts := strings.Split(name, "/")
+	if len(parts) != 2 {
+		return name
+	}
+	return parts[1]
+}
+
+// IsGlob reports whether a query name is a glob pattern like @babel/plugin-* or *-loader.
+// "*" matches within the scope or the name, "**" also across the "/" between them.
+func IsGlob(name string) bool {
+	return !strings.HasPrefix(name, RegexPrefix) && strings.Contains(name, "*")
+}
+
+// IsPattern reports whether a query name is a glob or regular expression that can match
+// several packages
+func IsPattern(name string) bool {
+	return IsGlob(name) || strings.HasPrefix(name, RegexPrefix)
+}
+
+// compileGlob translates a glob query into an anchored regular expression
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	key := "glob:" + glob
+	if re, ok := namePatterns.Load(key); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			pattern.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			pattern.WriteString("[^/]*")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	pattern.WriteString("$")
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, err
+	}
+	namePatterns.Store(key, re)
+	return re, nil
+}
+
+// ExpandPatterns splits the result of each glob or regex query into one result per concrete
+// package it matched, in name order, each recording the query in Pattern. Patterns that matched
+// nothing keep their single not-found result. Call it once all scans of a query set are merged.
+func ExpandPatterns(results []types.ScanResult) []types.ScanResult {
+	var expanded []types.ScanResult
+	for _, result := range results {
+		if !IsPattern(result.Package.Name) || !result.Found {
+			expanded = append(expanded, result)
+			continue
+		}
+
+		byName := make(map[string][]types.PackageInstance)
+		var names []string
+		for _, instance := range result.Instances {
+			if _, ok := byName[instance.Name]; !ok {
+				names = append(names, instance.Name)
+			}
+			byName[instance.Name] = append(byName[instance.Name], instance)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			query := result.Package
+			query.Pattern = query.Name
+			query.Name = name
+			expanded = append(expanded, types.ScanResult{
+				Package:        query,
+				Found:          true,
+				Instances:      byName[name],
+				TotalInstances: len(byName[name]),
+			})
+		}
+	}
+	return expanded
 }
 
 // applyFilters applies command-line filters to the found instances
@@ -199,8 +829,13 @@ func applyFilters(instances []types.PackageInstance, config FilterConfig) []type
 			continue
 		}
 
+		// Apply maximum depth filter
+		if config.MaxDepth != nil && instance.Depth > *config.MaxDepth {
+			continue
+		}
+
 		filtered = append(filtered, instance)
 	}
 
 	return filtered
-}
\ No newline at end of file
+}
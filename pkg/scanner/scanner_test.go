@@ -1,71 +1,214 @@
 package scanner
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"scnpm/pkg/types"
 )
 
-func TestMatchesPackageName(t *testing.T) {
+func TestMatchPackageName(t *testing.T) {
 	tests := []struct {
 		name        string
 		packageName string
 		queryName   string
-		want        bool
+		fuzzy       bool
+		unscoped    bool
+		ignoreCase  bool
+		want        string
 	}{
 		{
 			name:        "exact match",
 			packageName: "react",
 			queryName:   "react",
-			want:        true,
+			want:        MatchExact,
 		},
 		{
 			name:        "scoped package exact match",
 			packageName: "@types/node",
 			queryName:   "@types/node",
-			want:        true,
+			want:        MatchExact,
 		},
 		{
 			name:        "scoped package without @ prefix",
 			packageName: "@types/node",
 			queryName:   "node",
-			want:        true,
+			want:        "",
+		},
+		{
+			name:        "scoped package without @ prefix, --match-unscoped",
+			packageName: "@ctrl/tinycolor",
+			queryName:   "tinycolor",
+			unscoped:    true,
+			want:        MatchScopeRelaxed,
 		},
 		{
 			name:        "query scoped, package not",
 			packageName: "node",
 			queryName:   "@types/node",
-			want:        true,
+			want:        "",
 		},
 		{
-			name:        "partial match - contains",
+			name:        "query scoped, package not, --match-unscoped",
+			packageName: "node",
+			queryName:   "@types/node",
+			unscoped:    true,
+			want:        MatchScopeRelaxed,
+		},
+		{
+			name:        "different scopes, --match-unscoped",
+			packageName: "@a/node",
+			queryName:   "@b/node",
+			unscoped:    true,
+			want:        "",
+		},
+		{
+			name:        "partial match - contains, exact mode",
 			packageName: "react-dom",
 			queryName:   "react",
-			want:        true,
+			want:        "",
+		},
+		{
+			name:        "partial match - contains, fuzzy mode",
+			packageName: "react-dom",
+			queryName:   "react",
+			fuzzy:       true,
+			want:        MatchSubstring,
+		},
+		{
+			name:        "partial match - contained, fuzzy mode",
+			packageName: "preact",
+			queryName:   "preact-react-adapter",
+			fuzzy:       true,
+			want:        MatchSubstring,
 		},
 		{
 			name:        "no match",
 			packageName: "vue",
 			queryName:   "react",
-			want:        false,
+			fuzzy:       true,
+			want:        "",
+		},
+		{
+			name:        "regex",
+			packageName: "@acme-internal/api",
+			queryName:   "re:@(acme|internal)-.*",
+			want:        MatchRegex,
+		},
+		{
+			name:        "regex is anchored to the whole name",
+			packageName: "not-@acme-internal/api",
+			queryName:   "re:@(acme|internal)-.*",
+			want:        "",
+		},
+		{
+			name:        "regex searching with .*",
+			packageName: "babel-loader",
+			queryName:   "re:.*loader.*",
+			want:        MatchRegex,
+		},
+		{
+			name:        "glob within the name",
+			packageName: "babel-loader",
+			queryName:   "*-loader",
+			want:        MatchGlob,
+		},
+		{
+			name:        "glob star doesn't cross the scope",
+			packageName: "@webpack/css-loader",
+			queryName:   "*-loader",
+			want:        "",
+		},
+		{
+			name:        "glob double star crosses the scope",
+			packageName: "@webpack/css-loader",
+			queryName:   "**-loader",
+			want:        MatchGlob,
+		},
+		{
+			name:        "scope wildcard",
+			packageName: "@ctrl/tinycolor",
+			queryName:   "@ctrl/*",
+			want:        MatchGlob,
+		},
+		{
+			name:        "scope wildcard, other scope",
+			packageName: "@ctrlx/tinycolor",
+			queryName:   "@ctrl/*",
+			want:        "",
+		},
+		{
+			name:        "scope wildcard, unscoped package",
+			packageName: "ctrl",
+			queryName:   "@ctrl/*",
+			fuzzy:       true,
+			want:        "",
+		},
+		{
+			name:        "mixed case is a different name by default",
+			packageName: "JSONStream",
+			queryName:   "jsonstream",
+			want:        "",
+		},
+		{
+			name:        "mixed case, --ignore-case",
+			packageName: "JSONStream",
+			queryName:   "jsonstream",
+			ignoreCase:  true,
+			want:        MatchExact,
+		},
+		{
+			name:        "mixed case query, --ignore-case",
+			packageName: "jsonstream",
+			queryName:   "JSONStream",
+			ignoreCase:  true,
+			want:        MatchExact,
+		},
+		{
+			name:        "scoped mixed case, --ignore-case",
+			packageName: "@Acme/Utils",
+			queryName:   "@acme/utils",
+			ignoreCase:  true,
+			want:        MatchExact,
+		},
+		{
+			name:        "glob, --ignore-case",
+			packageName: "@Acme/Utils",
+			queryName:   "@acme/*",
+			ignoreCase:  true,
+			want:        MatchGlob,
+		},
+		{
+			name:        "regex, --ignore-case",
+			packageName: "JSONStream",
+			queryName:   "re:json\\w+",
+			ignoreCase:  true,
+			want:        MatchRegex,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := MatchesPackageName(tt.packageName, tt.queryName); got != tt.want {
-				t.Errorf("matchesPackageName(%q, %q) = %v, want %v", tt.packageName, tt.queryName, got, tt.want)
+			config := FilterConfig{Fuzzy: tt.fuzzy, MatchUnscoped: tt.unscoped, IgnoreCase: tt.ignoreCase}
+			if got := MatchPackageName(tt.packageName, tt.queryName, config); got != tt.want {
+				t.Errorf("MatchPackageName(%q, %q) = %q, want %q", tt.packageName, tt.queryName, got, tt.want)
 			}
 		})
 	}
 }
 
-func TestMatchesPackageInPath(t *testing.T) {
+func TestMatchPackageInPath(t *testing.T) {
 	tests := []struct {
 		name        string
 		path        string
 		packageName string
+		ignoreCase  bool
 		want        bool
 	}{
 		{
@@ -86,6 +229,12 @@ func TestMatchesPackageInPath(t *testing.T) {
 			packageName: "debug",
 			want:        true,
 		},
+		{
+			name:        "parent of nested package",
+			path:        "node_modules/express/node_modules/debug",
+			packageName: "express",
+			want:        false,
+		},
 		{
 			name:        "package not in path",
 			path:        "node_modules/react",
@@ -98,12 +247,32 @@ func TestMatchesPackageInPath(t *testing.T) {
 			packageName: "react",
 			want:        false,
 		},
+		{
+			name:        "mixed case in path",
+			path:        "node_modules/JSONStream",
+			packageName: "jsonstream",
+			want:        false,
+		},
+		{
+			name:        "mixed case in path, --ignore-case",
+			path:        "node_modules/request/node_modules/JSONStream",
+			packageName: "jsonstream",
+			ignoreCase:  true,
+			want:        true,
+		},
+		{
+			name:        "scoped mixed case in path, --ignore-case",
+			path:        "node_modules/@Acme/Utils",
+			packageName: "@acme/utils",
+			ignoreCase:  true,
+			want:        true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := matchesPackageInPath(tt.path, tt.packageName); got != tt.want {
-				t.Errorf("matchesPackageInPath(%q, %q) = %v, want %v", tt.path, tt.packageName, got, tt.want)
+			if got := matchPackageInPath(tt.path, tt.packageName, FilterConfig{IgnoreCase: tt.ignoreCase}) != ""; got != tt.want {
+				t.Errorf("matchPackageInPath(%q, %q) = %v, want %v", tt.path, tt.packageName, got, tt.want)
 			}
 		})
 	}
@@ -115,11 +284,12 @@ func TestApplyFilters(t *testing.T) {
 		{Version: "2.0.0", IsDev: false, IsNested: true, Depth: 1},
 		{Version: "3.0.0", IsDev: true, IsNested: true, Depth: 2},
 	}
+	direct, shallow := 0, 1
 
 	tests := []struct {
-		name           string
-		config         FilterConfig
-		expectedCount  int
+		name             string
+		config           FilterConfig
+		expectedCount    int
 		expectedVersions []string
 	}{
 		{
@@ -129,7 +299,7 @@ func TestApplyFilters(t *testing.T) {
 				ShowNestedOnly: false,
 				MinDepth:       0,
 			},
-			expectedCount: 3,
+			expectedCount:    3,
 			expectedVersions: []string{"1.0.0", "2.0.0", "3.0.0"},
 		},
 		{
@@ -139,7 +309,7 @@ func TestApplyFilters(t *testing.T) {
 				ShowNestedOnly: false,
 				MinDepth:       0,
 			},
-			expectedCount: 2,
+			expectedCount:    2,
 			expectedVersions: []string{"1.0.0", "3.0.0"},
 		},
 		{
@@ -149,7 +319,7 @@ func TestApplyFilters(t *testing.T) {
 				ShowNestedOnly: true,
 				MinDepth:       0,
 			},
-			expectedCount: 2,
+			expectedCount:    2,
 			expectedVersions: []string{"2.0.0", "3.0.0"},
 		},
 		{
@@ -159,9 +329,21 @@ func TestApplyFilters(t *testing.T) {
 				ShowNestedOnly: false,
 				MinDepth:       2,
 			},
-			expectedCount: 1,
+			expectedCount:    1,
 			expectedVersions: []string{"3.0.0"},
 		},
+		{
+			name:             "direct dependencies only",
+			config:           FilterConfig{MaxDepth: &direct},
+			expectedCount:    1,
+			expectedVersions: []string{"1.0.0"},
+		},
+		{
+			name:             "depth 1 to 1",
+			config:           FilterConfig{MinDepth: 1, MaxDepth: &shallow},
+			expectedCount:    1,
+			expectedVersions: []string{"2.0.0"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -230,6 +412,9 @@ func TestScanPackages(t *testing.T) {
 	if results[0].TotalInstances != 1 {
 		t.Errorf("Expected 1 instance of react, got %d", results[0].TotalInstances)
 	}
+	if got := results[0].Instances[0].InstallSource; got != types.SourceRegistry {
+		t.Errorf("react InstallSource = %q, want %q", got, types.SourceRegistry)
+	}
 
 	// Check @types/node was found
 	if !results[1].Found {
@@ -240,4 +425,493 @@ func TestScanPackages(t *testing.T) {
 	if results[2].Found {
 		t.Error("Expected vue to not be found")
 	}
-}
\ No newline at end of file
+}
+
+func TestScanPackagesContextCanceled(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages:        map[string]types.Package{"node_modules/react": {Version: "18.2.0"}},
+	}
+	queries := []types.PackageQuery{{Name: "react"}, {Name: "vue"}}
+
+	results, err := ScanPackagesContext(context.Background(), packageLock, queries, FilterConfig{})
+	if err != nil || len(results) != 2 || !results[0].Found {
+		t.Fatalf("ScanPackagesContext() = %+v, %v, want both results and react found", results, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err = ScanPackagesContext(ctx, packageLock, queries, FilterConfig{})
+	if !errors.Is(err, context.Canceled) || len(results) != 0 {
+		t.Errorf("ScanPackagesContext() canceled = %+v, %v, want no results and context.Canceled", results, err)
+	}
+}
+
+func TestScanPackagesDebugLog(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"node_modules/jsonstream":   {Version: "1.0.3"},
+			"node_modules/event-stream": {Version: "3.3.5"},
+		},
+	}
+	ScanPackages(packageLock, []types.PackageQuery{{Name: "JSONStream"}, {Name: "event-stream", Version: "3.3.6"}}, FilterConfig{})
+
+	for _, want := range []string{
+		`msg="name rejected" package=jsonstream query=JSONStream reason="differs only in case (--ignore-case)"`,
+		`msg="candidate rejected" path=node_modules/event-stream query=event-stream version=3.3.5 wanted=3.3.6 reason="version doesn't match"`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("debug log lacks %s:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestScanPackagesScopeWildcard(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"node_modules/@ctrl/tinycolor":                 {Version: "4.1.1"},
+			"node_modules/@ctrl/deluge":                    {Version: "7.2.2"},
+			"node_modules/@ctrl/deluge/node_modules/debug": {Version: "4.4.2"},
+			"node_modules/ctrl":                            {Version: "1.0.0"},
+			"node_modules/@ctrlx/other":                    {Version: "1.0.0"},
+		},
+	}
+
+	results := ScanPackages(packageLock, []types.PackageQuery{{Name: "@ctrl/*"}}, FilterConfig{})
+	names := make(map[string]bool)
+	for _, instance := range results[0].Instances {
+		names[instance.Name] = true
+	}
+	if len(names) != 2 || !names["@ctrl/tinycolor"] || !names["@ctrl/deluge"] {
+		t.Errorf("ScanPackages(@ctrl/*) matched %v, want @ctrl/tinycolor and @ctrl/deluge", names)
+	}
+}
+
+func TestScanPackagesIntegrity(t *testing.T) {
+	const bad = "sha512-bad0bad0bad0"
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"node_modules/foo":                {Version: "1.0.0", Integrity: bad},
+			"node_modules/a/node_modules/foo": {Version: "1.0.0", Integrity: "sha512-good0good0"},
+			"node_modules/b/node_modules/foo": {Version: "1.0.1", Integrity: "sha1-abc " + bad},
+			"node_modules/b":                  {Version: "1.0.0", Dependencies: map[string]string{"foo": "^1.0.0"}},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query types.PackageQuery
+		want  int
+	}{
+		{name: "hash only", query: types.PackageQuery{Name: "foo", Integrity: bad}, want: 2},
+		{name: "hash and version", query: types.PackageQuery{Name: "foo", Version: "1.0.0", Integrity: bad}, want: 1},
+		{name: "hash doesn't match version", query: types.PackageQuery{Name: "foo", Version: "1.0.0", Integrity: "sha512-other"}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := ScanPackages(packageLock, []types.PackageQuery{tt.query}, FilterConfig{})
+			if results[0].TotalInstances != tt.want {
+				t.Fatalf("ScanPackages() found %+v, want %d instances", results[0].Instances, tt.want)
+			}
+			for _, instance := range results[0].Instances {
+				if !instance.IntegrityMatch || instance.IsReference {
+					t.Errorf("instance %+v should be an integrity match, not a reference", instance)
+				}
+			}
+		})
+	}
+}
+
+func TestScanPackagesExactFallback(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"node_modules/foo": {Version: "1.0.0-beta.1+build5"},
+			"node_modules/bar": {Version: "file:vendor/bar"},
+		},
+	}
+
+	queries := []types.PackageQuery{{Name: "foo", Version: "1.0.0-beta.1"}, {Name: "bar", Version: "file:vendor/bar"}}
+	results := ScanPackages(packageLock, queries, FilterConfig{})
+	if !results[0].Found || results[0].Instances[0].ExactFallback {
+		t.Errorf("foo = %+v, want a semver match ignoring build metadata", results[0])
+	}
+	if !results[1].Found || !results[1].Instances[0].ExactFallback {
+		t.Errorf("bar = %+v, want a plain string match flagged as a fallback", results[1])
+	}
+}
+
+func TestScanPackagesVersionAlternatives(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"node_modules/foo":                {Version: "1.0.1"},
+			"node_modules/a/node_modules/foo": {Version: "2.0.0"},
+			"node_modules/b":                  {Version: "1.0.0", Dependencies: map[string]string{"foo": "~1.0.2"}},
+		},
+	}
+
+	query := types.PackageQuery{Name: "foo", Version: "1.0.0 || 1.0.1 || 1.0.2"}
+	results := ScanPackages(packageLock, []types.PackageQuery{query}, FilterConfig{})
+	if results[0].TotalInstances != 2 {
+		t.Fatalf("ScanPackages() found %+v, want 2 instances", results[0].Instances)
+	}
+	for _, instance := range results[0].Instances {
+		want := "1.0.1"
+		if instance.IsReference {
+			want = "1.0.2"
+		}
+		if instance.MatchedVersion != want {
+			t.Errorf("instance %s MatchedVersion = %q, want %q", instance.Path, instance.MatchedVersion, want)
+		}
+	}
+
+	results = ScanPackages(packageLock, []types.PackageQuery{{Name: "foo", Version: "1.0.1"}}, FilterConfig{})
+	if got := results[0].Instances[0].MatchedVersion; got != "" {
+		t.Errorf("MatchedVersion = %q for a single-version query, want none", got)
+	}
+}
+
+func TestScanPackagesAliases(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"":                       {Name: "app", Dependencies: map[string]string{"my-lodash": "npm:lodash@4.17.15"}},
+			"node_modules/my-lodash": {Name: "lodash", Version: "4.17.15"},
+			"node_modules/lodash":    {Version: "4.17.21"},
+		},
+	}
+
+	results := ScanPackages(packageLock, []types.PackageQuery{{Name: "lodash", Version: "4.17.15"}}, FilterConfig{ShowReferences: true})
+	if results[0].TotalInstances != 2 {
+		t.Fatalf("ScanPackages() found %+v, want the aliased install and its reference", results[0].Instances)
+	}
+	for _, instance := range results[0].Instances {
+		if instance.Name != "lodash" || instance.Alias != "my-lodash" || instance.Version != "4.17.15" {
+			t.Errorf("instance %+v, want lodash 4.17.15 aliased as my-lodash", instance)
+		}
+	}
+
+	// The install path still matches under the alias name
+	results = ScanPackages(packageLock, []types.PackageQuery{{Name: "my-lodash"}}, FilterConfig{})
+	if results[0].TotalInstances != 1 || !reflect.DeepEqual(results[0].Instances[0].ReferencedBy, []string{"(root)"}) {
+		t.Errorf("ScanPackages() found %+v for the alias name, want the install referenced by the root", results[0].Instances)
+	}
+
+	v1 := &types.PackageLock{
+		LockfileVersion: 1,
+		Dependencies: map[string]types.Dependency{
+			"my-lodash": {Version: "npm:lodash@4.17.15"},
+		},
+	}
+	results = ScanPackages(v1, []types.PackageQuery{{Name: "lodash", Version: "<4.17.19"}}, FilterConfig{})
+	if results[0].TotalInstances != 1 || results[0].Instances[0].Alias != "my-lodash" || results[0].Instances[0].Version != "4.17.15" {
+		t.Errorf("ScanPackages() v1 found %+v, want the aliased lodash 4.17.15", results[0].Instances)
+	}
+}
+
+func TestScanPackagesReferenceTypes(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"": {
+				Name:            "app",
+				Dependencies:    map[string]string{"debug": "^4.4.0"},
+				DevDependencies: map[string]string{"debug": "4.4.2"},
+			},
+			"node_modules/a": {Version: "1.0.0", OptionalDependencies: map[string]string{"debug": "~4.4.1"}},
+			"node_modules/b": {Version: "1.0.0", PeerDependencies: map[string]string{"debug": ">=4"}},
+		},
+	}
+	query := []types.PackageQuery{{Name: "debug", Version: "4.4.2"}}
+
+	tests := []struct {
+		name   string
+		config FilterConfig
+		want   []string
+	}{
+		{name: "default", want: []string{"dependencies", "devDependencies", "optionalDependencies"}},
+		{name: "--show-deps", config: FilterConfig{PeerReferences: true}, want: []string{"dependencies", "devDependencies", "optionalDependencies", "peerDependencies"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, instance := range ScanPackages(packageLock, query, tt.config)[0].Instances {
+				got = append(got, instance.ReferenceType)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reference types = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanPackagesConsolidatesReferences(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"":                                     {Name: "app", Dependencies: map[string]string{"lodash": "^4.17.0"}, DevDependencies: map[string]string{"lodash": "4.17.20"}},
+			"node_modules/lodash":                  {Version: "4.17.20"},
+			"node_modules/foo":                     {Version: "1.0.0", Dependencies: map[string]string{"lodash": "^4.17.0"}},
+			"node_modules/bar":                     {Version: "1.0.0", Dependencies: map[string]string{"lodash": "4.17.20"}},
+			"node_modules/bar/node_modules/lodash": {Version: "4.17.20"},
+			"node_modules/baz":                     {Version: "1.0.0", Dependencies: map[string]string{"lodash": "~4.17.20"}},
+			"node_modules/baz/node_modules/lodash": {Version: "4.17.21"},
+		},
+	}
+	query := []types.PackageQuery{{Name: "lodash", Version: "4.17.20"}}
+
+	results := ScanPackages(packageLock, query, FilterConfig{})
+	want := map[string][]string{
+		"node_modules/lodash":                  {"(root)", "node_modules/foo"},
+		"node_modules/bar/node_modules/lodash": {"node_modules/bar"},
+		"node_modules/baz -> lodash":           nil, // Resolves to a safe nested version
+	}
+	if results[0].TotalInstances != len(want) {
+		t.Fatalf("ScanPackages() found %+v, want %d unique locations", results[0].Instances, len(want))
+	}
+	for _, instance := range results[0].Instances {
+		if got, ok := want[instance.Path]; !ok || !reflect.DeepEqual(instance.ReferencedBy, got) {
+			t.Errorf("%s referencedBy = %v, want %v", instance.Path, instance.ReferencedBy, got)
+		}
+	}
+
+	results = ScanPackages(packageLock, query, FilterConfig{ShowReferences: true})
+	if results[0].TotalInstances != 7 {
+		t.Errorf("ScanPackages() with ShowReferences found %d instances, want every install and reference (7)", results[0].TotalInstances)
+	}
+}
+
+func TestScanPackagesConsolidatesReferencesResolutions(t *testing.T) {
+	tests := []struct {
+		name        string
+		packageLock *types.PackageLock
+		query       types.PackageQuery
+		want        map[string][]string
+	}{
+		{
+			name:        "yarn",
+			packageLock: yarnLock,
+			query:       types.PackageQuery{Name: "debug", Version: "2.6.9"},
+			want:        map[string][]string{"debug@2.6.9": {"express@4.18.2"}},
+		},
+		{
+			name:        "pnpm dependency",
+			packageLock: pnpmLock,
+			query:       types.PackageQuery{Name: "debug", Version: "2.6.9"},
+			want:        map[string][]string{"node_modules/.pnpm/debug@2.6.9/node_modules/debug": {"node_modules/.pnpm/express@4.18.2/node_modules/express"}},
+		},
+		{
+			name:        "pnpm importer",
+			packageLock: pnpmLock,
+			query:       types.PackageQuery{Name: "debug", Version: "4.3.4"},
+			want:        map[string][]string{"node_modules/.pnpm/debug@4.3.4/node_modules/debug": {"(root)"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := ScanPackages(tt.packageLock, []types.PackageQuery{tt.query}, FilterConfig{})
+			got := make(map[string][]string)
+			for _, instance := range results[0].Instances {
+				got[instance.Path] = instance.ReferencedBy
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ScanPackages() referencedBy = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAlias(t *testing.T) {
+	tests := []struct {
+		spec        string
+		wantName    string
+		wantVersion string
+		wantOK      bool
+	}{
+		{"npm:lodash@4.17.15", "lodash", "4.17.15", true},
+		{"npm:@scope/pkg@^1.0.0", "@scope/pkg", "^1.0.0", true},
+		{"npm:@scope/pkg", "@scope/pkg", "*", true},
+		{"^4.17.15", "", "", false},
+		{"npm:", "", "", false},
+	}
+
+	for _, tt := range tests {
+		name, version, ok := parseAlias(tt.spec)
+		if name != tt.wantName || version != tt.wantVersion || ok != tt.wantOK {
+			t.Errorf("parseAlias(%q) = %q, %q, %v, want %q, %q, %v", tt.spec, name, version, ok, tt.wantName, tt.wantVersion, tt.wantOK)
+		}
+	}
+}
+
+func TestIsGlob(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"@ctrl/*", true},
+		{"*-loader", true},
+		{"re:.*-loader", false},
+		{"lodash", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsGlob(tt.name); got != tt.want {
+			t.Errorf("IsGlob(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExpandPatterns(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"node_modules/css-loader":                     {Version: "6.0.0"},
+			"node_modules/style-loader":                   {Version: "3.0.0"},
+			"node_modules/a/node_modules/style-loader":    {Version: "2.0.0"},
+			"node_modules/@babel/plugin-transform-spread": {Version: "7.0.0"},
+			"node_modules/webpack":                        {Version: "5.0.0"},
+		},
+	}
+	queries := []types.PackageQuery{{Name: "*-loader"}, {Name: "@babel/**"}, {Name: "*-plugin"}}
+
+	results := ExpandPatterns(ScanPackages(packageLock, queries, FilterConfig{}))
+
+	want := []struct {
+		name, pattern string
+		instances     int
+	}{
+		{"css-loader", "*-loader", 1},
+		{"style-loader", "*-loader", 2},
+		{"@babel/plugin-transform-spread", "@babel/**", 1},
+		{"*-plugin", "", 0},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("ExpandPatterns() returned %d results, want %d: %+v", len(results), len(want), results)
+	}
+	for i, w := range want {
+		got := results[i]
+		if got.Package.Name != w.name || got.Package.Pattern != w.pattern || got.TotalInstances != w.instances {
+			t.Errorf("result[%d] = %s (pattern %q, %d instances), want %s (pattern %q, %d instances)",
+				i, got.Package.Name, got.Package.Pattern, got.TotalInstances, w.name, w.pattern, w.instances)
+		}
+	}
+}
+
+func TestMatchesReference(t *testing.T) {
+	tests := []struct {
+		spec         string
+		version      string
+		want         bool
+		wantFallback bool
+	}{
+		{spec: "^4.17.21", version: "4.17.2", want: false},
+		{spec: "^4.17.0", version: "4.17.21", want: true},
+		{spec: "^4.0.0", version: "5.0.0", want: false},
+		{spec: "~1.2.0", version: "1.2.9", want: true},
+		{spec: "~1.2.0", version: "1.3.0", want: false},
+		{spec: "1.x", version: "1.9.0", want: true},
+		{spec: "1.2.x", version: "1.3.0", want: false},
+		{spec: "*", version: "0.0.1", want: true},
+		{spec: "4.4.2", version: "4.4.2", want: true},
+		{spec: "4.4.1", version: "4.4.2", want: false},
+		{spec: "^4.17.0", version: "<4.17.12", want: true},
+		{spec: "^4.17.12", version: "<4.17.12", want: false},
+		{spec: "^1.0.0", version: "", want: true},
+		{spec: "github:user/repo#v1.0.0", version: "1.0.0", want: false, wantFallback: true},
+		{spec: "npm:other@1.0.0", version: "npm:other@1.0.0", want: true, wantFallback: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec+" "+tt.version, func(t *testing.T) {
+			got, fallback := MatchesReference(tt.spec, tt.version)
+			if got != tt.want || fallback != tt.wantFallback {
+				t.Errorf("MatchesReference(%q, %q) = %v, %v, want %v, %v", tt.spec, tt.version, got, fallback, tt.want, tt.wantFallback)
+			}
+		})
+	}
+}
+
+func TestMatchesVersion(t *testing.T) {
+	tests := []struct {
+		installed string
+		version   string
+		want      bool
+	}{
+		{installed: "1.2.3", version: "", want: true},
+		{installed: "1.2.3", version: "1.2.3", want: true},
+		{installed: "1.2.4", version: "1.2.3", want: false},
+		{installed: "4.17.11", version: "<4.17.12", want: true},
+		{installed: "4.17.12", version: "<4.17.12", want: false},
+		{installed: "2.0.1", version: "<1.0.1 || >=2.0.0 <2.0.3", want: true},
+		{installed: "file:../local", version: "<1.0.0", want: false},
+		{installed: "1.2.3+build.5", version: "1.2.3", want: true},
+		{installed: "1.2.5", version: ">=1.2.0 <1.3.0", want: true},
+		{installed: "1.3.0-beta.1", version: "<1.3.0", want: false},
+		{installed: "1.3.0-beta.2", version: ">=1.3.0-beta.1 <1.3.0", want: true},
+		{installed: "1.2.9", version: "1.2", want: true},
+		{installed: "1.0.0-beta.1+build5", version: "1.0.0-beta.1", want: true},
+		{installed: "1.0.0-beta.1", version: "1.0.0-beta.1+build7", want: true},
+		{installed: "1.0.0-beta.1", version: "1.0.0-beta.2", want: false},
+		{installed: "1.0.0-beta.3", version: "^1.0.0-beta.1", want: true},
+		{installed: "1.1.0-beta.1", version: "^1.0.0-beta.1", want: false},
+		{installed: "v1.0.0", version: "1.0.0", want: true},
+		{installed: "abc123def", version: "abc123def", want: true},
+		{installed: "abc123def", version: "abc123dee", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.installed+" "+tt.version, func(t *testing.T) {
+			if got := MatchesVersion(tt.installed, tt.version); got != tt.want {
+				t.Errorf("MatchesVersion(%q, %q) = %v, want %v", tt.installed, tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstalledPackages(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"":                                   {Name: "app", Version: "1.0.0"},
+			"packages/lib":                       {Name: "lib"},
+			"node_modules/lodash":                {Version: "4.17.21"},
+			"node_modules/a/node_modules/lodash": {Version: "4.17.20"},
+			"node_modules/b/node_modules/lodash": {Version: "4.17.20"},
+			"node_modules/a/node_modules/@types/node": {Version: "18.0.0"},
+			"debug@4.3.4":            {Name: "debug", Version: "4.3.4"},
+			"node_modules/my-lodash": {Name: "lodash", Version: "4.17.15"},
+		},
+	}
+
+	got := InstalledPackages(packageLock)
+	if len(got) != 3 {
+		t.Errorf("InstalledPackages() = %v, want 3 packages", got)
+	}
+	if len(got["lodash"]) != 3 {
+		t.Errorf("InstalledPackages()[lodash] = %v, want 3 distinct versions including the alias", got["lodash"])
+	}
+	if !reflect.DeepEqual(got["@types/node"], []string{"18.0.0"}) || !reflect.DeepEqual(got["debug"], []string{"4.3.4"}) {
+		t.Errorf("InstalledPackages() = %v, want scoped and yarn-style entries", got)
+	}
+
+	v1 := &types.PackageLock{
+		LockfileVersion: 1,
+		Dependencies: map[string]types.Dependency{
+			"a": {Version: "1.0.0", Dependencies: map[string]types.Dependency{"b": {Version: "2.0.0"}}},
+		},
+	}
+	if got := InstalledPackages(v1); len(got) != 2 || got["b"][0] != "2.0.0" {
+		t.Errorf("InstalledPackages() v1 = %v, want nested dependencies", got)
+	}
+}
@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"sort"
+	"strings"
+
+	"scnpm/pkg/semver"
+	"scnpm/pkg/types"
+)
+
+// MaxTopDuplicated is how many of the most duplicated packages Stats ranks
+const MaxTopDuplicated = 10
+
+// Stats summarizes what a lockfile installs
+type Stats struct {
+	Packages         int          `json:"packages"`         // Installed entries, counting every copy
+	UniqueNames      int          `json:"uniqueNames"`      // Distinct package names
+	UniqueVersions   int          `json:"uniqueVersions"`   // Distinct name@version pairs
+	Prod             int          `json:"prod"`             // Entries needed in production
+	Dev              int          `json:"dev"`              // Entries only development dependencies need
+	Depths           []DepthCount `json:"depths"`           // Entries at each nesting depth, shallowest first
+	InstallScripts   int          `json:"installScripts"`   // Entries that run install scripts
+	MissingIntegrity int          `json:"missingIntegrity"` // Entries without an integrity hash, as --require-integrity counts them
+	DuplicatedNames  int          `json:"duplicatedNames"`  // Names installed in more than one version
+	Duplicates       []Duplicate  `json:"duplicates"`       // The MaxTopDuplicated names with the most versions installed
+}
+
+// DepthCount is the number of entries installed at a nesting depth
+type DepthCount struct {
+	Depth int `json:"depth"`
+	Count int `json:"count"`
+}
+
+// Duplicate is a package installed in several versions
+type Duplicate struct {
+	Name     string   `json:"name"`
+	Versions []string `json:"versions"` // Distinct versions, in semver order where they parse
+	Copies   int      `json:"copies"`   // Installed entries across all versions
+}
+
+// LockfileStats counts what a lockfile installs: packages, names, versions, the dev and
+// production split, the nesting depths, install scripts, missing hashes and duplicates
+func LockfileStats(packageLock *types.PackageLock) Stats {
+	stats := Stats{Depths: []DepthCount{}, Duplicates: []Duplicate{}}
+	versions := make(map[string][]string)
+	copies := make(map[string]int)
+	depths := make(map[int]int)
+
+	for _, entry := range installedEntries(packageLock) {
+		stats.Packages++
+		if entry.Dev {
+			stats.Dev++
+		} else {
+			stats.Prod++
+		}
+		depths[strings.Count(entry.Path, "/node_modules/")]++
+		if entry.Install {
+			stats.InstallScripts++
+		}
+		if entry.Integrity == "" && !entry.Bundled && InstallSource(entry.Resolved, entry.Link) != types.SourceLink {
+			stats.MissingIntegrity++
+		}
+
+		copies[entry.Name]++
+		if entry.Version != "" && !containsVersion(versions[entry.Name], entry.Version) {
+			versions[entry.Name] = append(versions[entry.Name], entry.Version)
+		}
+	}
+
+	stats.UniqueNames = len(copies)
+	for depth, count := range depths {
+		stats.Depths = append(stats.Depths, DepthCount{Depth: depth, Count: count})
+	}
+	sort.Slice(stats.Depths, func(i, j int) bool { return stats.Depths[i].Depth < stats.Depths[j].Depth })
+
+	var duplicates []Duplicate
+	for name, installed := range versions {
+		stats.UniqueVersions += len(installed)
+		if len(installed) > 1 {
+			semver.SortStrings(installed)
+			duplicates = append(duplicates, Duplicate{Name: name, Versions: installed, Copies: copies[name]})
+		}
+	}
+	stats.DuplicatedNames = len(duplicates)
+	sort.Slice(duplicates, func(i, j int) bool {
+		a, b := duplicates[i], duplicates[j]
+		if len(a.Versions) != len(b.Versions) {
+			return len(a.Versions) > len(b.Versions)
+		}
+		if a.Copies != b.Copies {
+			return a.Copies > b.Copies
+		}
+		return a.Name < b.Name
+	})
+	if len(duplicates) > MaxTopDuplicated {
+		duplicates = duplicates[:MaxTopDuplicated]
+	}
+	stats.Duplicates = append(stats.Duplicates, duplicates...)
+	return stats
+}
+
+// containsVersion reports whether versions holds version
+func containsVersion(versions []string, version string) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestLockfileStats(t *testing.T) {
+	data, err := os.ReadFile("testdata/stats-package-lock.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var packageLock types.PackageLock
+	if err := json.Unmarshal(data, &packageLock); err != nil {
+		t.Fatal(err)
+	}
+
+	got := LockfileStats(&packageLock)
+	want := Stats{
+		Packages:         8,
+		UniqueNames:      5,
+		UniqueVersions:   6,
+		Prod:             6,
+		Dev:              2,
+		Depths:           []DepthCount{{Depth: 0, Count: 4}, {Depth: 1, Count: 3}, {Depth: 2, Count: 1}},
+		InstallScripts:   2,
+		MissingIntegrity: 2,
+		DuplicatedNames:  1,
+		Duplicates:       []Duplicate{{Name: "b", Versions: []string{"1.0.0", "2.0.0", "3.0.0"}, Copies: 3}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LockfileStats() = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestLockfileStatsTopDuplicated(t *testing.T) {
+	packageLock := &types.PackageLock{LockfileVersion: 3, Packages: map[string]types.Package{}}
+	// pkg00 has 2 versions, pkg01 3 and so on
+	for i := 0; i < MaxTopDuplicated+2; i++ {
+		name := fmt.Sprintf("pkg%02d", i)
+		packageLock.Packages["node_modules/"+name] = types.Package{Version: "1.0.0"}
+		for v := 0; v <= i; v++ {
+			packageLock.Packages[fmt.Sprintf("node_modules/host%02d/node_modules/%s", v, name)] = types.Package{Version: fmt.Sprintf("2.0.%d", v)}
+		}
+	}
+
+	got := LockfileStats(packageLock)
+	if got.DuplicatedNames != MaxTopDuplicated+2 || len(got.Duplicates) != MaxTopDuplicated {
+		t.Fatalf("LockfileStats() = %d duplicated names, %d ranked; want %d and %d", got.DuplicatedNames, len(got.Duplicates), MaxTopDuplicated+2, MaxTopDuplicated)
+	}
+	if first := got.Duplicates[0]; first.Name != fmt.Sprintf("pkg%02d", MaxTopDuplicated+1) || len(first.Versions) != MaxTopDuplicated+3 {
+		t.Errorf("Duplicates[0] = %+v, want the package with the most versions", first)
+	}
+}
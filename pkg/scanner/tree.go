@@ -0,0 +1,148 @@
+package scanner
+
+import (
+	"sort"
+
+	"scnpm/pkg/types"
+)
+
+// TreeNode is a package of a dependency tree
+type TreeNode struct {
+	Name     string      `json:"name"`
+	Version  string      `json:"version,omitempty"`
+	Path     string      `json:"path"`
+	Dev      bool        `json:"dev,omitempty"`
+	Risky    bool        `json:"risky,omitempty"`
+	Deduped  bool        `json:"deduped,omitempty"` // Its dependencies are under another occurrence in the tree
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// TreeConfig says which parts of the dependency tree DependencyTree returns
+type TreeConfig struct {
+	Risky map[string]bool // Install paths of the risky packages
+	All   bool            // Keep the branches that don't lead to a risky package
+	Depth int             // Levels of dependencies below the project; -1 for no limit
+}
+
+// DependencyTree returns the dependency tree of a lockfile, like npm ls: the project, its
+// dependencies, theirs, and so on, each resolved by resolveEntry. A package is only expanded
+// once, at its shallowest occurrence; the others are marked Deduped. Unless config.All is set,
+// the tree is pruned to the branches leading to a risky package. Installed packages and pnpm
+// workspace projects nothing depends on are listed under the project.
+func DependencyTree(packageLock *types.PackageLock, config TreeConfig) *TreeNode {
+	versions := make(map[string]types.PackageInstance)
+	for _, instance := range Inventory(packageLock) {
+		versions[instance.Path] = instance
+	}
+
+	labels, parents := dependencyGraph(packageLock)
+	children := make(map[string][]string)
+	for path, from := range parents {
+		for _, parent := range from {
+			children[parent] = append(children[parent], path)
+		}
+	}
+	for path := range labels {
+		if _, installed := versions[path]; !installed && packageLock.Resolutions == nil {
+			// npm's workspace sources are reached through their links
+			continue
+		}
+		if _, ok := parents[path]; !ok && path != "" {
+			children[""] = append(children[""], path)
+		}
+	}
+	// npm links depend on what the workspace or directory they point to depends on
+	for path, pkg := range packageLock.Packages {
+		if pkg.Link && pkg.Resolved != "" && packageLock.Resolutions == nil {
+			children[path] = children[pkg.Resolved]
+		}
+	}
+	for path, list := range children {
+		sort.Slice(list, func(i, j int) bool {
+			if labels[list[i]] != labels[list[j]] {
+				return labels[list[i]] < labels[list[j]]
+			}
+			return list[i] < list[j]
+		})
+		// A package declaring a dependency twice (dependencies and devDependencies) is its parent once
+		unique := list[:0]
+		for i, child := range list {
+			if i == 0 || child != list[i-1] {
+				unique = append(unique, child)
+			}
+		}
+		children[path] = unique
+	}
+
+	// Each package is expanded under the parent it's first reached from breadth-first
+	expandUnder := map[string]string{"": ""}
+	queue := []string{""}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		for _, child := range children[path] {
+			if _, seen := expandUnder[child]; !seen {
+				expandUnder[child] = path
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	// The packages leading to a risky one are those reached from it up the tree
+	leads := make(map[string]bool)
+	var risky []string
+	for path := range config.Risky {
+		if _, ok := labels[path]; ok {
+			risky = append(risky, path)
+			leads[path] = true
+		}
+	}
+	dependents := make(map[string][]string)
+	for path, list := range children {
+		for _, child := range list {
+			dependents[child] = append(dependents[child], path)
+		}
+	}
+	for len(risky) > 0 {
+		path := risky[0]
+		risky = risky[1:]
+		for _, dependent := range dependents[path] {
+			if !leads[dependent] {
+				leads[dependent] = true
+				risky = append(risky, dependent)
+			}
+		}
+	}
+
+	newNode := func(path string) *TreeNode {
+		instance := versions[path]
+		return &TreeNode{Name: labels[path], Version: instance.Version, Path: path, Dev: instance.IsDev, Risky: config.Risky[path]}
+	}
+	// expand adds the dependencies of parent, which are at level of the tree (0 for the project's)
+	var expand func(parent *TreeNode, level int)
+	expand = func(parent *TreeNode, level int) {
+		if config.Depth >= 0 && level > config.Depth {
+			return
+		}
+		for _, child := range children[parent.Path] {
+			if !config.All && !leads[child] {
+				continue
+			}
+			node := newNode(child)
+			if expandUnder[child] == parent.Path {
+				expand(node, level+1)
+			} else {
+				node.Deduped = true
+			}
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	root := newNode("")
+	root.Version = packageLock.Version
+	if pkg, ok := packageLock.Packages[""]; ok && pkg.Version != "" {
+		root.Version = pkg.Version
+	}
+	expand(root, 0)
+	return root
+}
@@ -0,0 +1,116 @@
+package scanner
+
+import (
+	"encoding/json"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+// renderTree returns the names of a tree, with the version of each node, deduped ones marked with
+// a trailing *
+func renderTree(node *TreeNode) interface{} {
+	label := node.Name + "@" + node.Version
+	if node.Deduped {
+		label += "*"
+	}
+	if len(node.Children) == 0 {
+		return label
+	}
+	children := []interface{}{}
+	for _, child := range node.Children {
+		children = append(children, renderTree(child))
+	}
+	return map[string]interface{}{label: children}
+}
+
+func TestDependencyTree(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"":                                    {Name: "app", Version: "1.0.0", Dependencies: map[string]string{"express": "^4", "koa": "^2", "web": "*"}, DevDependencies: map[string]string{"lodash": "^4"}},
+			"packages/web":                        {Name: "web", Dependencies: map[string]string{"debug": "^4"}},
+			"node_modules/web":                    {Resolved: "packages/web", Link: true},
+			"node_modules/express":                {Version: "4.18.2", Dependencies: map[string]string{"body-parser": "1", "debug": "2"}},
+			"node_modules/koa":                    {Version: "2.0.0", Dependencies: map[string]string{"body-parser": "1", "debug": "4"}},
+			"node_modules/koa/node_modules/debug": {Version: "4.3.4"},
+			"node_modules/body-parser":            {Version: "1.20.0", Dependencies: map[string]string{"debug": "2"}},
+			"node_modules/debug":                  {Version: "2.6.9"},
+			"node_modules/lodash":                 {Version: "4.17.21", Dev: true},
+			"node_modules/left-over":              {Version: "1.0.0"},
+		},
+	}
+	tests := []struct {
+		name   string
+		config TreeConfig
+		want   string
+	}{
+		{
+			// The workspace's debug resolves to the hoisted copy, like the others
+			name:   "pruned to the risky package, expanded where it's shallowest",
+			config: TreeConfig{Risky: map[string]bool{"node_modules/debug": true}, Depth: -1},
+			want:   `{"app@1.0.0":[{"express@4.18.2":[{"body-parser@1.20.0":["debug@2.6.9*"]},"debug@2.6.9"]},{"koa@2.0.0":["body-parser@1.20.0*"]},{"web@":["debug@2.6.9*"]}]}`,
+		},
+		{
+			name:   "risky package under a link",
+			config: TreeConfig{Risky: map[string]bool{"node_modules/koa/node_modules/debug": true}, Depth: -1},
+			want:   `{"app@1.0.0":[{"koa@2.0.0":["debug@4.3.4"]}]}`,
+		},
+		{
+			name:   "nothing risky",
+			config: TreeConfig{Depth: -1},
+			want:   `"app@1.0.0"`,
+		},
+		{
+			name:   "whole tree to a depth",
+			config: TreeConfig{All: true, Depth: 0},
+			want:   `{"app@1.0.0":["express@4.18.2","koa@2.0.0","left-over@1.0.0","lodash@4.17.21","web@"]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := json.Marshal(renderTree(DependencyTree(packageLock, tt.config)))
+			if string(got) != tt.want {
+				t.Errorf("DependencyTree() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDependencyTreeResolutions(t *testing.T) {
+	tests := []struct {
+		name        string
+		packageLock *types.PackageLock
+		risky       string
+		wantRisky   string
+		wantAll     string
+	}{
+		{
+			// yarn.lock has no root entry; what nothing depends on hangs under the project
+			name:        "yarn",
+			packageLock: yarnLock,
+			risky:       "debug@2.6.9",
+			wantRisky:   `{"(root)@":[{"express@4.18.2":["debug@2.6.9"]}]}`,
+			wantAll:     `{"(root)@":["debug@4.3.4",{"express@4.18.2":[{"debug@2.6.9":["ms@2.0.0"]}]}]}`,
+		},
+		{
+			name:        "pnpm",
+			packageLock: pnpmLock,
+			risky:       "node_modules/.pnpm/debug@2.6.9/node_modules/debug",
+			wantRisky:   `{"(root)@":[{"express@4.18.2":["debug@2.6.9"]}]}`,
+			wantAll:     `{"(root)@":["debug@4.3.4",{"express@4.18.2":["debug@2.6.9"]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := json.Marshal(renderTree(DependencyTree(tt.packageLock, TreeConfig{Risky: map[string]bool{tt.risky: true}, Depth: -1})))
+			if string(got) != tt.wantRisky {
+				t.Errorf("DependencyTree() = %s, want %s", got, tt.wantRisky)
+			}
+			got, _ = json.Marshal(renderTree(DependencyTree(tt.packageLock, TreeConfig{All: true, Depth: -1})))
+			if string(got) != tt.wantAll {
+				t.Errorf("DependencyTree(all) = %s, want %s", got, tt.wantAll)
+			}
+		})
+	}
+}
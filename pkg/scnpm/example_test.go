@@ -0,0 +1,40 @@
+package scnpm_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"scnpm/pkg/scnpm"
+)
+
+func ExampleScan() {
+	queries, err := scnpm.ParseQueries("badpak.json", "", []byte(`["event-stream@3.3.6", "left-pad"]`))
+	if err != nil {
+		panic(err)
+	}
+	packageLock := `{
+		"lockfileVersion": 3,
+		"packages": {
+			"": {"name": "app"},
+			"node_modules/event-stream": {"version": "3.3.6"}
+		}
+	}`
+
+	report, err := scnpm.Scan(context.Background(), scnpm.ScanRequest{
+		Lockfiles: []scnpm.Lockfile{{Name: "package-lock.json", Reader: strings.NewReader(packageLock)}},
+		Queries:   queries,
+	})
+	if err != nil {
+		panic(err)
+	}
+	for _, result := range report.Results {
+		for _, instance := range result.Instances {
+			fmt.Printf("%s@%s at %s, line %d\n", instance.Name, instance.Version, instance.Path, instance.LineNumber)
+		}
+	}
+	fmt.Printf("%d risky, %d safe\n", report.Totals.Risks, report.Totals.Safe)
+	// Output:
+	// event-stream@3.3.6 at node_modules/event-stream, line 5
+	// 1 risky, 1 safe
+}
@@ -0,0 +1,53 @@
+package scnpm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"scnpm/pkg/input"
+	"scnpm/pkg/lockfile"
+	"scnpm/pkg/types"
+)
+
+// ErrManifest is returned for a package.json given as a lockfile, unless manifests are allowed
+var ErrManifest = errors.New("this looks like a package.json manifest, not a lockfile")
+
+// ParseLockfile parses package-lock.json, npm-shrinkwrap.json, yarn.lock or pnpm-lock.yaml content.
+// The name is only used to recognize the format; content sniffing covers stdin and odd names.
+// A package.json is parsed for its declared dependency ranges when manifest is set, and
+// rejected with ErrManifest otherwise.
+func ParseLockfile(name string, data []byte, manifest bool) (*types.PackageLock, error) {
+	data, err := input.Normalize(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if lockfile.IsPnpm(name, data) {
+		return lockfile.ParsePnpm(data)
+	}
+
+	if lockfile.IsYarn(name, data) {
+		return lockfile.ParseYarn(data)
+	}
+
+	if lockfile.IsManifest(data) {
+		if !manifest {
+			return nil, ErrManifest
+		}
+		return lockfile.ParseManifest(data)
+	}
+
+	var packageLock types.PackageLock
+	if err := json.Unmarshal(data, &packageLock); err != nil {
+		return nil, err
+	}
+
+	lines, err := lockfile.LineIndex(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index line numbers: %v", err)
+	}
+	packageLock.Lines = lines
+
+	return &packageLock, nil
+}
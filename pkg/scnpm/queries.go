@@ -0,0 +1,554 @@
+package scnpm
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"scnpm/pkg/input"
+	"scnpm/pkg/ossindex"
+	"scnpm/pkg/scanner"
+	"scnpm/pkg/semver"
+	"scnpm/pkg/snyk"
+	"scnpm/pkg/types"
+
+	"gopkg.in/yaml.v3"
+)
+
+// packageEntry is the rich form of a bad-package list entry
+type packageEntry struct {
+	Name     string `json:"name" yaml:"name"`
+	Version  string `json:"version" yaml:"version"`
+	Severity string `json:"severity" yaml:"severity"`
+	Advisory string `json:"advisory" yaml:"advisory"`
+	Note     string `json:"note" yaml:"note"`
+
+	// Integrity is the SRI hash of a malicious tarball, from IoC lists
+	Integrity string `json:"integrity" yaml:"integrity"`
+}
+
+// ParseQuery parses "package@version". A bare name, or a version of "*", matches any
+// installed version of the package.
+func ParseQuery(input string) (types.PackageQuery, error) {
+	// Regular expressions may contain "@" themselves, so they always match any version
+	if strings.HasPrefix(input, scanner.RegexPrefix) {
+		query := types.PackageQuery{Name: input}
+		return query, validateName(query.Name)
+	}
+
+	// The version starts at the first "@" after the name, skipping a scope's leading "@"
+	name, version := input, ""
+	start := 0
+	if strings.HasPrefix(input, "@") {
+		start = 1
+	}
+	if i := strings.Index(input[start:], "@"); i >= 0 {
+		name, version = input[:start+i], input[start+i+1:]
+	}
+	if name == "" || name == "@" || (strings.HasPrefix(name, "@") && !strings.Contains(name, "/")) {
+		return types.PackageQuery{}, fmt.Errorf("invalid format, expected package or package@version")
+	}
+
+	query := types.PackageQuery{Name: name, Version: anyVersion(version)}
+	if err := validateName(query.Name); err != nil {
+		return types.PackageQuery{}, err
+	}
+	if err := validateVersion(query.Name, query.Version); err != nil {
+		return types.PackageQuery{}, err
+	}
+	return query, nil
+}
+
+// anyVersion normalizes the "*" wildcard to the empty version, which matches every installed
+// version, prereleases included
+func anyVersion(version string) string {
+	if strings.TrimSpace(version) == "*" {
+		return ""
+	}
+	return version
+}
+
+// minGlobLiteral is how many letters or digits in a row a glob needs when its scope isn't
+// literal: "*-loader" is specific enough, "*-*" or "*.*" would flag most of a lockfile
+const minGlobLiteral = 3
+
+// validateName compiles "re:" regular expressions and rejects globs too broad to mean anything:
+// a glob needs a literal scope, like "@ctrl/*", or a literal part of minGlobLiteral letters or
+// digits, like "*-loader"
+func validateName(name string) error {
+	if strings.HasPrefix(name, scanner.RegexPrefix) {
+		if _, err := scanner.CompileNamePattern(name); err != nil {
+			return fmt.Errorf("invalid regular expression '%s': %v", strings.TrimPrefix(name, scanner.RegexPrefix), err)
+		}
+		return nil
+	}
+	if !scanner.IsGlob(name) {
+		return nil
+	}
+	if scope, _, ok := strings.Cut(name, "/"); ok && len(scope) > 1 && strings.HasPrefix(scope, "@") && !strings.Contains(scope, "*") {
+		return nil
+	}
+	if longestAlphanumericRun(name) < minGlobLiteral {
+		return fmt.Errorf("invalid package name '%s': the pattern would match almost every package; give a scope such as @ctrl/* or at least %d letters or digits in a row", name, minGlobLiteral)
+	}
+	return nil
+}
+
+// longestAlphanumericRun returns the length of the longest run of ASCII letters and digits in s
+func longestAlphanumericRun(s string) int {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
+
+// validateIntegrity checks that an IoC hash is an SRI string like "sha512-<base64>"
+func validateIntegrity(name, integrity string) error {
+	for _, hash := range strings.Fields(integrity) {
+		algorithm, digest, ok := strings.Cut(hash, "-")
+		switch {
+		case !ok || digest == "":
+			return fmt.Errorf("invalid integrity '%s' for '%s': expected <algorithm>-<base64 digest>", hash, name)
+		case scanner.IntegrityStrength(algorithm) == 0:
+			return fmt.Errorf("invalid integrity '%s' for '%s': unsupported algorithm '%s'", hash, name, algorithm)
+		}
+	}
+	return nil
+}
+
+// validateVersion rejects versions that use range syntax but don't parse as an npm range, so a
+// typo like ">=1.2.0 <1.3" fails loudly instead of silently matching nothing
+func validateVersion(name, version string) error {
+	if !semver.IsRange(version) {
+		return nil
+	}
+	if _, err := semver.ParseRange(version); err != nil {
+		return fmt.Errorf("invalid version range '%s' for '%s': %v", version, name, err)
+	}
+	return nil
+}
+
+// ParseQueries parses a bad-package list: JSON, YAML, CSV, plain text, or a Snyk or OSS Index
+// report, as format says. An empty format is taken from the extension of name (a file path or
+// URL), and failing that from the content. The name is also used in error messages.
+func ParseQueries(name, format string, data []byte) ([]types.PackageQuery, error) {
+	data, err := input.Normalize(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode packages list '%s': %v", name, err)
+	}
+
+	format = strings.ToLower(format)
+	if format == "" {
+		format = ListFormat(name)
+	}
+	if format == "" {
+		// Unknown extension: JSON if it parses as such, otherwise a plain-text list
+		format = "text"
+		if json.Valid(data) {
+			format = "json"
+		}
+	}
+
+	var queries []types.PackageQuery
+	switch format {
+	case "text", "txt":
+		queries, err = parsePackagesText(data)
+	case "csv":
+		queries, err = parsePackagesCSV(data)
+	case "yaml", "yml":
+		queries, err = parsePackagesYAML(data)
+	case "snyk":
+		queries, err = snyk.Parse(data)
+	case "ossindex":
+		var skipped int
+		queries, skipped, err = ossindex.Parse(data)
+		if skipped > 0 {
+			slog.Info(fmt.Sprintf("skipped %d non-npm components in '%s'", skipped, name))
+		}
+	case "json":
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+			queries, err = parsePackagesObject(trimmed)
+		} else {
+			queries, err = parsePackagesArray(data)
+		}
+	default:
+		return nil, fmt.Errorf("unknown packages format '%s'", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse packages %s from '%s': %v", format, name, err)
+	}
+
+	return queries, nil
+}
+
+// MergeQueries appends more to queries, collapsing entries with the same name, version and integrity.
+// When both copies carry metadata the higher severity wins and missing fields are filled in;
+// every contributing source is recorded in Sources.
+func MergeQueries(queries, more []types.PackageQuery) []types.PackageQuery {
+	index := make(map[string]int, len(queries))
+	for i, query := range queries {
+		index[queryKey(query)] = i
+	}
+
+	for _, query := range more {
+		if len(query.Sources) == 0 && query.Source != "" {
+			query.Sources = []string{query.Source}
+		}
+
+		key := queryKey(query)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(queries)
+			queries = append(queries, query)
+			continue
+		}
+
+		existing := &queries[i]
+		if types.SeverityRank(query.Severity) > types.SeverityRank(existing.Severity) {
+			existing.Severity = query.Severity
+		}
+		if existing.Advisory == "" {
+			existing.Advisory = query.Advisory
+		}
+		if existing.Note == "" {
+			existing.Note = query.Note
+		}
+		for _, source := range query.Sources {
+			existing.Sources = appendUnique(existing.Sources, source)
+		}
+	}
+
+	return queries
+}
+
+// queryKey identifies a query for de-duplication: its name, version and IoC hash
+func queryKey(query types.PackageQuery) string {
+	return query.Name + "@" + query.Version + "#" + query.Integrity
+}
+
+// listFormats maps list file extensions to the format they are parsed as
+var listFormats = map[string]string{
+	".json": "json",
+	".csv":  "csv",
+	".txt":  "text",
+	".yaml": "yaml",
+	".yml":  "yaml",
+}
+
+// ListFormat returns the format of a bad-package list file from its extension, or "" when the
+// extension isn't one of a list
+func ListFormat(path string) string {
+	return listFormats[strings.ToLower(filepath.Ext(path))]
+}
+
+// appendUnique appends s to list unless it's already there
+func appendUnique(list []string, s string) []string {
+	for _, item := range list {
+		if item == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// parsePackagesText parses one "package@version" entry per line, as published in advisory
+// blog posts. Blank lines and lines starting with # are ignored and duplicates are collapsed.
+func parsePackagesText(data []byte) ([]types.PackageQuery, error) {
+	var queries []types.PackageQuery
+	seen := make(map[string]bool)
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
+		}
+		seen[line] = true
+
+		query, err := ParseQuery(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid entry '%s': %v", i+1, line, err)
+		}
+		queries = append(queries, query)
+	}
+
+	return queries, nil
+}
+
+// parsePackagesCSV parses name,version,severity,reference rows. A header row naming the
+// columns is optional and allows any column order; rows without a version match any version.
+func parsePackagesCSV(data []byte) ([]types.PackageQuery, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	columns := map[string]int{"name": 0, "version": 1, "severity": 2, "reference": 3}
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var queries []types.PackageQuery
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row, _ := reader.FieldPos(0)
+
+		if first && strings.EqualFold(strings.TrimSpace(record[0]), "name") {
+			columns = make(map[string]int)
+			for i, header := range record {
+				header = strings.ToLower(strings.TrimSpace(header))
+				if header == "advisory" || header == "url" {
+					header = "reference"
+				}
+				columns[header] = i
+			}
+			continue
+		}
+
+		entry := packageEntry{
+			Name:      field(record, "name"),
+			Version:   field(record, "version"),
+			Severity:  field(record, "severity"),
+			Advisory:  field(record, "reference"),
+			Note:      field(record, "note"),
+			Integrity: field(record, "integrity"),
+		}
+		if entry.Name == "" {
+			return nil, fmt.Errorf("row %d: missing package name", row)
+		}
+		// Accept "package@version" in the name column when the version column is empty
+		if entry.Version == "" && strings.LastIndex(entry.Name, "@") > 0 {
+			query, err := ParseQuery(entry.Name)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %v", row, err)
+			}
+			entry.Name, entry.Version = query.Name, query.Version
+		}
+
+		query, err := entry.query()
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", row, err)
+		}
+		queries = append(queries, query)
+	}
+
+	return queries, nil
+}
+
+// parsePackagesArray parses an array mixing "package@version" strings and entry objects
+func parsePackagesArray(data []byte) ([]types.PackageQuery, error) {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(data, &elements); err != nil {
+		return nil, err
+	}
+
+	var queries []types.PackageQuery
+	for i, element := range elements {
+		var spec string
+		if err := json.Unmarshal(element, &spec); err == nil {
+			query, err := ParseQuery(spec)
+			if err != nil {
+				return nil, fmt.Errorf("invalid entry '%s': %v", spec, err)
+			}
+			queries = append(queries, query)
+			continue
+		}
+
+		var entry packageEntry
+		if err := json.Unmarshal(element, &entry); err != nil {
+			return nil, fmt.Errorf("invalid entry at index %d: expected a \"package@version\" string or an object", i)
+		}
+		query, err := entry.query()
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry at index %d: %v", i, err)
+		}
+		queries = append(queries, query)
+	}
+
+	return queries, nil
+}
+
+// query validates an entry object and converts it into a package query
+func (e packageEntry) query() (types.PackageQuery, error) {
+	if e.Name == "" {
+		return types.PackageQuery{}, fmt.Errorf("missing name")
+	}
+	if err := validateName(e.Name); err != nil {
+		return types.PackageQuery{}, err
+	}
+	if err := validateVersion(e.Name, e.Version); err != nil {
+		return types.PackageQuery{}, err
+	}
+	if err := validateIntegrity(e.Name, e.Integrity); err != nil {
+		return types.PackageQuery{}, err
+	}
+	query := types.PackageQuery{
+		Name:      e.Name,
+		Version:   anyVersion(e.Version),
+		Advisory:  e.Advisory,
+		Note:      e.Note,
+		Integrity: e.Integrity,
+	}
+	if e.Severity != "" {
+		severity, ok := types.NormalizeSeverity(e.Severity)
+		if !ok {
+			return types.PackageQuery{}, fmt.Errorf("unknown severity '%s' for '%s' (expected one of %s)",
+				e.Severity, e.Name, strings.Join(types.Severities, ", "))
+		}
+		query.Severity = severity
+	}
+	return query, nil
+}
+
+// parsePackagesObject expands {"name": ["1.0.0", "1.0.1"]} into one query per version,
+// keeping the file's key order so versions of one package stay together in the output
+func parsePackagesObject(data []byte) ([]types.PackageQuery, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	var queries []types.PackageQuery
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name := tok.(string)
+
+		var versions []string
+		if err := dec.Decode(&versions); err != nil {
+			return nil, fmt.Errorf("invalid entry for '%s': expected an array of version strings", name)
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("invalid entry for '%s': no versions listed", name)
+		}
+		if err := validateName(name); err != nil {
+			return nil, err
+		}
+		for _, version := range versions {
+			if err := validateVersion(name, version); err != nil {
+				return nil, err
+			}
+			queries = append(queries, types.PackageQuery{Name: name, Version: anyVersion(version)})
+		}
+	}
+
+	return queries, nil
+}
+
+// yamlPackageGroup is the mapping form of a YAML list entry: a package name mapped to its bad
+// versions plus metadata shared by all of them
+type yamlPackageGroup struct {
+	Versions []string `yaml:"versions"`
+	Severity string   `yaml:"severity"`
+	Advisory string   `yaml:"advisory"`
+	Note     string   `yaml:"note"`
+}
+
+// parsePackagesYAML parses a YAML list: either a sequence mixing "package@version" strings and
+// entry mappings (as in the JSON array form), or a mapping from package name to a sequence of
+// versions or to a group with versions, severity, advisory and note. Errors name the YAML line.
+func parsePackagesYAML(data []byte) ([]types.PackageQuery, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	root := doc.Content[0]
+	var queries []types.PackageQuery
+	switch root.Kind {
+	case yaml.SequenceNode:
+		for _, node := range root.Content {
+			query, err := yamlSequenceEntry(node)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", node.Line, err)
+			}
+			queries = append(queries, query)
+		}
+
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			key, value := root.Content[i], root.Content[i+1]
+			group, err := yamlMappingEntry(key.Value, value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", value.Line, err)
+			}
+			for _, version := range group.Versions {
+				query, err := packageEntry{
+					Name:     key.Value,
+					Version:  version,
+					Severity: group.Severity,
+					Advisory: group.Advisory,
+					Note:     group.Note,
+				}.query()
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", value.Line, err)
+				}
+				queries = append(queries, query)
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("line %d: expected a sequence of entries or a mapping of package names", root.Line)
+	}
+
+	return queries, nil
+}
+
+// yamlSequenceEntry converts one element of the sequence form
+func yamlSequenceEntry(node *yaml.Node) (types.PackageQuery, error) {
+	if node.Kind == yaml.ScalarNode {
+		query, err := ParseQuery(node.Value)
+		if err != nil {
+			return types.PackageQuery{}, fmt.Errorf("invalid entry '%s': %v", node.Value, err)
+		}
+		return query, nil
+	}
+
+	var entry packageEntry
+	if node.Kind != yaml.MappingNode || node.Decode(&entry) != nil {
+		return types.PackageQuery{}, fmt.Errorf("expected a \"package@version\" string or a mapping")
+	}
+	return entry.query()
+}
+
+// yamlMappingEntry decodes the value under a package name in the mapping form
+func yamlMappingEntry(name string, node *yaml.Node) (yamlPackageGroup, error) {
+	var group yamlPackageGroup
+	var err error
+	if node.Kind == yaml.SequenceNode {
+		err = node.Decode(&group.Versions)
+	} else {
+		err = node.Decode(&group)
+	}
+	if err != nil {
+		return group, fmt.Errorf("invalid entry for '%s': expected a sequence of versions or a mapping with versions", name)
+	}
+	if len(group.Versions) == 0 {
+		return group, fmt.Errorf("invalid entry for '%s': no versions listed", name)
+	}
+	return group, nil
+}
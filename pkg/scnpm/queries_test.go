@@ -0,0 +1,293 @@
+package scnpm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    types.PackageQuery
+		wantErr bool
+	}{
+		{
+			name:  "simple package",
+			input: "react@18.2.0",
+			want: types.PackageQuery{
+				Name:    "react",
+				Version: "18.2.0",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "scoped package",
+			input: "@types/node@18.0.0",
+			want: types.PackageQuery{
+				Name:    "@types/node",
+				Version: "18.0.0",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "package with complex version",
+			input: "package@^1.2.3",
+			want: types.PackageQuery{
+				Name:    "package",
+				Version: "^1.2.3",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "version range",
+			input: "lodash@>=1.2.0 <1.3.0",
+			want: types.PackageQuery{
+				Name:    "lodash",
+				Version: ">=1.2.0 <1.3.0",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "version alternatives",
+			input: "event-stream@3.3.6||3.3.7",
+			want: types.PackageQuery{
+				Name:    "event-stream",
+				Version: "3.3.6||3.3.7",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "invalid version alternative",
+			input:   "event-stream@3.3.6||3.3.x.1",
+			wantErr: true,
+		},
+		{
+			name:    "invalid version range",
+			input:   "lodash@>=1.2.0 <1.3.x.4",
+			wantErr: true,
+		},
+		{
+			name:  "bare name matches any version",
+			input: "react",
+			want: types.PackageQuery{
+				Name:    "react",
+				Version: "",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "scoped bare name",
+			input: "@ctrl/tinycolor",
+			want: types.PackageQuery{
+				Name:    "@ctrl/tinycolor",
+				Version: "",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "scoped bare name with a trailing @",
+			input: "@types/node@",
+			want: types.PackageQuery{
+				Name:    "@types/node",
+				Version: "",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "wildcard version",
+			input: "node-ipc@*",
+			want: types.PackageQuery{
+				Name:    "node-ipc",
+				Version: "",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "scope wildcard",
+			input: "@ctrl/*@>=4.0.0",
+			want: types.PackageQuery{
+				Name:    "@ctrl/*",
+				Version: ">=4.0.0",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "regex with @",
+			input: "re:@(acme|internal)/.*",
+			want: types.PackageQuery{
+				Name:    "re:@(acme|internal)/.*",
+				Version: "",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "invalid regex",
+			input:   "re:(",
+			wantErr: true,
+		},
+		{
+			name:  "glob with any version",
+			input: "*-loader@*",
+			want: types.PackageQuery{
+				Name:    "*-loader",
+				Version: "",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "unscoped wildcard",
+			input:   "*",
+			wantErr: true,
+		},
+		{
+			name:    "wildcard over every scope",
+			input:   "@*/*",
+			wantErr: true,
+		},
+		{
+			name:    "wildcard around punctuation",
+			input:   "*-*",
+			wantErr: true,
+		},
+		{
+			name:    "wildcard around a dot",
+			input:   "*.*",
+			wantErr: true,
+		},
+		{
+			name:    "too short a literal part",
+			input:   "a*",
+			wantErr: true,
+		},
+		{
+			name:    "wildcard scope with a short name",
+			input:   "@*/x*",
+			wantErr: true,
+		},
+		{
+			name:  "short literal scope",
+			input: "@rx/*",
+			want: types.PackageQuery{
+				Name:    "@rx/*",
+				Version: "",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "wildcard scope with a literal name",
+			input: "@*/core-js",
+			want: types.PackageQuery{
+				Name:    "@*/core-js",
+				Version: "",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "invalid format - scope without name",
+			input:   "@ctrl",
+			wantErr: true,
+		},
+		{
+			name:  "multiple @ symbols in version",
+			input: "package@1.0.0@beta",
+			want: types.PackageQuery{
+				Name:    "package",
+				Version: "1.0.0@beta",
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuery(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && (got.Name != tt.want.Name || got.Version != tt.want.Version) {
+				t.Errorf("ParseQuery() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueries(t *testing.T) {
+	tests := []struct {
+		name, format, data string
+		want               string // name@version of each query
+	}{
+		{"badpak.json", "", `["evil@1.0.0", {"name": "left-pad", "severity": "high"}]`, "evil@1.0.0,left-pad@"},
+		{"badpak.json", "", `{"event-stream": ["3.3.6", "3.3.7"]}`, "event-stream@3.3.6,event-stream@3.3.7"},
+		{"list.txt", "", "# advisory\nevil@1.0.0\n\nevil@1.0.0\n", "evil@1.0.0"},
+		{"list.csv", "", "name,version\nevil,1.0.0\n", "evil@1.0.0"},
+		{"list.yaml", "", "evil:\n  - 1.0.0\n  - 1.0.1\n", "evil@1.0.0,evil@1.0.1"},
+		{"https://example.com/list", "", `["evil@1.0.0"]`, "evil@1.0.0"},
+		{"https://example.com/list", "", "evil@1.0.0\n", "evil@1.0.0"},
+		{"list.json", "text", "evil@1.0.0\n", "evil@1.0.0"},
+	}
+
+	for _, tt := range tests {
+		queries, err := ParseQueries(tt.name, tt.format, []byte(tt.data))
+		if err != nil {
+			t.Errorf("ParseQueries(%s, %q) error = %v", tt.name, tt.format, err)
+			continue
+		}
+		var got []string
+		for _, query := range queries {
+			got = append(got, query.Name+"@"+query.Version)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("ParseQueries(%s, %q) = %v, want %s", tt.name, tt.format, got, tt.want)
+		}
+	}
+
+	for _, data := range []string{`["evil@>=1.2.0 <1.3.x.4"]`, `[{"name": "evil", "severity": "dire"}]`, `[42]`} {
+		if _, err := ParseQueries("badpak.json", "", []byte(data)); err == nil {
+			t.Errorf("ParseQueries(%s) succeeded, want an error", data)
+		}
+	}
+	if _, err := ParseQueries("badpak.json", "toml", []byte(`[]`)); err == nil {
+		t.Error("ParseQueries() in an unknown format succeeded, want an error")
+	}
+}
+
+func TestListFormat(t *testing.T) {
+	for path, want := range map[string]string{"badpak.json": "json", "LIST.YML": "yaml", "iocs.csv": "csv", "list.txt": "text", "package-lock": "", "evil@1.0.0": ""} {
+		if got := ListFormat(path); got != want {
+			t.Errorf("ListFormat(%s) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMergeQueries(t *testing.T) {
+	first := []types.PackageQuery{
+		{Name: "lodash", Version: "4.17.20", Severity: types.SeverityModerate, Source: "team.json"},
+		{Name: "debug", Version: "4.4.2", Source: "team.json"},
+	}
+	second := []types.PackageQuery{
+		{Name: "lodash", Version: "4.17.20", Severity: types.SeverityCritical, Advisory: "GHSA-1", Source: "builtin"},
+		{Name: "lodash", Version: "4.17.19", Source: "builtin"},
+		{Name: "debug", Version: "4.4.2", Severity: types.SeverityLow, Note: "from second list", Source: "builtin"},
+	}
+
+	got := MergeQueries(MergeQueries(nil, first), second)
+	got = MergeQueries(got, []types.PackageQuery{{Name: "debug", Version: "4.4.2", Source: "team.json"}})
+	want := []types.PackageQuery{
+		{Name: "lodash", Version: "4.17.20", Severity: types.SeverityCritical, Advisory: "GHSA-1", Source: "team.json", Sources: []string{"team.json", "builtin"}},
+		{Name: "debug", Version: "4.4.2", Severity: types.SeverityLow, Note: "from second list", Source: "team.json", Sources: []string{"team.json", "builtin"}},
+		{Name: "lodash", Version: "4.17.19", Source: "builtin", Sources: []string{"builtin"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("MergeQueries() returned %d queries, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("MergeQueries()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,553 @@
+// Package scnpm scans npm lockfiles for bad packages, the way the scnpm command does, for Go
+// programs that would rather not run the command and parse its output. Scan takes the
+// lockfiles, the bad-package queries and the options of a scan and returns a Report; Write
+// renders it in any of the command's output formats. Nothing in the package prints to stdout
+// or exits; problems are returned as errors, and caveats as the report's warnings.
+//
+//	queries, err := scnpm.ParseQueries("badpak.json", "", data)
+//	...
+//	report, err := scnpm.Scan(ctx, scnpm.ScanRequest{
+//		Lockfiles: []scnpm.Lockfile{{Name: "package-lock.json", Reader: file}},
+//		Queries:   queries,
+//	})
+//	...
+//	if report.Totals.Risks > 0 { ... }
+package scnpm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"scnpm/pkg/baseline"
+	"scnpm/pkg/ignore"
+	"scnpm/pkg/lockfile"
+	"scnpm/pkg/output"
+	"scnpm/pkg/scanner"
+	"scnpm/pkg/types"
+	"scnpm/pkg/typosquat"
+	"scnpm/pkg/workspace"
+)
+
+// Lockfile is a lockfile to scan
+type Lockfile struct {
+	// Name identifies the lockfile in results and warnings. Its base name picks the format when
+	// it's package-lock.json, npm-shrinkwrap.json, yarn.lock or pnpm-lock.yaml; otherwise the
+	// content does.
+	Name   string
+	Reader io.Reader
+	// NoLines drops line numbers from the results, for content that didn't come from a file
+	// the reader of the report can open
+	NoLines bool
+}
+
+// Checks are the lockfile-wide checks of a scan, which report packages for what they are
+// rather than for matching a query. All are off in the zero value.
+type Checks struct {
+	NearMatch         bool     // Installed packages 1-2 edits from a query's name
+	Typosquat         bool     // Installed packages a small edit away from a popular package
+	TyposquatDistance int      // Largest edit distance Typosquat reports; 0 for the default
+	ScanScripts       bool     // Suspicious commands in lifecycle scripts
+	DetectScripts     bool     // Every package with install scripts
+	FlagLicenses      []string // SPDX identifiers of the licenses to report
+	RequireIntegrity  bool     // Entries without an integrity hash, or with a weak one
+	MinIntegrityAlgo  string   // Weakest algorithm RequireIntegrity accepts; "" for sha512
+	NonRegistry       bool     // Packages resolved from git or a remote tarball, with details
+	Sources           bool     // Every package installed from somewhere other than the registry
+}
+
+// Options tune a scan. The zero value reports every query, in the order given, against every
+// installed package.
+type Options struct {
+	Filter          scanner.FilterConfig
+	Checks          Checks
+	DefaultSeverity string // Severity of the queries that don't carry one
+	MinSeverity     string // Queries below this severity are left out of the report
+	SortBySeverity  bool   // Report the most severe queries first, rather than in the given order
+
+	Ignore   []ignore.Rule      // Accepted findings, reported as suppressed
+	Baseline *baseline.Baseline // Known findings, reported but not counted as risks
+
+	// Manifest lets a lockfile be a package.json, scanned for its declared dependency ranges
+	Manifest bool
+	// Dir is the directory the lockfiles were installed in, if any. Checks.ScanScripts reads
+	// the script bodies lockfiles don't record from the node_modules under it.
+	Dir string
+	// Workspaces attributes findings to the workspaces declared by Dir's package.json and
+	// also scans the workspaces with a lockfile of their own
+	Workspaces bool
+	// TagLockfiles records on every instance which lockfile it was found in; that's always
+	// done when several lockfiles are scanned
+	TagLockfiles bool
+	// Concurrency is how many lockfiles are read and scanned at once; 0 for runtime.NumCPU()
+	Concurrency int
+
+	// Lookup, when set, is called with every parsed lockfile and returns more queries for it,
+	// from online advisory sources for instance, with caveats about what it couldn't look up.
+	// With a Concurrency above 1 it's called for several lockfiles at once.
+	Lookup func(ctx context.Context, packageLock *types.PackageLock) ([]types.PackageQuery, []string)
+	// Emit, when set, is handed the finished results of each lockfile as soon as it and those
+	// before it are scanned, with that lockfile's warnings, instead of collecting them in the
+	// report. It's called in the order of the lockfiles, one call at a time.
+	Emit func(lockfile string, results []types.ScanResult, warnings []string)
+	// Progress, when set, is called as each lockfile is done with, skipped ones included, with
+	// how many of the total are done and how many queries were found so far, before suppressions
+	// and the baseline. It's called in the order of the lockfiles, one call at a time.
+	Progress func(done, total, risks int)
+}
+
+// ScanRequest is what Scan scans: the lockfiles, for the queries, with the options
+type ScanRequest struct {
+	Lockfiles []Lockfile
+	Queries   []types.PackageQuery
+	Options   Options
+}
+
+// Report is the outcome of a scan
+type Report struct {
+	// Results has one result per query, whether or not it was found, followed by the findings
+	// of the lockfile-wide checks. Results of several lockfiles are merged.
+	Results   []types.ScanResult
+	Totals    output.Totals           // Counts of Results by outcome
+	Inventory []types.PackageInstance // Every installed package
+	Warnings  []string                // Caveats about what the scan couldn't see or do
+	Lockfiles []string                // Names of the scanned lockfiles, in order
+	Queries   int                     // Number of queries scanned for, lookups included
+	ScannedAt time.Time
+	Duration  time.Duration
+}
+
+// Scan scans the lockfiles of request for its queries, Options.Concurrency at a time. Results are
+// merged in the order of the lockfiles, whichever finishes first. A lockfile that can't be read
+// or parsed is skipped with a warning, unless none can be, which is an error, as is a request
+// without any. With Options.Emit, the results and warnings of each lockfile go to it, and the
+// report only carries the warnings about the whole scan, such as skipped lockfiles and stale
+// baseline entries.
+//
+// When ctx is done before the scan is, Scan stops and returns the report of what it scanned so
+// far along with ctx.Err(). The lockfile it was scanning is reported as far as it got: the
+// queries it got through, without its lockfile-wide checks if it didn't get to them.
+func Scan(ctx context.Context, request ScanRequest) (*Report, error) {
+	if len(request.Lockfiles) == 0 {
+		return nil, fmt.Errorf("no lockfiles to scan")
+	}
+	options := request.Options
+	report := &Report{ScannedAt: time.Now()}
+	tag := options.TagLockfiles || len(request.Lockfiles) > 1
+	reported := make(map[string]bool) // Expired suppressions already warned about
+
+	var results, checks []types.ScanResult
+	if tag && options.Emit == nil {
+		results = scanner.ScanPackages(&types.PackageLock{}, request.Queries, options.Filter)
+	}
+	queried := make(map[string]bool)
+	for _, query := range request.Queries {
+		queried[queryKey(query)] = true
+	}
+	concurrency := concurrencyOf(options)
+	var interrupted, failed error
+	collected := 0
+	risky := make(map[string]bool) // Queries found so far, for Options.Progress
+	scanLockfiles(ctx, request.Lockfiles, request.Queries, options, concurrency, func(outcome lockfileOutcome) {
+		collected++
+		if options.Progress != nil {
+			defer func() { options.Progress(collected, len(request.Lockfiles), len(risky)) }()
+		}
+		file, scan, err := request.Lockfiles[outcome.index], outcome.scan, outcome.err
+		// A lockfile cut short by ctx still has results to report
+		if err != nil && err != ctx.Err() {
+			if failed == nil {
+				failed = err
+			}
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%v; skipped it", err))
+			return
+		}
+		if err != nil {
+			interrupted = err
+		}
+		report.Lockfiles = append(report.Lockfiles, file.Name)
+		for _, query := range scan.queries {
+			queried[queryKey(query)] = true
+		}
+		for _, result := range scan.results {
+			if result.Found {
+				risky[queryKey(result.Package)] = true
+			}
+		}
+		if tag {
+			for _, group := range [][]types.ScanResult{scan.results, scan.checks} {
+				for i := range group {
+					for j := range group[i].Instances {
+						group[i].Instances[j].Lockfile = file.Name
+					}
+				}
+			}
+			for i := range scan.inventory {
+				scan.inventory[i].Lockfile = file.Name
+			}
+		}
+
+		if options.Emit != nil {
+			finished, expired := finish(scan.results, scan.checks, options, file.Name)
+			for _, warning := range expired {
+				if !reported[warning] {
+					reported[warning] = true
+					scan.warnings = append(scan.warnings, warning)
+				}
+			}
+			options.Emit(file.Name, finished, scan.warnings)
+		} else {
+			if results == nil {
+				results = scan.results
+			} else {
+				results = scanner.MergeResults(results, scan.results)
+			}
+			checks = append(checks, scan.checks...)
+			report.Inventory = append(report.Inventory, scan.inventory...)
+			report.Warnings = append(report.Warnings, scan.warnings...)
+		}
+	})
+	if collected < len(request.Lockfiles) {
+		// The rest were never started
+		interrupted = ctx.Err()
+	}
+	if failed != nil && len(report.Lockfiles) == 0 && interrupted == nil {
+		return nil, failed
+	}
+	report.Queries = len(queried)
+
+	if options.Emit == nil {
+		var expired []string
+		report.Results, expired = finish(results, checks, options, request.Lockfiles[0].Name)
+		report.Warnings = append(report.Warnings, expired...)
+		report.Totals = output.CountResults(report.Results)
+	}
+	// A partial scan can't tell which baseline entries are stale
+	if options.Baseline != nil && interrupted == nil {
+		for _, fingerprint := range options.Baseline.Stale() {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("baseline entry %s no longer matches a finding; prune it with 'scnpm baseline write'", fingerprint))
+		}
+	}
+	report.Duration = time.Since(report.ScannedAt)
+	return report, interrupted
+}
+
+// concurrencyOf returns how many lockfiles options scan at once
+func concurrencyOf(options Options) int {
+	if options.Concurrency <= 0 {
+		return runtime.NumCPU()
+	}
+	return options.Concurrency
+}
+
+// lockfileOutcome is a lockfile scanned by scanLockfiles, by its index in the request
+type lockfileOutcome struct {
+	index int
+	scan  lockfileScan
+	err   error
+}
+
+// scanLockfiles scans lockfiles with up to concurrency at once and hands each outcome to collect,
+// one call at a time, in the order of lockfiles whatever order they finish in. A lockfile holds
+// its slot until it's collected, so that at most concurrency scans are in memory even when an
+// early lockfile is slow. No more lockfiles are started once ctx is done.
+func scanLockfiles(ctx context.Context, lockfiles []Lockfile, queries []types.PackageQuery, options Options, concurrency int, collect func(lockfileOutcome)) {
+	slots := make(chan struct{}, concurrency)
+	outcomes := make(chan lockfileOutcome)
+	go func() {
+		var wg sync.WaitGroup
+		for i := range lockfiles {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				scan, err := scanLockfile(ctx, lockfiles[i], queries, options)
+				outcomes <- lockfileOutcome{index: i, scan: scan, err: err}
+			}(i)
+		}
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	pending := make(map[int]lockfileOutcome)
+	next := 0
+	for outcome := range outcomes {
+		pending[outcome.index] = outcome
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			collect(ready)
+			next++
+			<-slots
+		}
+	}
+}
+
+// lockfileScan is what scanning a single lockfile found, before the results are finished
+type lockfileScan struct {
+	results   []types.ScanResult
+	checks    []types.ScanResult
+	inventory []types.PackageInstance
+	queries   []types.PackageQuery // Those of Options.Lookup
+	warnings  []string
+}
+
+// scanLockfile reads, parses and scans one lockfile. When ctx is done it returns ctx.Err() and
+// what it scanned so far.
+func scanLockfile(ctx context.Context, file Lockfile, queries []types.PackageQuery, options Options) (lockfileScan, error) {
+	var scan lockfileScan
+	data, err := io.ReadAll(file.Reader)
+	if err != nil {
+		return scan, fmt.Errorf("failed to read '%s': %v", file.Name, err)
+	}
+	packageLock, err := ParseLockfile(file.Name, data, options.Manifest)
+	if err != nil {
+		return scan, fmt.Errorf("failed to parse '%s': %w", file.Name, err)
+	}
+	if file.NoLines {
+		packageLock.Lines = nil
+	}
+	slog.Debug("lockfile loaded", "path", file.Name, "lockfileVersion", packageLock.LockfileVersion,
+		"packages", len(packageLock.Packages), "dependencies", len(packageLock.Dependencies))
+	if lockfile.IsEmpty(packageLock) {
+		scan.warnings = append(scan.warnings, fmt.Sprintf("'%s' contains no installed packages; nothing was actually scanned", file.Name))
+	}
+
+	if options.Lookup != nil {
+		var warnings []string
+		scan.queries, warnings = options.Lookup(ctx, packageLock)
+		scan.warnings = append(scan.warnings, warnings...)
+		queries = MergeQueries(CopyQueries(queries), scan.queries)
+		if err := ctx.Err(); err != nil {
+			return scan, err
+		}
+	}
+	if options.Checks.ScanScripts && options.Dir != "" && loadInstalledScripts(options.Dir, packageLock) == 0 {
+		scan.warnings = append(scan.warnings, "the script check found no script bodies to check; install the project (node_modules) next to the lockfile first")
+	}
+
+	scan.results, err = scanner.ScanPackagesContext(ctx, packageLock, queries, options.Filter)
+	if err != nil {
+		return scan, err
+	}
+	scan.checks = options.Checks.run(packageLock, queries, options.Filter)
+	scan.inventory = scanner.Inventory(packageLock)
+	if options.Workspaces && options.Dir != "" {
+		scan.results, err = scanWorkspaces(ctx, options.Dir, packageLock, queries, options, scan.results)
+		if err != nil && err != ctx.Err() {
+			return scan, fmt.Errorf("failed to scan workspaces: %v", err)
+		}
+		return scan, err
+	}
+	return scan, nil
+}
+
+// CopyQueries copies queries so that MergeQueries can update the copy in place, sources
+// included, without touching the caller's. Callers that share one query list between scans, such
+// as a server, merge their extra entries into a copy.
+func CopyQueries(queries []types.PackageQuery) []types.PackageQuery {
+	copied := make([]types.PackageQuery, len(queries))
+	for i, query := range queries {
+		query.Sources = query.Sources[:len(query.Sources):len(query.Sources)]
+		copied[i] = query
+	}
+	return copied
+}
+
+// run runs the enabled checks on a lockfile. Only NearMatch looks at the bad-package queries;
+// the results carry the check's name and are reported separately.
+func (c Checks) run(packageLock *types.PackageLock, queries []types.PackageQuery, config scanner.FilterConfig) []types.ScanResult {
+	var results []types.ScanResult
+	if c.NearMatch {
+		results = append(results, scanner.NearMatches(packageLock, queries, config)...)
+	}
+	if c.Typosquat {
+		distance := c.TyposquatDistance
+		if distance == 0 {
+			distance = typosquat.DefaultMaxDistance
+		}
+		results = append(results, scanner.Typosquats(packageLock, distance)...)
+	}
+	if c.ScanScripts {
+		results = append(results, scanner.SuspiciousScripts(packageLock)...)
+	}
+	if c.DetectScripts {
+		results = append(results, scanner.InstallScripts(packageLock)...)
+	}
+	if len(c.FlagLicenses) > 0 {
+		results = append(results, scanner.LicenseViolations(packageLock, c.FlagLicenses, config)...)
+	}
+	if c.RequireIntegrity {
+		algorithm := c.MinIntegrityAlgo
+		if algorithm == "" {
+			algorithm = "sha512"
+		}
+		results = append(results, scanner.MissingIntegrity(packageLock)...)
+		results = append(results, scanner.WeakIntegrity(packageLock, algorithm)...)
+	}
+	if c.NonRegistry {
+		results = append(results, scanner.NonRegistry(packageLock)...)
+	}
+	if c.Sources {
+		results = append(results, scanner.NonRegistryPackages(packageLock)...)
+	}
+	return results
+}
+
+// Enabled reports whether any check is on, which makes a scan worthwhile even without queries
+func (c Checks) Enabled() bool {
+	return c.NearMatch || c.Typosquat || c.ScanScripts || c.DetectScripts || len(c.FlagLicenses) > 0 ||
+		c.RequireIntegrity || c.NonRegistry || c.Sources
+}
+
+// finish turns the raw results of lockfile into those reported: patterns expanded into a result
+// per matching package, severities applied, checks appended and suppressions and the baseline
+// marked. It also returns a warning per expired suppression.
+func finish(results, checks []types.ScanResult, options Options, lockfile string) ([]types.ScanResult, []string) {
+	results = scanner.ExpandPatterns(results)
+	logFallbacks(results)
+	results = append(applySeverity(results, options), checks...)
+
+	var warnings []string
+	for _, rule := range ignore.Apply(results, options.Ignore, time.Now()) {
+		warnings = append(warnings, fmt.Sprintf("suppression of '%s' (%s) expired on %s; its findings are reported again", rule.Name, rule.Reason, rule.Expires))
+	}
+	if options.Baseline != nil {
+		options.Baseline.Apply(results, lockfile)
+	}
+	return results, warnings
+}
+
+// applySeverity gives bad-package results without a severity the default severity, drops those
+// below the minimum and, when sorting by severity, moves the most severe first. Results of
+// equal severity keep their order.
+func applySeverity(results []types.ScanResult, options Options) []types.ScanResult {
+	kept := results[:0:0]
+	for _, result := range results {
+		if result.Package.Severity == "" {
+			result.Package.Severity = options.DefaultSeverity
+		}
+		if options.MinSeverity != "" && types.SeverityRank(result.Package.Severity) < types.SeverityRank(options.MinSeverity) {
+			continue
+		}
+		kept = append(kept, result)
+	}
+	if options.SortBySeverity {
+		sort.SliceStable(kept, func(i, j int) bool {
+			return types.SeverityRank(kept[i].Package.Severity) > types.SeverityRank(kept[j].Package.Severity)
+		})
+	}
+	return kept
+}
+
+// logFallbacks logs every finding whose version wasn't semver and was matched as a plain
+// string, since those matches can't account for ranges or build metadata
+func logFallbacks(results []types.ScanResult) {
+	for _, result := range results {
+		for _, instance := range result.Instances {
+			if !instance.ExactFallback {
+				continue
+			}
+			if instance.IsReference {
+				slog.Info(fmt.Sprintf("compared the declaration '%s' of %s in %s to '%s' as plain strings: not a semver range", instance.Version, instance.Name, instance.Path, result.Package.Version))
+			} else {
+				slog.Info(fmt.Sprintf("compared %s@%s at %s to '%s' as plain strings: not a semver version", instance.Name, instance.Version, instance.Path, result.Package.Version))
+			}
+		}
+	}
+}
+
+// scanWorkspaces attributes results to the workspaces of the project in rootDir and merges in
+// the results of the workspaces that have their own lockfile, Options.Concurrency at a time, with
+// their paths made relative to the root. When ctx is done it returns ctx.Err() and the results
+// merged so far.
+func scanWorkspaces(ctx context.Context, rootDir string, packageLock *types.PackageLock, queries []types.PackageQuery, options Options, results []types.ScanResult) ([]types.ScanResult, error) {
+	workspaces, err := workspace.Discover(rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		for j := range results[i].Instances {
+			instance := &results[i].Instances[j]
+			instance.Workspace = workspace.Attribute(packageLock, workspaces, instance.Path)
+		}
+	}
+
+	var separate []workspace.Workspace
+	var lockfiles []Lockfile
+	for _, ws := range workspaces {
+		if ws.Lockfile == "" {
+			continue
+		}
+		file, err := os.Open(ws.Lockfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lockfile of workspace '%s': %v", ws.Name, err)
+		}
+		defer file.Close()
+		separate = append(separate, ws)
+		lockfiles = append(lockfiles, Lockfile{Name: ws.Lockfile, Reader: file})
+	}
+
+	// The queries already hold the lookups, and the checks only cover the root lockfile
+	wsOptions := options
+	wsOptions.Workspaces, wsOptions.Lookup, wsOptions.Checks = false, nil, Checks{}
+	var failed error
+	scanLockfiles(ctx, lockfiles, queries, wsOptions, concurrencyOf(options), func(outcome lockfileOutcome) {
+		ws := separate[outcome.index]
+		if outcome.err != nil && outcome.err != ctx.Err() {
+			if failed == nil {
+				failed = fmt.Errorf("workspace '%s': %v", ws.Name, outcome.err)
+			}
+			return
+		}
+		workspace.Rebase(outcome.scan.results, ws)
+		results = scanner.MergeResults(results, outcome.scan.results)
+	})
+	if failed != nil {
+		return nil, failed
+	}
+	return results, ctx.Err()
+}
+
+// loadInstalledScripts fills in the script bodies of lockfileVersion 2+ entries from the
+// package.json files installed under root, as lockfiles don't record them, and returns how many
+// entries have scripts. Entries that aren't installed are left alone.
+func loadInstalledScripts(root string, packageLock *types.PackageLock) int {
+	count := 0
+	for path, pkg := range packageLock.Packages {
+		if len(pkg.Scripts) == 0 && strings.HasPrefix(path, "node_modules/") {
+			if data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(path), "package.json")); err == nil {
+				var manifest struct {
+					Scripts map[string]string `json:"scripts"`
+				}
+				if json.Unmarshal(data, &manifest) == nil && len(manifest.Scripts) > 0 {
+					pkg.Scripts = manifest.Scripts
+					packageLock.Packages[path] = pkg
+				}
+			}
+		}
+		if len(pkg.Scripts) > 0 {
+			count++
+		}
+	}
+	return count
+}
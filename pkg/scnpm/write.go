@@ -0,0 +1,39 @@
+package scnpm
+
+import (
+	"fmt"
+	"io"
+
+	"scnpm/pkg/output"
+)
+
+// Formats are the report formats Write renders
+var Formats = []string{"table", "json", "sarif", "html", "cyclonedx", "spdx", "github"}
+
+// Write renders report to w in format, one of Formats. The warnings and inventory of config
+// default to the report's.
+func Write(w io.Writer, format string, report *Report, config output.OutputConfig) error {
+	if config.Warnings == nil {
+		config.Warnings = report.Warnings
+	}
+	if config.Inventory == nil {
+		config.Inventory = report.Inventory
+	}
+	switch format {
+	case "table":
+		return output.OutputTable(w, report.Results, config)
+	case "json":
+		return output.OutputJSON(w, report.Results, config)
+	case "sarif":
+		return output.OutputSARIF(w, report.Results, config)
+	case "html":
+		return output.OutputHTML(w, report.Results, config)
+	case "cyclonedx":
+		return output.OutputCycloneDX(w, report.Results, config)
+	case "spdx":
+		return output.OutputSPDX(w, report.Results, config)
+	case "github":
+		return output.OutputGitHub(w, report.Results, config)
+	}
+	return fmt.Errorf("unknown output format '%s'", format)
+}
@@ -0,0 +1,41 @@
+package scnpm
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"scnpm/pkg/output"
+	"scnpm/pkg/types"
+)
+
+func TestWrite(t *testing.T) {
+	report, err := Scan(context.Background(), ScanRequest{
+		Lockfiles: []Lockfile{{Name: "package-lock.json", Reader: strings.NewReader(packageLockJSON("node_modules/event-stream", "3.3.6"))}},
+		Queries:   []types.PackageQuery{{Name: "event-stream", Version: "3.3.6"}},
+	})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	report.Warnings = []string{"registry unreachable"}
+
+	for _, format := range Formats {
+		var buf bytes.Buffer
+		if err := Write(&buf, format, report, output.OutputConfig{Lockfile: "package-lock.json", ASCII: true}); err != nil {
+			t.Errorf("Write(%s) error = %v", format, err)
+			continue
+		}
+		if !strings.Contains(buf.String(), "event-stream") {
+			t.Errorf("Write(%s) = %q, want the finding", format, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "json", report, output.OutputConfig{}); err != nil || !strings.Contains(buf.String(), "registry unreachable") {
+		t.Errorf("Write(json) = %q, %v, want the report's warnings", buf.String(), err)
+	}
+	if err := Write(&buf, "ndjson", report, output.OutputConfig{}); err == nil {
+		t.Error("Write(ndjson) succeeded, want an error for a format it doesn't render")
+	}
+}
@@ -0,0 +1,107 @@
+// Package scriptscan looks for suspicious commands in npm lifecycle scripts, such as piping a
+// download into a shell or sending environment variables to a remote host, using a table of
+// regular-expression heuristics.
+package scriptscan
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Lifecycle lists the scripts npm runs when a package is installed, in the order it runs them
+var Lifecycle = []string{"preinstall", "install", "postinstall", "preprepare", "prepare", "postprepare"}
+
+// Heuristic is a pattern of suspicious script content
+type Heuristic struct {
+	ID          string         // Stable identifier reported with findings
+	Description string         // What the pattern catches
+	Pattern     *regexp.Regexp // Matched against each script body
+	Implies     []string       // Weaker heuristics not reported for a script this one matched
+}
+
+// Heuristics is the table of patterns scripts are checked against. Patterns are case-insensitive
+// and should match the smallest snippet that shows the problem; each one needs true and false
+// positive cases in the tests.
+var Heuristics = []Heuristic{
+	{
+		ID:          "pipe-to-shell",
+		Description: "downloads a script and pipes it into a shell",
+		Pattern:     regexp.MustCompile(`(?i)\b(curl|wget)\b[^|;&\n]*\|\s*(sudo\s+)?(ba|da|z|k)?sh\b`),
+		Implies:     []string{"remote-fetch"},
+	},
+	{
+		ID:          "remote-fetch",
+		Description: "fetches a URL with curl or wget",
+		Pattern:     regexp.MustCompile(`(?i)\b(curl|wget)\b[^|;&\n]*\bhttps?://\S+`),
+	},
+	{
+		ID:          "base64-exec",
+		Description: "decodes base64 and executes the result",
+		Pattern:     regexp.MustCompile(`(?i)\bbase64\s+(-d|-D|--decode)\b[^;&\n]*\|\s*(ba|da|z|k)?sh\b|\beval\s*\(\s*(atob\s*\(|Buffer\.from\s*\([^)]*['"]base64['"])`),
+	},
+	{
+		ID:          "ssh-write",
+		Description: "writes to ~/.ssh",
+		Pattern:     regexp.MustCompile(`(?i)(>>?|\btee\b|\bcp\b|\bmv\b|writeFileSync|appendFileSync|writeFile|appendFile)[^;&|\n]*(~|\$HOME|\$\{HOME\}|homedir\(\))['"\s/,+]*\.ssh\b`),
+	},
+	{
+		ID:          "env-exfiltration",
+		Description: "sends environment variables to a network tool",
+		Pattern:     regexp.MustCompile(`(?i)(\bprintenv\b|\benv\b|process\.env)[^;&|\n]*\|\s*(curl|wget|nc|ncat|netcat)\b|\b(curl|wget)\b[^|;&\n]*(\$\((print)?env\)|` + "`(print)?env`" + `|JSON\.stringify\(process\.env\))`),
+		Implies:     []string{"remote-fetch"},
+	},
+	{
+		ID:          "reverse-shell",
+		Description: "opens a shell over a network connection",
+		Pattern:     regexp.MustCompile(`(?i)/dev/tcp/|\b(nc|ncat|netcat)\b[^;&|\n]*\s-e\s`),
+	},
+}
+
+// MaxSnippet is the longest snippet a finding quotes; longer matches are cut short with "..."
+const MaxSnippet = 80
+
+// Finding is a heuristic that matched a script
+type Finding struct {
+	Script    string // Name of the script, such as postinstall
+	Heuristic string // ID of the heuristic that matched
+	Snippet   string // Offending part of the script body
+}
+
+// Scan checks the lifecycle scripts among scripts against every heuristic and returns a finding
+// per script and heuristic that matched, in lifecycle order, leaving out heuristics implied by
+// another match. Other scripts (test, build, ...)
+// don't run on install and are ignored.
+func Scan(scripts map[string]string) []Finding {
+	var findings []Finding
+	for _, name := range Lifecycle {
+		body, ok := scripts[name]
+		if !ok {
+			continue
+		}
+		var matched []Finding
+		implied := make(map[string]bool)
+		for _, heuristic := range Heuristics {
+			if match := heuristic.Pattern.FindString(body); match != "" {
+				matched = append(matched, Finding{Script: name, Heuristic: heuristic.ID, Snippet: snippet(match)})
+				for _, id := range heuristic.Implies {
+					implied[id] = true
+				}
+			}
+		}
+		for _, finding := range matched {
+			if !implied[finding.Heuristic] {
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings
+}
+
+// snippet flattens whitespace in a match and shortens it to MaxSnippet runes
+func snippet(match string) string {
+	s := strings.Join(strings.Fields(match), " ")
+	if runes := []rune(s); len(runes) > MaxSnippet {
+		return string(runes[:MaxSnippet-3]) + "..."
+	}
+	return s
+}
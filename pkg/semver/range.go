@@ -0,0 +1,360 @@
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Range is a parsed npm version range: a union ("||") of comparator sets,
+// each of which is an intersection of comparators
+type Range struct {
+	raw  string
+	sets [][]comparator
+}
+
+type comparator struct {
+	op      string // one of "<", "<=", ">", ">=", "="
+	version Version
+}
+
+// partial is a version in range syntax where minor and patch may be missing or wildcards
+type partial struct {
+	major, minor, patch int
+	pre                 []string
+	// number of components given before the first wildcard/omission (0-3)
+	given int
+}
+
+var partialPattern = regexp.MustCompile(`^v?(\d+|[xX*])(?:\.(\d+|[xX*]))?(?:\.(\d+|[xX*]))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// IsRange reports whether a version spec uses range syntax (operators, wildcards, unions,
+// hyphen ranges or partial versions like "1.2") rather than naming a single version
+func IsRange(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return false
+	}
+	if strings.ContainsAny(s, "<>=~^*| \t") {
+		return true
+	}
+	core := s
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+	parts := strings.Split(strings.TrimPrefix(core, "v"), ".")
+	for _, part := range parts {
+		if part == "x" || part == "X" {
+			return true
+		}
+	}
+	if len(parts) < 3 {
+		for _, part := range parts {
+			if _, err := strconv.Atoi(part); err != nil {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// ParseRange parses npm range syntax: exact versions, comparators (<, <=, >, >=, =),
+// hyphen ranges (1.2.3 - 2.3.4), x-ranges (1.2.x, 1.*, *), tilde (~1.2.3),
+// caret (^1.2.3), whitespace-separated intersections and "||" unions.
+func ParseRange(s string) (Range, error) {
+	r := Range{raw: s}
+	for _, part := range strings.Split(s, "||") {
+		set, err := parseComparatorSet(strings.TrimSpace(part))
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid range '%s': %v", s, err)
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+// String returns the range as it was written
+func (r Range) String() string {
+	return r.raw
+}
+
+// Satisfies reports whether v falls within the range. Following npm, a prerelease
+// version only satisfies a comparator set if one of its comparators names the same
+// major.minor.patch with a prerelease tag, so "^1.0.0" does not match "1.1.0-beta".
+func (r Range) Satisfies(v Version) bool {
+	for _, set := range r.sets {
+		if setSatisfies(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersects reports whether some version satisfies both ranges, e.g. whether a dependency
+// declared as "^4.17.0" can resolve to a version in the bad range "<4.17.12". Prerelease
+// exclusion is not taken into account.
+func (r Range) Intersects(other Range) bool {
+	for _, a := range r.sets {
+		for _, b := range other.sets {
+			if satisfiable(append(append([]comparator{}, a...), b...)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// satisfiable reports whether the intersection of comparators admits any version, by
+// narrowing it to its tightest lower and upper bounds
+func satisfiable(set []comparator) bool {
+	var lower, upper *comparator
+	for i := range set {
+		c := &set[i]
+		if c.op == ">" || c.op == ">=" || c.op == "=" {
+			if lower == nil || tighterLower(c, lower) {
+				lower = c
+			}
+		}
+		if c.op == "<" || c.op == "<=" || c.op == "=" {
+			if upper == nil || tighterUpper(c, upper) {
+				upper = c
+			}
+		}
+	}
+	if lower == nil || upper == nil {
+		return true
+	}
+
+	cmp := Compare(lower.version, upper.version)
+	if cmp != 0 {
+		return cmp < 0
+	}
+	return lower.op != ">" && upper.op != "<"
+}
+
+// tighterLower reports whether lower bound a excludes more versions than b
+func tighterLower(a, b *comparator) bool {
+	cmp := Compare(a.version, b.version)
+	return cmp > 0 || (cmp == 0 && a.op == ">")
+}
+
+// tighterUpper reports whether upper bound a excludes more versions than b
+func tighterUpper(a, b *comparator) bool {
+	cmp := Compare(a.version, b.version)
+	return cmp < 0 || (cmp == 0 && a.op == "<")
+}
+
+func setSatisfies(set []comparator, v Version) bool {
+	for _, c := range set {
+		if !c.matches(v) {
+			return false
+		}
+	}
+
+	if !v.IsPrerelease() {
+		return true
+	}
+	for _, c := range set {
+		cv := c.version
+		if cv.IsPrerelease() && cv.Major == v.Major && cv.Minor == v.Minor && cv.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+func (c comparator) matches(v Version) bool {
+	cmp := Compare(v, c.version)
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	default:
+		return cmp == 0
+	}
+}
+
+func parseComparatorSet(s string) ([]comparator, error) {
+	if s == "" || s == "*" || strings.EqualFold(s, "x") || s == "latest" {
+		return []comparator{{op: ">=", version: Version{}}}, nil
+	}
+
+	// Hyphen range: "1.2.3 - 2.3.4"
+	if parts := strings.SplitN(s, " - ", 2); len(parts) == 2 {
+		from, err := parsePartial(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, err
+		}
+		to, err := parsePartial(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		set := []comparator{{op: ">=", version: from.floor()}}
+		if to.given == 3 {
+			set = append(set, comparator{op: "<=", version: to.floor()})
+		} else if to.given > 0 {
+			set = append(set, comparator{op: "<", version: to.bump(to.given)})
+		}
+		return set, nil
+	}
+
+	var set []comparator
+	for _, token := range splitComparators(s) {
+		comparators, err := parseComparator(token)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, comparators...)
+	}
+	return set, nil
+}
+
+// splitComparators splits on whitespace while keeping operators attached to their versions ("> 1.2" -> ">1.2")
+func splitComparators(s string) []string {
+	var tokens []string
+	fields := strings.Fields(s)
+	for i := 0; i < len(fields); i++ {
+		token := fields[i]
+		if strings.Trim(token, "<>=~^") == "" && i+1 < len(fields) {
+			token += fields[i+1]
+			i++
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func parseComparator(token string) ([]comparator, error) {
+	op := ""
+	for _, prefix := range []string{"<=", ">=", "<", ">", "=", "~>", "~", "^"} {
+		if strings.HasPrefix(token, prefix) {
+			op = prefix
+			token = token[len(prefix):]
+			break
+		}
+	}
+
+	p, err := parsePartial(token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "^":
+		return caret(p), nil
+	case "~", "~>":
+		return tilde(p), nil
+	case "", "=":
+		return xRange(p), nil
+	case ">":
+		if p.given == 0 {
+			// >* can never be satisfied
+			return []comparator{{op: "<", version: Version{}}}, nil
+		}
+		if p.given < 3 {
+			return []comparator{{op: ">=", version: p.bump(p.given)}}, nil
+		}
+		return []comparator{{op: ">", version: p.floor()}}, nil
+	case ">=":
+		return []comparator{{op: ">=", version: p.floor()}}, nil
+	case "<":
+		return []comparator{{op: "<", version: p.floor()}}, nil
+	case "<=":
+		if p.given == 0 {
+			return []comparator{{op: ">=", version: Version{}}}, nil
+		}
+		if p.given < 3 {
+			return []comparator{{op: "<", version: p.bump(p.given)}}, nil
+		}
+		return []comparator{{op: "<=", version: p.floor()}}, nil
+	}
+	return nil, fmt.Errorf("unsupported operator '%s'", op)
+}
+
+func parsePartial(s string) (partial, error) {
+	m := partialPattern.FindStringSubmatch(s)
+	if m == nil {
+		return partial{}, fmt.Errorf("invalid version '%s'", s)
+	}
+
+	var p partial
+	numbers := []*int{&p.major, &p.minor, &p.patch}
+	for i, group := range m[1:4] {
+		if group == "" || group == "x" || group == "X" || group == "*" {
+			break
+		}
+		n, err := strconv.Atoi(group)
+		if err != nil {
+			return partial{}, fmt.Errorf("invalid version '%s'", s)
+		}
+		*numbers[i] = n
+		p.given = i + 1
+	}
+	if m[4] != "" {
+		if p.given < 3 {
+			return partial{}, fmt.Errorf("invalid version '%s': prerelease on partial version", s)
+		}
+		p.pre = strings.Split(m[4], ".")
+	}
+	return p, nil
+}
+
+// floor is the lowest version matching the partial (missing components are zero)
+func (p partial) floor() Version {
+	return Version{Major: p.major, Minor: p.minor, Patch: p.patch, Prerelease: p.pre}
+}
+
+// bump increments the component at the given precision and zeroes the rest,
+// e.g. 1.2 bumped at precision 2 is 1.3.0
+func (p partial) bump(precision int) Version {
+	switch precision {
+	case 1:
+		return Version{Major: p.major + 1}
+	case 2:
+		return Version{Major: p.major, Minor: p.minor + 1}
+	default:
+		return Version{Major: p.major, Minor: p.minor, Patch: p.patch + 1}
+	}
+}
+
+func xRange(p partial) []comparator {
+	switch p.given {
+	case 0:
+		return []comparator{{op: ">=", version: Version{}}}
+	case 3:
+		return []comparator{{op: "=", version: p.floor()}}
+	default:
+		return []comparator{{op: ">=", version: p.floor()}, {op: "<", version: p.bump(p.given)}}
+	}
+}
+
+func tilde(p partial) []comparator {
+	switch p.given {
+	case 0:
+		return []comparator{{op: ">=", version: Version{}}}
+	case 1:
+		return []comparator{{op: ">=", version: p.floor()}, {op: "<", version: p.bump(1)}}
+	default:
+		return []comparator{{op: ">=", version: p.floor()}, {op: "<", version: p.bump(2)}}
+	}
+}
+
+func caret(p partial) []comparator {
+	switch {
+	case p.given == 0:
+		return []comparator{{op: ">=", version: Version{}}}
+	case p.major != 0 || p.given == 1:
+		return []comparator{{op: ">=", version: p.floor()}, {op: "<", version: p.bump(1)}}
+	case p.minor != 0 || p.given == 2:
+		return []comparator{{op: ">=", version: p.floor()}, {op: "<", version: p.bump(2)}}
+	default:
+		return []comparator{{op: ">=", version: p.floor()}, {op: "<", version: p.bump(3)}}
+	}
+}
@@ -0,0 +1,147 @@
+// Package semver implements the subset of node-semver that scnpm needs: parsing and
+// comparing versions, and evaluating npm range syntax against them.
+package semver
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version. Build metadata is kept for display but,
+// per the semver spec, never affects comparisons.
+type Version struct {
+	Major, Minor, Patch int
+	Prerelease          []string
+	Build               string
+}
+
+// Parse parses a version like "1.2.3", "v1.2.3-beta.1" or "=1.2.3+build.5"
+func Parse(s string) (Version, error) {
+	original := s
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "=")
+	s = strings.TrimPrefix(s, "v")
+
+	var v Version
+	if i := strings.Index(s, "+"); i >= 0 {
+		v.Build = s[i+1:]
+		s = s[:i]
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		pre := s[i+1:]
+		s = s[:i]
+		if pre == "" {
+			return Version{}, fmt.Errorf("invalid version '%s': empty prerelease", original)
+		}
+		v.Prerelease = strings.Split(pre, ".")
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid version '%s'", original)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || part == "" || (len(part) > 1 && part[0] == '0') {
+			return Version{}, fmt.Errorf("invalid version '%s'", original)
+		}
+		numbers[i] = n
+	}
+	v.Major, v.Minor, v.Patch = numbers[0], numbers[1], numbers[2]
+
+	return v, nil
+}
+
+// String renders the version without build metadata
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	return s
+}
+
+// IsPrerelease reports whether the version carries a prerelease tag
+func (v Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// SortStrings sorts versions by semver precedence, followed by those that aren't semver (ranges
+// of references, git specs) as strings
+func SortStrings(versions []string) {
+	sort.Slice(versions, func(i, j int) bool {
+		a, errA := Parse(versions[i])
+		b, errB := Parse(versions[j])
+		switch {
+		case errA != nil && errB != nil:
+			return versions[i] < versions[j]
+		case errA != nil || errB != nil:
+			return errB != nil
+		case Compare(a, b) != 0:
+			return Compare(a, b) < 0
+		default:
+			// "v1.0.0" and "1.0.0" are equal versions but separate groups
+			return versions[i] < versions[j]
+		}
+	})
+}
+
+// Compare returns -1, 0 or +1 depending on whether a is lower than, equal to or higher than b
+func Compare(a, b Version) int {
+	for _, d := range []int{a.Major - b.Major, a.Minor - b.Minor, a.Patch - b.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+
+	// A version without prerelease has higher precedence than one with
+	switch {
+	case len(a.Prerelease) == 0 && len(b.Prerelease) == 0:
+		return 0
+	case len(a.Prerelease) == 0:
+		return 1
+	case len(b.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a.Prerelease) && i < len(b.Prerelease); i++ {
+		if c := comparePrereleaseID(a.Prerelease[i], b.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return sign(len(a.Prerelease) - len(b.Prerelease))
+}
+
+// Equal reports whether two versions have the same precedence (build metadata is ignored)
+func Equal(a, b Version) bool {
+	return Compare(a, b) == 0
+}
+
+func comparePrereleaseID(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return sign(na - nb)
+	case errA == nil:
+		return -1 // numeric identifiers sort before alphanumeric ones
+	case errB == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
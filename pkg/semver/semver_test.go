@@ -0,0 +1,179 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "1.2.3", want: "1.2.3"},
+		{input: "v1.2.3", want: "1.2.3"},
+		{input: "=1.2.3", want: "1.2.3"},
+		{input: "1.2.3-beta.1", want: "1.2.3-beta.1"},
+		{input: "1.2.3+build.5", want: "1.2.3"},
+		{input: "1.2", wantErr: true},
+		{input: "01.2.3", wantErr: true},
+		{input: "1.2.3-", wantErr: true},
+		{input: "latest", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("Parse(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.2.3", b: "1.2.3", want: 0},
+		{a: "1.2.3", b: "1.10.0", want: -1},
+		{a: "2.0.0", b: "1.99.99", want: 1},
+		{a: "1.0.0-alpha", b: "1.0.0", want: -1},
+		{a: "1.0.0-alpha", b: "1.0.0-alpha.1", want: -1},
+		{a: "1.0.0-alpha.1", b: "1.0.0-alpha.beta", want: -1},
+		{a: "1.0.0-beta.2", b: "1.0.0-beta.11", want: -1},
+		{a: "1.0.0-rc.1", b: "1.0.0-beta.11", want: 1},
+		{a: "1.0.0+build.1", b: "1.0.0+build.2", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			a, _ := Parse(tt.a)
+			b, _ := Parse(tt.b)
+			if got := Compare(a, b); got != tt.want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRangeSatisfies(t *testing.T) {
+	tests := []struct {
+		rng     string
+		version string
+		want    bool
+	}{
+		{rng: "1.2.3", version: "1.2.3", want: true},
+		{rng: "1.2.3", version: "1.2.4", want: false},
+		{rng: ">=1.0.0 <1.2.3", version: "1.2.2", want: true},
+		{rng: ">=1.0.0 <1.2.3", version: "1.2.3", want: false},
+		{rng: "<=2.0.0", version: "2.0.0", want: true},
+		{rng: "> 1.0.0", version: "1.0.1", want: true},
+		{rng: "^1.2.3", version: "1.9.0", want: true},
+		{rng: "^1.2.3", version: "2.0.0", want: false},
+		{rng: "^0.2.3", version: "0.2.9", want: true},
+		{rng: "^0.2.3", version: "0.3.0", want: false},
+		{rng: "^0.0.3", version: "0.0.4", want: false},
+		{rng: "~1.2.3", version: "1.2.9", want: true},
+		{rng: "~1.2.3", version: "1.3.0", want: false},
+		{rng: "~1", version: "1.9.0", want: true},
+		{rng: "1.2.x", version: "1.2.7", want: true},
+		{rng: "1.x", version: "2.0.0", want: false},
+		{rng: "*", version: "3.1.4", want: true},
+		{rng: "1.2.3 - 2.3.4", version: "2.3.4", want: true},
+		{rng: "1.2.3 - 2.3", version: "2.3.9", want: true},
+		{rng: "1.2.3 - 2.3", version: "2.4.0", want: false},
+		{rng: "1.0.0 || 2.0.0", version: "2.0.0", want: true},
+		{rng: "1.0.0 || 2.0.0", version: "1.5.0", want: false},
+		{rng: ">1.2", version: "1.2.9", want: false},
+		{rng: ">1.2", version: "1.3.0", want: true},
+		{rng: "<=1.2", version: "1.2.9", want: true},
+		// Prereleases only match ranges that opt in on the same major.minor.patch
+		{rng: "^1.0.0", version: "1.1.0-beta", want: false},
+		{rng: ">=1.1.0-alpha <2.0.0", version: "1.1.0-beta", want: true},
+		{rng: ">=1.1.0-alpha <2.0.0", version: "1.2.0-beta", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rng+" "+tt.version, func(t *testing.T) {
+			r, err := ParseRange(tt.rng)
+			if err != nil {
+				t.Fatalf("ParseRange(%q) error = %v", tt.rng, err)
+			}
+			v, err := Parse(tt.version)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.version, err)
+			}
+			if got := r.Satisfies(v); got != tt.want {
+				t.Errorf("ParseRange(%q).Satisfies(%s) = %v, want %v", tt.rng, tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRangeErrors(t *testing.T) {
+	for _, rng := range []string{">=abc", "1.2.3.4", "^", "1.x-beta"} {
+		if _, err := ParseRange(rng); err == nil {
+			t.Errorf("ParseRange(%q) expected error, got nil", rng)
+		}
+	}
+}
+
+func TestIsRange(t *testing.T) {
+	tests := []struct {
+		spec string
+		want bool
+	}{
+		{"1.2.3", false},
+		{"v1.2.3", false},
+		{"1.0.0-beta.1", false},
+		{"1.0.0@beta", false},
+		{"", false},
+		{"<4.17.12", true},
+		{">=1.2.0 <1.3.0", true},
+		{"^1.2.3", true},
+		{"1.2.x", true},
+		{"1.2", true},
+		{"1.0.0 - 2.0.0", true},
+		{"1.0.0 || 2.0.0", true},
+	}
+
+	for _, tt := range tests {
+		if got := IsRange(tt.spec); got != tt.want {
+			t.Errorf("IsRange(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestRangeIntersects(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"^4.17.0", "<4.17.12", true},
+		{"^4.17.12", "<4.17.12", false},
+		{"^4.17.12", "<=4.17.12", true},
+		{">=1.2.0 <1.3.0", "~1.2.5", true},
+		{"1.2.3", "1.2.3", true},
+		{"1.2.3", "1.2.4", false},
+		{"<1.0.0 || >=2.0.0", "^1.5.0", false},
+		{"<1.0.0 || >=2.0.0", "^2.1.0", true},
+		{"*", "1.0.0", true},
+	}
+
+	for _, tt := range tests {
+		a, err := ParseRange(tt.a)
+		if err != nil {
+			t.Fatalf("ParseRange(%q) error: %v", tt.a, err)
+		}
+		b, err := ParseRange(tt.b)
+		if err != nil {
+			t.Fatalf("ParseRange(%q) error: %v", tt.b, err)
+		}
+		if got := a.Intersects(b); got != tt.want {
+			t.Errorf("ParseRange(%q).Intersects(%q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
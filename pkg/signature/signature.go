@@ -0,0 +1,264 @@
+// Package signature verifies detached signatures on downloaded lists and databases.
+// It supports minisign signatures and SSH signatures made with ed25519 keys
+// (`ssh-keygen -Y sign -n file`).
+package signature
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// SSHNamespace is the signature namespace expected on SSH signatures
+const SSHNamespace = "file"
+
+// PublicKey is a minisign or ssh-ed25519 public key
+type PublicKey struct {
+	key   ed25519.PublicKey
+	keyID []byte // minisign key id
+	blob  []byte // SSH wire-format public key
+}
+
+// Result describes a successful verification
+type Result struct {
+	Fingerprint string    // Minisign key id or SSH SHA256 fingerprint
+	SignedAt    time.Time // From the minisign trusted comment, zero when the signature doesn't carry one
+}
+
+// LoadPublicKey parses a public key given inline or as the path of a file holding one
+func LoadPublicKey(value string) (*PublicKey, error) {
+	if data, err := os.ReadFile(value); err == nil {
+		value = string(data)
+	}
+	return ParsePublicKey(value)
+}
+
+// ParsePublicKey parses a minisign public key (with or without its "untrusted comment" line)
+// or an OpenSSH "ssh-ed25519 AAAA..." key
+func ParsePublicKey(s string) (*PublicKey, error) {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) != 1 {
+		return nil, fmt.Errorf("expected a single minisign or ssh-ed25519 public key")
+	}
+
+	if strings.HasPrefix(lines[0], "ssh-") {
+		return parseSSHPublicKey(lines[0])
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil || len(raw) != 42 || string(raw[:2]) != "Ed" {
+		return nil, fmt.Errorf("malformed minisign public key")
+	}
+	return &PublicKey{keyID: raw[2:10], key: ed25519.PublicKey(raw[10:])}, nil
+}
+
+func parseSSHPublicKey(line string) (*PublicKey, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "ssh-ed25519" {
+		return nil, fmt.Errorf("only ssh-ed25519 keys are supported")
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed ssh public key: %v", err)
+	}
+	key, err := ed25519FromSSHBlob(blob)
+	if err != nil {
+		return nil, err
+	}
+	return &PublicKey{key: key, blob: blob}, nil
+}
+
+// Fingerprint identifies the key the way minisign and ssh-keygen display it
+func (k *PublicKey) Fingerprint() string {
+	if k.blob != nil {
+		sum := sha256.Sum256(k.blob)
+		return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+	}
+	// minisign prints the little-endian key id as hex
+	id := make([]byte, len(k.keyID))
+	for i := range k.keyID {
+		id[i] = k.keyID[len(k.keyID)-1-i]
+	}
+	return strings.ToUpper(hex.EncodeToString(id))
+}
+
+// Verify checks a detached signature (the contents of a .sig file) over data
+func Verify(key *PublicKey, data, sig []byte) (Result, error) {
+	if bytes.Contains(sig, []byte("-----BEGIN SSH SIGNATURE-----")) {
+		if key.blob == nil {
+			return Result{}, fmt.Errorf("SSH signature but the configured key is a minisign key")
+		}
+		return verifySSH(key, data, sig)
+	}
+	if key.blob != nil {
+		return Result{}, fmt.Errorf("minisign signature but the configured key is an SSH key")
+	}
+	return verifyMinisign(key, data, sig)
+}
+
+func verifyMinisign(key *PublicKey, data, sig []byte) (Result, error) {
+	lines := strings.Split(strings.TrimSpace(string(sig)), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return Result{}, fmt.Errorf("malformed minisign signature")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(raw) != 74 {
+		return Result{}, fmt.Errorf("malformed minisign signature")
+	}
+	algorithm, keyID, signature := string(raw[:2]), raw[2:10], raw[10:]
+	if !bytes.Equal(keyID, key.keyID) {
+		return Result{}, fmt.Errorf("signed with key %s, not the configured key %s", (&PublicKey{keyID: keyID}).Fingerprint(), key.Fingerprint())
+	}
+
+	message := data
+	switch algorithm {
+	case "Ed":
+	case "ED":
+		sum := blake2b.Sum512(data)
+		message = sum[:]
+	default:
+		return Result{}, fmt.Errorf("unsupported minisign algorithm '%s'", algorithm)
+	}
+	if !ed25519.Verify(key.key, message, signature) {
+		return Result{}, fmt.Errorf("signature does not match the content")
+	}
+
+	// The global signature covers the signature and the trusted comment, which carries the timestamp
+	trustedComment := strings.TrimPrefix(strings.TrimRight(lines[2], "\r"), "trusted comment: ")
+	globalSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || !ed25519.Verify(key.key, append(append([]byte{}, signature...), trustedComment...), globalSignature) {
+		return Result{}, fmt.Errorf("trusted comment signature does not match")
+	}
+
+	result := Result{Fingerprint: key.Fingerprint()}
+	for _, field := range strings.Fields(trustedComment) {
+		if value, ok := strings.CutPrefix(field, "timestamp:"); ok {
+			if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+				result.SignedAt = time.Unix(seconds, 0).UTC()
+			}
+		}
+	}
+	return result, nil
+}
+
+func verifySSH(key *PublicKey, data, sig []byte) (Result, error) {
+	var armored []string
+	for _, line := range strings.Split(string(sig), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "-----") {
+			armored = append(armored, line)
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.Join(armored, ""))
+	if err != nil || !bytes.HasPrefix(raw, []byte("SSHSIG")) {
+		return Result{}, fmt.Errorf("malformed SSH signature")
+	}
+
+	r := &wireReader{data: raw[6:]}
+	version := r.uint32()
+	publicKey := r.bytes()
+	namespace := string(r.bytes())
+	reserved := r.bytes()
+	hashAlgorithm := string(r.bytes())
+	signatureBlob := r.bytes()
+	if r.err != nil || version != 1 {
+		return Result{}, fmt.Errorf("malformed SSH signature")
+	}
+	if !bytes.Equal(publicKey, key.blob) {
+		return Result{}, fmt.Errorf("signed with a different key than the configured key %s", key.Fingerprint())
+	}
+	if namespace != SSHNamespace {
+		return Result{}, fmt.Errorf("signature namespace is '%s', expected '%s'", namespace, SSHNamespace)
+	}
+
+	var h hash.Hash
+	switch hashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return Result{}, fmt.Errorf("unsupported SSH signature hash '%s'", hashAlgorithm)
+	}
+	h.Write(data)
+
+	var signed bytes.Buffer
+	signed.WriteString("SSHSIG")
+	writeWireBytes(&signed, []byte(namespace))
+	writeWireBytes(&signed, reserved)
+	writeWireBytes(&signed, []byte(hashAlgorithm))
+	writeWireBytes(&signed, h.Sum(nil))
+
+	sr := &wireReader{data: signatureBlob}
+	format := string(sr.bytes())
+	signature := sr.bytes()
+	if sr.err != nil || format != "ssh-ed25519" {
+		return Result{}, fmt.Errorf("unsupported SSH signature format '%s'", format)
+	}
+	if !ed25519.Verify(key.key, signed.Bytes(), signature) {
+		return Result{}, fmt.Errorf("signature does not match the content")
+	}
+
+	return Result{Fingerprint: key.Fingerprint()}, nil
+}
+
+func ed25519FromSSHBlob(blob []byte) (ed25519.PublicKey, error) {
+	r := &wireReader{data: blob}
+	keyType := string(r.bytes())
+	key := r.bytes()
+	if r.err != nil || keyType != "ssh-ed25519" || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("malformed ssh-ed25519 public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// wireReader decodes SSH wire-format uint32s and length-prefixed strings
+type wireReader struct {
+	data []byte
+	err  error
+}
+
+func (r *wireReader) uint32() uint32 {
+	if r.err != nil || len(r.data) < 4 {
+		r.err = fmt.Errorf("truncated")
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.data)
+	r.data = r.data[4:]
+	return v
+}
+
+func (r *wireReader) bytes() []byte {
+	n := r.uint32()
+	if r.err != nil || uint32(len(r.data)) < n {
+		r.err = fmt.Errorf("truncated")
+		return nil
+	}
+	v := r.data[:n]
+	r.data = r.data[n:]
+	return v
+}
+
+func writeWireBytes(buf *bytes.Buffer, b []byte) {
+	binary.Write(buf, binary.BigEndian, uint32(len(b)))
+	buf.Write(b)
+}
@@ -0,0 +1,108 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisignFixture signs data the way `minisign -S` does and returns the public key and .sig contents
+func minisignFixture(t *testing.T, data []byte, algorithm string, timestamp int64) (string, string) {
+	t.Helper()
+	private := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	publicKey := "untrusted comment: minisign public key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), private.Public().(ed25519.PublicKey)...))
+
+	message := data
+	if algorithm == "ED" {
+		sum := blake2b.Sum512(data)
+		message = sum[:]
+	}
+	signature := ed25519.Sign(private, message)
+	trustedComment := fmt.Sprintf("timestamp:%d\tfile:list.json", timestamp)
+	globalSignature := ed25519.Sign(private, append(append([]byte{}, signature...), trustedComment...))
+
+	sig := "untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte(algorithm), keyID...), signature...)) + "\n" +
+		"trusted comment: " + trustedComment + "\n" +
+		base64.StdEncoding.EncodeToString(globalSignature) + "\n"
+	return publicKey, sig
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	data := []byte(`["evil@1.0.0"]`)
+
+	for _, algorithm := range []string{"ED", "Ed"} {
+		t.Run(algorithm, func(t *testing.T) {
+			publicKey, sig := minisignFixture(t, data, algorithm, 1700000000)
+			key, err := ParsePublicKey(publicKey)
+			if err != nil {
+				t.Fatalf("ParsePublicKey() error = %v", err)
+			}
+
+			result, err := Verify(key, data, []byte(sig))
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if result.Fingerprint != "0807060504030201" || !result.SignedAt.Equal(time.Unix(1700000000, 0)) {
+				t.Errorf("Verify() = %+v, want key id 0807060504030201 signed at 1700000000", result)
+			}
+
+			if _, err := Verify(key, []byte(`["evil@1.0.1"]`), []byte(sig)); err == nil {
+				t.Error("Verify() expected error for tampered content")
+			}
+			tampered := strings.Replace(sig, "timestamp:1700000000", "timestamp:1800000000", 1)
+			if _, err := Verify(key, data, []byte(tampered)); err == nil {
+				t.Error("Verify() expected error for tampered trusted comment")
+			}
+		})
+	}
+}
+
+func TestVerifySSH(t *testing.T) {
+	// Generated with: ssh-keygen -Y sign -f key -n file list.json
+	data, _ := os.ReadFile("testdata/list.json")
+	sig, _ := os.ReadFile("testdata/list.json.sshsig")
+
+	key, err := LoadPublicKey("testdata/ssh.pub")
+	if err != nil {
+		t.Fatalf("LoadPublicKey() error = %v", err)
+	}
+	if got := key.Fingerprint(); got != "SHA256:1qPbo2h6ic1d2i60OuiCSQuSyfWYvs1m53Uq1jVqXEU" {
+		t.Errorf("Fingerprint() = %s, want the ssh-keygen -l fingerprint", got)
+	}
+
+	result, err := Verify(key, data, sig)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if result.Fingerprint != key.Fingerprint() || !result.SignedAt.IsZero() {
+		t.Errorf("Verify() = %+v, want the key fingerprint and no timestamp", result)
+	}
+
+	if _, err := Verify(key, append(data, ' '), sig); err == nil {
+		t.Error("Verify() expected error for tampered content")
+	}
+
+	minisignKey, _ := minisignFixture(t, data, "ED", 0)
+	otherKey, _ := ParsePublicKey(minisignKey)
+	if _, err := Verify(otherKey, data, sig); err == nil {
+		t.Error("Verify() expected error for an SSH signature with a minisign key")
+	}
+}
+
+func TestParsePublicKeyErrors(t *testing.T) {
+	for _, value := range []string{"", "not a key", "ssh-rsa AAAAB3NzaC1yc2E", "untrusted comment: x\nAAAA"} {
+		if _, err := ParsePublicKey(value); err == nil {
+			t.Errorf("ParsePublicKey(%q) expected error", value)
+		}
+	}
+}
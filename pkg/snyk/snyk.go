@@ -0,0 +1,121 @@
+// Package snyk converts the JSON report of `snyk test --json` into scnpm package queries.
+package snyk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"scnpm/pkg/input"
+	"scnpm/pkg/semver"
+	"scnpm/pkg/types"
+)
+
+// issueURL is where Snyk publishes an issue when the report doesn't carry its URL
+const issueURL = "https://security.snyk.io/vuln/"
+
+// report is one project's `snyk test --json` output; multi-project runs (--all-projects) print
+// an array of them
+type report struct {
+	Vulnerabilities []json.RawMessage `json:"vulnerabilities"`
+}
+
+// vulnerability is the part of a Snyk issue scnpm models
+type vulnerability struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	PackageName string `json:"packageName"`
+	Version     string `json:"version"`
+	Severity    string `json:"severity"`
+	URL         string `json:"url"`
+	Semver      struct {
+		Vulnerable []string `json:"vulnerable"`
+	} `json:"semver"`
+}
+
+// Parse converts a Snyk report (or an array of reports) into one query per vulnerable package
+// and issue. Snyk lists an issue once per dependency path; those repeats are collapsed.
+func Parse(data []byte) ([]types.PackageQuery, error) {
+	var reports []report
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &reports); err != nil {
+			return nil, err
+		}
+	} else {
+		var single struct {
+			report
+			OK *bool `json:"ok"`
+		}
+		if err := json.Unmarshal(data, &single); err != nil {
+			return nil, err
+		}
+		if single.Vulnerabilities == nil && single.OK == nil {
+			return nil, fmt.Errorf("not a Snyk report: missing vulnerabilities")
+		}
+		reports = []report{single.report}
+	}
+
+	var queries []types.PackageQuery
+	seen := make(map[string]bool)
+	for _, r := range reports {
+		for i, raw := range r.Vulnerabilities {
+			query, err := convert(raw)
+			if err != nil {
+				return nil, fmt.Errorf("vulnerability %d: %v", i, err)
+			}
+			key := query.Advisory + " " + query.Name + "@" + query.Version
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			queries = append(queries, query)
+		}
+	}
+
+	return queries, nil
+}
+
+// convert maps one Snyk vulnerability onto a query
+func convert(raw json.RawMessage) (types.PackageQuery, error) {
+	var v vulnerability
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return types.PackageQuery{}, err
+	}
+	if v.PackageName == "" {
+		return types.PackageQuery{}, fmt.Errorf("missing packageName")
+	}
+
+	version := strings.Join(v.Semver.Vulnerable, " || ")
+	if version == "" {
+		// Without ranges only the reported version is known to be affected
+		version = v.Version
+	}
+	if semver.IsRange(version) {
+		if _, err := semver.ParseRange(version); err != nil {
+			return types.PackageQuery{}, fmt.Errorf("invalid version range '%s' for '%s': %v", version, v.PackageName, err)
+		}
+	}
+
+	query := types.PackageQuery{
+		Name:     v.PackageName,
+		Version:  version,
+		Advisory: v.URL,
+		Note:     v.Title,
+	}
+	if query.Advisory == "" && v.ID != "" {
+		query.Advisory = issueURL + v.ID
+	}
+	if severity, ok := types.NormalizeSeverity(v.Severity); ok {
+		query.Severity = severity
+	}
+
+	extra, err := input.UnmodeledFields(raw, "packageName", "version", "semver", "severity", "url")
+	if err != nil {
+		return types.PackageQuery{}, err
+	}
+	query.Extra = extra
+
+	return query, nil
+}
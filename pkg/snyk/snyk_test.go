@@ -0,0 +1,91 @@
+package snyk
+
+import (
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+const testReport = `{
+  "ok": false,
+  "packageManager": "npm",
+  "vulnerabilities": [
+    {
+      "id": "SNYK-JS-LODASH-567746",
+      "title": "Prototype Pollution",
+      "packageName": "lodash",
+      "version": "4.17.15",
+      "severity": "medium",
+      "semver": {"vulnerable": ["<4.17.16"]},
+      "identifiers": {"CVE": ["CVE-2020-8203"]},
+      "cvssScore": 7.4,
+      "from": ["app@1.0.0", "lodash@4.17.15"]
+    },
+    {
+      "id": "SNYK-JS-LODASH-567746",
+      "title": "Prototype Pollution",
+      "packageName": "lodash",
+      "version": "4.17.15",
+      "severity": "medium",
+      "semver": {"vulnerable": ["<4.17.16"]},
+      "from": ["app@1.0.0", "a@1.0.0", "lodash@4.17.15"]
+    },
+    {
+      "id": "SNYK-JS-MINIMIST-559764",
+      "title": "Prototype Pollution",
+      "url": "https://example.com/minimist",
+      "packageName": "minimist",
+      "version": "1.2.0",
+      "severity": "low",
+      "semver": {"vulnerable": ["<0.2.1", ">=1.0.0 <1.2.3"]}
+    }
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	queries, err := Parse([]byte(testReport))
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	if len(queries) != 2 {
+		t.Fatalf("Parse() returned %d queries, want 2 (repeated paths collapsed): %+v", len(queries), queries)
+	}
+
+	lodash := queries[0]
+	if lodash.Name != "lodash" || lodash.Version != "<4.17.16" || lodash.Severity != types.SeverityModerate {
+		t.Errorf("lodash query = %+v", lodash)
+	}
+	if lodash.Advisory != "https://security.snyk.io/vuln/SNYK-JS-LODASH-567746" || lodash.Note != "Prototype Pollution" {
+		t.Errorf("lodash advisory = %q, note = %q", lodash.Advisory, lodash.Note)
+	}
+	if lodash.Extra["id"] != "SNYK-JS-LODASH-567746" || lodash.Extra["cvssScore"] != 7.4 || lodash.Extra["identifiers"] == nil {
+		t.Errorf("lodash extra = %v, want unmodeled fields passed through", lodash.Extra)
+	}
+	if _, ok := lodash.Extra["packageName"]; ok {
+		t.Errorf("lodash extra = %v, want modeled fields left out", lodash.Extra)
+	}
+
+	minimist := queries[1]
+	if minimist.Version != "<0.2.1 || >=1.0.0 <1.2.3" || minimist.Advisory != "https://example.com/minimist" {
+		t.Errorf("minimist query = %+v", minimist)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "not a report", data: `{"name": "app"}`},
+		{name: "missing package", data: `{"vulnerabilities": [{"id": "SNYK-1"}]}`},
+		{name: "invalid range", data: `{"vulnerabilities": [{"packageName": "a", "semver": {"vulnerable": [">=1.x.2.3"]}}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data)); err == nil {
+				t.Errorf("Parse() error = nil, want an error")
+			}
+		})
+	}
+}
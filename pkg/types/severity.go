@@ -0,0 +1,55 @@
+package types
+
+import "strings"
+
+// Severity levels a bad-package entry can carry, from most to least severe
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityModerate = "moderate"
+	SeverityLow      = "low"
+)
+
+// Severities lists the known severity levels ordered from most to least severe
+var Severities = []string{SeverityCritical, SeverityHigh, SeverityModerate, SeverityLow}
+
+// NormalizeSeverity lowercases a severity and maps common synonyms (e.g. "medium") onto
+// the known levels. It reports false for values that aren't a recognizable severity.
+func NormalizeSeverity(severity string) (string, bool) {
+	switch s := strings.ToLower(strings.TrimSpace(severity)); s {
+	case SeverityCritical, SeverityHigh, SeverityModerate, SeverityLow:
+		return s, true
+	case "medium":
+		return SeverityModerate, true
+	default:
+		return "", false
+	}
+}
+
+// SeverityRank orders severities for comparison: higher is more severe, and an empty or
+// unknown severity ranks below every known level
+func SeverityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return len(Severities) - i
+		}
+	}
+	return 0
+}
+
+// SeverityFromCVSS maps a CVSS v3 base score onto the severity levels using the CVSS
+// qualitative rating scale. A score of 0 (none or unknown) has no severity.
+func SeverityFromCVSS(score float64) string {
+	switch {
+	case score >= 9:
+		return SeverityCritical
+	case score >= 7:
+		return SeverityHigh
+	case score >= 4:
+		return SeverityModerate
+	case score > 0:
+		return SeverityLow
+	default:
+		return ""
+	}
+}
@@ -7,6 +7,19 @@ type PackageLock struct {
 	LockfileVersion int                   `json:"lockfileVersion"`
 	Dependencies    map[string]Dependency `json:"dependencies,omitempty"`
 	Packages        map[string]Package    `json:"packages,omitempty"`
+
+	// Lines maps install paths to the line their entry starts on in the source file.
+	// It is nil when positions are unavailable (e.g. the lockfile was read from stdin).
+	Lines map[string]int `json:"-"`
+
+	// Importers maps install paths to the pnpm importers (workspace projects) that depend on them.
+	// It is only populated for pnpm-lock.yaml sources.
+	Importers map[string][]string `json:"-"`
+
+	// Resolutions maps the key of each entry to the keys of the entries its dependencies resolve
+	// to, by dependency name. yarn.lock and pnpm-lock.yaml sources record it, as their keys don't
+	// nest like node_modules; it is nil for npm lockfiles.
+	Resolutions map[string]map[string]string `json:"-"`
 }
 
 // Dependency represents a dependency in the old format (lockfileVersion 1)
@@ -15,46 +28,130 @@ type Dependency struct {
 	Resolved     string                `json:"resolved,omitempty"`
 	Integrity    string                `json:"integrity,omitempty"`
 	Dev          bool                  `json:"dev,omitempty"`
+	Bundled      bool                  `json:"bundled,omitempty"`
+	Requires     map[string]string     `json:"requires,omitempty"`
 	Dependencies map[string]Dependency `json:"dependencies,omitempty"`
 }
 
 // Package represents a package in the new format (lockfileVersion 2+)
 type Package struct {
-	Version          string            `json:"version,omitempty"`
-	Resolved         string            `json:"resolved,omitempty"`
-	Integrity        string            `json:"integrity,omitempty"`
-	Dev              bool              `json:"dev,omitempty"`
-	DevOptional      bool              `json:"devOptional,omitempty"`
-	Dependencies     map[string]string `json:"dependencies,omitempty"`
-	PeerDependencies map[string]string `json:"peerDependencies,omitempty"`
-	Engines          any               `json:"engines,omitempty"`
-	License          string            `json:"license,omitempty"`
-	Bin              any               `json:"bin,omitempty"`
-	Scripts          map[string]string `json:"scripts,omitempty"`
+	Name                 string            `json:"name,omitempty"` // Real package name, recorded for npm: aliases and the root entry
+	Version              string            `json:"version,omitempty"`
+	Resolved             string            `json:"resolved,omitempty"`
+	Integrity            string            `json:"integrity,omitempty"`
+	Dev                  bool              `json:"dev,omitempty"`
+	DevOptional          bool              `json:"devOptional,omitempty"`
+	Link                 bool              `json:"link,omitempty"`             // Symlink to a local directory; Resolved holds its path
+	InBundle             bool              `json:"inBundle,omitempty"`         // Shipped inside its parent's tarball (bundleDependencies)
+	HasInstallScript     bool              `json:"hasInstallScript,omitempty"` // Runs preinstall/install/postinstall scripts
+	Dependencies         map[string]string `json:"dependencies,omitempty"`
+	DevDependencies      map[string]string `json:"devDependencies,omitempty"`
+	PeerDependencies     map[string]string `json:"peerDependencies,omitempty"`
+	OptionalDependencies map[string]string `json:"optionalDependencies,omitempty"`
+	Engines              any               `json:"engines,omitempty"`
+	License              string            `json:"license,omitempty"`
+	Bin                  any               `json:"bin,omitempty"`
+	Scripts              map[string]string `json:"scripts,omitempty"`
 }
 
 // PackageQuery represents a package to search for
 type PackageQuery struct {
-	Name    string
-	Version string
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Severity string `json:"severity,omitempty"` // One of Severities, empty when the source didn't say
+	Advisory string `json:"advisory,omitempty"` // Advisory URL or identifier (e.g. GHSA-xxxx-xxxx-xxxx)
+	Note     string `json:"note,omitempty"`
+
+	// Integrity is the SRI hash of a known-malicious tarball (IoC lists); when set, only entries
+	// with this exact artifact match, whatever their version
+	Integrity string `json:"integrity,omitempty"`
+
+	Source    string `json:"source,omitempty"`    // List path or URL the entry came from, "builtin" or "cli"
+	FetchedAt string `json:"fetchedAt,omitempty"` // RFC 3339 time the remote list was fetched
+	SignedBy  string `json:"signedBy,omitempty"`  // Fingerprint of the key that signed the remote list
+	SignedAt  string `json:"signedAt,omitempty"`  // RFC 3339 signing time, when the signature records one
+
+	// Pattern is the glob or "re:" query a concrete package was matched by; Name is then the
+	// matched package
+	Pattern string `json:"pattern,omitempty"`
+
+	// Sources lists every list, flag or database that contributed this entry
+	Sources []string `json:"sources,omitempty"`
+
+	// Tag is the dist-tag (e.g. latest) the entry named; Version then holds the version the tag
+	// pointed at when scanned
+	Tag string `json:"tag,omitempty"`
+
+	// Error explains why the entry couldn't be checked, e.g. its dist-tag didn't resolve; such
+	// entries match nothing
+	Error string `json:"error,omitempty"`
+
+	// Extra carries fields of imported reports (Snyk, OSS Index) that scnpm doesn't model, so
+	// findings can be correlated with the original issue
+	Extra map[string]any `json:"extra,omitempty"`
 }
 
 // ScanResult represents the result of scanning for a package
 type ScanResult struct {
-	Package        PackageQuery
-	Found          bool
-	Instances      []PackageInstance
-	TotalInstances int
+	Package        PackageQuery      `json:"package"`
+	Found          bool              `json:"found"`
+	Instances      []PackageInstance `json:"instances"`
+	TotalInstances int               `json:"totalInstances"`
+
+	// Check names the lockfile-wide check (one of Checks) that produced this result; it's empty
+	// for results of bad-package queries
+	Check string `json:"check,omitempty"`
+
+	// Remediation suggests how to get rid of the findings (--suggest-fixes)
+	Remediation *Remediation `json:"remediation,omitempty"`
 }
 
+// Remediation suggests how to get rid of a bad package
+type Remediation struct {
+	Investigate string            `json:"investigate"`           // Command showing why the package is installed, e.g. "npm ls event-stream"
+	SafeVersion string            `json:"safeVersion,omitempty"` // Highest version of the package already in the lockfile that the query doesn't match
+	Upgrade     string            `json:"upgrade,omitempty"`     // Command upgrading it where it's a direct dependency
+	Overrides   map[string]string `json:"overrides,omitempty"`   // package.json "overrides" forcing SafeVersion where it's a transitive dependency
+}
+
+// Lockfile-wide checks, reported apart from bad-package findings
+const (
+	CheckInstallSource    = "install-source"    // Packages installed from somewhere other than the registry (--sources)
+	CheckTyposquat        = "typosquat"         // Names a small edit away from a popular package (--typosquat)
+	CheckNearMatch        = "near-match"        // Names a small edit away from a bad-package query (--near-match)
+	CheckMissingIntegrity = "missing-integrity" // Entries without an integrity hash (--require-integrity)
+	CheckWeakIntegrity    = "weak-integrity"    // Entries hashed with an algorithm below --min-integrity-algo (--require-integrity)
+	CheckInstallScript    = "install-script"    // Packages that run install scripts (--detect-scripts)
+	CheckNonRegistry      = "non-registry"      // Packages resolved from git or remote tarballs (--non-registry)
+	CheckLicense          = "license"           // Packages under a license denied by --flag-licenses
+	CheckSuspiciousScript = "suspicious-script" // Lifecycle scripts matching a suspicious-command heuristic (--scan-scripts)
+	CheckLockMissing      = "lock-missing"      // Dependencies a package.json declares that the lockfile doesn't install (check-lock)
+	CheckLockOrphan       = "lock-orphan"       // Installed packages nothing declares or depends on (check-lock)
+	CheckLockStale        = "lock-stale"        // Locked versions outside the range package.json declares (check-lock)
+)
+
+// Checks lists the lockfile-wide checks in the order they're reported
+var Checks = []string{CheckNearMatch, CheckTyposquat, CheckSuspiciousScript, CheckInstallScript, CheckLicense, CheckMissingIntegrity, CheckWeakIntegrity, CheckNonRegistry, CheckInstallSource, CheckLockMissing, CheckLockStale, CheckLockOrphan}
+
+// Install sources a package can come from
+const (
+	SourceRegistry      = "registry"
+	SourceFile          = "file"
+	SourceLink          = "link"
+	SourceGit           = "git"
+	SourceRemoteTarball = "remote-tarball"
+)
+
 // PackageInstance represents a single instance of a package found
 type PackageInstance struct {
+	Name             string            `json:"name,omitempty"`  // Real package name; differs from the query for patterns and from the install path for aliases
+	Alias            string            `json:"alias,omitempty"` // Name an npm: alias installed the package under (node_modules/<alias>)
 	Version          string            `json:"version"`
 	Path             string            `json:"path"`
 	IsDev            bool              `json:"isDev"`
 	IsNested         bool              `json:"isNested"`
 	Depth            int               `json:"depth"`
-	LineNumber       int               `json:"lineNumber,omitempty"`       // Line number in package-lock.json
+	LineNumber       int               `json:"lineNumber,omitempty"` // Line number in package-lock.json
 	Resolved         string            `json:"resolved,omitempty"`
 	Integrity        string            `json:"integrity,omitempty"`
 	License          string            `json:"license,omitempty"`
@@ -63,7 +160,29 @@ type PackageInstance struct {
 	Engines          any               `json:"engines,omitempty"`
 	Bin              any               `json:"bin,omitempty"`
 	Scripts          map[string]string `json:"scripts,omitempty"`
-	IsReference      bool              `json:"isReference,omitempty"`   // True if found as dependency reference
-	ReferencedBy     string            `json:"referencedBy,omitempty"`  // Package that references this
-	ReferenceType    string            `json:"referenceType,omitempty"` // "dependencies", "peerDependencies", etc.
-}
\ No newline at end of file
+	IsReference      bool              `json:"isReference,omitempty"`      // True if found as dependency reference
+	ReferencedBy     []string          `json:"referencedBy,omitempty"`     // Install paths of the packages whose dependency maps reference this installed instance, "(root)" for the project
+	ExactFallback    bool              `json:"exactFallback,omitempty"`    // True if the version (a reference's declaration) isn't semver and was compared as a plain string
+	ReferenceType    string            `json:"referenceType,omitempty"`    // "dependencies", "devDependencies", "optionalDependencies" or "peerDependencies"
+	Workspace        string            `json:"workspace,omitempty"`        // Workspace that owns this instance (--workspaces)
+	Lockfile         string            `json:"lockfile,omitempty"`         // Lockfile this instance was found in, when scanning several
+	Group            string            `json:"group,omitempty"`            // Group of the finding with --group-by: its package, package@version, lockfile or path prefix
+	Chains           [][]string        `json:"chains,omitempty"`           // Dependency chains from a direct dependency down to this package, e.g. [express body-parser evil@1.0.0]
+	InstallSource    string            `json:"installSource,omitempty"`    // Where an installed instance came from: "registry", "file", "link", "git" or "remote-tarball"
+	PinnedCommit     string            `json:"pinnedCommit,omitempty"`     // Full commit SHA a git or remote tarball source is pinned to
+	DependedOnBy     []string          `json:"dependedOnBy,omitempty"`     // Packages that depend on this one (non-registry check)
+	IntegrityAlgo    string            `json:"integrityAlgo,omitempty"`    // Strongest hash algorithm of the entry's integrity (weak-integrity check)
+	Resembles        string            `json:"resembles,omitempty"`        // Package or query whose name this one imitates (typosquat and near-match checks)
+	Distance         int               `json:"distance,omitempty"`         // Edit distance to Resembles
+	MatchReason      string            `json:"matchReason,omitempty"`      // How the name matched: "exact", "glob", "scope-relaxed", "substring" or "regex"
+	MatchedVersion   string            `json:"matchedVersion,omitempty"`   // Which "||" alternative of the queried version matched
+	HasInstallScript bool              `json:"hasInstallScript,omitempty"` // True if the installed package runs install scripts
+	Script           string            `json:"script,omitempty"`           // Lifecycle script a heuristic matched (suspicious-script check)
+	Heuristic        string            `json:"heuristic,omitempty"`        // ID of the heuristic that matched Script
+	Snippet          string            `json:"snippet,omitempty"`          // Offending part of Script
+	IntegrityMatch   bool              `json:"integrityMatch,omitempty"`   // True if matched by the query's integrity hash (IoC)
+	Suppressed       bool              `json:"suppressed,omitempty"`       // True if an --ignore-file entry accepted this finding
+	SuppressedReason string            `json:"suppressedReason,omitempty"` // Reason recorded on the matching ignore entry
+	Baseline         bool              `json:"baseline,omitempty"`         // True if the finding is recorded in the --baseline file, so it doesn't count as a risk
+	Declared         string            `json:"declared,omitempty"`         // Range package.json declares that the locked version doesn't satisfy (lock-stale check)
+}
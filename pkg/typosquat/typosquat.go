@@ -0,0 +1,122 @@
+// Package typosquat spots package names that imitate popular npm packages, such as lodahs,
+// crossenv or react-dmo, by their edit distance to an embedded list of popular names.
+package typosquat
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxDistance is the largest edit distance reported unless configured otherwise
+const DefaultMaxDistance = 1
+
+// MinLength is the shortest popular name candidates are compared against. Short names are one
+// edit away from too many unrelated packages (ms, qs, ws) to be worth flagging.
+const MinLength = 5
+
+//go:embed popular.txt
+var popularData []byte
+
+var (
+	popularOnce  sync.Once
+	popularNames []string
+	popularSet   map[string]bool
+)
+
+// Popular returns the embedded popular package names, most popular first
+func Popular() []string {
+	popularOnce.Do(func() {
+		popularNames, popularSet = parseNames(popularData)
+	})
+	return popularNames
+}
+
+// IsPopular reports whether a name is on the embedded popular list
+func IsPopular(name string) bool {
+	Popular()
+	return popularSet[name]
+}
+
+// parseNames reads one name per line, skipping blank lines and # comments
+func parseNames(data []byte) ([]string, map[string]bool) {
+	var names []string
+	set := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" || strings.HasPrefix(name, "#") || set[name] {
+			continue
+		}
+		set[name] = true
+		names = append(names, name)
+	}
+	return names, set
+}
+
+// Match is a package name that resembles a popular one
+type Match struct {
+	Name      string // The suspicious name
+	Resembles string // The popular package it imitates
+	Distance  int    // Edit distance between the two
+}
+
+// Check compares a package name against the popular names and returns the closest one within
+// maxDistance edits, preferring the more popular package on ties. Popular packages themselves
+// never match.
+func Check(name string, maxDistance int) (Match, bool) {
+	if IsPopular(name) {
+		return Match{}, false
+	}
+
+	best := Match{Name: name, Distance: maxDistance + 1}
+	for _, popular := range Popular() {
+		if len(popular) < MinLength || abs(len(popular)-len(name)) >= best.Distance {
+			continue
+		}
+		if d := Distance(name, popular); d < best.Distance {
+			best.Resembles, best.Distance = popular, d
+		}
+	}
+	return best, best.Resembles != ""
+}
+
+// Distance returns the Damerau-Levenshtein distance between two names (in its optimal string
+// alignment form): the number of insertions, deletions, substitutions and transpositions of
+// adjacent characters that turn one into the other
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// Three rolling rows suffice: transpositions look two rows back
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,62 @@
+package typosquat
+
+import "testing"
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"lodash", "lodash", 0},
+		{"lodahs", "lodash", 1},
+		{"crossenv", "cross-env", 1},
+		{"react-dmo", "react-dom", 1},
+		{"expres", "express", 1},
+		{"axois", "axios", 1},
+		{"evnet-stream", "event-stream", 1},
+		{"@angulr/core", "@angular/core", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	}
+
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxDistance   int
+		wantResembles string
+		wantDistance  int
+	}{
+		{name: "lodahs", maxDistance: 1, wantResembles: "lodash", wantDistance: 1},
+		{name: "crossenv", maxDistance: 1, wantResembles: "cross-env", wantDistance: 1},
+		{name: "react-dmo", maxDistance: 1, wantResembles: "react-dom", wantDistance: 1},
+		{name: "lodassh", maxDistance: 1, wantResembles: "lodash", wantDistance: 1},
+		{name: "loadsh-x", maxDistance: 1},
+		{name: "lodash", maxDistance: 1},
+		{name: "my-internal-package", maxDistance: 2},
+		{name: "mx", maxDistance: 1},
+	}
+
+	for _, tt := range tests {
+		match, ok := Check(tt.name, tt.maxDistance)
+		if ok != (tt.wantResembles != "") || match.Resembles != tt.wantResembles || (ok && match.Distance != tt.wantDistance) {
+			t.Errorf("Check(%q, %d) = %+v, %v, want resemblance to %q at %d", tt.name, tt.maxDistance, match, ok, tt.wantResembles, tt.wantDistance)
+		}
+	}
+}
+
+func TestPopular(t *testing.T) {
+	names := Popular()
+	if len(names) < 1000 {
+		t.Errorf("Popular() has %d names, want the embedded list", len(names))
+	}
+	if names[0] != "lodash" || !IsPopular("@babel/core") || IsPopular("# Popular npm package names") {
+		t.Errorf("Popular() = %v..., want names without comments, most popular first", names[:3])
+	}
+}
@@ -0,0 +1,246 @@
+// Package verify compares the integrity hashes a lockfile records against the ones the registry
+// publishes, to catch tampered lockfiles, compromised mirrors and unpublished packages.
+package verify
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"scnpm/pkg/cache"
+	"scnpm/pkg/registry"
+	"scnpm/pkg/remote"
+	"scnpm/pkg/types"
+)
+
+// defaultConcurrency is how many packuments are fetched at once when Client.Concurrency is unset
+const defaultConcurrency = 8
+
+const cacheNamespace = "dist"
+
+// Outcomes of verifying a package
+const (
+	StatusVerified    = "verified"    // The lockfile's hash matches the published one
+	StatusMismatch    = "mismatch"    // The lockfile's hash differs from the published one
+	StatusUnpublished = "unpublished" // The registry doesn't have the package or this version of it
+	StatusUnchecked   = "unchecked"   // The hashes share no algorithm to compare
+	StatusError       = "error"       // The registry couldn't be asked
+)
+
+// Package is an installed package to verify
+type Package struct {
+	Name      string
+	Version   string
+	Path      string
+	Integrity string // SRI hash recorded in the lockfile, e.g. "sha512-..."
+}
+
+// Result is the verification of a package@version and the lockfile hash recorded for it
+type Result struct {
+	Name      string   `json:"name"`
+	Version   string   `json:"version"`
+	Paths     []string `json:"paths"`
+	Status    string   `json:"status"`             // One of the Status* values
+	Severity  string   `json:"severity,omitempty"` // critical for mismatches, high for unpublished packages
+	Lockfile  string   `json:"lockfileIntegrity"`
+	Published string   `json:"publishedIntegrity,omitempty"` // The registry's integrity, or its sha1 shasum as SRI
+	Error     string   `json:"error,omitempty"`
+}
+
+// Client fetches packuments from a registry
+type Client struct {
+	Remote      *remote.Client
+	Registry    string       // Defaults to audit.DefaultRegistry
+	Token       string       // Sent as a bearer token when set
+	Cache       *cache.Cache // Optional; the published hashes are cached per package
+	Concurrency int
+
+	// Progress, when set, is called after each package name is looked up, from one goroutine
+	// at a time
+	Progress func(done, total int)
+}
+
+// Dist is what a packument publishes about the tarball of a version
+type Dist struct {
+	Integrity string `json:"integrity,omitempty"`
+	Shasum    string `json:"shasum,omitempty"` // Hex sha1
+}
+
+// Verify looks up every package and compares its hash with the published one. Packages of the
+// same name share a lookup. Results are sorted by name and version.
+func (c *Client) Verify(ctx context.Context, packages []Package) []Result {
+	// Group the installed copies of each package@version and hash
+	type key struct{ name, version, integrity string }
+	grouped := make(map[key]*Result)
+	byName := make(map[string][]*Result)
+	for _, pkg := range packages {
+		k := key{pkg.Name, pkg.Version, pkg.Integrity}
+		if result, ok := grouped[k]; ok {
+			result.Paths = append(result.Paths, pkg.Path)
+			continue
+		}
+		result := &Result{Name: pkg.Name, Version: pkg.Version, Paths: []string{pkg.Path}, Lockfile: pkg.Integrity}
+		grouped[k] = result
+		byName[pkg.Name] = append(byName[pkg.Name], result)
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var mu sync.Mutex
+	done := 0
+	c.parallel(len(names), func(i int) {
+		versions, err := c.published(ctx, names[i], byName[names[i]])
+		for _, result := range byName[names[i]] {
+			switch {
+			case errors.Is(err, registry.ErrNotFound):
+				result.Status, result.Severity = StatusUnpublished, types.SeverityHigh
+			case err != nil:
+				result.Status, result.Error = StatusError, err.Error()
+			default:
+				published, ok := versions[result.Version]
+				if !ok {
+					result.Status, result.Severity = StatusUnpublished, types.SeverityHigh
+					continue
+				}
+				result.Status, result.Published = Compare(result.Lockfile, published)
+				if result.Status == StatusMismatch {
+					result.Severity = types.SeverityCritical
+				}
+			}
+		}
+		if c.Progress != nil {
+			mu.Lock()
+			done++
+			c.Progress(done, len(names))
+			mu.Unlock()
+		}
+	})
+
+	results := make([]Result, 0, len(grouped))
+	for _, result := range grouped {
+		sort.Strings(result.Paths)
+		results = append(results, *result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Name != results[j].Name {
+			return results[i].Name < results[j].Name
+		}
+		if results[i].Version != results[j].Version {
+			return results[i].Version < results[j].Version
+		}
+		return results[i].Lockfile < results[j].Lockfile
+	})
+	return results
+}
+
+// Compare checks a lockfile's SRI hash against what the registry published for the version: its
+// integrity, and its sha1 shasum for older lockfiles that only record sha1. It returns the status
+// and the published hash it compared with. A mismatch in any shared algorithm is a mismatch.
+func Compare(lockfile string, published Dist) (string, string) {
+	publishedHashes := make(map[string]string)
+	for _, hash := range strings.Fields(published.Integrity) {
+		if algorithm, _, ok := strings.Cut(hash, "-"); ok {
+			publishedHashes[algorithm] = hash
+		}
+	}
+	if _, ok := publishedHashes["sha1"]; !ok && published.Shasum != "" {
+		if sum, err := hex.DecodeString(published.Shasum); err == nil {
+			publishedHashes["sha1"] = "sha1-" + base64.StdEncoding.EncodeToString(sum)
+		}
+	}
+
+	status, compared := StatusUnchecked, ""
+	for _, hash := range strings.Fields(lockfile) {
+		algorithm, _, _ := strings.Cut(hash, "-")
+		want, ok := publishedHashes[algorithm]
+		if !ok {
+			continue
+		}
+		if hash != want {
+			return StatusMismatch, want
+		}
+		status, compared = StatusVerified, want
+	}
+	if status == StatusUnchecked {
+		compared = published.Integrity
+	}
+	return status, compared
+}
+
+// published returns the tarball hashes of every published version of a package, from the cache when it
+// has all the versions asked about
+func (c *Client) published(ctx context.Context, name string, wanted []*Result) (map[string]Dist, error) {
+	if c.Cache != nil {
+		var versions map[string]Dist
+		if data, ok := c.Cache.Get(cacheNamespace, name); ok && json.Unmarshal(data, &versions) == nil {
+			complete := true
+			for _, result := range wanted {
+				if _, ok := versions[result.Version]; !ok {
+					// Maybe published since; ask again
+					complete = false
+				}
+			}
+			if complete {
+				return versions, nil
+			}
+		}
+	}
+
+	versions, err := c.fetch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if c.Cache != nil {
+		if data, err := json.Marshal(versions); err == nil {
+			c.Cache.Put(cacheNamespace, name, data)
+		}
+	}
+	return versions, nil
+}
+
+// fetch reads the tarball hashes of every version from the abbreviated packument
+func (c *Client) fetch(ctx context.Context, name string) (map[string]Dist, error) {
+	var packument struct {
+		Versions map[string]struct {
+			Dist Dist `json:"dist"`
+		} `json:"versions"`
+	}
+	registryClient := &registry.Client{Remote: c.Remote, Registry: c.Registry, Token: c.Token}
+	if err := registryClient.Packument(ctx, name, &packument); err != nil {
+		return nil, err
+	}
+	versions := make(map[string]Dist, len(packument.Versions))
+	for version, manifest := range packument.Versions {
+		versions[version] = manifest.Dist
+	}
+	return versions, nil
+}
+
+// parallel runs fn for 0..n-1 with at most Concurrency calls in flight
+func (c *Client) parallel(n int, fn func(i int)) {
+	concurrency := c.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,102 @@
+package verify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"scnpm/pkg/cache"
+	"scnpm/pkg/types"
+)
+
+func TestCompare(t *testing.T) {
+	published := Dist{Integrity: "sha512-good", Shasum: "0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33"}
+	tests := []struct {
+		lockfile string
+		want     string
+	}{
+		{"sha512-good", StatusVerified},
+		{"sha512-evil", StatusMismatch},
+		{"sha1-C+7Hteo/D9vJXQ3UfzxbwnXaijM=", StatusVerified}, // The shasum as SRI
+		{"sha1-AAAAAAAAAAAAAAAAAAAAAAAAAAA=", StatusMismatch},
+		{"sha1-C+7Hteo/D9vJXQ3UfzxbwnXaijM= sha512-evil", StatusMismatch},
+		{"sha384-other", StatusUnchecked},
+	}
+	for _, tt := range tests {
+		if got, _ := Compare(tt.lockfile, published); got != tt.want {
+			t.Errorf("Compare(%q) = %s, want %s", tt.lockfile, got, tt.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("Accept") != "application/vnd.npm.install-v1+json" {
+			http.Error(w, "bad accept", http.StatusBadRequest)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/debug":
+			w.Write([]byte(`{"versions": {"4.3.4": {"dist": {"integrity": "sha512-good"}}}}`))
+		case "/@scope%2Fpkg":
+			w.Write([]byte(`{"versions": {"1.0.0": {"dist": {"integrity": "sha512-scoped"}}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var progress []int
+	client := &Client{
+		Registry: server.URL,
+		Cache:    &cache.Cache{Dir: t.TempDir(), TTL: time.Hour},
+		Progress: func(done, total int) { progress = append(progress, done) },
+	}
+	packages := []Package{
+		{Name: "debug", Version: "4.3.4", Path: "node_modules/debug", Integrity: "sha512-good"},
+		{Name: "debug", Version: "4.3.4", Path: "node_modules/a/node_modules/debug", Integrity: "sha512-evil"},
+		{Name: "debug", Version: "9.9.9", Path: "node_modules/b/node_modules/debug", Integrity: "sha512-new"},
+		{Name: "@scope/pkg", Version: "1.0.0", Path: "node_modules/@scope/pkg", Integrity: "sha512-scoped"},
+		{Name: "left-pad", Version: "1.0.0", Path: "node_modules/left-pad", Integrity: "sha512-gone"},
+	}
+
+	results := client.Verify(context.Background(), packages)
+	want := []struct{ name, version, status, severity string }{
+		{"@scope/pkg", "1.0.0", StatusVerified, ""},
+		{"debug", "4.3.4", StatusMismatch, types.SeverityCritical},
+		{"debug", "4.3.4", StatusVerified, ""},
+		{"debug", "9.9.9", StatusUnpublished, types.SeverityHigh},
+		{"left-pad", "1.0.0", StatusUnpublished, types.SeverityHigh},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Verify() = %+v, want %d results", results, len(want))
+	}
+	for i, w := range want {
+		got := results[i]
+		if got.Name != w.name || got.Version != w.version || got.Status != w.status || got.Severity != w.severity {
+			t.Errorf("result %d = %+v, want %s@%s %s %s", i, got, w.name, w.version, w.status, w.severity)
+		}
+	}
+	if results[1].Published != "sha512-good" || results[1].Lockfile != "sha512-evil" {
+		t.Errorf("mismatch = %+v, want both hashes", results[1])
+	}
+	if len(progress) != 3 || progress[2] != 3 {
+		t.Errorf("progress = %v, want a call per package name", progress)
+	}
+
+	// The scoped package is fully cached; debug misses 9.9.9 and is asked again
+	atomic.StoreInt32(&requests, 0)
+	client.Verify(context.Background(), packages[3:4])
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("second Verify() made %d requests, want the cached packument", n)
+	}
+	client.Verify(context.Background(), packages[:3])
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Verify() with an uncached version made %d requests, want 1", n)
+	}
+}
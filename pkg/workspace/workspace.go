@@ -0,0 +1,252 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"scnpm/pkg/input"
+	"scnpm/pkg/types"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RootName is the workspace name reported for findings that belong to the monorepo root
+const RootName = "(root)"
+
+// ErrNoWorkspaces is returned by Discover when the root declares no workspaces
+var ErrNoWorkspaces = errors.New("no workspaces declared")
+
+// Workspace describes a single npm workspace declared in the root package.json
+type Workspace struct {
+	Name     string // package name from the workspace's package.json
+	Dir      string // slash-separated path relative to the root, as used in lockfile keys
+	Lockfile string // absolute path to the workspace's own package-lock.json, if it has one
+}
+
+// manifest is the subset of package.json needed for workspace discovery
+type manifest struct {
+	Name       string          `json:"name"`
+	Workspaces json.RawMessage `json:"workspaces"`
+}
+
+// Discover expands the workspace globs declared in rootDir/pnpm-workspace.yaml or,
+// for npm and yarn, in the `workspaces` field of rootDir/package.json.
+// Both the array form and the `{"packages": [...]}` object form are supported;
+// patterns starting with "!" exclude previously matched directories.
+func Discover(rootDir string) ([]Workspace, error) {
+	var patterns []string
+	var source string
+	if pnpmPath := filepath.Join(rootDir, "pnpm-workspace.yaml"); fileExists(pnpmPath) {
+		var err error
+		source = pnpmPath
+		patterns, err = readPnpmPatterns(pnpmPath)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		source = filepath.Join(rootDir, "package.json")
+		root, err := readManifest(source)
+		if err != nil {
+			return nil, err
+		}
+		patterns, err = parsePatterns(root.Workspaces)
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspaces field in %s: %v", source, err)
+		}
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoWorkspaces, source)
+	}
+
+	dirs := make(map[string]bool)
+	for _, pattern := range patterns {
+		exclude := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		matches, err := filepath.Glob(filepath.Join(rootDir, filepath.FromSlash(pattern)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspace pattern '%s': %v", pattern, err)
+		}
+		for _, match := range matches {
+			if _, err := os.Stat(filepath.Join(match, "package.json")); err != nil {
+				continue
+			}
+			rel, err := filepath.Rel(rootDir, match)
+			if err != nil {
+				return nil, err
+			}
+			dirs[filepath.ToSlash(rel)] = !exclude
+		}
+	}
+
+	var workspaces []Workspace
+	for dir, included := range dirs {
+		if !included {
+			continue
+		}
+		absDir := filepath.Join(rootDir, filepath.FromSlash(dir))
+		ws := Workspace{Name: dir, Dir: dir}
+		if m, err := readManifest(filepath.Join(absDir, "package.json")); err == nil && m.Name != "" {
+			ws.Name = m.Name
+		}
+		if lockPath := filepath.Join(absDir, "package-lock.json"); fileExists(lockPath) {
+			ws.Lockfile = lockPath
+		}
+		workspaces = append(workspaces, ws)
+	}
+
+	sort.Slice(workspaces, func(i, j int) bool {
+		return workspaces[i].Dir < workspaces[j].Dir
+	})
+	return workspaces, nil
+}
+
+// Attribute returns the name of the workspace an instance path belongs to within the root lockfile.
+// Paths under a workspace directory belong to it; hoisted packages are attributed to the first
+// workspace that directly declares their top-level ancestor. pnpm lockfiles record exactly which
+// importers reach each package, so those are all listed. Everything else belongs to RootName.
+func Attribute(packageLock *types.PackageLock, workspaces []Workspace, path string) string {
+	// Reference paths look like "node_modules/foo -> dep"
+	if i := strings.Index(path, " -> "); i >= 0 {
+		path = path[:i]
+	}
+
+	if importers, ok := packageLock.Importers[path]; ok {
+		names := make([]string, 0, len(importers))
+		for _, importer := range importers {
+			names = append(names, importerName(workspaces, importer))
+		}
+		return strings.Join(names, ", ")
+	}
+
+	for _, ws := range workspaces {
+		if path == ws.Dir || strings.HasPrefix(path, ws.Dir+"/") {
+			return ws.Name
+		}
+	}
+
+	topLevel := topLevelPackage(path)
+	if topLevel == "" {
+		return RootName
+	}
+	for _, ws := range workspaces {
+		entry, ok := packageLock.Packages[ws.Dir]
+		if !ok {
+			continue
+		}
+		if _, declared := entry.Dependencies[topLevel]; declared {
+			return ws.Name
+		}
+		if _, declared := entry.DevDependencies[topLevel]; declared {
+			return ws.Name
+		}
+	}
+	return RootName
+}
+
+// Rebase attributes the instances of results, found in the own lockfile of ws, to ws and makes
+// their paths and referrers relative to the monorepo root. The root project of that lockfile is
+// ws itself, so RootName referrers become ws.Dir.
+func Rebase(results []types.ScanResult, ws Workspace) {
+	for i := range results {
+		for j := range results[i].Instances {
+			instance := &results[i].Instances[j]
+			instance.Path = ws.Dir + "/" + instance.Path
+			for k, referrer := range instance.ReferencedBy {
+				if referrer == RootName {
+					instance.ReferencedBy[k] = ws.Dir
+				} else {
+					instance.ReferencedBy[k] = ws.Dir + "/" + referrer
+				}
+			}
+			instance.Workspace = ws.Name
+		}
+	}
+}
+
+// importerName maps a pnpm importer directory to its workspace name
+func importerName(workspaces []Workspace, dir string) string {
+	for _, ws := range workspaces {
+		if ws.Dir == dir {
+			return ws.Name
+		}
+	}
+	if dir == "." {
+		return RootName
+	}
+	return dir
+}
+
+// topLevelPackage extracts the hoisted ancestor name from a root node_modules path
+func topLevelPackage(path string) string {
+	rest, ok := strings.CutPrefix(path, "node_modules/")
+	if !ok {
+		return ""
+	}
+	parts := strings.Split(rest, "/")
+	if strings.HasPrefix(parts[0], "@") && len(parts) > 1 {
+		return parts[0] + "/" + parts[1]
+	}
+	return parts[0]
+}
+
+func parsePatterns(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var patterns []string
+	if err := json.Unmarshal(raw, &patterns); err == nil {
+		return patterns, nil
+	}
+
+	var object struct {
+		Packages []string `json:"packages"`
+	}
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return nil, fmt.Errorf("expected an array of globs or an object with a packages array")
+	}
+	return object.Packages, nil
+}
+
+func readPnpmPatterns(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config struct {
+		Packages []string `yaml:"packages"`
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return config.Packages, nil
+}
+
+func readManifest(path string) (*manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = input.Normalize(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %v", path, err)
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return &m, nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,131 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"scnpm/pkg/types"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create %s: %v", path, err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "package.json"), `{"name": "monorepo", "workspaces": ["packages/*", "!packages/legacy"]}`)
+	writeFile(t, filepath.Join(root, "packages/web/package.json"), `{"name": "@acme/web"}`)
+	writeFile(t, filepath.Join(root, "packages/api/package.json"), `{"name": "@acme/api"}`)
+	writeFile(t, filepath.Join(root, "packages/api/package-lock.json"), `{"lockfileVersion": 3}`)
+	writeFile(t, filepath.Join(root, "packages/legacy/package.json"), `{"name": "legacy"}`)
+	writeFile(t, filepath.Join(root, "packages/docs/README.md"), `not a workspace`)
+
+	workspaces, err := Discover(root)
+	if err != nil {
+		t.Fatalf("Discover() returned error: %v", err)
+	}
+
+	if len(workspaces) != 2 {
+		t.Fatalf("Discover() returned %d workspaces, want 2: %+v", len(workspaces), workspaces)
+	}
+
+	if workspaces[0].Name != "@acme/api" || workspaces[0].Dir != "packages/api" {
+		t.Errorf("workspaces[0] = %+v, want @acme/api in packages/api", workspaces[0])
+	}
+	if workspaces[0].Lockfile == "" {
+		t.Error("Expected packages/api to have its own lockfile")
+	}
+	if workspaces[1].Name != "@acme/web" || workspaces[1].Lockfile != "" {
+		t.Errorf("workspaces[1] = %+v, want @acme/web without lockfile", workspaces[1])
+	}
+}
+
+func TestDiscoverObjectForm(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "package.json"), `{"workspaces": {"packages": ["apps/*"]}}`)
+	writeFile(t, filepath.Join(root, "apps/site/package.json"), `{}`)
+
+	workspaces, err := Discover(root)
+	if err != nil {
+		t.Fatalf("Discover() returned error: %v", err)
+	}
+	if len(workspaces) != 1 || workspaces[0].Name != "apps/site" {
+		t.Errorf("Discover() = %+v, want single workspace named after its directory", workspaces)
+	}
+}
+
+func TestDiscoverWithoutWorkspaces(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "package.json"), `{"name": "single"}`)
+
+	if _, err := Discover(root); err == nil {
+		t.Error("Expected error when no workspaces are declared, got nil")
+	}
+}
+
+func TestAttribute(t *testing.T) {
+	packageLock := &types.PackageLock{
+		LockfileVersion: 3,
+		Packages: map[string]types.Package{
+			"packages/web": {
+				Dependencies: map[string]string{"react": "^18.2.0"},
+			},
+			"packages/api": {
+				DevDependencies: map[string]string{"@types/node": "^18.0.0"},
+			},
+		},
+	}
+	workspaces := []Workspace{
+		{Name: "@acme/api", Dir: "packages/api"},
+		{Name: "@acme/web", Dir: "packages/web"},
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"packages/web/node_modules/debug", "@acme/web"},
+		{"packages/api -> express", "@acme/api"},
+		{"node_modules/react", "@acme/web"},
+		{"node_modules/react/node_modules/loose-envify", "@acme/web"},
+		{"node_modules/@types/node", "@acme/api"},
+		{"node_modules/lodash", RootName},
+		{"node_modules/packages/web-extra", RootName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := Attribute(packageLock, workspaces, tt.path); got != tt.want {
+				t.Errorf("Attribute(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebase(t *testing.T) {
+	results := []types.ScanResult{{
+		Instances: []types.PackageInstance{
+			{Path: "node_modules/debug", ReferencedBy: []string{RootName, "node_modules/express"}},
+			{Path: "node_modules/ms"},
+		},
+	}}
+	Rebase(results, Workspace{Name: "@acme/api", Dir: "packages/api"})
+
+	debug, ms := results[0].Instances[0], results[0].Instances[1]
+	if debug.Path != "packages/api/node_modules/debug" || ms.Path != "packages/api/node_modules/ms" {
+		t.Errorf("Rebase() paths = %q, %q, want them under packages/api", debug.Path, ms.Path)
+	}
+	if len(debug.ReferencedBy) != 2 || debug.ReferencedBy[0] != "packages/api" || debug.ReferencedBy[1] != "packages/api/node_modules/express" {
+		t.Errorf("Rebase() referrers = %q, want the workspace and its express", debug.ReferencedBy)
+	}
+	if debug.Workspace != "@acme/api" || ms.Workspace != "@acme/api" {
+		t.Errorf("Rebase() workspaces = %q, %q, want @acme/api", debug.Workspace, ms.Workspace)
+	}
+}
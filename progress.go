@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressDelay is how long an operation runs before its progress line appears, so that quick
+// scans don't flash one
+const progressDelay = 300 * time.Millisecond
+
+// progressInterval is how often the progress line is redrawn, turning its spinner
+const progressInterval = 100 * time.Millisecond
+
+// progressBarWidth is the number of cells of the progress bar
+const progressBarWidth = 20
+
+// Spinner frames of the progress line; consoles without Unicode get the ASCII ones
+var (
+	spinnerFrames      = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+	asciiSpinnerFrames = []string{"|", "/", "-", `\`}
+)
+
+// progress keeps a line on stderr saying how far a long operation got, redrawn in place: a
+// spinner, a bar once the total is known, the count done and a detail such as the risks found so
+// far. A nil *progress draws nothing, so callers needn't check whether it's shown.
+type progress struct {
+	out   io.Writer
+	unit  string // What's counted, such as "lockfiles"
+	ascii bool
+
+	mu     sync.Mutex
+	done   int
+	total  int
+	detail string
+	frame  int
+	drawn  bool // Whether the line is on screen
+	stop   chan struct{}
+	ticker sync.WaitGroup
+}
+
+// newProgress starts a progress line counting unit on stderr, or returns nil when there should be
+// none: with --no-progress or --quiet, when stderr isn't a terminal, or when info or debug logs
+// stream to it and would break the line up
+func newProgress(unit string, ascii bool) *progress {
+	if noProgress || quiet || !isTerminal(os.Stderr) || slog.Default().Enabled(context.Background(), slog.LevelInfo) {
+		return nil
+	}
+	p := &progress{out: os.Stderr, unit: unit, ascii: ascii, stop: make(chan struct{})}
+	p.ticker.Add(1)
+	go p.tick()
+	return p
+}
+
+// tick redraws the line every progressInterval once progressDelay has passed, until finish
+func (p *progress) tick() {
+	defer p.ticker.Done()
+	select {
+	case <-time.After(progressDelay):
+	case <-p.stop:
+		return
+	}
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		p.mu.Lock()
+		p.draw()
+		p.mu.Unlock()
+		select {
+		case <-ticker.C:
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// update records how many of total are done, with a detail to show after the count; total is 0
+// when it isn't known. It's safe to call from several goroutines.
+func (p *progress) update(done, total int, detail string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done, p.total, p.detail = done, total, detail
+}
+
+// finish stops the line and clears it, so that whatever is printed next starts on a clean line
+func (p *progress) finish() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	p.ticker.Wait()
+	if p.drawn {
+		fmt.Fprint(p.out, "\r\033[K")
+		p.drawn = false
+	}
+}
+
+// draw writes the line over the previous one; p.mu must be held
+func (p *progress) draw() {
+	frames, full, empty := spinnerFrames, "█", "░"
+	if p.ascii {
+		frames, full, empty = asciiSpinnerFrames, "#", "-"
+	}
+	var line strings.Builder
+	line.WriteString(frames[p.frame%len(frames)])
+	p.frame++
+	if p.total > 0 {
+		filled := min(p.done*progressBarWidth/p.total, progressBarWidth)
+		fmt.Fprintf(&line, " [%s%s] %d/%d %s", strings.Repeat(full, filled), strings.Repeat(empty, progressBarWidth-filled), p.done, p.total, p.unit)
+	} else {
+		fmt.Fprintf(&line, " %d %s", p.done, p.unit)
+	}
+	line.WriteString(p.detail)
+	fmt.Fprintf(p.out, "\r%s\033[K", line.String())
+	p.drawn = true
+}
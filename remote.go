@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"scnpm/pkg/cache"
+	"scnpm/pkg/signature"
+	"scnpm/pkg/types"
+)
+
+// packagesURLAuthEnv supplies the Authorization header for --packages-url. Like every secret,
+// it's only read from the environment, never from flags or config files.
+const packagesURLAuthEnv = "SCNPM_PACKAGES_URL_AUTH"
+
+// packagesCacheNamespace holds the last fetched copy of each --packages-url list
+const packagesCacheNamespace = "packages"
+
+// maxDownloadSize caps how much of a remote list or database is read
+const maxDownloadSize = 64 << 20
+
+// readPackagesFromURL fetches a bad-package list over HTTPS and parses it like a packages file.
+// The list is always fetched so new entries show up immediately, but every copy is kept in c
+// (when not nil); if a fetch fails and allowStale is set, the cached copy is used instead,
+// however old. When key is set the list must carry a valid detached signature at <url>.sig.
+// Each query records the URL, the time its list was fetched and who signed it.
+func readPackagesFromURL(ctx context.Context, client *http.Client, c *cache.Cache, key *signature.PublicKey, rawURL, auth string, allowStale bool) ([]types.PackageQuery, []string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid URL '%s': %v", rawURL, err)
+	}
+	if u.Scheme != "https" {
+		return nil, nil, fmt.Errorf("refusing to fetch '%s': only https URLs are supported", rawURL)
+	}
+	// Query strings often carry signatures (e.g. presigned S3 URLs), keep them out of output
+	source := u.Scheme + "://" + u.Host + u.Path
+
+	var warnings []string
+	var sig []byte
+	var sigErr error
+	fetchedAt := time.Now().UTC()
+	data, err := fetchURL(ctx, client, rawURL, auth)
+	fresh := err == nil
+	if err != nil {
+		err = fmt.Errorf("failed to fetch packages list: %v", err)
+		// A canceled fetch says nothing about the server, so it doesn't fall back to the cache
+		if !allowStale || ctx.Err() != nil {
+			return nil, nil, err
+		}
+		if c == nil {
+			return nil, nil, fmt.Errorf("%v (no cached copy available, the cache is disabled)", err)
+		}
+		cached, storedAt, ok := c.GetStale(packagesCacheNamespace, rawURL)
+		if !ok {
+			return nil, nil, fmt.Errorf("%v (no cached copy available)", err)
+		}
+		data = cached
+		fetchedAt = storedAt.UTC()
+		warnings = append(warnings, fmt.Sprintf("could not fetch '%s' (%v); using cached copy from %s", source, err, fetchedAt.Format(time.RFC3339)))
+
+		if key != nil {
+			var ok bool
+			if sig, _, ok = c.GetStale(packagesCacheNamespace, rawURL+".sig"); !ok {
+				sigErr = fmt.Errorf("no cached signature")
+			}
+		}
+	} else if key != nil {
+		sig, sigErr = fetchURL(ctx, client, signatureURL(u), auth)
+	}
+
+	verified, warning, err := verifyDownload(key, source, data, sig, sigErr)
+	if err != nil {
+		return nil, warnings, err
+	}
+	if warning != "" {
+		warnings = append(warnings, warning)
+	}
+
+	// Only cache fresh copies, and only once they passed verification: a list used despite a
+	// failed check (--insecure-skip-verify) mustn't come back later as a trusted stale copy
+	if c != nil && fresh && warning == "" {
+		if err := c.Put(packagesCacheNamespace, rawURL, data); err != nil {
+			logVerbose("failed to cache '%s': %v", source, err)
+		}
+		if sig != nil {
+			c.Put(packagesCacheNamespace, rawURL+".sig", sig)
+		}
+	}
+
+	queries, err := parsePackages(source, data)
+	if err != nil {
+		return nil, warnings, err
+	}
+	for i := range queries {
+		queries[i].Source = source
+		queries[i].FetchedAt = fetchedAt.Format(time.RFC3339)
+		if verified != nil {
+			queries[i].SignedBy = verified.Fingerprint
+			if !verified.SignedAt.IsZero() {
+				queries[i].SignedAt = verified.SignedAt.Format(time.RFC3339)
+			}
+		}
+	}
+	logVerbose("loaded %d entries from '%s'", len(queries), source)
+
+	return queries, warnings, nil
+}
+
+// signatureURL returns where the detached signature of a download is published: <url>.sig
+func signatureURL(u *url.URL) string {
+	sigURL := *u
+	sigURL.Path += ".sig"
+	return sigURL.String()
+}
+
+// loadVerifyKey returns the --verify-key public key, or nil when none is configured
+func loadVerifyKey() (*signature.PublicKey, error) {
+	if verifyKey == "" {
+		return nil, nil
+	}
+	key, err := signature.LoadPublicKey(verifyKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --verify-key: %v", err)
+	}
+	return key, nil
+}
+
+// verifyDownload checks data against its detached signature (sigErr reports why it couldn't be
+// fetched). It returns nil when no key is configured. Failures are errors unless
+// --insecure-skip-verify is set, in which case they come back as a warning.
+func verifyDownload(key *signature.PublicKey, name string, data, sig []byte, sigErr error) (*signature.Result, string, error) {
+	if key == nil {
+		logVerbose("'%s' is not signature-verified; pass --verify-key to require a signature", name)
+		return nil, "", nil
+	}
+
+	var result signature.Result
+	err := sigErr
+	if err != nil {
+		err = fmt.Errorf("no signature available: %v", err)
+	} else {
+		result, err = signature.Verify(key, data, sig)
+	}
+	if err != nil {
+		if insecureSkipVerify {
+			return nil, fmt.Sprintf("signature verification failed for '%s' (%v); used anyway because of --insecure-skip-verify", name, err), nil
+		}
+		return nil, "", fmt.Errorf("signature verification failed for '%s': %v (pass --insecure-skip-verify to use it anyway)", name, err)
+	}
+
+	if result.SignedAt.IsZero() {
+		logVerbose("verified signature on '%s' by key %s", name, result.Fingerprint)
+	} else {
+		logVerbose("verified signature on '%s' by key %s, signed %s", name, result.Fingerprint, result.SignedAt.Format(time.RFC3339))
+	}
+	return &result, "", nil
+}
+
+// fetchURL performs a GET with an optional Authorization header and returns the body
+func fetchURL(ctx context.Context, client *http.Client, rawURL, auth string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	req.Header.Set("User-Agent", "scnpm/"+version)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	if len(data) > maxDownloadSize {
+		return nil, fmt.Errorf("response is larger than %d MB", maxDownloadSize>>20)
+	}
+
+	return data, nil
+}
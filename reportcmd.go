@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"scnpm/pkg/output"
+
+	"github.com/spf13/cobra"
+)
+
+// reportFormats are the formats scnpm report renders a saved report in. CycloneDX and SPDX need
+// the inventory of the lockfile, which saved reports don't carry.
+var reportFormats = []string{"table", "json", "html", "sarif", "markdown", "csv", "github"}
+
+// reportOutput is the format scnpm report renders in
+var reportOutput string
+
+var reportCmd = &cobra.Command{
+	Use:   "report REPORT.json",
+	Short: "Render a saved JSON report in another format",
+	Long: `Render a JSON report (--output json), such as one archived by a CI job, in another format
+without scanning again: an HTML page or Markdown summary to publish, SARIF to upload, CSV for a
+spreadsheet, or the table. Reports written by older scnpm versions are upgraded to the current
+schema first; reports of newer versions are refused. "-" reads the report from stdin.
+
+The rendered report keeps the scan time, lockfiles and warnings of the saved one. The exit
+status is 1 when the report has risks.
+
+  scnpm report results.json --output html --output-file report.html
+  scnpm report results.json --output csv > findings.csv`,
+	Args: cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return []string{"json"}, cobra.ShellCompDirectiveFilterFileExt
+	},
+	Run: runReport,
+}
+
+func init() {
+	reportCmd.Flags().StringVarP(&reportOutput, "output", "o", "table", "Output format ("+strings.Join(reportFormats, ", ")+")")
+	reportCmd.Flags().StringVar(&outputFile, "output-file", "", "Write the report to this file instead of stdout")
+	completeFlagValues(reportCmd, "output", reportFormats)
+	rootCmd.AddCommand(reportCmd)
+}
+
+func runReport(cmd *cobra.Command, args []string) {
+	if !containsString(reportFormats, reportOutput) {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", reportOutput)
+		os.Exit(exitError)
+	}
+	var data []byte
+	var err error
+	if args[0] == stdinPath {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	saved, err := output.ReadReport(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", args[0], err)
+		os.Exit(exitError)
+	}
+	if saved.SchemaVersion != output.JSONSchemaVersion {
+		logVerbose("upgraded report from schema version %d to %d", saved.SchemaVersion, output.JSONSchemaVersion)
+	}
+
+	var report io.Writer = os.Stdout
+	var reportFile *os.File
+	if outputFile != "" {
+		reportFile, err = os.Create(outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+			os.Exit(exitError)
+		}
+		report = reportFile
+	}
+	color, _ := useColor("auto", report)
+	config := output.OutputConfig{
+		ShowSafe:      true,
+		ShowLockfiles: len(saved.Lockfiles) > 1,
+		ToolVersion:   saved.ToolVersion,
+		ToolCommit:    saved.ToolCommit,
+		Warnings:      saved.Warnings,
+		ASCII:         asciiLocale(),
+		Color:         color,
+	}
+	if len(saved.Lockfiles) > 0 {
+		config.Lockfile = saved.Lockfiles[0]
+	}
+	switch reportOutput {
+	case "json":
+		err = output.OutputReportJSON(report, saved, config)
+	case "html":
+		err = output.OutputReportHTML(report, saved, config)
+	case "sarif":
+		err = output.OutputSARIF(report, saved.Results, config)
+	case "markdown":
+		err = output.OutputGitHubSummary(report, saved.Results, config)
+	case "csv":
+		err = output.OutputCSV(report, saved.Results, config)
+	case "github":
+		err = output.OutputGitHub(report, saved.Results, config)
+	default:
+		err = output.OutputTable(report, saved.Results, config)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(exitError)
+	}
+	closeReport(reportFile)
+	if output.CountResults(saved.Results).Risks > 0 {
+		os.Exit(exitFindings)
+	}
+}
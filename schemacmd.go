@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+
+	"scnpm/pkg/output"
+
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of --output json",
+	Long: `Print the JSON Schema (draft 2020-12) that --output json reports conform to. Reports
+carry a schemaVersion; it changes whenever the shape of the report does, along with this schema.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		os.Stdout.Write(output.JSONSchema)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
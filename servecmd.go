@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"scnpm/pkg/output"
+	"scnpm/pkg/scanner"
+	"scnpm/pkg/scnpm"
+	"scnpm/pkg/types"
+
+	"github.com/spf13/cobra"
+)
+
+// serveShutdownTimeout is how long scans in flight may take to finish once the server is told
+// to stop
+const serveShutdownTimeout = 30 * time.Second
+
+var (
+	serveListen   string
+	serveMaxBody  int64
+	serveMaxScans int
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [badpak.json ...]",
+	Short: "Scan lockfiles POSTed over HTTP",
+	Long: `Run an HTTP server that scans the lockfiles other tools POST to it, so that they don't need
+the CLI installed:
+
+  POST /scan     The body is a lockfile (package-lock.json, yarn.lock or pnpm-lock.yaml), or a
+                 JSON envelope {"lockfile": ..., "lockfileName": ..., "packages": [...],
+                 "options": {...}}. The response is the JSON report of a scan (--output json).
+  GET  /healthz  Reports that the server is up and how many bad packages it scans for.
+  GET  /version  The scnpm version, commit and build date.
+
+Bad packages are loaded once at startup, from the lists given as arguments, --packages-file,
+--packages-url, --packages and the built-in database, as for a scan. A request can add its own
+with the packages query parameter or envelope field, or scan for only those with no-defaults.
+Filters are set per request: dev-only, nested-only, min-depth, max-depth, fuzzy, ignore-case,
+match-unscoped and risk-only.
+
+The server stops on SIGTERM or Ctrl-C, letting the scans in flight finish.
+
+  scnpm serve --listen :8080 badpak.json
+  curl --data-binary @package-lock.json 'http://localhost:8080/scan?risk-only=true'`,
+	Args: cobra.ArbitraryArgs,
+	Run:  runServe,
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", ":8080", "Address to listen on")
+	serveCmd.Flags().Int64Var(&serveMaxBody, "max-body-size", 32<<20, "Largest request body accepted, in bytes")
+	serveCmd.Flags().IntVar(&serveMaxScans, "max-scans", runtime.NumCPU(), "How many scans run at once; further requests wait for a slot")
+	serveCmd.Flags().StringSliceVar(&packagesFiles, "packages-file", []string{}, "Path to a file (or directory of files) listing bad packages to scan for; repeat to merge several lists")
+	serveCmd.Flags().StringSliceVarP(&packagesFlag, "packages", "p", []string{}, "List of packages to scan for (format: package@version, or a bare name for any version)")
+	serveCmd.Flags().StringVar(&packagesURL, "packages-url", "", "HTTPS URL of a bad-packages list to fetch at startup; its Authorization header comes from $"+packagesURLAuthEnv)
+	serveCmd.Flags().DurationVar(&packagesURLTimeout, "packages-url-timeout", 30*time.Second, "Timeout for fetching --packages-url")
+	serveCmd.Flags().StringVar(&osvFile, "osv-file", "", "Path to an OSV advisory, JSON array of advisories, or zip export")
+	serveCmd.Flags().BoolVar(&noBuiltin, "no-builtin", false, "Don't scan for the packages in the built-in advisory database")
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, args []string) {
+	if serveMaxBody < 1 || serveMaxScans < 1 {
+		fmt.Fprintf(os.Stderr, "Error: --max-body-size and --max-scans must be 1 or more\n")
+		os.Exit(exitError)
+	}
+	queries, warnings, err := loadQueries(cmd.Context(), args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	printWarnings(warnings)
+
+	listener, err := net.Listen("tcp", serveListen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	server := &http.Server{
+		Handler:           newScanServer(queries, serveMaxBody, serveMaxScans),
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+	fmt.Fprintf(os.Stderr, "Listening on %s, scanning for %d bad packages\n", listener.Addr(), len(queries))
+
+	select {
+	case err := <-served:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	case <-ctx.Done():
+	}
+	fmt.Fprintf(os.Stderr, "Shutting down\n")
+	shutdown, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdown); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: scans still running after %s: %v\n", serveShutdownTimeout, err)
+		os.Exit(exitError)
+	}
+}
+
+// scanServer answers the HTTP API of scnpm serve
+type scanServer struct {
+	queries []types.PackageQuery // Loaded at startup, shared read-only by every request
+	maxBody int64
+	slots   chan struct{} // Holds a token per scan running
+}
+
+// scanRequest is the JSON envelope POST /scan accepts instead of a bare lockfile
+type scanRequest struct {
+	Lockfile     json.RawMessage `json:"lockfile"`     // The lockfile's JSON, or a string holding a yarn.lock or pnpm-lock.yaml
+	LockfileName string          `json:"lockfileName"` // Reported in the results and used to recognize the format
+	Packages     []string        `json:"packages"`     // package@version entries to scan for besides the startup lists
+	Options      scanOptions     `json:"options"`
+}
+
+// scanOptions are the per-request settings of a scan, as query parameters or in the envelope
+type scanOptions struct {
+	NoDefaults    bool `json:"noDefaults"` // Scan for the request's packages only, not the startup lists
+	DevOnly       bool `json:"devOnly"`
+	NestedOnly    bool `json:"nestedOnly"`
+	MinDepth      int  `json:"minDepth"`
+	MaxDepth      *int `json:"maxDepth"`
+	Fuzzy         bool `json:"fuzzy"`
+	IgnoreCase    bool `json:"ignoreCase"`
+	MatchUnscoped bool `json:"matchUnscoped"`
+	RiskOnly      bool `json:"riskOnly"`
+}
+
+// newScanServer returns the handler of scnpm serve, scanning for queries and the packages each
+// request adds, with bodies up to maxBody bytes and at most maxScans scans at once
+func newScanServer(queries []types.PackageQuery, maxBody int64, maxScans int) http.Handler {
+	s := &scanServer{queries: queries, maxBody: maxBody, slots: make(chan struct{}, maxScans)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /scan", s.scan)
+	mux.HandleFunc("GET /healthz", s.healthz)
+	mux.HandleFunc("GET /version", s.version)
+	return mux
+}
+
+func (s *scanServer) healthz(w http.ResponseWriter, r *http.Request) {
+	writeServeJSON(w, http.StatusOK, map[string]any{"status": "ok", "queries": len(s.queries)})
+}
+
+func (s *scanServer) version(w http.ResponseWriter, r *http.Request) {
+	writeServeJSON(w, http.StatusOK, map[string]string{"version": version, "commit": commit, "date": date})
+}
+
+func (s *scanServer) scan(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	request, err := s.readScanRequest(w, r)
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+			err = fmt.Errorf("request body larger than %d bytes", tooLarge.Limit)
+		}
+		writeServeError(w, status, err)
+		return
+	}
+
+	queries := s.queries
+	if request.Options.NoDefaults {
+		queries = nil
+	}
+	var warnings []string
+	if len(request.Packages) > 0 {
+		var extra []types.PackageQuery
+		for _, spec := range request.Packages {
+			query, err := scnpm.ParseQuery(spec)
+			if err != nil {
+				writeServeError(w, http.StatusBadRequest, fmt.Errorf("invalid package '%s': %v", spec, err))
+				return
+			}
+			query.Source = cliSource
+			extra = append(extra, query)
+		}
+		extra, warnings = resolveDistTags(r.Context(), extra)
+		queries = scnpm.MergeQueries(scnpm.CopyQueries(queries), extra)
+	}
+	if len(queries) == 0 {
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf("no packages to scan for"))
+		return
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-r.Context().Done():
+		writeScanInterrupted(w, r.Context().Err())
+		return
+	}
+
+	options := request.Options
+	config := scanner.FilterConfig{
+		ShowDevOnly:    options.DevOnly,
+		ShowNestedOnly: options.NestedOnly,
+		MinDepth:       options.MinDepth,
+		MaxDepth:       options.MaxDepth,
+		Fuzzy:          options.Fuzzy,
+		MatchUnscoped:  options.MatchUnscoped,
+		IgnoreCase:     options.IgnoreCase,
+	}
+	if warning := depthBoundsWarning(config); warning != "" {
+		warnings = append(warnings, warning)
+	}
+	scan, err := scnpm.Scan(r.Context(), scnpm.ScanRequest{
+		Lockfiles: []scnpm.Lockfile{{Name: request.LockfileName, Reader: bytes.NewReader(request.data)}},
+		Queries:   queries,
+		Options:   scnpm.Options{Filter: config, SortBySeverity: true},
+	})
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		writeScanInterrupted(w, err)
+		return
+	}
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf("invalid lockfile: %v", manifestHint(err)))
+		return
+	}
+	results := scan.Results
+	warnings = append(warnings, scan.Warnings...)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = output.OutputJSON(w, results, output.OutputConfig{
+		ShowSafe:    true,
+		RiskOnly:    options.RiskOnly,
+		ToolVersion: version,
+		ToolCommit:  commit,
+		Lockfile:    request.LockfileName,
+		Warnings:    warnings,
+	})
+	if err != nil {
+		slog.Error("writing scan response failed", "error", err)
+		return
+	}
+	slog.Info("scan", "lockfile", request.LockfileName, "queries", len(queries), "risks", scan.Totals.Risks, "duration", time.Since(start))
+}
+
+// parsedScanRequest is a POST /scan request with its lockfile content decoded
+type parsedScanRequest struct {
+	scanRequest
+	data []byte
+}
+
+// readScanRequest reads the lockfile and options of a POST /scan request. Query parameters set
+// the options first; an envelope's fields override them.
+func (s *scanServer) readScanRequest(w http.ResponseWriter, r *http.Request) (*parsedScanRequest, error) {
+	request := &parsedScanRequest{}
+	if err := scanOptionsFromQuery(r.URL.Query(), &request.scanRequest); err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBody))
+	if err != nil {
+		return nil, err
+	}
+
+	// A lockfile has no top-level "lockfile" field; anything that isn't JSON is a yarn.lock or
+	// pnpm-lock.yaml
+	var probe struct {
+		Lockfile json.RawMessage `json:"lockfile"`
+	}
+	if json.Unmarshal(body, &probe) == nil && len(probe.Lockfile) > 0 {
+		if err := json.Unmarshal(body, &request.scanRequest); err != nil {
+			return nil, fmt.Errorf("invalid scan request: %v", err)
+		}
+		request.data = request.Lockfile
+		var text string
+		if json.Unmarshal(request.Lockfile, &text) == nil {
+			request.data = []byte(text)
+		}
+	} else {
+		request.data = body
+	}
+	if len(request.data) == 0 {
+		return nil, fmt.Errorf("the request has no lockfile")
+	}
+	if request.LockfileName == "" {
+		request.LockfileName = "package-lock.json"
+	}
+	if request.Options.MinDepth < 0 || request.Options.MaxDepth != nil && *request.Options.MaxDepth < 0 {
+		return nil, fmt.Errorf("depths must be 0 or more")
+	}
+	return request, nil
+}
+
+// scanOptionsFromQuery reads the lockfile name, packages and options given as query parameters
+func scanOptionsFromQuery(query url.Values, request *scanRequest) error {
+	request.LockfileName = query.Get("lockfile-name")
+	for _, packages := range query["packages"] {
+		request.Packages = append(request.Packages, strings.Split(packages, ",")...)
+	}
+
+	options := &request.Options
+	for name, value := range map[string]*bool{
+		"no-defaults":    &options.NoDefaults,
+		"dev-only":       &options.DevOnly,
+		"nested-only":    &options.NestedOnly,
+		"fuzzy":          &options.Fuzzy,
+		"ignore-case":    &options.IgnoreCase,
+		"match-unscoped": &options.MatchUnscoped,
+		"risk-only":      &options.RiskOnly,
+	} {
+		if !query.Has(name) {
+			continue
+		}
+		// A bare ?risk-only turns it on
+		parsed, err := strconv.ParseBool(query.Get(name))
+		if query.Get(name) == "" {
+			parsed, err = true, nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s must be true or false, got '%s'", name, query.Get(name))
+		}
+		*value = parsed
+	}
+	if query.Has("min-depth") {
+		depth, err := strconv.Atoi(query.Get("min-depth"))
+		if err != nil {
+			return fmt.Errorf("min-depth must be a number, got '%s'", query.Get("min-depth"))
+		}
+		options.MinDepth = depth
+	}
+	if query.Has("max-depth") {
+		depth, err := strconv.Atoi(query.Get("max-depth"))
+		if err != nil {
+			return fmt.Errorf("max-depth must be a number, got '%s'", query.Get("max-depth"))
+		}
+		options.MaxDepth = &depth
+	}
+	return nil
+}
+
+// writeServeJSON writes document as the JSON response of a request
+func writeServeJSON(w http.ResponseWriter, status int, document any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(document)
+}
+
+// writeServeError answers a request with an error message
+func writeServeError(w http.ResponseWriter, status int, err error) {
+	writeServeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// statusClientClosedRequest is nginx's status for a request the client gave up on before the
+// answer, which net/http has no name for
+const statusClientClosedRequest = 499
+
+// writeScanInterrupted answers a scan its request's context cut short: 499 when the client went
+// away and 503 when it ran out of time
+func writeScanInterrupted(w http.ResponseWriter, err error) {
+	status := statusClientClosedRequest
+	if errors.Is(err, context.DeadlineExceeded) {
+		status = http.StatusServiceUnavailable
+	}
+	slog.Warn("scan interrupted", "error", err)
+	writeServeError(w, status, fmt.Errorf("scan interrupted: %v", err))
+}
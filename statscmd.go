@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"scnpm/pkg/output"
+	"scnpm/pkg/scanner"
+
+	"github.com/spf13/cobra"
+)
+
+// statsOutput is the format of the statistics: table or json
+var statsOutput string
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Print statistics about what a lockfile installs",
+	Long: `Count what the lockfile installs: packages (every copy), unique names and name@version pairs,
+the production and development split, a histogram of nesting depths, packages with install
+scripts or without an integrity hash, and the packages installed in the most versions. Compare
+the numbers before and after a dependency cleanup.
+
+  scnpm stats -f package-lock.json
+  scnpm stats --output json`,
+	Args: cobra.NoArgs,
+	Run:  runStats,
+}
+
+func init() {
+	statsCmd.Flags().StringVarP(&packageLockPath, "file", "f", "package-lock.json", "Path to package-lock.json, yarn.lock or pnpm-lock.yaml (use - for stdin)")
+	statsCmd.Flags().StringVarP(&statsOutput, "output", "o", "table", "Output format (table, json)")
+	completeLockfileFlags(statsCmd, []string{"table", "json"})
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStats(cmd *cobra.Command, args []string) {
+	if statsOutput != "table" && statsOutput != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", statsOutput)
+		os.Exit(exitError)
+	}
+	packageLock, err := readPackageLock(packageLockPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading '%s': %v\n", packageLockPath, err)
+		os.Exit(exitError)
+	}
+
+	stats := scanner.LockfileStats(packageLock)
+	if statsOutput == "json" {
+		err = output.OutputStatsJSON(os.Stdout, stats, relativePath(packageLockPath))
+	} else {
+		err = output.OutputStats(os.Stdout, stats, output.OutputConfig{ASCII: asciiLocale()})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(exitError)
+	}
+}
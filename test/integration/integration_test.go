@@ -1,11 +1,18 @@
 package integration
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"unicode/utf16"
 
+	"scnpm/pkg/input"
+	"scnpm/pkg/output"
 	"scnpm/pkg/scanner"
 	"scnpm/pkg/types"
 )
@@ -143,13 +150,110 @@ func TestCrossDirectoryScanning(t *testing.T) {
 	}
 }
 
+func TestEncodedLockfiles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	packageLockContent := `{
+		"name": "encoded-project",
+		"version": "1.0.0",
+		"lockfileVersion": 2,
+		"packages": {
+			"node_modules/debug": {
+				"version": "4.3.4",
+				"dev": false
+			}
+		}
+	}`
+
+	// Fixtures as produced by Windows editors and PowerShell redirection
+	utf16LE := []byte{0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune(packageLockContent)) {
+		utf16LE = binary.LittleEndian.AppendUint16(utf16LE, u)
+	}
+	utf16BE := []byte{0xFE, 0xFF}
+	for _, u := range utf16.Encode([]rune(packageLockContent)) {
+		utf16BE = binary.BigEndian.AppendUint16(utf16BE, u)
+	}
+
+	fixtures := map[string][]byte{
+		"utf8-bom.json": append([]byte{0xEF, 0xBB, 0xBF}, packageLockContent...),
+		"utf16le.json":  utf16LE,
+		"utf16be.json":  utf16BE,
+	}
+
+	queries := []types.PackageQuery{{Name: "debug", Version: "4.3.4"}}
+
+	for name, content := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, name)
+			if err := os.WriteFile(path, content, 0644); err != nil {
+				t.Fatalf("Failed to create %s: %v", name, err)
+			}
+
+			packageLock, err := readTestPackageLock(path)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", name, err)
+			}
+
+			if packageLock.Name != "encoded-project" {
+				t.Errorf("Expected project name 'encoded-project', got '%s'", packageLock.Name)
+			}
+
+			results := scanner.ScanPackages(packageLock, queries, scanner.FilterConfig{})
+			if !results[0].Found {
+				t.Errorf("Expected debug@4.3.4 to be found in %s", name)
+			}
+		})
+	}
+}
+
 // Helper function to read package-lock.json for tests
+func TestDeterministicOutput(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1714564800")
+
+	// Many instances and references of the same package, so that map order would show
+	packages := map[string]types.Package{"": {Dependencies: map[string]string{"lodash": "^4.17.0"}}}
+	for i := 0; i < 20; i++ {
+		parent := fmt.Sprintf("node_modules/dep-%02d", i)
+		packages[parent] = types.Package{Version: "1.0.0", Dependencies: map[string]string{"lodash": "^4.17.0"}}
+		packages[parent+"/node_modules/lodash"] = types.Package{Version: fmt.Sprintf("4.17.%d", i)}
+	}
+	packageLock := &types.PackageLock{LockfileVersion: 3, Packages: packages}
+	queries := []types.PackageQuery{{Name: "lodash", Version: "<4.17.21"}, {Name: "dep-*"}}
+
+	run := func(format func(io.Writer, []types.ScanResult, output.OutputConfig) error) []byte {
+		results := scanner.ExpandPatterns(scanner.ScanPackages(packageLock, queries, scanner.FilterConfig{}))
+		var buf bytes.Buffer
+		if err := format(&buf, results, output.OutputConfig{ShowSafe: true}); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	for name, format := range map[string]func(io.Writer, []types.ScanResult, output.OutputConfig) error{
+		"json":  output.OutputJSON,
+		"table": output.OutputTable,
+	} {
+		first := run(format)
+		for i := 0; i < 5; i++ {
+			if again := run(format); !bytes.Equal(first, again) {
+				t.Fatalf("%s output differs between identical runs:\n%s\n---\n%s", name, first, again)
+			}
+		}
+	}
+}
+
 func readTestPackageLock(path string) (*types.PackageLock, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
+	data, err = input.Normalize(data)
+	if err != nil {
+		return nil, err
+	}
+
 	var packageLock types.PackageLock
 	err = json.Unmarshal(data, &packageLock)
 	if err != nil {
@@ -157,4 +261,4 @@ func readTestPackageLock(path string) (*types.PackageLock, error) {
 	}
 
 	return &packageLock, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"scnpm/pkg/output"
+	"scnpm/pkg/scanner"
+
+	"github.com/spf13/cobra"
+)
+
+// Options of scnpm tree
+var (
+	treeOutput string
+	treeDepth  int
+	treeAll    bool
+)
+
+var treeCmd = &cobra.Command{
+	Use:   "tree [badpak.json | package@version ...]",
+	Short: "Show the dependency tree leading to risky packages",
+	Long: `Print the dependency tree of the lockfile in the style of npm ls, pruned to the branches that
+lead to a risky package, so it shows where each one sits and what pulls it in. Risky packages
+are marked; --all prints the whole tree. A package is listed in full once, at its shallowest
+place in the tree, and marked deduped everywhere else.
+
+Bad packages come from the lists and package@version arguments, --packages-file, --packages and
+the built-in database, as for a scan. The exit status is 1 when a risky package is installed.
+
+  scnpm tree badpak.json -f package-lock.json
+  scnpm tree --all --depth 1`,
+	Args: cobra.ArbitraryArgs,
+	Run:  runTree,
+}
+
+func init() {
+	treeCmd.Flags().StringVarP(&packageLockPath, "file", "f", "package-lock.json", "Path to package-lock.json, yarn.lock or pnpm-lock.yaml (use - for stdin)")
+	treeCmd.Flags().StringSliceVar(&packagesFiles, "packages-file", []string{}, "Path to a file (or directory of files) listing bad packages to scan for; repeat to merge several lists")
+	treeCmd.Flags().StringSliceVarP(&packagesFlag, "packages", "p", []string{}, "List of packages to scan for (format: package@version, or a bare name for any version)")
+	treeCmd.Flags().BoolVar(&noBuiltin, "no-builtin", false, "Don't scan for the packages in the built-in advisory database")
+	treeCmd.Flags().IntVar(&treeDepth, "depth", unlimitedDepth, "Levels of dependencies to show (0 for direct dependencies only, -1 for no limit)")
+	treeCmd.Flags().BoolVar(&treeAll, "all", false, "Show the whole tree, not only the branches leading to risky packages")
+	treeCmd.Flags().BoolVar(&asciiOutput, "ascii", false, "Draw the tree with ASCII connectors; on by default when the locale isn't UTF-8")
+	treeCmd.Flags().StringVarP(&treeOutput, "output", "o", "table", "Output format (table, json)")
+	completeLockfileFlags(treeCmd, []string{"table", "json"})
+	rootCmd.AddCommand(treeCmd)
+}
+
+func runTree(cmd *cobra.Command, args []string) {
+	if treeOutput != "table" && treeOutput != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", treeOutput)
+		os.Exit(exitError)
+	}
+	if treeDepth < unlimitedDepth {
+		fmt.Fprintf(os.Stderr, "Error: --depth must be %d (no limit) or more\n", unlimitedDepth)
+		os.Exit(exitError)
+	}
+	queries, warnings, err := loadQueries(cmd.Context(), args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	packageLock, err := readPackageLock(packageLockPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading '%s': %v\n", packageLockPath, err)
+		os.Exit(exitError)
+	}
+
+	risky := make(map[string]bool)
+	for _, result := range scanner.ExpandPatterns(scanner.ScanPackages(packageLock, queries, scanner.FilterConfig{})) {
+		for _, instance := range result.Instances {
+			if !instance.IsReference {
+				risky[instance.Path] = true
+			}
+		}
+	}
+	tree := scanner.DependencyTree(packageLock, scanner.TreeConfig{Risky: risky, All: treeAll, Depth: treeDepth})
+
+	printWarnings(warnings)
+	if treeOutput == "json" {
+		err = output.OutputTreeJSON(os.Stdout, tree, relativePath(packageLockPath))
+	} else {
+		color, _ := useColor("auto", os.Stdout)
+		err = output.OutputTree(os.Stdout, tree, output.OutputConfig{
+			Color: color,
+			ASCII: asciiOutput || !cmd.Flags().Changed("ascii") && asciiLocale(),
+		})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(exitError)
+	}
+	if len(risky) > 0 {
+		os.Exit(exitFindings)
+	}
+	if !treeAll && treeOutput != "json" {
+		fmt.Fprintf(os.Stderr, "No risky packages are installed by '%s'\n", packageLockPath)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"scnpm/pkg/advisories"
+	"scnpm/pkg/signature"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultDatabaseURL is where releases publish the advisory database (with a "<url>.sha256" checksum)
+const defaultDatabaseURL = "https://github.com/GigacoreLLC/scnpm/releases/latest/download/advisories.json"
+
+// exitUpdateAvailable is the update-db --check exit code when a newer database exists,
+// following `dnf check-update`
+const exitUpdateAvailable = 100
+
+var updateDBCmd = &cobra.Command{
+	Use:   "update-db",
+	Short: "Download the latest advisory database",
+	Long: `Download the latest advisory database into the local data directory
+($XDG_DATA_HOME/scnpm, ~/.local/share/scnpm by default). Scans use it instead of the
+database built into the binary whenever it is newer.
+
+With --check nothing is downloaded to disk; the exit code is 0 when the local database is
+current and ` + fmt.Sprint(exitUpdateAvailable) + ` when an update is available, for use from cron.`,
+	Args: cobra.NoArgs,
+	Run:  runUpdateDB,
+}
+
+var (
+	databaseURL     string
+	databaseTimeout time.Duration
+	checkOnly       bool
+)
+
+func init() {
+	updateDBCmd.Flags().StringVar(&databaseURL, "db-url", defaultDatabaseURL, "URL of the advisory database; its checksum is fetched from <url>.sha256")
+	updateDBCmd.Flags().DurationVar(&databaseTimeout, "timeout", 60*time.Second, "Timeout for each download")
+	updateDBCmd.Flags().BoolVar(&checkOnly, "check", false, "Only report whether an update is available")
+	rootCmd.AddCommand(updateDBCmd)
+}
+
+func runUpdateDB(cmd *cobra.Command, args []string) {
+	path, err := advisories.DefaultPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error locating data directory: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	current, err := advisories.Load(path)
+	if current == nil {
+		fmt.Fprintf(os.Stderr, "Error loading advisory database: %v\n", err)
+		os.Exit(exitError)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	key, err := loadVerifyKey()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	client := &http.Client{Timeout: databaseTimeout}
+	db, data, verified, err := fetchDatabase(cmd.Context(), client, key, databaseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error updating advisory database: %v\n", err)
+		os.Exit(exitError)
+	}
+	if verified != nil {
+		fmt.Printf("Signature verified: key %s\n", verified.Fingerprint)
+	}
+
+	newEntries := db.NewEntries(current)
+	if !db.Updated.After(current.Updated) {
+		fmt.Printf("Advisory database is up to date (updated %s, %d entries)\n", current.Updated.Format(time.RFC3339), len(current.Queries()))
+		return
+	}
+
+	if checkOnly {
+		fmt.Printf("Update available: database from %s with %d new entries (local copy from %s)\n",
+			db.Updated.Format(time.RFC3339), newEntries, current.Updated.Format(time.RFC3339))
+		os.Exit(exitUpdateAvailable)
+	}
+
+	if err := advisories.Save(path, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving advisory database to '%s': %v\n", path, err)
+		os.Exit(exitError)
+	}
+	fmt.Printf("Updated advisory database to %s: %d new entries, %d total (saved to %s)\n",
+		db.Updated.Format(time.RFC3339), newEntries, len(db.Queries()), path)
+}
+
+// fetchDatabase downloads the database with its checksum, and its signature when a key is
+// configured, and validates all of them
+func fetchDatabase(ctx context.Context, client *http.Client, key *signature.PublicKey, rawURL string) (*advisories.Database, []byte, *signature.Result, error) {
+	data, err := fetchURL(ctx, client, rawURL, "")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to fetch '%s': %v", rawURL, err)
+	}
+	checksum, err := fetchURL(ctx, client, rawURL+".sha256", "")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to fetch checksum '%s.sha256': %v", rawURL, err)
+	}
+	if err := advisories.VerifyChecksum(data, checksum); err != nil {
+		return nil, nil, nil, fmt.Errorf("refusing database from '%s': %v", rawURL, err)
+	}
+
+	var sig []byte
+	var sigErr error
+	if key != nil {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid database URL '%s': %v", rawURL, err)
+		}
+		sig, sigErr = fetchURL(ctx, client, signatureURL(u), "")
+	}
+	verified, warning, err := verifyDownload(key, rawURL, data, sig, sigErr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if warning != "" {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	db, err := advisories.Parse(data)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid database from '%s': %v", rawURL, err)
+	}
+	return db, data, verified, nil
+}
+
+// loadAdvisoryDatabase returns the newest available advisory database, falling back to the
+// built-in one when the downloaded copy can't be used
+func loadAdvisoryDatabase() (*advisories.Database, error) {
+	path, err := advisories.DefaultPath()
+	if err != nil {
+		return advisories.Builtin()
+	}
+
+	db, err := advisories.Load(path)
+	if err != nil && db != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v; using the built-in database\n", err)
+		return db, nil
+	}
+	return db, err
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"scnpm/pkg/audit"
+	"scnpm/pkg/output"
+	"scnpm/pkg/scanner"
+	"scnpm/pkg/scnpm"
+	"scnpm/pkg/types"
+	"scnpm/pkg/verify"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyOutput      string
+	verifyOnly        []string
+	verifyConcurrency int
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the lockfile's integrity hashes against the registry",
+	Long: `Fetch the metadata of every installed registry package and compare the integrity hash the
+registry publishes for that exact version with the one the lockfile records. A mismatch means the
+lockfile was tampered with or resolved through a compromised mirror, and is reported as critical.
+Packages and versions the registry doesn't have (unpublished, or never published there) are
+reported on their own. Packages without an integrity hash or not installed from the registry are
+skipped; --require-integrity reports those.
+
+Lookups run --concurrency at a time and are cached like the online advisory sources. The exit
+status is 1 when a hash doesn't match or a package isn't published.
+
+  scnpm verify -f package-lock.json
+  scnpm verify --only event-stream,@ctrl/tinycolor --output json`,
+	Args: cobra.NoArgs,
+	Run:  runVerify,
+}
+
+func init() {
+	verifyCmd.Flags().StringVarP(&packageLockPath, "file", "f", "package-lock.json", "Path to package-lock.json, yarn.lock or pnpm-lock.yaml (use - for stdin)")
+	verifyCmd.Flags().StringVarP(&verifyOutput, "output", "o", "table", "Output format (table, json)")
+	verifyCmd.Flags().StringSliceVar(&verifyOnly, "only", []string{}, "Verify only these packages (package, package@version or a glob such as @ctrl/*)")
+	verifyCmd.Flags().IntVar(&verifyConcurrency, "concurrency", 8, "How many packages are looked up at once")
+	verifyCmd.Flags().StringVar(&registryURL, "registry", audit.DefaultRegistry, "npm registry to verify against (token from $"+registryTokenEnv+")")
+	verifyCmd.Flags().BoolVar(&noCache, "no-cache", false, "Always ask the registry rather than reuse cached metadata")
+	completeLockfileFlags(verifyCmd, []string{"table", "json"})
+	rootCmd.AddCommand(verifyCmd)
+}
+
+func runVerify(cmd *cobra.Command, args []string) {
+	if verifyOutput != "table" && verifyOutput != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", verifyOutput)
+		os.Exit(exitError)
+	}
+	if verifyConcurrency < 1 {
+		fmt.Fprintf(os.Stderr, "Error: --concurrency must be 1 or more\n")
+		os.Exit(exitError)
+	}
+	var only []types.PackageQuery
+	for _, spec := range verifyOnly {
+		query, err := scnpm.ParseQuery(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --only '%s': %v\n", spec, err)
+			os.Exit(exitError)
+		}
+		only = append(only, query)
+	}
+
+	packageLock, err := readPackageLock(packageLockPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading '%s': %v\n", packageLockPath, err)
+		os.Exit(exitError)
+	}
+	packages, skipped := verifiablePackages(scanner.Inventory(packageLock), only)
+
+	client := &verify.Client{
+		Remote:      newRemoteClient(),
+		Registry:    registryURL,
+		Token:       os.Getenv(registryTokenEnv),
+		Cache:       openCache(),
+		Concurrency: verifyConcurrency,
+	}
+	bar := newProgress("packages", asciiLocale())
+	client.Progress = func(done, total int) {
+		bar.update(done, total, "")
+	}
+	ctx := cmd.Context()
+	results := client.Verify(ctx, packages)
+	bar.finish()
+
+	// Once interrupted, the packages not looked up yet all fail alike; one warning covers them
+	interrupted := ctx.Err() != nil
+	failed := 0
+	for _, result := range results {
+		if result.Status == verify.StatusError {
+			failed++
+			if !interrupted {
+				printWarnings([]string{fmt.Sprintf("%s@%s was not verified: %s", result.Name, result.Version, result.Error)})
+			}
+		}
+	}
+	if interrupted {
+		printWarnings([]string{fmt.Sprintf("verification interrupted; %d packages were not verified", failed)})
+	}
+
+	report := output.VerifyReport{Lockfile: relativePath(packageLockPath), Registry: registryURL, Results: results, Skipped: skipped}
+	if verifyOutput == "json" {
+		err = output.OutputVerifyJSON(os.Stdout, report)
+	} else {
+		color, _ := useColor("auto", os.Stdout)
+		err = output.OutputVerify(os.Stdout, report, output.OutputConfig{Color: color, ASCII: asciiLocale()})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	counts := output.VerifyCounts(results)
+	switch {
+	case interrupted:
+		os.Exit(exitInterrupted)
+	case counts[verify.StatusMismatch]+counts[verify.StatusUnpublished] > 0:
+		os.Exit(exitFindings)
+	case failed > 0 && failed == len(results):
+		// Nothing could be checked, which mustn't pass for a clean result
+		os.Exit(exitError)
+	}
+}
+
+// verifiablePackages returns the installed registry packages with an integrity hash, narrowed to
+// those matching one of only when it's set, and how many installed packages were skipped for
+// lacking a hash or coming from elsewhere
+func verifiablePackages(inventory []types.PackageInstance, only []types.PackageQuery) ([]verify.Package, int) {
+	var packages []verify.Package
+	skipped := 0
+	for _, instance := range inventory {
+		if len(only) > 0 && !matchesAny(instance, only) {
+			continue
+		}
+		if instance.InstallSource != types.SourceRegistry || instance.Integrity == "" || instance.Version == "" {
+			skipped++
+			continue
+		}
+		packages = append(packages, verify.Package{Name: instance.Name, Version: instance.Version, Path: instance.Path, Integrity: instance.Integrity})
+	}
+	return packages, skipped
+}
+
+// matchesAny reports whether an installed instance matches one of the queries by name and version
+func matchesAny(instance types.PackageInstance, queries []types.PackageQuery) bool {
+	for _, query := range queries {
+		if scanner.MatchPackageName(instance.Name, query.Name, scanner.FilterConfig{}) != "" && scanner.MatchesVersion(instance.Version, query.Version) {
+			return true
+		}
+	}
+	return false
+}
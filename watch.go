@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the watched files must stay quiet before a scan runs; npm rewrites
+// a lockfile several times during an install
+const watchDebounce = 500 * time.Millisecond
+
+// watchSummary is the part of the --summary-file of each run that --watch compares between runs
+type watchSummary struct {
+	Summary struct {
+		Risks int `json:"risks"`
+	} `json:"summary"`
+	Risky []string `json:"riskyPackages"`
+}
+
+// runWatch scans, then scans again whenever the lockfile or a packages file changes, until
+// interrupted. Every scan is a fresh scnpm process with the same flags, so that each one behaves
+// exactly like a one-off scan, and a scan that fails on a half-written lockfile doesn't end the
+// watch.
+func runWatch(args []string) {
+	if packageLockPath == stdinPath {
+		fmt.Fprintf(os.Stderr, "Error: --watch needs a lockfile path, not stdin\n")
+		os.Exit(exitError)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	targets, err := watchTargets(packageLockPath, append(leadingPackagesFiles(args), packagesFiles...))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to start watching: %v\n", err)
+		os.Exit(exitError)
+	}
+	defer watcher.Close()
+	// Editors and npm replace files rather than write them in place, which drops a watch on the
+	// file itself; the directories holding them are watched instead
+	dirs := make(map[string]bool)
+	for target := range targets {
+		dir := target
+		if info, err := os.Stat(target); err != nil || !info.IsDir() {
+			dir = filepath.Dir(target)
+		}
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+		if err := watcher.Add(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to watch '%s': %v\n", dir, err)
+			os.Exit(exitError)
+		}
+	}
+
+	// The summary of each run tells which risks are new
+	summaryPath, tempDir := summaryFile, ""
+	if summaryPath == "" {
+		tempDir, err = os.MkdirTemp("", "scnpm-watch-")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		defer os.RemoveAll(tempDir)
+		summaryPath = filepath.Join(tempDir, "summary.json")
+	}
+	// Flags go first, since anything after a "--" is a packages file
+	childArgs := append([]string{"--summary-file", summaryPath}, withoutWatchFlag(os.Args[1:])...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var previous *watchSummary
+	scan := func(reason string) bool {
+		fmt.Fprintf(os.Stderr, "[%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), reason)
+		os.Remove(summaryPath)
+		child := exec.Command(executable, childArgs...)
+		child.Stdout, child.Stderr = os.Stdout, os.Stderr
+		err := child.Run()
+		var exitErr *exec.ExitError
+		if err != nil && (!errors.As(err, &exitErr) || exitErr.ExitCode() == exitError) {
+			return false
+		}
+		current, err := readWatchSummary(summaryPath)
+		if err != nil {
+			printWarnings([]string{fmt.Sprintf("can't compare with the previous scan: %v", err)})
+			return true
+		}
+		fmt.Fprintln(os.Stderr, watchStatus(previous, current))
+		previous = current
+		return true
+	}
+
+	// A first scan that fails is most likely a bad flag, which no change to the files will fix
+	if !scan(fmt.Sprintf("Scanning %s", relativePath(packageLockPath))) {
+		os.RemoveAll(tempDir)
+		os.Exit(exitError)
+	}
+	watchLoop(ctx, watcher.Events, watcher.Errors, func(path string) bool {
+		return targets[filepath.Clean(path)] || targets[filepath.Dir(filepath.Clean(path))]
+	}, watchDebounce, func(changed []string) {
+		if !scan(fmt.Sprintf("%s changed, scanning again", strings.Join(changed, ", "))) && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Scan failed; waiting for the next change\n")
+		}
+	})
+	fmt.Fprintf(os.Stderr, "Stopped watching\n")
+}
+
+// watchLoop calls run with the watched files that changed once events about them stop arriving
+// for delay, until ctx is done. Events run doesn't care about are ignored; watcher errors are
+// warned about.
+func watchLoop(ctx context.Context, events <-chan fsnotify.Event, errs <-chan error, watched func(path string) bool, delay time.Duration, run func(changed []string)) {
+	timer := time.NewTimer(delay)
+	timer.Stop()
+	changed := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			// Chmod alone doesn't change what's scanned
+			if event.Op == fsnotify.Chmod || !watched(event.Name) {
+				continue
+			}
+			changed[relativePath(event.Name)] = true
+			timer.Reset(delay)
+		case err, ok := <-errs:
+			if !ok {
+				return
+			}
+			printWarnings([]string{fmt.Sprintf("watch error: %v", err)})
+		case <-timer.C:
+			paths := make([]string, 0, len(changed))
+			for path := range changed {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			changed = make(map[string]bool)
+			run(paths)
+		}
+	}
+}
+
+// watchTargets returns the absolute paths of the lockfile and packages files to watch. A
+// packages directory is watched as a whole.
+func watchTargets(lockfile string, packagesPaths []string) (map[string]bool, error) {
+	targets := make(map[string]bool)
+	for _, path := range append([]string{lockfile}, packagesPaths...) {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve path '%s': %v", path, err)
+		}
+		targets[abs] = true
+	}
+	return targets, nil
+}
+
+// leadingPackagesFiles returns the packages files among the leading positional arguments, as
+// loadQueries reads them
+func leadingPackagesFiles(args []string) []string {
+	var paths []string
+	for len(args) > 0 && isPackagesFile(args[0]) {
+		paths = append(paths, args[0])
+		args = args[1:]
+	}
+	return paths
+}
+
+// withoutWatchFlag returns the command line of a single scan from the one of scnpm --watch. The
+// scan gets --watch=false, in case anything else turns watching on, so that it can't start a
+// watch of its own.
+func withoutWatchFlag(args []string) []string {
+	kept := []string{"--watch=false"}
+	for _, arg := range args {
+		if arg == "--watch" || strings.HasPrefix(arg, "--watch=") {
+			continue
+		}
+		kept = append(kept, arg)
+	}
+	return kept
+}
+
+// readWatchSummary reads the --summary-file of a run
+func readWatchSummary(path string) (*watchSummary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var summary watchSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, fmt.Errorf("invalid summary file: %v", err)
+	}
+	return &summary, nil
+}
+
+// watchStatus is the line printed after each run: the risky packages that weren't risky in the
+// previous run, with a terminal bell, or how many risks are left. The first run has nothing to
+// compare with, so all its risks are new.
+func watchStatus(previous, current *watchSummary) string {
+	var known []string
+	if previous != nil {
+		known = previous.Risky
+	}
+	var added []string
+	for _, name := range current.Risky {
+		if !containsString(known, name) {
+			added = append(added, name)
+		}
+	}
+	if len(added) > 0 {
+		return fmt.Sprintf("\a%d new risky package(s): %s (%d risks in total)", len(added), strings.Join(added, ", "), current.Summary.Risks)
+	}
+	if current.Summary.Risks > 0 {
+		return fmt.Sprintf("No new risks (%d risks in total); waiting for changes", current.Summary.Risks)
+	}
+	return "No risks; waiting for changes"
+}
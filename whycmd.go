@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"scnpm/pkg/output"
+	"scnpm/pkg/scanner"
+	"scnpm/pkg/scnpm"
+
+	"github.com/spf13/cobra"
+)
+
+// exitNotInstalled is the exit status of why when the package isn't in the lockfile, as grep
+// exits 1 when nothing matches
+const exitNotInstalled = 1
+
+// whyOutput is the format of the explanation: table or json
+var whyOutput string
+
+var whyCmd = &cobra.Command{
+	Use:   "why PACKAGE[@VERSION]",
+	Short: "Explain why a package is installed",
+	Long: `Print every install location of a package and, under each, the tree of packages depending on
+it up to the project's direct dependencies, like npm explain. Everything comes from the lockfile,
+so node_modules doesn't need to be installed. A version or range narrows the install locations.
+The exit status is 1 when the package isn't installed.
+
+  scnpm why debug -f package-lock.json
+  scnpm why debug@^3.0.0 --output json`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePackageNames,
+	Run:               runWhy,
+}
+
+func init() {
+	whyCmd.Flags().StringVarP(&packageLockPath, "file", "f", "package-lock.json", "Path to package-lock.json, yarn.lock or pnpm-lock.yaml (use - for stdin)")
+	whyCmd.Flags().StringVarP(&whyOutput, "output", "o", "table", "Output format (table, json)")
+	completeLockfileFlags(whyCmd, []string{"table", "json"})
+	rootCmd.AddCommand(whyCmd)
+}
+
+func runWhy(cmd *cobra.Command, args []string) {
+	if whyOutput != "table" && whyOutput != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", whyOutput)
+		os.Exit(exitError)
+	}
+	query, err := scnpm.ParseQuery(args[0])
+	if err != nil || scanner.IsPattern(query.Name) {
+		fmt.Fprintf(os.Stderr, "Error: '%s' isn't a package name, expected package or package@version\n", args[0])
+		os.Exit(exitError)
+	}
+
+	packageLock, err := readPackageLock(packageLockPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading '%s': %v\n", packageLockPath, err)
+		os.Exit(exitError)
+	}
+	explanations := scanner.Explain(packageLock, query.Name, query.Version)
+
+	if whyOutput == "json" {
+		err = output.OutputWhyJSON(os.Stdout, query.Name, relativePath(packageLockPath), explanations)
+	} else if len(explanations) > 0 {
+		err = output.OutputWhy(os.Stdout, explanations, output.OutputConfig{ASCII: asciiLocale()})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(exitError)
+	}
+	if len(explanations) == 0 {
+		if whyOutput != "json" {
+			fmt.Fprintf(os.Stderr, "'%s' is not installed by '%s'\n", args[0], packageLockPath)
+		}
+		os.Exit(exitNotInstalled)
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// workspaceRoot returns the directory holding the root package.json for a lockfile path
+func workspaceRoot(lockPath string) string {
+	if lockPath == stdinPath {
+		if wd, err := os.Getwd(); err == nil {
+			return wd
+		}
+		return "."
+	}
+	return filepath.Dir(lockPath)
+}